@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+)
+
+// prefixIPNet - masked network of the subnet for the radix tree.
+func prefixIPNet(subnet netip.Prefix) net.IPNet {
+	subnet = subnet.Masked()
+
+	return net.IPNet{IP: subnet.Addr().AsSlice(), Mask: net.CIDRMask(subnet.Bits(), subnet.Addr().BitLen())}
+}
+
+// ipNetPrefix - subnet of the radix tree network.
+func ipNetPrefix(network net.IPNet) netip.Prefix {
+	ip, _ := netip.AddrFromSlice(network.IP)
+	ones, bits := network.Mask.Size()
+
+	if bits == 8*net.IPv4len {
+		ip = ip.Unmap()
+	}
+
+	return netip.PrefixFrom(ip, ones)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+const addrDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <ip>010.1.1.1</ip>
+        <ip>300.1.1.1</ip>
+        <ipv6>fd00::1</ipv6>
+        <ipSubnet>10.0.0.0/8</ipSubnet>
+        <ipSubnet>10.0.0.0</ipSubnet>
+        <ipv6Subnet>fd00::/16</ipv6Subnet>
+</content>
+</reg:register>`
+
+// TestAddrWire tests netip selectors keep the payload and protobuf forms, and bad addresses are skipped.
+func TestAddrWire(t *testing.T) {
+	defer func(dump *Dump, warnings *parser.Warnings) { CurrentDump, ParseWarnings = dump, warnings }(CurrentDump, ParseWarnings)
+
+	CurrentDump = NewDump()
+	ParseWarnings = parser.NewWarnings()
+
+	if err := Parse(strings.NewReader(addrDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := CurrentDump.ContentIdx[1]
+	payload := string(pack.PayloadBytes())
+
+	for _, s := range []string{
+		`"ip4":[{"ip4":167837953}]`,
+		`"ip6":[{"ip6":"/QAAAAAAAAAAAAAAAAAAAQ=="}]`,
+		`"sb4":[{"sb4":"10.0.0.0/8"}]`,
+		`"sb6":[{"sb6":"fd00::/16"}]`,
+	} {
+		if !strings.Contains(payload, s) {
+			t.Errorf("Payload: no %s: %s\n", s, payload)
+		}
+	}
+
+	if warnings, _ := ParseWarnings.List(parser.WarnBadAddress); len(warnings) != 2 {
+		t.Errorf("Warnings: %+v\n", warnings)
+	}
+
+	s := &server{}
+
+	resp, _ := s.SearchIP4(context.Background(), &pb.IP4Request{Query: 167837953})
+	if len(resp.GetResults()) != 2 || resp.GetResults()[0].GetAggr() != "10.0.0.0/8" || resp.GetResults()[1].GetIp4() != 167837953 {
+		t.Errorf("IPv4: %v\n", resp)
+	}
+
+	ip6 := netip.MustParseAddr("fd00::1").As16()
+
+	resp, _ = s.SearchIP6(context.Background(), &pb.IP6Request{Query: ip6[:]})
+	if len(resp.GetResults()) != 2 || resp.GetResults()[0].GetAggr() != "fd00::/16" || string(resp.GetResults()[1].GetIp6()) != string(ip6[:]) {
+		t.Errorf("IPv6: %v\n", resp)
+	}
+
+	// snapshots written with the former uint32 and string selectors are read.
+	type legacyIP4 struct {
+		IP4 uint32
+		Ts  int64
+	}
+
+	type legacySubnet4 struct {
+		Subnet4 string
+		Ts      int64
+	}
+
+	type legacyContent struct {
+		ID                 int32
+		RegistryUpdateTime int64
+		Decision           uint64
+		IP4                []legacyIP4
+		Subnet4            []legacySubnet4
+		Payload            []byte
+	}
+
+	type legacySnapshot struct {
+		Generation int64
+		UpdateTime int64
+		Contents   []*legacyContent
+	}
+
+	filename := filepath.Join(t.TempDir(), "legacy.gob.gz")
+
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw := gzip.NewWriter(f)
+	if err := gob.NewEncoder(zw).Encode(&legacySnapshot{Generation: 1, UpdateTime: CurrentDump.utime, Contents: []*legacyContent{{
+		ID: 1, RegistryUpdateTime: pack.RegistryUpdateTime, Decision: pack.Decision,
+		IP4: []legacyIP4{{IP4: 167837953}}, Subnet4: []legacySubnet4{{Subnet4: "10.0.0.0/8"}}, Payload: []byte(payload),
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	zw.Close()
+	f.Close()
+
+	snap, err := ReadSnapshot(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	CurrentDump = NewDump()
+	if err := CurrentDump.RestoreSnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(CurrentDump.ip4Idx[netip.MustParseAddr("10.1.1.1")]) != 1 || len(CurrentDump.subnet6Idx[netip.MustParsePrefix("fd00::/16")]) != 1 {
+		t.Errorf("Restored: %v %v\n", CurrentDump.ip4Idx, CurrentDump.subnet6Idx)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// adminTokenEnv - environment variable of the admin token, it is not passed in argv.
+const adminTokenEnv = "U2CK_DUMP_ADMIN_TOKEN"
+
+// AdminToken - bearer token of the RPCs changing the instance: index repair, tags, maintenance, faults.
+// Without it they are refused, it is set once at startup.
+var AdminToken string
+
+// adminAllowed - the request carries "authorization: Bearer <AdminToken>" metadata.
+func adminAllowed(ctx context.Context) bool {
+	if AdminToken == "" {
+		return false
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	expected := []byte("Bearer " + AdminToken)
+
+	for _, v := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(v), expected) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
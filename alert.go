@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Alert kinds.
+const (
+	AlertChurn         = "churn"          // anomalous add/update/remove counts.
+	AlertOversized     = "oversized"      // the dump is over the size or compression ratio limits, see ErrTooBig.
+	AlertShadow        = "shadow"         // the shadow parse disagrees with the live one, see ShadowParse.
+	AlertRegistryStale = "registry-stale" // the registry update time is older than RegistryMaxAge.
+)
+
+// AlertWebhook - URL to POST alert events as JSON, empty disables, it is set once at startup.
+var AlertWebhook string
+
+// AlertEvent - alert as it is sent to the webhook.
+type AlertEvent struct {
+	Kind       string    `json:"kind"`
+	Message    string    `json:"message"`
+	Generation int64     `json:"generation"`
+	Time       time.Time `json:"time"`
+}
+
+// RaiseAlert - log, count and send the alert event.
+func RaiseAlert(kind string, generation int64, message string) {
+	logger.Warning.Printf("Alert %s: %s\n", kind, message)
+
+	metricAlerts.Add(kind, 1)
+
+	if AlertWebhook == "" {
+		return
+	}
+
+	event := AlertEvent{Kind: kind, Message: message, Generation: generation, Time: time.Now()}
+
+	go func() {
+		if err := postJSON(AlertWebhook, event); err != nil {
+			logger.Error.Printf("Can't send alert: %s\n", err.Error())
+		}
+	}()
+}
+
+// postJSON - POST the event as JSON to the webhook.
+func postJSON(url string, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook status: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"expvar"
+	"sort"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// Anomaly - offending address of a record, see parser.Anomaly.
+type Anomaly = parser.Anomaly
+
+// setAnomalies - anomalies of the applied record, call it under lock.
+func (dump *Dump) setAnomalies(id int32, anomalies []Anomaly) {
+	if len(anomalies) == 0 {
+		delete(dump.anomalies, id)
+
+		return
+	}
+
+	if dump.anomalies == nil {
+		dump.anomalies = make(map[int32][]Anomaly)
+	}
+
+	dump.anomalies[id] = anomalies
+}
+
+// Anomalies - address anomalies of the current records of the kind (all if it is empty), ordered by id,
+// and the counts by kind. Records are checked when they are parsed, a restored snapshot has none
+// until its records change. Call it under read lock.
+func (dump *Dump) Anomalies(kind string) ([]Anomaly, map[string]int) {
+	var list []Anomaly
+
+	counts := make(map[string]int)
+
+	for _, anomalies := range dump.anomalies {
+		for _, a := range anomalies {
+			counts[a.Kind]++
+
+			if kind == "" || a.Kind == kind {
+				list = append(list, a)
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ID != list[j].ID {
+			return list[i].ID < list[j].ID
+		}
+
+		if list[i].Field != list[j].Field {
+			return list[i].Field < list[j].Field
+		}
+
+		return list[i].Value < list[j].Value
+	})
+
+	return list, counts
+}
+
+// publishAnomalies - anomaly counts of the current records to the metrics, the total
+// and the private and bogon addresses are returned. Call it under read lock.
+func (dump *Dump) publishAnomalies() (int, int) {
+	_, counts := dump.Anomalies("")
+
+	var total int
+
+	for _, kind := range []string{parser.AnomalyBadAddress, parser.AnomalyLeadingZeros, parser.AnomalyHostBits, parser.AnomalyTooWide, parser.AnomalySpecial, parser.AnomalyPrivate, parser.AnomalyBogon} {
+		n := new(expvar.Int)
+		n.Set(int64(counts[kind]))
+		metricAddressAnomalies.Set(kind, n)
+
+		total += counts[kind]
+	}
+
+	return total, counts[parser.AnomalyPrivate] + counts[parser.AnomalyBogon]
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+const anomalyDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <ip>010.1.1.1</ip>
+        <ip>127.0.0.1</ip>
+        <ip>1.2.3.4</ip>
+        <ip>garbage</ip>
+        <ipSubnet>10.0.0.1/8</ipSubnet>
+        <ipSubnet>0.0.0.0/0</ipSubnet>
+        <ipSubnet>11.0.0.0/8</ipSubnet>
+        <ipv6Subnet>fd00::/8</ipv6Subnet>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <ip>1.2.3.5</ip>
+</content>
+</reg:register>`
+
+// TestAddressAnomalies tests anomalies are reported, kept or rejected by the policy, and dropped with their records.
+func TestAddressAnomalies(t *testing.T) {
+	defer func(dump *Dump, warnings *parser.Warnings, policy string) {
+		CurrentDump, ParseWarnings, ParseConfig.Anomalies = dump, warnings, policy
+	}(CurrentDump, ParseWarnings, ParseConfig.Anomalies)
+
+	ParseWarnings = parser.NewWarnings()
+
+	for _, tc := range []struct {
+		policy          string
+		ip4, subnet4    int
+		specialIndexed  bool
+		wideSubnetKnown bool
+	}{
+		{parser.AnomalyKeep, 4, 3, true, true},
+		{parser.AnomalyReject, 3, 1, false, false},
+	} {
+		CurrentDump, ParseConfig.Anomalies = NewDump(), tc.policy
+
+		if err := Parse(strings.NewReader(anomalyDump)); err != nil {
+			t.Fatal(err)
+		}
+
+		_, special := CurrentDump.ip4Idx[netip.MustParseAddr("127.0.0.1")]
+		_, wide := CurrentDump.subnet4Idx[netip.MustParsePrefix("0.0.0.0/0")]
+
+		if len(CurrentDump.ip4Idx) != tc.ip4 || len(CurrentDump.subnet4Idx) != tc.subnet4 || special != tc.specialIndexed || wide != tc.wideSubnetKnown {
+			t.Errorf("%s: %v %v\n", tc.policy, CurrentDump.ip4Idx, CurrentDump.subnet4Idx)
+		}
+
+		if Stats.Anomalies != 6 {
+			t.Errorf("%s: stats: %d\n", tc.policy, Stats.Anomalies)
+		}
+	}
+
+	resp, _ := (&server{}).GetAddressAnomalies(context.Background(), &pb.AnomaliesRequest{Kind: parser.AnomalyTooWide})
+	if resp.GetTotal() != 2 || resp.GetPolicy() != parser.AnomalyReject || resp.GetAnomalies()[0].GetId() != 1 ||
+		resp.GetAnomalies()[0].GetField() != parser.ElementIP4Subnet || resp.GetAnomalies()[0].GetValue() != "0.0.0.0/0" {
+		t.Errorf("Too wide: %v\n", resp)
+	}
+
+	counts := make(map[string]int32)
+	for _, c := range resp.GetCounts() {
+		counts[c.GetKind()] = c.GetCount()
+	}
+
+	if counts[parser.AnomalyBadAddress] != 1 || counts[parser.AnomalyLeadingZeros] != 1 || counts[parser.AnomalySpecial] != 1 || counts[parser.AnomalyHostBits] != 1 {
+		t.Errorf("Counts: %v\n", counts)
+	}
+
+	if metricAddressAnomalies.Get(parser.AnomalyTooWide).String() != "2" {
+		t.Errorf("Metric: %s\n", metricAddressAnomalies.String())
+	}
+
+	// the record with anomalies is removed.
+	removed := anomalyDump[:strings.Index(anomalyDump, `<content id="1"`)] + anomalyDump[strings.Index(anomalyDump, `<content id="2"`):]
+	if err := Parse(strings.NewReader(removed)); err != nil {
+		t.Fatal(err)
+	}
+
+	if Stats.Anomalies != 0 {
+		t.Errorf("Removed: %d\n", Stats.Anomalies)
+	}
+
+	// stats.csv rows written before the anomalies column.
+	row := StatsPoint{Count: 2, Anomalies: 6}.row()
+	if p, err := parseStatsRow(row[:statsHistoryV1Columns]); err != nil || p.Count != 2 || p.Anomalies != 0 {
+		t.Errorf("Old row: %+v %v\n", p, err)
+	}
+}
+
+// TestPrivateBogonAddresses tests private and bogon addresses are flagged, indexed and left out of filtered exports.
+func TestPrivateBogonAddresses(t *testing.T) {
+	for value, want := range map[string]string{
+		"10.1.1.1":       parser.AnomalyPrivate,
+		"172.31.0.0/16":  parser.AnomalyPrivate,
+		"172.0.0.0/8":    "",
+		"fd00::/64":      parser.AnomalyPrivate,
+		"100.64.1.1":     parser.AnomalyBogon,
+		"192.0.2.0/25":   parser.AnomalyBogon,
+		"2001:db8::1":    parser.AnomalyBogon,
+		"198.18.0.0/14":  "",
+		"8.8.8.8":        "",
+		"2a00:1450::/32": "",
+	} {
+		p, err := netip.ParsePrefix(value)
+		if err != nil {
+			ip := netip.MustParseAddr(value)
+			p = netip.PrefixFrom(ip, ip.BitLen())
+		}
+
+		if got := parser.AddressClass(p); got != want {
+			t.Errorf("%s: %q\n", value, got)
+		}
+	}
+
+	defer func(dump *Dump, policy string) {
+		CurrentDump, ParseConfig.Anomalies = dump, policy
+	}(CurrentDump, ParseConfig.Anomalies)
+
+	CurrentDump, ParseConfig.Anomalies = NewDump(), parser.AnomalyReject
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	// private addresses are indexed even if anomalies are rejected.
+	if _, ok := CurrentDump.ip4Idx[netip.MustParseAddr("10.1.1.1")]; !ok {
+		t.Errorf("Not indexed: %v\n", CurrentDump.ip4Idx)
+	}
+
+	if list, counts := CurrentDump.Anomalies(parser.AnomalyPrivate); len(list) == 0 || Stats.Bogons != counts[parser.AnomalyPrivate] || list[0].ID != 111 {
+		t.Errorf("Private: %d %v\n", Stats.Bogons, list)
+	}
+
+	filter, err := ParseExportFilter("ipv=4;addr=!bogon,!private")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := CurrentDump.ContentIdx[111].Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record.IP4 = append(record.IP4, IP4{IP4: netip.MustParseAddr("1.2.3.4")}, IP4{IP4: netip.MustParseAddr("100.64.0.1")})
+	record.IP6 = append(record.IP6, IP6{IP6: netip.MustParseAddr("2a00::1")})
+
+	if line := registryCSVLine(record, filter); !strings.HasPrefix(line, "1.2.3.4;") {
+		t.Errorf("Line: %q\n", line)
+	}
+}
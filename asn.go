@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// ASNTable - IP ranges of autonomous systems in the iptoasn.com TSV format:
+// range_start, range_end, AS number, country code, AS description.
+// ASN 0 covers not routed and unknown addresses.
+type ASNTable struct {
+	ranges4 []asnRange4 // sorted by start, not overlapping.
+	ranges6 []asnRange6
+	names   map[uint32]asnName
+}
+
+type asnRange4 struct {
+	start, end uint32
+	asn        uint32
+}
+
+type asnRange6 struct {
+	start, end [net.IPv6len]byte
+	asn        uint32
+}
+
+type asnName struct {
+	name, country string
+}
+
+// ASNs - loaded ASN table, nil disables ASN reports. It is set once at startup.
+var ASNs *ASNTable
+
+// LoadASNTable - read the ASN table from the file.
+func LoadASNTable(filename string) (*ASNTable, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	defer f.Close()
+
+	return ParseASNTable(f)
+}
+
+// ParseASNTable - one range per tab separated line, # starts a comment.
+func ParseASNTable(r io.Reader) (*ASNTable, error) {
+	t := &ASNTable{names: make(map[uint32]asnName)}
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: %d fields", n, len(fields))
+		}
+
+		start, end := net.ParseIP(fields[0]), net.ParseIP(fields[1])
+		if start == nil || end == nil {
+			return nil, fmt.Errorf("line %d: bad range", n)
+		}
+
+		asn, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "AS"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad ASN: %w", n, err)
+		}
+
+		if asn == 0 {
+			continue
+		}
+
+		name := asnName{}
+		if len(fields) > 3 {
+			name.country = fields[3]
+		}
+
+		if len(fields) > 4 {
+			name.name = fields[4]
+		}
+
+		t.names[uint32(asn)] = name
+
+		start4, end4 := start.To4(), end.To4()
+
+		switch {
+		case start4 != nil && end4 != nil:
+			r := asnRange4{start: ip4ToInt(start4), end: ip4ToInt(end4), asn: uint32(asn)}
+			if r.start > r.end {
+				return nil, fmt.Errorf("line %d: bad range", n)
+			}
+
+			t.ranges4 = append(t.ranges4, r)
+		case start4 == nil && end4 == nil:
+			r := asnRange6{asn: uint32(asn)}
+			copy(r.start[:], start.To16())
+			copy(r.end[:], end.To16())
+
+			if bytes.Compare(r.start[:], r.end[:]) > 0 {
+				return nil, fmt.Errorf("line %d: bad range", n)
+			}
+
+			t.ranges6 = append(t.ranges6, r)
+		default:
+			return nil, fmt.Errorf("line %d: mixed range", n)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	sort.Slice(t.ranges4, func(i, j int) bool { return t.ranges4[i].start < t.ranges4[j].start })
+	sort.Slice(t.ranges6, func(i, j int) bool { return bytes.Compare(t.ranges6[i].start[:], t.ranges6[j].start[:]) < 0 })
+
+	return t, nil
+}
+
+func ip4ToInt(ip net.IP) uint32 {
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
+
+// Lookup4 - ASN of the IPv4 address, 0 if unknown.
+func (t *ASNTable) Lookup4(ip uint32) uint32 {
+	i := sort.Search(len(t.ranges4), func(i int) bool { return t.ranges4[i].start > ip }) - 1
+	if i >= 0 && t.ranges4[i].end >= ip {
+		return t.ranges4[i].asn
+	}
+
+	return 0
+}
+
+// Lookup6 - ASN of the 16 byte IPv6 address, 0 if unknown.
+func (t *ASNTable) Lookup6(ip []byte) uint32 {
+	i := sort.Search(len(t.ranges6), func(i int) bool { return bytes.Compare(t.ranges6[i].start[:], ip) > 0 }) - 1
+	if i >= 0 && bytes.Compare(t.ranges6[i].end[:], ip) >= 0 {
+		return t.ranges6[i].asn
+	}
+
+	return 0
+}
+
+// split4 - call f for the parts of [start, end] in ASN ranges, gaps go to ASN 0.
+func (t *ASNTable) split4(start, end uint32, f func(asn, start, end uint32)) {
+	i := sort.Search(len(t.ranges4), func(i int) bool { return t.ranges4[i].end >= start })
+	cursor := uint64(start)
+
+	for ; i < len(t.ranges4) && t.ranges4[i].start <= end; i++ {
+		r := t.ranges4[i]
+
+		if uint64(r.start) > cursor {
+			f(0, uint32(cursor), r.start-1)
+			cursor = uint64(r.start)
+		}
+
+		last := r.end
+		if last > end {
+			last = end
+		}
+		f(r.asn, uint32(cursor), last)
+		cursor = uint64(last) + 1
+	}
+
+	if cursor <= uint64(end) {
+		f(0, uint32(cursor), end)
+	}
+}
+
+// ASNUsage - blocked addresses of one autonomous system.
+type ASNUsage struct {
+	ASN     uint32
+	Name    string
+	Country string
+	IP4     uint64 // distinct IPv4 addresses in blocked IPs and subnets.
+	IP6     int    // blocked IPv6 addresses.
+	Subnet6 int    // blocked IPv6 subnets, by the network address.
+	Records int    // records blocking any of them.
+}
+
+// asnReportCache - report of the last generation, it is built once per generation and filter.
+var asnReportCache struct {
+	sync.Mutex
+	table      *ASNTable
+	generation int64
+	filter     string
+	usage      []ASNUsage
+}
+
+// ASNReport - per ASN usage of the records of the current generation passing the filter,
+// by IPv4 addresses descending.
+func ASNReport(t *ASNTable, filter *ExportFilter) []ASNUsage {
+	asnReportCache.Lock()
+	defer asnReportCache.Unlock()
+
+	generation, _ := CurrentDump.Changes()
+	if asnReportCache.table == t && asnReportCache.generation == generation && asnReportCache.filter == filter.String() {
+		return asnReportCache.usage
+	}
+
+	asnReportCache.table, asnReportCache.generation, asnReportCache.filter = t, generation, filter.String()
+	asnReportCache.usage = t.report(CurrentDump, filter)
+
+	return asnReportCache.usage
+}
+
+// asnAccum - usage being collected.
+type asnAccum struct {
+	intervals [][2]uint32
+	ip6       int
+	subnet6   int
+	records   map[int32]Nothing
+}
+
+func (t *ASNTable) report(dump *Dump, filter *ExportFilter) []ASNUsage {
+	accums := make(map[uint32]*asnAccum)
+
+	get := func(asn uint32, ids ArrayIntSet) *asnAccum {
+		a, ok := accums[asn]
+		if !ok {
+			a = &asnAccum{records: make(map[int32]Nothing)}
+			accums[asn] = a
+		}
+
+		for _, id := range ids {
+			a.records[id] = Nothing{}
+		}
+
+		return a
+	}
+
+	dump.RLock()
+
+	// passed - records of the selector passing the filter.
+	passed := func(ids ArrayIntSet) ArrayIntSet {
+		if filter == nil {
+			return ids
+		}
+
+		var result ArrayIntSet
+
+		for _, id := range ids {
+			if pack, ok := dump.ContentIdx[id]; ok && filter.Match(pack) {
+				result = append(result, id)
+			}
+		}
+
+		return result
+	}
+
+	for ip, ids := range dump.ip4Idx {
+		if ids = passed(ids); len(ids) == 0 || !filter.Addr(ip) {
+			continue
+		}
+
+		ip4 := parser.Addr4Uint32(ip)
+		a := get(t.Lookup4(ip4), ids)
+		a.intervals = append(a.intervals, [2]uint32{ip4, ip4})
+	}
+
+	for subnet, ids := range dump.subnet4Idx {
+		if ids = passed(ids); len(ids) == 0 || !filter.Address(subnet) {
+			continue
+		}
+
+		start := parser.Addr4Uint32(subnet.Masked().Addr())
+		end := start | uint32(uint64(1)<<(32-subnet.Bits())-1)
+
+		t.split4(start, end, func(asn, start, end uint32) {
+			a := get(asn, ids)
+			a.intervals = append(a.intervals, [2]uint32{start, end})
+		})
+	}
+
+	for ip, ids := range dump.ip6Idx {
+		if ids = passed(ids); len(ids) == 0 || !filter.Addr(ip) {
+			continue
+		}
+
+		ip6 := ip.As16()
+		get(t.Lookup6(ip6[:]), ids).ip6++
+	}
+
+	for subnet, ids := range dump.subnet6Idx {
+		if ids = passed(ids); len(ids) == 0 || !filter.Address(subnet) {
+			continue
+		}
+
+		ip6 := subnet.Masked().Addr().As16()
+		get(t.Lookup6(ip6[:]), ids).subnet6++
+	}
+
+	dump.RUnlock()
+
+	usage := make([]ASNUsage, 0, len(accums))
+
+	for asn, a := range accums {
+		name := t.names[asn]
+		usage = append(usage, ASNUsage{
+			ASN:     asn,
+			Name:    name.name,
+			Country: name.country,
+			IP4:     mergedSize(a.intervals),
+			IP6:     a.ip6,
+			Subnet6: a.subnet6,
+			Records: len(a.records),
+		})
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].IP4 != usage[j].IP4 {
+			return usage[i].IP4 > usage[j].IP4
+		}
+
+		if usage[i].Records != usage[j].Records {
+			return usage[i].Records > usage[j].Records
+		}
+
+		return usage[i].ASN < usage[j].ASN
+	})
+
+	return usage
+}
+
+// mergedSize - number of addresses in the union of the intervals.
+func mergedSize(intervals [][2]uint32) uint64 {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i][0] < intervals[j][0] })
+
+	var (
+		size uint64
+		next uint64 // first address not counted yet.
+	)
+
+	for _, iv := range intervals {
+		start, end := uint64(iv[0]), uint64(iv[1])
+		if end < next {
+			continue
+		}
+
+		if start < next {
+			start = next
+		}
+
+		size += end - start + 1
+		next = end + 1
+	}
+
+	return size
+}
+
+const asnReportFilename = "asn.csv"
+
+// asnReportColumns - CSV header of the ASN report.
+var asnReportColumns = []string{"asn", "name", "country", "ip4", "ip6", "subnet6", "records"}
+
+// WriteASNReport - replace the ASN report CSV in the dir, the first line is "# <provenance>".
+func WriteASNReport(dir string, usage []ASNUsage, prov Provenance) error {
+	filename := dir + "/" + asnReportFilename
+	tmpfilename := filename + "-tmp"
+
+	f, err := os.Create(tmpfilename)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	fmt.Fprintf(f, "# %s\n", prov)
+
+	w := csv.NewWriter(f)
+	w.Write(asnReportColumns)
+
+	for _, u := range usage {
+		w.Write([]string{
+			strconv.FormatUint(uint64(u.ASN), 10),
+			u.Name,
+			u.Country,
+			strconv.FormatUint(u.IP4, 10),
+			strconv.Itoa(u.IP6),
+			strconv.Itoa(u.Subnet6),
+			strconv.Itoa(u.Records),
+		})
+	}
+
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		f.Close()
+
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tmpfilename, filename); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// ExportASNReport - write the ASN report of the new generation, if the ASN table is loaded,
+// the ASNs written and true if it is written. Only records passing ExportFilterConfig are counted.
+func ExportASNReport(dir string) (int, bool) {
+	if ASNs == nil {
+		return 0, false
+	}
+
+	usage := ASNReport(ASNs, ExportFilterConfig)
+
+	if err := WriteASNReport(dir, usage, CurrentDump.Provenance()); err != nil {
+		logger.Error.Printf("Can't save ASN report: %s\n", err.Error())
+
+		return 0, false
+	}
+
+	return len(usage), true
+}
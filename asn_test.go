@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+const asnTable = `# range_start	range_end	AS_number	country_code	AS_description
+10.0.0.0	10.3.255.255	64500	RU	TEN
+10.4.0.0	10.4.127.255	64501	RU	HALF
+10.9.0.0	10.9.255.255	0	None	Not routed
+192.168.0.0	192.168.255.255	64502	NL	PRIVATE
+fd00::	fd3f:ffff:ffff:ffff:ffff:ffff:ffff:ffff	64503	US	ULA
+`
+
+// TestASNReport tests attribution of blocked IPs and subnets to autonomous systems.
+func TestASNReport(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	table, err := ParseASNTable(strings.NewReader(asnTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table.Lookup4(parser.IPv4StrToInt("10.9.1.1")) != 0 || table.Lookup4(parser.IPv4StrToInt("10.3.255.255")) != 64500 {
+		t.Error("lookup")
+	}
+
+	if _, err := ParseASNTable(strings.NewReader("10.0.0.1\t10.0.0.0\t1\n")); err == nil {
+		t.Error("reversed range is accepted")
+	}
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10.4.0.0/16 is split between AS64501 and unknown, 10.4.4.4 is inside it.
+	want := []ASNUsage{
+		{ASN: 0, IP4: 32769, IP6: 5, Records: 5},
+		{ASN: 64501, Name: "HALF", Country: "RU", IP4: 32768, Records: 1},
+		{ASN: 64502, Name: "PRIVATE", Country: "NL", IP4: 8, Records: 5},
+		{ASN: 64500, Name: "TEN", Country: "RU", IP4: 3, Records: 3},
+		{ASN: 64503, Name: "ULA", Country: "US", IP6: 6, Records: 3},
+	}
+
+	usage := ASNReport(table, nil)
+	if len(usage) != len(want) {
+		t.Fatalf("report: %v", usage)
+	}
+
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("row %d: %+v, want %+v", i, usage[i], want[i])
+		}
+	}
+
+	dir := t.TempDir()
+	if err := WriteASNReport(dir, usage, Provenance{Generation: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(dir + "/" + asnReportFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+
+	rows, err := r.ReadAll()
+	if err != nil || len(rows) != len(want)+1 || rows[2][0] != "64501" || rows[2][3] != "32768" {
+		t.Errorf("csv: %v %v", rows, err)
+	}
+}
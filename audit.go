@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// AuditTargetSyslog - audit target of the local syslog.
+const AuditTargetSyslog = "syslog"
+
+// AuditLog - append-only log of every RPC, one JSON object per line.
+type AuditLog struct {
+	mu   sync.Mutex
+	w    io.WriteCloser
+	salt []byte // selectors are replaced with their HMAC if set.
+}
+
+// Audit - audit log, nil disables it. It is set once at startup.
+var Audit *AuditLog
+
+// AuditRecord - audited call.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	ReqID   string    `json:"request_id,omitempty"`
+	Peer    string    `json:"peer,omitempty"`
+	Key     string    `json:"key,omitempty"`   // API key fingerprint, the key is never logged.
+	Query   string    `json:"query,omitempty"` // selector or its hash.
+	Results int       `json:"results"`         // found records, sent messages for streams.
+	Code    string    `json:"code"`
+	Error   string    `json:"error,omitempty"` // in-band error of the response.
+	Latency float64   `json:"latency_ms"`
+}
+
+// OpenAuditLog - audit to the file or the local syslog, a non empty salt enables selector hashing.
+func OpenAuditLog(target, salt string) (*AuditLog, error) {
+	var (
+		w   io.WriteCloser
+		err error
+	)
+
+	if target == AuditTargetSyslog {
+		w, err = syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "u2ckdump")
+	} else {
+		w, err = os.OpenFile(target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	return NewAuditLog(w, salt), nil
+}
+
+// NewAuditLog - audit to the writer.
+func NewAuditLog(w io.WriteCloser, salt string) *AuditLog {
+	a := &AuditLog{w: w}
+	if salt != "" {
+		a.salt = []byte(salt)
+	}
+
+	return a
+}
+
+// Close - close the log.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.w.Close()
+}
+
+// ServerOptions - interceptors auditing every RPC except health checks.
+func (a *AuditLog) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.unary),
+		grpc.ChainStreamInterceptor(a.stream),
+	}
+}
+
+func (a *AuditLog) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if auditSkipped(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	started := time.Now()
+	resp, err := handler(ctx, req)
+
+	record := a.newRecord(ctx, info.FullMethod, req, started, err)
+
+	if r, ok := resp.(interface{ GetResults() []*pb.Content }); ok {
+		record.Results = len(r.GetResults())
+	}
+
+	if r, ok := resp.(interface{ GetError() string }); ok {
+		record.Error = r.GetError()
+	}
+
+	a.write(record)
+
+	return resp, err
+}
+
+func (a *AuditLog) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if auditSkipped(info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	started := time.Now()
+	counted := &auditStream{ServerStream: ss}
+	err := handler(srv, counted)
+
+	record := a.newRecord(ss.Context(), info.FullMethod, counted.req, started, err)
+	record.Results = counted.sent
+
+	a.write(record)
+
+	return err
+}
+
+// auditStream - count sent messages, keep the request.
+type auditStream struct {
+	grpc.ServerStream
+	req  interface{}
+	sent int
+}
+
+func (s *auditStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+
+	return err
+}
+
+func (s *auditStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && s.req == nil {
+		s.req = m
+	}
+
+	return err
+}
+
+// auditSkipped - probes would flood the log.
+func auditSkipped(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.")
+}
+
+func (a *AuditLog) newRecord(ctx context.Context, method string, req interface{}, started time.Time, err error) AuditRecord {
+	record := AuditRecord{
+		Time:    started.UTC(),
+		Method:  method,
+		Code:    status.Code(err).String(),
+		Latency: float64(time.Since(started).Microseconds()) / 1000,
+	}
+
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		record.ReqID = id
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		record.Peer = p.Addr.String()
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, name := range []string{"x-api-key", "authorization"} {
+			if v := md.Get(name); len(v) > 0 && v[0] != "" {
+				sum := sha256.Sum256([]byte(v[0]))
+				record.Key = hex.EncodeToString(sum[:8])
+
+				break
+			}
+		}
+	}
+
+	if query := auditQuery(req); query != "" {
+		record.Query = a.hideSelector(query)
+	}
+
+	return record
+}
+
+// hideSelector - HMAC of the selector if hashing is enabled: the same selector
+// has the same hash, but it can't be recovered without the salt.
+func (a *AuditLog) hideSelector(s string) string {
+	if a.salt == nil {
+		return s
+	}
+
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(s))
+
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// auditQuery - selector of the search request, empty for others.
+func auditQuery(req interface{}) string {
+	switch r := req.(type) {
+	case interface{ GetQuery() string }:
+		return r.GetQuery()
+	case interface{ GetQuery() uint32 }:
+		return int2Ip4(r.GetQuery())
+	case interface{ GetQuery() []byte }:
+		return net.IP(r.GetQuery()).String()
+	case interface{ GetQuery() int32 }:
+		return strconv.FormatInt(int64(r.GetQuery()), 10)
+	case interface{ GetQuery() uint64 }:
+		return strconv.FormatUint(r.GetQuery(), 10)
+	}
+
+	return ""
+}
+
+func (a *AuditLog) write(record AuditRecord) {
+	dat, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// one write per record, concurrent appends don't interleave.
+	if _, err := a.w.Write(append(dat, '\n')); err != nil {
+		logger.Error.Printf("Can't write audit log: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// auditBuffer - audit writer for tests.
+type auditBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *auditBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.Buffer.Write(p)
+}
+
+func (b *auditBuffer) Close() error {
+	return nil
+}
+
+// TestAuditLog tests audit records of searches with hashed selectors.
+func TestAuditLog(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &auditBuffer{}
+	audit := NewAuditLog(buf, "salt")
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer(audit.ServerOptions()...)
+	pb.RegisterCheckServer(srv, &server{})
+
+	go srv.Serve(listen)
+
+	conn, err := grpc.Dial(listen.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := pb.NewCheckClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+
+	if _, err := client.SearchIP4(ctx, &pb.IP4Request{Query: parser.IPv4StrToInt("192.168.0.100")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Ping(context.Background(), &pb.PingRequest{Ping: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Close()
+	srv.Stop()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines: %q", lines)
+	}
+
+	record := AuditRecord{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if record.Method != "/msg.Check/SearchIP4" || record.Results != 3 || record.Code != "OK" || record.Peer == "" {
+		t.Errorf("record: %+v", record)
+	}
+
+	if record.Query != audit.hideSelector("192.168.0.100") || record.Query == "192.168.0.100" {
+		t.Errorf("query: %s", record.Query)
+	}
+
+	if record.Key == "" || strings.Contains(lines[0], "secret") {
+		t.Errorf("key: %s", record.Key)
+	}
+
+	if strings.Contains(lines[1], `"key"`) || !strings.Contains(lines[1], "Ping") {
+		t.Errorf("ping: %s", lines[1])
+	}
+}
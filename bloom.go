@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// SNI bloom filter artifact files.
+const (
+	sniBloomFilename     = "sni.bloom"
+	sniBloomMetaFilename = "sni.bloom.json"
+)
+
+// sniBloomMagic, sniBloomVersion - header of the artifact.
+const (
+	sniBloomMagic   = "U2SB"
+	sniBloomVersion = 1
+)
+
+// sniBloomHeaderLen - magic, version, hashes, 2 reserved bytes, bits, items, registry update time, generation.
+const sniBloomHeaderLen = 4 + 1 + 1 + 2 + 8 + 8 + 8 + 8
+
+// SNIBloomFP - false positive rate of the SNI bloom filter exported after every parse, 0 disables.
+var SNIBloomFP float64
+
+// ErrBadBloom - not an SNI bloom filter artifact.
+var ErrBadBloom = errors.New("bad bloom filter")
+
+// BloomFilter - bloom filter of host names. Positions of a name are (h1 + i*h2) mod bits
+// for i < hashes, where h is FNV-1a 64 of the name, h1 is its low 32 bits, h2 the high
+// 32 bits with the lowest bit set. Bit n is bit n%8 (LSB first) of byte n/8.
+type BloomFilter struct {
+	bits   []byte
+	m      uint64
+	hashes uint8
+}
+
+// NewBloomFilter - filter for n names with the false positive rate.
+func NewBloomFilter(n int, fp float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	if k > 32 {
+		k = 32
+	}
+
+	return &BloomFilter{bits: make([]byte, (m+7)/8), m: m, hashes: uint8(k)}
+}
+
+// positions - call fn for every bit of the name.
+func (f *BloomFilter) positions(name string, fn func(n uint64) bool) bool {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		if !fn((h1 + i*h2) % f.m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Add - add the name.
+func (f *BloomFilter) Add(name string) {
+	f.positions(name, func(n uint64) bool {
+		f.bits[n/8] |= 1 << (n % 8)
+
+		return true
+	})
+}
+
+// Test - the name may be in the filter, false means it is not.
+func (f *BloomFilter) Test(name string) bool {
+	return f.positions(name, func(n uint64) bool {
+		return f.bits[n/8]&(1<<(n%8)) != 0
+	})
+}
+
+// SNIBloomMeta - version metadata of the artifact, sni.bloom.json.
+type SNIBloomMeta struct {
+	Version            int     `json:"version"`
+	Generation         int64   `json:"generation"`
+	RegistryUpdateTime int64   `json:"registryUpdateTime"`
+	Items              int     `json:"items"`
+	Bits               uint64  `json:"bits"`
+	Hashes             int     `json:"hashes"`
+	FPRate             float64 `json:"fpRate"`
+	Size               int     `json:"size"`
+	SHA256             string  `json:"sha256"`           // of sni.bloom, also its ETag.
+	DumpID             string  `json:"dumpId,omitempty"` // provenance, not in sni.bloom.
+	CRC                string  `json:"crc,omitempty"`
+	ToolVersion        string  `json:"toolVersion,omitempty"`
+}
+
+// marshalSNIBloom - the artifact: big endian header and the bits.
+func marshalSNIBloom(f *BloomFilter, meta *SNIBloomMeta) []byte {
+	buf := make([]byte, sniBloomHeaderLen, sniBloomHeaderLen+len(f.bits))
+
+	copy(buf, sniBloomMagic)
+	buf[4], buf[5] = sniBloomVersion, f.hashes
+	binary.BigEndian.PutUint64(buf[8:], f.m)
+	binary.BigEndian.PutUint64(buf[16:], uint64(meta.Items))
+	binary.BigEndian.PutUint64(buf[24:], uint64(meta.RegistryUpdateTime))
+	binary.BigEndian.PutUint64(buf[32:], uint64(meta.Generation))
+
+	return append(buf, f.bits...)
+}
+
+// ReadSNIBloom - filter and metadata of the artifact.
+func ReadSNIBloom(r io.Reader) (*BloomFilter, *SNIBloomMeta, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(data) < sniBloomHeaderLen || string(data[:4]) != sniBloomMagic || data[4] != sniBloomVersion || data[5] == 0 {
+		return nil, nil, ErrBadBloom
+	}
+
+	f := &BloomFilter{m: binary.BigEndian.Uint64(data[8:]), hashes: data[5], bits: data[sniBloomHeaderLen:]}
+	if f.m == 0 || uint64(len(f.bits)) != (f.m+7)/8 {
+		return nil, nil, ErrBadBloom
+	}
+
+	sum := sha256.Sum256(data)
+	meta := &SNIBloomMeta{
+		Version:            sniBloomVersion,
+		Items:              int(binary.BigEndian.Uint64(data[16:])),
+		RegistryUpdateTime: int64(binary.BigEndian.Uint64(data[24:])),
+		Generation:         int64(binary.BigEndian.Uint64(data[32:])),
+		Bits:               f.m,
+		Hashes:             int(f.hashes),
+		Size:               len(data),
+		SHA256:             hex.EncodeToString(sum[:]),
+	}
+
+	return f, meta, nil
+}
+
+// WriteSNIBloom - write sni.bloom of the SNI names of records passing the filter and sni.bloom.json to the dir.
+func WriteSNIBloom(dir string, dump *Dump, filter *ExportFilter, fp float64) (*SNIBloomMeta, error) {
+	dump.RLock()
+	names := dump.SNIList(filter)
+	prov := dump.provenance()
+	meta := &SNIBloomMeta{
+		Version:            sniBloomVersion,
+		Generation:         prov.Generation,
+		RegistryUpdateTime: prov.RegistryUpdateTime,
+		Items:              len(names),
+		FPRate:             fp,
+		DumpID:             prov.DumpID,
+		CRC:                prov.CRC,
+		ToolVersion:        prov.Version,
+	}
+	dump.RUnlock()
+
+	// the list is never changed, so it can be read without the lock.
+	f := NewBloomFilter(len(names), fp)
+	for _, name := range names {
+		f.Add(name)
+	}
+
+	data := marshalSNIBloom(f, meta)
+	sum := sha256.Sum256(data)
+
+	meta.Bits, meta.Hashes, meta.Size, meta.SHA256 = f.m, int(f.hashes), len(data), hex.EncodeToString(sum[:])
+
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	// the metadata follows the filter, a reader never sees a newer one with the old filter.
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{sniBloomFilename, data},
+		{sniBloomMetaFilename, metaData},
+	} {
+		filename := dir + "/" + file.name
+		if err := os.WriteFile(filename+"-tmp", file.data, 0644); err != nil {
+			return nil, fmt.Errorf("write: %w", err)
+		}
+
+		if err := os.Rename(filename+"-tmp", filename); err != nil {
+			return nil, fmt.Errorf("rename: %w", err)
+		}
+	}
+
+	return meta, nil
+}
+
+// ExportSNIBloom - write the SNI bloom filter of the new generation, if enabled, the names added
+// and true if it is written. Only records passing ExportFilterConfig are added.
+func ExportSNIBloom(dir string) (int, bool) {
+	if SNIBloomFP <= 0 {
+		return 0, false
+	}
+
+	meta, err := WriteSNIBloom(dir, CurrentDump, ExportFilterConfig, SNIBloomFP)
+	if err != nil {
+		logger.Error.Printf("Can't save %s: %s\n", sniBloomFilename, err.Error())
+
+		return 0, false
+	}
+
+	logger.Info.Printf("SNI bloom filter: %d names, %d bytes\n", meta.Items, meta.Size)
+
+	return meta.Items, true
+}
+
+// sniBloomHandler - /sni.bloom with the version in headers and the hash as ETag, /sni.bloom.json.
+func sniBloomHandler(dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if SNIBloomFP <= 0 {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if r.URL.Path == "/"+sniBloomMetaFilename {
+			w.Header().Set("Content-Type", "application/json")
+			http.ServeFile(w, r, dir+"/"+sniBloomMetaFilename)
+
+			return
+		}
+
+		// the filter and its version are read together, the file is replaced by rename.
+		data, err := os.ReadFile(dir + "/" + sniBloomFilename)
+		if err != nil {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, meta, err := ReadSNIBloom(bytes.NewReader(data))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("ETag", strconv.Quote(meta.SHA256))
+		w.Header().Set("X-Generation", strconv.FormatInt(meta.Generation, 10))
+		w.Header().Set("X-Registry-Update-Time", strconv.FormatInt(meta.RegistryUpdateTime, 10))
+
+		http.ServeContent(w, r, sniBloomFilename, time.Unix(meta.RegistryUpdateTime, 0), bytes.NewReader(data))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestBloomFilter tests no false negatives and the false positive rate.
+func TestBloomFilter(t *testing.T) {
+	const n = 10000
+
+	f := NewBloomFilter(n, 0.01)
+	for i := 0; i < n; i++ {
+		f.Add(fmt.Sprintf("www.e%d.tld", i))
+	}
+
+	positives := 0
+
+	for i := 0; i < n; i++ {
+		if !f.Test(fmt.Sprintf("www.e%d.tld", i)) {
+			t.Fatalf("False negative: %d\n", i)
+		}
+
+		if f.Test(fmt.Sprintf("www.x%d.tld", i)) {
+			positives++
+		}
+	}
+
+	if positives > n*3/100 {
+		t.Errorf("False positives: %d of %d\n", positives, n)
+	}
+}
+
+// TestSNIBloomArtifact tests the exported filter, its metadata and conditional downloads.
+func TestSNIBloomArtifact(t *testing.T) {
+	defer func(dump *Dump, fp float64) { CurrentDump, SNIBloomFP = dump, fp }(CurrentDump, SNIBloomFP)
+
+	CurrentDump = NewDump()
+	SNIBloomFP = 0.001
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	ExportSNIBloom(dir)
+
+	data, err := os.ReadFile(dir + "/" + sniBloomFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, meta, err := ReadSNIBloom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	CurrentDump.RLock()
+	names := CurrentDump.SNIList(nil)
+	generation, utime := CurrentDump.generation, CurrentDump.utime
+	CurrentDump.RUnlock()
+
+	if len(names) == 0 || meta.Items != len(names) || meta.Generation != generation || meta.RegistryUpdateTime != utime {
+		t.Fatalf("Meta: %+v %v\n", meta, names)
+	}
+
+	for _, name := range names {
+		if !f.Test(name) {
+			t.Errorf("Not in filter: %s\n", name)
+		}
+	}
+
+	if f.Test("www.e404.tld") {
+		t.Errorf("Not blocked name is in the filter\n")
+	}
+
+	// the json has the same version.
+	written := SNIBloomMeta{}
+
+	jsonData, err := os.ReadFile(dir + "/" + sniBloomMetaFilename)
+	if err != nil || json.Unmarshal(jsonData, &written) != nil || written.SHA256 != meta.SHA256 || written.FPRate != SNIBloomFP {
+		t.Errorf("Json: %s %v\n", jsonData, err)
+	}
+
+	handler := sniBloomHandler(dir)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/"+sniBloomFilename, nil))
+
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) || w.Header().Get("X-Generation") != fmt.Sprint(generation) {
+		t.Fatalf("Download: %d %v\n", w.Code, w.Header())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/"+sniBloomFilename, nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Conditional download: %d\n", w.Code)
+	}
+
+	if _, _, err := ReadSNIBloom(bytes.NewReader(data[:len(data)-1])); err != ErrBadBloom {
+		t.Errorf("Cut filter: %v\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// cacheEpoch - cached responses are valid for one generation, registry update time, exclusion list and tags.
+type cacheEpoch struct {
+	generation int64
+	utime      int64
+	exclusions int64
+	tags       int64
+}
+
+type cacheEntry struct {
+	key  string
+	resp *pb.SearchResponse
+}
+
+// QueryCache - LRU of recent search responses.
+type QueryCache struct {
+	sync.Mutex
+	size  int
+	epoch cacheEpoch
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// NewQueryCache - QueryCache constructor, size 0 disables the cache.
+func NewQueryCache(size int) *QueryCache {
+	return &QueryCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// SearchCache - cache of search RPCs, it is set once at startup.
+var SearchCache = NewQueryCache(0)
+
+// cacheKey - RPC, selector and field mask.
+func cacheKey(rpc string, selector any, mask contentMask) string {
+	return fmt.Sprintf("%s\x00%v\x00%d", rpc, selector, mask)
+}
+
+// Get - cached response of the epoch.
+func (c *QueryCache) Get(key string, epoch cacheEpoch) (*pb.SearchResponse, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if epoch != c.epoch {
+		c.reset(epoch)
+
+		return nil, false
+	}
+
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+
+		return e.Value.(*cacheEntry).resp, true
+	}
+
+	return nil, false
+}
+
+// Put - remember the response of the epoch.
+func (c *QueryCache) Put(key string, epoch cacheEpoch, resp *pb.SearchResponse) {
+	c.Lock()
+	defer c.Unlock()
+
+	if epoch != c.epoch {
+		c.reset(epoch)
+	}
+
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*cacheEntry).resp = resp
+
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, resp: resp})
+
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*cacheEntry).key)
+	}
+}
+
+// Purge - drop all responses, e.g. after the index repair.
+func (c *QueryCache) Purge() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.reset(cacheEpoch{})
+}
+
+func (c *QueryCache) reset(epoch cacheEpoch) {
+	c.epoch = epoch
+	c.ll.Init()
+	c.items = make(map[string]*list.Element, c.size)
+}
+
+// cachedSearch - serve the search from the cache or run it, call it under read lock.
+// Responses are shared between callers and must not be modified.
+func cachedSearch(key string, search func() *pb.SearchResponse) *pb.SearchResponse {
+	if SearchCache.size <= 0 {
+		return search()
+	}
+
+	epoch := cacheEpoch{generation: CurrentDump.generation, utime: CurrentDump.utime, exclusions: CurrentExclusions().Version(), tags: RecordTags.Version()}
+
+	if resp, ok := SearchCache.Get(key, epoch); ok {
+		metricCacheHits.Add(1)
+
+		return resp
+	}
+
+	metricCacheMisses.Add(1)
+
+	resp := search()
+	SearchCache.Put(key, epoch, resp)
+
+	return resp
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestQueryCache tests LRU eviction and epoch invalidation of QueryCache.
+func TestQueryCache(t *testing.T) {
+	c := NewQueryCache(2)
+	epoch := cacheEpoch{generation: 1, utime: 100}
+
+	c.Put("a", epoch, &pb.SearchResponse{RegistryUpdateTime: 1})
+	c.Put("b", epoch, &pb.SearchResponse{RegistryUpdateTime: 2})
+
+	if _, ok := c.Get("a", epoch); !ok {
+		t.Errorf("expected a cached")
+	}
+
+	c.Put("c", epoch, &pb.SearchResponse{RegistryUpdateTime: 3})
+
+	if _, ok := c.Get("b", epoch); ok {
+		t.Errorf("expected b evicted")
+	}
+
+	if resp, ok := c.Get("a", epoch); !ok || resp.RegistryUpdateTime != 1 {
+		t.Errorf("expected a kept, got %v", resp)
+	}
+
+	if _, ok := c.Get("a", cacheEpoch{generation: 2, utime: 100}); ok {
+		t.Errorf("expected a invalidated by the new generation")
+	}
+
+	if _, ok := c.Get("c", epoch); ok {
+		t.Errorf("expected c dropped with the old epoch")
+	}
+}
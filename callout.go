@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Proxy callout: a proxy asks /check if the request URL is blocked before it is served,
+// e.g. nginx auth_request. 204 allows it, 403 denies it with the matched records.
+const (
+	CalloutURLHeader = "X-Original-URL" // the URL to check, the url query parameter works too.
+	CalloutIDsHeader = "X-Registry-IDs" // comma separated ids of the records denying the URL.
+)
+
+// Callout actions.
+const (
+	CalloutAllow = "allow"
+	CalloutDeny  = "deny"
+)
+
+// CalloutMatch - record denying the URL.
+type CalloutMatch struct {
+	ID        int32    `json:"id"`
+	Kind      string   `json:"kind"`     // match kind, see Match.
+	Selector  string   `json:"selector"` // the matched selector of the record.
+	BlockType int32    `json:"blockType"`
+	Decision  Decision `json:"decision"`
+}
+
+// CalloutVerdict - answer of the callout.
+type CalloutVerdict struct {
+	Action   string         `json:"action"`
+	URL      string         `json:"url"`                // normalized.
+	Excluded string         `json:"excluded,omitempty"` // local exclusion rule allowing the URL.
+	Matches  []CalloutMatch `json:"matches,omitempty"`
+}
+
+// CheckURL - is the request URL blocked, call it under read lock. A URL without a path, e.g. a CONNECT
+// target, is denied by HTTPS records of its host too, their URLs are enforced by the server name.
+// ok is false for a URL without a host.
+func (dump *Dump) CheckURL(raw string) (*CalloutVerdict, bool) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	nURL := NormalizeURL(raw)
+
+	parsed, err := url.Parse(nURL)
+	if err != nil || parsed.Hostname() == "" {
+		return nil, false
+	}
+
+	verdict := &CalloutVerdict{Action: CalloutAllow, URL: nURL}
+
+	if rule := CurrentExclusions().URL(nURL); rule != "" {
+		verdict.Excluded = rule
+
+		return verdict, true
+	}
+
+	seen := make(map[int32]Nothing)
+
+	add := func(ids ArrayIntSet, m Match, blocks func(*PackedContent) bool) {
+		for _, id := range ids {
+			pack, ok := dump.ContentIdx[id]
+			if _, dup := seen[id]; dup || !ok || !blocks(pack) {
+				continue
+			}
+
+			seen[id] = Nothing{}
+
+			match := CalloutMatch{ID: id, Kind: m.Kind, Selector: m.selector(), BlockType: pack.BlockType}
+			if record, err := pack.Record(); err == nil {
+				match.Decision = record.Decision
+			}
+
+			verdict.Matches = append(verdict.Matches, match)
+		}
+	}
+
+	blockType := func(types ...int32) func(*PackedContent) bool {
+		return func(pack *PackedContent) bool {
+			for _, t := range types {
+				if pack.BlockType == t {
+					return true
+				}
+			}
+
+			return false
+		}
+	}
+
+	add(dump.urlIdx[nURL], Match{Kind: SelectorURL, URL: nURL}, func(*PackedContent) bool { return true })
+
+	host := parsed.Hostname()
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		ip = ip.Unmap()
+
+		kind, subnetKind := SelectorIP4, SelectorSubnet4
+		if ip.Is6() {
+			kind, subnetKind = SelectorIP6, SelectorSubnet6
+		}
+
+		add(dump.ipIDs(ip), Match{Kind: kind, IP: ip}, blockType(BlockTypeIP))
+
+		for _, subnet := range dump.containingSubnets(ip, -1) {
+			add(dump.subnetIDs(subnet), Match{Kind: subnetKind, Subnet: subnet}, blockType(BlockTypeIP))
+		}
+	} else {
+		domain := NormalizeDomain(host)
+
+		add(dump.domainIdx[domain], Match{Kind: SelectorDomain, Domain: domain}, blockType(BlockTypeDomain, BlockTypeMask))
+
+		if parsed.Path == "" || parsed.Path == "/" {
+			add(dump.domainIdx[domain], Match{Kind: SelectorDomain, Domain: domain}, (*PackedContent).SNIOnly)
+		}
+
+		for _, parent := range parentDomains(domain) {
+			add(dump.domainIdx[parent], Match{Kind: MatchDomainMask, Domain: parent}, blockType(BlockTypeMask))
+		}
+	}
+
+	if len(verdict.Matches) > 0 {
+		verdict.Action = CalloutDeny
+
+		sort.Slice(verdict.Matches, func(i, j int) bool { return verdict.Matches[i].ID < verdict.Matches[j].ID })
+	}
+
+	return verdict, true
+}
+
+// handleCallout - /check: 204 if the URL is allowed, 403 with the verdict if it is denied.
+func handleCallout(w http.ResponseWriter, r *http.Request) {
+	raw := r.Header.Get(CalloutURLHeader)
+	if raw == "" {
+		raw = r.URL.Query().Get("url")
+	}
+
+	if raw == "" {
+		http.Error(w, SrvBadSelector, http.StatusBadRequest)
+
+		return
+	}
+
+	CurrentDump.RLock()
+
+	if CurrentDump.utime == 0 {
+		CurrentDump.RUnlock()
+		http.Error(w, SrvDataNotReady, http.StatusServiceUnavailable)
+
+		return
+	}
+
+	verdict, ok := CurrentDump.CheckURL(raw)
+
+	CurrentDump.RUnlock()
+
+	if !ok {
+		http.Error(w, SrvBadSelector, http.StatusBadRequest)
+
+		return
+	}
+
+	if verdict.Action == CalloutAllow {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	ids := make([]string, 0, len(verdict.Matches))
+	for _, m := range verdict.Matches {
+		ids = append(ids, strconv.Itoa(int(m.ID)))
+	}
+
+	w.Header().Set(CalloutIDsHeader, strings.Join(ids, ","))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+
+	json.NewEncoder(w).Encode(verdict)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestCallout tests allow and deny verdicts of the proxy callout.
+func TestCallout(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	dump := strings.Replace(dnsblDump, "</reg:register>", `<content id="5" includeTime="2001-01-01T01:01:01" entryType="1" hash="5">
+        <decision date="2000-01-01" number="5" org="ONE"/>
+        <url><![CDATA[https://s.tld/p]]></url>
+        <domain><![CDATA[s.tld]]></domain>
+</content>
+</reg:register>`, 1)
+
+	if err := Parse(strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+
+	for raw, want := range map[string]string{
+		"http://u.tld/x":      "4",
+		"http://u.tld/y":      "",
+		"http://A.tld/any":    "2",
+		"http://x.m.tld/":     "3",
+		"http://10.1.2.3/":    "1",
+		"http://10.0.0.2/":    "",
+		"s.tld:443":           "5",
+		"https://s.tld/other": "",
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/check", nil)
+		r.Header.Set(CalloutURLHeader, raw)
+
+		handleCallout(w, r)
+
+		if want == "" {
+			if w.Code != http.StatusNoContent {
+				t.Errorf("%s: %d, want allow\n", raw, w.Code)
+			}
+
+			continue
+		}
+
+		var verdict CalloutVerdict
+
+		if err := json.Unmarshal(w.Body.Bytes(), &verdict); err != nil || w.Code != http.StatusForbidden ||
+			w.Header().Get(CalloutIDsHeader) != want || verdict.Action != CalloutDeny || verdict.Matches[0].Decision.Number != want {
+			t.Errorf("%s: %d %s %s, want %s\n", raw, w.Code, w.Header().Get(CalloutIDsHeader), w.Body.String(), want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handleCallout(w, httptest.NewRequest("GET", "/check?url="+url.QueryEscape("http://a.tld/"), nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Query parameter: %d\n", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handleCallout(w, httptest.NewRequest("GET", "/check", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("No URL: %d\n", w.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// changelogSize - number of generations kept in the changelog.
+const changelogSize = 100
+
+// ChangelogEntry - summary of one applied parse.
+type ChangelogEntry struct {
+	Generation  int64
+	UpdateTime  int64
+	Parsed      time.Time
+	Count       int
+	Added       int
+	Updated     int
+	Removed     int
+	Annotations []string // anomalies and other notes on the generation.
+	Transitions []BlockTypeTransition
+}
+
+// ChangelogRing - last generations, oldest first.
+type ChangelogRing struct {
+	sync.RWMutex
+	entries []ChangelogEntry
+}
+
+// Changelog - changelog of the running service.
+var Changelog = &ChangelogRing{}
+
+// Append - add the entry, drop the oldest ones.
+func (c *ChangelogRing) Append(entry ChangelogEntry) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.entries = append(c.entries, entry)
+	if len(c.entries) > changelogSize {
+		c.entries = append(c.entries[:0:0], c.entries[len(c.entries)-changelogSize:]...)
+	}
+}
+
+// Since - entries newer than the generation, at most limit latest ones.
+func (c *ChangelogRing) Since(generation int64, limit int) []ChangelogEntry {
+	c.RLock()
+	defer c.RUnlock()
+
+	var entries []ChangelogEntry
+
+	for _, entry := range c.entries {
+		if entry.Generation > generation {
+			entries = append(entries, entry)
+		}
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	return entries
+}
+
+// newChangelogEntry - entry of the current generation from parse statistics.
+func newChangelogEntry(stats ParseStatistics) ChangelogEntry {
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	return ChangelogEntry{
+		Generation:  CurrentDump.generation,
+		UpdateTime:  CurrentDump.utime,
+		Parsed:      stats.Updated,
+		Count:       stats.Count,
+		Added:       stats.AddCount,
+		Updated:     stats.UpdateCount,
+		Removed:     stats.RemoveCount,
+		Transitions: stats.Transitions,
+	}
+}
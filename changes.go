@@ -0,0 +1,101 @@
+package main
+
+import "sync/atomic"
+
+// WatchKeep - generations of record changes retained for Watch subscribers to catch up, 0 disables.
+// It is set once at startup.
+var WatchKeep = 100
+
+// GenerationChanges - records changed by one generation, never changed after it is published.
+type GenerationChanges struct {
+	Generation int64
+	UpdateTime int64
+	Added      []int32
+	Updated    []int32
+	Removed    []int32
+	Urgent     []int32 // urgent records of Added and Updated.
+
+	Transitions []BlockTypeTransition // records of Updated whose block type changed, ordered as Updated.
+
+	SelectorsAdded   []Selector // normalized selectors no record had before the generation.
+	SelectorsRemoved []Selector // normalized selectors no record has since the generation.
+}
+
+// changeBuffer - changes of the last WatchKeep generations, oldest first. The list is copied
+// on write, so readers take it without locks.
+type changeBuffer struct {
+	entries atomic.Value // []*GenerationChanges
+}
+
+// load - the retained changes, they are shared, don't change them.
+func (b *changeBuffer) load() []*GenerationChanges {
+	entries, _ := b.entries.Load().([]*GenerationChanges)
+
+	return entries
+}
+
+// push - retain the changes of the new generation, call it under the dump write lock.
+func (b *changeBuffer) push(changes *GenerationChanges, keep int) {
+	if keep <= 0 {
+		return
+	}
+
+	prev := b.load()
+
+	start := len(prev) + 1 - keep
+	if start < 0 {
+		start = 0
+	}
+
+	next := make([]*GenerationChanges, 0, len(prev)-start+1)
+	next = append(next, prev[start:]...)
+	next = append(next, changes)
+
+	b.entries.Store(next)
+}
+
+// reset - drop the retained changes, the next generation is not caught up from them.
+func (b *changeBuffer) reset() {
+	b.entries.Store([]*GenerationChanges(nil))
+}
+
+// ChangesSince - changes of the generations after the given one up to the current one, oldest first.
+// ok is false if some of them are not retained, the subscriber has to resync all records.
+func (d *Dump) ChangesSince(generation int64) ([]*GenerationChanges, bool) {
+	d.RLock()
+	current := d.generation
+	entries := d.changes.load()
+	d.RUnlock()
+
+	if generation == current {
+		return nil, true
+	}
+
+	// retained generations are consecutive.
+	for i, c := range entries {
+		if c.Generation == generation+1 {
+			return entries[i:], entries[len(entries)-1].Generation == current
+		}
+	}
+
+	return nil, false
+}
+
+// newGenerationChanges - changes of the generation of the Commit results, call it under lock.
+func (dump *Dump) newGenerationChanges(generation, utime int64, added, updated []int32, removed []*PackedContent) *GenerationChanges {
+	changes := &GenerationChanges{
+		Generation: generation,
+		UpdateTime: utime,
+		Added:      append([]int32(nil), added...),
+		Updated:    updated,
+		Removed:    make([]int32, 0, len(removed)),
+	}
+
+	for _, pack := range removed {
+		changes.Removed = append(changes.Removed, pack.ID)
+	}
+
+	changes.Urgent = dump.urgentIDs(added, updated)
+
+	return changes
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestChangesSince tests record changes retained per generation for subscribers to catch up.
+func TestChangesSince(t *testing.T) {
+	defer func(dump *Dump, keep int) { CurrentDump, WatchKeep = dump, keep }(CurrentDump, WatchKeep)
+
+	CurrentDump, WatchKeep = NewDump(), 2
+
+	// drop 555, add 666 and change 111.
+	xml := strings.Replace(xml01, `<content id="555"`, `<content id="666"`, 1)
+	xml = strings.Replace(xml, "www.e01.tld", "www.e01-changed.tld", 1)
+
+	for _, dump := range []string{xml01, xml, xml, xml01} {
+		if err := Parse(strings.NewReader(dump)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := CurrentDump.ChangesSince(0); ok {
+		t.Errorf("the initial load must not be caught up from")
+	}
+
+	if changes, ok := CurrentDump.ChangesSince(4); !ok || len(changes) != 0 {
+		t.Errorf("Current: %v %t\n", changes, ok)
+	}
+
+	changes, ok := CurrentDump.ChangesSince(2)
+	if !ok || len(changes) != 2 || changes[0].Generation != 3 || changes[1].Generation != 4 {
+		t.Fatalf("Changes: %v %t\n", changes, ok)
+	}
+
+	if len(changes[0].Added)+len(changes[0].Updated)+len(changes[0].Removed) != 0 {
+		t.Errorf("Same dump: %+v\n", changes[0])
+	}
+
+	if c := changes[1]; !reflect.DeepEqual(c.Added, []int32{555}) || !reflect.DeepEqual(c.Updated, []int32{111}) || !reflect.DeepEqual(c.Removed, []int32{666}) {
+		t.Errorf("Generation 4: %+v\n", c)
+	}
+
+	// generation 2 is not retained.
+	if _, ok := CurrentDump.ChangesSince(1); ok {
+		t.Errorf("Dropped generation is caught up from\n")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+const charsetDump = `<?xml version="1.0" encoding="%s"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="1">
+        <decision date="2000-01-01" number="1" org="%s"/>
+        <domain><![CDATA[www.e1.tld]]></domain>
+</content>
+</reg:register>`
+
+// TestParseCharset tests charset modes on dumps with matched and mismatched declarations.
+func TestParseCharset(t *testing.T) {
+	defer func(dump *Dump, mode string) { CurrentDump, ParseConfig.Charset = dump, mode }(CurrentDump, ParseConfig.Charset)
+
+	const org = "ИФНС «Москва»"
+
+	cp1251, err := charmap.Windows1251.NewEncoder().String(org)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dump := func(encoding, org string) string {
+		return strings.Replace(strings.Replace(charsetDump, "%s", encoding, 1), "%s", org, 1)
+	}
+
+	for _, tc := range []struct {
+		name, mode, dump string
+		ok               bool // parsed and the organization is right.
+		replacements     int
+		err              error
+	}{
+		{"cp1251", parser.CharsetDeclared, dump("windows-1251", cp1251), true, 0, nil},
+		{"utf-8", parser.CharsetDeclared, dump("utf-8", org), true, 0, nil},
+		{"utf-8 as cp1251", parser.CharsetDeclared, dump("windows-1251", org), false, 1, nil},
+		{"utf-8 as cp1251 strict", parser.CharsetStrict, dump("windows-1251", org), false, 0, parser.ErrBadCharset},
+		{"utf-8 as cp1251 lenient", parser.CharsetLenient, dump("windows-1251", org), true, 0, nil},
+		{"cp1251 as utf-8 lenient", parser.CharsetLenient, dump("utf-8", cp1251), true, 0, nil},
+		{"cp1251 lenient", parser.CharsetLenient, dump("windows-1251", cp1251), true, 0, nil},
+	} {
+		ParseConfig.Charset = tc.mode
+		CurrentDump = NewDump()
+
+		err := Parse(strings.NewReader(tc.dump))
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) || len(CurrentDump.ContentIdx) != 0 {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+
+			continue
+		}
+
+		if Stats.ReplacementRecords != tc.replacements {
+			t.Errorf("%s: replacement records: %d", tc.name, Stats.ReplacementRecords)
+		}
+
+		pack, ok := CurrentDump.ContentIdx[1]
+		if !ok {
+			t.Errorf("%s: no record", tc.name)
+
+			continue
+		}
+
+		record := Content{}
+		if err := json.Unmarshal(pack.PayloadBytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+
+		if (record.Decision.Org == org) != tc.ok {
+			t.Errorf("%s: org %q", tc.name, record.Decision.Org)
+		}
+	}
+}
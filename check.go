@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// Verdicts of the check report.
+const (
+	VerdictBlocked = "blocked" // some record has the selector.
+	VerdictClean   = "clean"
+	VerdictInvalid = "invalid" // the line is not a selector.
+	VerdictError   = "error"   // the search failed.
+)
+
+// checkReportColumns - CSV header of the check report.
+var checkReportColumns = []string{"selector", "kind", "verdict", "records", "ids", "matched", "error"}
+
+// CheckVerdict - verdict of one selector of the check file.
+type CheckVerdict struct {
+	Selector string
+	Kind     string
+	Verdict  string
+	IDs      []int32
+	Matched  []string // distinct "kind selector" the records are matched by.
+	Err      string
+}
+
+// checkSearch - search of one selector, in-process or over gRPC.
+type checkSearch func(ctx context.Context, q selectorQuery) (*pb.SearchResponse, error)
+
+// guessSelectorKind - kind of the selector: url with a scheme, ip, subnet or domain, empty if it is none.
+func guessSelectorKind(s string) string {
+	switch {
+	case strings.Contains(s, "://"):
+		return "url"
+	case strings.Contains(s, "/"):
+		if _, err := netip.ParsePrefix(s); err == nil {
+			return "subnet"
+		}
+	default:
+		if _, err := netip.ParseAddr(s); err == nil {
+			return "ip"
+		}
+
+		if isDomainName(strings.TrimSuffix(s, ".")) {
+			return "domain"
+		}
+	}
+
+	return ""
+}
+
+// CheckSelector - verdict of the selector, search errors are the verdict too.
+func CheckSelector(ctx context.Context, search checkSearch, selector string) CheckVerdict {
+	v := CheckVerdict{Selector: selector, Kind: guessSelectorKind(selector)}
+
+	if v.Kind == "" {
+		v.Verdict = VerdictInvalid
+
+		return v
+	}
+
+	q, err := newSelectorQuery(v.Kind, selector)
+	if err != nil {
+		v.Verdict, v.Err = VerdictInvalid, err.Error()
+
+		return v
+	}
+
+	resp, err := search(ctx, q)
+	if err == nil && resp.GetError() != "" {
+		err = fmt.Errorf("%s", resp.GetError())
+	}
+
+	if err != nil {
+		v.Verdict, v.Err = VerdictError, err.Error()
+
+		return v
+	}
+
+	v.Verdict = VerdictClean
+
+	seen := make(StringMap)
+
+	for _, r := range resp.GetResults() {
+		v.Verdict = VerdictBlocked
+		v.IDs = append(v.IDs, r.GetId())
+
+		if m := r.GetMatchedBy(); m.GetSelector() != "" {
+			matched := m.GetKind() + " " + m.GetSelector()
+			if _, ok := seen[matched]; !ok {
+				seen[matched] = Nothing{}
+				v.Matched = append(v.Matched, matched)
+			}
+		}
+	}
+
+	return v
+}
+
+// row - CSV row of the verdict.
+func (v CheckVerdict) row() []string {
+	ids := make([]string, 0, len(v.IDs))
+	for _, id := range v.IDs {
+		ids = append(ids, strconv.Itoa(int(id)))
+	}
+
+	return []string{v.Selector, v.Kind, v.Verdict, strconv.Itoa(len(v.IDs)), strings.Join(ids, " "), strings.Join(v.Matched, "; "), v.Err}
+}
+
+// RunCheck - verdicts of the selectors of r, one per line, blank lines and # comments are skipped,
+// written to w as CSV. Returns the number of failed searches.
+func RunCheck(r io.Reader, w io.Writer, search checkSearch, newContext func() (context.Context, context.CancelFunc)) (int, error) {
+	cw := csv.NewWriter(w)
+	cw.Write(checkReportColumns)
+
+	failed := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ctx, cancel := newContext()
+		v := CheckSelector(ctx, search, line)
+		cancel()
+
+		if v.Verdict == VerdictError {
+			failed++
+		}
+
+		cw.Write(v.row())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return failed, fmt.Errorf("read: %w", err)
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		return failed, fmt.Errorf("write: %w", err)
+	}
+
+	return failed, nil
+}
+
+// checkCommand - CSV verdicts of a file of selectors, "-" is stdin, by the running instance
+// or in-process by a snapshot file: check [flags] <file>.
+func checkCommand(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	cf := clientFlags{
+		addr:    fs.String("addr", "localhost:50001", "gRPC address of the running instance"),
+		timeout: fs.Duration("timeout", 10*time.Second, "Timeout of every search"),
+	}
+	snapshot := fs.String("snapshot", "", "Check by the snapshot file in-process instead of the running instance")
+	output := fs.String("o", "", "Write the CSV report to the file instead of stdout")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s check [flags] <selectors file>\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	in := os.Stdin
+
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't open selectors file: %s\n", err.Error())
+
+			return 1
+		}
+
+		defer f.Close()
+
+		in = f
+	}
+
+	var search checkSearch
+
+	if *snapshot != "" {
+		snap, err := ReadSnapshot(*snapshot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read snapshot: %s\n", err.Error())
+
+			return 1
+		}
+
+		if err := CurrentDump.RestoreSnapshot(snap); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't restore snapshot: %s\n", err.Error())
+
+			return 1
+		}
+
+		s := &server{}
+		search = func(ctx context.Context, q selectorQuery) (*pb.SearchResponse, error) { return q.local(ctx, s) }
+	} else {
+		client, conn, _, cancel, err := cf.dial()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+
+			return 1
+		}
+
+		defer conn.Close()
+		defer cancel()
+
+		search = func(ctx context.Context, q selectorQuery) (*pb.SearchResponse, error) { return q.peer(ctx, client) }
+	}
+
+	out := os.Stdout
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't create report: %s\n", err.Error())
+
+			return 1
+		}
+
+		defer f.Close()
+
+		out = f
+	}
+
+	// every search has its own timeout, the file may have thousands of selectors.
+	newContext := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(context.Background(), *cf.timeout)
+	}
+
+	failed, err := RunCheck(in, out, search, newContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Check failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Failed searches: %d\n", failed)
+
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestRunCheck tests verdicts of a mixed selectors file by the in-process index.
+func TestRunCheck(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+	search := func(ctx context.Context, q selectorQuery) (*pb.SearchResponse, error) { return q.local(ctx, s) }
+	newContext := func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) }
+
+	selectors := "# audit\n\nWWW.E02.TLD\nhttp://www.e01.tld/cheese\n192.168.0.100\n10.4.1.0/24\n203.0.113.1\nnot a selector\n"
+
+	var out bytes.Buffer
+
+	failed, err := RunCheck(strings.NewReader(selectors), &out, search, newContext)
+	if err != nil || failed != 0 {
+		t.Fatalf("RunCheck: %d failed, %v", failed, err)
+	}
+
+	expected := `selector,kind,verdict,records,ids,matched,error
+WWW.E02.TLD,domain,blocked,2,222 555,domain www.e02.tld,
+http://www.e01.tld/cheese,url,blocked,1,111,url http://www.e01.tld/cheese,
+192.168.0.100,ip,blocked,3,111 222 333,ip4 192.168.0.100,
+10.4.1.0/24,subnet,blocked,1,444,,
+203.0.113.1,ip,clean,0,,,
+not a selector,,invalid,0,,,
+`
+
+	if out.String() != expected {
+		t.Errorf("Report:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+
+	CurrentDump = NewDump()
+
+	out.Reset()
+
+	if failed, _ := RunCheck(strings.NewReader("www.e02.tld\n"), &out, search, newContext); failed != 1 ||
+		!strings.Contains(out.String(), SrvDataNotReady) {
+		t.Errorf("Not ready: %d failed, %s", failed, out.String())
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// parseCheckpointFilename - checkpoint of the parse in progress, it is removed when the parse ends.
+const parseCheckpointFilename = "parse.checkpoint"
+
+// ParseCheckpointEvery - records between checkpoints of the parse stage, 0 disables them.
+// It is set once at startup.
+var ParseCheckpointEvery int
+
+// errUnknownOffset - input offsets of multibyte charsets are unknown, the parse can't be resumed.
+var errUnknownOffset = errors.New("unknown input offset")
+
+// checkpointHeader - the first line of the checkpoint: the dump and the index it is valid for.
+type checkpointHeader struct {
+	DumpID     string `json:"dumpId"`
+	Size       int64  `json:"size"`
+	ModTime    int64  `json:"modTime"`    // unix nanoseconds of dump.xml.
+	UpdateTime int64  `json:"updateTime"` // of the live index the stage is compared with.
+	Records    int    `json:"records"`
+	Config     string `json:"config"` // ParseConfig, it changes what is staged.
+	HeaderEnd  int64  `json:"headerEnd"`
+}
+
+// checkpointRecord - staged record.
+type checkpointRecord struct {
+	ID        int32     `json:"id"`
+	Payload   []byte    `json:"payload"`
+	Raw       []byte    `json:"raw,omitempty"`
+	Same      bool      `json:"same,omitempty"`
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+}
+
+// checkpointSegment - a line of the checkpoint: records processed since the previous one.
+type checkpointSegment struct {
+	Journal []int32            `json:"journal"`
+	Records []checkpointRecord `json:"records"` // staged or restaged since the previous segment.
+	Offset  int64              `json:"offset"`  // of dump.xml after the last processed <content>.
+	Stats   ParseStatistics    `json:"stats"`
+}
+
+// parseCheckpoint - append only checkpoint of the parse stage. Nothing is applied to the index
+// before the whole dump is read, so the stage and the position in the dump are all it takes
+// to resume an interrupted parse. nil is a parse without checkpoints.
+type parseCheckpoint struct {
+	filename string
+	file     *os.File // nil if checkpoints are off.
+	dump     *os.File
+	header   checkpointHeader
+	written  bool                // the header is written.
+	restored []checkpointSegment // to resume from, see resume.
+	shift    int64               // dump.xml offset minus the parsed stream offset.
+	seen     []int32             // ids processed since the last segment.
+}
+
+// newCheckpointHeader - identity of the dump and the live index.
+func newCheckpointHeader(dumpID string, info os.FileInfo) checkpointHeader {
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	return checkpointHeader{
+		DumpID:     dumpID,
+		Size:       info.Size(),
+		ModTime:    info.ModTime().UnixNano(),
+		UpdateTime: CurrentDump.utime,
+		Records:    len(CurrentDump.ContentIdx),
+		Config:     fmt.Sprintf("%+v", ParseConfig),
+	}
+}
+
+// sameDump - the checkpoint is of the same dump and index, HeaderEnd is not compared.
+func (h checkpointHeader) sameDump(other checkpointHeader) bool {
+	other.HeaderEnd = h.HeaderEnd
+
+	return h == other
+}
+
+// ParseCheckpointed - dump.xml of the dir is parsed partially and can be resumed, so it isn't fetched again.
+func ParseCheckpointed(dir, dumpID string) bool {
+	if ParseCheckpointEvery <= 0 {
+		return false
+	}
+
+	info, err := os.Stat(dir + "/dump.xml")
+	if err != nil {
+		return false
+	}
+
+	f, err := os.Open(dir + "/" + parseCheckpointFilename)
+	if err != nil {
+		return false
+	}
+
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil {
+		return false
+	}
+
+	var header checkpointHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return false
+	}
+
+	return header.sameDump(newCheckpointHeader(dumpID, info))
+}
+
+// ParseResumable - parse the dump with checkpoints, an interrupted parse of the same dump is resumed.
+func ParseResumable(dumpFile *os.File, dir, dumpID string) error {
+	ckpt, err := openParseCheckpoint(dir+"/"+parseCheckpointFilename, dumpFile, dumpID)
+	if err != nil {
+		logger.Error.Printf("Can't open parse checkpoint, checkpoints are off: %s\n", err.Error())
+
+		return Parse(dumpFile)
+	}
+
+	defer ckpt.close()
+
+	return parse(ckpt.input(), ckpt)
+}
+
+// openParseCheckpoint - read complete segments of a checkpoint of the same dump, others are dropped.
+func openParseCheckpoint(filename string, dump *os.File, dumpID string) (*parseCheckpoint, error) {
+	info, err := dump.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	c := &parseCheckpoint{
+		filename: filename,
+		file:     f,
+		dump:     dump,
+		header:   newCheckpointHeader(dumpID, info),
+	}
+
+	valid := c.load(bufio.NewReader(f))
+
+	if err := f.Truncate(valid); err != nil {
+		c.close()
+
+		return nil, fmt.Errorf("truncate: %w", err)
+	}
+
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		c.close()
+
+		return nil, fmt.Errorf("seek: %w", err)
+	}
+
+	return c, nil
+}
+
+// load - read the segments, the length of the valid part is returned, 0 if it is of another dump.
+func (c *parseCheckpoint) load(r *bufio.Reader) int64 {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return 0
+	}
+
+	var header checkpointHeader
+	if err := json.Unmarshal(line, &header); err != nil || !header.sameDump(c.header) {
+		return 0
+	}
+
+	valid := int64(len(line))
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+
+		var segment checkpointSegment
+		if err := json.Unmarshal(line, &segment); err != nil {
+			break
+		}
+
+		c.restored = append(c.restored, segment)
+		valid += int64(len(line))
+	}
+
+	c.header, c.written = header, true
+
+	if len(c.restored) > 0 {
+		c.shift = c.restored[len(c.restored)-1].Offset - header.HeaderEnd
+	}
+
+	return valid
+}
+
+// input - the dump to parse: the head of the dump up to the register start
+// and the rest after the last checkpoint, if it is resumed.
+func (c *parseCheckpoint) input() io.Reader {
+	if len(c.restored) == 0 {
+		return c.dump
+	}
+
+	offset := c.restored[len(c.restored)-1].Offset
+
+	return io.MultiReader(
+		io.NewSectionReader(c.dump, 0, c.header.HeaderEnd),
+		io.NewSectionReader(c.dump, offset, c.header.Size-offset),
+	)
+}
+
+// resume - restore the stage and stats of the checkpoint.
+func (c *parseCheckpoint) resume(s *parseStage, stats *ParseStatistics, hasher parser.Hasher) error {
+	if len(c.restored) == 0 {
+		return nil
+	}
+
+	for _, segment := range c.restored {
+		for _, id := range segment.Journal {
+			s.see(id)
+		}
+
+		for _, rec := range segment.Records {
+			content := &Content{}
+			if err := json.Unmarshal(rec.Payload, content); err != nil {
+				return fmt.Errorf("checkpoint: content %d: %w", rec.ID, err)
+			}
+
+			content.DecisionHash = hasher.Decision(&content.Decision)
+			content.Anomalies = rec.Anomalies
+
+			s.restore(rec.ID, &stagedRecord{content: content, payload: rec.Payload, raw: rec.Raw, same: rec.Same})
+		}
+	}
+
+	last := c.restored[len(c.restored)-1]
+	*stats = last.Stats
+
+	logger.Info.Printf("Parse resumed at %d of %d bytes: %d records\n", last.Offset, c.header.Size, stats.Count)
+
+	c.restored = nil
+
+	return nil
+}
+
+// registerStarted - the register start ends the head of the dump, a resumed parse reads it again.
+func (c *parseCheckpoint) registerStarted(capturer *parser.RawElementCapturer) {
+	if c == nil || c.file == nil || c.written {
+		return
+	}
+
+	offset, ok := capturer.InputOffset()
+	if !ok {
+		logger.Warning.Printf("Parse checkpoints are off: %s\n", errUnknownOffset.Error())
+		c.off()
+
+		return
+	}
+
+	c.header.HeaderEnd = offset
+}
+
+// processed - the record is staged, every ParseCheckpointEvery records a segment is appended.
+func (c *parseCheckpoint) processed(id int32, s *parseStage, stats *ParseStatistics, capturer *parser.RawElementCapturer) error {
+	if c == nil || c.file == nil {
+		return nil
+	}
+
+	c.seen = append(c.seen, id)
+	if len(c.seen) < ParseCheckpointEvery {
+		return nil
+	}
+
+	offset, ok := capturer.InputOffset()
+	if !ok {
+		c.off()
+
+		return errUnknownOffset
+	}
+
+	if err := c.append(s, stats, offset+c.shift); err != nil {
+		c.off()
+
+		return err
+	}
+
+	c.seen = c.seen[:0]
+
+	return nil
+}
+
+// append - write a segment of the records seen since the previous one and sync it.
+func (c *parseCheckpoint) append(s *parseStage, stats *ParseStatistics, offset int64) error {
+	segment := checkpointSegment{Journal: c.seen, Offset: offset, Stats: *stats}
+
+	done := make(map[int32]Nothing, len(c.seen))
+
+	// ids are staged in the order they are seen.
+	for _, id := range c.seen {
+		staged, ok := s.records[id]
+		if _, dup := done[id]; dup || !ok {
+			continue
+		}
+
+		done[id] = Nothing{}
+
+		segment.Records = append(segment.Records, checkpointRecord{
+			ID:        id,
+			Payload:   staged.payload,
+			Raw:       staged.raw,
+			Same:      staged.same,
+			Anomalies: staged.content.Anomalies,
+		})
+	}
+
+	w := bufio.NewWriter(c.file)
+	enc := json.NewEncoder(w)
+
+	if !c.written {
+		if err := enc.Encode(c.header); err != nil {
+			return fmt.Errorf("header: %w", err)
+		}
+	}
+
+	if err := enc.Encode(segment); err != nil {
+		return fmt.Errorf("segment: %w", err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+
+	c.written = true
+
+	return nil
+}
+
+// off - stop checkpointing, the parse goes on.
+func (c *parseCheckpoint) off() {
+	c.file.Close()
+	c.file = nil
+
+	os.Remove(c.filename)
+}
+
+// close - the parse is over, applied or failed, the checkpoint is useless.
+func (c *parseCheckpoint) close() {
+	if c.file != nil {
+		c.off()
+	}
+}
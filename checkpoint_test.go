@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// TestParseResume tests an interrupted parse resumed from its checkpoint.
+func TestParseResume(t *testing.T) {
+	defer func(dump *Dump, every int) {
+		CurrentDump, ParseCheckpointEvery = dump, every
+	}(CurrentDump, ParseCheckpointEvery)
+
+	// cp1251 bytes are shorter than the decoded stream.
+	dump, err := charmap.Windows1251.NewEncoder().String(strings.Replace(xml01, `org="ONE"`, `org="Суд"`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/dump.xml", []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := CurrentDump
+
+	CurrentDump, ParseCheckpointEvery = NewDump(), 2
+
+	dumpFile, err := os.Open(dir + "/dump.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dumpFile.Close()
+
+	ckpt, err := openParseCheckpoint(dir+"/"+parseCheckpointFilename, dumpFile, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the process dies in the middle of the fourth record.
+	if err := parse(io.LimitReader(ckpt.input(), int64(strings.Index(dump, `<content id="444"`)+100)), ckpt); err == nil {
+		t.Fatal("Interrupted parse is applied")
+	}
+
+	ckpt.file.Close()
+
+	if ParseCheckpointed(dir, "2") || !ParseCheckpointed(dir, "1") {
+		t.Fatal("Checkpoint of the dump is not found")
+	}
+
+	if err := ParseDumpFile(dir, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dir + "/" + parseCheckpointFilename); !os.IsNotExist(err) {
+		t.Errorf("Checkpoint is left: %v\n", err)
+	}
+
+	if Stats.Count != 5 || Stats.AddCount != 5 || len(CurrentDump.ContentIdx) != len(want.ContentIdx) {
+		t.Errorf("Resumed: %d records, %d added, %d indexed\n", Stats.Count, Stats.AddCount, len(CurrentDump.ContentIdx))
+	}
+
+	for id, pack := range want.ContentIdx {
+		got, ok := CurrentDump.ContentIdx[id]
+		if !ok || got.RecordHash != pack.RecordHash || string(got.Payload) != string(pack.Payload) {
+			t.Errorf("Resumed: content %d differs\n", id)
+		}
+	}
+
+	if record, err := CurrentDump.ContentIdx[111].Record(); err != nil || record.Decision.Org != "Суд" {
+		t.Errorf("Resumed: %v %v\n", record, err)
+	}
+}
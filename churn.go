@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// ChurnOptions - churn anomaly detection knobs.
+type ChurnOptions struct {
+	Window   int     // parses in the rolling baseline, 0 disables detection.
+	Sigma    float64 // how many standard deviations above the mean is anomalous.
+	MinCount int     // smaller counts are never anomalous.
+}
+
+// ChurnConfig - churn detection configuration, it is set once at startup.
+var ChurnConfig = ChurnOptions{Window: 20, Sigma: 4, MinCount: 100}
+
+// churnMinSamples - no verdicts until the baseline has this many parses.
+const churnMinSamples = 5
+
+const churnFilename = "churn.json"
+
+// ChurnSample - churn of one parse.
+type ChurnSample struct {
+	Added   int `json:"a"`
+	Updated int `json:"u"`
+	Removed int `json:"r"`
+	Delta   int `json:"d"` // absolute change of the record count.
+}
+
+// churnSeries - names of the sample values.
+var churnSeries = [...]string{"added", "updated", "removed", "record count change"}
+
+// values - sample values in churnSeries order.
+func (s ChurnSample) values() [len(churnSeries)]int {
+	return [...]int{s.Added, s.Updated, s.Removed, s.Delta}
+}
+
+// churnFile - persisted baseline.
+type churnFile struct {
+	Samples []ChurnSample `json:"samples"`
+}
+
+// ChurnBaseline - rolling window of recent parses, persisted in the dump cache dir.
+type ChurnBaseline struct {
+	sync.Mutex
+	samples []ChurnSample
+	loaded  bool
+}
+
+var churnBaseline = &ChurnBaseline{}
+
+// newChurnSample - churn of the parse.
+func newChurnSample(stats ParseStatistics) ChurnSample {
+	delta := stats.AddCount - stats.RemoveCount
+	if delta < 0 {
+		delta = -delta
+	}
+
+	return ChurnSample{Added: stats.AddCount, Updated: stats.UpdateCount, Removed: stats.RemoveCount, Delta: delta}
+}
+
+// Check - anomalies of the sample against the baseline, then the sample joins the baseline.
+func (b *ChurnBaseline) Check(dir string, sample ChurnSample, opts ChurnOptions) []string {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.loaded {
+		b.load(dir)
+	}
+
+	var anomalies []string
+
+	if len(b.samples) >= churnMinSamples {
+		for i, value := range sample.values() {
+			mean, stddev := b.stat(i)
+
+			// a steady series has no deviation, count noise is at least Poisson-like.
+			noise := math.Max(stddev, math.Max(math.Sqrt(mean), 1))
+
+			if value >= opts.MinCount && float64(value) > mean+opts.Sigma*noise {
+				anomalies = append(anomalies, fmt.Sprintf("%s %d is anomalous: baseline %.0f±%.0f over %d parses",
+					churnSeries[i], value, mean, stddev, len(b.samples)))
+			}
+		}
+	}
+
+	b.samples = append(b.samples, sample)
+	if len(b.samples) > opts.Window {
+		b.samples = append(b.samples[:0:0], b.samples[len(b.samples)-opts.Window:]...)
+	}
+
+	b.save(dir)
+
+	return anomalies
+}
+
+// stat - mean and standard deviation of the i-th series.
+func (b *ChurnBaseline) stat(i int) (float64, float64) {
+	var sum, sq float64
+
+	for _, s := range b.samples {
+		v := float64(s.values()[i])
+		sum += v
+		sq += v * v
+	}
+
+	n := float64(len(b.samples))
+	mean := sum / n
+
+	return mean, math.Sqrt(math.Max(sq/n-mean*mean, 0))
+}
+
+func (b *ChurnBaseline) load(dir string) {
+	b.loaded = true
+
+	dat, err := os.ReadFile(dir + "/" + churnFilename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error.Printf("Can't read churn baseline: %s\n", err.Error())
+		}
+
+		return
+	}
+
+	file := churnFile{}
+
+	if err := json.Unmarshal(dat, &file); err != nil {
+		logger.Error.Printf("Can't unmarshal churn baseline: %s\n", err.Error())
+
+		return
+	}
+
+	b.samples = file.Samples
+}
+
+func (b *ChurnBaseline) save(dir string) {
+	dat, err := json.Marshal(churnFile{Samples: b.samples})
+	if err != nil {
+		logger.Error.Printf("Can't marshal churn baseline: %s\n", err.Error())
+
+		return
+	}
+
+	tfn := dir + "/" + churnFilename + "-tmp"
+
+	if err := os.WriteFile(tfn, dat, 0644); err != nil {
+		logger.Error.Printf("Can't write churn baseline: %s\n", err.Error())
+
+		return
+	}
+
+	if err := os.Rename(tfn, dir+"/"+churnFilename); err != nil {
+		logger.Error.Printf("Can't rename churn baseline: %s\n", err.Error())
+	}
+}
+
+// DetectChurn - compare the parse with the baseline, raise alerts and annotate the changelog entry.
+// Initial loads add everything, they are neither judged nor counted.
+func DetectChurn(dir string, stats ParseStatistics, entry *ChangelogEntry) {
+	metricChurnAdded.Set(int64(stats.AddCount))
+	metricChurnUpdated.Set(int64(stats.UpdateCount))
+	metricChurnRemoved.Set(int64(stats.RemoveCount))
+
+	if stats.Initial {
+		entry.Annotations = append(entry.Annotations, "initial load")
+
+		return
+	}
+
+	if ChurnConfig.Window <= 0 {
+		return
+	}
+
+	for _, anomaly := range churnBaseline.Check(dir, newChurnSample(stats), ChurnConfig) {
+		metricChurnAnomalies.Add(1)
+		RaiseAlert(AlertChurn, entry.Generation, anomaly)
+
+		entry.Annotations = append(entry.Annotations, anomaly)
+	}
+}
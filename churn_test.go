@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestChurnBaseline tests anomaly verdicts and persistence of the churn baseline.
+func TestChurnBaseline(t *testing.T) {
+	dir := t.TempDir()
+	opts := ChurnOptions{Window: 10, Sigma: 4, MinCount: 100}
+
+	b := &ChurnBaseline{}
+
+	for i := 0; i < churnMinSamples; i++ {
+		if anomalies := b.Check(dir, ChurnSample{Added: 500 + i*10, Removed: 400 + i*10, Delta: 100}, opts); len(anomalies) != 0 {
+			t.Fatalf("no verdicts expected while learning, got %v", anomalies)
+		}
+	}
+
+	if anomalies := b.Check(dir, ChurnSample{Added: 530, Removed: 420, Delta: 110}, opts); len(anomalies) != 0 {
+		t.Errorf("normal churn, got %v", anomalies)
+	}
+
+	// a fresh baseline from the same dir remembers the window.
+	b = &ChurnBaseline{}
+
+	anomalies := b.Check(dir, ChurnSample{Added: 500, Removed: 5000, Delta: 4500}, opts)
+	if len(anomalies) != 2 || !strings.HasPrefix(anomalies[0], "removed 5000 is anomalous") ||
+		!strings.HasPrefix(anomalies[1], "record count change 4500 is anomalous") {
+		t.Errorf("expected removed and record count anomalies, got %v", anomalies)
+	}
+
+	if len(b.samples) != churnMinSamples+2 {
+		t.Errorf("expected %d samples, got %d", churnMinSamples+2, len(b.samples))
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// RunCommand - run CLI subcommand, returns false if it is not a subcommand.
+func RunCommand(name string, args []string) (int, bool) {
+	switch name {
+	case "diff":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return diffCommand(args), true
+	case "verify":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return verifyCommand(args), true
+	case "query":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return queryCommand(args), true
+	case "compare":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return compareCommand(args), true
+	case "status":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return statusCommand(args), true
+	case "changes":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return changesCommand(args), true
+	case "version":
+		fmt.Printf("%s %s %s %s/%s\n", ToolVersion(), ToolCommit(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+		return 0, true
+	case "fetch":
+		logger.LogInit(io.Discard, os.Stdout, os.Stderr, os.Stderr)
+
+		return fetchCommand(args), true
+	case "stream":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return streamCommand(args), true
+	case "shadow":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return shadowCommand(args), true
+	case "maintenance":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return maintenanceCommand(args), true
+	case "fault":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return faultCommand(args), true
+	case "check":
+		logger.LogInit(io.Discard, io.Discard, os.Stderr, os.Stderr)
+
+		return checkCommand(args), true
+	}
+
+	return 0, false
+}
+
+// diffCommand - print selector level diff between two snapshot files.
+func diffCommand(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff <from snapshot> <to snapshot>\n", os.Args[0])
+
+		return 2
+	}
+
+	from, err := ReadSnapshot(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read snapshot: %s\n", err.Error())
+
+		return 1
+	}
+
+	to, err := ReadSnapshot(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read snapshot: %s\n", err.Error())
+
+		return 1
+	}
+
+	added, removed := DiffSelectors(from.Selectors(), to.Selectors())
+
+	fmt.Printf("# generation %d -> %d\n", from.Generation, to.Generation)
+
+	for _, sel := range removed {
+		fmt.Printf("- %s %s\n", sel.Kind, sel.Value)
+	}
+
+	for _, sel := range added {
+		fmt.Printf("+ %s %s\n", sel.Kind, sel.Value)
+	}
+
+	return 0
+}
+
+// streamCommand - print the records of the dump file as JSON lines, as they are parsed, offline.
+func streamCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s stream <dump.xml>\n", os.Args[0])
+
+		return 2
+	}
+
+	dumpFile, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't open dump file: %s\n", err.Error())
+
+		return 1
+	}
+
+	defer dumpFile.Close()
+
+	stream, err := parser.Stream(dumpFile, ParseConfig.decoderConfig())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't stream: %s\n", err.Error())
+
+		return 1
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	enc := json.NewEncoder(w)
+
+	for {
+		record, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Parse error: %s\n", err.Error())
+
+			return 1
+		}
+
+		if err := enc.Encode(record); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't write: %s\n", err.Error())
+
+			return 1
+		}
+	}
+
+	stats := stream.Statistics()
+	fmt.Fprintf(os.Stderr, "# records %d, bytes %d\n", stats.Count, stats.Size)
+
+	warnings, _ := stream.Warnings()
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "# warning %s <%s> %s: %d, last %q of %d\n", w.Kind, w.Element, w.Attr, w.Count, w.Value, w.ID)
+	}
+
+	return 0
+}
+
+// verifyCommand - parse the dump file from scratch and check index consistency, offline.
+func verifyCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify <dump.xml>\n", os.Args[0])
+
+		return 2
+	}
+
+	dumpFile, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't open dump file: %s\n", err.Error())
+
+		return 1
+	}
+
+	defer dumpFile.Close()
+
+	if err := Parse(dumpFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Parse error: %s\n", err.Error())
+
+		return 1
+	}
+
+	report := CurrentDump.VerifyIndexes()
+
+	for _, p := range report.Problems {
+		fmt.Println(p.String())
+	}
+
+	fmt.Printf("# records %d, index entries %d, problems %d\n", report.Records, report.Entries, len(report.Problems))
+
+	if len(report.Problems) > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// clientFlags - common flags of client subcommands.
+type clientFlags struct {
+	addr    *string
+	json    *bool
+	timeout *time.Duration
+}
+
+func newClientFlags(fs *flag.FlagSet) clientFlags {
+	return clientFlags{
+		addr:    fs.String("addr", "localhost:50001", "gRPC address of the running instance"),
+		json:    fs.Bool("json", false, "Print JSON"),
+		timeout: fs.Duration("timeout", 10*time.Second, "Request timeout"),
+	}
+}
+
+// dial - connect to the running instance.
+func (f clientFlags) dial() (pb.CheckClient, *grpc.ClientConn, context.Context, context.CancelFunc, error) {
+	conn, err := grpc.Dial(*f.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("dial: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *f.timeout)
+
+	return pb.NewCheckClient(conn), conn, ctx, cancel, nil
+}
+
+// withAdminToken - the context carries the admin token of the environment, if it is set.
+func withAdminToken(ctx context.Context) context.Context {
+	if token := os.Getenv(adminTokenEnv); token != "" {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
+	return ctx
+}
+
+// printJSON - protobuf message as JSON.
+func printJSON(m proto.Message) {
+	dat, err := protojson.MarshalOptions{Multiline: true}.Marshal(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't marshal: %s\n", err.Error())
+
+		return
+	}
+
+	fmt.Println(string(dat))
+}
+
+// queryCommand - search the running instance: query ip|domain|url|id|decision|decision-text|ts|query <value>,
+// ts takes hours: records changed in the registry in the last hours, query takes a Query expression.
+func queryCommand(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	cf := newClientFlags(fs)
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s query [flags] ip|domain|url|id|decision|decision-text|ts|query <value>\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	kind, value := fs.Arg(0), fs.Arg(1)
+
+	client, conn, ctx, cancel, err := cf.dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+
+		return 1
+	}
+
+	defer conn.Close()
+	defer cancel()
+
+	var resp *pb.SearchResponse
+
+	switch kind {
+	case "ip":
+		ip := net.ParseIP(value)
+		if ip == nil {
+			fmt.Fprintf(os.Stderr, "Bad IP: %s\n", value)
+
+			return 2
+		}
+
+		if ip4 := ip.To4(); ip4 != nil {
+			resp, err = client.SearchIP4(ctx, &pb.IP4Request{Query: parser.IPv4StrToInt(ip4.String())})
+		} else {
+			resp, err = client.SearchIP6(ctx, &pb.IP6Request{Query: ip.To16()})
+		}
+	case "domain":
+		resp, err = client.SearchDomain(ctx, &pb.DomainRequest{Query: NormalizeDomain(value)})
+	case "url":
+		resp, err = client.SearchURL(ctx, &pb.URLRequest{Query: NormalizeURL(value)})
+	case MatchDecisionText:
+		resp, err = client.SearchTextDecision(ctx, &pb.TextDecisionRequest{Query: value})
+	case MatchTs:
+		hours, perr := strconv.ParseFloat(value, 64)
+		if perr != nil || hours <= 0 {
+			fmt.Fprintf(os.Stderr, "Bad hours: %s\n", value)
+
+			return 2
+		}
+
+		resp, err = client.SearchRegistryTs(ctx, &pb.RegistryTsRequest{Within: int64(hours * 3600)})
+	case MatchQuery:
+		resp, err = client.Query(ctx, &pb.QueryRequest{Query: value, Limit: maxPageLimit})
+	case "id", "decision":
+		n, perr := strconv.ParseUint(value, 10, 64)
+		if perr != nil {
+			fmt.Fprintf(os.Stderr, "Bad number: %s\n", value)
+
+			return 2
+		}
+
+		if kind == "id" {
+			resp, err = client.SearchID(ctx, &pb.IDRequest{Query: int32(n)})
+		} else {
+			resp, err = client.SearchDecision(ctx, &pb.DecisionRequest{Query: n})
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown query: %s\n", kind)
+
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	if *cf.json {
+		printJSON(resp)
+	} else {
+		printSearchResponse(resp)
+	}
+
+	if resp.GetError() != "" {
+		return 1
+	}
+
+	return 0
+}
+
+// printSearchResponse - one line per result.
+func printSearchResponse(resp *pb.SearchResponse) {
+	if resp.GetError() != "" {
+		fmt.Printf("error: %s\n", resp.GetError())
+
+		return
+	}
+
+	fmt.Printf("# registry %s, results %d\n", formatUnix(resp.GetRegistryUpdateTime()), len(resp.GetResults()))
+
+	for _, result := range resp.GetResults() {
+		fields := []string{fmt.Sprintf("id=%d", result.GetId())}
+
+		switch {
+		case result.GetIp4() != 0:
+			fields = append(fields, "ip4="+int2Ip4(result.GetIp4()))
+		case len(result.GetIp6()) > 0:
+			fields = append(fields, "ip6="+net.IP(result.GetIp6()).String())
+		case result.GetDomain() != "":
+			fields = append(fields, "domain="+result.GetDomain())
+		case result.GetUrl() != "":
+			fields = append(fields, "url="+result.GetUrl())
+		case result.GetAggr() != "":
+			fields = append(fields, "subnet="+result.GetAggr())
+		}
+
+		if d := result.GetDecision(); d != nil {
+			fields = append(fields, fmt.Sprintf("decision=%q", strings.Join([]string{d.GetNumber(), d.GetDate(), d.GetOrg()}, " ")))
+		}
+
+		record := Content{}
+		if err := json.Unmarshal(result.GetPack(), &record); err == nil {
+			fields = append(fields, "included="+formatUnix(record.IncludeTime))
+		}
+
+		if result.GetExcluded() != "" {
+			fields = append(fields, "excluded="+result.GetExcluded())
+		}
+
+		fmt.Println(strings.Join(fields, " "))
+	}
+}
+
+// compareCommand - diff a search of the running instance and its peer: compare -peer host:port ip|subnet|domain|url|id|decision <value>.
+func compareCommand(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	cf := newClientFlags(fs)
+	peer := fs.String("peer", "", "gRPC address of the peer, one of -peers of the running instance")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 || *peer == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s compare [flags] -peer host:port ip|subnet|domain|url|id|decision <value>\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	client, conn, ctx, cancel, err := cf.dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+
+		return 1
+	}
+
+	defer conn.Close()
+	defer cancel()
+
+	resp, err := client.CompareSelector(ctx, &pb.CompareSelectorRequest{Peer: *peer, Kind: fs.Arg(0), Query: fs.Arg(1)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Compare failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	if *cf.json {
+		printJSON(resp)
+	} else {
+		printCompareSelector(resp)
+	}
+
+	if resp.GetError() != "" || len(resp.GetDiffs()) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// printCompareSelector - one line per differing result.
+func printCompareSelector(resp *pb.CompareSelectorResponse) {
+	if resp.GetError() != "" {
+		fmt.Printf("error: %s\n", resp.GetError())
+
+		return
+	}
+
+	fmt.Printf("# registry %s, peer %s, results %d, peer %d, differ %d\n",
+		formatUnix(resp.GetRegistryUpdateTime()), formatUnix(resp.GetPeerRegistryUpdateTime()),
+		resp.GetResults(), resp.GetPeerResults(), len(resp.GetDiffs()))
+
+	for _, d := range resp.GetDiffs() {
+		status := "differ=" + strings.Join(d.GetFields(), ",")
+
+		switch {
+		case d.GetPeer() == nil:
+			status = "local-only"
+		case d.GetLocal() == nil:
+			status = "peer-only"
+		}
+
+		fmt.Printf("id=%d %s %s %s\n", d.GetId(), d.GetMatchedBy().GetKind(), d.GetMatchedBy().GetSelector(), status)
+	}
+}
+
+// statusCommand - health, generation and the last parse of the running instance.
+func statusCommand(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	cf := newClientFlags(fs)
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s status [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	client, conn, ctx, cancel, err := cf.dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+
+		return 1
+	}
+
+	defer conn.Close()
+	defer cancel()
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: healthService})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Health check failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	pong, err := client.Ping(ctx, &pb.PingRequest{Ping: "status"})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ping failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	changelog, err := client.GetChangelog(ctx, &pb.ChangelogRequest{Limit: 1})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Changelog failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	// older instances have no GetVersion.
+	version, err := client.GetVersion(ctx, &pb.VersionRequest{})
+	if err != nil && status.Code(err) != codes.Unimplemented {
+		fmt.Fprintf(os.Stderr, "Version failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	// nor SetMaintenance.
+	maintenance, err := client.SetMaintenance(ctx, &pb.MaintenanceRequest{})
+	if err != nil && status.Code(err) != codes.Unimplemented {
+		fmt.Fprintf(os.Stderr, "Maintenance failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	if *cf.json {
+		printJSON(health)
+		printJSON(pong)
+		printJSON(changelog)
+
+		if version != nil {
+			printJSON(version)
+		}
+
+		if maintenance != nil {
+			printJSON(maintenance)
+		}
+
+		return 0
+	}
+
+	fmt.Printf("readiness: %s\n", health.GetStatus())
+
+	if maintenance != nil {
+		fmt.Printf("polling: %s\n", formatMaintenance(maintenance))
+	}
+
+	if version != nil {
+		fmt.Printf("version: %s %s %s/%s\n", version.GetVersion(), version.GetCommit(), version.GetOs(), version.GetArch())
+		fmt.Printf("features: %s\n", strings.Join(version.GetFeatures(), " "))
+	} else {
+		fmt.Printf("version: unknown\n")
+	}
+
+	if pong.GetError() != "" {
+		fmt.Printf("error: %s\n", pong.GetError())
+	} else {
+		fmt.Printf("registry: %s\n", formatUnix(pong.GetRegistryUpdateTime()))
+		fmt.Printf("registry age: %s\n", time.Duration(pong.GetRegistryAge())*time.Second)
+		fmt.Printf("generation: %d\n", pong.GetGeneration())
+		fmt.Printf("dataset: %s\n", pong.GetDatasetHash())
+	}
+
+	for _, entry := range changelog.GetEntries() {
+		fmt.Print("last parse: ")
+		printChangelogEntry(entry)
+	}
+
+	if health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return 1
+	}
+
+	return 0
+}
+
+// maintenanceCommand - pause or resume the poller of the running instance, or show its state.
+func maintenanceCommand(args []string) int {
+	fs := flag.NewFlagSet("maintenance", flag.ContinueOnError)
+	cf := newClientFlags(fs)
+
+	usage := func() int {
+		fmt.Fprintf(os.Stderr, "Usage: %s maintenance [flags] [pause <reason>|resume]\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return usage()
+	}
+
+	req := &pb.MaintenanceRequest{}
+
+	switch {
+	case fs.NArg() == 0:
+	case fs.Arg(0) == "pause" && fs.NArg() > 1:
+		req.Pause, req.Reason = true, strings.Join(fs.Args()[1:], " ")
+	case fs.Arg(0) == "resume" && fs.NArg() == 1:
+		req.Resume = true
+	default:
+		return usage()
+	}
+
+	client, conn, ctx, cancel, err := cf.dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+
+		return 1
+	}
+
+	defer conn.Close()
+	defer cancel()
+
+	// the state is open, pause and resume need the admin token.
+	resp, err := client.SetMaintenance(withAdminToken(ctx), req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Maintenance failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	if resp.GetError() != "" {
+		fmt.Fprintf(os.Stderr, "%s\n", resp.GetError())
+
+		return 1
+	}
+
+	if *cf.json {
+		printJSON(resp)
+
+		return 0
+	}
+
+	fmt.Printf("polling: %s\n", formatMaintenance(resp))
+
+	return 0
+}
+
+// faultCommand - arm or clear poller failures of the running instance in the test mode.
+func faultCommand(args []string) int {
+	fs := flag.NewFlagSet("fault", flag.ContinueOnError)
+	cf := newClientFlags(fs)
+
+	usage := func() int {
+		fmt.Fprintf(os.Stderr, "Usage: %s fault [flags] <stage>[:<polls>]|clear [<stage>]\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return usage()
+	}
+
+	req := &pb.FaultRequest{}
+
+	switch {
+	case fs.Arg(0) == "clear" && fs.NArg() <= 2:
+		req.Clear, req.Stage = true, fs.Arg(1)
+	case fs.NArg() == 1:
+		faults, err := ParseFaults(fs.Arg(0))
+		if err != nil || len(faults) != 1 {
+			return usage()
+		}
+
+		req.Stage, req.Count = faults[0].Stage, faults[0].Remaining
+	default:
+		return usage()
+	}
+
+	client, conn, ctx, cancel, err := cf.dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+
+		return 1
+	}
+
+	defer conn.Close()
+	defer cancel()
+
+	resp, err := client.InjectFault(withAdminToken(ctx), req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fault failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	if resp.GetError() != "" {
+		fmt.Fprintf(os.Stderr, "%s\n", resp.GetError())
+
+		return 1
+	}
+
+	if *cf.json {
+		printJSON(resp)
+
+		return 0
+	}
+
+	for _, f := range resp.GetFaults() {
+		fmt.Printf("%s: %d\n", f.GetStage(), f.GetRemaining())
+	}
+
+	return 0
+}
+
+// formatMaintenance - "active" or "paused since <time>: <reason>".
+func formatMaintenance(m *pb.MaintenanceResponse) string {
+	if !m.GetPaused() {
+		return "active"
+	}
+
+	return fmt.Sprintf("paused since %s: %s", formatUnix(m.GetSince()), m.GetReason())
+}
+
+// changesCommand - changelog of the running instance for the recent period.
+func changesCommand(args []string) int {
+	fs := flag.NewFlagSet("changes", flag.ContinueOnError)
+	cf := newClientFlags(fs)
+	since := fs.Duration("since", 24*time.Hour, "Period to show")
+	transitions := fs.String("transitions", "", "Only generations with block type transitions, comma separated \"from>to\" pairs, e.g. \"url>domain,*>ip\"")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s changes [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	client, conn, ctx, cancel, err := cf.dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+
+		return 1
+	}
+
+	defer conn.Close()
+	defer cancel()
+
+	changelog, err := client.GetChangelog(ctx, &pb.ChangelogRequest{Transitions: *transitions})
+	if err == nil && changelog.GetError() != "" {
+		err = fmt.Errorf("%s", changelog.GetError())
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Changelog failed: %s\n", err.Error())
+
+		return 1
+	}
+
+	from := time.Now().Add(-*since).Unix()
+	entries := changelog.GetEntries()[:0]
+
+	for _, entry := range changelog.GetEntries() {
+		if entry.GetParsed() >= from {
+			entries = append(entries, entry)
+		}
+	}
+
+	changelog.Entries = entries
+
+	if *cf.json {
+		printJSON(changelog)
+
+		return 0
+	}
+
+	for _, entry := range entries {
+		printChangelogEntry(entry)
+	}
+
+	return 0
+}
+
+func printChangelogEntry(entry *pb.ChangelogEntry) {
+	fmt.Printf("%s generation %d registry %s records %d +%d ~%d -%d\n",
+		formatUnix(entry.GetParsed()), entry.GetGeneration(), formatUnix(entry.GetRegistryUpdateTime()),
+		entry.GetCount(), entry.GetAdded(), entry.GetUpdated(), entry.GetRemoved())
+
+	for _, note := range entry.GetAnnotations() {
+		fmt.Printf("  ! %s\n", note)
+	}
+
+	for _, t := range entry.GetTransitions() {
+		fmt.Printf("  > %s\n", BlockTypeTransition{ID: t.GetId(), From: t.GetFrom(), To: t.GetTo()})
+	}
+}
+
+func formatUnix(t int64) string {
+	if t == 0 {
+		return "-"
+	}
+
+	return time.Unix(t, 0).Format(time.RFC3339)
+}
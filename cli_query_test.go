@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// runClient - exit code and stdout of the client subcommand.
+func runClient(t *testing.T, command func([]string) int, args ...string) (int, string) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(stdout *os.File) { os.Stdout = stdout }(os.Stdout)
+
+	os.Stdout = w
+
+	out := make(chan string)
+
+	go func() {
+		dat, _ := io.ReadAll(r)
+		out <- string(dat)
+	}()
+
+	code := command(args)
+
+	w.Close()
+
+	return code, <-out
+}
+
+// TestClientCommands tests query, status and changes against a running instance.
+func TestClientCommands(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer()
+	pb.RegisterCheckServer(srv, &server{})
+
+	hs := health.NewServer()
+	hs.SetServingStatus(healthService, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	addr := "-addr=" + listen.Addr().String()
+
+	for _, tc := range []struct {
+		name    string
+		command func([]string) int
+		args    []string
+		code    int
+		expect  []string
+	}{
+		{"query id", queryCommand, []string{addr, "id", "111"}, 0, []string{"results 1", "id=111 "}},
+		{"query ip", queryCommand, []string{addr, "ip", "192.168.0.100"}, 0, []string{"results 3", "id=111 ", "id=222 ", "id=333 "}},
+		{"query json", queryCommand, []string{addr, "-json", "id", "111"}, 0, []string{`"results"`, `"matchedBy"`}},
+		{"query bad ip", queryCommand, []string{addr, "ip", "300.1.1.1"}, 2, nil},
+		{"query unknown", queryCommand, []string{addr, "asn", "1"}, 2, nil},
+		{"query usage", queryCommand, []string{addr, "id"}, 2, nil},
+		{"status", statusCommand, []string{addr}, 0, []string{"readiness: SERVING", "generation: "}},
+		{"changes", changesCommand, []string{addr, "-since=1h"}, 0, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			code, out := runClient(t, tc.command, tc.args...)
+			if code != tc.code {
+				t.Errorf("Exit code: %d, output: %s\n", code, out)
+			}
+
+			for _, s := range tc.expect {
+				if !strings.Contains(out, s) {
+					t.Errorf("No %q in output: %s\n", s, out)
+				}
+			}
+		})
+	}
+}
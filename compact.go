@@ -0,0 +1,169 @@
+package main
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// CompactThreshold - compact the index maps when records removed since the last compaction
+// are over this fraction of the records and them, 0 disables. It is set once at startup.
+var CompactThreshold float64
+
+// CompactStats - result of one compaction.
+type CompactStats struct {
+	Removed    int           // records removed since the previous compaction.
+	Duration   time.Duration // rebuild under the write lock.
+	HeapBefore uint64        // heap in use before the rebuild.
+	HeapAfter  uint64        // heap in use after the rebuild and a GC.
+}
+
+// needCompact - removed records are over the threshold.
+func (dump *Dump) needCompact(threshold float64) bool {
+	if threshold <= 0 || dump.removed == 0 {
+		return false
+	}
+
+	return float64(dump.removed) > threshold*float64(len(dump.ContentIdx)+dump.removed)
+}
+
+// CompactIndexes - rebuild the index maps of the dump if removed records are over the threshold.
+// Go maps keep their buckets after deletes, the rebuilt ones are sized to the current counts.
+func CompactIndexes(dump *Dump, threshold float64) (CompactStats, bool) {
+	dump.RLock()
+	need := dump.needCompact(threshold)
+	dump.RUnlock()
+
+	if !need {
+		return CompactStats{}, false
+	}
+
+	var mem runtime.MemStats
+
+	runtime.ReadMemStats(&mem)
+
+	stats := CompactStats{HeapBefore: mem.HeapInuse}
+
+	dump.Lock()
+
+	started := time.Now()
+	stats.Removed = dump.removed
+
+	dump.compact()
+
+	stats.Duration = time.Since(started)
+
+	dump.Unlock()
+
+	// the old maps are garbage now.
+	runtime.GC()
+	runtime.ReadMemStats(&mem)
+
+	stats.HeapAfter = mem.HeapInuse
+
+	metricCompactions.Add(1)
+	metricCompactDuration.Set(stats.Duration.Milliseconds())
+	metricCompactHeapBefore.Set(int64(stats.HeapBefore))
+	metricCompactHeapAfter.Set(int64(stats.HeapAfter))
+
+	logger.Info.Printf("Compacted indexes: %d removed records, %s, heap %d -> %d\n",
+		stats.Removed, stats.Duration, stats.HeapBefore, stats.HeapAfter)
+
+	return stats, true
+}
+
+// compact - replace the index maps with copies, call it under write lock.
+// ID slices are shared with the copies, they are changed under the same lock only.
+func (dump *Dump) compact() {
+	dump.ContentIdx = dump.ContentIdx.compact()
+	dump.ip4Idx = dump.ip4Idx.compact()
+	dump.ip6Idx = dump.ip6Idx.compact()
+	dump.subnet4Idx = dump.subnet4Idx.compact()
+	dump.subnet6Idx = dump.subnet6Idx.compact()
+	dump.urlIdx = dump.urlIdx.compact()
+	dump.urlAnyIdx = dump.urlAnyIdx.compact()
+	dump.domainIdx = dump.domainIdx.compact()
+	dump.decisionIdx = dump.decisionIdx.compact()
+	dump.orgIdx = dump.orgIdx.compact()
+	dump.decisionDateIdx.compact()
+	dump.includeTimeIdx.compact()
+	dump.tsIdx.compact()
+	dump.hot.compact()
+
+	dump.removed = 0
+}
+
+func (a MinContentMap) compact() MinContentMap {
+	b := make(MinContentMap, len(a))
+	for k, v := range a {
+		b[k] = v
+	}
+
+	return b
+}
+
+func (a AddrSet) compact() AddrSet {
+	b := make(AddrSet, len(a))
+	for k, v := range a {
+		b[k] = v
+	}
+
+	return b
+}
+
+func (a PrefixSet) compact() PrefixSet {
+	b := make(PrefixSet, len(a))
+	for k, v := range a {
+		b[k] = v
+	}
+
+	return b
+}
+
+func (a StringIntSet) compact() StringIntSet {
+	b := make(StringIntSet, len(a))
+	for k, v := range a {
+		b[k] = v
+	}
+
+	return b
+}
+
+func (a DecisionSet) compact() DecisionSet {
+	b := make(DecisionSet, len(a))
+	for k, v := range a {
+		b[k] = v
+	}
+
+	return b
+}
+
+func (a *TimeSet) compact() {
+	idx := make(map[int64]ArrayIntSet, len(a.idx))
+	for k, v := range a.idx {
+		idx[k] = v
+	}
+
+	a.idx = idx
+}
+
+func (h *hotCounter) compact() {
+	count := make(map[hotKey]int, len(h.count))
+	for k, v := range h.count {
+		count[k] = v
+	}
+
+	buckets := make(map[int]map[hotKey]Nothing, len(h.buckets))
+
+	for n, bucket := range h.buckets {
+		b := make(map[hotKey]Nothing, len(bucket))
+		for k := range bucket {
+			b[k] = Nothing{}
+		}
+
+		buckets[n] = b
+	}
+
+	h.count, h.buckets = count, buckets
+}
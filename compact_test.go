@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCompactIndexes tests the threshold and that compacted indexes are the same.
+func TestCompactIndexes(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	// only 111 is left.
+	start, end := strings.Index(xml01, `<content id="222"`), strings.Index(xml01, "</reg:register>")
+	if err := Parse(strings.NewReader(xml01[:start] + xml01[end:])); err != nil {
+		t.Fatal(err)
+	}
+
+	if CurrentDump.removed != 4 {
+		t.Fatalf("Removed: %d\n", CurrentDump.removed)
+	}
+
+	if _, ok := CompactIndexes(CurrentDump, 0.9); ok {
+		t.Errorf("Compacted under the threshold\n")
+	}
+
+	ip4Idx, domainIdx, contentIdx := CurrentDump.ip4Idx, CurrentDump.domainIdx, CurrentDump.ContentIdx
+	includeTimes := CurrentDump.includeTimeIdx.Range(0, 1<<62)
+	compactions := metricCompactions.Value()
+
+	stats, ok := CompactIndexes(CurrentDump, 0.5)
+	if !ok || stats.Removed != 4 || CurrentDump.removed != 0 || metricCompactions.Value() != compactions+1 || stats.HeapBefore == 0 {
+		t.Fatalf("Compaction: %v %+v\n", ok, stats)
+	}
+
+	if !reflect.DeepEqual(ip4Idx, CurrentDump.ip4Idx) || !reflect.DeepEqual(domainIdx, CurrentDump.domainIdx) ||
+		!reflect.DeepEqual(contentIdx, CurrentDump.ContentIdx) || !reflect.DeepEqual(includeTimes, CurrentDump.includeTimeIdx.Range(0, 1<<62)) {
+		t.Errorf("Compacted indexes differ\n")
+	}
+
+	// the compacted indexes are updated as usual.
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(CurrentDump.ContentIdx) != 5 || len(CurrentDump.ip4Idx[netip.MustParseAddr("192.168.0.100")]) != 3 {
+		t.Errorf("After compaction: %d %v\n", len(CurrentDump.ContentIdx), CurrentDump.ip4Idx)
+	}
+}
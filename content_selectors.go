@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentSelectors - typed selectors of one record, without the payload.
+type ContentSelectors struct {
+	ID                 int32    `json:"id"`
+	RegistryUpdateTime int64    `json:"registryUpdateTime"`
+	BlockType          int32    `json:"blockType"`
+	Ordinal            int32    `json:"ordinal,omitempty"` // position in the dump file of the generation.
+	IP4                []string `json:"ip4"`
+	IP6                []string `json:"ip6"`
+	Subnet4            []string `json:"subnet4"`
+	Subnet6            []string `json:"subnet6"`
+	Domains            []string `json:"domains"`
+	URLs               []string `json:"urls"`
+}
+
+// newContentSelectors - selectors of the record in the registry order.
+func newContentSelectors(pack *PackedContent) *ContentSelectors {
+	v := &ContentSelectors{
+		ID:                 pack.ID,
+		RegistryUpdateTime: pack.RegistryUpdateTime,
+		BlockType:          pack.BlockType,
+		IP4:                make([]string, 0, len(pack.IP4)),
+		IP6:                make([]string, 0, len(pack.IP6)),
+		Subnet4:            make([]string, 0, len(pack.Subnet4)),
+		Subnet6:            make([]string, 0, len(pack.Subnet6)),
+		Domains:            make([]string, 0, len(pack.Domain)),
+		URLs:               make([]string, 0, len(pack.URL)),
+	}
+
+	for _, ip4 := range pack.IP4 {
+		v.IP4 = append(v.IP4, ip4.IP4.String())
+	}
+
+	for _, ip6 := range pack.IP6 {
+		v.IP6 = append(v.IP6, ip6.IP6.String())
+	}
+
+	for _, subnet4 := range pack.Subnet4 {
+		v.Subnet4 = append(v.Subnet4, subnet4.Subnet4.String())
+	}
+
+	for _, subnet6 := range pack.Subnet6 {
+		v.Subnet6 = append(v.Subnet6, subnet6.Subnet6.String())
+	}
+
+	for _, domain := range pack.Domain {
+		v.Domains = append(v.Domains, domain.Domain)
+	}
+
+	for _, u := range pack.URL {
+		v.URLs = append(v.URLs, u.URL)
+	}
+
+	return v
+}
+
+// handleContentSelectors - GET /content/{id}/selectors: the selectors of the record as JSON.
+func handleContentSelectors(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/content/"), "/selectors")
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, err := strconv.ParseInt(rest, 10, 32)
+	if err != nil || id <= 0 {
+		http.Error(w, SrvBadID, http.StatusBadRequest)
+
+		return
+	}
+
+	CurrentDump.RLock()
+
+	if CurrentDump.utime == 0 {
+		CurrentDump.RUnlock()
+		http.Error(w, SrvDataNotReady, http.StatusServiceUnavailable)
+
+		return
+	}
+
+	pack, ok := CurrentDump.ContentIdx[int32(id)]
+
+	var v *ContentSelectors
+	if ok {
+		v = newContentSelectors(pack)
+		v.Ordinal = CurrentDump.ordinals.Get(pack.ID)
+	}
+
+	CurrentDump.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(v)
+}
+
+// contentSelectorCount - selectors of the kind in the record, the i-th one as a string.
+func contentSelectorCount(pack *PackedContent, kind string) (int, func(i int) string) {
+	switch kind {
+	case SelectorIP4:
+		return len(pack.IP4), func(i int) string { return pack.IP4[i].IP4.String() }
+	case SelectorIP6:
+		return len(pack.IP6), func(i int) string { return pack.IP6[i].IP6.String() }
+	case SelectorSubnet4:
+		return len(pack.Subnet4), func(i int) string { return pack.Subnet4[i].Subnet4.String() }
+	case SelectorSubnet6:
+		return len(pack.Subnet6), func(i int) string { return pack.Subnet6[i].Subnet6.String() }
+	case SelectorDomain:
+		return len(pack.Domain), func(i int) string { return pack.Domain[i].Domain }
+	case SelectorURL:
+		return len(pack.URL), func(i int) string { return pack.URL[i].URL }
+	}
+
+	return 0, nil
+}
+
+// contentSelectorKind - the kind has selectors in a record.
+func contentSelectorKind(kind string) bool {
+	return kind != "" && hotKind(kind)
+}
+
+// ContentSelectorPage - the selectors of the kind in the registry order from the cursor, the cursor of
+// the next page, empty on the last one. The cursor is bound to the record version, false if the record
+// changed since or the cursor is bad. Call it under read lock.
+func ContentSelectorPage(pack *PackedContent, kind, cursor string, limit int32) ([]string, int, string, bool) {
+	n, at := contentSelectorCount(pack, kind)
+
+	var offset int32
+
+	if cursor != "" {
+		version, pos, found := strings.Cut(cursor, ".")
+		if !found || version != strconv.FormatInt(pack.RegistryUpdateTime, 10) {
+			return nil, n, "", false
+		}
+
+		v, err := strconv.ParseInt(pos, 10, 32)
+		if err != nil || v <= 0 || int(v) > n {
+			return nil, n, "", false
+		}
+
+		offset = int32(v)
+	}
+
+	start, end := pageBounds(n, offset, limit)
+
+	page := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		page = append(page, at(i))
+	}
+
+	var next string
+	if end < n {
+		next = strconv.FormatInt(pack.RegistryUpdateTime, 10) + "." + strconv.Itoa(end)
+	}
+
+	return page, n, next, true
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestContentSelectors tests the selectors endpoint of one record.
+func TestContentSelectors(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handleContentSelectors(w, httptest.NewRequest("GET", path, nil))
+
+		return w
+	}
+
+	if w := get("/content/111/selectors"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Not ready: %d\n", w.Code)
+	}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := get("/content/111/selectors")
+
+	var v ContentSelectors
+
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Selectors: %d %s\n", w.Code, w.Body.String())
+	}
+
+	if v.ID != 111 || len(v.IP4) != 3 || v.IP4[1] != "192.168.0.100" || len(v.IP6) != 3 || len(v.URLs) != 3 ||
+		len(v.Domains) != 1 || v.Domains[0] != "www.e01.tld" {
+		t.Errorf("Selectors: %s\n", w.Body.String())
+	}
+
+	for path, code := range map[string]int{
+		"/content/999/selectors": http.StatusNotFound,
+		"/content/x/selectors":   http.StatusBadRequest,
+		"/content/111":           http.StatusNotFound,
+	} {
+		if w := get(path); w.Code != code {
+			t.Errorf("%s: %d, want %d\n", path, w.Code, code)
+		}
+	}
+}
+
+// TestGetContentSelectors tests paging through the selectors of one record by cursor.
+func TestGetContentSelectors(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	var urls []string
+
+	cursor, pages := "", 0
+
+	for {
+		resp, _ := s.GetContentSelectors(context.Background(), &pb.ContentSelectorsRequest{Id: 111, Type: SelectorURL, Cursor: cursor, Limit: 2})
+		if resp.GetError() != "" || resp.GetTotal() != 3 {
+			t.Fatalf("Page: %v\n", resp)
+		}
+
+		urls = append(urls, resp.GetSelectors()...)
+		pages++
+
+		if cursor = resp.GetNextCursor(); cursor == "" {
+			break
+		}
+	}
+
+	if pages != 2 || strings.Join(urls, " ") != "https://www.e01.tld/sex http://www.e01.tld/cheese http://www.e01.tld/slip" {
+		t.Errorf("URLs in %d pages: %v\n", pages, urls)
+	}
+
+	first, _ := s.GetContentSelectors(context.Background(), &pb.ContentSelectorsRequest{Id: 111, Type: SelectorIP4, Limit: 1})
+
+	for _, in := range []*pb.ContentSelectorsRequest{
+		{Id: 111, Type: "decision"},
+		{Id: 111, Type: SelectorURL, Cursor: "1.1"},
+		{Id: 111, Type: SelectorURL, Cursor: "x"},
+	} {
+		if resp, _ := s.GetContentSelectors(context.Background(), in); resp.GetError() == "" {
+			t.Errorf("Bad request %v: %v\n", in, resp)
+		}
+	}
+
+	// the record changes, its cursors are refused.
+	xml := strings.Replace(xml01, "updateTime=\"2011-01-01T01:01:01+03:00\"", "updateTime=\"2012-01-01T01:01:01+03:00\"", 1)
+	xml = strings.Replace(xml, "<ip>192.168.1.11</ip>", "<ip>192.168.1.12</ip>", 1)
+
+	if err := Parse(strings.NewReader(xml)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, _ := s.GetContentSelectors(context.Background(), &pb.ContentSelectorsRequest{Id: 111, Type: SelectorIP4, Cursor: first.GetNextCursor()})
+	if first.GetNextCursor() == "" || resp.GetError() != SrvBadCursor {
+		t.Errorf("Changed record: %v %v\n", first, resp)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"sync"
+)
+
+// datasetCache - dataset hash of one generation.
+type datasetCache struct {
+	sync.Mutex
+	generation int64
+	hash       string
+}
+
+// DatasetHash - stable hash of the parsed records of the generation, call it under read lock.
+// Records are hashed in id order with their record hash, block type and decision hash,
+// so instances with the same hash settings that parsed the same dump the same way have the same hash.
+func (dump *Dump) DatasetHash() string {
+	dump.dataset.Lock()
+	defer dump.dataset.Unlock()
+
+	if dump.dataset.hash != "" && dump.dataset.generation == dump.generation {
+		return dump.dataset.hash
+	}
+
+	ids := make([]int32, 0, len(dump.ContentIdx))
+	for id := range dump.ContentIdx {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	h := sha256.New()
+	buf := make([]byte, 24)
+
+	for _, id := range ids {
+		pack := dump.ContentIdx[id]
+
+		binary.BigEndian.PutUint32(buf[0:], uint32(pack.ID))
+		binary.BigEndian.PutUint32(buf[4:], uint32(pack.BlockType))
+		binary.BigEndian.PutUint64(buf[8:], pack.RecordHash)
+		binary.BigEndian.PutUint64(buf[16:], pack.Decision)
+		h.Write(buf)
+	}
+
+	dump.dataset.generation, dump.dataset.hash = dump.generation, hex.EncodeToString(h.Sum(nil)[:16])
+
+	return dump.dataset.hash
+}
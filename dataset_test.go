@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestDatasetHash tests that the same dump gives the same hash and another one does not.
+func TestDatasetHash(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	hashes := make([]string, 0, 3)
+
+	for _, xml := range []string{xml01, xml01, xml02} {
+		CurrentDump = NewDump()
+
+		if err := Parse(strings.NewReader(xml)); err != nil {
+			t.Fatal(err)
+		}
+
+		hashes = append(hashes, CurrentDump.DatasetHash())
+	}
+
+	if hashes[0] != hashes[1] || hashes[0] == hashes[2] || len(hashes[0]) != 32 {
+		t.Errorf("hashes: %v", hashes)
+	}
+
+	// the cache is dropped with the generation.
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if CurrentDump.DatasetHash() != hashes[0] {
+		t.Errorf("hash of the next generation: %s", CurrentDump.DatasetHash())
+	}
+}
+
+// TestCompareWith tests comparison with an allowed peer.
+func TestCompareWith(t *testing.T) {
+	defer func(dump *Dump, peers StringMap) { CurrentDump, ComparePeers = dump, peers }(CurrentDump, ComparePeers)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer()
+	pb.RegisterCheckServer(srv, &server{})
+
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	ComparePeers = make(StringMap)
+
+	s := &server{}
+	peer := listen.Addr().String()
+
+	resp, err := s.CompareWith(context.Background(), &pb.CompareRequest{Peer: peer})
+	if err != nil || resp.GetError() != SrvUnknownPeer {
+		t.Errorf("unknown peer: %v %v", resp, err)
+	}
+
+	SetComparePeers(" " + peer + ",")
+
+	// the peer serves the same dump.
+	resp, err = s.CompareWith(context.Background(), &pb.CompareRequest{Peer: peer})
+	if err != nil || resp.GetError() != "" || !resp.GetSameDump() || !resp.GetIdentical() || resp.GetPeerDatasetHash() != resp.GetDatasetHash() {
+		t.Errorf("compare: %v %v", resp, err)
+	}
+}
+
+// stalePeer - peer with an extra IPv4 result and a changed block type.
+type stalePeer struct {
+	*server
+}
+
+func (p stalePeer) SearchIP4(ctx context.Context, in *pb.IP4Request) (*pb.SearchResponse, error) {
+	resp, err := p.server.SearchIP4(ctx, in)
+	resp = proto.Clone(resp).(*pb.SearchResponse)
+
+	resp.Results[0].BlockType++
+	resp.Results = append(resp.Results, &pb.Content{Id: 999, MatchedBy: &pb.MatchedBy{Kind: SelectorIP4}})
+
+	return resp, err
+}
+
+// TestCompareSelector tests results of the same search here and on the peer are diffed.
+func TestCompareSelector(t *testing.T) {
+	defer func(dump *Dump, peers StringMap) { CurrentDump, ComparePeers = dump, peers }(CurrentDump, ComparePeers)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer()
+	pb.RegisterCheckServer(srv, stalePeer{&server{}})
+
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	peer := listen.Addr().String()
+	ComparePeers = make(StringMap)
+	SetComparePeers(peer)
+
+	s := &server{}
+
+	resp, err := s.CompareSelector(context.Background(), &pb.CompareSelectorRequest{Peer: peer, Kind: "domain", Query: "www.e01.tld"})
+	if err != nil || resp.GetError() != "" || !resp.GetSameDump() || resp.GetResults() == 0 || resp.GetResults() != resp.GetPeerResults() || len(resp.GetDiffs()) != 0 {
+		t.Errorf("Same: %v %v\n", resp, err)
+	}
+
+	resp, _ = s.CompareSelector(context.Background(), &pb.CompareSelectorRequest{Peer: peer, Kind: "ip", Query: "192.168.0.100"})
+
+	diffs := resp.GetDiffs()
+	if resp.GetPeerResults() != resp.GetResults()+1 || len(diffs) != 2 ||
+		diffs[0].GetId() != 111 || len(diffs[0].GetFields()) != 1 || diffs[0].GetFields()[0] != "blockType" ||
+		diffs[1].GetId() != 999 || diffs[1].GetLocal() != nil {
+		t.Errorf("Stale: %v\n", resp)
+	}
+
+	if resp, _ := s.CompareSelector(context.Background(), &pb.CompareSelectorRequest{Peer: peer, Kind: "ip", Query: "bad"}); !strings.HasPrefix(resp.GetError(), SrvBadSelector) {
+		t.Errorf("Bad selector: %v\n", resp)
+	}
+}
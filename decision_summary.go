@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// DecisionSummary - records of a decision and their distinct normalized selectors by kind.
+type DecisionSummary struct {
+	Hash      uint64
+	IDs       []int32 // ordered.
+	Selectors map[string]int
+}
+
+// DecisionHash - SearchDecision key of the decision as in the registry.
+func DecisionHash(org, number, date string) (uint64, error) {
+	hasher, err := parser.NewHasher(ParseConfig.Hash, ParseConfig.HashSeed)
+	if err != nil {
+		return 0, err
+	}
+
+	return hasher.Decision(&Decision{Date: date, Number: number, Org: org}), nil
+}
+
+// DecisionSummary - summary of the decision, false if no record has it. Call it under read lock.
+func (dump *Dump) DecisionSummary(hash uint64) (DecisionSummary, bool) {
+	members := dump.decisionIdx[hash]
+	if len(members) == 0 {
+		return DecisionSummary{}, false
+	}
+
+	summary := DecisionSummary{Hash: hash, IDs: make([]int32, 0, len(members)), Selectors: make(map[string]int)}
+
+	set := make(SelectorSet)
+
+	for _, id := range members {
+		if pack, ok := dump.ContentIdx[id]; ok {
+			summary.IDs = append(summary.IDs, id)
+			pack.addSelectors(set)
+		}
+	}
+
+	sort.Slice(summary.IDs, func(i, j int) bool { return summary.IDs[i] < summary.IDs[j] })
+
+	for sel := range set {
+		summary.Selectors[sel.Kind]++
+	}
+
+	return summary, len(summary.IDs) > 0
+}
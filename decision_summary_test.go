@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestGetDecisionSummary tests selector counts and paged records of a decision.
+func TestGetDecisionSummary(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	s := &server{}
+
+	decision := &pb.Decision{Org: "ONE", Number: "1/1/11-1111", Date: "2000-01-01"}
+
+	if resp, _ := s.GetDecisionSummary(context.Background(), &pb.DecisionSummaryRequest{Decision: decision}); resp.GetError() != SrvDataNotReady {
+		t.Errorf("Not ready: %v\n", resp)
+	}
+
+	// 222 has the decision of 111.
+	xml := strings.Replace(xml01, `number="2/2/22-2222" org="TWO"`, `number="1/1/11-1111" org="ONE"`, 1)
+	xml = strings.Replace(xml, `<decision date="2000-01-02"`, `<decision date="2000-01-01"`, 1)
+
+	if err := Parse(strings.NewReader(xml)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, _ := s.GetDecisionSummary(context.Background(), &pb.DecisionSummaryRequest{Decision: decision, Offset: 1, Limit: 1})
+	if resp.GetError() != "" || resp.GetTotal() != 2 || !reflect.DeepEqual(resp.GetIds(), []int32{222}) ||
+		resp.GetIp4() != 5 || resp.GetIp6() != 5 || resp.GetDomains() != 2 || resp.GetUrls() != 3 || resp.GetSubnet4() != 0 ||
+		resp.GetDecision().GetOrg() != "ONE" {
+		t.Errorf("Summary: %v\n", resp)
+	}
+
+	byHash, _ := s.GetDecisionSummary(context.Background(), &pb.DecisionSummaryRequest{Decision: &pb.Decision{Hash: resp.GetDecision().GetHash()}})
+	if !reflect.DeepEqual(byHash.GetIds(), []int32{111, 222}) {
+		t.Errorf("By hash: %v\n", byHash)
+	}
+
+	if resp, _ := s.GetDecisionSummary(context.Background(), &pb.DecisionSummaryRequest{Decision: &pb.Decision{Org: "ONE"}}); resp.GetError() != SrvBadDecision {
+		t.Errorf("Bad decision: %v\n", resp)
+	}
+
+	if resp, _ := s.GetDecisionSummary(context.Background(), &pb.DecisionSummaryRequest{Decision: &pb.Decision{Hash: 1}}); resp.GetError() != "" || resp.GetTotal() != 0 {
+		t.Errorf("Unknown decision: %v\n", resp)
+	}
+}
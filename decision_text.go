@@ -0,0 +1,132 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MatchDecisionText - match kind of SearchTextDecision results.
+const MatchDecisionText = "decision-text"
+
+// decisionTextMinLen - shorter queries match most of the registry.
+const decisionTextMinLen = 2
+
+// decisionText - folded number and organizations of a decision and its records.
+type decisionText struct {
+	text string // number, organization and canonical organization, separated by \x00.
+	ids  []int32
+}
+
+// decisionTextCache - decision texts of one generation.
+type decisionTextCache struct {
+	sync.Mutex
+	generation int64
+	texts      []decisionText
+}
+
+// FoldText - case, accent and ё insensitive form of the text: lower case, ё is е, combining marks are dropped,
+// but й stays й, spaces are collapsed.
+func FoldText(s string) string {
+	var b strings.Builder
+
+	b.Grow(len(s))
+
+	space := false
+
+	for _, r := range norm.NFC.String(s) {
+		r = unicode.ToLower(r)
+
+		switch {
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+
+			continue
+		case unicode.IsControl(r):
+			continue
+		}
+
+		if space {
+			b.WriteByte(' ')
+
+			space = false
+		}
+
+		switch {
+		case r < utf8.RuneSelf || r == 'й':
+			b.WriteRune(r)
+		case r == 'ё':
+			b.WriteRune('е')
+		default:
+			for _, d := range norm.NFD.String(string(r)) {
+				if !unicode.Is(unicode.Mn, d) {
+					b.WriteRune(d)
+				}
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// decisionTexts - folded decision texts of the generation, call it under read lock.
+// Decision numbers are read from payloads, without them only organizations are searched.
+func (dump *Dump) decisionTexts() []decisionText {
+	dump.decisionText.Lock()
+	defer dump.decisionText.Unlock()
+
+	if dump.decisionText.texts != nil && dump.decisionText.generation == dump.generation {
+		return dump.decisionText.texts
+	}
+
+	byDecision := make(map[uint64]int)
+	texts := make([]decisionText, 0)
+
+	for id, pack := range dump.ContentIdx {
+		if i, ok := byDecision[pack.Decision]; ok {
+			texts[i].ids = append(texts[i].ids, id)
+
+			continue
+		}
+
+		record, err := pack.Record()
+		if err != nil {
+			continue
+		}
+
+		byDecision[pack.Decision] = len(texts)
+		texts = append(texts, decisionText{
+			text: FoldText(record.Decision.Number) + "\x00" + FoldText(record.Decision.Org) + "\x00" + FoldText(pack.Org),
+			ids:  []int32{id},
+		})
+	}
+
+	dump.decisionText.generation, dump.decisionText.texts = dump.generation, texts
+
+	return texts
+}
+
+// SearchDecisionText - sorted ids of records whose decision number or organization contains
+// the query, see FoldText. ok is false for a too short query. Call it under read lock.
+func (dump *Dump) SearchDecisionText(query string) ([]int32, bool) {
+	query = FoldText(query)
+	if utf8.RuneCountInString(query) < decisionTextMinLen {
+		return nil, false
+	}
+
+	ids := make([]int32, 0)
+
+	for _, t := range dump.decisionTexts() {
+		if strings.Contains(t.text, query) {
+			ids = append(ids, t.ids...)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, true
+}
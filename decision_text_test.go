@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestFoldText tests case, accent and ё folding.
+func TestFoldText(t *testing.T) {
+	for s, want := range map[string]string{
+		"ЁЛКА":               "елка",
+		"Мосгорсу́д":         "мосгорсуд",
+		"Краснодарский КРАЙ": "краснодарский край",
+		"Й":                 "й",
+		" Café \t Noir ":     "cafe noir",
+		"2-6-27/ЕАИС":        "2-6-27/еаис",
+	} {
+		if got := FoldText(s); got != want {
+			t.Errorf("%q: %q, want %q\n", s, got, want)
+		}
+	}
+}
+
+// TestSearchTextDecision tests decision text search of Russian variants.
+func TestSearchTextDecision(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	dump := strings.Replace(xml01, `encoding="windows-1251"`, `encoding="utf-8"`, 1)
+	dump = strings.Replace(dump, `number="1/1/11-1111" org="ONE"`, `number="2-6-27/ЕАИС/Ёлка" org="Мосгорсу`+"́"+`д"`, 1)
+
+	if err := Parse(strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	for query, want := range map[string]int32{
+		"мосгорсуд": 111,
+		"МОСГОРСУД": 111,
+		"еаис/елка": 111,
+		"ЁЛКА":      111,
+	} {
+		resp, _ := s.SearchTextDecision(context.Background(), &pb.TextDecisionRequest{Query: query})
+		if resp.GetError() != "" || resp.GetTotal() != 1 || resp.GetResults()[0].GetId() != want {
+			t.Errorf("%s: %v\n", query, resp)
+		}
+	}
+
+	if resp, _ := s.SearchTextDecision(context.Background(), &pb.TextDecisionRequest{Query: " я "}); resp.GetError() != SrvShortQuery {
+		t.Errorf("Short query: %v\n", resp)
+	}
+
+	if resp, _ := s.SearchTextDecision(context.Background(), &pb.TextDecisionRequest{Query: "нет такого"}); resp.GetError() != "" || resp.GetTotal() != 0 {
+		t.Errorf("No match: %v\n", resp)
+	}
+}
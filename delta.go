@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pbv2 "github.com/usher2/u2ckdump/msg/v2"
+)
+
+// deltaContentType - media type of /delta.
+const deltaContentType = "application/x-protobuf"
+
+// deltaIndexes - indexes of the delta in the order they are sent.
+var deltaIndexes = []string{SelectorIP4, SelectorIP6, SelectorSubnet4, SelectorSubnet6, SelectorDomain, SelectorURL}
+
+// touchedSelectors - selectors the stage may add or remove and whether they are indexed now.
+// Call it under lock before the stage is applied.
+func (dump *Dump) touchedSelectors(s *parseStage) map[Selector]bool {
+	set := make(SelectorSet)
+
+	for _, id := range s.order {
+		staged := s.records[id]
+		if staged.same {
+			continue
+		}
+
+		record := staged.content
+		pack := PackedContent{URL: record.URL, IP4: record.IP4, IP6: record.IP6,
+			Subnet4: record.Subnet4, Subnet6: record.Subnet6, Domain: record.Domain}
+		pack.addSelectors(set)
+
+		if prev, ok := dump.ContentIdx[id]; ok {
+			prev.addSelectors(set)
+		}
+	}
+
+	// records to purge.
+	for id, pack := range dump.ContentIdx {
+		if !s.journal.Has(id) {
+			pack.addSelectors(set)
+		}
+	}
+
+	indexed := make(map[Selector]bool, len(set))
+	for sel := range set {
+		indexed[sel] = dump.indexed(sel)
+	}
+
+	return indexed
+}
+
+// selectorChanges - selectors of touchedSelectors which appeared and disappeared, ordered by kind and value.
+// Call it under lock after the stage is applied.
+func (dump *Dump) selectorChanges(before map[Selector]bool) (added, removed []Selector) {
+	for sel, was := range before {
+		switch now := dump.indexed(sel); {
+		case now && !was:
+			added = append(added, sel)
+		case !now && was:
+			removed = append(removed, sel)
+		}
+	}
+
+	sortSelectors(added)
+	sortSelectors(removed)
+
+	return added, removed
+}
+
+// indexed - some record has the normalized selector, call it under lock.
+func (dump *Dump) indexed(sel Selector) bool {
+	return len(dump.selectorRecords(sel)) > 0
+}
+
+// DeltaSince - changes of the index since the generation, all selectors and records with resync
+// if the generation is 0 or its changes are not retained. false if the dump is not loaded.
+func DeltaSince(from int64) (*pbv2.Delta, bool) {
+	changes, ok := CurrentDump.ChangesSince(from)
+
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	if CurrentDump.utime == 0 {
+		return nil, false
+	}
+
+	if !ok || from <= 0 {
+		return CurrentDump.fullDelta(), true
+	}
+
+	return CurrentDump.newDelta(from, changes), true
+}
+
+// generationDelta - delta of one generation for Watch.
+func generationDelta(c *GenerationChanges) *pbv2.Delta {
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	return CurrentDump.newDelta(c.Generation-1, []*GenerationChanges{c})
+}
+
+// newDelta - net changes of the consecutive generations after from, oldest first: a selector or
+// a record changed back and forth is left out. Records are taken as they are now. Call it under read lock.
+func (dump *Dump) newDelta(from int64, changes []*GenerationChanges) *pbv2.Delta {
+	delta := &pbv2.Delta{FromGeneration: from, Generation: from, RegistryUpdateTime: dump.utime}
+
+	// whether the selector is indexed before the first change and after the last one.
+	type state struct{ before, after bool }
+
+	selectors := make(map[Selector]*state)
+
+	set := func(sel Selector, indexed bool) {
+		st, ok := selectors[sel]
+		if !ok {
+			st = &state{before: !indexed}
+			selectors[sel] = st
+		}
+
+		st.after = indexed
+	}
+
+	// ids of changed records, true if the replica has not got the record before from.
+	fresh := make(map[int32]bool)
+
+	touch := func(ids []int32, added bool) {
+		for _, id := range ids {
+			if _, ok := fresh[id]; !ok {
+				fresh[id] = added
+			}
+		}
+	}
+
+	for _, c := range changes {
+		for _, sel := range c.SelectorsAdded {
+			set(sel, true)
+		}
+
+		for _, sel := range c.SelectorsRemoved {
+			set(sel, false)
+		}
+
+		touch(c.Added, true)
+		touch(c.Updated, false)
+		touch(c.Removed, false)
+
+		delta.Generation, delta.RegistryUpdateTime = c.Generation, c.UpdateTime
+	}
+
+	var added, removed []Selector
+
+	for sel, st := range selectors {
+		switch {
+		case st.after && !st.before:
+			added = append(added, sel)
+		case !st.after && st.before:
+			removed = append(removed, sel)
+		}
+	}
+
+	delta.Indexes = newPbIndexDeltas(added, removed)
+
+	ids := make([]int32, 0, len(fresh))
+	for id := range fresh {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		if pack, ok := dump.ContentIdx[id]; ok {
+			delta.Records = append(delta.Records, newPbPackedRecord(pack))
+		} else if !fresh[id] {
+			delta.Removed = append(delta.Removed, id)
+		}
+	}
+
+	return delta
+}
+
+// fullDelta - all selectors and records of the dump, call it under read lock.
+func (dump *Dump) fullDelta() *pbv2.Delta {
+	delta := &pbv2.Delta{Generation: dump.generation, RegistryUpdateTime: dump.utime, Resync: true}
+
+	all := dump.Selectors()
+
+	added := make([]Selector, 0, len(all))
+	for sel := range all {
+		added = append(added, sel)
+	}
+
+	delta.Indexes = newPbIndexDeltas(added, nil)
+
+	ids := make([]int32, 0, len(dump.ContentIdx))
+	for id := range dump.ContentIdx {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	delta.Records = make([]*pbv2.PackedRecord, 0, len(ids))
+
+	for _, id := range ids {
+		delta.Records = append(delta.Records, newPbPackedRecord(dump.ContentIdx[id]))
+	}
+
+	return delta
+}
+
+// newPbIndexDeltas - selectors grouped by index in the deltaIndexes order, ordered by value.
+func newPbIndexDeltas(added, removed []Selector) []*pbv2.IndexDelta {
+	sortSelectors(added)
+	sortSelectors(removed)
+
+	byIndex := make(map[string]*pbv2.IndexDelta)
+
+	get := func(kind string) *pbv2.IndexDelta {
+		idx, ok := byIndex[kind]
+		if !ok {
+			idx = &pbv2.IndexDelta{Index: kind}
+			byIndex[kind] = idx
+		}
+
+		return idx
+	}
+
+	for _, sel := range added {
+		idx := get(sel.Kind)
+		idx.Added = append(idx.Added, sel.Value)
+	}
+
+	for _, sel := range removed {
+		idx := get(sel.Kind)
+		idx.Removed = append(idx.Removed, sel.Value)
+	}
+
+	var list []*pbv2.IndexDelta
+
+	for _, kind := range deltaIndexes {
+		if idx, ok := byIndex[kind]; ok {
+			list = append(list, idx)
+		}
+	}
+
+	return list
+}
+
+// newPbPackedRecord - the record as it is stored, the payload is rebuilt if payloads are not kept.
+func newPbPackedRecord(pack *PackedContent) *pbv2.PackedRecord {
+	v := &pbv2.PackedRecord{
+		Id:                 pack.ID,
+		RegistryUpdateTime: pack.RegistryUpdateTime,
+		Decision:           pack.Decision,
+		Payload:            pack.Payload,
+		Compressed:         pack.Compressed,
+	}
+
+	if pack.Payload == nil {
+		if record, err := pack.Record(); err == nil {
+			v.Payload, _ = json.Marshal(record)
+		}
+	}
+
+	return v
+}
+
+// handleDelta - GET /delta?from=N: the Delta since the generation N as protobuf, all records without from.
+func handleDelta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var from int64
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		var err error
+
+		from, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || from < 0 {
+			http.Error(w, SrvBadGeneration, http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	delta, ok := DeltaSince(from)
+	if !ok {
+		http.Error(w, SrvDataNotReady, http.StatusServiceUnavailable)
+
+		return
+	}
+
+	body, err := proto.Marshal(delta)
+	if err != nil {
+		logger.Error.Printf("Can't marshal delta: %s\n", err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", deltaContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+
+	if r.Method == http.MethodGet {
+		w.Write(body)
+	}
+}
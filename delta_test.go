@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	pbv2 "github.com/usher2/u2ckdump/msg/v2"
+)
+
+// TestDeltaSince tests net selector and record changes between generations for replicas.
+func TestDeltaSince(t *testing.T) {
+	defer func(dump *Dump, keep int) { CurrentDump, WatchKeep = dump, keep }(CurrentDump, WatchKeep)
+
+	CurrentDump, WatchKeep = NewDump(), 2
+
+	if _, ok := DeltaSince(0); ok {
+		t.Errorf("Delta of an empty dump\n")
+	}
+
+	// drop 555, add 666 with the same selectors and change a URL of 111.
+	xml := strings.Replace(xml01, `<content id="555"`, `<content id="666"`, 1)
+	xml = strings.Replace(xml, "www.e01.tld", "www.e01-changed.tld", 1)
+
+	for _, dump := range []string{xml01, xml} {
+		if err := Parse(strings.NewReader(dump)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	delta, ok := DeltaSince(1)
+	if !ok || delta.GetResync() || delta.GetFromGeneration() != 1 || delta.GetGeneration() != 2 {
+		t.Fatalf("Delta: %v %t\n", delta, ok)
+	}
+
+	want := []*pbv2.IndexDelta{{
+		Index:   SelectorURL,
+		Added:   []string{NormalizeURL("https://www.e01-changed.tld/sex")},
+		Removed: []string{NormalizeURL("https://www.e01.tld/sex")},
+	}}
+	if len(delta.GetIndexes()) != 1 || !proto.Equal(delta.GetIndexes()[0], want[0]) {
+		t.Errorf("Indexes: %v\n", delta.GetIndexes())
+	}
+
+	if ids := packedRecordIDs(delta); !reflect.DeepEqual(ids, []int32{111, 666}) || !reflect.DeepEqual(delta.GetRemoved(), []int32{555}) {
+		t.Errorf("Records: %v, removed: %v\n", ids, delta.GetRemoved())
+	}
+
+	var record Content
+	if err := json.Unmarshal(payloadBytes(111, delta.GetRecords()[0].GetPayload(), delta.GetRecords()[0].GetCompressed()), &record); err != nil || record.ID != 111 {
+		t.Errorf("Payload: %+v %v\n", record, err)
+	}
+
+	// changes back and forth are left out: 666 is new to the replica, 555 is restored.
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	delta, _ = DeltaSince(1)
+	if len(delta.GetIndexes()) != 0 || len(delta.GetRemoved()) != 0 || !reflect.DeepEqual(packedRecordIDs(delta), []int32{111, 555}) {
+		t.Errorf("Net delta: %v\n", delta)
+	}
+
+	// generation 0 is before the initial load, which is not retained.
+	full, _ := DeltaSince(0)
+	if !full.GetResync() || len(full.GetRecords()) != 5 || full.GetIndexes()[0].GetIndex() != SelectorIP4 {
+		t.Errorf("Full delta: resync %t, %d records\n", full.GetResync(), len(full.GetRecords()))
+	}
+
+	rec := httptest.NewRecorder()
+	handleDelta(rec, httptest.NewRequest(http.MethodGet, "/delta?from=x", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Bad from: %d\n", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handleDelta(rec, httptest.NewRequest(http.MethodGet, "/delta?from=2", nil))
+
+	got := &pbv2.Delta{}
+	if err := proto.Unmarshal(rec.Body.Bytes(), got); err != nil || rec.Header().Get("Content-Type") != deltaContentType || got.GetGeneration() != 3 {
+		t.Errorf("/delta: %d %v %v\n", rec.Code, got, err)
+	}
+}
+
+func packedRecordIDs(delta *pbv2.Delta) []int32 {
+	var ids []int32
+
+	for _, rec := range delta.GetRecords() {
+		ids = append(ids, rec.GetId())
+	}
+
+	return ids
+}
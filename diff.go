@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Selector kinds.
+const (
+	SelectorIP4     = "ip4"
+	SelectorIP6     = "ip6"
+	SelectorSubnet4 = "subnet4"
+	SelectorSubnet6 = "subnet6"
+	SelectorDomain  = "domain"
+	SelectorURL     = "url"
+)
+
+// Selector - one enforcement selector in the normalized form.
+type Selector struct {
+	Kind  string
+	Value string
+}
+
+// SelectorSet - set of selectors.
+type SelectorSet map[Selector]Nothing
+
+// Selectors - all normalized selectors of the snapshot, records with broken payloads are skipped.
+func (snap *Snapshot) Selectors() SelectorSet {
+	set := make(SelectorSet)
+
+	for _, rec := range snap.Contents {
+		record := Content{}
+		if err := json.Unmarshal(rec.PayloadBytes(), &record); err != nil {
+			logger.Error.Printf("Can't decode snapshot record: %d: %s\n", rec.ID, err.Error())
+
+			continue
+		}
+
+		pack := PackedContent{URL: record.URL, IP4: record.IP4, IP6: record.IP6,
+			Subnet4: record.Subnet4, Subnet6: record.Subnet6, Domain: record.Domain}
+		pack.addSelectors(set)
+	}
+
+	return set
+}
+
+// Selectors - all normalized selectors of the dump, call it under lock.
+func (dump *Dump) Selectors() SelectorSet {
+	set := make(SelectorSet)
+
+	for _, pack := range dump.ContentIdx {
+		pack.addSelectors(set)
+	}
+
+	return set
+}
+
+// addSelectors - add normalized selectors of the record to the set.
+func (pack *PackedContent) addSelectors(set SelectorSet) {
+	for _, ip4 := range pack.IP4 {
+		set[Selector{SelectorIP4, ip4.IP4.String()}] = Nothing{}
+	}
+
+	for _, ip6 := range pack.IP6 {
+		set[Selector{SelectorIP6, ip6.IP6.String()}] = Nothing{}
+	}
+
+	for _, subnet4 := range pack.Subnet4 {
+		set[Selector{SelectorSubnet4, subnet4.Subnet4.String()}] = Nothing{}
+	}
+
+	for _, subnet6 := range pack.Subnet6 {
+		set[Selector{SelectorSubnet6, subnet6.Subnet6.String()}] = Nothing{}
+	}
+
+	for _, domain := range pack.Domain {
+		set[Selector{SelectorDomain, NormalizeDomain(domain.Domain)}] = Nothing{}
+	}
+
+	for _, u := range pack.URL {
+		set[Selector{SelectorURL, NormalizeURL(u.URL)}] = Nothing{}
+	}
+}
+
+// selectorRecords - records of the normalized selector, call it under lock.
+func (dump *Dump) selectorRecords(sel Selector) ArrayIntSet {
+	switch sel.Kind {
+	case SelectorIP4, SelectorIP6, SelectorSubnet4, SelectorSubnet6:
+		return dump.selectorIDs(sel.Value)
+	case SelectorDomain:
+		return dump.domainIdx[sel.Value]
+	case SelectorURL:
+		return dump.urlIdx[sel.Value]
+	}
+
+	return nil
+}
+
+// DiffSelectors - selectors added and removed between two sets, ordered by kind and value.
+func DiffSelectors(from, to SelectorSet) (added, removed []Selector) {
+	for sel := range to {
+		if _, ok := from[sel]; !ok {
+			added = append(added, sel)
+		}
+	}
+
+	for sel := range from {
+		if _, ok := to[sel]; !ok {
+			removed = append(removed, sel)
+		}
+	}
+
+	sortSelectors(added)
+	sortSelectors(removed)
+
+	return added, removed
+}
+
+func sortSelectors(a []Selector) {
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].Kind != a[j].Kind {
+			return a[i].Kind < a[j].Kind
+		}
+
+		return a[i].Value < a[j].Value
+	})
+}
+
+// int2Ip4 - uint32 to dotted IPv4 string.
+func int2Ip4(ip uint32) string {
+	return fmt.Sprintf("%d.%d.%d.%d", byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip))
+}
+
+// GenerationSelectors - selectors of the current or of a retained generation.
+func GenerationSelectors(dir string, generation int64) (SelectorSet, error) {
+	CurrentDump.RLock()
+
+	if generation == CurrentDump.generation {
+		set := CurrentDump.Selectors()
+
+		CurrentDump.RUnlock()
+
+		return set, nil
+	}
+
+	CurrentDump.RUnlock()
+
+	snap, err := ReadSnapshot(SnapshotFilename(dir, generation))
+	if err != nil {
+		return nil, fmt.Errorf("generation %d: %w", generation, err)
+	}
+
+	return snap.Selectors(), nil
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// DNSBL answers: 127.0.0.x of the most severe status of the matched records.
+const (
+	DNSBLListed  = 2 // blocked itself: an IP of an ip record, a domain of a domain or mask record.
+	DNSBLCovered = 3 // a subnet of an ip record or a mask of a parent domain covers it.
+	DNSBLPartial = 4 // only in records of other block types, e.g. a host of URL blocks.
+)
+
+const (
+	dnsblTTL       = 60  // seconds.
+	dnsblMaxIDs    = 40  // record ids in TXT, an answer has to fit dnsblMaxAnswer.
+	dnsblMaxTXT    = 255 // TXT string length.
+	dnsblMaxAnswer = 512 // UDP message size without EDNS.
+)
+
+var errDNSBLZone = errors.New("not in the zone")
+
+// DNSBL - DNS responder of the zone like a DNS blocklist: <reversed IPv4 octets>.<zone>,
+// <reversed IPv6 nibbles>.<zone> and <domain>.<zone> are A 127.0.0.x if blocked and NXDOMAIN if not,
+// TXT carries the record ids.
+type DNSBL struct {
+	zone string // lower case, with the leading and the trailing dots.
+}
+
+// NewDNSBL - responder of the zone, e.g. "blocked.example".
+func NewDNSBL(zone string) *DNSBL {
+	return &DNSBL{zone: "." + strings.Trim(strings.ToLower(zone), ".") + "."}
+}
+
+// Lookup - the status and the record ids of the query name, 0 if it is not blocked.
+func (b *DNSBL) Lookup(name string) (int, []int32, error) {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	if !strings.HasSuffix(name, b.zone) || len(name) == len(b.zone) {
+		return 0, nil, errDNSBLZone
+	}
+
+	query := strings.TrimSuffix(name, b.zone)
+
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	if CurrentDump.utime == 0 {
+		return 0, nil, errors.New(SrvDataNotReady)
+	}
+
+	if ip, ok := dnsblAddr(query); ok {
+		if CurrentExclusions().IP(ip.AsSlice()) != "" {
+			return 0, nil, nil
+		}
+
+		status, ids := CurrentDump.dnsblIP(ip)
+
+		return status, ids, nil
+	}
+
+	domain := NormalizeDomain(query)
+	if CurrentExclusions().Domain(domain) != "" {
+		return 0, nil, nil
+	}
+
+	status, ids := CurrentDump.dnsblDomain(domain)
+
+	return status, ids, nil
+}
+
+// dnsblAddr - the address of reversed IPv4 octets or IPv6 nibbles.
+func dnsblAddr(query string) (netip.Addr, bool) {
+	labels := strings.Split(query, ".")
+
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+
+	switch len(labels) {
+	case 4:
+		ip, err := netip.ParseAddr(strings.Join(labels, "."))
+		if err != nil || !ip.Is4() {
+			return netip.Addr{}, false
+		}
+
+		return ip, true
+	case 32:
+		var b [16]byte
+
+		for i, label := range labels {
+			n, err := strconv.ParseUint(label, 16, 8)
+			if err != nil || len(label) != 1 {
+				return netip.Addr{}, false
+			}
+
+			b[i/2] |= byte(n) << (4 * (1 - i%2))
+		}
+
+		return netip.AddrFrom16(b), true
+	}
+
+	return netip.Addr{}, false
+}
+
+// dnsblStatus - status and records matched by a DNSBL query.
+type dnsblStatus struct {
+	status int
+	ids    map[int32]Nothing
+}
+
+// add - records of the selector, the status of a record is listed if it has the block type, partial if not.
+func (s *dnsblStatus) add(dump *Dump, ids ArrayIntSet, status int, blockTypes ...int32) {
+	for _, id := range ids {
+		pack, ok := dump.ContentIdx[id]
+		if !ok {
+			continue
+		}
+
+		st := DNSBLPartial
+
+		for _, bt := range blockTypes {
+			if pack.BlockType == bt {
+				st = status
+			}
+		}
+
+		if s.status == 0 || st < s.status {
+			s.status = st
+		}
+
+		s.ids[id] = Nothing{}
+	}
+}
+
+// result - the status and sorted record ids.
+func (s *dnsblStatus) result() (int, []int32) {
+	ids := make([]int32, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return s.status, ids
+}
+
+// dnsblIP - status and records of the IP, call it under read lock.
+func (dump *Dump) dnsblIP(ip netip.Addr) (int, []int32) {
+	s := &dnsblStatus{ids: make(map[int32]Nothing)}
+
+	s.add(dump, dump.ipIDs(ip), DNSBLListed, BlockTypeIP)
+
+	for _, subnet := range dump.containingSubnets(ip, -1) {
+		s.add(dump, dump.subnetIDs(subnet), DNSBLCovered, BlockTypeIP)
+	}
+
+	return s.result()
+}
+
+// dnsblDomain - status and records of the normalized domain, call it under read lock.
+// Masks of parent domains cover it, other records of parent domains do not.
+func (dump *Dump) dnsblDomain(domain string) (int, []int32) {
+	s := &dnsblStatus{ids: make(map[int32]Nothing)}
+
+	s.add(dump, dump.domainIdx[domain], DNSBLListed, BlockTypeDomain, BlockTypeMask)
+
+	for _, parent := range parentDomains(domain) {
+		var masks ArrayIntSet
+
+		for _, id := range dump.domainIdx[parent] {
+			if pack, ok := dump.ContentIdx[id]; ok && pack.BlockType == BlockTypeMask {
+				masks = append(masks, id)
+			}
+		}
+
+		s.add(dump, masks, DNSBLCovered, BlockTypeMask)
+	}
+
+	return s.result()
+}
+
+// dnsblTXT - TXT strings of the record ids: "ids=1,2,3", at most dnsblMaxIDs of them.
+func dnsblTXT(ids []int32) []string {
+	var more int
+
+	if len(ids) > dnsblMaxIDs {
+		more, ids = len(ids)-dnsblMaxIDs, ids[:dnsblMaxIDs]
+	}
+
+	var (
+		txt []string
+		sb  strings.Builder
+	)
+
+	sb.WriteString("ids=")
+
+	for i, id := range ids {
+		s := strconv.Itoa(int(id))
+		if i > 0 {
+			s = "," + s
+		}
+
+		if sb.Len()+len(s) > dnsblMaxTXT {
+			txt = append(txt, sb.String())
+			sb.Reset()
+		}
+
+		sb.WriteString(s)
+	}
+
+	txt = append(txt, sb.String())
+
+	if more > 0 {
+		txt = append(txt, fmt.Sprintf("more=%d", more))
+	}
+
+	return txt
+}
+
+// Answer - the response to the query message, nil if it is not a query.
+func (b *DNSBL) Answer(msg []byte) []byte {
+	var p dnsmessage.Parser
+
+	h, err := p.Start(msg)
+	if err != nil || h.Response {
+		return nil
+	}
+
+	q, err := p.Question()
+	if err != nil {
+		return nil
+	}
+
+	resp := dnsmessage.Header{ID: h.ID, Response: true, OpCode: h.OpCode, RecursionDesired: h.RecursionDesired}
+
+	var (
+		status int
+		ids    []int32
+	)
+
+	switch {
+	case h.OpCode != 0:
+		resp.RCode = dnsmessage.RCodeNotImplemented
+	case q.Class != dnsmessage.ClassINET:
+		resp.RCode = dnsmessage.RCodeRefused
+	default:
+		status, ids, err = b.Lookup(q.Name.String())
+
+		switch {
+		case err == errDNSBLZone:
+			resp.RCode = dnsmessage.RCodeRefused
+		case err != nil:
+			resp.RCode = dnsmessage.RCodeServerFailure
+		case status == 0:
+			resp.Authoritative, resp.RCode = true, dnsmessage.RCodeNameError
+		default:
+			resp.Authoritative = true
+		}
+	}
+
+	builder := dnsmessage.NewBuilder(make([]byte, 0, dnsblMaxAnswer), resp)
+	builder.EnableCompression()
+
+	if err := b.build(&builder, q, status, ids); err != nil {
+		logger.Error.Printf("Can't build DNSBL answer: %s: %s\n", q.Name.String(), err.Error())
+
+		return nil
+	}
+
+	answer, err := builder.Finish()
+	if err != nil {
+		logger.Error.Printf("Can't build DNSBL answer: %s: %s\n", q.Name.String(), err.Error())
+
+		return nil
+	}
+
+	return answer
+}
+
+// build - the question and A and TXT answers of the blocked name.
+func (b *DNSBL) build(builder *dnsmessage.Builder, q dnsmessage.Question, status int, ids []int32) error {
+	if err := builder.StartQuestions(); err != nil {
+		return err
+	}
+
+	if err := builder.Question(q); err != nil {
+		return err
+	}
+
+	if status == 0 {
+		return nil
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return err
+	}
+
+	rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: dnsblTTL}
+
+	if q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeALL {
+		if err := builder.AResource(rh, dnsmessage.AResource{A: [4]byte{127, 0, 0, byte(status)}}); err != nil {
+			return err
+		}
+	}
+
+	if q.Type == dnsmessage.TypeTXT || q.Type == dnsmessage.TypeALL {
+		if err := builder.TXTResource(rh, dnsmessage.TXTResource{TXT: dnsblTXT(ids)}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ServeDNSBL - answer UDP queries until kill.
+func ServeDNSBL(conn net.PacketConn, b *DNSBL, done chan<- struct{}, kill <-chan struct{}) {
+	go func() {
+		<-kill
+
+		conn.Close()
+	}()
+
+	defer close(done)
+
+	buf := make([]byte, 1500)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error.Printf("DNSBL failed to read: %s\n", err.Error())
+			}
+
+			return
+		}
+
+		if answer := b.Answer(buf[:n]); answer != nil {
+			if _, err := conn.WriteTo(answer, addr); err != nil {
+				logger.Debug.Printf("DNSBL can't answer %s: %s\n", addr.String(), err.Error())
+			}
+		}
+	}
+}
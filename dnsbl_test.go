@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const dnsblDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <ip>10.0.0.1</ip>
+        <ipSubnet>10.1.0.0/16</ipSubnet>
+        <ipv6>fd00::1</ipv6>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <domain><![CDATA[a.tld]]></domain>
+</content>
+<content id="3" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain-mask" hash="3">
+        <decision date="2000-01-01" number="3" org="ONE"/>
+        <domain><![CDATA[*.m.tld]]></domain>
+</content>
+<content id="4" includeTime="2001-01-01T01:01:01" entryType="1" hash="4">
+        <decision date="2000-01-01" number="4" org="ONE"/>
+        <url><![CDATA[http://u.tld/x]]></url>
+        <domain><![CDATA[u.tld]]></domain>
+        <ip>10.0.0.2</ip>
+        <ip>10.0.0.1</ip>
+</content>
+</reg:register>`
+
+// TestDNSBL tests DNSBL statuses of IPs and domains and the UDP answers.
+func TestDNSBL(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	b := NewDNSBL("Blocked.Example.")
+
+	if _, _, err := b.Lookup("1.0.0.10.blocked.example"); err == nil {
+		t.Errorf("Not ready: no error\n")
+	}
+
+	if err := Parse(strings.NewReader(dnsblDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]struct {
+		status int
+		ids    []int32
+	}{
+		"1.0.0.10": {DNSBLListed, []int32{1, 4}},
+		"5.0.1.10": {DNSBLCovered, []int32{1}},
+		"2.0.0.10": {DNSBLPartial, []int32{4}},
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f": {DNSBLListed, []int32{1}},
+		"A.tld":   {DNSBLListed, []int32{2}},
+		"m.tld":   {DNSBLListed, []int32{3}},
+		"x.m.tld": {DNSBLCovered, []int32{3}},
+		"x.a.tld": {0, []int32{}},
+		"u.tld":   {DNSBLPartial, []int32{4}},
+		"9.9.9.9": {0, []int32{}},
+	} {
+		status, ids, err := b.Lookup(name + ".blocked.example.")
+		if err != nil || status != want.status || !reflect.DeepEqual(ids, want.ids) {
+			t.Errorf("%s: %d %v %v, want %d %v\n", name, status, ids, err, want.status, want.ids)
+		}
+	}
+
+	if _, _, err := b.Lookup("1.0.0.10.other.example."); err != errDNSBLZone {
+		t.Errorf("Other zone: %v\n", err)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, kill := make(chan struct{}), make(chan struct{})
+	go ServeDNSBL(conn, b, done, kill)
+
+	defer func() {
+		close(kill)
+		<-done
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	ask := func(name string, qtype dnsmessage.Type) dnsmessage.Message {
+		q := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: 7, RecursionDesired: true},
+			Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+		}
+
+		packed, err := q.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := client.Write(packed); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, 1500)
+
+		n, err := client.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var m dnsmessage.Message
+		if err := m.Unpack(buf[:n]); err != nil {
+			t.Fatal(err)
+		}
+
+		return m
+	}
+
+	m := ask("1.0.0.10.blocked.example.", dnsmessage.TypeA)
+	if m.ID != 7 || m.RCode != dnsmessage.RCodeSuccess || len(m.Answers) != 1 ||
+		m.Answers[0].Body.(*dnsmessage.AResource).A != [4]byte{127, 0, 0, DNSBLListed} {
+		t.Errorf("A: %+v\n", m)
+	}
+
+	m = ask("1.0.0.10.blocked.example.", dnsmessage.TypeTXT)
+	if len(m.Answers) != 1 || !reflect.DeepEqual(m.Answers[0].Body.(*dnsmessage.TXTResource).TXT, []string{"ids=1,4"}) {
+		t.Errorf("TXT: %+v\n", m)
+	}
+
+	if m = ask("9.9.9.9.blocked.example.", dnsmessage.TypeA); m.RCode != dnsmessage.RCodeNameError {
+		t.Errorf("NXDOMAIN: %+v\n", m)
+	}
+
+	if m = ask("example.com.", dnsmessage.TypeA); m.RCode != dnsmessage.RCodeRefused {
+		t.Errorf("REFUSED: %+v\n", m)
+	}
+}
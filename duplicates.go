@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// duplicatesReportFilename - selectors shared by many records, written after every parse.
+const duplicatesReportFilename = "duplicates.csv"
+
+// duplicatesReportColumns - CSV header of the duplicates report.
+var duplicatesReportColumns = []string{"kind", "value", "records", "ids"}
+
+// DuplicatesReportMin - selectors of at least this many records are written to duplicates.csv
+// after every parse, 0 disables. It is set once at startup.
+var DuplicatesReportMin int
+
+// DuplicateSelector - selector shared by records with their ids.
+type DuplicateSelector struct {
+	HotSelector
+	IDs []int32
+}
+
+// DuplicateSelectors - selectors of at least minCount records, most referenced first, with their records.
+// Call it under read lock.
+func (dump *Dump) DuplicateSelectors(minCount int) []DuplicateSelector {
+	hot := dump.hot.top("", minCount, dump.hot.total("", minCount))
+
+	list := make([]DuplicateSelector, 0, len(hot))
+
+	for _, h := range hot {
+		list = append(list, DuplicateSelector{HotSelector: h, IDs: append([]int32(nil), dump.selectorRecords(h.Sel)...)})
+	}
+
+	return list
+}
+
+// WriteDuplicatesReport - replace the duplicates report CSV in the dir, the first line is "# <provenance>".
+func WriteDuplicatesReport(dir string, list []DuplicateSelector, prov Provenance) error {
+	filename := dir + "/" + duplicatesReportFilename
+	tmpfilename := filename + "-tmp"
+
+	f, err := os.Create(tmpfilename)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	fmt.Fprintf(f, "# %s\n", prov)
+
+	w := csv.NewWriter(f)
+	w.Write(duplicatesReportColumns)
+
+	for _, d := range list {
+		ids := make([]string, 0, len(d.IDs))
+		for _, id := range d.IDs {
+			ids = append(ids, strconv.Itoa(int(id)))
+		}
+
+		w.Write([]string{d.Sel.Kind, d.Sel.Value, strconv.Itoa(d.Count), strings.Join(ids, " ")})
+	}
+
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		f.Close()
+
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tmpfilename, filename); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// ExportDuplicatesReport - write the duplicates report of the new generation, if enabled, the selectors
+// written and true if it is written.
+func ExportDuplicatesReport(dir string) (int, bool) {
+	if DuplicatesReportMin <= 0 {
+		return 0, false
+	}
+
+	CurrentDump.RLock()
+	list := CurrentDump.DuplicateSelectors(DuplicatesReportMin)
+	CurrentDump.RUnlock()
+
+	logger.Info.Printf("Selectors of %d and more records: %d\n", DuplicatesReportMin, len(list))
+
+	if err := WriteDuplicatesReport(dir, list, CurrentDump.Provenance()); err != nil {
+		logger.Error.Printf("Can't save %s: %s\n", duplicatesReportFilename, err.Error())
+
+		return 0, false
+	}
+
+	return len(list), true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestDuplicatesReport tests the report of selectors shared by records, URLs included.
+func TestDuplicatesReport(t *testing.T) {
+	defer func(dump *Dump, n int) { CurrentDump, DuplicatesReportMin = dump, n }(CurrentDump, DuplicatesReportMin)
+
+	CurrentDump, DuplicatesReportMin = NewDump(), 3
+
+	// 222 gets a URL of 111.
+	xml := strings.Replace(xml01, "<domain><![CDATA[www.e02.tld]]></domain>", "<url><![CDATA[http://www.e01.tld/slip]]></url>\n<domain><![CDATA[www.e02.tld]]></domain>", 1)
+	if err := Parse(strings.NewReader(xml)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	if _, ok := ExportDuplicatesReport(dir); !ok {
+		t.Fatalf("Not exported\n")
+	}
+
+	dat, err := os.ReadFile(dir + "/" + duplicatesReportFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(dat)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "# ") || lines[1] != "kind,value,records,ids" ||
+		lines[2] != "ip6,fdaa:f::100,5,111 222 333 444 555" || lines[3] != "ip4,192.168.0.100,3,111 222 333" {
+		t.Errorf("Report:\n%s", dat)
+	}
+
+	CurrentDump.RLock()
+	hot, total := CurrentDump.HotSelectors(SelectorURL, 0, 0, 0)
+	CurrentDump.RUnlock()
+
+	if total != 1 || hot[0].Sel.Value != NormalizeURL("http://www.e01.tld/slip") || hot[0].Count != 2 {
+		t.Errorf("URLs: %v %d\n", hot, total)
+	}
+}
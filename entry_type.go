@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Registry entry types, the legal ground of the blocking, see EntryType of the v2 API.
+// Other values of the registry pass through as numbers.
+const (
+	EntryTypeEAIS          = 1 // the unified register of prohibited information, 149-FZ art. 15.1.
+	EntryTypeCopyright     = 2 // copyright infringement, art. 15.2.
+	EntryType398FZ         = 3 // extremism and calls to riots, 398-FZ, art. 15.3.
+	EntryType97FZ          = 4 // information dissemination organizers, 97-FZ, art. 15.4.
+	EntryTypeCopyrightPerm = 5 // permanent blocking of repeated copyright infringement, art. 15.6.
+	EntryTypePersonalData  = 6 // personal data violators, 152-FZ, art. 15.5.
+)
+
+// entryTypeNames - names of the entry types in filters and queries.
+var entryTypeNames = map[string]int32{
+	"eais":           EntryTypeEAIS,
+	"copyright":      EntryTypeCopyright,
+	"398-fz":         EntryType398FZ,
+	"97-fz":          EntryType97FZ,
+	"copyright-perm": EntryTypeCopyrightPerm,
+	"personal-data":  EntryTypePersonalData,
+}
+
+// ParseEntryType - entry type of the name or the number, unknown numbers pass through.
+func ParseEntryType(value string) (int32, error) {
+	if entryType, ok := entryTypeNames[value]; ok {
+		return entryType, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad entry type: %s", value)
+	}
+
+	return int32(n), nil
+}
+
+// EntryTypeName - name of the entry type, the number if it is unknown.
+func EntryTypeName(entryType int32) string {
+	for name, et := range entryTypeNames {
+		if et == entryType {
+			return name
+		}
+	}
+
+	return strconv.Itoa(int(entryType))
+}
+
+// EntryTypeCount - number of records of the entry type.
+type EntryTypeCount struct {
+	EntryType int32
+	Count     int
+}
+
+// EntryTypeCounts - entry types of the records ordered by the type, call it under read lock.
+func (dump *Dump) EntryTypeCounts() []EntryTypeCount {
+	counts := make(map[int32]int)
+
+	for _, pack := range dump.ContentIdx {
+		counts[pack.EntryType]++
+	}
+
+	result := make([]EntryTypeCount, 0, len(counts))
+
+	for entryType, n := range counts {
+		result = append(result, EntryTypeCount{EntryType: entryType, Count: n})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].EntryType < result[j].EntryType })
+
+	return result
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestEntryTypes tests entry type names, counts and multi-value filters of exports and queries.
+func TestEntryTypes(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	for value, expected := range map[string]int32{"eais": EntryTypeEAIS, "personal-data": EntryTypePersonalData, "5": EntryTypeCopyrightPerm, "42": 42} {
+		if entryType, err := ParseEntryType(value); err != nil || entryType != expected {
+			t.Errorf("%q: %d, %v", value, entryType, err)
+		}
+	}
+
+	if _, err := ParseEntryType("gambling"); err == nil {
+		t.Errorf("unknown name is parsed")
+	}
+
+	s := &server{}
+
+	if resp, _ := s.ListEntryTypes(context.Background(), &pb.EntryTypeRequest{}); resp.GetError() != SrvDataNotReady {
+		t.Errorf("Not ready: %v", resp)
+	}
+
+	// 222 is a permanent copyright block, 333 has an entry type unknown yet.
+	xml := strings.Replace(xml01, `<content id="222" includeTime="2001-01-01T02:02:02" entryType="1"`,
+		`<content id="222" includeTime="2001-01-01T02:02:02" entryType="5"`, 1)
+	xml = strings.Replace(xml, `<content id="333" includeTime="2001-01-01T03:03:03" entryType="1"`,
+		`<content id="333" includeTime="2001-01-01T03:03:03" entryType="42"`, 1)
+
+	if err := Parse(strings.NewReader(xml)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, _ := s.ListEntryTypes(context.Background(), &pb.EntryTypeRequest{})
+
+	var counts []string
+	for _, c := range resp.GetEntryTypes() {
+		counts = append(counts, fmt.Sprintf("%d %s %d", c.GetEntryType(), c.GetName(), c.GetCount()))
+	}
+
+	if expected := []string{"1 eais 3", "5 copyright-perm 1", "42 42 1"}; resp.GetError() != "" || !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Counts: %v, expected %v", counts, expected)
+	}
+
+	filter, err := ParseExportFilter("entry=copyright-perm,42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exported []int32
+
+	for _, id := range []int32{111, 222, 333, 444, 555} {
+		if filter.Match(CurrentDump.ContentIdx[id]) {
+			exported = append(exported, id)
+		}
+	}
+
+	if !reflect.DeepEqual(exported, []int32{222, 333}) {
+		t.Errorf("Exported: %v", exported)
+	}
+
+	query, err := ParseQuery("entry = eais and not entry = 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := query.Records(CurrentDump); !reflect.DeepEqual(ids, []int32{111, 444, 555}) {
+		t.Errorf("Query: %v", ids)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yl2chen/cidranger"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// ExclusionSource - file name or http(s) URL of the local exclusion list, empty disables.
+// It is set once at startup.
+var ExclusionSource string
+
+// Exclusions - local allowlist of IPs, subnets and domains which are never blocked,
+// even if the registry says so. A domain covers its subdomains.
+type Exclusions struct {
+	version int64 // bumped on every applied change, part of the search cache epoch.
+	sum     [sha256.Size]byte
+	nets    cidranger.Ranger
+	domains StringMap
+	count   int
+}
+
+var (
+	currentExclusions atomic.Pointer[Exclusions]
+	exclusionsVersion atomic.Int64
+)
+
+// CurrentExclusions - applied exclusion list, nil if none.
+func CurrentExclusions() *Exclusions {
+	return currentExclusions.Load()
+}
+
+// ParseExclusions - one IP, CIDR or domain per line, # starts a comment.
+func ParseExclusions(r io.Reader) (*Exclusions, error) {
+	e := &Exclusions{
+		nets:    cidranger.NewPCTrieRanger(),
+		domains: NewStringSet(0),
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		s := scanner.Text()
+		if i := strings.IndexByte(s, '#'); i >= 0 {
+			s = s[:i]
+		}
+
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		network, err := parseExclusionNet(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if network != nil {
+			if err := e.nets.Insert(cidranger.NewBasicRangerEntry(*network)); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		} else {
+			e.domains[NormalizeDomain(s)] = Nothing{}
+		}
+
+		e.count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return e, nil
+}
+
+// parseExclusionNet - network of IP or CIDR, nil for domains.
+func parseExclusionNet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("bad subnet: %w", err)
+		}
+
+		return network, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// readExclusions - raw list from the file or the API.
+func readExclusions(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// RefreshExclusions - load the list from ExclusionSource and apply it if it is changed.
+// On errors the previous list stays applied.
+func RefreshExclusions() {
+	if ExclusionSource == "" {
+		return
+	}
+
+	dat, err := readExclusions(ExclusionSource)
+	if err != nil {
+		logger.Error.Printf("Can't read exclusions: %s\n", err.Error())
+
+		return
+	}
+
+	sum := sha256.Sum256(dat)
+	if prev := CurrentExclusions(); prev != nil && prev.sum == sum {
+		return
+	}
+
+	e, err := ParseExclusions(bytes.NewReader(dat))
+	if err != nil {
+		logger.Error.Printf("Can't parse exclusions: %s\n", err.Error())
+
+		return
+	}
+
+	e.sum = sum
+	e.version = exclusionsVersion.Add(1)
+
+	currentExclusions.Store(e)
+
+	logger.Info.Printf("Exclusions applied: %d\n", e.count)
+}
+
+// Version - 0 for no list.
+func (e *Exclusions) Version() int64 {
+	if e == nil {
+		return 0
+	}
+
+	return e.version
+}
+
+// IP - rule excluding the address, empty if not excluded.
+func (e *Exclusions) IP(ip net.IP) string {
+	if e == nil || ip == nil {
+		return ""
+	}
+
+	nets, err := e.nets.ContainingNetworks(ip)
+	if err != nil || len(nets) == 0 {
+		return ""
+	}
+
+	network := nets[0].Network()
+
+	return network.String()
+}
+
+// Subnet - rule excluding the whole subnet, empty if not excluded.
+func (e *Exclusions) Subnet(cidr string) string {
+	if e == nil {
+		return ""
+	}
+
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ""
+	}
+
+	ones, _ := subnet.Mask.Size()
+
+	nets, err := e.nets.ContainingNetworks(subnet.IP)
+	if err != nil {
+		return ""
+	}
+
+	for _, entry := range nets {
+		network := entry.Network()
+		if n, _ := network.Mask.Size(); n <= ones {
+			return network.String()
+		}
+	}
+
+	return ""
+}
+
+// Domain - rule excluding the normalized domain or its parent, empty if not excluded.
+func (e *Exclusions) Domain(domain string) string {
+	if e == nil || len(e.domains) == 0 {
+		return ""
+	}
+
+	domain = strings.TrimPrefix(domain, "*.")
+
+	for domain != "" {
+		if _, ok := e.domains[domain]; ok {
+			return domain
+		}
+
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+
+		domain = domain[i+1:]
+	}
+
+	return ""
+}
+
+// URL - rule excluding the host of the normalized URL, empty if not excluded.
+func (e *Exclusions) URL(u string) string {
+	if e == nil {
+		return ""
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return e.IP(ip)
+	}
+
+	return e.Domain(host)
+}
+
+// Selector - rule excluding the selector, empty if not excluded.
+func (e *Exclusions) Selector(sel Selector) string {
+	switch sel.Kind {
+	case SelectorIP4, SelectorIP6:
+		return e.IP(net.ParseIP(sel.Value))
+	case SelectorSubnet4, SelectorSubnet6:
+		return e.Subnet(sel.Value)
+	case SelectorDomain:
+		return e.Domain(sel.Value)
+	case SelectorURL:
+		return e.URL(sel.Value)
+	}
+
+	return ""
+}
+
+// match - rule excluding the matched selector of a search result.
+func (e *Exclusions) match(ip netip.Addr, domain, u string, subnet netip.Prefix) string {
+	switch {
+	case e == nil:
+		return ""
+	case ip.IsValid():
+		return e.IP(ip.AsSlice())
+	case domain != "":
+		return e.Domain(domain)
+	case u != "":
+		return e.URL(u)
+	case subnet.IsValid():
+		return e.Subnet(subnet.String())
+	}
+
+	return ""
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// TestExclusions tests parsing and matching of the local exclusion list.
+func TestExclusions(t *testing.T) {
+	e, err := ParseExclusions(strings.NewReader(`
+# our own resources
+192.168.0.100
+10.4.0.0/15 # office
+fd44::/16
+Example.COM
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		sel      Selector
+		expected string
+	}{
+		{Selector{SelectorIP4, "192.168.0.100"}, "192.168.0.100/32"},
+		{Selector{SelectorIP4, "192.168.0.101"}, ""},
+		{Selector{SelectorIP4, "10.5.1.1"}, "10.4.0.0/15"},
+		{Selector{SelectorIP6, "fd44:4::1"}, "fd44::/16"},
+		{Selector{SelectorSubnet4, "10.4.0.0/16"}, "10.4.0.0/15"},
+		{Selector{SelectorSubnet4, "10.0.0.0/8"}, ""},
+		{Selector{SelectorDomain, "www.example.com"}, "example.com"},
+		{Selector{SelectorDomain, "notexample.com"}, ""},
+		{Selector{SelectorURL, "https://a.example.com/path"}, "example.com"},
+		{Selector{SelectorURL, "http://192.168.0.100/x"}, "192.168.0.100/32"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.sel.Kind+" "+tc.sel.Value, func(t *testing.T) {
+			if result := e.Selector(tc.sel); result != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+
+	if result := e.match(netip.MustParseAddr("fd44::1"), "", "", netip.Prefix{}); result != "fd44::/16" {
+		t.Errorf("search result match: %q", result)
+	}
+
+	if _, err := ParseExclusions(strings.NewReader("10.0.0.0/33\n")); err == nil {
+		t.Errorf("expected error for bad subnet")
+	}
+
+	var none *Exclusions
+	if none.Domain("example.com") != "" || none.Version() != 0 {
+		t.Errorf("nil list excludes nothing")
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// blockTypeNames - block types of the registry, https is a URL block with https URLs.
+var blockTypeNames = map[string]int32{
+	"url":         BlockTypeURL,
+	"https":       BlockTypeHTTPS,
+	"domain":      BlockTypeDomain,
+	"domain-mask": BlockTypeMask,
+	"ip":          BlockTypeIP,
+}
+
+// Address classes of the addr export filter kind, besides parser.AnomalyPrivate and parser.AnomalyBogon.
+const addrClassPublic = "public"
+
+// ExportFilter - records of an export profile, nil means all records.
+// Values of a kind are either allowed or denied, "!" denies the value.
+// Address kinds filter the IPs and subnets of the exported records.
+type ExportFilter struct {
+	spec      string
+	kinds     map[string]*filterKind
+	addrKinds map[string]*addrFilterKind
+}
+
+// ExportFilterConfig - filter of the exported files, nil exports all records.
+var ExportFilterConfig *ExportFilter
+
+// filterKind - allowed and denied values of one kind as record matchers.
+type filterKind struct {
+	allow []func(*PackedContent) bool
+	deny  []func(*PackedContent) bool
+}
+
+// pass - the record passes if any allowed value matches, if there are any, and no denied one matches.
+func (k *filterKind) pass(pack *PackedContent) bool {
+	for _, match := range k.deny {
+		if match(pack) {
+			return false
+		}
+	}
+
+	if len(k.allow) == 0 {
+		return true
+	}
+
+	for _, match := range k.allow {
+		if match(pack) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addrFilterKind - allowed and denied values of one address kind as IP and subnet matchers.
+type addrFilterKind struct {
+	allow []func(netip.Prefix) bool
+	deny  []func(netip.Prefix) bool
+}
+
+// pass - the address passes if any allowed value matches, if there are any, and no denied one matches.
+func (k *addrFilterKind) pass(p netip.Prefix) bool {
+	for _, match := range k.deny {
+		if match(p) {
+			return false
+		}
+	}
+
+	if len(k.allow) == 0 {
+		return true
+	}
+
+	for _, match := range k.allow {
+		if match(p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// add - matcher of a value of the kind.
+func (f *ExportFilter) add(kind string, deny bool, match func(*PackedContent) bool) {
+	k, ok := f.kinds[kind]
+	if !ok {
+		k = &filterKind{}
+		f.kinds[kind] = k
+	}
+
+	if deny {
+		k.deny = append(k.deny, match)
+	} else {
+		k.allow = append(k.allow, match)
+	}
+}
+
+// addAddr - matcher of a value of the address kind.
+func (f *ExportFilter) addAddr(kind string, deny bool, match func(netip.Prefix) bool) {
+	k, ok := f.addrKinds[kind]
+	if !ok {
+		k = &addrFilterKind{}
+		f.addrKinds[kind] = k
+	}
+
+	if deny {
+		k.deny = append(k.deny, match)
+	} else {
+		k.allow = append(k.allow, match)
+	}
+}
+
+// ParseExportFilter - filter from the spec: semicolon separated "kind=value,value" clauses,
+// kinds are entry (registry entry types), urgency (registry urgency types, 0 is not urgent), block (url, https, domain, domain-mask, ip),
+// org (canonical organizations, * matches any text) and subnet (IPv4 or IPv6 CIDR).
+// Address kinds are ipv (4 or 6) and addr (private, bogon or public), they filter the IPs and subnets
+// of the records, an ip block record without any of them left is not exported.
+// For example "org=*суд*;block=!ip" or "entry=!5;subnet=10.0.0.0/8,!10.1.0.0/16" or "ipv=4;addr=!private,!bogon".
+// Empty spec means no filter.
+func ParseExportFilter(spec string) (*ExportFilter, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	filter := &ExportFilter{spec: spec, kinds: make(map[string]*filterKind), addrKinds: make(map[string]*addrFilterKind)}
+
+	for _, clause := range strings.Split(spec, ";") {
+		if strings.TrimSpace(clause) == "" {
+			continue
+		}
+
+		kind, values, ok := strings.Cut(clause, "=")
+		if !ok {
+			return nil, fmt.Errorf("no values: %s", clause)
+		}
+
+		kind = strings.TrimSpace(kind)
+
+		for _, value := range strings.Split(values, ",") {
+			value = strings.TrimSpace(value)
+
+			deny := strings.HasPrefix(value, "!")
+			value = strings.TrimSpace(strings.TrimPrefix(value, "!"))
+
+			if value == "" {
+				continue
+			}
+
+			switch kind {
+			case "entry":
+				entryType, err := ParseEntryType(value)
+				if err != nil {
+					return nil, err
+				}
+
+				filter.add(kind, deny, func(pack *PackedContent) bool { return pack.EntryType == entryType })
+			case "urgency":
+				urgencyType, err := strconv.ParseInt(value, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("bad urgency type: %s", value)
+				}
+
+				filter.add(kind, deny, func(pack *PackedContent) bool { return pack.UrgencyType == int32(urgencyType) })
+			case "block":
+				blockType, ok := blockTypeNames[value]
+				if !ok {
+					return nil, fmt.Errorf("bad block type: %s", value)
+				}
+
+				filter.add(kind, deny, func(pack *PackedContent) bool { return pack.BlockType == blockType })
+			case "org":
+				pattern := strings.ToLower(parser.NormalizeOrg(value))
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("bad org pattern: %s", value)
+				}
+
+				filter.add(kind, deny, func(pack *PackedContent) bool {
+					ok, _ := path.Match(pattern, strings.ToLower(pack.Org))
+
+					return ok
+				})
+			case "subnet":
+				network, err := netip.ParsePrefix(value)
+				if err != nil {
+					return nil, fmt.Errorf("bad subnet: %s", value)
+				}
+
+				filter.add(kind, deny, func(pack *PackedContent) bool { return pack.overlaps(network) })
+			case "ipv":
+				var is4 bool
+
+				switch value {
+				case "4":
+					is4 = true
+				case "6":
+				default:
+					return nil, fmt.Errorf("bad IP version: %s", value)
+				}
+
+				filter.addAddr(kind, deny, func(p netip.Prefix) bool { return p.Addr().Is4() == is4 })
+			case "addr":
+				class := value
+
+				switch class {
+				case parser.AnomalyPrivate, parser.AnomalyBogon:
+				case addrClassPublic:
+					class = ""
+				default:
+					return nil, fmt.Errorf("bad address class: %s", value)
+				}
+
+				filter.addAddr(kind, deny, func(p netip.Prefix) bool { return parser.AddressClass(p) == class })
+			default:
+				return nil, fmt.Errorf("unknown kind: %s", kind)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// Match - is the record in the export? It must pass every kind.
+func (f *ExportFilter) Match(pack *PackedContent) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, k := range f.kinds {
+		if !k.pass(pack) {
+			return false
+		}
+	}
+
+	if pack.BlockType == BlockTypeIP && len(f.addrKinds) > 0 {
+		return pack.hasAddress(f)
+	}
+
+	return true
+}
+
+// Address - is the IP or subnet of an exported record in the export? It must pass every address kind.
+func (f *ExportFilter) Address(p netip.Prefix) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, k := range f.addrKinds {
+		if !k.pass(p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Addr - is the IP of an exported record in the export?
+func (f *ExportFilter) Addr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	return f.Address(netip.PrefixFrom(ip, ip.BitLen()))
+}
+
+// hasAddress - has the record IPs or subnets in the export?
+func (pack *PackedContent) hasAddress(f *ExportFilter) bool {
+	for _, ip4 := range pack.IP4 {
+		if f.Addr(ip4.IP4) {
+			return true
+		}
+	}
+
+	for _, ip6 := range pack.IP6 {
+		if f.Addr(ip6.IP6) {
+			return true
+		}
+	}
+
+	for _, subnet4 := range pack.Subnet4 {
+		if f.Address(subnet4.Subnet4) {
+			return true
+		}
+	}
+
+	for _, subnet6 := range pack.Subnet6 {
+		if f.Address(subnet6.Subnet6) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String - the spec of the filter.
+func (f *ExportFilter) String() string {
+	if f == nil {
+		return ""
+	}
+
+	return f.spec
+}
+
+// overlaps - has the record IPs or subnets in the network, or subnets containing it?
+func (pack *PackedContent) overlaps(network netip.Prefix) bool {
+	for _, ip4 := range pack.IP4 {
+		if network.Contains(ip4.IP4) {
+			return true
+		}
+	}
+
+	for _, ip6 := range pack.IP6 {
+		if network.Contains(ip6.IP6.Unmap()) {
+			return true
+		}
+	}
+
+	for _, subnet4 := range pack.Subnet4 {
+		if network.Overlaps(subnet4.Subnet4) {
+			return true
+		}
+	}
+
+	for _, subnet6 := range pack.Subnet6 {
+		if network.Overlaps(subnet6.Subnet6) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestExportFilter tests export filter specs on records.
+func TestExportFilter(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	for spec, want := range map[string]string{
+		"":                                   "[111 222 333 444 555]",
+		"block=ip":                           "[333 444]",
+		"block=!ip; org=f*":                  "[555]",
+		"org=one,Three":                      "[111 333]",
+		"subnet=10.4.0.0/24":                 "[444]",
+		"subnet=192.168.0.0/16,!10.4.0.0/16": "[111 222 333 555]",
+		"subnet=fd11::/16":                   "[111]",
+		"entry=1;block=https,domain":         "[111 222 555]",
+		"entry=!1":                           "[]",
+		"addr=!private":                      "[111 222 555]",
+		"block=ip;ipv=6":                     "[333 444]",
+	} {
+		filter, err := ParseExportFilter(spec)
+		if err != nil {
+			t.Fatalf("%s: %v", spec, err)
+		}
+
+		var ids []int
+		for id, pack := range CurrentDump.ContentIdx {
+			if filter.Match(pack) {
+				ids = append(ids, int(id))
+			}
+		}
+
+		sort.Ints(ids)
+
+		if got := fmt.Sprint(ids); got != want {
+			t.Errorf("%s: %s", spec, got)
+		}
+	}
+
+	for _, spec := range []string{"block=foo", "subnet=10.0.0.0", "entry=x", "kind=1", "org", "ipv=5", "addr=foo"} {
+		if _, err := ParseExportFilter(spec); err == nil {
+			t.Errorf("%s: accepted", spec)
+		}
+	}
+}
+
+// TestExportFilterCSV tests the filtered dump.csv download.
+func TestExportFilterCSV(t *testing.T) {
+	defer func(dump *Dump, enabled bool) { CurrentDump, RegistryCSV = dump, enabled }(CurrentDump, RegistryCSV)
+
+	CurrentDump = NewDump()
+	RegistryCSV = true
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	registryCSVHandler(t.TempDir())(w, httptest.NewRequest("GET", "/dump.csv?filter=block%3Dip", nil))
+
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if w.Code != 200 || len(lines) != 4 || !strings.HasSuffix(lines[2], ";THREE;3/3/33-3333;2001-01-03") {
+		t.Errorf("filtered: %d %q", w.Code, lines)
+	}
+
+	w = httptest.NewRecorder()
+	registryCSVHandler(t.TempDir())(w, httptest.NewRequest("GET", "/dump.csv?filter=block%3Dfoo", nil))
+
+	if w.Code != 400 {
+		t.Errorf("bad filter: %d", w.Code)
+	}
+}
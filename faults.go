@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Poller stages failures are injected into.
+const (
+	FaultFetch = "fetch" // the dump API or the z-i mirror is unreachable.
+	FaultUnzip = "unzip" // dump.zip of a new dump can't be extracted.
+	FaultParse = "parse" // dump.xml of a new dump can't be parsed, the previous generation stays.
+)
+
+// faultsEnv - faults armed at startup in the test mode, e.g. "fetch,parse:3", see ParseFaults.
+const faultsEnv = "U2CK_DUMP_FAULTS"
+
+// FaultInjection - test mode: poller failures may be armed by InjectFault or faultsEnv,
+// so alerting and stale serving are checked before a real outage. It is set once at startup.
+var FaultInjection bool
+
+// ErrInjectedFault - the failure is armed by the test mode, not a real one.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Faults - armed poller failures.
+var Faults = &FaultSet{}
+
+// Fault - armed failure of a poller stage.
+type Fault struct {
+	Stage     string
+	Remaining int32 // failures left, 0 fails until it is cleared.
+}
+
+// FaultSet - armed failures by stage.
+type FaultSet struct {
+	sync.Mutex
+	armed map[string]int32
+}
+
+// validFaultStage - the stage failures can be injected into.
+func validFaultStage(stage string) bool {
+	switch stage {
+	case FaultFetch, FaultUnzip, FaultParse:
+		return true
+	}
+
+	return false
+}
+
+// ParseFaults - comma separated stages with optional failure counts, e.g. "fetch,parse:3".
+func ParseFaults(spec string) ([]Fault, error) {
+	var faults []Fault
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		stage, count, found := strings.Cut(item, ":")
+
+		fault := Fault{Stage: stage}
+
+		if !validFaultStage(stage) {
+			return nil, fmt.Errorf("unknown stage: %q", stage)
+		}
+
+		if found {
+			n, err := strconv.ParseInt(count, 10, 32)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("bad count: %q", item)
+			}
+
+			fault.Remaining = int32(n)
+		}
+
+		faults = append(faults, fault)
+	}
+
+	return faults, nil
+}
+
+// Arm - fail the stage the next count times, 0 fails it until it is cleared.
+func (f *FaultSet) Arm(stage string, count int32) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.armed == nil {
+		f.armed = make(map[string]int32)
+	}
+
+	f.armed[stage] = count
+}
+
+// Clear - disarm the stage, all stages if it is empty.
+func (f *FaultSet) Clear(stage string) {
+	f.Lock()
+	defer f.Unlock()
+
+	if stage == "" {
+		f.armed = nil
+
+		return
+	}
+
+	delete(f.armed, stage)
+}
+
+// List - armed failures ordered by stage.
+func (f *FaultSet) List() []Fault {
+	f.Lock()
+	defer f.Unlock()
+
+	list := make([]Fault, 0, len(f.armed))
+	for stage, n := range f.armed {
+		list = append(list, Fault{Stage: stage, Remaining: n})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Stage < list[j].Stage })
+
+	return list
+}
+
+// Fail - ErrInjectedFault if a failure of the stage is armed, nil outside of the test mode.
+func (f *FaultSet) Fail(stage string) error {
+	if !FaultInjection {
+		return nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	n, ok := f.armed[stage]
+	if !ok {
+		return nil
+	}
+
+	switch n {
+	case 0:
+	case 1:
+		delete(f.armed, stage)
+	default:
+		f.armed[stage] = n - 1
+	}
+
+	metricInjectedFaults.Add(stage, 1)
+
+	logger.Warning.Printf("Injected %s fault\n", stage)
+
+	return fmt.Errorf("%w: %s", ErrInjectedFault, stage)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestInjectFault tests poller failures armed in the test mode.
+func TestInjectFault(t *testing.T) {
+	defer func(on bool, token string) { FaultInjection, AdminToken = on, token; Faults.Clear("") }(FaultInjection, AdminToken)
+
+	AdminToken = "secret"
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+
+	s := &server{}
+
+	FaultInjection = false
+
+	if resp, _ := s.InjectFault(context.Background(), &pb.FaultRequest{Stage: FaultParse}); resp.GetError() != SrvNoFaults {
+		t.Errorf("Disabled: %v\n", resp)
+	}
+
+	if err := Faults.Fail(FaultParse); err != nil {
+		t.Errorf("Fault outside of the test mode: %s\n", err)
+	}
+
+	FaultInjection = true
+
+	if resp, _ := s.InjectFault(context.Background(), &pb.FaultRequest{Stage: FaultParse, Count: 2}); resp.GetError() != SrvAdminOnly || len(Faults.List()) != 0 {
+		t.Errorf("Without the token: %v %v\n", resp, Faults.List())
+	}
+
+	if resp, _ := s.InjectFault(admin, &pb.FaultRequest{Stage: "download"}); resp.GetError() != SrvBadStage {
+		t.Errorf("Bad stage: %v\n", resp)
+	}
+
+	resp, _ := s.InjectFault(admin, &pb.FaultRequest{Stage: FaultParse, Count: 2})
+	if len(resp.GetFaults()) != 1 || resp.GetFaults()[0].GetRemaining() != 2 {
+		t.Fatalf("Armed: %v\n", resp)
+	}
+
+	dir := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		if err := ParseDumpFile(dir, "x"); !errors.Is(err, ErrInjectedFault) {
+			t.Errorf("Parse %d: %v\n", i, err)
+		}
+	}
+
+	// no dump.xml, a real failure.
+	if err := ParseDumpFile(dir, "x"); err == nil || errors.Is(err, ErrInjectedFault) {
+		t.Errorf("Disarmed parse: %v\n", err)
+	}
+
+	faults, err := ParseFaults("fetch, unzip:3")
+	if err != nil || len(faults) != 2 || faults[0] != (Fault{Stage: FaultFetch}) || faults[1] != (Fault{Stage: FaultUnzip, Remaining: 3}) {
+		t.Errorf("ParseFaults: %v %v\n", faults, err)
+	}
+
+	if _, err := ParseFaults("parse:-1"); err == nil {
+		t.Errorf("Negative count is parsed\n")
+	}
+
+	Faults.Arm(FaultFetch, 0)
+
+	for i := 0; i < 3; i++ {
+		if err := Faults.Fail(FaultFetch); !errors.Is(err, ErrInjectedFault) {
+			t.Errorf("Fetch %d: %v\n", i, err)
+		}
+	}
+
+	if resp, _ := s.InjectFault(admin, &pb.FaultRequest{Clear: true}); len(resp.GetFaults()) != 0 {
+		t.Errorf("Cleared: %v\n", resp)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// FeedSize - number of items in every feed, 0 disables feeds. It is set once at startup.
+var FeedSize = 1000
+
+// feedMaxSelectors - selectors quoted in a feed item.
+const feedMaxSelectors = 20
+
+// FeedItem - added or removed record.
+type FeedItem struct {
+	ID          int32
+	Generation  int64
+	UpdateTime  int64 // registry update time.
+	Time        time.Time
+	Decision    Decision
+	IncludeTime int64
+	BlockType   int32
+	Selectors   []Selector // first feedMaxSelectors of them.
+	More        int        // selectors not quoted.
+	Urgent      bool
+}
+
+// FeedRing - most recent items, newest first on read.
+type FeedRing struct {
+	sync.RWMutex
+	items []FeedItem
+	next  int
+	size  int
+}
+
+// Recent feeds.
+var (
+	RecentAdded   = &FeedRing{}
+	RecentRemoved = &FeedRing{}
+)
+
+// Push - add items, the oldest ones are dropped.
+func (f *FeedRing) Push(items []FeedItem, size int) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.size != size {
+		f.items, f.next, f.size = nil, 0, size
+	}
+
+	for _, item := range items {
+		if len(f.items) < size {
+			f.items = append(f.items, item)
+
+			continue
+		}
+
+		f.items[f.next] = item
+		f.next = (f.next + 1) % size
+	}
+}
+
+// Latest - up to limit newest items, 0 means all.
+func (f *FeedRing) Latest(limit int) []FeedItem {
+	f.RLock()
+	defer f.RUnlock()
+
+	n := len(f.items)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+
+	latest := make([]FeedItem, 0, limit)
+
+	// before the ring is full next is 0 and the newest item is the last one.
+	for i := 0; i < limit; i++ {
+		latest = append(latest, f.items[((f.next-1-i)%n+n)%n])
+	}
+
+	return latest
+}
+
+// newFeedItem - feed item of the record, call it under lock.
+func newFeedItem(pack *PackedContent, generation, utime int64, now time.Time) FeedItem {
+	item := FeedItem{
+		ID:         pack.ID,
+		Generation: generation,
+		UpdateTime: utime,
+		Time:       now,
+		BlockType:  pack.BlockType,
+		Urgent:     pack.Urgent(),
+	}
+
+	if record, err := pack.Record(); err == nil {
+		item.Decision = record.Decision
+		item.IncludeTime = record.IncludeTime
+	}
+
+	set := make(SelectorSet)
+	pack.addSelectors(set)
+
+	selectors := make([]Selector, 0, len(set))
+	for sel := range set {
+		selectors = append(selectors, sel)
+	}
+
+	sortSelectors(selectors)
+
+	if len(selectors) > feedMaxSelectors {
+		item.More = len(selectors) - feedMaxSelectors
+		selectors = selectors[:feedMaxSelectors]
+	}
+
+	item.Selectors = selectors
+
+	return item
+}
+
+// PublishFeeds - push records of the just published generation to the feeds.
+// Only the last FeedSize records of every kind are rendered.
+func PublishFeeds(added []int32, removed []*PackedContent) {
+	if FeedSize <= 0 {
+		return
+	}
+
+	if len(added) > FeedSize {
+		added = added[len(added)-FeedSize:]
+	}
+
+	if len(removed) > FeedSize {
+		removed = removed[len(removed)-FeedSize:]
+	}
+
+	now := time.Now()
+
+	CurrentDump.RLock()
+
+	generation, utime := CurrentDump.generation, CurrentDump.utime
+
+	addedItems := make([]FeedItem, 0, len(added))
+
+	for _, id := range added {
+		if pack, ok := CurrentDump.ContentIdx[id]; ok {
+			addedItems = append(addedItems, newFeedItem(pack, generation, utime, now))
+		}
+	}
+
+	CurrentDump.RUnlock()
+
+	removedItems := make([]FeedItem, 0, len(removed))
+
+	// removed records are out of the index, nobody changes them.
+	for _, pack := range removed {
+		removedItems = append(removedItems, newFeedItem(pack, generation, utime, now))
+	}
+
+	RecentAdded.Push(addedItems, FeedSize)
+	RecentRemoved.Push(removedItems, FeedSize)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestFeeds tests that a reparse pushes added and removed records to the feeds, newest first.
+func TestFeeds(t *testing.T) {
+	defer func(dump *Dump, added, removed *FeedRing) {
+		CurrentDump, RecentAdded, RecentRemoved = dump, added, removed
+	}(CurrentDump, RecentAdded, RecentRemoved)
+
+	CurrentDump, RecentAdded, RecentRemoved = NewDump(), &FeedRing{}, &FeedRing{}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(RecentAdded.Latest(0)) != 0 {
+		t.Errorf("initial load must not be in the feed")
+	}
+
+	// drop 555 and add 666.
+	xml := strings.Replace(xml01, `<content id="555"`, `<content id="666"`, 1)
+	if err := Parse(strings.NewReader(xml)); err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed := RecentAdded.Latest(0), RecentRemoved.Latest(0)
+	if len(added) != 1 || added[0].ID != 666 || len(removed) != 1 || removed[0].ID != 555 {
+		t.Fatalf("unexpected feeds: added %v, removed %v", added, removed)
+	}
+
+	if added[0].Decision.Number != "5/5/55-5555" || len(added[0].Selectors) == 0 {
+		t.Errorf("feed item without decision or selectors: %+v", added[0])
+	}
+
+	for _, path := range []string{"/feed/added.rss", "/feed/removed.atom"} {
+		w := httptest.NewRecorder()
+		handleFeed(w, httptest.NewRequest("GET", path, nil))
+
+		if body := w.Body.String(); w.Code != 200 || !strings.Contains(body, "5/5/55-5555") {
+			t.Errorf("%s: %d %s", path, w.Code, body)
+		}
+	}
+
+	ring := &FeedRing{}
+	for i := int32(1); i <= 5; i++ {
+		ring.Push([]FeedItem{{ID: i}}, 3)
+	}
+
+	if latest := ring.Latest(2); len(latest) != 2 || latest[0].ID != 5 || latest[1].ID != 4 {
+		t.Errorf("ring order: %v", latest)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// rss - RSS 2.0 document.
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Description string `xml:"description"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// atom - Atom 1.0 document.
+type atom struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Updated string `xml:"updated"`
+	Summary string `xml:"summary"`
+}
+
+// feedItemTitle - "#id number org", urgent ones are marked.
+func feedItemTitle(item FeedItem) string {
+	title := strings.TrimSpace(fmt.Sprintf("#%d %s %s", item.ID, item.Decision.Number, item.Decision.Org))
+	if item.Urgent {
+		title = SrvUrgentMark + " " + title
+	}
+
+	return title
+}
+
+// feedItemSummary - decision and selectors, one per line.
+func feedItemSummary(item FeedItem) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s %s\n", item.Decision.Number, item.Decision.Date, item.Decision.Org)
+
+	for _, sel := range item.Selectors {
+		fmt.Fprintf(&b, "%s %s\n", sel.Kind, sel.Value)
+	}
+
+	if item.More > 0 {
+		fmt.Fprintf(&b, "+%d\n", item.More)
+	}
+
+	return b.String()
+}
+
+// feedItemID - unique per record and generation, a record may come and go many times.
+func feedItemID(kind string, item FeedItem) string {
+	return fmt.Sprintf("urn:u2ckdump:%s:%d:%d", kind, item.ID, item.Generation)
+}
+
+// handleFeed - /feed/{added,removed,urgent}.{rss,atom}?limit=N.
+func handleFeed(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/feed/")
+
+	kind, format, _ := strings.Cut(name, ".")
+
+	var (
+		feed  *FeedRing
+		title string
+	)
+
+	switch kind {
+	case "added":
+		feed, title = RecentAdded, SrvFeedAdded
+	case "removed":
+		feed, title = RecentRemoved, SrvFeedRemoved
+	case "urgent":
+		feed, title = RecentUrgent, SrvFeedUrgent
+	default:
+		http.NotFound(w, r)
+
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	items := feed.Latest(limit)
+
+	var doc any
+
+	switch format {
+	case "rss":
+		channel := rssChannel{Title: title, Link: "http://" + r.Host + r.URL.Path, Description: title}
+
+		for _, item := range items {
+			channel.Items = append(channel.Items, rssItem{
+				Title:       feedItemTitle(item),
+				Description: feedItemSummary(item),
+				GUID:        feedItemID(kind, item),
+				PubDate:     item.Time.UTC().Format(time.RFC1123Z),
+			})
+		}
+
+		doc = rss{Version: "2.0", Channel: channel}
+
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	case "atom":
+		feed := atom{ID: "urn:u2ckdump:" + kind, Title: title, Updated: time.Now().UTC().Format(time.RFC3339)}
+		if len(items) > 0 {
+			feed.Updated = items[0].Time.UTC().Format(time.RFC3339)
+		}
+
+		for _, item := range items {
+			feed.Entries = append(feed.Entries, atomEntry{
+				ID:      feedItemID(kind, item),
+				Title:   feedItemTitle(item),
+				Updated: item.Time.UTC().Format(time.RFC3339),
+				Summary: feedItemSummary(item),
+			})
+		}
+
+		doc = feed
+
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	default:
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.Write([]byte(xml.Header))
+
+	if err := xml.NewEncoder(w).Encode(doc); err != nil {
+		logger.Debug.Printf("Can't write feed: %s\n", err.Error())
+	}
+}
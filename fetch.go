@@ -2,12 +2,15 @@ package main
 
 import (
 	"archive/zip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/usher2/u2ckdump/internal/logger"
 )
@@ -29,14 +32,46 @@ type DumpAnswer struct {
 var (
 	ErrNot200HTTPCode = errors.New("not 200 HTTP code")
 	ErrEmptyAnswer    = errors.New("empty answer")
+	ErrTooBig         = errors.New("extracted file is too big")
+	ErrNoDumpEntry    = errors.New("no dump entry in archive")
+	ErrAmbiguousEntry = errors.New("several entries match")
 )
 
+// HTTPStatusError - answer of the dump API other than 200, it is ErrNot200HTTPCode.
+type HTTPStatusError struct {
+	Code int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrNot200HTTPCode, e.Code)
+}
+
+func (e *HTTPStatusError) Unwrap() error {
+	return ErrNot200HTTPCode
+}
+
+// Dump size limits, they are set once at startup.
+var (
+	UnzipMaxBytes        int64   // max size of extracted dump.xml, 0 - unlimited.
+	UnzipMaxArchiveBytes int64   // max size of dump.zip, downloaded or extracted, 0 - unlimited.
+	UnzipMaxRatio        float64 // max extracted to compressed size ratio of the entries, 0 - unlimited.
+)
+
+// Archive entries, comma separated glob patterns in the order of preference.
+// They are set once at startup.
+var (
+	UnzipEntry     = "dump.xml"
+	UnzipSignature = "dump.xml.sig" // empty - don't extract.
+)
+
+// errorBodyMax - bytes of an error answer logged.
+const errorBodyMax = 1024
+
 // GetLastDumpID - fetch last dump ID from "vigruzki".
-func GetLastDumpID(ts int64, u, key string) (*DumpAnswer, error) {
+func GetLastDumpID(ctx context.Context, client *http.Client, ts int64, u, key string) (*DumpAnswer, error) {
 	answer := make([]DumpAnswer, 0)
-	client := &http.Client{}
 
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/last", u), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/last", u), nil)
 	if err != nil {
 		return nil, fmt.Errorf("construct request: %w", err)
 	}
@@ -52,10 +87,13 @@ func GetLastDumpID(ts int64, u, key string) (*DumpAnswer, error) {
 		return nil, fmt.Errorf("do request: %w", err)
 	}
 
+	defer resp.Body.Close()
+
 	if resp.StatusCode != 200 {
-		logger.Debug.Printf("%s\n", resp.Body)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyMax))
+		logger.Debug.Printf("%s\n", body)
 
-		return nil, fmt.Errorf("%w: %d", ErrNot200HTTPCode, resp.StatusCode)
+		return nil, &HTTPStatusError{Code: resp.StatusCode}
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(&answer)
@@ -71,8 +109,7 @@ func GetLastDumpID(ts int64, u, key string) (*DumpAnswer, error) {
 }
 
 // FetchDump - fetch dump from "vigruzki".
-func FetchDump(id, filename, u, key string) error {
-	client := &http.Client{}
+func FetchDump(ctx context.Context, client *http.Client, id, filename, u, key string) error {
 	tfn := fmt.Sprintf("%s-tmp", filename)
 
 	out, err := os.Create(tfn)
@@ -82,7 +119,7 @@ func FetchDump(id, filename, u, key string) error {
 
 	defer out.Close()
 
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/get/%s", u, id), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/get/%s", u, id), nil)
 	if err != nil {
 		return fmt.Errorf("%w", err)
 	}
@@ -96,14 +133,30 @@ func FetchDump(id, filename, u, key string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("%w: %d", ErrNot200HTTPCode, resp.StatusCode)
+		return &HTTPStatusError{Code: resp.StatusCode}
+	}
+
+	if UnzipMaxArchiveBytes > 0 && resp.ContentLength > UnzipMaxArchiveBytes {
+		return fmt.Errorf("%w: archive: declared %d bytes", ErrTooBig, resp.ContentLength)
+	}
+
+	// don't trust the declared size.
+	var body io.Reader = resp.Body
+	if UnzipMaxArchiveBytes > 0 {
+		body = io.LimitReader(resp.Body, UnzipMaxArchiveBytes+1)
 	}
 
-	_, err = io.Copy(out, resp.Body)
+	n, err := io.Copy(out, body)
 	if err != nil {
 		return fmt.Errorf("body copy: %w", err)
 	}
 
+	if UnzipMaxArchiveBytes > 0 && n > UnzipMaxArchiveBytes {
+		os.Remove(tfn)
+
+		return fmt.Errorf("%w: archive: more than %d bytes", ErrTooBig, UnzipMaxArchiveBytes)
+	}
+
 	err = os.Rename(tfn, filename)
 	if err != nil {
 		return fmt.Errorf("file rename: %w", err)
@@ -146,9 +199,19 @@ func WriteCurrentDumpID(filename string, dump *DumpAnswer) error {
 	return nil
 }
 
-// DumpUnzip - unzip dump file.
+// DumpUnzip - extract the dump entry and its signature, if any.
+// The signature is saved next to the dump as filename.sig.
 func DumpUnzip(src, filename string) error {
-	tmpfilename := fmt.Sprintf("%s-temp", filename)
+	if UnzipMaxArchiveBytes > 0 {
+		info, err := os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("stat zip arch: %w", err)
+		}
+
+		if info.Size() > UnzipMaxArchiveBytes {
+			return fmt.Errorf("%w: archive: %d bytes, more than %d", ErrTooBig, info.Size(), UnzipMaxArchiveBytes)
+		}
+	}
 
 	r, err := zip.OpenReader(src)
 	if err != nil {
@@ -157,36 +220,181 @@ func DumpUnzip(src, filename string) error {
 
 	defer r.Close()
 
-	for _, f := range r.File {
-		// look over file list and handle this one
-		if f.Name != "dump.xml" {
+	entry, err := findEntry(r.File, UnzipEntry)
+	if err != nil {
+		return err
+	}
+
+	if entry == nil {
+		return fmt.Errorf("%w: %s not found, entries: %s", ErrNoDumpEntry, UnzipEntry, entryNames(r.File))
+	}
+
+	logger.Debug.Printf("Dump entry: %s\n", entry.Name)
+
+	err = extractEntry(entry, filename)
+	if err != nil {
+		return err
+	}
+
+	sigFilename := filename + ".sig"
+
+	sig, err := findEntry(r.File, UnzipSignature)
+	if err != nil {
+		return err
+	}
+
+	if sig == nil {
+		// the stale one doesn't sign the new dump.
+		if err := os.Remove(sigFilename); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove signature: %w", err)
+		}
+
+		return nil
+	}
+
+	logger.Debug.Printf("Signature entry: %s\n", sig.Name)
+
+	return extractEntry(sig, sigFilename)
+}
+
+// findEntry - file matched by the first pattern with matches, nil if nothing is matched.
+// Patterns are comma separated globs, a pattern without / is matched against the base name.
+func findEntry(files []*zip.File, patterns string) (*zip.File, error) {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
 			continue
 		}
 
-		if f.FileInfo().IsDir() {
-			return fmt.Errorf("file is dir")
+		var matched []*zip.File
+
+		for _, f := range files {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+
+			name := f.Name
+			if !strings.Contains(pattern, "/") {
+				name = path.Base(name)
+			}
+
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+			}
+
+			if ok {
+				matched = append(matched, f)
+			}
 		}
 
-		rc, err := f.Open()
-		if err != nil {
-			return fmt.Errorf("open zipped file: %w", err)
+		switch len(matched) {
+		case 0:
+			continue
+		case 1:
+			return matched[0], nil
+		default:
+			return nil, fmt.Errorf("%w: %s matches %s", ErrAmbiguousEntry, pattern, entryNames(matched))
 		}
+	}
 
-		defer rc.Close()
+	return nil, nil
+}
 
-		f, err := os.Create(tmpfilename)
-		if err != nil {
-			return fmt.Errorf("create tmpfile: %w", err)
+// entryNames - archive content for error messages.
+func entryNames(files []*zip.File) string {
+	const maxNames = 20
+
+	names := make([]string, 0, maxNames+1)
+
+	for i, f := range files {
+		if i == maxNames {
+			names = append(names, fmt.Sprintf("and %d more", len(files)-maxNames))
+
+			break
 		}
 
-		defer f.Close()
+		names = append(names, f.Name)
+	}
 
-		_, err = io.Copy(f, rc)
-		if err != nil {
-			return fmt.Errorf("write unzipped: %w", err)
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// extractLimit - max extracted size of the entry by the size and ratio limits, -1 - unlimited.
+func extractLimit(entry *zip.File) int64 {
+	limit := int64(-1)
+	if UnzipMaxBytes > 0 {
+		limit = UnzipMaxBytes
+	}
+
+	if UnzipMaxRatio > 0 {
+		if byRatio := int64(UnzipMaxRatio * float64(entry.CompressedSize64)); limit < 0 || byRatio < limit {
+			limit = byRatio
 		}
+	}
 
-		break
+	return limit
+}
+
+// extractEntry - write the entry to the file through a temp file, the size and the compression ratio
+// are checked as declared and as extracted, the archive is not trusted.
+func extractEntry(entry *zip.File, filename string) error {
+	tmpfilename := fmt.Sprintf("%s-temp", filename)
+
+	if UnzipMaxBytes > 0 && entry.UncompressedSize64 > uint64(UnzipMaxBytes) {
+		return fmt.Errorf("%w: %s: declared %d bytes", ErrTooBig, entry.Name, entry.UncompressedSize64)
+	}
+
+	limit := extractLimit(entry)
+	if limit >= 0 && entry.UncompressedSize64 > uint64(limit) {
+		return fmt.Errorf("%w: %s: declared %d bytes of %d compressed, ratio over %g", ErrTooBig,
+			entry.Name, entry.UncompressedSize64, entry.CompressedSize64, UnzipMaxRatio)
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("open zipped file: %w", err)
+	}
+
+	defer rc.Close()
+
+	f, err := os.Create(tmpfilename)
+	if err != nil {
+		return fmt.Errorf("create tmpfile: %w", err)
+	}
+
+	defer f.Close()
+
+	// don't trust the declared size.
+	var src io.Reader = rc
+	if limit >= 0 {
+		src = io.LimitReader(rc, limit+1)
+	}
+
+	n, err := io.Copy(f, src)
+	if err != nil {
+		return fmt.Errorf("write unzipped: %w", err)
+	}
+
+	if limit >= 0 && n > limit {
+		f.Close()
+		os.Remove(tmpfilename)
+
+		if UnzipMaxBytes > 0 && n > UnzipMaxBytes {
+			return fmt.Errorf("%w: %s: more than %d bytes", ErrTooBig, entry.Name, UnzipMaxBytes)
+		}
+
+		return fmt.Errorf("%w: %s: more than %d bytes of %d compressed, ratio over %g", ErrTooBig,
+			entry.Name, limit, entry.CompressedSize64, UnzipMaxRatio)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("close tmpfile: %w", err)
 	}
 
 	err = os.Rename(tmpfilename, filename)
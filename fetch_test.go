@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip - archive with the given entries.
+func writeZip(t *testing.T, filename string, entries ...string) {
+	t.Helper()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+
+	for _, name := range entries {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := fw.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDumpUnzip tests dump and signature entry selection.
+func TestDumpUnzip(t *testing.T) {
+	defer func(entry, sig string) { UnzipEntry, UnzipSignature = entry, sig }(UnzipEntry, UnzipSignature)
+
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "dump.zip"), filepath.Join(dir, "dump.xml")
+
+	writeZip(t, src, "dump.xml", "dump.xml.sig", "attachments/order.pdf")
+
+	if err := DumpUnzip(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if dat, _ := os.ReadFile(dst + ".sig"); string(dat) != "dump.xml.sig" {
+		t.Errorf("signature: %q", dat)
+	}
+
+	// preference order, nested entry, the stale signature is removed.
+	UnzipEntry = "dump.xml, *.xml"
+
+	writeZip(t, src, "register/reg.xml", "attachments/order.pdf")
+
+	if err := DumpUnzip(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if dat, _ := os.ReadFile(dst); string(dat) != "register/reg.xml" {
+		t.Errorf("entry: %q", dat)
+	}
+
+	if _, err := os.Stat(dst + ".sig"); !os.IsNotExist(err) {
+		t.Errorf("stale signature: %v", err)
+	}
+
+	writeZip(t, src, "a.xml", "b.xml")
+
+	if err := DumpUnzip(src, dst); !errors.Is(err, ErrAmbiguousEntry) {
+		t.Errorf("ambiguous: %v", err)
+	}
+
+	UnzipEntry = "dump.xml"
+
+	err := DumpUnzip(src, dst)
+	if !errors.Is(err, ErrNoDumpEntry) || !strings.Contains(err.Error(), "a.xml, b.xml") {
+		t.Errorf("missing: %v", err)
+	}
+}
+
+// TestDumpUnzipLimits tests archives over the size and compression ratio limits are refused.
+func TestDumpUnzipLimits(t *testing.T) {
+	defer func(max, archive int64, ratio float64) {
+		UnzipMaxBytes, UnzipMaxArchiveBytes, UnzipMaxRatio = max, archive, ratio
+	}(UnzipMaxBytes, UnzipMaxArchiveBytes, UnzipMaxRatio)
+
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "dump.zip"), filepath.Join(dir, "dump.xml")
+
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := zip.NewWriter(f)
+
+	fw, err := w.Create("dump.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a megabyte of zeros is compressed a thousand times.
+	if _, err := fw.Write(make([]byte, 1<<20)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	for name, limits := range map[string]struct {
+		max, archive int64
+		ratio        float64
+		ok           bool
+	}{
+		"unlimited": {0, 0, 0, true},
+		"ratio":     {0, 0, 100, false},
+		"archive":   {0, 100, 0, false},
+		"size":      {1 << 10, 0, 0, false},
+		"fits":      {2 << 20, 1 << 20, 5000, true},
+	} {
+		UnzipMaxBytes, UnzipMaxArchiveBytes, UnzipMaxRatio = limits.max, limits.archive, limits.ratio
+
+		os.Remove(dst)
+
+		err := DumpUnzip(src, dst)
+		if limits.ok != (err == nil) || (err != nil && !errors.Is(err, ErrTooBig)) {
+			t.Errorf("%s: %v", name, err)
+		}
+
+		if info, err := os.Stat(dst); limits.ok != (err == nil) || (err == nil && info.Size() != 1<<20) {
+			t.Errorf("%s: extracted: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// NewGateway - HTTP gateway with probes, metrics, feeds, exports of dir and gRPC-Web for srv, if enabled.
+func NewGateway(addr, dir string, srv *grpc.Server) *http.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", handleLiveness)
+	mux.HandleFunc("/readyz", handleReadiness)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/feed/", handleFeed)
+	mux.HandleFunc("/"+registryCSVFilename, registryCSVHandler(dir))
+	mux.HandleFunc("/"+urgentCSVFilename, urgentCSVHandler(dir))
+	mux.HandleFunc("/"+sniBloomFilename, sniBloomHandler(dir))
+	mux.HandleFunc("/"+sniBloomMetaFilename, sniBloomHandler(dir))
+	mux.HandleFunc("/"+sqliteFilename, sqliteHandler(dir))
+	mux.HandleFunc("/"+manifestFilename, manifestHandler(dir))
+	mux.HandleFunc("/check", handleCallout)
+	mux.HandleFunc("/content/", handleContentSelectors)
+	mux.HandleFunc("/delta", handleDelta)
+
+	// gRPC-Web calls are limited by the gRPC interceptors.
+	var handler http.Handler = mux
+	if RateLimit != nil {
+		handler = RateLimit.Handler(mux)
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           wrapGRPCWeb(srv, handler),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// ServeGateway - run the gateway on the listener until kill.
+func ServeGateway(srv *http.Server, ln net.Listener, done chan<- struct{}, kill <-chan struct{}) {
+	go func() {
+		<-kill
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Error.Printf("Gateway shutdown: %s\n", err.Error())
+		}
+
+		close(done)
+	}()
+
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		logger.Error.Printf("Gateway failed to serve: %s\n", err.Error())
+	}
+}
+
+// handleLiveness - the process is up.
+func handleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadiness - the index is loaded and fresh.
+func handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ready, reason := Ready(time.Now())
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	w.Write([]byte(reason + "\n"))
+}
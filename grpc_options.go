@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/keepalive"
+)
+
+// GRPCServerOptions - gRPC server tuning, zero values keep grpc defaults.
+type GRPCServerOptions struct {
+	Compressors          string        // comma separated: gzip, zstd. A response is compressed as the request.
+	GzipLevel            int           // 0 - default level.
+	KeepaliveTime        time.Duration // ping a client idle for this long.
+	KeepaliveTimeout     time.Duration // close the connection if the ping is not answered.
+	KeepaliveMinTime     time.Duration // close connections of clients pinging more often.
+	KeepalivePermit      bool          // allow client pings without active streams.
+	MaxConcurrentStreams uint32        // per connection.
+	MaxRecvMsgSize       int           // bytes.
+	MaxSendMsgSize       int           // bytes.
+}
+
+// GRPCConfig - gRPC server configuration, it is set once at startup.
+var GRPCConfig GRPCServerOptions
+
+// ServerOptions - grpc.NewServer options, compressors are registered globally.
+func (o GRPCServerOptions) ServerOptions() ([]grpc.ServerOption, error) {
+	for _, name := range strings.Split(o.Compressors, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case gzipName:
+			if err := registerGzip(o.GzipLevel); err != nil {
+				return nil, fmt.Errorf("gzip: %w", err)
+			}
+		case zstdName:
+			if err := registerZstd(); err != nil {
+				return nil, fmt.Errorf("zstd: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown compressor: %s", name)
+		}
+	}
+
+	opts := requestIDOptions() // first, other interceptors see the request id.
+
+	if o.KeepaliveTime > 0 || o.KeepaliveTimeout > 0 {
+		opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    o.KeepaliveTime,
+			Timeout: o.KeepaliveTimeout,
+		}))
+	}
+
+	if o.KeepaliveMinTime > 0 || o.KeepalivePermit {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             o.KeepaliveMinTime,
+			PermitWithoutStream: o.KeepalivePermit,
+		}))
+	}
+
+	if o.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(o.MaxConcurrentStreams))
+	}
+
+	if o.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(o.MaxRecvMsgSize))
+	}
+
+	if o.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(o.MaxSendMsgSize))
+	}
+
+	return opts, nil
+}
+
+// Compressor names, as in grpc-encoding.
+const (
+	gzipName = "gzip"
+	zstdName = "zstd"
+)
+
+// gzipCompressor - grpc compressor with pooled writers of the configured level.
+type gzipCompressor struct {
+	level   int
+	writers sync.Pool
+}
+
+// registerGzip - make gzip available for requests and responses.
+func registerGzip(level int) error {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	// check the level once, NewWriterLevel fails only on it.
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return err
+	}
+
+	encoding.RegisterCompressor(&gzipCompressor{level: level})
+
+	return nil
+}
+
+func (c *gzipCompressor) Name() string {
+	return gzipName
+}
+
+func (c *gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if z, ok := c.writers.Get().(*gzip.Writer); ok {
+		z.Reset(w)
+
+		return &gzipWriter{Writer: z, pool: &c.writers}, nil
+	}
+
+	z, err := gzip.NewWriterLevel(w, c.level)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gzipWriter{Writer: z, pool: &c.writers}, nil
+}
+
+func (c *gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// gzipWriter - return the writer to the pool on Close.
+type gzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (z *gzipWriter) Close() error {
+	defer z.pool.Put(z.Writer)
+
+	return z.Writer.Close()
+}
+
+// zstdCompressor - grpc compressor on shared stateless zstd coders, messages are buffered whole anyway.
+type zstdCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+var registerZstdOnce sync.Once
+
+// registerZstd - make zstd available for requests and responses.
+func registerZstd() error {
+	var err error
+
+	registerZstdOnce.Do(func() {
+		c := &zstdCompressor{}
+
+		c.encoder, err = zstd.NewWriter(nil)
+		if err != nil {
+			return
+		}
+
+		c.decoder, err = zstd.NewReader(nil)
+		if err != nil {
+			return
+		}
+
+		encoding.RegisterCompressor(c)
+	})
+
+	return err
+}
+
+func (c *zstdCompressor) Name() string {
+	return zstdName
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &zstdWriter{w: w, encoder: c.encoder}, nil
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := c.decoder.DecodeAll(src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(dst), nil
+}
+
+// zstdWriter - buffer the message, compress it on Close.
+type zstdWriter struct {
+	bytes.Buffer
+	w       io.Writer
+	encoder *zstd.Encoder
+}
+
+func (z *zstdWriter) Close() error {
+	_, err := z.w.Write(z.encoder.EncodeAll(z.Bytes(), nil))
+
+	return err
+}
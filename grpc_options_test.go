@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestGRPCServerOptions tests compressed round trips and the request size limit.
+func TestGRPCServerOptions(t *testing.T) {
+	if _, err := (GRPCServerOptions{Compressors: "brotli"}).ServerOptions(); err == nil {
+		t.Error("unknown compressor is accepted")
+	}
+
+	opts, err := GRPCServerOptions{Compressors: "gzip, zstd", GzipLevel: 9, MaxRecvMsgSize: 1 << 20}.ServerOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer(opts...)
+	pb.RegisterCheckServer(srv, &server{})
+
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(listen.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCheckClient(conn)
+
+	for _, name := range []string{gzipName, zstdName} {
+		pong, err := client.Ping(context.Background(), &pb.PingRequest{Ping: strings.Repeat("ping", 1000)}, grpc.UseCompressor(name))
+		if err != nil || pong.GetPong() == "" && pong.GetError() == "" {
+			t.Errorf("%s: %v %v", name, pong, err)
+		}
+	}
+
+	_, err = client.SearchURL(context.Background(), &pb.URLRequest{Query: strings.Repeat("x", 2<<20)})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("big request: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+)
+
+// GRPCWebOrigins - browser origins allowed to call gRPC-Web, "*" allows any, empty disables gRPC-Web.
+var GRPCWebOrigins []string
+
+// ParseOrigins - comma separated origins list.
+func ParseOrigins(s string) []string {
+	var origins []string
+
+	for _, origin := range strings.Split(s, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+
+	return origins
+}
+
+// allowedOrigin - is the browser origin in GRPCWebOrigins?
+func allowedOrigin(origin string) bool {
+	for _, allowed := range GRPCWebOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wrapGRPCWeb - gRPC-Web requests and their CORS preflights go to the gRPC server, the rest go to next.
+func wrapGRPCWeb(srv *grpc.Server, next http.Handler) http.Handler {
+	if srv == nil || len(GRPCWebOrigins) == 0 {
+		return next
+	}
+
+	web := grpcweb.WrapServer(srv, grpcweb.WithOriginFunc(allowedOrigin))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if web.IsGrpcWebRequest(r) || web.IsAcceptableGrpcCorsRequest(r) {
+			web.ServeHTTP(w, r)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestGRPCWeb tests gRPC-Web calls and preflights of allowed origins, the rest goes to the gateway.
+func TestGRPCWeb(t *testing.T) {
+	defer func(dump *Dump, origins []string) { CurrentDump, GRPCWebOrigins = dump, origins }(CurrentDump, GRPCWebOrigins)
+
+	if origins := ParseOrigins(" https://a.tld/, ,https://b.tld"); !reflect.DeepEqual(origins, []string{"https://a.tld", "https://b.tld"}) {
+		t.Errorf("Origins: %q\n", origins)
+	}
+
+	CurrentDump = NewDump()
+
+	srv := grpc.NewServer()
+	pb.RegisterCheckServer(srv, &server{})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+
+	// gRPC-Web is disabled without origins.
+	GRPCWebOrigins = nil
+
+	if handler := wrapGRPCWeb(srv, next); reflect.ValueOf(handler).Pointer() != reflect.ValueOf(next).Pointer() {
+		t.Errorf("gRPC-Web without origins\n")
+	}
+
+	GRPCWebOrigins = []string{"https://a.tld"}
+	handler := wrapGRPCWeb(srv, next)
+
+	preflight := func(origin string) string {
+		r := httptest.NewRequest(http.MethodOptions, "/msg.Check/Ping", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		r.Header.Set("Access-Control-Request-Headers", "x-grpc-web,content-type")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if allowed := preflight("https://a.tld"); allowed != "https://a.tld" {
+		t.Errorf("Allowed origin preflight: %q\n", allowed)
+	}
+
+	if allowed := preflight("https://evil.tld"); allowed != "" {
+		t.Errorf("Unknown origin preflight: %q\n", allowed)
+	}
+
+	msg, err := proto.Marshal(&pb.PingRequest{Ping: "ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+
+	r := httptest.NewRequest(http.MethodPost, "/"+pb.Check_ServiceDesc.ServiceName+"/Ping", bytes.NewReader(append(frame, msg...)))
+	r.Header.Set("Content-Type", "application/grpc-web+proto")
+	r.Header.Set("Origin", "https://a.tld")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	body, _ := io.ReadAll(rec.Body)
+	if rec.Code != http.StatusOK || len(body) < 5 || body[0] != 0 {
+		t.Fatalf("gRPC-Web call: %d %q\n", rec.Code, body)
+	}
+
+	var pong pb.PongResponse
+
+	size := binary.BigEndian.Uint32(body[1:5])
+	if err := proto.Unmarshal(body[5:5+size], &pong); err != nil || pong.GetError() != SrvDataNotReady {
+		t.Errorf("Pong: %v %v\n", &pong, err)
+	}
+
+	// the rest of the gateway.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("Gateway request: %d\n", rec.Code)
+	}
+}
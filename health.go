@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ReadyStaleness - readiness flips false if no poll cycle succeeded for this long, 0 disables.
+var ReadyStaleness time.Duration
+
+// lastRefresh - unix time of the last successful poll cycle.
+var lastRefresh atomic.Int64
+
+// RegistryMaxAge - alert if the registry update time of the served dump is older than this, 0 disables.
+// The poller may succeed while the upstream keeps giving the same stale dump.
+var RegistryMaxAge time.Duration
+
+// registryStale - the stale registry alert is raised, it is raised again after the data is fresh.
+var registryStale atomic.Bool
+
+// healthService - gRPC health service name for readiness, "" is liveness. v2Service is the same.
+const healthService = v1Service
+
+// MarkRefreshed - a poll cycle finished successfully.
+func MarkRefreshed() {
+	lastRefresh.Store(time.Now().Unix())
+}
+
+// Ready - the index is loaded and the poller is not stuck.
+func Ready(now time.Time) (bool, string) {
+	CurrentDump.RLock()
+	utime := CurrentDump.utime
+	CurrentDump.RUnlock()
+
+	if utime == 0 {
+		return false, "index is not loaded"
+	}
+
+	// the paused poller is not stuck.
+	if m := Maintenance(); m.Paused {
+		return true, "ok, polling paused: " + m.Reason
+	}
+
+	if ReadyStaleness > 0 {
+		age := now.Sub(time.Unix(lastRefresh.Load(), 0))
+		if age > ReadyStaleness {
+			return false, fmt.Sprintf("last successful refresh %s ago", age.Truncate(time.Second))
+		}
+	}
+
+	return true, "ok"
+}
+
+// RegistryAge - time since the registry update time of the served dump, false if it is not loaded.
+func RegistryAge(now time.Time) (time.Duration, int64, bool) {
+	CurrentDump.RLock()
+	utime, generation := CurrentDump.utime, CurrentDump.generation
+	CurrentDump.RUnlock()
+
+	if utime == 0 {
+		return 0, 0, false
+	}
+
+	return now.Sub(time.Unix(utime, 0)), generation, true
+}
+
+// CheckRegistryAge - raise AlertRegistryStale once the registry data gets older than RegistryMaxAge.
+func CheckRegistryAge(now time.Time) {
+	if RegistryMaxAge <= 0 {
+		return
+	}
+
+	age, generation, ok := RegistryAge(now)
+	if !ok {
+		return
+	}
+
+	if age <= RegistryMaxAge {
+		registryStale.Store(false)
+
+		return
+	}
+
+	if registryStale.CompareAndSwap(false, true) {
+		RaiseAlert(AlertRegistryStale, generation, fmt.Sprintf("registry data is %s old, over %s", age.Truncate(time.Second), RegistryMaxAge))
+	}
+}
+
+// HealthWatch - keep gRPC health statuses up to date.
+func HealthWatch(hs *health.Server, done chan<- struct{}, kill <-chan struct{}) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	for {
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if ready, _ := Ready(time.Now()); ready {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+
+		hs.SetServingStatus(healthService, status)
+		hs.SetServingStatus(v2Service, status)
+
+		CheckRegistryAge(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-kill:
+			hs.Shutdown()
+			close(done)
+
+			return
+		}
+	}
+}
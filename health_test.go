@@ -0,0 +1,115 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReadiness tests liveness and readiness probes of an empty, a stale and a fresh index.
+func TestReadiness(t *testing.T) {
+	defer func(dump *Dump, staleness time.Duration, refresh int64) {
+		CurrentDump, ReadyStaleness = dump, staleness
+		lastRefresh.Store(refresh)
+	}(CurrentDump, ReadyStaleness, lastRefresh.Load())
+
+	CurrentDump, ReadyStaleness = NewDump(), time.Minute
+
+	probe := func(path string) int {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		NewGateway("", t.TempDir(), nil).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec.Code
+	}
+
+	if ready, reason := Ready(time.Now()); ready || probe("/readyz") != http.StatusServiceUnavailable {
+		t.Errorf("Empty index is ready: %s\n", reason)
+	}
+
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("Liveness of an empty index: %d\n", code)
+	}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	lastRefresh.Store(time.Now().Add(-time.Hour).Unix())
+
+	if ready, reason := Ready(time.Now()); ready || probe("/readyz") != http.StatusServiceUnavailable {
+		t.Errorf("Stale index is ready: %s\n", reason)
+	}
+
+	MarkRefreshed()
+
+	if ready, reason := Ready(time.Now()); !ready || probe("/readyz") != http.StatusOK {
+		t.Errorf("Fresh index is not ready: %s\n", reason)
+	}
+
+	// staleness is not checked if it is disabled.
+	ReadyStaleness = 0
+
+	lastRefresh.Store(0)
+
+	if ready, reason := Ready(time.Now()); !ready {
+		t.Errorf("Index is not ready without staleness: %s\n", reason)
+	}
+}
+
+// TestCheckRegistryAge tests the stale registry alert is raised once per staleness.
+func TestCheckRegistryAge(t *testing.T) {
+	defer func(dump *Dump, maxAge time.Duration) { CurrentDump, RegistryMaxAge = dump, maxAge }(CurrentDump, RegistryMaxAge)
+	defer registryStale.Store(false)
+
+	CurrentDump, RegistryMaxAge = NewDump(), 24*time.Hour
+
+	alerts := func() int64 {
+		if v, ok := metricAlerts.Get(AlertRegistryStale).(*expvar.Int); ok {
+			return v.Value()
+		}
+
+		return 0
+	}
+
+	if _, _, ok := RegistryAge(time.Now()); ok {
+		t.Errorf("Age of an empty dump\n")
+	}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the registry is updated at 2011-01-01T01:01:01+03:00.
+	updated := time.Date(2010, 12, 31, 22, 1, 1, 0, time.UTC)
+	before := alerts()
+
+	if age, _, ok := RegistryAge(updated.Add(time.Hour)); !ok || age != time.Hour {
+		t.Errorf("Age: %s %t\n", age, ok)
+	}
+
+	CheckRegistryAge(updated.Add(time.Hour))
+
+	if alerts() != before {
+		t.Errorf("Fresh registry is alerted\n")
+	}
+
+	CheckRegistryAge(updated.Add(25 * time.Hour))
+	CheckRegistryAge(updated.Add(26 * time.Hour))
+
+	if alerts() != before+1 {
+		t.Errorf("Stale registry alerts: %d\n", alerts()-before)
+	}
+
+	// a fresh dump rearms the alert.
+	CheckRegistryAge(updated.Add(time.Hour))
+	CheckRegistryAge(updated.Add(25 * time.Hour))
+
+	if alerts() != before+2 {
+		t.Errorf("Rearmed alerts: %d\n", alerts()-before)
+	}
+}
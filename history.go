@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StatsHistoryKeep - rows kept in the statistics history, 0 disables it. It is set once at startup.
+var StatsHistoryKeep = 100000
+
+const statsHistoryFilename = "stats.csv"
+
+// statsHistoryColumns - CSV header, the order of StatsPoint.row().
+var statsHistoryColumns = []string{
+	"time", "generation", "registry_update_time",
+	"count", "added", "updated", "removed", "duplicates",
+	"ip4", "ip6", "subnet4", "subnet6", "domains", "urls",
+	"max_id_set", "max_content", "size", "duration_ms",
+	"anomalies",
+}
+
+// statsHistoryV1Columns - rows written before the anomalies column, it is 0 for them.
+const statsHistoryV1Columns = 18
+
+// StatsPoint - statistics of one applied parse.
+type StatsPoint struct {
+	Time       int64 // unix time of the parse.
+	Generation int64
+	UpdateTime int64 // registry update time.
+	Count      int
+	Added      int
+	Updated    int
+	Removed    int
+	Duplicates int
+	IP4        int // index sizes.
+	IP6        int
+	Subnet4    int
+	Subnet6    int
+	Domains    int
+	URLs       int
+	MaxIDSet   int
+	MaxContent int
+	Size       int64 // dump bytes.
+	Duration   time.Duration
+	Anomalies  int // address anomalies of the current records.
+}
+
+// The history is appended by the poller and read by RPCs.
+var (
+	statsHistoryMu   sync.Mutex
+	statsHistoryRows = -1 // rows in the file, unknown until it is read.
+)
+
+// newStatsPoint - point of the current generation from parse statistics.
+func newStatsPoint(stats ParseStatistics) StatsPoint {
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	return StatsPoint{
+		Time:       stats.Updated.Unix(),
+		Generation: CurrentDump.generation,
+		UpdateTime: CurrentDump.utime,
+		Count:      stats.Count,
+		Added:      stats.AddCount,
+		Updated:    stats.UpdateCount,
+		Removed:    stats.RemoveCount,
+		Duplicates: stats.DuplicateCount,
+		IP4:        len(CurrentDump.ip4Idx),
+		IP6:        len(CurrentDump.ip6Idx),
+		Subnet4:    len(CurrentDump.subnet4Idx),
+		Subnet6:    len(CurrentDump.subnet6Idx),
+		Domains:    len(CurrentDump.domainIdx),
+		URLs:       len(CurrentDump.urlIdx),
+		MaxIDSet:   stats.MaxIDSetLen,
+		MaxContent: stats.MaxContentSize,
+		Size:       stats.Size,
+		Duration:   stats.Duration,
+		Anomalies:  stats.Anomalies,
+	}
+}
+
+func (p StatsPoint) row() []string {
+	ints := []int64{
+		p.Time, p.Generation, p.UpdateTime,
+		int64(p.Count), int64(p.Added), int64(p.Updated), int64(p.Removed), int64(p.Duplicates),
+		int64(p.IP4), int64(p.IP6), int64(p.Subnet4), int64(p.Subnet6), int64(p.Domains), int64(p.URLs),
+		int64(p.MaxIDSet), int64(p.MaxContent), p.Size, p.Duration.Milliseconds(),
+		int64(p.Anomalies),
+	}
+
+	row := make([]string, len(ints))
+	for i, v := range ints {
+		row[i] = strconv.FormatInt(v, 10)
+	}
+
+	return row
+}
+
+func parseStatsRow(row []string) (StatsPoint, error) {
+	if len(row) != len(statsHistoryColumns) && len(row) != statsHistoryV1Columns {
+		return StatsPoint{}, fmt.Errorf("%d columns", len(row))
+	}
+
+	v := make([]int64, len(statsHistoryColumns))
+
+	for i, s := range row {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return StatsPoint{}, fmt.Errorf("%s: %w", statsHistoryColumns[i], err)
+		}
+
+		v[i] = n
+	}
+
+	return StatsPoint{
+		Time: v[0], Generation: v[1], UpdateTime: v[2],
+		Count: int(v[3]), Added: int(v[4]), Updated: int(v[5]), Removed: int(v[6]), Duplicates: int(v[7]),
+		IP4: int(v[8]), IP6: int(v[9]), Subnet4: int(v[10]), Subnet6: int(v[11]), Domains: int(v[12]), URLs: int(v[13]),
+		MaxIDSet: int(v[14]), MaxContent: int(v[15]), Size: v[16], Duration: time.Duration(v[17]) * time.Millisecond,
+		Anomalies: int(v[18]),
+	}, nil
+}
+
+// AppendStatsHistory - append the point to dir/stats.csv, the oldest rows
+// are dropped when the file is a tenth over StatsHistoryKeep.
+func AppendStatsHistory(dir string, point StatsPoint) error {
+	if StatsHistoryKeep <= 0 {
+		return nil
+	}
+
+	statsHistoryMu.Lock()
+	defer statsHistoryMu.Unlock()
+
+	filename := dir + "/" + statsHistoryFilename
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		w.Write(statsHistoryColumns)
+	}
+
+	w.Write(point.row())
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		f.Close()
+
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	statsHistoryRows++
+
+	// the file is read once per process and on trims only.
+	if statsHistoryRows > 0 && statsHistoryRows <= StatsHistoryKeep+StatsHistoryKeep/10 {
+		return nil
+	}
+
+	points, err := readStatsHistory(filename)
+	if err != nil {
+		return err
+	}
+
+	statsHistoryRows = len(points)
+	if statsHistoryRows <= StatsHistoryKeep+StatsHistoryKeep/10 {
+		return nil
+	}
+
+	statsHistoryRows = StatsHistoryKeep
+
+	return writeStatsHistory(filename, points[len(points)-StatsHistoryKeep:])
+}
+
+// writeStatsHistory - rewrite the history through a temp file.
+func writeStatsHistory(filename string, points []StatsPoint) error {
+	tmpfilename := filename + "-tmp"
+
+	f, err := os.Create(tmpfilename)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+	w.Write(statsHistoryColumns)
+
+	for _, point := range points {
+		w.Write(point.row())
+	}
+
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		f.Close()
+
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tmpfilename, filename); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// readStatsHistory - all points, broken rows are skipped.
+func readStatsHistory(filename string) ([]StatsPoint, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(f))
+	r.FieldsPerRecord = -1
+
+	var points []StatsPoint
+
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			continue
+		}
+
+		point, err := parseStatsRow(row)
+		if err != nil {
+			continue // the header or a broken row.
+		}
+
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// StatsHistory - points with time in [from, to], at most limit latest ones.
+// Zero to means now, zero limit means all.
+func StatsHistory(dir string, from, to int64, limit int) ([]StatsPoint, error) {
+	statsHistoryMu.Lock()
+	points, err := readStatsHistory(dir + "/" + statsHistoryFilename)
+	statsHistoryMu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if to == 0 {
+		to = time.Now().Unix()
+	}
+
+	selected := points[:0]
+
+	for _, point := range points {
+		if point.Time >= from && point.Time <= to {
+			selected = append(selected, point)
+		}
+	}
+
+	if limit > 0 && len(selected) > limit {
+		selected = selected[len(selected)-limit:]
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestStatsHistory tests append, trim and range reads of the statistics history.
+func TestStatsHistory(t *testing.T) {
+	defer func(keep, rows int) { StatsHistoryKeep, statsHistoryRows = keep, rows }(StatsHistoryKeep, statsHistoryRows)
+
+	dir := t.TempDir()
+	StatsHistoryKeep, statsHistoryRows = 10, -1
+
+	for i := int64(1); i <= 11; i++ {
+		point := StatsPoint{Time: i * 100, Generation: i, Count: int(i), Duration: time.Duration(i) * time.Second}
+		if err := AppendStatsHistory(dir, point); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a torn write is skipped.
+	f, err := os.OpenFile(dir+"/"+statsHistoryFilename, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.WriteString("1200,12,0,1\n")
+	f.Close()
+
+	points, err := StatsHistory(dir, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(points) != 11 || points[10].Duration != 11*time.Second {
+		t.Fatalf("points: %v", points)
+	}
+
+	// 12 rows are over the tenth, the oldest two are dropped.
+	if err := AppendStatsHistory(dir, StatsPoint{Time: 1200, Generation: 12}); err != nil {
+		t.Fatal(err)
+	}
+
+	points, _ = StatsHistory(dir, 0, 0, 0)
+	if len(points) != 10 || points[0].Generation != 3 {
+		t.Fatalf("trim: %d points from %d", len(points), points[0].Generation)
+	}
+
+	points, _ = StatsHistory(dir, 500, 900, 2)
+	if len(points) != 2 || points[0].Generation != 8 || points[1].Generation != 9 {
+		t.Errorf("range: %v", points)
+	}
+}
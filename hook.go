@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Hook events.
+const (
+	HookAdded   = "added"
+	HookUpdated = "updated"
+	HookRemoved = "removed"
+)
+
+// hookFunction - Lua function called for every changed record: on_record(event, record).
+const hookFunction = "on_record"
+
+// Hook - Lua script with custom per-record processing. The script runs in one Lua state,
+// calls are serialized.
+type Hook struct {
+	mu      sync.Mutex
+	state   *lua.LState
+	fn      *lua.LFunction
+	timeout time.Duration // of one call, 0 means unlimited.
+}
+
+// Hooks - record hook, nil disables it. It is set once at startup.
+var Hooks *Hook
+
+// LoadHook - load the script, it must define on_record(event, record).
+// The script can use the Lua standard library and log(message).
+func LoadHook(filename string, timeout time.Duration) (*Hook, error) {
+	state := lua.NewState()
+
+	state.SetGlobal("log", state.NewFunction(func(L *lua.LState) int {
+		logger.Info.Printf("Hook: %s\n", L.CheckString(1))
+
+		return 0
+	}))
+
+	if err := state.DoFile(filename); err != nil {
+		state.Close()
+
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	fn, ok := state.GetGlobal(hookFunction).(*lua.LFunction)
+	if !ok {
+		state.Close()
+
+		return nil, fmt.Errorf("no %s function", hookFunction)
+	}
+
+	return &Hook{state: state, fn: fn, timeout: timeout}, nil
+}
+
+// Close - close the Lua state.
+func (h *Hook) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state.Close()
+}
+
+// Call - call the hook for the record.
+func (h *Hook) Call(event string, record *Content) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		defer cancel()
+
+		h.state.SetContext(ctx)
+		defer h.state.RemoveContext()
+	}
+
+	return h.state.CallByParam(lua.P{Fn: h.fn, NRet: 0, Protect: true}, lua.LString(event), h.recordTable(record))
+}
+
+// recordTable - the record as a Lua table, IPs are strings.
+func (h *Hook) recordTable(record *Content) *lua.LTable {
+	L := h.state
+	t := L.NewTable()
+
+	t.RawSetString("id", lua.LNumber(record.ID))
+	t.RawSetString("entry_type", lua.LNumber(record.EntryType))
+	t.RawSetString("urgency_type", lua.LNumber(record.UrgencyType))
+	t.RawSetString("include_time", lua.LNumber(record.IncludeTime))
+	t.RawSetString("block_type", lua.LString(record.BlockType))
+	t.RawSetString("hash", lua.LString(record.Hash))
+	t.RawSetString("record_hash", lua.LString(strconv.FormatUint(record.RecordHash, 16)))
+
+	decision := L.NewTable()
+	decision.RawSetString("date", lua.LString(record.Decision.Date))
+	decision.RawSetString("number", lua.LString(record.Decision.Number))
+	decision.RawSetString("org", lua.LString(record.Decision.Org))
+	t.RawSetString("decision", decision)
+
+	list := func(name string, values []string) {
+		l := L.NewTable()
+		for _, v := range values {
+			l.Append(lua.LString(v))
+		}
+
+		t.RawSetString(name, l)
+	}
+
+	urls := make([]string, 0, len(record.URL))
+	for _, u := range record.URL {
+		urls = append(urls, u.URL)
+	}
+
+	domains := make([]string, 0, len(record.Domain))
+	for _, domain := range record.Domain {
+		domains = append(domains, domain.Domain)
+	}
+
+	ip4s := make([]string, 0, len(record.IP4))
+	for _, ip4 := range record.IP4 {
+		ip4s = append(ip4s, ip4.IP4.String())
+	}
+
+	ip6s := make([]string, 0, len(record.IP6))
+	for _, ip6 := range record.IP6 {
+		ip6s = append(ip6s, ip6.IP6.String())
+	}
+
+	subnet4s := make([]string, 0, len(record.Subnet4))
+	for _, subnet4 := range record.Subnet4 {
+		subnet4s = append(subnet4s, subnet4.Subnet4.String())
+	}
+
+	subnet6s := make([]string, 0, len(record.Subnet6))
+	for _, subnet6 := range record.Subnet6 {
+		subnet6s = append(subnet6s, subnet6.Subnet6.String())
+	}
+
+	list("url", urls)
+	list("domain", domains)
+	list("ip4", ip4s)
+	list("ip6", ip6s)
+	list("subnet4", subnet4s)
+	list("subnet6", subnet6s)
+
+	return t
+}
+
+// RunHooks - call the hook for records of the just published generation:
+// staged ones are added or updated, removed ones are decoded from their payload.
+func RunHooks(s *parseStage, added []int32, removed []*PackedContent) {
+	if Hooks == nil {
+		return
+	}
+
+	fresh := make(Int32Map, len(added))
+	for _, id := range added {
+		fresh[id] = Nothing{}
+	}
+
+	failed := 0
+
+	call := func(event string, record *Content) {
+		if err := Hooks.Call(event, record); err != nil {
+			logger.Debug.Printf("Hook failed: %s %d: %s\n", event, record.ID, err.Error())
+
+			failed++
+		}
+	}
+
+	for _, id := range s.order {
+		if s.records[id].same {
+			continue
+		}
+
+		event := HookUpdated
+		if _, ok := fresh[id]; ok {
+			event = HookAdded
+		}
+
+		call(event, s.records[id].content)
+	}
+
+	// removed records are out of the index, nobody changes them.
+	for _, pack := range removed {
+		record, err := pack.Record()
+		if err != nil {
+			logger.Error.Printf("Can't decode payload: %d: %s\n", pack.ID, err.Error())
+
+			continue
+		}
+
+		call(HookRemoved, record)
+	}
+
+	if failed > 0 {
+		logger.Error.Printf("Hook failed for %d records\n", failed)
+	}
+}
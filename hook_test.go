@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHook tests the Lua hook calls for changed records.
+func TestHook(t *testing.T) {
+	defer func(dump *Dump, hooks *Hook) { CurrentDump, Hooks = dump, hooks }(CurrentDump, Hooks)
+
+	script := t.TempDir() + "/hook.lua"
+	err := os.WriteFile(script, []byte(`
+events = {}
+
+function on_record(event, record)
+	table.insert(events, event .. " " .. record.id .. " " .. record.decision.org .. " " .. table.concat(record.ip4, ","))
+end
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook, err := LoadHook(script, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer hook.Close()
+
+	CurrentDump, Hooks = NewDump(), hook
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 111 is changed, 555 is removed, 666 is added.
+	next := strings.Replace(xml01, `hash="XXXX"`, `hash="XXXZ"`, 1)
+	next = strings.Replace(next, "<ip>10.1.1.1</ip>", "<ip>10.1.1.2</ip>", 1)
+	next = strings.Replace(next, `id="555"`, `id="666"`, 1)
+
+	if err := Parse(strings.NewReader(next)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hook.state.DoString(`result = table.concat(events, "; ")`); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "updated 111 ONE 192.168.1.11,192.168.0.100,10.1.1.2; added 666 FIVE 192.168.5.55,192.168.0.111,10.5.5.5; removed 555 FIVE 192.168.5.55,192.168.0.111,10.5.5.5"
+	if got := hook.state.GetGlobal("result").String(); got != want {
+		t.Errorf("events: %s", got)
+	}
+}
+
+// TestHookTimeout tests that a stuck hook is stopped.
+func TestHookTimeout(t *testing.T) {
+	script := t.TempDir() + "/hook.lua"
+	if err := os.WriteFile(script, []byte(`function on_record(event, record) while true do end end`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hook, err := LoadHook(script, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer hook.Close()
+
+	if err := hook.Call(HookAdded, &Content{ID: 1}); err == nil {
+		t.Error("stuck hook is not stopped")
+	}
+
+	if err := os.WriteFile(script, []byte(`x = 1`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadHook(script, 0); err == nil {
+		t.Error("script without on_record is loaded")
+	}
+}
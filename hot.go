@@ -0,0 +1,191 @@
+package main
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// hotMinCount - selectors of fewer records are not tracked, they are not shared.
+const hotMinCount = 2
+
+// hotKey - indexed selector: ip for IPs, subnet for subnets, key for domains and URLs.
+type hotKey struct {
+	kind   string
+	ip     netip.Addr
+	subnet netip.Prefix
+	key    string
+}
+
+// String - the selector as it is queried.
+func (k hotKey) String() string {
+	switch k.kind {
+	case SelectorIP4, SelectorIP6:
+		return k.ip.String()
+	case SelectorSubnet4, SelectorSubnet6:
+		return k.subnet.String()
+	}
+
+	return k.key
+}
+
+// HotSelector - selector with the number of records referencing it.
+type HotSelector struct {
+	Sel   Selector
+	Count int
+}
+
+// hotCounter - record counts of shared IPs, subnets, domains and URLs bucketed by the count.
+// It is kept by the index functions, so the most referenced ones are read without a scan.
+type hotCounter struct {
+	count   map[hotKey]int
+	buckets map[int]map[hotKey]Nothing
+	kinds   map[string]int // tracked selectors of the kind.
+	max     int
+}
+
+func newHotCounter() hotCounter {
+	return hotCounter{
+		count:   make(map[hotKey]int),
+		buckets: make(map[int]map[hotKey]Nothing),
+		kinds:   make(map[string]int),
+	}
+}
+
+// set - the selector is referenced by n records now.
+func (h *hotCounter) set(k hotKey, n int) {
+	old := h.count[k]
+	if old == n || (old < hotMinCount && n < hotMinCount) {
+		return
+	}
+
+	if old >= hotMinCount {
+		bucket := h.buckets[old]
+		delete(bucket, k)
+
+		if len(bucket) == 0 {
+			delete(h.buckets, old)
+		}
+	}
+
+	if n < hotMinCount {
+		delete(h.count, k)
+		h.kinds[k.kind]--
+
+		for h.max >= hotMinCount && h.buckets[h.max] == nil {
+			h.max--
+		}
+
+		return
+	}
+
+	if old < hotMinCount {
+		h.kinds[k.kind]++
+	}
+
+	h.count[k] = n
+
+	bucket, ok := h.buckets[n]
+	if !ok {
+		bucket = make(map[hotKey]Nothing)
+		h.buckets[n] = bucket
+	}
+
+	bucket[k] = Nothing{}
+
+	if n > h.max {
+		h.max = n
+	}
+
+	for h.max >= hotMinCount && h.buckets[h.max] == nil {
+		h.max--
+	}
+}
+
+// total - tracked selectors of the kind (all kinds if it is empty) of at least minCount records.
+func (h *hotCounter) total(kind string, minCount int) int {
+	if minCount <= hotMinCount {
+		if kind != "" {
+			return h.kinds[kind]
+		}
+
+		return len(h.count)
+	}
+
+	var n int
+
+	for c := h.max; c >= minCount; c-- {
+		if kind == "" {
+			n += len(h.buckets[c])
+
+			continue
+		}
+
+		for k := range h.buckets[c] {
+			if k.kind == kind {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// top - the first n selectors of the kind (all if it is empty) of at least minCount records by record count,
+// equal counts are ordered by kind and value.
+func (h *hotCounter) top(kind string, minCount, n int) []HotSelector {
+	var result []HotSelector
+
+	if minCount < hotMinCount {
+		minCount = hotMinCount
+	}
+
+	for c := h.max; c >= minCount && len(result) < n; c-- {
+		start := len(result)
+
+		for k := range h.buckets[c] {
+			if kind == "" || k.kind == kind {
+				result = append(result, HotSelector{Sel: Selector{k.kind, k.String()}, Count: c})
+			}
+		}
+
+		page := result[start:]
+		sort.Slice(page, func(i, j int) bool {
+			if page[i].Sel.Kind != page[j].Sel.Kind {
+				return page[i].Sel.Kind < page[j].Sel.Kind
+			}
+
+			return page[i].Sel.Value < page[j].Sel.Value
+		})
+	}
+
+	if len(result) > n {
+		result = result[:n]
+	}
+
+	return result
+}
+
+// HotSelectors - IPs, subnets, domains and URLs of the most records, kind is a selector kind
+// or empty for all of them. Only selectors of at least minCount records, and at least hotMinCount,
+// are counted. Call it under read lock.
+func (dump *Dump) HotSelectors(kind string, minCount int, offset, limit int32) ([]HotSelector, int) {
+	total := dump.hot.total(kind, minCount)
+	start, end := pageBounds(total, offset, limit)
+
+	top := dump.hot.top(kind, minCount, end)
+	if start > len(top) {
+		return nil, total
+	}
+
+	return top[start:], total
+}
+
+// hotKind - the kind is counted, empty means all.
+func hotKind(kind string) bool {
+	switch kind {
+	case "", SelectorIP4, SelectorIP6, SelectorSubnet4, SelectorSubnet6, SelectorDomain, SelectorURL:
+		return true
+	}
+
+	return false
+}
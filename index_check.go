@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// SelectorDecision - decision index pseudo selector, value is the decimal hash.
+const SelectorDecision = "decision"
+
+// IndexProblem - disagreement between an index and ContentIdx.
+type IndexProblem struct {
+	Sel      Selector
+	ID       int32
+	Dangling bool // index entry without a record carrying it, otherwise a record selector missing from the index.
+}
+
+func (p IndexProblem) String() string {
+	if p.Dangling {
+		return fmt.Sprintf("dangling %s %s -> %d", p.Sel.Kind, p.Sel.Value, p.ID)
+	}
+
+	return fmt.Sprintf("missing %s %s <- %d", p.Sel.Kind, p.Sel.Value, p.ID)
+}
+
+// recordSelectors - normalized selectors of the record including the decision.
+func (pack *PackedContent) recordSelectors() SelectorSet {
+	set := make(SelectorSet)
+
+	pack.addSelectors(set)
+	set[Selector{SelectorDecision, strconv.FormatUint(pack.Decision, 10)}] = Nothing{}
+
+	return set
+}
+
+// lookupIndex - index entry of the selector, call it under lock.
+func (dump *Dump) lookupIndex(sel Selector) ArrayIntSet {
+	switch sel.Kind {
+	case SelectorIP4:
+		ip, _ := parser.ParseIP4(sel.Value)
+
+		return dump.ip4Idx[ip]
+	case SelectorIP6:
+		ip, _ := parser.ParseIP6(sel.Value)
+
+		return dump.ip6Idx[ip]
+	case SelectorSubnet4, SelectorSubnet6:
+		subnet, _ := netip.ParsePrefix(sel.Value)
+		if sel.Kind == SelectorSubnet4 {
+			return dump.subnet4Idx[subnet]
+		}
+
+		return dump.subnet6Idx[subnet]
+	case SelectorDomain:
+		return dump.domainIdx[sel.Value]
+	case SelectorURL:
+		return dump.urlIdx[sel.Value]
+	case SelectorDecision:
+		decision, err := strconv.ParseUint(sel.Value, 10, 64)
+		if err != nil {
+			return nil
+		}
+
+		return dump.decisionIdx[decision]
+	}
+
+	return nil
+}
+
+// rangeIndexes - call fn for every entry of every selector index, call it under lock.
+// fn returns false to skip the rest of the current index.
+func (dump *Dump) rangeIndexes(fn func(sel Selector, ids ArrayIntSet) bool) {
+	for ip4, ids := range dump.ip4Idx {
+		if !fn(Selector{SelectorIP4, ip4.String()}, ids) {
+			break
+		}
+	}
+
+	for ip6, ids := range dump.ip6Idx {
+		if !fn(Selector{SelectorIP6, ip6.String()}, ids) {
+			break
+		}
+	}
+
+	for subnet4, ids := range dump.subnet4Idx {
+		if !fn(Selector{SelectorSubnet4, subnet4.String()}, ids) {
+			break
+		}
+	}
+
+	for subnet6, ids := range dump.subnet6Idx {
+		if !fn(Selector{SelectorSubnet6, subnet6.String()}, ids) {
+			break
+		}
+	}
+
+	for _, idx := range []struct {
+		kind string
+		set  StringIntSet
+	}{
+		{SelectorDomain, dump.domainIdx},
+		{SelectorURL, dump.urlIdx},
+	} {
+		for key, ids := range idx.set {
+			if !fn(Selector{idx.kind, key}, ids) {
+				break
+			}
+		}
+	}
+
+	for decision, ids := range dump.decisionIdx {
+		if !fn(Selector{SelectorDecision, strconv.FormatUint(decision, 10)}, ids) {
+			break
+		}
+	}
+}
+
+// checkRecord - selectors of the record missing from indexes, call it under lock.
+func (dump *Dump) checkRecord(pack *PackedContent) []IndexProblem {
+	var problems []IndexProblem
+
+	for sel := range pack.recordSelectors() {
+		if !containsID(dump.lookupIndex(sel), pack.ID) {
+			problems = append(problems, IndexProblem{Sel: sel, ID: pack.ID})
+		}
+	}
+
+	return problems
+}
+
+// checkIndexEntry - ids of the index entry pointing to absent records
+// or to records without the selector, call it under lock.
+// selectors returns selectors of the record, it may be memoized by the caller.
+func (dump *Dump) checkIndexEntry(sel Selector, ids ArrayIntSet, selectors func(*PackedContent) SelectorSet) []IndexProblem {
+	var problems []IndexProblem
+
+	for _, id := range ids {
+		pack, ok := dump.ContentIdx[id]
+		if ok {
+			_, ok = selectors(pack)[sel]
+		}
+
+		if !ok {
+			problems = append(problems, IndexProblem{Sel: sel, ID: id, Dangling: true})
+		}
+	}
+
+	return problems
+}
+
+func containsID(ids ArrayIntSet, id int32) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
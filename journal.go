@@ -0,0 +1,230 @@
+package main
+
+// journalMaxDense - ids up to this are kept in the bitset, 16 MB at most, others in the sparse map.
+const journalMaxDense = 1 << 27
+
+// IDJournal - set of content ids seen by a parse. Registry ids are dense, so a bitset is smaller
+// and cheaper than a map, it is reused by the next parse, see acquireJournal.
+type IDJournal struct {
+	bits   []uint64
+	sparse map[int32]Nothing // negative and huge ids.
+	n      int
+	reused bool // the bitset is left by a previous parse.
+}
+
+// journalSlot - the journal of the last committed parse, parses are sequential.
+var journalSlot = make(chan *IDJournal, 1)
+
+// acquireJournal - the journal of the previous parse cleared, or a new one.
+func acquireJournal() *IDJournal {
+	select {
+	case j := <-journalSlot:
+		j.reset()
+		j.reused = true
+
+		return j
+	default:
+		return &IDJournal{}
+	}
+}
+
+// releaseJournal - keep the journal for the next parse, it must not be used after.
+func releaseJournal(j *IDJournal) {
+	select {
+	case journalSlot <- j:
+	default:
+	}
+}
+
+// reset - empty the journal keeping the bitset.
+func (j *IDJournal) reset() {
+	for i := range j.bits {
+		j.bits[i] = 0
+	}
+
+	j.sparse, j.n = nil, 0
+}
+
+// Add - add the id, false if it is already there.
+func (j *IDJournal) Add(id int32) bool {
+	if id < 0 || id >= journalMaxDense {
+		if _, ok := j.sparse[id]; ok {
+			return false
+		}
+
+		if j.sparse == nil {
+			j.sparse = make(map[int32]Nothing)
+		}
+
+		j.sparse[id] = Nothing{}
+		j.n++
+
+		return true
+	}
+
+	w := int(id >> 6)
+	if w >= len(j.bits) {
+		j.grow(w + 1)
+	}
+
+	mask := uint64(1) << (uint(id) & 63)
+	if j.bits[w]&mask != 0 {
+		return false
+	}
+
+	j.bits[w] |= mask
+	j.n++
+
+	return true
+}
+
+// grow - room for at least words, doubled to amortize.
+func (j *IDJournal) grow(words int) {
+	size := 2 * len(j.bits)
+	if size < words {
+		size = words
+	}
+
+	if limit := journalMaxDense >> 6; size > limit {
+		size = limit
+	}
+
+	if size <= cap(j.bits) {
+		j.bits = j.bits[:size]
+
+		return
+	}
+
+	grown := make([]uint64, size)
+	copy(grown, j.bits)
+	j.bits = grown
+}
+
+// Has - is the id in the journal?
+func (j *IDJournal) Has(id int32) bool {
+	if id < 0 || id >= journalMaxDense {
+		_, ok := j.sparse[id]
+
+		return ok
+	}
+
+	w := int(id >> 6)
+
+	return w < len(j.bits) && j.bits[w]&(uint64(1)<<(uint(id)&63)) != 0
+}
+
+// Len - number of ids.
+func (j *IDJournal) Len() int {
+	return j.n
+}
+
+// Bytes - approximate memory of the journal.
+func (j *IDJournal) Bytes() int64 {
+	// a map entry is about the key, the empty value and the bucket overhead.
+	return int64(cap(j.bits))*8 + int64(len(j.sparse))*16
+}
+
+// ordinalsMaxDense - ids up to this are kept in the slice, 64 MB at most, others in the sparse map.
+const ordinalsMaxDense = 1 << 24
+
+// IDOrdinals - 1-based positions of content ids in the dump. Like IDJournal it is dense by id
+// and reused: the ordinals of the replaced generation are taken by a next parse, see acquireOrdinals.
+type IDOrdinals struct {
+	dense  []int32
+	sparse map[int32]int32 // negative and huge ids.
+}
+
+// ordinalsSlot - the ordinals of the replaced generation, parses are sequential.
+var ordinalsSlot = make(chan *IDOrdinals, 1)
+
+// acquireOrdinals - the ordinals of a replaced generation cleared, or new ones.
+func acquireOrdinals() *IDOrdinals {
+	select {
+	case o := <-ordinalsSlot:
+		o.reset()
+
+		return o
+	default:
+		return &IDOrdinals{}
+	}
+}
+
+// releaseOrdinals - keep the ordinals of the replaced generation for a next parse, call it under lock,
+// they must not be used after.
+func releaseOrdinals(o *IDOrdinals) {
+	if o == nil {
+		return
+	}
+
+	select {
+	case ordinalsSlot <- o:
+	default:
+	}
+}
+
+// reset - no ids, keeping the slice.
+func (o *IDOrdinals) reset() {
+	for i := range o.dense {
+		o.dense[i] = 0
+	}
+
+	o.sparse = nil
+}
+
+// Set - position of the id.
+func (o *IDOrdinals) Set(id, ordinal int32) {
+	if id < 0 || id >= ordinalsMaxDense {
+		if o.sparse == nil {
+			o.sparse = make(map[int32]int32)
+		}
+
+		o.sparse[id] = ordinal
+
+		return
+	}
+
+	if int(id) >= len(o.dense) {
+		o.grow(int(id) + 1)
+	}
+
+	o.dense[id] = ordinal
+}
+
+// Get - position of the id, 0 if it is not in the dump or the ordinals are nil.
+func (o *IDOrdinals) Get(id int32) int32 {
+	if o == nil {
+		return 0
+	}
+
+	if id < 0 || id >= ordinalsMaxDense {
+		return o.sparse[id]
+	}
+
+	if int(id) < len(o.dense) {
+		return o.dense[id]
+	}
+
+	return 0
+}
+
+// grow - room for at least n ids, doubled to amortize.
+func (o *IDOrdinals) grow(n int) {
+	size := 2 * len(o.dense)
+	if size < n {
+		size = n
+	}
+
+	if size > ordinalsMaxDense {
+		size = ordinalsMaxDense
+	}
+
+	if size <= cap(o.dense) {
+		o.dense = o.dense[:size]
+
+		return
+	}
+
+	grown := make([]int32, size)
+	copy(grown, o.dense)
+	o.dense = grown
+}
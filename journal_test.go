@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestIDJournal tests dense and sparse ids, and reuse of the journal between parses.
+func TestIDJournal(t *testing.T) {
+	j := &IDJournal{}
+
+	for _, id := range []int32{5, 64, 5, -1, journalMaxDense + 1, -1} {
+		j.Add(id)
+	}
+
+	if j.Len() != 4 {
+		t.Errorf("Len: %d, expected 4", j.Len())
+	}
+
+	for _, id := range []int32{5, 64, -1, journalMaxDense + 1} {
+		if !j.Has(id) {
+			t.Errorf("Has(%d): false", id)
+		}
+	}
+
+	for _, id := range []int32{0, 63, 65, 1 << 20, -2} {
+		if j.Has(id) {
+			t.Errorf("Has(%d): true", id)
+		}
+	}
+
+	j.reset()
+
+	if j.Len() != 0 || j.Has(5) || j.Has(-1) || len(j.bits) == 0 {
+		t.Errorf("reset: %d ids, %d words", j.Len(), len(j.bits))
+	}
+
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	for i := 0; i < 2; i++ {
+		if err := Parse(strings.NewReader(xml01)); err != nil {
+			t.Fatalf("Parse: %s", err.Error())
+		}
+	}
+
+	if !Stats.JournalReused || Stats.JournalBytes == 0 {
+		t.Errorf("Stats: reused %t, %d bytes", Stats.JournalReused, Stats.JournalBytes)
+	}
+
+	if len(CurrentDump.ContentIdx) != 5 {
+		t.Errorf("Records: %d, expected 5", len(CurrentDump.ContentIdx))
+	}
+}
+
+// TestIDOrdinals tests dense and sparse ids, and reuse of the ordinals of a replaced generation.
+func TestIDOrdinals(t *testing.T) {
+	o := &IDOrdinals{}
+
+	for i, id := range []int32{5, 64, -1, ordinalsMaxDense + 1} {
+		o.Set(id, int32(i+1))
+	}
+
+	if o.Get(5) != 1 || o.Get(64) != 2 || o.Get(-1) != 3 || o.Get(ordinalsMaxDense+1) != 4 || o.Get(6) != 0 || o.Get(1<<20) != 0 {
+		t.Errorf("Get: %v %v", o.dense, o.sparse)
+	}
+
+	if (*IDOrdinals)(nil).Get(5) != 0 {
+		t.Error("nil ordinals must have none")
+	}
+
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	var generations []*IDOrdinals
+
+	for i := 0; i < 3; i++ {
+		if err := Parse(strings.NewReader(xml01)); err != nil {
+			t.Fatalf("Parse: %s", err.Error())
+		}
+
+		generations = append(generations, CurrentDump.ordinals)
+	}
+
+	// two generations alternate, the third parse takes the ordinals of the first one.
+	if generations[2] != generations[0] || generations[1] == generations[0] {
+		t.Errorf("Ordinals are not reused: %p %p %p", generations[0], generations[1], generations[2])
+	}
+
+	if CurrentDump.ordinals.Get(111) != 1 || CurrentDump.ordinals.Get(555) != 5 {
+		t.Errorf("Ordinals: %v", CurrentDump.ordinals.dense[100:])
+	}
+}
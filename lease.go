@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// LeaseRecord - content of the shared lease file.
+type LeaseRecord struct {
+	Owner     string `json:"owner"`
+	Heartbeat int64  `json:"hb"`
+}
+
+// Lease - file based leadership lease for a hot/warm pair of instances.
+// Both instances serve queries, only the leader polls the dump service.
+// The leader renews the heartbeat, the standby takes over when
+// the heartbeat is older than ttl.
+type Lease struct {
+	filename string
+	owner    string
+	ttl      time.Duration
+	leader   atomic.Bool
+}
+
+// NewLease - Lease constructor.
+func NewLease(filename, owner string, ttl time.Duration) *Lease {
+	return &Lease{
+		filename: filename,
+		owner:    owner,
+		ttl:      ttl,
+	}
+}
+
+// DefaultLeaseOwner - hostname and pid of the current process.
+func DefaultLeaseOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// Leader - are we holding the lease? Nil lease means single instance mode.
+func (l *Lease) Leader() bool {
+	if l == nil {
+		return true
+	}
+
+	return l.leader.Load()
+}
+
+// TryAcquire - acquire or renew the lease if it is free, stale or ours.
+func (l *Lease) TryAcquire(now time.Time) (bool, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return false, err
+	}
+
+	defer unlock()
+
+	record, err := l.read()
+	if err != nil {
+		return false, err
+	}
+
+	if record.Owner != "" && record.Owner != l.owner && now.Sub(time.Unix(record.Heartbeat, 0)) < l.ttl {
+		l.leader.Store(false)
+
+		return false, nil
+	}
+
+	err = l.write(LeaseRecord{Owner: l.owner, Heartbeat: now.Unix()})
+	if err != nil {
+		l.leader.Store(false)
+
+		return false, err
+	}
+
+	l.leader.Store(true)
+
+	return true, nil
+}
+
+// Release - drop the lease if we are holding it, so the standby can take over at once.
+func (l *Lease) Release() error {
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	l.leader.Store(false)
+
+	record, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	if record.Owner != l.owner {
+		return nil
+	}
+
+	err = os.Remove(l.filename)
+	if err != nil {
+		return fmt.Errorf("remove lease: %w", err)
+	}
+
+	return nil
+}
+
+// Heartbeat - renew or acquire the lease periodically.
+func (l *Lease) Heartbeat(done chan<- struct{}, kill <-chan struct{}) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		was := l.Leader()
+
+		leader, err := l.TryAcquire(time.Now())
+		if err != nil {
+			logger.Error.Printf("Can't acquire lease: %s\n", err.Error())
+		}
+
+		switch {
+		case leader && !was:
+			logger.Warning.Printf("Lease acquired, %s is the leader now\n", l.owner)
+		case !leader && was:
+			logger.Warning.Printf("Lease lost, %s is the standby now\n", l.owner)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-kill:
+			if err := l.Release(); err != nil {
+				logger.Error.Printf("Can't release lease: %s\n", err.Error())
+			}
+
+			close(done)
+
+			return
+		}
+	}
+}
+
+// lock - serialize read-modify-write of the lease file between instances.
+func (l *Lease) lock() (func(), error) {
+	f, err := os.OpenFile(l.filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock: %w", err)
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		f.Close()
+
+		return nil, fmt.Errorf("flock: %w", err)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (l *Lease) read() (LeaseRecord, error) {
+	record := LeaseRecord{}
+
+	dat, err := os.ReadFile(l.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return record, nil
+		}
+
+		return record, fmt.Errorf("read lease: %w", err)
+	}
+
+	err = json.Unmarshal(dat, &record)
+	if err != nil {
+		// broken lease is a free lease.
+		logger.Warning.Printf("Can't unmarshal lease: %s\n", err.Error())
+
+		return LeaseRecord{}, nil
+	}
+
+	return record, nil
+}
+
+func (l *Lease) write(record LeaseRecord) error {
+	dat, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	tfn := l.filename + "-tmp"
+
+	err = os.WriteFile(tfn, dat, 0644)
+	if err != nil {
+		return fmt.Errorf("write lease: %w", err)
+	}
+
+	err = os.Rename(tfn, l.filename)
+	if err != nil {
+		return fmt.Errorf("rename lease: %w", err)
+	}
+
+	return nil
+}
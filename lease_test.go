@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLease tests the warm standby lease handover.
+func TestLease(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "lease")
+	primary := NewLease(filename, "primary", 30*time.Second)
+	standby := NewLease(filename, "standby", 30*time.Second)
+	now := time.Now()
+
+	if ok, err := primary.TryAcquire(now); !ok || err != nil {
+		t.Fatalf("primary must acquire free lease: %v %v", ok, err)
+	}
+
+	if ok, err := standby.TryAcquire(now.Add(10 * time.Second)); ok || err != nil {
+		t.Fatalf("standby must not acquire fresh lease: %v %v", ok, err)
+	}
+
+	if ok, err := primary.TryAcquire(now.Add(20 * time.Second)); !ok || err != nil {
+		t.Fatalf("primary must renew own lease: %v %v", ok, err)
+	}
+
+	if ok, err := standby.TryAcquire(now.Add(60 * time.Second)); !ok || err != nil {
+		t.Fatalf("standby must take over stale lease: %v %v", ok, err)
+	}
+
+	if err := primary.Release(); err != nil || primary.Leader() {
+		t.Fatalf("primary release must not touch foreign lease: %v", err)
+	}
+
+	if !standby.Leader() {
+		t.Fatalf("standby must stay the leader")
+	}
+
+	if err := standby.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	if ok, err := primary.TryAcquire(now.Add(61 * time.Second)); !ok || err != nil {
+		t.Fatalf("primary must acquire released lease: %v %v", ok, err)
+	}
+
+	var single *Lease
+	if !single.Leader() {
+		t.Fatalf("nil lease is always the leader")
+	}
+}
+
+// countingSource - dump source counting refreshes.
+type countingSource struct{ refreshes int }
+
+func (c *countingSource) Refresh(ctx context.Context, dir string) { c.refreshes++ }
+
+// TestPollStandby tests that only the lease holder refreshes and the standby is not marked fresh.
+func TestPollStandby(t *testing.T) {
+	defer func(refresh int64) { lastRefresh.Store(refresh) }(lastRefresh.Load())
+	defer func(staleness time.Duration) { ReadyStaleness = staleness }(ReadyStaleness)
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	ReadyStaleness = time.Minute
+	stale := time.Now().Add(-time.Hour).Unix()
+	lastRefresh.Store(stale)
+
+	filename := filepath.Join(t.TempDir(), "lease")
+	primary := NewLease(filename, "primary", 30*time.Second)
+	standby := NewLease(filename, "standby", 30*time.Second)
+
+	if ok, err := primary.TryAcquire(time.Now()); !ok || err != nil {
+		t.Fatalf("primary must acquire free lease: %v %v", ok, err)
+	}
+
+	source := &countingSource{}
+
+	pollOnce(context.Background(), standby, source, t.TempDir())
+
+	if source.refreshes != 0 || lastRefresh.Load() != stale {
+		t.Errorf("standby: %d refreshes, last refresh %d", source.refreshes, lastRefresh.Load())
+	}
+
+	if ready, _ := Ready(time.Now()); ready {
+		t.Errorf("standby with a stale index is ready")
+	}
+
+	pollOnce(context.Background(), primary, source, t.TempDir())
+
+	if source.refreshes != 1 {
+		t.Errorf("leader: %d refreshes", source.refreshes)
+	}
+}
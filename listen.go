@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Listener names of socket activation, FileDescriptorName= of the systemd socket units.
+const (
+	ListenerGRPC = "grpc"
+	ListenerHTTP = "http"
+)
+
+// sdListenFDsStart - the first passed file descriptor, see sd_listen_fds(3).
+const sdListenFDsStart = 3
+
+// ListenReusePort - listen with SO_REUSEPORT, a new instance binds the same port before the old one exits.
+// It is set once at startup.
+var ListenReusePort bool
+
+// activatedListeners - sockets passed by the service manager by name, unnamed ones are "fd3", "fd4"...
+// They are taken once, see ActivatedListeners.
+var activatedListeners map[string]net.Listener
+
+// ActivatedListeners - take the stream sockets passed by systemd socket activation, if they are for this process.
+// The first unnamed one is the gRPC listener, the second one is the HTTP gateway.
+func ActivatedListeners() (map[string]net.Listener, error) {
+	listeners := make(map[string]net.Listener)
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return listeners, nil
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return listeners, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// not for children.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	unnamed := []string{ListenerGRPC, ListenerHTTP}
+
+	for i := 0; i < n; i++ {
+		fd := sdListenFDsStart + i
+		syscall.CloseOnExec(fd)
+
+		name := "fd" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" && names[i] != "unknown" {
+			name = names[i]
+		} else if len(unnamed) > 0 {
+			name, unnamed = unnamed[0], unnamed[1:]
+		}
+
+		f := os.NewFile(uintptr(fd), name)
+
+		ln, err := net.FileListener(f)
+		f.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("fd %d: %w", fd, err)
+		}
+
+		listeners[name] = ln
+	}
+
+	return listeners, nil
+}
+
+// Listen - the activated socket of the name or a new TCP listener of the address.
+func Listen(name, addr string) (net.Listener, error) {
+	if ln, ok := activatedListeners[name]; ok {
+		delete(activatedListeners, name)
+
+		return ln, nil
+	}
+
+	lc := net.ListenConfig{}
+	if ListenReusePort {
+		lc.Control = reusePort
+	}
+
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// reusePort - set SO_REUSEPORT on the socket before it is bound.
+func reusePort(network, address string, c syscall.RawConn) error {
+	var serr error
+
+	if err := c.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); err != nil {
+		return err
+	}
+
+	return serr
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestListenReusePort tests two listeners of the same port with SO_REUSEPORT.
+func TestListenReusePort(t *testing.T) {
+	defer func(reuse bool) { ListenReusePort = reuse }(ListenReusePort)
+
+	ListenReusePort = true
+
+	old, err := Listen(ListenerGRPC, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer old.Close()
+
+	fresh, err := Listen(ListenerGRPC, old.Addr().String())
+	if err != nil {
+		t.Fatalf("Reuse port: %s\n", err.Error())
+	}
+
+	fresh.Close()
+
+	ListenReusePort = false
+
+	if ln, err := Listen(ListenerGRPC, old.Addr().String()); err == nil {
+		ln.Close()
+		t.Errorf("Port is bound twice without SO_REUSEPORT\n")
+	}
+}
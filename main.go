@@ -11,24 +11,137 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strings"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/usher2/u2ckdump/internal/logger"
 	pb "github.com/usher2/u2ckdump/msg"
+	pbv2 "github.com/usher2/u2ckdump/msg/v2"
+	"github.com/usher2/u2ckdump/parser"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if code, ok := RunCommand(os.Args[1], os.Args[2:]); ok {
+			os.Exit(code)
+		}
+	}
+
 	debug.SetGCPercent(20)
 	//go func() {
 	//	logger.Println(http.ListenAndServe("localhost:6060", nil))
 	//}()
 	confAPIURL := flag.String("u", "https://example.com", "Dump API URL")
 	confAPIKey := flag.String("k", "xxxxxxxxxyyyyyyyyyyzzzzzzzzzqqqqqqqqqwwwwwwweeeeeeeerrrrrrrrrttt", "Dump API Key")
+	confZIRepo := flag.String("zi-repo", "", "zapret-info git mirror URL (e.g. https://github.com/zapret-info/z-i.git) polled instead of -u, empty disables")
+	confZIBranch := flag.String("zi-branch", "master", "Branch of the zapret-info git mirror")
+	confAPICA := flag.String("u-ca", "", "PEM CA bundle the dump API certificate is verified with, empty means the system roots")
+	confAPICert := flag.String("u-cert", "", "PEM client certificate for mTLS to the dump API, with -u-key")
+	confAPICertKey := flag.String("u-key", "", "PEM client key for mTLS to the dump API")
+	confAPIInsecure := flag.Bool("u-insecure", false, "Don't verify the dump API certificate, INSECURE: the dump may be forged")
+	confZICA := flag.String("zi-ca", "", "PEM CA bundle the zapret-info mirror certificate is verified with, empty means the system roots")
+	confZICert := flag.String("zi-cert", "", "PEM client certificate for mTLS to the zapret-info mirror, with -zi-key")
+	confZICertKey := flag.String("zi-key", "", "PEM client key for mTLS to the zapret-info mirror")
+	confZIInsecure := flag.Bool("zi-insecure", false, "Don't verify the zapret-info mirror certificate, INSECURE: the dump may be forged")
 	confPBPort := flag.String("p", "50001", "gRPC port")
 	confDumpCacheDir := flag.String("d", "res", "Dump cache dir")
 	confLogLevel := flag.String("l", "Debug", "Logging level")
+	confLeaseFile := flag.String("lease", "", "Shared lease file for warm standby, empty means single instance")
+	confLeaseTTL := flag.Int("lease-ttl", 30, "Lease heartbeat TTL in seconds")
+	confKeepRaw := flag.Bool("keep-raw", false, "Keep compressed original <content> XML for GetRawContent")
+	confOrgAliases := flag.String("org-aliases", "", "File with \"variant = canonical\" decision organization aliases")
+	confDuplicatePolicy := flag.String("duplicates", DuplicateKeepLast, "Duplicate content id policy: last, first, merge")
+	confHTTPAddr := flag.String("http", "", "HTTP gateway address (e.g. :8080), empty disables")
+	confDNSBLAddr := flag.String("dnsbl", "", "DNSBL responder UDP address (e.g. :5353), empty disables")
+	confDNSBLZone := flag.String("dnsbl-zone", "blocked.example", "DNSBL zone: 4.3.2.1.<zone> and <domain>.<zone> are queried")
+	confGRPCWeb := flag.String("grpc-web", "", "Comma separated browser origins allowed to use gRPC-Web on the HTTP gateway, * allows any, empty disables")
+	confRegistryMaxAge := flag.Duration("registry-max-age", 0, "Alert if the registry update time of the served dump is older than this (e.g. 24h), 0 disables")
+	confRetries := flag.Int("refresh-retries", RetryConfig.Attempts, "Retries of transient fetch failures within one poll cycle, 0 disables")
+	confRetryDelay := flag.Int("refresh-retry-delay", int(RetryConfig.Delay/time.Second), "Delay before the first retry in seconds, doubled and jittered for the next ones")
+	confRetryMaxDelay := flag.Int("refresh-retry-max-delay", int(RetryConfig.MaxDelay/time.Second), "Max delay between retries in seconds")
+	confPaused := flag.String("paused", "", "Start with the poller paused for maintenance with this reason, the current index is served, empty polls as usual")
+	confResolveInterval := flag.Int("resolve-interval", 0, "Resolve hosts of domain-only records to IPs every this many seconds for ResolveHosts, 0 disables")
+	confResolveMax := flag.Int("resolve-max", ResolveConfig.MaxHosts, "Max hosts resolved in one round")
+	confResolveTTL := flag.Int("resolve-ttl", int(ResolveConfig.TTL/time.Second), "Resolved addresses are reused for this many seconds")
+	confResolveWorkers := flag.Int("resolve-workers", ResolveConfig.Workers, "Concurrent lookups of a resolution round")
+	confReadyStaleness := flag.Int("ready-staleness", 0, "Readiness fails if no poll cycle succeeded for this many seconds, 0 disables")
+	confSandbox := flag.Bool("sandbox", false, "Fetch and unzip dumps in a separate process")
+	confSandboxUID := flag.Int("sandbox-uid", -1, "Run the sandboxed fetch as this uid, -1 keeps current")
+	confSandboxGID := flag.Int("sandbox-gid", -1, "Run the sandboxed fetch as this gid, -1 keeps current")
+	confDumpEntry := flag.String("dump-entry", UnzipEntry, "Comma separated glob patterns of the dump entry in dump.zip, in the order of preference")
+	confDumpSig := flag.String("dump-sig", UnzipSignature, "Comma separated glob patterns of the signature entry in dump.zip, empty disables")
+	confUnzipMax := flag.Int64("unzip-max", 0, "Max extracted dump.xml size in MB, 0 means unlimited")
+	confUnzipMaxArchive := flag.Int64("unzip-max-archive", 0, "Max dump.zip size in MB, downloaded or extracted, 0 means unlimited")
+	confUnzipMaxRatio := flag.Float64("unzip-max-ratio", 0, "Max extracted to compressed size ratio of archive entries, 0 means unlimited")
+	confKeepDumps := flag.Int("keep-dumps", 0, "Number of gzipped old dump.xml files to keep, 0 disables")
+	confKeepDumpsSize := flag.Int64("keep-dumps-size", 0, "Max total size of kept old dumps in MB, 0 means unlimited")
+	confSnapshotKeep := flag.Int("snapshots", 0, "Number of retained generation snapshots, 0 disables")
+	confHandoff := flag.Bool("handoff", false, "Zero-downtime restarts: start from the latest snapshot instead of dump.xml and poll after the interval, so the old instance can exit")
+	confReusePort := flag.Bool("reuseport", false, "Listen with SO_REUSEPORT, a new instance binds the ports before the old one exits")
+	confCompressThreshold := flag.Int("compress-threshold", 0, "Compress record payloads not smaller than this many bytes, 0 disables")
+	confAnomalies := flag.String("anomalies", parser.AnomalyKeep, "Address anomalies (host bits, too wide subnets, special addresses): keep, reject (skip them); both report them")
+	confParseCheckpoint := flag.Int("parse-checkpoint", 0, "Checkpoint the parse every N records, an interrupted parse of the same dump is resumed, 0 disables")
+	confMaxRecordSelectors := flag.Int("max-record-selectors", 0, "Max IPs, subnets, domains and URLs of a record, 0 means unlimited")
+	confMaxRecordBytes := flag.Int("max-record-bytes", 0, "Max <content> size in bytes, bigger records are quarantined, 0 means unlimited")
+	confOversized := flag.String("oversized", parser.OversizedTruncate, "Records over -max-record-selectors: truncate (keep the first selectors), quarantine (keep the previous version, if any)")
+	confPayload := flag.String("payload", PayloadKeep, "Record payloads: keep, none (IDs and selectors only, pack is empty, less memory)")
+	confMixedURLs := flag.String("mixed-urls", MixedHTTPS, "URL records with https and other URLs are: https (HTTPS blocks), url (URL blocks)")
+	confCompare := flag.String("compare", CompareHash, "Changed records: hash (any byte change is an update), semantic (only changes of the parsed record)")
+	confCharset := flag.String("charset", parser.CharsetDeclared, "Dump charset handling: declared, lenient (UTF-8 or cp1251 whatever is declared), strict (fail on undecodable bytes)")
+	confHash := flag.String("hash", parser.HashFNV, "Record and decision hash: fnv, xxhash. Changing it changes SearchDecision keys")
+	confHashSeed := flag.Uint64("hash-seed", 0, "Hash seed, the same seed gives reproducible snapshots")
+	confChurnWindow := flag.Int("churn-window", ChurnConfig.Window, "Parses in the churn baseline, 0 disables anomaly detection")
+	confChurnSigma := flag.Float64("churn-sigma", ChurnConfig.Sigma, "Churn above the baseline mean by this many standard deviations is anomalous")
+	confChurnMin := flag.Int("churn-min", ChurnConfig.MinCount, "Churn below this count is never anomalous")
+	confAlertWebhook := flag.String("alert-webhook", "", "URL to POST alert events as JSON, empty disables")
+	confExclude := flag.String("exclude", "", "Local exclusion list file or http(s) URL: IPs, subnets, domains never blocked, reloaded every poll")
+	confFeedSize := flag.Int("feed-size", FeedSize, "Number of recently added and removed records kept for feeds, 0 disables")
+	confWatchKeep := flag.Int("watch-keep", WatchKeep, "Number of generations of record changes kept for v2 Watch subscribers to catch up, 0 disables")
+	confStatsHistory := flag.Int("stats-history", StatsHistoryKeep, "Number of per-parse statistics rows kept in stats.csv, 0 disables")
+	confGRPCCompress := flag.String("grpc-compress", "", "Comma separated gRPC compressors offered to clients: gzip, zstd")
+	confGRPCGzipLevel := flag.Int("grpc-gzip-level", 0, "gzip level of gRPC responses, 0 means default")
+	confGRPCKeepalive := flag.Duration("grpc-keepalive", 0, "Ping gRPC clients idle for this long, 0 keeps the grpc default")
+	confGRPCKeepaliveTimeout := flag.Duration("grpc-keepalive-timeout", 0, "Close the connection if the ping is not answered in this time, 0 keeps the grpc default")
+	confGRPCKeepaliveMin := flag.Duration("grpc-keepalive-min", 0, "Close connections of clients pinging more often than this, 0 keeps the grpc default")
+	confGRPCKeepalivePermit := flag.Bool("grpc-keepalive-permit", false, "Allow client keepalive pings without active streams")
+	confGRPCMaxStreams := flag.Uint("grpc-max-streams", 0, "Max concurrent streams per gRPC connection, 0 means unlimited")
+	confGRPCMaxRecv := flag.Int("grpc-max-recv", 0, "Max gRPC request size in MB, 0 keeps the grpc default")
+	confGRPCMaxSend := flag.Int("grpc-max-send", 0, "Max gRPC response size in MB, 0 keeps the grpc default")
+	confASN := flag.String("asn", "", "IP to ASN table (iptoasn.com TSV) for GetASNReport and asn.csv exported after every parse, empty disables")
+	confUrgentCSV := flag.Bool("urgent-csv", false, "Export urgent.csv of urgent records in the dump.csv format after every parse, served by the HTTP gateway")
+	confUrgentWebhook := flag.String("urgent-webhook", "", "URL to POST urgent added and updated records of every parse as JSON right after it is applied, empty disables")
+	confRegistryCSV := flag.Bool("registry-csv", false, "Export dump.csv in the community \"Реестр\" format (z-i) after every parse, served by the HTTP gateway")
+	confRegistryCSVOrder := flag.String("registry-csv-order", OrderID, "Order of the records in dump.csv and urgent.csv: id, ordinal (as in the registry file)")
+	confSNIBloom := flag.Float64("sni-bloom", 0, "False positive rate of sni.bloom, the bloom filter of SNI names exported after every parse for edge devices, 0 disables")
+	confSQLite := flag.Bool("sqlite", false, "Export dump.sqlite, the records as a normalized SQLite database (content, decisions, ips, domains, urls), after every parse, served by the HTTP gateway")
+	confExportFilter := flag.String("export-filter", "", "Records of exported files (dump.csv, asn.csv, dump.sqlite): semicolon separated entry=, block=, org=, subnet=, ipv=, addr= value lists, \"!\" denies a value, empty exports all")
+	confS3Endpoint := flag.String("s3-endpoint", "https://s3.amazonaws.com", "S3 compatible endpoint URL for uploads, credentials are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN")
+	confS3Bucket := flag.String("s3-bucket", "", "S3 bucket for uploads after every parse, empty disables")
+	confS3Region := flag.String("s3-region", S3Config.Region, "S3 region")
+	confS3Prefix := flag.String("s3-prefix", "", "S3 object key prefix")
+	confS3Upload := flag.String("s3-upload", "snapshot", "Comma separated uploaded objects: snapshot, dump")
+	confS3Keep := flag.Int("s3-keep", 0, "Number of uploaded objects of each kind kept in the bucket, 0 keeps all")
+	confS3Bootstrap := flag.Bool("s3-bootstrap", false, "Load the latest snapshot from -s3-bucket at startup if there is no usable local dump")
+	confAudit := flag.String("audit", "", "Audit log of every RPC: file to append JSON lines to or \"syslog\", empty disables")
+	confAuditSalt := flag.String("audit-salt", "", "Log HMAC-SHA256 of queried selectors with this key instead of the selectors")
+	confHook := flag.String("hook", "", "Lua script with on_record(event, record) called for every added, updated and removed record, empty disables")
+	confHookTimeout := flag.Duration("hook-timeout", 5*time.Second, "Max time of one hook call, 0 means unlimited")
+	confPeers := flag.String("peers", "", "Comma separated host:port of instances CompareWith may compare the dataset with")
+	confRateLimit := flag.Float64("rate-limit", 0, "Requests per second per source IP on gRPC and the HTTP gateway, 0 disables")
+	confRateBurst := flag.Int("rate-burst", 20, "Requests per source IP allowed at once over -rate-limit")
+	confCache := flag.Int("cache", 0, "Number of cached search responses, 0 disables")
+	confCompact := flag.Float64("compact", 0, "Rebuild index maps when records removed since the last rebuild are over this fraction, 0 disables")
+	confDuplicatesReport := flag.Int("duplicates-report", 0, "Export duplicates.csv of IPs, subnets, domains and URLs of at least N records with their ids after every parse, 0 disables")
+	confFaultInjection := flag.Bool("fault-injection", false, "Test mode: InjectFault and "+faultsEnv+" (e.g. fetch,parse:3) arm fetch, unzip and parse failures of the poller, never use it in production")
+	confShadow := flag.Bool("shadow", false, "Shadow parse every applied dump in a separate process and report records it disagrees with by GetShadowReport, serving is not affected")
+	confShadowBinary := flag.String("shadow-binary", "", "Parser executable of the shadow parse (e.g. a new build), empty is this one")
+	confShadowArgs := flag.String("shadow-args", "", "Space separated parse flags of the shadow parse overriding the live ones (e.g. \"-compare semantic -mixed-urls url\")")
+	confV1Sunset := flag.String("v1-sunset", "", "YYYY-MM-DD the deprecated msg.Check (v1) API is served until, announced in the sunset metadata of v1 responses")
 	flag.Parse()
 	switch *confLogLevel {
 	case "Info":
@@ -40,6 +153,311 @@ func main() {
 	default:
 		logger.LogInit(os.Stderr, os.Stdout, os.Stderr, os.Stderr)
 	}
+
+	ParseConfig.KeepRaw = *confKeepRaw
+	ParseConfig.CompressThreshold = *confCompressThreshold
+	SnapshotKeep = *confSnapshotKeep
+	ListenReusePort = *confReusePort
+
+	// the new instance starts from the snapshot of the old one.
+	if *confHandoff && SnapshotKeep <= 0 {
+		SnapshotKeep = 1
+	}
+
+	var err error
+
+	activatedListeners, err = ActivatedListeners()
+	if err != nil {
+		logger.Error.Printf("Bad activated sockets: %s\n", err.Error())
+		os.Exit(1)
+	}
+	RotateConfig.Keep = *confKeepDumps
+	RotateConfig.MaxBytes = *confKeepDumpsSize << 20
+	ReadyStaleness = time.Duration(*confReadyStaleness) * time.Second
+
+	if *confRegistryMaxAge < 0 {
+		logger.Error.Printf("Bad registry max age: %s\n", *confRegistryMaxAge)
+		os.Exit(1)
+	}
+
+	RegistryMaxAge = *confRegistryMaxAge
+
+	if *confRetries < 0 || *confRetryDelay < 0 || *confRetryMaxDelay < 0 {
+		logger.Error.Printf("Bad refresh retries: %d, delay %d, max delay %d\n", *confRetries, *confRetryDelay, *confRetryMaxDelay)
+		os.Exit(1)
+	}
+
+	if *confResolveInterval < 0 || *confResolveMax < 0 || *confResolveTTL < 0 || *confResolveWorkers <= 0 {
+		logger.Error.Printf("Bad resolution: interval %d, max %d, ttl %d, workers %d\n", *confResolveInterval, *confResolveMax, *confResolveTTL, *confResolveWorkers)
+		os.Exit(1)
+	}
+
+	ResolveConfig.Interval = time.Duration(*confResolveInterval) * time.Second
+	ResolveConfig.MaxHosts, ResolveConfig.Workers = *confResolveMax, *confResolveWorkers
+	ResolveConfig.TTL = time.Duration(*confResolveTTL) * time.Second
+
+	if *confPaused != "" {
+		PausePolling(*confPaused, time.Now())
+	}
+
+	RetryConfig = RetryOptions{Attempts: *confRetries, Delay: time.Duration(*confRetryDelay) * time.Second,
+		MaxDelay: time.Duration(*confRetryMaxDelay) * time.Second}
+
+	UnzipMaxBytes = *confUnzipMax << 20
+	UnzipMaxArchiveBytes = *confUnzipMaxArchive << 20
+
+	if *confUnzipMaxRatio < 0 {
+		logger.Error.Printf("Bad max compression ratio: %g\n", *confUnzipMaxRatio)
+		os.Exit(1)
+	}
+
+	UnzipMaxRatio = *confUnzipMaxRatio
+
+	apiTLS := UpstreamTLS{CAFile: *confAPICA, CertFile: *confAPICert, KeyFile: *confAPICertKey, Insecure: *confAPIInsecure}
+	ziTLS := UpstreamTLS{CAFile: *confZICA, CertFile: *confZICert, KeyFile: *confZICertKey, Insecure: *confZIInsecure}
+
+	upstream, upstreamName := apiTLS, *confAPIURL
+	if *confZIRepo != "" {
+		upstream, upstreamName = ziTLS, *confZIRepo
+	}
+
+	if _, err := upstream.Config(); err != nil {
+		logger.Error.Printf("Bad upstream TLS of %s: %s\n", upstreamName, err.Error())
+		os.Exit(1)
+	}
+
+	if upstream.Insecure {
+		logger.Warning.Printf("!!! TLS certificate of %s is NOT verified, the dump may be forged !!!\n", upstreamName)
+	}
+	UnzipEntry, UnzipSignature = *confDumpEntry, *confDumpSig
+	SearchCache = NewQueryCache(*confCache)
+	GRPCWebOrigins = ParseOrigins(*confGRPCWeb)
+	ChurnConfig = ChurnOptions{Window: *confChurnWindow, Sigma: *confChurnSigma, MinCount: *confChurnMin}
+	AlertWebhook = *confAlertWebhook
+	ExclusionSource = *confExclude
+	FeedSize = *confFeedSize
+	WatchKeep = *confWatchKeep
+	StatsHistoryKeep = *confStatsHistory
+	GRPCConfig = GRPCServerOptions{
+		Compressors:          *confGRPCCompress,
+		GzipLevel:            *confGRPCGzipLevel,
+		KeepaliveTime:        *confGRPCKeepalive,
+		KeepaliveTimeout:     *confGRPCKeepaliveTimeout,
+		KeepaliveMinTime:     *confGRPCKeepaliveMin,
+		KeepalivePermit:      *confGRPCKeepalivePermit,
+		MaxConcurrentStreams: uint32(*confGRPCMaxStreams),
+		MaxRecvMsgSize:       *confGRPCMaxRecv << 20,
+		MaxSendMsgSize:       *confGRPCMaxSend << 20,
+	}
+	S3Config = S3UploadOptions{
+		Endpoint: *confS3Endpoint,
+		Bucket:   *confS3Bucket,
+		Region:   *confS3Region,
+		Prefix:   *confS3Prefix,
+		Keep:     *confS3Keep,
+	}
+
+	S3Bootstrap = *confS3Bootstrap
+
+	if err := S3Config.SetUploads(*confS3Upload); err != nil {
+		logger.Error.Printf("Bad S3 uploads: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	SandboxConfig = SandboxOptions{Enabled: *confSandbox, UID: *confSandboxUID, GID: *confSandboxGID}
+
+	if *confASN != "" {
+		table, err := LoadASNTable(*confASN)
+		if err != nil {
+			logger.Error.Printf("Can't load ASN table: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		ASNs = table
+	}
+
+	RegistryCSV = *confRegistryCSV
+
+	switch *confRegistryCSVOrder {
+	case OrderID, OrderOrdinal:
+		RegistryCSVOrder = *confRegistryCSVOrder
+	default:
+		logger.Error.Printf("Unknown dump.csv order: %s\n", *confRegistryCSVOrder)
+		os.Exit(1)
+	}
+	UrgentCSV, UrgentWebhook = *confUrgentCSV, *confUrgentWebhook
+
+	if *confSNIBloom < 0 || *confSNIBloom >= 1 {
+		logger.Error.Printf("Bad SNI bloom filter false positive rate: %g\n", *confSNIBloom)
+		os.Exit(1)
+	}
+
+	SNIBloomFP = *confSNIBloom
+	SQLiteExport = *confSQLite
+
+	if *confV1Sunset != "" {
+		if _, err := time.Parse("2006-01-02", *confV1Sunset); err != nil {
+			logger.Error.Printf("Bad v1 sunset date: %s\n", *confV1Sunset)
+			os.Exit(1)
+		}
+	}
+
+	V1Sunset = *confV1Sunset
+
+	if *confCompact < 0 || *confCompact >= 1 {
+		logger.Error.Printf("Bad compaction threshold: %g\n", *confCompact)
+		os.Exit(1)
+	}
+
+	CompactThreshold = *confCompact
+
+	SetComparePeers(*confPeers)
+
+	if filter, err := ParseExportFilter(*confExportFilter); err != nil {
+		logger.Error.Printf("Bad export filter: %s\n", err.Error())
+		os.Exit(1)
+	} else {
+		ExportFilterConfig = filter
+	}
+
+	if *confHook != "" {
+		hook, err := LoadHook(*confHook, *confHookTimeout)
+		if err != nil {
+			logger.Error.Printf("Can't load hook: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		Hooks = hook
+	}
+
+	if *confOrgAliases != "" {
+		if err := LoadOrgAliases(*confOrgAliases); err != nil {
+			logger.Error.Printf("Can't load organization aliases: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	switch *confDuplicatePolicy {
+	case DuplicateKeepLast, DuplicateKeepFirst, DuplicateMerge:
+		ParseConfig.DuplicatePolicy = *confDuplicatePolicy
+	default:
+		logger.Error.Printf("Unknown duplicate policy: %s\n", *confDuplicatePolicy)
+		os.Exit(1)
+	}
+
+	switch *confCharset {
+	case parser.CharsetDeclared, parser.CharsetLenient, parser.CharsetStrict:
+		ParseConfig.Charset = *confCharset
+	default:
+		logger.Error.Printf("Unknown charset mode: %s\n", *confCharset)
+		os.Exit(1)
+	}
+
+	// the lenient conversion has no input offsets, the original fragments are unknown.
+	if ParseConfig.KeepRaw && ParseConfig.Charset == parser.CharsetLenient {
+		logger.Error.Printf("-keep-raw can't be used with the lenient charset mode\n")
+		os.Exit(1)
+	}
+
+	switch *confCompare {
+	case CompareHash, CompareSemantic:
+		ParseConfig.Compare = *confCompare
+	default:
+		logger.Error.Printf("Unknown compare mode: %s\n", *confCompare)
+		os.Exit(1)
+	}
+
+	switch *confPayload {
+	case PayloadKeep, PayloadNone:
+		ParseConfig.Payload = *confPayload
+	default:
+		logger.Error.Printf("Unknown payload policy: %s\n", *confPayload)
+		os.Exit(1)
+	}
+
+	switch *confAnomalies {
+	case parser.AnomalyKeep, parser.AnomalyReject:
+		ParseConfig.Anomalies = *confAnomalies
+	default:
+		logger.Error.Printf("Unknown anomaly policy: %s\n", *confAnomalies)
+		os.Exit(1)
+	}
+
+	switch *confOversized {
+	case parser.OversizedTruncate, parser.OversizedQuarantine:
+		ParseConfig.Oversized = *confOversized
+	default:
+		logger.Error.Printf("Unknown oversized record policy: %s\n", *confOversized)
+		os.Exit(1)
+	}
+
+	ParseConfig.MaxRecordSelectors, ParseConfig.MaxRecordBytes = *confMaxRecordSelectors, *confMaxRecordBytes
+	ParseCheckpointEvery = *confParseCheckpoint
+	FaultInjection = *confFaultInjection
+
+	if *confDuplicatesReport < 0 || *confDuplicatesReport == 1 {
+		logger.Error.Printf("Bad duplicates report min records: %d, 0 or at least %d\n", *confDuplicatesReport, hotMinCount)
+		os.Exit(1)
+	}
+
+	DuplicatesReportMin = *confDuplicatesReport
+	ShadowConfig = ShadowOptions{
+		Enabled:    *confShadow,
+		Binary:     *confShadowBinary,
+		Args:       strings.Fields(*confShadowArgs),
+		OrgAliases: *confOrgAliases,
+	}
+
+	AdminToken = os.Getenv(adminTokenEnv)
+	if AdminToken == "" {
+		logger.Info.Printf("%s is not set, admin RPCs are refused\n", adminTokenEnv)
+	}
+
+	if spec := os.Getenv(faultsEnv); spec != "" && FaultInjection {
+		faults, err := ParseFaults(spec)
+		if err != nil {
+			logger.Error.Printf("Bad %s: %s\n", faultsEnv, err.Error())
+			os.Exit(1)
+		}
+
+		for _, f := range faults {
+			Faults.Arm(f.Stage, f.Remaining)
+		}
+
+		logger.Warning.Printf("Injected faults: %s\n", spec)
+	}
+
+	if ParseConfig.Payload == PayloadNone && ParseConfig.Compare == CompareSemantic {
+		logger.Error.Printf("Semantic compare needs payloads: -payload %s\n", PayloadKeep)
+		os.Exit(1)
+	}
+
+	switch *confMixedURLs {
+	case MixedHTTPS, MixedURL:
+		ParseConfig.MixedURLs = *confMixedURLs
+	default:
+		logger.Error.Printf("Unknown mixed URL policy: %s\n", *confMixedURLs)
+		os.Exit(1)
+	}
+
+	if _, err := parser.NewHasher(*confHash, *confHashSeed); err != nil {
+		logger.Error.Printf("Bad hash: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	ParseConfig.Hash, ParseConfig.HashSeed = *confHash, *confHashSeed
+
+	RestoreGeneration(*confDumpCacheDir)
+
+	RefreshExclusions()
+
+	if err := RecordTags.LoadTags(*confDumpCacheDir); err != nil {
+		logger.Error.Printf("Can't load tags: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	CleanupOrphans(*confDumpCacheDir)
+
 	if _, err := os.Stat(*confDumpCacheDir + "/current"); !os.IsNotExist(err) {
 		err := os.Remove(*confDumpCacheDir + "/current") // remove cache
 		if err != nil {
@@ -47,7 +465,18 @@ func main() {
 			os.Exit(1)
 		}
 	}
-	if _, err := os.Stat(*confDumpCacheDir + "/dump.zip"); !os.IsNotExist(err) {
+	// dump.xml is parsed by the next poll, it is an update of the snapshot.
+	handedOff := false
+
+	if *confHandoff {
+		if err := RestoreLatestSnapshot(*confDumpCacheDir); err != nil {
+			logger.Warning.Printf("No handoff snapshot: %s\n", err.Error())
+		} else {
+			handedOff = true
+		}
+	}
+
+	if _, err := os.Stat(*confDumpCacheDir + "/dump.zip"); !handedOff && !os.IsNotExist(err) {
 		logger.Info.Println("Zipped dump detecteded")
 		err = DumpUnzip(*confDumpCacheDir+"/dump.zip", *confDumpCacheDir+"/dump.xml")
 		if err != nil {
@@ -56,51 +485,159 @@ func main() {
 			logger.Info.Println("Dump extracted")
 		}
 	}
-	if _, err := os.Stat(*confDumpCacheDir + "/dump.xml"); !os.IsNotExist(err) {
+	if _, err := os.Stat(*confDumpCacheDir + "/dump.xml"); !handedOff && !os.IsNotExist(err) {
 		logger.Info.Println("Saved dump detecteded")
 		// parse xml
 		if dumpFile, err := os.Open(*confDumpCacheDir + "/dump.xml"); err != nil {
 			logger.Error.Printf("Can't open last dump: %s\n", err.Error())
 		} else {
-			err = Parse(dumpFile)
-			if err != nil {
+			if err = CheckDumpTail(dumpFile); err != nil {
+				logger.Error.Printf("Dump check error: %s\n", err.Error())
+			} else if err = Parse(dumpFile); err != nil {
 				logger.Error.Printf("Parse error: %s\n", err.Error())
 			} else {
 				logger.Info.Printf("Dump parsed")
+
+				PostParse(*confDumpCacheDir)
 			}
 			dumpFile.Close()
 		}
 	}
 
-	listen, err := net.Listen("tcp", ":"+*confPBPort)
+	if S3Bootstrap && S3Config.Bucket != "" && CurrentDump.utime == 0 {
+		if err := S3Config.BootstrapFromS3(); err != nil {
+			logger.Error.Printf("Can't bootstrap from S3: %s\n", err.Error())
+		}
+	}
+
+	listen, err := Listen(ListenerGRPC, ":"+*confPBPort)
 	if err != nil {
 		logger.Error.Printf("Failed to listen: %s\n", err.Error())
 		os.Exit(1)
 	}
 
-	serverGRPC := grpc.NewServer()
-	pb.RegisterCheckServer(serverGRPC, &server{})
-
 	quit := make(chan os.Signal, 1)
 	done := make(chan struct{})
 	killPoll := make(chan struct{})
+
+	grpcOptions, err := GRPCConfig.ServerOptions()
+	if err != nil {
+		logger.Error.Printf("Bad gRPC options: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if *confAudit != "" {
+		Audit, err = OpenAuditLog(*confAudit, *confAuditSalt)
+		if err != nil {
+			logger.Error.Printf("Can't open audit log: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		grpcOptions = append(grpcOptions, Audit.ServerOptions()...)
+	}
+
+	grpcOptions = append(grpcOptions, deprecationOptions()...)
+
+	// after the audit, rejected calls are audited too.
+	if *confRateLimit > 0 {
+		RateLimit = NewRateLimiter(*confRateLimit, *confRateBurst)
+		grpcOptions = append(grpcOptions, RateLimit.ServerOptions()...)
+	}
+
+	serverGRPC := grpc.NewServer(grpcOptions...)
+	srv := &server{dir: *confDumpCacheDir, kill: killPoll}
+	pb.RegisterCheckServer(serverGRPC, srv)
+	pbv2.RegisterCheckServer(serverGRPC, &serverV2{v1: srv})
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(serverGRPC, healthServer)
+
 	donePoll := make(chan struct{})
+	doneLease := make(chan struct{})
+	doneHealth := make(chan struct{})
+	doneGateway := make(chan struct{})
+	doneDNSBL := make(chan struct{})
+	doneResolve := make(chan struct{})
+
+	MarkRefreshed()
+
+	go HealthWatch(healthServer, doneHealth, killPoll)
+
+	if *confHTTPAddr != "" {
+		ln, err := Listen(ListenerHTTP, *confHTTPAddr)
+		if err != nil {
+			logger.Error.Printf("Gateway failed to listen: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		go ServeGateway(NewGateway(*confHTTPAddr, *confDumpCacheDir, serverGRPC), ln, doneGateway, killPoll)
+	} else {
+		close(doneGateway)
+	}
+
+	if *confDNSBLAddr != "" {
+		conn, err := net.ListenPacket("udp", *confDNSBLAddr)
+		if err != nil {
+			logger.Error.Printf("DNSBL failed to listen: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		go ServeDNSBL(conn, NewDNSBL(*confDNSBLZone), doneDNSBL, killPoll)
+	} else {
+		close(doneDNSBL)
+	}
+
+	if ResolveConfig.Interval > 0 {
+		go ResolveLoop(doneResolve, killPoll)
+	} else {
+		close(doneResolve)
+	}
+
+	var lease *Lease
+	if *confLeaseFile != "" {
+		lease = NewLease(*confLeaseFile, DefaultLeaseOwner(), time.Duration(*confLeaseTTL)*time.Second)
+		go lease.Heartbeat(doneLease, killPoll)
+	} else {
+		close(doneLease)
+	}
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		<-quit
 
+		if _, err := SdNotify(SdStopping); err != nil {
+			logger.Error.Printf("Can't notify systemd: %s\n", err.Error())
+		}
+
 		close(killPoll)
 
 		serverGRPC.GracefulStop()
 
 		<-donePoll
+		<-doneLease
+		<-doneHealth
+		<-doneGateway
+		<-doneDNSBL
+		<-doneResolve
 
 		close(done)
 	}()
 
-	go DumpPoll(serverGRPC, donePoll, killPoll, *confAPIURL, *confAPIKey, *confDumpCacheDir, 60)
+	var source DumpSource = &VigruzkiSource{URL: *confAPIURL, Token: *confAPIKey, TLS: apiTLS}
+	if *confZIRepo != "" {
+		source = &ZISource{Repo: *confZIRepo, Branch: *confZIBranch, TLS: ziTLS}
+	}
+
+	// the old instance polls until it exits.
+	firstPoll := time.Millisecond
+	if handedOff {
+		firstPoll = 60 * time.Second
+	}
+
+	go DumpPoll(serverGRPC, donePoll, killPoll, lease, source, *confDumpCacheDir, firstPoll, 60)
+
+	sdNotifyReady()
 
 	if err := serverGRPC.Serve(listen); err != nil {
 		logger.Error.Printf("Failed to serve: %v", err.Error())
@@ -109,5 +646,13 @@ func main() {
 
 	<-done
 
+	if Audit != nil {
+		Audit.Close()
+	}
+
+	if Hooks != nil {
+		Hooks.Close()
+	}
+
 	logger.Warning.Printf("Exiting...")
 }
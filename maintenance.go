@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// MaintenanceState - the poller is paused, e.g. during registry maintenance windows or local disk
+// issues: no new dumps are fetched, queries are answered from the current index.
+type MaintenanceState struct {
+	Paused bool
+	Since  time.Time
+	Reason string
+}
+
+var maintenance struct {
+	sync.Mutex
+	state MaintenanceState
+}
+
+// Maintenance - the current state.
+func Maintenance() MaintenanceState {
+	maintenance.Lock()
+	defer maintenance.Unlock()
+
+	return maintenance.state
+}
+
+// PausePolling - pause the poller, the reason of a paused one is replaced and its time is kept.
+func PausePolling(reason string, now time.Time) MaintenanceState {
+	maintenance.Lock()
+	defer maintenance.Unlock()
+
+	if !maintenance.state.Paused {
+		maintenance.state.Paused, maintenance.state.Since = true, now
+	}
+
+	maintenance.state.Reason = reason
+
+	metricPollPaused.Set(1)
+
+	logger.Warning.Printf("Polling is paused: %s\n", reason)
+
+	return maintenance.state
+}
+
+// ResumePolling - resume the poller, the next poll cycle fetches as usual.
+func ResumePolling() MaintenanceState {
+	maintenance.Lock()
+	defer maintenance.Unlock()
+
+	if maintenance.state.Paused {
+		logger.Warning.Printf("Polling is resumed after %s\n", time.Since(maintenance.state.Since).Truncate(time.Second))
+
+		// the readiness staleness counts from the resume.
+		MarkRefreshed()
+	}
+
+	maintenance.state = MaintenanceState{}
+
+	metricPollPaused.Set(0)
+
+	return maintenance.state
+}
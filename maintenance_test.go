@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestSetMaintenance tests the pause, the state in Ping and readiness, and the resume.
+func TestSetMaintenance(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+	defer func(staleness time.Duration) { ReadyStaleness = staleness }(ReadyStaleness)
+	defer func(refresh int64) { lastRefresh.Store(refresh) }(lastRefresh.Load())
+	defer func(token string) { AdminToken = token }(AdminToken)
+	defer ResumePolling()
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	ReadyStaleness = time.Minute
+	lastRefresh.Store(time.Now().Add(-time.Hour).Unix())
+
+	if ready, _ := Ready(time.Now()); ready {
+		t.Errorf("Stale poller is ready")
+	}
+
+	AdminToken = "secret"
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+
+	s := &server{}
+
+	// only the state is open.
+	for _, req := range []*pb.MaintenanceRequest{{Pause: true, Reason: "guess"}, {Resume: true}} {
+		if resp, _ := s.SetMaintenance(context.Background(), req); resp.GetError() != SrvAdminOnly || Maintenance().Paused {
+			t.Errorf("Without the token: %v", resp)
+		}
+	}
+
+	resp, _ := s.SetMaintenance(admin, &pb.MaintenanceRequest{Pause: true, Reason: "registry window"})
+	if !resp.GetPaused() || resp.GetSince() == 0 || resp.GetReason() != "registry window" || metricPollPaused.Value() != 1 {
+		t.Errorf("Pause: %v, metric %d", resp, metricPollPaused.Value())
+	}
+
+	since := resp.GetSince()
+
+	// the reason is replaced, the time is kept.
+	resp, _ = s.SetMaintenance(admin, &pb.MaintenanceRequest{Pause: true, Reason: "disk"})
+	if resp.GetSince() != since || resp.GetReason() != "disk" {
+		t.Errorf("Pause again: %v", resp)
+	}
+
+	if ready, reason := Ready(time.Now()); !ready || !strings.Contains(reason, "disk") {
+		t.Errorf("Paused poller readiness: %t, %q", ready, reason)
+	}
+
+	if pong, _ := s.Ping(context.Background(), &pb.PingRequest{}); !pong.GetPaused() {
+		t.Errorf("Ping: %v", pong)
+	}
+
+	if resp, _ := s.SetMaintenance(context.Background(), &pb.MaintenanceRequest{}); !resp.GetPaused() || resp.GetReason() != "disk" {
+		t.Errorf("State without the token: %v", resp)
+	}
+
+	if resp, _ := s.SetMaintenance(context.Background(), &pb.MaintenanceRequest{Pause: true, Resume: true}); resp.GetError() != SrvBadQuery {
+		t.Errorf("Pause and resume: %v", resp)
+	}
+
+	resp, _ = s.SetMaintenance(admin, &pb.MaintenanceRequest{Resume: true})
+	if resp.GetPaused() || resp.GetSince() != 0 || metricPollPaused.Value() != 0 {
+		t.Errorf("Resume: %v, metric %d", resp, metricPollPaused.Value())
+	}
+
+	// the staleness counts from the resume.
+	if ready, reason := Ready(time.Now()); !ready {
+		t.Errorf("Resumed poller readiness: %q", reason)
+	}
+
+	if resp, _ := s.SetMaintenance(context.Background(), &pb.MaintenanceRequest{}); resp.GetPaused() || formatMaintenance(resp) != "active" {
+		t.Errorf("State: %v", resp)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// contentMask - set of pb.Content fields to fill.
+type contentMask uint32
+
+// pb.Content fields.
+const (
+	maskID contentMask = 1 << iota
+	maskRegistryUpdateTime
+	maskBlockType
+	maskIP4
+	maskIP6
+	maskDomain
+	maskURL
+	maskAggr
+	maskPack
+	maskSNI
+	maskExcluded
+	maskDecision
+	maskHTTPURLs
+	maskHTTPSURLs
+	maskSNIOnly
+	maskMatchedBy
+	maskTags
+	maskUrgencyType
+	maskOrdinal
+	maskTs
+	maskChangeTime
+
+	maskAll = maskChangeTime<<1 - 1
+)
+
+// maskPaths - field mask paths, both proto and JSON names.
+var maskPaths = map[string]contentMask{
+	"id":                   maskID,
+	"registryUpdateTime":   maskRegistryUpdateTime,
+	"registry_update_time": maskRegistryUpdateTime,
+	"blockType":            maskBlockType,
+	"block_type":           maskBlockType,
+	"ip4":                  maskIP4,
+	"ip6":                  maskIP6,
+	"domain":               maskDomain,
+	"url":                  maskURL,
+	"aggr":                 maskAggr,
+	"pack":                 maskPack,
+	"sni":                  maskSNI,
+	"excluded":             maskExcluded,
+	"decision":             maskDecision,
+	"httpUrls":             maskHTTPURLs,
+	"http_urls":            maskHTTPURLs,
+	"httpsUrls":            maskHTTPSURLs,
+	"https_urls":           maskHTTPSURLs,
+	"sniOnly":              maskSNIOnly,
+	"sni_only":             maskSNIOnly,
+	"matchedBy":            maskMatchedBy,
+	"matched_by":           maskMatchedBy,
+	"tags":                 maskTags,
+	"urgencyType":          maskUrgencyType,
+	"urgency_type":         maskUrgencyType,
+	"ordinal":              maskOrdinal,
+	"ts":                   maskTs,
+	"changeTime":           maskChangeTime,
+	"change_time":          maskChangeTime,
+}
+
+// newContentMask - mask from the request, empty field mask means all fields.
+func newContentMask(fm *fieldmaskpb.FieldMask) (contentMask, error) {
+	if len(fm.GetPaths()) == 0 {
+		return maskAll, nil
+	}
+
+	var mask contentMask
+
+	for _, path := range fm.GetPaths() {
+		m, ok := maskPaths[path]
+		if !ok {
+			return 0, fmt.Errorf("unknown field: %s", path)
+		}
+
+		mask |= m
+	}
+
+	return mask, nil
+}
+
+// has - is the field requested?
+func (mask contentMask) has(m contentMask) bool {
+	return mask&m != 0
+}
+
+// newMaskedPbContent - fill only requested fields, payload and SNI are expensive.
+// Call it under read lock of CurrentDump.
+func (v *PackedContent) newMaskedPbContent(mask contentMask, m Match) *pb.Content {
+	v0 := pb.Content{}
+
+	if mask.has(maskID) {
+		v0.Id = v.ID
+	}
+
+	if mask.has(maskRegistryUpdateTime) {
+		v0.RegistryUpdateTime = CurrentDump.utime
+	}
+
+	if mask.has(maskChangeTime) {
+		v0.ChangeTime = v.RegistryUpdateTime
+	}
+
+	if mask.has(maskBlockType) {
+		v0.BlockType = v.BlockType
+	}
+
+	if mask.has(maskIP4) {
+		v0.Ip4 = parser.Addr4Uint32(m.IP)
+	}
+
+	if mask.has(maskIP6) {
+		if m.IP.Is6() {
+			ip6 := m.IP.As16()
+			v0.Ip6 = ip6[:]
+		}
+	}
+
+	if mask.has(maskDomain) {
+		v0.Domain = m.Domain
+	}
+
+	if mask.has(maskURL) {
+		v0.Url = m.URL
+	}
+
+	if mask.has(maskAggr) {
+		if m.Subnet.IsValid() {
+			v0.Aggr = m.Subnet.String()
+		}
+	}
+
+	if mask.has(maskPack) {
+		v0.Pack = v.PayloadBytes()
+	}
+
+	if mask.has(maskSNI) {
+		v0.Sni = v.SNI()
+	}
+
+	if mask.has(maskExcluded) {
+		v0.Excluded = CurrentExclusions().match(m.IP, m.Domain, m.URL, m.Subnet)
+	}
+
+	if mask.has(maskDecision) {
+		v0.Decision = v.newPbDecision()
+	}
+
+	if mask.has(maskHTTPURLs) {
+		v0.HttpUrls = v.URLHTTP
+	}
+
+	if mask.has(maskHTTPSURLs) {
+		v0.HttpsUrls = v.URLHTTPS
+	}
+
+	if mask.has(maskSNIOnly) {
+		v0.SniOnly = v.SNIOnly()
+	}
+
+	if mask.has(maskMatchedBy) {
+		v0.MatchedBy = m.newPbMatchedBy()
+	}
+
+	if mask.has(maskUrgencyType) {
+		v0.UrgencyType = v.UrgencyType
+	}
+
+	if mask.has(maskOrdinal) {
+		v0.Ordinal = CurrentDump.ordinals.Get(v.ID)
+	}
+
+	if mask.has(maskTs) {
+		v0.Ts = v.Ts
+	}
+
+	if mask.has(maskTags) {
+		v0.Tags = newPbTags(RecordTags.Tags(v.ID))
+	}
+
+	return &v0
+}
+
+// newPbDecision - decision of the record, number, date and org are kept in the payload only.
+// Without payloads the number is empty and the org is the canonical one.
+func (v *PackedContent) newPbDecision() *pb.Decision {
+	decision := &pb.Decision{Hash: v.Decision}
+
+	record, err := v.Record()
+	if err != nil {
+		logger.Error.Printf("Can't decode payload: %d: %s\n", v.ID, err.Error())
+
+		return decision
+	}
+
+	decision.Number = record.Decision.Number
+	decision.Date = record.Decision.Date
+	decision.Org = record.Decision.Org
+
+	return decision
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestFieldMask tests the requested Content fields of searches and unknown mask paths.
+func TestFieldMask(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	resp, _ := s.SearchID(context.Background(), &pb.IDRequest{Query: 111})
+	if len(resp.GetResults()) != 1 || len(resp.GetResults()[0].GetPack()) == 0 || len(resp.GetResults()[0].GetSni()) == 0 {
+		t.Fatalf("all fields: %v", resp)
+	}
+
+	resp, _ = s.SearchID(context.Background(), &pb.IDRequest{Query: 111, Fields: &fieldmaskpb.FieldMask{Paths: []string{"id", "block_type"}}})
+	if len(resp.GetResults()) != 1 {
+		t.Fatalf("masked: %v", resp)
+	}
+
+	v := resp.GetResults()[0]
+	if v.GetId() != 111 || v.GetRegistryUpdateTime() != 0 || len(v.GetPack()) != 0 || len(v.GetSni()) != 0 {
+		t.Errorf("masked fields: %v", v)
+	}
+
+	resp, _ = s.SearchIP6(context.Background(), &pb.IP6Request{Query: []byte{0xfd, 0xaa, 0, 0xf, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
+		Fields: &fieldmaskpb.FieldMask{Paths: []string{"ip6"}}})
+	if len(resp.GetResults()) != 5 || len(resp.GetResults()[0].GetIp6()) != 16 || resp.GetResults()[0].GetId() != 0 {
+		t.Errorf("masked ip6 search: %v", resp)
+	}
+
+	resp, _ = s.SearchID(context.Background(), &pb.IDRequest{Query: 111, Fields: &fieldmaskpb.FieldMask{Paths: []string{"nope"}}})
+	if resp.GetError() != SrvBadFieldMask || len(resp.GetResults()) != 0 {
+		t.Errorf("unknown path: %v", resp)
+	}
+}
+
+// TestSearchDecisionFields tests typed decision fields and their field mask.
+func TestSearchDecisionFields(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	resp, err := s.SearchID(context.Background(), &pb.IDRequest{Query: 222})
+	if err != nil || len(resp.GetResults()) == 0 {
+		t.Fatalf("search: %v %v", resp, err)
+	}
+
+	d := resp.GetResults()[0].GetDecision()
+	if d.GetNumber() != "2/2/22-2222" || d.GetDate() != "2000-01-02" || d.GetOrg() != "TWO" ||
+		d.GetHash() != CurrentDump.ContentIdx[222].Decision {
+		t.Errorf("decision: %v", d)
+	}
+
+	resp, _ = s.SearchID(context.Background(), &pb.IDRequest{Query: 222, Fields: &fieldmaskpb.FieldMask{Paths: []string{"id"}}})
+	if len(resp.GetResults()) == 0 || resp.GetResults()[0].GetDecision() != nil {
+		t.Errorf("masked decision: %v", resp.GetResults())
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/netip"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// Match kinds: selector kinds of selector searches and the searches not by a selector.
+const (
+	MatchID           = "id"
+	MatchDecision     = SelectorDecision
+	MatchDecisionDate = "decision-date"
+	MatchIncludeTime  = "include-time"
+	MatchTs           = "ts"
+	MatchDomainMask   = "domain-mask" // the queried domain is the domain of a mask record.
+)
+
+// Match - why the record is in the result, filled by the lookup.
+type Match struct {
+	Kind   string       // SelectorIP4... for selector searches, MatchID... for others.
+	IP     netip.Addr   // queried IPv4 or IPv6 of an exact match.
+	Domain string       // queried domain.
+	URL    string       // queried URL.
+	Subnet netip.Prefix // indexed subnet containing the queried address.
+}
+
+// domainMatch - match of the queried domain, masks block the domain and its subdomains.
+func (v *PackedContent) domainMatch(domain string) Match {
+	if v.BlockType == BlockTypeMask {
+		return Match{Kind: MatchDomainMask, Domain: domain}
+	}
+
+	return Match{Kind: SelectorDomain, Domain: domain}
+}
+
+// selector - the selector of the record which matched, empty for searches not by a selector.
+func (m Match) selector() string {
+	switch m.Kind {
+	case SelectorIP4, SelectorIP6:
+		return m.IP.String()
+	case SelectorSubnet4, SelectorSubnet6:
+		return m.Subnet.String()
+	case SelectorDomain:
+		return m.Domain
+	case MatchDomainMask:
+		return "*." + m.Domain
+	case SelectorURL:
+		return m.URL
+	}
+
+	return ""
+}
+
+// newPbMatchedBy - matched_by of the result, nil if the lookup didn't say.
+func (m Match) newPbMatchedBy() *pb.MatchedBy {
+	if m.Kind == "" {
+		return nil
+	}
+
+	return &pb.MatchedBy{Kind: m.Kind, Selector: m.selector()}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+const matchDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <ip>10.1.1.1</ip>
+        <ipv6>fd00:1::1</ipv6>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <ipSubnet>10.1.0.0/16</ipSubnet>
+        <ipv6Subnet>fd00:1::/32</ipv6Subnet>
+</content>
+<content id="3" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain-mask" hash="3">
+        <decision date="2000-01-01" number="3" org="ONE"/>
+        <domain><![CDATA[*.e3.tld]]></domain>
+</content>
+<content id="4" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="4">
+        <decision date="2000-01-01" number="4" org="ONE"/>
+        <domain><![CDATA[e3.tld]]></domain>
+</content>
+<content id="5" includeTime="2001-01-01T01:01:01" entryType="1" hash="5">
+        <decision date="2000-01-01" number="5" org="ONE"/>
+        <url><![CDATA[http://www.e5.tld/a]]></url>
+</content>
+</reg:register>`
+
+// TestSearchMatchedBy tests the match kind and selector of every lookup path.
+func TestSearchMatchedBy(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(matchDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &server{}
+	ctx := context.Background()
+
+	matched := func(resp *pb.SearchResponse, err error) map[int32]string {
+		t.Helper()
+
+		if err != nil || resp.GetError() != "" {
+			t.Fatalf("search: %v %v", resp, err)
+		}
+
+		got := map[int32]string{}
+		for _, r := range resp.GetResults() {
+			got[r.GetId()] = r.GetMatchedBy().GetKind() + " " + r.GetMatchedBy().GetSelector()
+		}
+
+		return got
+	}
+
+	for name, tc := range map[string]struct {
+		got  map[int32]string
+		want map[int32]string
+	}{
+		"ip4": {
+			matched(srv.SearchIP4(ctx, &pb.IP4Request{Query: parser.IPv4StrToInt("10.1.1.1")})),
+			map[int32]string{1: "ip4 10.1.1.1", 2: "subnet4 10.1.0.0/16"},
+		},
+		"ip6": {
+			matched(srv.SearchIP6(ctx, &pb.IP6Request{Query: net.ParseIP("fd00:1::1").To16()})),
+			map[int32]string{1: "ip6 fd00:1::1", 2: "subnet6 fd00:1::/32"},
+		},
+		"domain": {
+			matched(srv.SearchDomain(ctx, &pb.DomainRequest{Query: "e3.tld"})),
+			map[int32]string{3: "domain-mask *.e3.tld", 4: "domain e3.tld"},
+		},
+		"url": {
+			matched(srv.SearchURL(ctx, &pb.URLRequest{Query: NormalizeURL("http://www.e5.tld/a")})),
+			map[int32]string{5: "url " + NormalizeURL("http://www.e5.tld/a")},
+		},
+		"id": {
+			matched(srv.SearchID(ctx, &pb.IDRequest{Query: 5})),
+			map[int32]string{5: "id "},
+		},
+	} {
+		if len(tc.got) != len(tc.want) {
+			t.Errorf("%s: %v", name, tc.got)
+
+			continue
+		}
+
+		for id, want := range tc.want {
+			if tc.got[id] != want {
+				t.Errorf("%s: %d: %q, want %q", name, id, tc.got[id], want)
+			}
+		}
+	}
+}
+
+// TestSearchURLSchemeAgnostic tests the search of http and https URLs of the same host and path.
+func TestSearchURLSchemeAgnostic(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	record := func(id, urls string) string {
+		return `<content id="` + id + `" includeTime="2001-01-01T01:01:01" entryType="1" hash="` + id + `">
+        <decision date="2000-01-01" number="` + id + `" org="ONE"/>` + urls + `
+</content>
+`
+	}
+
+	dump := func(urls6 string) string {
+		return `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+` + record("5", `<url><![CDATA[http://www.e5.tld/a]]></url>`) + record("6", urls6) +
+			record("7", `<url><![CDATA[https://www.e5.tld/b]]></url>`) + `</reg:register>`
+	}
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(dump(`<url><![CDATA[http://www.e5.tld/a]]></url><url><![CDATA[https://www.e5.tld/a]]></url>`))); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &server{}
+
+	search := func(query string, anyScheme bool) string {
+		resp, _ := srv.SearchURL(context.Background(), &pb.URLRequest{Query: query, SchemeAgnostic: anyScheme})
+
+		var got []string
+		for _, r := range resp.GetResults() {
+			got = append(got, strconv.Itoa(int(r.GetId()))+" "+r.GetMatchedBy().GetSelector())
+		}
+
+		return strings.Join(got, ", ")
+	}
+
+	for _, tc := range []struct {
+		query     string
+		anyScheme bool
+		want      string
+	}{
+		{"http://www.e5.tld/a", false, "5 http://www.e5.tld/a, 6 http://www.e5.tld/a"},
+		{"https://www.e5.tld/a", false, "6 https://www.e5.tld/a"},
+		{"https://www.e5.tld/a", true, "5 http://www.e5.tld/a, 6 https://www.e5.tld/a"},
+		{"www.e5.tld/a", true, "5 http://www.e5.tld/a, 6 https://www.e5.tld/a"},
+		{"http://www.e5.tld/b", true, "7 https://www.e5.tld/b"},
+	} {
+		if got := search(tc.query, tc.anyScheme); got != tc.want {
+			t.Errorf("%s %t: %q, want %q", tc.query, tc.anyScheme, got, tc.want)
+		}
+	}
+
+	// 6 keeps the https URL only, then loses it.
+	if err := Parse(strings.NewReader(strings.Replace(dump(`<url><![CDATA[https://www.e5.tld/a]]></url>`), "updateTime=\"2011", "updateTime=\"2012", 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := search("www.e5.tld/a", true); got != "5 http://www.e5.tld/a, 6 https://www.e5.tld/a" {
+		t.Errorf("Without http: %q", got)
+	}
+
+	if err := Parse(strings.NewReader(strings.Replace(dump(``), "updateTime=\"2011", "updateTime=\"2013", 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := search("www.e5.tld/a", true); got != "5 http://www.e5.tld/a" {
+		t.Errorf("Without URLs: %q", got)
+	}
+}
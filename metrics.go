@@ -0,0 +1,70 @@
+package main
+
+import (
+	"expvar"
+	"time"
+)
+
+// Metrics, served by the HTTP gateway at /debug/vars.
+var (
+	metricCacheHits   = expvar.NewInt("cache_hits")
+	metricCacheMisses = expvar.NewInt("cache_misses")
+
+	metricChurnAdded     = expvar.NewInt("churn_added")   // records added by the last parse.
+	metricChurnUpdated   = expvar.NewInt("churn_updated") // records updated by the last parse.
+	metricChurnRemoved   = expvar.NewInt("churn_removed") // records removed by the last parse.
+	metricChurnAnomalies = expvar.NewInt("churn_anomalies")
+	metricUrgent         = expvar.NewInt("urgent_records") // urgent records added or updated by the last parse.
+
+	metricAlerts = expvar.NewMap("alerts") // raised alerts by kind.
+
+	metricInjectedFaults = expvar.NewMap("injected_faults") // poller failures of the test mode by stage, see FaultInjection.
+
+	metricShadowDiscrepancies = expvar.NewInt("shadow_discrepancies") // records the last shadow parse disagrees with, see ShadowParse.
+
+	metricAddressAnomalies = expvar.NewMap("address_anomalies") // address anomalies of the current records by kind.
+
+	metricRateLimited = expvar.NewMap("rate_limited") // rejected requests by listener: grpc, http.
+
+	metricJournalBytes = expvar.NewInt("journal_bytes") // ids journal of the last parse, see IDJournal.
+
+	metricRefreshRetries = expvar.NewMap("refresh_retries") // retried refresh failures by stage, see RetryBudget.
+	metricPollPaused     = expvar.NewInt("poll_paused")     // 1 if the poller is paused for maintenance.
+
+	metricResolvedHosts   = expvar.NewInt("resolved_hosts")   // hosts of domain-only records of the last round, see ResolvedView.
+	metricResolveFailures = expvar.NewInt("resolve_failures") // failed lookups of the last round.
+
+	metricCompactions       = expvar.NewInt("compactions")
+	metricCompactDuration   = expvar.NewInt("compact_last_ms")          // rebuild of the last compaction.
+	metricCompactHeapBefore = expvar.NewInt("compact_last_heap_before") // heap in use bytes.
+	metricCompactHeapAfter  = expvar.NewInt("compact_last_heap_after")  // heap in use bytes after a GC.
+)
+
+func init() {
+	expvar.Publish("cache_hit_rate", expvar.Func(func() any {
+		hits, misses := metricCacheHits.Value(), metricCacheMisses.Value()
+		if hits+misses == 0 {
+			return 0.0
+		}
+
+		return float64(hits) / float64(hits+misses)
+	}))
+
+	// seconds since the registry update time of the served dump, -1 before it is loaded.
+	expvar.Publish("registry_age", expvar.Func(func() any {
+		age, _, ok := RegistryAge(time.Now())
+		if !ok {
+			return -1
+		}
+
+		return int64(age / time.Second)
+	}))
+
+	expvar.Publish("rate_limit_clients", expvar.Func(func() any {
+		if RateLimit == nil {
+			return 0
+		}
+
+		return RateLimit.clients()
+	}))
+}
@@ -33,6 +33,41 @@ type CheckClient interface {
 	SearchSubnet6(ctx context.Context, in *Subnet6Request, opts ...grpc.CallOption) (*SearchResponse, error)
 	Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error)
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PongResponse, error)
+	GetRawContent(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*RawContentResponse, error)
+	SearchDecisionDate(ctx context.Context, in *DecisionDateRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	SearchIncludeTime(ctx context.Context, in *IncludeTimeRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	SearchRegistryTs(ctx context.Context, in *RegistryTsRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	DiffGenerations(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (Check_DiffGenerationsClient, error)
+	ListSNI(ctx context.Context, in *SNIRequest, opts ...grpc.CallOption) (*SNIResponse, error)
+	WaitForChange(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*WaitResponse, error)
+	ListOrganizations(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*OrgResponse, error)
+	SelfTest(ctx context.Context, in *SelfTestRequest, opts ...grpc.CallOption) (*SelfTestResponse, error)
+	VerifyIndexes(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	GetChangelog(ctx context.Context, in *ChangelogRequest, opts ...grpc.CallOption) (*ChangelogResponse, error)
+	ListRecent(ctx context.Context, in *RecentRequest, opts ...grpc.CallOption) (*RecentResponse, error)
+	GetStatsHistory(ctx context.Context, in *StatsHistoryRequest, opts ...grpc.CallOption) (*StatsHistoryResponse, error)
+	GetASNReport(ctx context.Context, in *ASNReportRequest, opts ...grpc.CallOption) (*ASNReportResponse, error)
+	Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (*SimulateResponse, error)
+	ProjectSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*ScheduleResponse, error)
+	CompareWith(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error)
+	CompareSelector(ctx context.Context, in *CompareSelectorRequest, opts ...grpc.CallOption) (*CompareSelectorResponse, error)
+	SearchDomainSuffix(ctx context.Context, in *DomainSuffixRequest, opts ...grpc.CallOption) (*DomainSuffixResponse, error)
+	ListHotSelectors(ctx context.Context, in *HotSelectorRequest, opts ...grpc.CallOption) (*HotSelectorResponse, error)
+	ListSelectorlessRecords(ctx context.Context, in *SelectorlessRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	GetParseWarnings(ctx context.Context, in *ParseWarningsRequest, opts ...grpc.CallOption) (*ParseWarningsResponse, error)
+	GetAddressAnomalies(ctx context.Context, in *AnomaliesRequest, opts ...grpc.CallOption) (*AnomaliesResponse, error)
+	TagRecord(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*TagResponse, error)
+	ListTagged(ctx context.Context, in *TaggedRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	GetVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error)
+	InjectFault(ctx context.Context, in *FaultRequest, opts ...grpc.CallOption) (*FaultResponse, error)
+	QueryPayload(ctx context.Context, in *PayloadQueryRequest, opts ...grpc.CallOption) (*PayloadQueryResponse, error)
+	GetShadowReport(ctx context.Context, in *ShadowReportRequest, opts ...grpc.CallOption) (*ShadowReportResponse, error)
+	GetDecisionSummary(ctx context.Context, in *DecisionSummaryRequest, opts ...grpc.CallOption) (*DecisionSummaryResponse, error)
+	GetContentSelectors(ctx context.Context, in *ContentSelectorsRequest, opts ...grpc.CallOption) (*ContentSelectorsResponse, error)
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	ListEntryTypes(ctx context.Context, in *EntryTypeRequest, opts ...grpc.CallOption) (*EntryTypeResponse, error)
+	ResolveHosts(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error)
+	SetMaintenance(ctx context.Context, in *MaintenanceRequest, opts ...grpc.CallOption) (*MaintenanceResponse, error)
 }
 
 type checkClient struct {
@@ -142,6 +177,344 @@ func (c *checkClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *checkClient) GetRawContent(ctx context.Context, in *IDRequest, opts ...grpc.CallOption) (*RawContentResponse, error) {
+	out := new(RawContentResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetRawContent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) SearchDecisionDate(ctx context.Context, in *DecisionDateRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/SearchDecisionDate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) SearchIncludeTime(ctx context.Context, in *IncludeTimeRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/SearchIncludeTime", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) SearchRegistryTs(ctx context.Context, in *RegistryTsRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/SearchRegistryTs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) DiffGenerations(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (Check_DiffGenerationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Check_ServiceDesc.Streams[0], "/msg.Check/DiffGenerations", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &checkDiffGenerationsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Check_DiffGenerationsClient interface {
+	Recv() (*SelectorDelta, error)
+	grpc.ClientStream
+}
+
+type checkDiffGenerationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *checkDiffGenerationsClient) Recv() (*SelectorDelta, error) {
+	m := new(SelectorDelta)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *checkClient) ListSNI(ctx context.Context, in *SNIRequest, opts ...grpc.CallOption) (*SNIResponse, error) {
+	out := new(SNIResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ListSNI", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) WaitForChange(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*WaitResponse, error) {
+	out := new(WaitResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/WaitForChange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ListOrganizations(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*OrgResponse, error) {
+	out := new(OrgResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ListOrganizations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) SelfTest(ctx context.Context, in *SelfTestRequest, opts ...grpc.CallOption) (*SelfTestResponse, error) {
+	out := new(SelfTestResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/SelfTest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) VerifyIndexes(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/VerifyIndexes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetChangelog(ctx context.Context, in *ChangelogRequest, opts ...grpc.CallOption) (*ChangelogResponse, error) {
+	out := new(ChangelogResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetChangelog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ListRecent(ctx context.Context, in *RecentRequest, opts ...grpc.CallOption) (*RecentResponse, error) {
+	out := new(RecentResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ListRecent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetStatsHistory(ctx context.Context, in *StatsHistoryRequest, opts ...grpc.CallOption) (*StatsHistoryResponse, error) {
+	out := new(StatsHistoryResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetStatsHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetASNReport(ctx context.Context, in *ASNReportRequest, opts ...grpc.CallOption) (*ASNReportResponse, error) {
+	out := new(ASNReportResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetASNReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (*SimulateResponse, error) {
+	out := new(SimulateResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/Simulate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ProjectSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*ScheduleResponse, error) {
+	out := new(ScheduleResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ProjectSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) CompareWith(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error) {
+	out := new(CompareResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/CompareWith", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) CompareSelector(ctx context.Context, in *CompareSelectorRequest, opts ...grpc.CallOption) (*CompareSelectorResponse, error) {
+	out := new(CompareSelectorResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/CompareSelector", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) SearchDomainSuffix(ctx context.Context, in *DomainSuffixRequest, opts ...grpc.CallOption) (*DomainSuffixResponse, error) {
+	out := new(DomainSuffixResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/SearchDomainSuffix", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ListHotSelectors(ctx context.Context, in *HotSelectorRequest, opts ...grpc.CallOption) (*HotSelectorResponse, error) {
+	out := new(HotSelectorResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ListHotSelectors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ListSelectorlessRecords(ctx context.Context, in *SelectorlessRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ListSelectorlessRecords", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetParseWarnings(ctx context.Context, in *ParseWarningsRequest, opts ...grpc.CallOption) (*ParseWarningsResponse, error) {
+	out := new(ParseWarningsResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetParseWarnings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetAddressAnomalies(ctx context.Context, in *AnomaliesRequest, opts ...grpc.CallOption) (*AnomaliesResponse, error) {
+	out := new(AnomaliesResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetAddressAnomalies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) TagRecord(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*TagResponse, error) {
+	out := new(TagResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/TagRecord", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ListTagged(ctx context.Context, in *TaggedRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ListTagged", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error) {
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) InjectFault(ctx context.Context, in *FaultRequest, opts ...grpc.CallOption) (*FaultResponse, error) {
+	out := new(FaultResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/InjectFault", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) QueryPayload(ctx context.Context, in *PayloadQueryRequest, opts ...grpc.CallOption) (*PayloadQueryResponse, error) {
+	out := new(PayloadQueryResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/QueryPayload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetShadowReport(ctx context.Context, in *ShadowReportRequest, opts ...grpc.CallOption) (*ShadowReportResponse, error) {
+	out := new(ShadowReportResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetShadowReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetDecisionSummary(ctx context.Context, in *DecisionSummaryRequest, opts ...grpc.CallOption) (*DecisionSummaryResponse, error) {
+	out := new(DecisionSummaryResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetDecisionSummary", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) GetContentSelectors(ctx context.Context, in *ContentSelectorsRequest, opts ...grpc.CallOption) (*ContentSelectorsResponse, error) {
+	out := new(ContentSelectorsResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/GetContentSelectors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/Query", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ListEntryTypes(ctx context.Context, in *EntryTypeRequest, opts ...grpc.CallOption) (*EntryTypeResponse, error) {
+	out := new(EntryTypeResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ListEntryTypes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) ResolveHosts(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error) {
+	out := new(ResolveResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/ResolveHosts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) SetMaintenance(ctx context.Context, in *MaintenanceRequest, opts ...grpc.CallOption) (*MaintenanceResponse, error) {
+	out := new(MaintenanceResponse)
+	err := c.cc.Invoke(ctx, "/msg.Check/SetMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CheckServer is the server API for Check service.
 // All implementations must embed UnimplementedCheckServer
 // for forward compatibility
@@ -157,253 +530,1026 @@ type CheckServer interface {
 	SearchSubnet6(context.Context, *Subnet6Request) (*SearchResponse, error)
 	Stat(context.Context, *StatRequest) (*StatResponse, error)
 	Ping(context.Context, *PingRequest) (*PongResponse, error)
+	GetRawContent(context.Context, *IDRequest) (*RawContentResponse, error)
+	SearchDecisionDate(context.Context, *DecisionDateRequest) (*SearchResponse, error)
+	SearchIncludeTime(context.Context, *IncludeTimeRequest) (*SearchResponse, error)
+	SearchRegistryTs(context.Context, *RegistryTsRequest) (*SearchResponse, error)
+	DiffGenerations(*DiffRequest, Check_DiffGenerationsServer) error
+	ListSNI(context.Context, *SNIRequest) (*SNIResponse, error)
+	WaitForChange(context.Context, *WaitRequest) (*WaitResponse, error)
+	ListOrganizations(context.Context, *OrgRequest) (*OrgResponse, error)
+	SelfTest(context.Context, *SelfTestRequest) (*SelfTestResponse, error)
+	VerifyIndexes(context.Context, *VerifyRequest) (*VerifyResponse, error)
+	GetChangelog(context.Context, *ChangelogRequest) (*ChangelogResponse, error)
+	ListRecent(context.Context, *RecentRequest) (*RecentResponse, error)
+	GetStatsHistory(context.Context, *StatsHistoryRequest) (*StatsHistoryResponse, error)
+	GetASNReport(context.Context, *ASNReportRequest) (*ASNReportResponse, error)
+	Simulate(context.Context, *SimulateRequest) (*SimulateResponse, error)
+	ProjectSchedule(context.Context, *ScheduleRequest) (*ScheduleResponse, error)
+	CompareWith(context.Context, *CompareRequest) (*CompareResponse, error)
+	CompareSelector(context.Context, *CompareSelectorRequest) (*CompareSelectorResponse, error)
+	SearchDomainSuffix(context.Context, *DomainSuffixRequest) (*DomainSuffixResponse, error)
+	ListHotSelectors(context.Context, *HotSelectorRequest) (*HotSelectorResponse, error)
+	ListSelectorlessRecords(context.Context, *SelectorlessRequest) (*SearchResponse, error)
+	GetParseWarnings(context.Context, *ParseWarningsRequest) (*ParseWarningsResponse, error)
+	GetAddressAnomalies(context.Context, *AnomaliesRequest) (*AnomaliesResponse, error)
+	TagRecord(context.Context, *TagRequest) (*TagResponse, error)
+	ListTagged(context.Context, *TaggedRequest) (*SearchResponse, error)
+	GetVersion(context.Context, *VersionRequest) (*VersionResponse, error)
+	InjectFault(context.Context, *FaultRequest) (*FaultResponse, error)
+	QueryPayload(context.Context, *PayloadQueryRequest) (*PayloadQueryResponse, error)
+	GetShadowReport(context.Context, *ShadowReportRequest) (*ShadowReportResponse, error)
+	GetDecisionSummary(context.Context, *DecisionSummaryRequest) (*DecisionSummaryResponse, error)
+	GetContentSelectors(context.Context, *ContentSelectorsRequest) (*ContentSelectorsResponse, error)
+	Query(context.Context, *QueryRequest) (*SearchResponse, error)
+	ListEntryTypes(context.Context, *EntryTypeRequest) (*EntryTypeResponse, error)
+	ResolveHosts(context.Context, *ResolveRequest) (*ResolveResponse, error)
+	SetMaintenance(context.Context, *MaintenanceRequest) (*MaintenanceResponse, error)
 	mustEmbedUnimplementedCheckServer()
 }
 
-// UnimplementedCheckServer must be embedded to have forward compatible implementations.
-type UnimplementedCheckServer struct {
+// UnimplementedCheckServer must be embedded to have forward compatible implementations.
+type UnimplementedCheckServer struct {
+}
+
+func (UnimplementedCheckServer) SearchID(context.Context, *IDRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchID not implemented")
+}
+func (UnimplementedCheckServer) SearchIP4(context.Context, *IP4Request) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchIP4 not implemented")
+}
+func (UnimplementedCheckServer) SearchIP6(context.Context, *IP6Request) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchIP6 not implemented")
+}
+func (UnimplementedCheckServer) SearchURL(context.Context, *URLRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchURL not implemented")
+}
+func (UnimplementedCheckServer) SearchDomain(context.Context, *DomainRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchDomain not implemented")
+}
+func (UnimplementedCheckServer) SearchDecision(context.Context, *DecisionRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchDecision not implemented")
+}
+func (UnimplementedCheckServer) SearchTextDecision(context.Context, *TextDecisionRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchTextDecision not implemented")
+}
+func (UnimplementedCheckServer) SearchSubnet4(context.Context, *Subnet4Request) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchSubnet4 not implemented")
+}
+func (UnimplementedCheckServer) SearchSubnet6(context.Context, *Subnet6Request) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchSubnet6 not implemented")
+}
+func (UnimplementedCheckServer) Stat(context.Context, *StatRequest) (*StatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stat not implemented")
+}
+func (UnimplementedCheckServer) Ping(context.Context, *PingRequest) (*PongResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedCheckServer) GetRawContent(context.Context, *IDRequest) (*RawContentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRawContent not implemented")
+}
+func (UnimplementedCheckServer) SearchDecisionDate(context.Context, *DecisionDateRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchDecisionDate not implemented")
+}
+func (UnimplementedCheckServer) SearchIncludeTime(context.Context, *IncludeTimeRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchIncludeTime not implemented")
+}
+func (UnimplementedCheckServer) SearchRegistryTs(context.Context, *RegistryTsRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchRegistryTs not implemented")
+}
+func (UnimplementedCheckServer) DiffGenerations(*DiffRequest, Check_DiffGenerationsServer) error {
+	return status.Errorf(codes.Unimplemented, "method DiffGenerations not implemented")
+}
+func (UnimplementedCheckServer) ListSNI(context.Context, *SNIRequest) (*SNIResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSNI not implemented")
+}
+func (UnimplementedCheckServer) WaitForChange(context.Context, *WaitRequest) (*WaitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitForChange not implemented")
+}
+func (UnimplementedCheckServer) ListOrganizations(context.Context, *OrgRequest) (*OrgResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrganizations not implemented")
+}
+func (UnimplementedCheckServer) SelfTest(context.Context, *SelfTestRequest) (*SelfTestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SelfTest not implemented")
+}
+func (UnimplementedCheckServer) VerifyIndexes(context.Context, *VerifyRequest) (*VerifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyIndexes not implemented")
+}
+func (UnimplementedCheckServer) GetChangelog(context.Context, *ChangelogRequest) (*ChangelogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChangelog not implemented")
+}
+func (UnimplementedCheckServer) ListRecent(context.Context, *RecentRequest) (*RecentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRecent not implemented")
+}
+func (UnimplementedCheckServer) GetStatsHistory(context.Context, *StatsHistoryRequest) (*StatsHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatsHistory not implemented")
+}
+func (UnimplementedCheckServer) GetASNReport(context.Context, *ASNReportRequest) (*ASNReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetASNReport not implemented")
+}
+func (UnimplementedCheckServer) Simulate(context.Context, *SimulateRequest) (*SimulateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Simulate not implemented")
+}
+func (UnimplementedCheckServer) ProjectSchedule(context.Context, *ScheduleRequest) (*ScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProjectSchedule not implemented")
+}
+func (UnimplementedCheckServer) CompareWith(context.Context, *CompareRequest) (*CompareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareWith not implemented")
+}
+func (UnimplementedCheckServer) CompareSelector(context.Context, *CompareSelectorRequest) (*CompareSelectorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareSelector not implemented")
+}
+func (UnimplementedCheckServer) SearchDomainSuffix(context.Context, *DomainSuffixRequest) (*DomainSuffixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchDomainSuffix not implemented")
+}
+func (UnimplementedCheckServer) ListHotSelectors(context.Context, *HotSelectorRequest) (*HotSelectorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHotSelectors not implemented")
+}
+func (UnimplementedCheckServer) ListSelectorlessRecords(context.Context, *SelectorlessRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSelectorlessRecords not implemented")
+}
+func (UnimplementedCheckServer) GetParseWarnings(context.Context, *ParseWarningsRequest) (*ParseWarningsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetParseWarnings not implemented")
+}
+func (UnimplementedCheckServer) GetAddressAnomalies(context.Context, *AnomaliesRequest) (*AnomaliesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAddressAnomalies not implemented")
+}
+func (UnimplementedCheckServer) TagRecord(context.Context, *TagRequest) (*TagResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TagRecord not implemented")
+}
+func (UnimplementedCheckServer) ListTagged(context.Context, *TaggedRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTagged not implemented")
+}
+func (UnimplementedCheckServer) GetVersion(context.Context, *VersionRequest) (*VersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
+func (UnimplementedCheckServer) InjectFault(context.Context, *FaultRequest) (*FaultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InjectFault not implemented")
+}
+func (UnimplementedCheckServer) QueryPayload(context.Context, *PayloadQueryRequest) (*PayloadQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryPayload not implemented")
+}
+func (UnimplementedCheckServer) GetShadowReport(context.Context, *ShadowReportRequest) (*ShadowReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetShadowReport not implemented")
+}
+func (UnimplementedCheckServer) GetDecisionSummary(context.Context, *DecisionSummaryRequest) (*DecisionSummaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDecisionSummary not implemented")
+}
+func (UnimplementedCheckServer) GetContentSelectors(context.Context, *ContentSelectorsRequest) (*ContentSelectorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContentSelectors not implemented")
+}
+func (UnimplementedCheckServer) Query(context.Context, *QueryRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
+}
+func (UnimplementedCheckServer) ListEntryTypes(context.Context, *EntryTypeRequest) (*EntryTypeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEntryTypes not implemented")
+}
+func (UnimplementedCheckServer) ResolveHosts(context.Context, *ResolveRequest) (*ResolveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveHosts not implemented")
+}
+func (UnimplementedCheckServer) SetMaintenance(context.Context, *MaintenanceRequest) (*MaintenanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMaintenance not implemented")
+}
+func (UnimplementedCheckServer) mustEmbedUnimplementedCheckServer() {}
+
+// UnsafeCheckServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CheckServer will
+// result in compilation errors.
+type UnsafeCheckServer interface {
+	mustEmbedUnimplementedCheckServer()
+}
+
+func RegisterCheckServer(s grpc.ServiceRegistrar, srv CheckServer) {
+	s.RegisterService(&Check_ServiceDesc, srv)
+}
+
+func _Check_SearchID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchID(ctx, req.(*IDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchIP4_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IP4Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchIP4(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchIP4",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchIP4(ctx, req.(*IP4Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchIP6_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IP6Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchIP6(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchIP6",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchIP6(ctx, req.(*IP6Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(URLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchURL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchURL(ctx, req.(*URLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchDomain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchDomain(ctx, req.(*DomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchDecision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchDecision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchDecision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchDecision(ctx, req.(*DecisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchTextDecision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TextDecisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchTextDecision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchTextDecision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchTextDecision(ctx, req.(*TextDecisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchSubnet4_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Subnet4Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchSubnet4(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchSubnet4",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchSubnet4(ctx, req.(*Subnet4Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchSubnet6_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Subnet6Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchSubnet6(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchSubnet6",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchSubnet6(ctx, req.(*Subnet6Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/Stat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Stat(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_GetRawContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).GetRawContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/GetRawContent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).GetRawContent(ctx, req.(*IDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchDecisionDate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecisionDateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchDecisionDate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchDecisionDate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchDecisionDate(ctx, req.(*DecisionDateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchIncludeTime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncludeTimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchIncludeTime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchIncludeTime",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchIncludeTime(ctx, req.(*IncludeTimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SearchRegistryTs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegistryTsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchRegistryTs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchRegistryTs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchRegistryTs(ctx, req.(*RegistryTsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_DiffGenerations_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DiffRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CheckServer).DiffGenerations(m, &checkDiffGenerationsServer{stream})
+}
+
+type Check_DiffGenerationsServer interface {
+	Send(*SelectorDelta) error
+	grpc.ServerStream
+}
+
+type checkDiffGenerationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *checkDiffGenerationsServer) Send(m *SelectorDelta) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Check_ListSNI_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SNIRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).ListSNI(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/ListSNI",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).ListSNI(ctx, req.(*SNIRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_WaitForChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).WaitForChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/WaitForChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).WaitForChange(ctx, req.(*WaitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_ListOrganizations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).ListOrganizations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/ListOrganizations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).ListOrganizations(ctx, req.(*OrgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_SelfTest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelfTestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SelfTest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SelfTest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SelfTest(ctx, req.(*SelfTestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_VerifyIndexes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).VerifyIndexes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/VerifyIndexes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).VerifyIndexes(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_GetChangelog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangelogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).GetChangelog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/GetChangelog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).GetChangelog(ctx, req.(*ChangelogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_ListRecent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).ListRecent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/ListRecent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).ListRecent(ctx, req.(*RecentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_GetStatsHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).GetStatsHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/GetStatsHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).GetStatsHistory(ctx, req.(*StatsHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_GetASNReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ASNReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).GetASNReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/GetASNReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).GetASNReport(ctx, req.(*ASNReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_Simulate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimulateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Simulate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/Simulate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Simulate(ctx, req.(*SimulateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_ProjectSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).ProjectSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/ProjectSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).ProjectSchedule(ctx, req.(*ScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_CompareWith_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).CompareWith(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/CompareWith",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).CompareWith(ctx, req.(*CompareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedCheckServer) SearchID(context.Context, *IDRequest) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchID not implemented")
-}
-func (UnimplementedCheckServer) SearchIP4(context.Context, *IP4Request) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchIP4 not implemented")
-}
-func (UnimplementedCheckServer) SearchIP6(context.Context, *IP6Request) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchIP6 not implemented")
-}
-func (UnimplementedCheckServer) SearchURL(context.Context, *URLRequest) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchURL not implemented")
-}
-func (UnimplementedCheckServer) SearchDomain(context.Context, *DomainRequest) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchDomain not implemented")
-}
-func (UnimplementedCheckServer) SearchDecision(context.Context, *DecisionRequest) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchDecision not implemented")
-}
-func (UnimplementedCheckServer) SearchTextDecision(context.Context, *TextDecisionRequest) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchTextDecision not implemented")
+func _Check_CompareSelector_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareSelectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).CompareSelector(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/CompareSelector",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).CompareSelector(ctx, req.(*CompareSelectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCheckServer) SearchSubnet4(context.Context, *Subnet4Request) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchSubnet4 not implemented")
+
+func _Check_SearchDomainSuffix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DomainSuffixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).SearchDomainSuffix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/SearchDomainSuffix",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).SearchDomainSuffix(ctx, req.(*DomainSuffixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCheckServer) SearchSubnet6(context.Context, *Subnet6Request) (*SearchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SearchSubnet6 not implemented")
+
+func _Check_ListHotSelectors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HotSelectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).ListHotSelectors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/ListHotSelectors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).ListHotSelectors(ctx, req.(*HotSelectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCheckServer) Stat(context.Context, *StatRequest) (*StatResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Stat not implemented")
+
+func _Check_ListSelectorlessRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectorlessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).ListSelectorlessRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/ListSelectorlessRecords",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).ListSelectorlessRecords(ctx, req.(*SelectorlessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCheckServer) Ping(context.Context, *PingRequest) (*PongResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+
+func _Check_GetParseWarnings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParseWarningsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).GetParseWarnings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/GetParseWarnings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).GetParseWarnings(ctx, req.(*ParseWarningsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCheckServer) mustEmbedUnimplementedCheckServer() {}
 
-// UnsafeCheckServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to CheckServer will
-// result in compilation errors.
-type UnsafeCheckServer interface {
-	mustEmbedUnimplementedCheckServer()
+func _Check_GetAddressAnomalies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnomaliesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).GetAddressAnomalies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/GetAddressAnomalies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).GetAddressAnomalies(ctx, req.(*AnomaliesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterCheckServer(s grpc.ServiceRegistrar, srv CheckServer) {
-	s.RegisterService(&Check_ServiceDesc, srv)
+func _Check_TagRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).TagRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Check/TagRecord",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).TagRecord(ctx, req.(*TagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(IDRequest)
+func _Check_ListTagged_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaggedRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchID(ctx, in)
+		return srv.(CheckServer).ListTagged(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchID",
+		FullMethod: "/msg.Check/ListTagged",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchID(ctx, req.(*IDRequest))
+		return srv.(CheckServer).ListTagged(ctx, req.(*TaggedRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchIP4_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(IP4Request)
+func _Check_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchIP4(ctx, in)
+		return srv.(CheckServer).GetVersion(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchIP4",
+		FullMethod: "/msg.Check/GetVersion",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchIP4(ctx, req.(*IP4Request))
+		return srv.(CheckServer).GetVersion(ctx, req.(*VersionRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchIP6_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(IP6Request)
+func _Check_InjectFault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FaultRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchIP6(ctx, in)
+		return srv.(CheckServer).InjectFault(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchIP6",
+		FullMethod: "/msg.Check/InjectFault",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchIP6(ctx, req.(*IP6Request))
+		return srv.(CheckServer).InjectFault(ctx, req.(*FaultRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(URLRequest)
+func _Check_QueryPayload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PayloadQueryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchURL(ctx, in)
+		return srv.(CheckServer).QueryPayload(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchURL",
+		FullMethod: "/msg.Check/QueryPayload",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchURL(ctx, req.(*URLRequest))
+		return srv.(CheckServer).QueryPayload(ctx, req.(*PayloadQueryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DomainRequest)
+func _Check_GetShadowReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShadowReportRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchDomain(ctx, in)
+		return srv.(CheckServer).GetShadowReport(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchDomain",
+		FullMethod: "/msg.Check/GetShadowReport",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchDomain(ctx, req.(*DomainRequest))
+		return srv.(CheckServer).GetShadowReport(ctx, req.(*ShadowReportRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchDecision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DecisionRequest)
+func _Check_GetDecisionSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecisionSummaryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchDecision(ctx, in)
+		return srv.(CheckServer).GetDecisionSummary(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchDecision",
+		FullMethod: "/msg.Check/GetDecisionSummary",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchDecision(ctx, req.(*DecisionRequest))
+		return srv.(CheckServer).GetDecisionSummary(ctx, req.(*DecisionSummaryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchTextDecision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(TextDecisionRequest)
+func _Check_GetContentSelectors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContentSelectorsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchTextDecision(ctx, in)
+		return srv.(CheckServer).GetContentSelectors(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchTextDecision",
+		FullMethod: "/msg.Check/GetContentSelectors",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchTextDecision(ctx, req.(*TextDecisionRequest))
+		return srv.(CheckServer).GetContentSelectors(ctx, req.(*ContentSelectorsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchSubnet4_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Subnet4Request)
+func _Check_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchSubnet4(ctx, in)
+		return srv.(CheckServer).Query(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchSubnet4",
+		FullMethod: "/msg.Check/Query",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchSubnet4(ctx, req.(*Subnet4Request))
+		return srv.(CheckServer).Query(ctx, req.(*QueryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_SearchSubnet6_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Subnet6Request)
+func _Check_ListEntryTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntryTypeRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).SearchSubnet6(ctx, in)
+		return srv.(CheckServer).ListEntryTypes(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/SearchSubnet6",
+		FullMethod: "/msg.Check/ListEntryTypes",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).SearchSubnet6(ctx, req.(*Subnet6Request))
+		return srv.(CheckServer).ListEntryTypes(ctx, req.(*EntryTypeRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StatRequest)
+func _Check_ResolveHosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).Stat(ctx, in)
+		return srv.(CheckServer).ResolveHosts(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/Stat",
+		FullMethod: "/msg.Check/ResolveHosts",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).Stat(ctx, req.(*StatRequest))
+		return srv.(CheckServer).ResolveHosts(ctx, req.(*ResolveRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Check_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PingRequest)
+func _Check_SetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaintenanceRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CheckServer).Ping(ctx, in)
+		return srv.(CheckServer).SetMaintenance(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/msg.Check/Ping",
+		FullMethod: "/msg.Check/SetMaintenance",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CheckServer).Ping(ctx, req.(*PingRequest))
+		return srv.(CheckServer).SetMaintenance(ctx, req.(*MaintenanceRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -459,7 +1605,149 @@ var Check_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Check_Ping_Handler,
 		},
+		{
+			MethodName: "GetRawContent",
+			Handler:    _Check_GetRawContent_Handler,
+		},
+		{
+			MethodName: "SearchDecisionDate",
+			Handler:    _Check_SearchDecisionDate_Handler,
+		},
+		{
+			MethodName: "SearchIncludeTime",
+			Handler:    _Check_SearchIncludeTime_Handler,
+		},
+		{
+			MethodName: "SearchRegistryTs",
+			Handler:    _Check_SearchRegistryTs_Handler,
+		},
+		{
+			MethodName: "ListSNI",
+			Handler:    _Check_ListSNI_Handler,
+		},
+		{
+			MethodName: "WaitForChange",
+			Handler:    _Check_WaitForChange_Handler,
+		},
+		{
+			MethodName: "ListOrganizations",
+			Handler:    _Check_ListOrganizations_Handler,
+		},
+		{
+			MethodName: "SelfTest",
+			Handler:    _Check_SelfTest_Handler,
+		},
+		{
+			MethodName: "VerifyIndexes",
+			Handler:    _Check_VerifyIndexes_Handler,
+		},
+		{
+			MethodName: "GetChangelog",
+			Handler:    _Check_GetChangelog_Handler,
+		},
+		{
+			MethodName: "ListRecent",
+			Handler:    _Check_ListRecent_Handler,
+		},
+		{
+			MethodName: "GetStatsHistory",
+			Handler:    _Check_GetStatsHistory_Handler,
+		},
+		{
+			MethodName: "GetASNReport",
+			Handler:    _Check_GetASNReport_Handler,
+		},
+		{
+			MethodName: "Simulate",
+			Handler:    _Check_Simulate_Handler,
+		},
+		{
+			MethodName: "ProjectSchedule",
+			Handler:    _Check_ProjectSchedule_Handler,
+		},
+		{
+			MethodName: "CompareWith",
+			Handler:    _Check_CompareWith_Handler,
+		},
+		{
+			MethodName: "CompareSelector",
+			Handler:    _Check_CompareSelector_Handler,
+		},
+		{
+			MethodName: "SearchDomainSuffix",
+			Handler:    _Check_SearchDomainSuffix_Handler,
+		},
+		{
+			MethodName: "ListHotSelectors",
+			Handler:    _Check_ListHotSelectors_Handler,
+		},
+		{
+			MethodName: "ListSelectorlessRecords",
+			Handler:    _Check_ListSelectorlessRecords_Handler,
+		},
+		{
+			MethodName: "GetParseWarnings",
+			Handler:    _Check_GetParseWarnings_Handler,
+		},
+		{
+			MethodName: "GetAddressAnomalies",
+			Handler:    _Check_GetAddressAnomalies_Handler,
+		},
+		{
+			MethodName: "TagRecord",
+			Handler:    _Check_TagRecord_Handler,
+		},
+		{
+			MethodName: "ListTagged",
+			Handler:    _Check_ListTagged_Handler,
+		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _Check_GetVersion_Handler,
+		},
+		{
+			MethodName: "InjectFault",
+			Handler:    _Check_InjectFault_Handler,
+		},
+		{
+			MethodName: "QueryPayload",
+			Handler:    _Check_QueryPayload_Handler,
+		},
+		{
+			MethodName: "GetShadowReport",
+			Handler:    _Check_GetShadowReport_Handler,
+		},
+		{
+			MethodName: "GetDecisionSummary",
+			Handler:    _Check_GetDecisionSummary_Handler,
+		},
+		{
+			MethodName: "GetContentSelectors",
+			Handler:    _Check_GetContentSelectors_Handler,
+		},
+		{
+			MethodName: "Query",
+			Handler:    _Check_Query_Handler,
+		},
+		{
+			MethodName: "ListEntryTypes",
+			Handler:    _Check_ListEntryTypes_Handler,
+		},
+		{
+			MethodName: "ResolveHosts",
+			Handler:    _Check_ResolveHosts_Handler,
+		},
+		{
+			MethodName: "SetMaintenance",
+			Handler:    _Check_SetMaintenance_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DiffGenerations",
+			Handler:       _Check_DiffGenerations_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "msg.proto",
 }
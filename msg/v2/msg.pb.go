@@ -0,0 +1,1528 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.12.4
+// source: v2/msg.proto
+
+// Version 2 of the API: typed records instead of the JSON pack, gRPC status codes
+// instead of in-band errors, streamed results. msg.Check is still served, deprecated.
+
+package msgv2
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BlockType int32
+
+const (
+	BlockType_BLOCK_TYPE_URL         BlockType = 0
+	BlockType_BLOCK_TYPE_HTTPS       BlockType = 1
+	BlockType_BLOCK_TYPE_DOMAIN      BlockType = 2
+	BlockType_BLOCK_TYPE_DOMAIN_MASK BlockType = 3
+	BlockType_BLOCK_TYPE_IP          BlockType = 4
+)
+
+// Enum value maps for BlockType.
+var (
+	BlockType_name = map[int32]string{
+		0: "BLOCK_TYPE_URL",
+		1: "BLOCK_TYPE_HTTPS",
+		2: "BLOCK_TYPE_DOMAIN",
+		3: "BLOCK_TYPE_DOMAIN_MASK",
+		4: "BLOCK_TYPE_IP",
+	}
+	BlockType_value = map[string]int32{
+		"BLOCK_TYPE_URL":         0,
+		"BLOCK_TYPE_HTTPS":       1,
+		"BLOCK_TYPE_DOMAIN":      2,
+		"BLOCK_TYPE_DOMAIN_MASK": 3,
+		"BLOCK_TYPE_IP":          4,
+	}
+)
+
+func (x BlockType) Enum() *BlockType {
+	p := new(BlockType)
+	*p = x
+	return p
+}
+
+func (x BlockType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BlockType) Descriptor() protoreflect.EnumDescriptor {
+	return file_v2_msg_proto_enumTypes[0].Descriptor()
+}
+
+func (BlockType) Type() protoreflect.EnumType {
+	return &file_v2_msg_proto_enumTypes[0]
+}
+
+func (x BlockType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BlockType.Descriptor instead.
+func (BlockType) EnumDescriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{0}
+}
+
+// EntryType - registry entry type, the legal ground of the blocking.
+// Values the registry adds later pass through as numbers.
+type EntryType int32
+
+const (
+	EntryType_ENTRY_TYPE_UNSPECIFIED    EntryType = 0
+	EntryType_ENTRY_TYPE_EAIS           EntryType = 1 // the unified register of prohibited information, 149-FZ art. 15.1.
+	EntryType_ENTRY_TYPE_COPYRIGHT      EntryType = 2 // copyright infringement, art. 15.2.
+	EntryType_ENTRY_TYPE_398_FZ         EntryType = 3 // extremism and calls to riots, art. 15.3.
+	EntryType_ENTRY_TYPE_97_FZ          EntryType = 4 // information dissemination organizers, art. 15.4.
+	EntryType_ENTRY_TYPE_COPYRIGHT_PERM EntryType = 5 // permanent blocking of repeated copyright infringement, art. 15.6.
+	EntryType_ENTRY_TYPE_PERSONAL_DATA  EntryType = 6 // personal data violators, art. 15.5.
+)
+
+// Enum value maps for EntryType.
+var (
+	EntryType_name = map[int32]string{
+		0: "ENTRY_TYPE_UNSPECIFIED",
+		1: "ENTRY_TYPE_EAIS",
+		2: "ENTRY_TYPE_COPYRIGHT",
+		3: "ENTRY_TYPE_398_FZ",
+		4: "ENTRY_TYPE_97_FZ",
+		5: "ENTRY_TYPE_COPYRIGHT_PERM",
+		6: "ENTRY_TYPE_PERSONAL_DATA",
+	}
+	EntryType_value = map[string]int32{
+		"ENTRY_TYPE_UNSPECIFIED":    0,
+		"ENTRY_TYPE_EAIS":           1,
+		"ENTRY_TYPE_COPYRIGHT":      2,
+		"ENTRY_TYPE_398_FZ":         3,
+		"ENTRY_TYPE_97_FZ":          4,
+		"ENTRY_TYPE_COPYRIGHT_PERM": 5,
+		"ENTRY_TYPE_PERSONAL_DATA":  6,
+	}
+)
+
+func (x EntryType) Enum() *EntryType {
+	p := new(EntryType)
+	*p = x
+	return p
+}
+
+func (x EntryType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EntryType) Descriptor() protoreflect.EnumDescriptor {
+	return file_v2_msg_proto_enumTypes[1].Descriptor()
+}
+
+func (EntryType) Type() protoreflect.EnumType {
+	return &file_v2_msg_proto_enumTypes[1]
+}
+
+func (x EntryType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EntryType.Descriptor instead.
+func (EntryType) EnumDescriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{1}
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Query:
+	//	*SearchRequest_Id
+	//	*SearchRequest_Ip4
+	//	*SearchRequest_Ip6
+	//	*SearchRequest_Url
+	//	*SearchRequest_Domain
+	//	*SearchRequest_Decision
+	Query      isSearchRequest_Query `protobuf_oneof:"query"`
+	EntryTypes []EntryType           `protobuf:"varint,7,rep,packed,name=entryTypes,proto3,enum=msg.v2.EntryType" json:"entryTypes,omitempty"` // only records of these entry types, empty means all.
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{0}
+}
+
+func (m *SearchRequest) GetQuery() isSearchRequest_Query {
+	if m != nil {
+		return m.Query
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetId() int32 {
+	if x, ok := x.GetQuery().(*SearchRequest_Id); ok {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetIp4() string {
+	if x, ok := x.GetQuery().(*SearchRequest_Ip4); ok {
+		return x.Ip4
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetIp6() string {
+	if x, ok := x.GetQuery().(*SearchRequest_Ip6); ok {
+		return x.Ip6
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetUrl() string {
+	if x, ok := x.GetQuery().(*SearchRequest_Url); ok {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetDomain() string {
+	if x, ok := x.GetQuery().(*SearchRequest_Domain); ok {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetDecision() uint64 {
+	if x, ok := x.GetQuery().(*SearchRequest_Decision); ok {
+		return x.Decision
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetEntryTypes() []EntryType {
+	if x != nil {
+		return x.EntryTypes
+	}
+	return nil
+}
+
+type isSearchRequest_Query interface {
+	isSearchRequest_Query()
+}
+
+type SearchRequest_Id struct {
+	Id int32 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type SearchRequest_Ip4 struct {
+	Ip4 string `protobuf:"bytes,2,opt,name=ip4,proto3,oneof"` // 1.2.3.4, indexed subnets containing it match too.
+}
+
+type SearchRequest_Ip6 struct {
+	Ip6 string `protobuf:"bytes,3,opt,name=ip6,proto3,oneof"` // 2001:db8::1, indexed subnets containing it match too.
+}
+
+type SearchRequest_Url struct {
+	Url string `protobuf:"bytes,4,opt,name=url,proto3,oneof"` // normalized.
+}
+
+type SearchRequest_Domain struct {
+	Domain string `protobuf:"bytes,5,opt,name=domain,proto3,oneof"` // normalized, masks of the domain match too.
+}
+
+type SearchRequest_Decision struct {
+	Decision uint64 `protobuf:"varint,6,opt,name=decision,proto3,oneof"` // Decision.hash.
+}
+
+func (*SearchRequest_Id) isSearchRequest_Query() {}
+
+func (*SearchRequest_Ip4) isSearchRequest_Query() {}
+
+func (*SearchRequest_Ip6) isSearchRequest_Query() {}
+
+func (*SearchRequest_Url) isSearchRequest_Query() {}
+
+func (*SearchRequest_Domain) isSearchRequest_Query() {}
+
+func (*SearchRequest_Decision) isSearchRequest_Query() {}
+
+type Record struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id                 int32     `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	RegistryUpdateTime int64     `protobuf:"varint,2,opt,name=registryUpdateTime,proto3" json:"registryUpdateTime,omitempty"` // registry update time of the dump the record was added or last changed in.
+	BlockType          BlockType `protobuf:"varint,3,opt,name=blockType,proto3,enum=msg.v2.BlockType" json:"blockType,omitempty"`
+	EntryType          EntryType `protobuf:"varint,4,opt,name=entryType,proto3,enum=msg.v2.EntryType" json:"entryType,omitempty"`
+	IncludeTime        int64     `protobuf:"varint,5,opt,name=includeTime,proto3" json:"includeTime,omitempty"` // Unix time.
+	Decision           *Decision `protobuf:"bytes,6,opt,name=decision,proto3" json:"decision,omitempty"`
+	Urls               []string  `protobuf:"bytes,7,rep,name=urls,proto3" json:"urls,omitempty"`
+	Domains            []string  `protobuf:"bytes,8,rep,name=domains,proto3" json:"domains,omitempty"` // as in the registry, "*." for masks.
+	Ip4                []string  `protobuf:"bytes,9,rep,name=ip4,proto3" json:"ip4,omitempty"`
+	Ip6                []string  `protobuf:"bytes,10,rep,name=ip6,proto3" json:"ip6,omitempty"`
+	Subnet4            []string  `protobuf:"bytes,11,rep,name=subnet4,proto3" json:"subnet4,omitempty"`
+	Subnet6            []string  `protobuf:"bytes,12,rep,name=subnet6,proto3" json:"subnet6,omitempty"`
+	Sni                []string  `protobuf:"bytes,13,rep,name=sni,proto3" json:"sni,omitempty"` // server names an SNI filter needs, "*." prefix means the whole subtree.
+	HttpUrls           int32     `protobuf:"varint,14,opt,name=httpUrls,proto3" json:"httpUrls,omitempty"`
+	HttpsUrls          int32     `protobuf:"varint,15,opt,name=httpsUrls,proto3" json:"httpsUrls,omitempty"`
+	SniOnly            bool      `protobuf:"varint,16,opt,name=sniOnly,proto3" json:"sniOnly,omitempty"`  // URL block with https URLs only.
+	Excluded           string    `protobuf:"bytes,17,opt,name=excluded,proto3" json:"excluded,omitempty"` // local exclusion rule matching the selector.
+	Match              *Match    `protobuf:"bytes,18,opt,name=match,proto3" json:"match,omitempty"`       // why the record is in the result.
+}
+
+func (x *Record) Reset() {
+	*x = Record{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Record) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Record) ProtoMessage() {}
+
+func (x *Record) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Record.ProtoReflect.Descriptor instead.
+func (*Record) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Record) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Record) GetRegistryUpdateTime() int64 {
+	if x != nil {
+		return x.RegistryUpdateTime
+	}
+	return 0
+}
+
+func (x *Record) GetBlockType() BlockType {
+	if x != nil {
+		return x.BlockType
+	}
+	return BlockType_BLOCK_TYPE_URL
+}
+
+func (x *Record) GetEntryType() EntryType {
+	if x != nil {
+		return x.EntryType
+	}
+	return EntryType_ENTRY_TYPE_UNSPECIFIED
+}
+
+func (x *Record) GetIncludeTime() int64 {
+	if x != nil {
+		return x.IncludeTime
+	}
+	return 0
+}
+
+func (x *Record) GetDecision() *Decision {
+	if x != nil {
+		return x.Decision
+	}
+	return nil
+}
+
+func (x *Record) GetUrls() []string {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+func (x *Record) GetDomains() []string {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+func (x *Record) GetIp4() []string {
+	if x != nil {
+		return x.Ip4
+	}
+	return nil
+}
+
+func (x *Record) GetIp6() []string {
+	if x != nil {
+		return x.Ip6
+	}
+	return nil
+}
+
+func (x *Record) GetSubnet4() []string {
+	if x != nil {
+		return x.Subnet4
+	}
+	return nil
+}
+
+func (x *Record) GetSubnet6() []string {
+	if x != nil {
+		return x.Subnet6
+	}
+	return nil
+}
+
+func (x *Record) GetSni() []string {
+	if x != nil {
+		return x.Sni
+	}
+	return nil
+}
+
+func (x *Record) GetHttpUrls() int32 {
+	if x != nil {
+		return x.HttpUrls
+	}
+	return 0
+}
+
+func (x *Record) GetHttpsUrls() int32 {
+	if x != nil {
+		return x.HttpsUrls
+	}
+	return 0
+}
+
+func (x *Record) GetSniOnly() bool {
+	if x != nil {
+		return x.SniOnly
+	}
+	return false
+}
+
+func (x *Record) GetExcluded() string {
+	if x != nil {
+		return x.Excluded
+	}
+	return ""
+}
+
+func (x *Record) GetMatch() *Match {
+	if x != nil {
+		return x.Match
+	}
+	return nil
+}
+
+type Decision struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Number string `protobuf:"bytes,1,opt,name=number,proto3" json:"number,omitempty"`
+	Date   string `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`  // YYYY-MM-DD as in the registry.
+	Org    string `protobuf:"bytes,3,opt,name=org,proto3" json:"org,omitempty"`    // as in the registry, not normalized.
+	Hash   uint64 `protobuf:"varint,4,opt,name=hash,proto3" json:"hash,omitempty"` // SearchRequest.decision key.
+}
+
+func (x *Decision) Reset() {
+	*x = Decision{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Decision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Decision) ProtoMessage() {}
+
+func (x *Decision) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Decision.ProtoReflect.Descriptor instead.
+func (*Decision) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Decision) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *Decision) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Decision) GetOrg() string {
+	if x != nil {
+		return x.Org
+	}
+	return ""
+}
+
+func (x *Decision) GetHash() uint64 {
+	if x != nil {
+		return x.Hash
+	}
+	return 0
+}
+
+type Match struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Kind     string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`         // ip4, ip6, subnet4, subnet6, domain, domain-mask, url, id, decision.
+	Selector string `protobuf:"bytes,2,opt,name=selector,proto3" json:"selector,omitempty"` // the matched selector, empty for id and decision.
+}
+
+func (x *Match) Reset() {
+	*x = Match{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Match) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Match) ProtoMessage() {}
+
+func (x *Match) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Match.ProtoReflect.Descriptor instead.
+func (*Match) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Match) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Match) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Generation  int64  `protobuf:"varint,1,opt,name=generation,proto3" json:"generation,omitempty"`  // last generation known to the client.
+	Changes     bool   `protobuf:"varint,2,opt,name=changes,proto3" json:"changes,omitempty"`        // send record changes of every generation, datasetHash of their messages is empty.
+	Delta       bool   `protobuf:"varint,3,opt,name=delta,proto3" json:"delta,omitempty"`            // with changes: every generation carries its Delta, a replica applies it without searches.
+	Transitions string `protobuf:"bytes,4,opt,name=transitions,proto3" json:"transitions,omitempty"` // with changes: only block type transitions matching the "from>to" pairs, see v1 ChangelogRequest.transitions, empty sends all.
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WatchRequest) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetChanges() bool {
+	if x != nil {
+		return x.Changes
+	}
+	return false
+}
+
+func (x *WatchRequest) GetDelta() bool {
+	if x != nil {
+		return x.Delta
+	}
+	return false
+}
+
+func (x *WatchRequest) GetTransitions() string {
+	if x != nil {
+		return x.Transitions
+	}
+	return ""
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{5}
+}
+
+type Generation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Generation         int64                  `protobuf:"varint,1,opt,name=generation,proto3" json:"generation,omitempty"`
+	RegistryUpdateTime int64                  `protobuf:"varint,2,opt,name=registryUpdateTime,proto3" json:"registryUpdateTime,omitempty"`
+	DatasetHash        string                 `protobuf:"bytes,3,opt,name=datasetHash,proto3" json:"datasetHash,omitempty"` // the same on instances that parsed the same dump identically.
+	Payloads           bool                   `protobuf:"varint,4,opt,name=payloads,proto3" json:"payloads,omitempty"`      // record payloads are kept, otherwise Decision.number is empty and Decision.org is the canonical one.
+	Added              []int32                `protobuf:"varint,5,rep,packed,name=added,proto3" json:"added,omitempty"`     // WatchRequest.changes: ids of records added by the generation.
+	Updated            []int32                `protobuf:"varint,6,rep,packed,name=updated,proto3" json:"updated,omitempty"`
+	Removed            []int32                `protobuf:"varint,7,rep,packed,name=removed,proto3" json:"removed,omitempty"`
+	Resync             bool                   `protobuf:"varint,8,opt,name=resync,proto3" json:"resync,omitempty"`           // WatchRequest.changes: the changes since the client's generation are not retained, search all records again.
+	Urgent             []int32                `protobuf:"varint,9,rep,packed,name=urgent,proto3" json:"urgent,omitempty"`    // WatchRequest.changes: urgent records of added and updated, enforce them first.
+	Delta              *Delta                 `protobuf:"bytes,10,opt,name=delta,proto3" json:"delta,omitempty"`             // WatchRequest.delta: the changes of the generation, empty with resync, download the whole Delta then.
+	Transitions        []*BlockTypeTransition `protobuf:"bytes,11,rep,name=transitions,proto3" json:"transitions,omitempty"` // WatchRequest.changes: updated records whose block type changed, reconfigure their enforcement.
+}
+
+func (x *Generation) Reset() {
+	*x = Generation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Generation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Generation) ProtoMessage() {}
+
+func (x *Generation) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Generation.ProtoReflect.Descriptor instead.
+func (*Generation) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Generation) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *Generation) GetRegistryUpdateTime() int64 {
+	if x != nil {
+		return x.RegistryUpdateTime
+	}
+	return 0
+}
+
+func (x *Generation) GetDatasetHash() string {
+	if x != nil {
+		return x.DatasetHash
+	}
+	return ""
+}
+
+func (x *Generation) GetPayloads() bool {
+	if x != nil {
+		return x.Payloads
+	}
+	return false
+}
+
+func (x *Generation) GetAdded() []int32 {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+func (x *Generation) GetUpdated() []int32 {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *Generation) GetRemoved() []int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+func (x *Generation) GetResync() bool {
+	if x != nil {
+		return x.Resync
+	}
+	return false
+}
+
+func (x *Generation) GetUrgent() []int32 {
+	if x != nil {
+		return x.Urgent
+	}
+	return nil
+}
+
+func (x *Generation) GetDelta() *Delta {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+func (x *Generation) GetTransitions() []*BlockTypeTransition {
+	if x != nil {
+		return x.Transitions
+	}
+	return nil
+}
+
+// BlockTypeTransition - updated record whose computed block type changed.
+type BlockTypeTransition struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   int32     `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	From BlockType `protobuf:"varint,2,opt,name=from,proto3,enum=msg.v2.BlockType" json:"from,omitempty"`
+	To   BlockType `protobuf:"varint,3,opt,name=to,proto3,enum=msg.v2.BlockType" json:"to,omitempty"`
+}
+
+func (x *BlockTypeTransition) Reset() {
+	*x = BlockTypeTransition{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockTypeTransition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockTypeTransition) ProtoMessage() {}
+
+func (x *BlockTypeTransition) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockTypeTransition.ProtoReflect.Descriptor instead.
+func (*BlockTypeTransition) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BlockTypeTransition) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *BlockTypeTransition) GetFrom() BlockType {
+	if x != nil {
+		return x.From
+	}
+	return BlockType_BLOCK_TYPE_URL
+}
+
+func (x *BlockTypeTransition) GetTo() BlockType {
+	if x != nil {
+		return x.To
+	}
+	return BlockType_BLOCK_TYPE_URL
+}
+
+// Delta - changes of the index between two generations, the body of /delta too.
+type Delta struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FromGeneration     int64           `protobuf:"varint,1,opt,name=fromGeneration,proto3" json:"fromGeneration,omitempty"` // 0 if resync.
+	Generation         int64           `protobuf:"varint,2,opt,name=generation,proto3" json:"generation,omitempty"`
+	RegistryUpdateTime int64           `protobuf:"varint,3,opt,name=registryUpdateTime,proto3" json:"registryUpdateTime,omitempty"`
+	Resync             bool            `protobuf:"varint,4,opt,name=resync,proto3" json:"resync,omitempty"`          // the changes since fromGeneration are not retained: these are all selectors and records, drop the others.
+	Indexes            []*IndexDelta   `protobuf:"bytes,5,rep,name=indexes,proto3" json:"indexes,omitempty"`         // ip4, ip6, subnet4, subnet6, domain, url, ones without changes are left out.
+	Records            []*PackedRecord `protobuf:"bytes,6,rep,name=records,proto3" json:"records,omitempty"`         // added and updated records as they are now, a later generation may change them again.
+	Removed            []int32         `protobuf:"varint,7,rep,packed,name=removed,proto3" json:"removed,omitempty"` // ids of removed records.
+}
+
+func (x *Delta) Reset() {
+	*x = Delta{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Delta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Delta) ProtoMessage() {}
+
+func (x *Delta) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Delta.ProtoReflect.Descriptor instead.
+func (*Delta) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Delta) GetFromGeneration() int64 {
+	if x != nil {
+		return x.FromGeneration
+	}
+	return 0
+}
+
+func (x *Delta) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *Delta) GetRegistryUpdateTime() int64 {
+	if x != nil {
+		return x.RegistryUpdateTime
+	}
+	return 0
+}
+
+func (x *Delta) GetResync() bool {
+	if x != nil {
+		return x.Resync
+	}
+	return false
+}
+
+func (x *Delta) GetIndexes() []*IndexDelta {
+	if x != nil {
+		return x.Indexes
+	}
+	return nil
+}
+
+func (x *Delta) GetRecords() []*PackedRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+func (x *Delta) GetRemoved() []int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+// IndexDelta - normalized selectors of an index which appeared and disappeared.
+type IndexDelta struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Index   string   `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	Added   []string `protobuf:"bytes,2,rep,name=added,proto3" json:"added,omitempty"`
+	Removed []string `protobuf:"bytes,3,rep,name=removed,proto3" json:"removed,omitempty"`
+}
+
+func (x *IndexDelta) Reset() {
+	*x = IndexDelta{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IndexDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexDelta) ProtoMessage() {}
+
+func (x *IndexDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexDelta.ProtoReflect.Descriptor instead.
+func (*IndexDelta) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *IndexDelta) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+func (x *IndexDelta) GetAdded() []string {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+func (x *IndexDelta) GetRemoved() []string {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+// PackedRecord - record as it is stored.
+type PackedRecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id                 int32  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	RegistryUpdateTime int64  `protobuf:"varint,2,opt,name=registryUpdateTime,proto3" json:"registryUpdateTime,omitempty"` // of the dump the record was added or last changed in.
+	Decision           uint64 `protobuf:"varint,3,opt,name=decision,proto3" json:"decision,omitempty"`                     // SearchRequest.decision key.
+	Payload            []byte `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`                        // JSON of the record, Decision.number is empty and Decision.org is the canonical one if payloads are not kept.
+	Compressed         bool   `protobuf:"varint,5,opt,name=compressed,proto3" json:"compressed,omitempty"`                 // payload is snappy compressed.
+}
+
+func (x *PackedRecord) Reset() {
+	*x = PackedRecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_v2_msg_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PackedRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackedRecord) ProtoMessage() {}
+
+func (x *PackedRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_msg_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackedRecord.ProtoReflect.Descriptor instead.
+func (*PackedRecord) Descriptor() ([]byte, []int) {
+	return file_v2_msg_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PackedRecord) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PackedRecord) GetRegistryUpdateTime() int64 {
+	if x != nil {
+		return x.RegistryUpdateTime
+	}
+	return 0
+}
+
+func (x *PackedRecord) GetDecision() uint64 {
+	if x != nil {
+		return x.Decision
+	}
+	return 0
+}
+
+func (x *PackedRecord) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *PackedRecord) GetCompressed() bool {
+	if x != nil {
+		return x.Compressed
+	}
+	return false
+}
+
+var File_v2_msg_proto protoreflect.FileDescriptor
+
+var file_v2_msg_proto_rawDesc = []byte{
+	0x0a, 0x0c, 0x76, 0x32, 0x2f, 0x6d, 0x73, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06,
+	0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x22, 0xd1, 0x01, 0x0a, 0x0d, 0x53, 0x65, 0x61, 0x72, 0x63,
+	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x03, 0x69, 0x70,
+	0x34, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x03, 0x69, 0x70, 0x34, 0x12, 0x12,
+	0x0a, 0x03, 0x69, 0x70, 0x36, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x03, 0x69,
+	0x70, 0x36, 0x12, 0x12, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x48,
+	0x00, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x18, 0x0a, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e,
+	0x12, 0x1c, 0x0a, 0x08, 0x64, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x04, 0x48, 0x00, 0x52, 0x08, 0x64, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x31,
+	0x0a, 0x0a, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x73, 0x18, 0x07, 0x20, 0x03,
+	0x28, 0x0e, 0x32, 0x11, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x54, 0x79, 0x70, 0x65, 0x52, 0x0a, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65,
+	0x73, 0x42, 0x07, 0x0a, 0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x22, 0xa7, 0x04, 0x0a, 0x06, 0x52,
+	0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x02, 0x69, 0x64, 0x12, 0x2e, 0x0a, 0x12, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72,
+	0x79, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x12, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2f, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x54, 0x79,
+	0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76,
+	0x32, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x52, 0x09, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x12, 0x2f, 0x0a, 0x09, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x54,
+	0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x6d, 0x73, 0x67, 0x2e,
+	0x76, 0x32, 0x2e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x52, 0x09, 0x65, 0x6e,
+	0x74, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x69, 0x6e, 0x63, 0x6c, 0x75,
+	0x64, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x69, 0x6e,
+	0x63, 0x6c, 0x75, 0x64, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x08, 0x64, 0x65, 0x63,
+	0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x73,
+	0x67, 0x2e, 0x76, 0x32, 0x2e, 0x44, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x64,
+	0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x18,
+	0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x75, 0x72, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x64,
+	0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x64, 0x6f,
+	0x6d, 0x61, 0x69, 0x6e, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x70, 0x34, 0x18, 0x09, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x03, 0x69, 0x70, 0x34, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x70, 0x36, 0x18, 0x0a,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x70, 0x36, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x62,
+	0x6e, 0x65, 0x74, 0x34, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6e,
+	0x65, 0x74, 0x34, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x36, 0x18, 0x0c,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x36, 0x12, 0x10, 0x0a,
+	0x03, 0x73, 0x6e, 0x69, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x73, 0x6e, 0x69, 0x12,
+	0x1a, 0x0a, 0x08, 0x68, 0x74, 0x74, 0x70, 0x55, 0x72, 0x6c, 0x73, 0x18, 0x0e, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x08, 0x68, 0x74, 0x74, 0x70, 0x55, 0x72, 0x6c, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x68,
+	0x74, 0x74, 0x70, 0x73, 0x55, 0x72, 0x6c, 0x73, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09,
+	0x68, 0x74, 0x74, 0x70, 0x73, 0x55, 0x72, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x6e, 0x69,
+	0x4f, 0x6e, 0x6c, 0x79, 0x18, 0x10, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x6e, 0x69, 0x4f,
+	0x6e, 0x6c, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x64, 0x18,
+	0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x64, 0x12,
+	0x23, 0x0a, 0x05, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d,
+	0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x52, 0x05, 0x6d,
+	0x61, 0x74, 0x63, 0x68, 0x22, 0x5c, 0x0a, 0x08, 0x44, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x16, 0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65, 0x12, 0x10, 0x0a, 0x03,
+	0x6f, 0x72, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6f, 0x72, 0x67, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x68, 0x61,
+	0x73, 0x68, 0x22, 0x37, 0x0a, 0x05, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x6b,
+	0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12,
+	0x1a, 0x0a, 0x08, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x22, 0x80, 0x01, 0x0a, 0x0c,
+	0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a,
+	0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07,
+	0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x63,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x12, 0x20, 0x0a, 0x0b,
+	0x74, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x0f,
+	0x0a, 0x0d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22,
+	0xf8, 0x02, 0x0a, 0x0a, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1e,
+	0x0a, 0x0a, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0a, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2e,
+	0x0a, 0x12, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12, 0x72, 0x65, 0x67, 0x69,
+	0x73, 0x74, 0x72, 0x79, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x20,
+	0x0a, 0x0b, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x48, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x48, 0x61, 0x73, 0x68,
+	0x12, 0x1a, 0x0a, 0x08, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x08, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x61, 0x64, 0x64, 0x65, 0x64, 0x18, 0x05, 0x20, 0x03, 0x28, 0x05, 0x52, 0x05, 0x61, 0x64, 0x64,
+	0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x05, 0x52, 0x07, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07,
+	0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x18, 0x07, 0x20, 0x03, 0x28, 0x05, 0x52, 0x07, 0x72,
+	0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x12, 0x16,
+	0x0a, 0x06, 0x75, 0x72, 0x67, 0x65, 0x6e, 0x74, 0x18, 0x09, 0x20, 0x03, 0x28, 0x05, 0x52, 0x06,
+	0x75, 0x72, 0x67, 0x65, 0x6e, 0x74, 0x12, 0x23, 0x0a, 0x05, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x44,
+	0x65, 0x6c, 0x74, 0x61, 0x52, 0x05, 0x64, 0x65, 0x6c, 0x74, 0x61, 0x12, 0x3d, 0x0a, 0x0b, 0x74,
+	0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x1b, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x54,
+	0x79, 0x70, 0x65, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x74,
+	0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x6f, 0x0a, 0x13, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x69,
+	0x64, 0x12, 0x25, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x11, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x54, 0x79,
+	0x70, 0x65, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x21, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x52, 0x02, 0x74, 0x6f, 0x22, 0x8f, 0x02, 0x0a, 0x05,
+	0x44, 0x65, 0x6c, 0x74, 0x61, 0x12, 0x26, 0x0a, 0x0e, 0x66, 0x72, 0x6f, 0x6d, 0x47, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x66,
+	0x72, 0x6f, 0x6d, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x0a,
+	0x0a, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0a, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2e, 0x0a,
+	0x12, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x54,
+	0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x72, 0x65, 0x73, 0x79, 0x6e, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x72,
+	0x65, 0x73, 0x79, 0x6e, 0x63, 0x12, 0x2c, 0x0a, 0x07, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x65, 0x73,
+	0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e,
+	0x49, 0x6e, 0x64, 0x65, 0x78, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x52, 0x07, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x07, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x06,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x50, 0x61,
+	0x63, 0x6b, 0x65, 0x64, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x52, 0x07, 0x72, 0x65, 0x63, 0x6f,
+	0x72, 0x64, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x18, 0x07,
+	0x20, 0x03, 0x28, 0x05, 0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x22, 0x52, 0x0a,
+	0x0a, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x65, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x05, 0x61, 0x64, 0x64, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76,
+	0x65, 0x64, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65,
+	0x64, 0x22, 0xa4, 0x01, 0x0a, 0x0c, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x64, 0x52, 0x65, 0x63, 0x6f,
+	0x72, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x2e, 0x0a, 0x12, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x12,
+	0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x64, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x18,
+	0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6d, 0x70,
+	0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x63, 0x6f,
+	0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x2a, 0x7b, 0x0a, 0x09, 0x42, 0x6c, 0x6f, 0x63,
+	0x6b, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x42, 0x4c, 0x4f, 0x43, 0x4b, 0x5f, 0x54,
+	0x59, 0x50, 0x45, 0x5f, 0x55, 0x52, 0x4c, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x42, 0x4c, 0x4f,
+	0x43, 0x4b, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x48, 0x54, 0x54, 0x50, 0x53, 0x10, 0x01, 0x12,
+	0x15, 0x0a, 0x11, 0x42, 0x4c, 0x4f, 0x43, 0x4b, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x44, 0x4f,
+	0x4d, 0x41, 0x49, 0x4e, 0x10, 0x02, 0x12, 0x1a, 0x0a, 0x16, 0x42, 0x4c, 0x4f, 0x43, 0x4b, 0x5f,
+	0x54, 0x59, 0x50, 0x45, 0x5f, 0x44, 0x4f, 0x4d, 0x41, 0x49, 0x4e, 0x5f, 0x4d, 0x41, 0x53, 0x4b,
+	0x10, 0x03, 0x12, 0x11, 0x0a, 0x0d, 0x42, 0x4c, 0x4f, 0x43, 0x4b, 0x5f, 0x54, 0x59, 0x50, 0x45,
+	0x5f, 0x49, 0x50, 0x10, 0x04, 0x2a, 0xc0, 0x01, 0x0a, 0x09, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x54,
+	0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x16, 0x45, 0x4e, 0x54, 0x52, 0x59, 0x5f, 0x54, 0x59, 0x50,
+	0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12,
+	0x13, 0x0a, 0x0f, 0x45, 0x4e, 0x54, 0x52, 0x59, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x45, 0x41,
+	0x49, 0x53, 0x10, 0x01, 0x12, 0x18, 0x0a, 0x14, 0x45, 0x4e, 0x54, 0x52, 0x59, 0x5f, 0x54, 0x59,
+	0x50, 0x45, 0x5f, 0x43, 0x4f, 0x50, 0x59, 0x52, 0x49, 0x47, 0x48, 0x54, 0x10, 0x02, 0x12, 0x15,
+	0x0a, 0x11, 0x45, 0x4e, 0x54, 0x52, 0x59, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x33, 0x39, 0x38,
+	0x5f, 0x46, 0x5a, 0x10, 0x03, 0x12, 0x14, 0x0a, 0x10, 0x45, 0x4e, 0x54, 0x52, 0x59, 0x5f, 0x54,
+	0x59, 0x50, 0x45, 0x5f, 0x39, 0x37, 0x5f, 0x46, 0x5a, 0x10, 0x04, 0x12, 0x1d, 0x0a, 0x19, 0x45,
+	0x4e, 0x54, 0x52, 0x59, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x43, 0x4f, 0x50, 0x59, 0x52, 0x49,
+	0x47, 0x48, 0x54, 0x5f, 0x50, 0x45, 0x52, 0x4d, 0x10, 0x05, 0x12, 0x1c, 0x0a, 0x18, 0x45, 0x4e,
+	0x54, 0x52, 0x59, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x50, 0x45, 0x52, 0x53, 0x4f, 0x4e, 0x41,
+	0x4c, 0x5f, 0x44, 0x41, 0x54, 0x41, 0x10, 0x06, 0x32, 0xa4, 0x01, 0x0a, 0x05, 0x43, 0x68, 0x65,
+	0x63, 0x6b, 0x12, 0x31, 0x0a, 0x06, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x12, 0x15, 0x2e, 0x6d,
+	0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x52, 0x65, 0x63,
+	0x6f, 0x72, 0x64, 0x30, 0x01, 0x12, 0x33, 0x0a, 0x05, 0x57, 0x61, 0x74, 0x63, 0x68, 0x12, 0x14,
+	0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x47, 0x65,
+	0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x30, 0x01, 0x12, 0x33, 0x0a, 0x06, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x12, 0x15, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x6d, 0x73,
+	0x67, 0x2e, 0x76, 0x32, 0x2e, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x42,
+	0x29, 0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x75, 0x73,
+	0x68, 0x65, 0x72, 0x32, 0x2f, 0x75, 0x32, 0x63, 0x6b, 0x64, 0x75, 0x6d, 0x70, 0x2f, 0x6d, 0x73,
+	0x67, 0x2f, 0x76, 0x32, 0x3b, 0x6d, 0x73, 0x67, 0x76, 0x32, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_v2_msg_proto_rawDescOnce sync.Once
+	file_v2_msg_proto_rawDescData = file_v2_msg_proto_rawDesc
+)
+
+func file_v2_msg_proto_rawDescGZIP() []byte {
+	file_v2_msg_proto_rawDescOnce.Do(func() {
+		file_v2_msg_proto_rawDescData = protoimpl.X.CompressGZIP(file_v2_msg_proto_rawDescData)
+	})
+	return file_v2_msg_proto_rawDescData
+}
+
+var file_v2_msg_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_v2_msg_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_v2_msg_proto_goTypes = []interface{}{
+	(BlockType)(0),              // 0: msg.v2.BlockType
+	(EntryType)(0),              // 1: msg.v2.EntryType
+	(*SearchRequest)(nil),       // 2: msg.v2.SearchRequest
+	(*Record)(nil),              // 3: msg.v2.Record
+	(*Decision)(nil),            // 4: msg.v2.Decision
+	(*Match)(nil),               // 5: msg.v2.Match
+	(*WatchRequest)(nil),        // 6: msg.v2.WatchRequest
+	(*StatusRequest)(nil),       // 7: msg.v2.StatusRequest
+	(*Generation)(nil),          // 8: msg.v2.Generation
+	(*BlockTypeTransition)(nil), // 9: msg.v2.BlockTypeTransition
+	(*Delta)(nil),               // 10: msg.v2.Delta
+	(*IndexDelta)(nil),          // 11: msg.v2.IndexDelta
+	(*PackedRecord)(nil),        // 12: msg.v2.PackedRecord
+}
+var file_v2_msg_proto_depIdxs = []int32{
+	1,  // 0: msg.v2.SearchRequest.entryTypes:type_name -> msg.v2.EntryType
+	0,  // 1: msg.v2.Record.blockType:type_name -> msg.v2.BlockType
+	1,  // 2: msg.v2.Record.entryType:type_name -> msg.v2.EntryType
+	4,  // 3: msg.v2.Record.decision:type_name -> msg.v2.Decision
+	5,  // 4: msg.v2.Record.match:type_name -> msg.v2.Match
+	10, // 5: msg.v2.Generation.delta:type_name -> msg.v2.Delta
+	9,  // 6: msg.v2.Generation.transitions:type_name -> msg.v2.BlockTypeTransition
+	0,  // 7: msg.v2.BlockTypeTransition.from:type_name -> msg.v2.BlockType
+	0,  // 8: msg.v2.BlockTypeTransition.to:type_name -> msg.v2.BlockType
+	11, // 9: msg.v2.Delta.indexes:type_name -> msg.v2.IndexDelta
+	12, // 10: msg.v2.Delta.records:type_name -> msg.v2.PackedRecord
+	2,  // 11: msg.v2.Check.Search:input_type -> msg.v2.SearchRequest
+	6,  // 12: msg.v2.Check.Watch:input_type -> msg.v2.WatchRequest
+	7,  // 13: msg.v2.Check.Status:input_type -> msg.v2.StatusRequest
+	3,  // 14: msg.v2.Check.Search:output_type -> msg.v2.Record
+	8,  // 15: msg.v2.Check.Watch:output_type -> msg.v2.Generation
+	8,  // 16: msg.v2.Check.Status:output_type -> msg.v2.Generation
+	14, // [14:17] is the sub-list for method output_type
+	11, // [11:14] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_v2_msg_proto_init() }
+func file_v2_msg_proto_init() {
+	if File_v2_msg_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_v2_msg_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Record); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Decision); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Match); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Generation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockTypeTransition); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Delta); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IndexDelta); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_v2_msg_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PackedRecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_v2_msg_proto_msgTypes[0].OneofWrappers = []interface{}{
+		(*SearchRequest_Id)(nil),
+		(*SearchRequest_Ip4)(nil),
+		(*SearchRequest_Ip6)(nil),
+		(*SearchRequest_Url)(nil),
+		(*SearchRequest_Domain)(nil),
+		(*SearchRequest_Decision)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_v2_msg_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_v2_msg_proto_goTypes,
+		DependencyIndexes: file_v2_msg_proto_depIdxs,
+		EnumInfos:         file_v2_msg_proto_enumTypes,
+		MessageInfos:      file_v2_msg_proto_msgTypes,
+	}.Build()
+	File_v2_msg_proto = out.File
+	file_v2_msg_proto_rawDesc = nil
+	file_v2_msg_proto_goTypes = nil
+	file_v2_msg_proto_depIdxs = nil
+}
@@ -0,0 +1,242 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.12.4
+// source: v2/msg.proto
+
+package msgv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// CheckClient is the client API for Check service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CheckClient interface {
+	// Records of the selector, one message per record. NOT_FOUND is never returned, the stream is just empty.
+	// UNAVAILABLE: the data is not ready. INVALID_ARGUMENT: no query or a bad address.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (Check_SearchClient, error)
+	// The current generation if it differs from the client's one, then every new generation.
+	// With changes every generation since the client's one is sent with its record changes,
+	// or the current one with resync if they are not retained.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Check_WatchClient, error)
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*Generation, error)
+}
+
+type checkClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCheckClient(cc grpc.ClientConnInterface) CheckClient {
+	return &checkClient{cc}
+}
+
+func (c *checkClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (Check_SearchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Check_ServiceDesc.Streams[0], "/msg.v2.Check/Search", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &checkSearchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Check_SearchClient interface {
+	Recv() (*Record, error)
+	grpc.ClientStream
+}
+
+type checkSearchClient struct {
+	grpc.ClientStream
+}
+
+func (x *checkSearchClient) Recv() (*Record, error) {
+	m := new(Record)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *checkClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Check_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Check_ServiceDesc.Streams[1], "/msg.v2.Check/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &checkWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Check_WatchClient interface {
+	Recv() (*Generation, error)
+	grpc.ClientStream
+}
+
+type checkWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *checkWatchClient) Recv() (*Generation, error) {
+	m := new(Generation)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *checkClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*Generation, error) {
+	out := new(Generation)
+	err := c.cc.Invoke(ctx, "/msg.v2.Check/Status", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CheckServer is the server API for Check service.
+// All implementations must embed UnimplementedCheckServer
+// for forward compatibility
+type CheckServer interface {
+	// Records of the selector, one message per record. NOT_FOUND is never returned, the stream is just empty.
+	// UNAVAILABLE: the data is not ready. INVALID_ARGUMENT: no query or a bad address.
+	Search(*SearchRequest, Check_SearchServer) error
+	// The current generation if it differs from the client's one, then every new generation.
+	// With changes every generation since the client's one is sent with its record changes,
+	// or the current one with resync if they are not retained.
+	Watch(*WatchRequest, Check_WatchServer) error
+	Status(context.Context, *StatusRequest) (*Generation, error)
+	mustEmbedUnimplementedCheckServer()
+}
+
+// UnimplementedCheckServer must be embedded to have forward compatible implementations.
+type UnimplementedCheckServer struct {
+}
+
+func (UnimplementedCheckServer) Search(*SearchRequest, Check_SearchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedCheckServer) Watch(*WatchRequest, Check_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedCheckServer) Status(context.Context, *StatusRequest) (*Generation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedCheckServer) mustEmbedUnimplementedCheckServer() {}
+
+// UnsafeCheckServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CheckServer will
+// result in compilation errors.
+type UnsafeCheckServer interface {
+	mustEmbedUnimplementedCheckServer()
+}
+
+func RegisterCheckServer(s grpc.ServiceRegistrar, srv CheckServer) {
+	s.RegisterService(&Check_ServiceDesc, srv)
+}
+
+func _Check_Search_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CheckServer).Search(m, &checkSearchServer{stream})
+}
+
+type Check_SearchServer interface {
+	Send(*Record) error
+	grpc.ServerStream
+}
+
+type checkSearchServer struct {
+	grpc.ServerStream
+}
+
+func (x *checkSearchServer) Send(m *Record) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Check_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CheckServer).Watch(m, &checkWatchServer{stream})
+}
+
+type Check_WatchServer interface {
+	Send(*Generation) error
+	grpc.ServerStream
+}
+
+type checkWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *checkWatchServer) Send(m *Generation) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Check_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.v2.Check/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Check_ServiceDesc is the grpc.ServiceDesc for Check service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Check_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "msg.v2.Check",
+	HandlerType: (*CheckServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Status",
+			Handler:    _Check_Status_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Search",
+			Handler:       _Check_Search_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Check_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "v2/msg.proto",
+}
@@ -77,6 +77,16 @@ func NormalizeURL(u string) string {
 	return nurl.String()
 }
 
+// URLWithoutScheme - normalized http or https URL without the scheme, the key of scheme agnostic
+// URL search, false for other schemes.
+func URLWithoutScheme(nURL string) (string, bool) {
+	if rest, ok := strings.CutPrefix(nURL, "https://"); ok {
+		return rest, true
+	}
+
+	return strings.CutPrefix(nURL, "http://")
+}
+
 // protocolPattern - regexp for remove misspelled protocol.
 var protocolPattern = regexp.MustCompile(`^(https?):?[/\\]*|^(http?):?[/\\]*|^//`)
 
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// LoadOrgAliases - extend parser.OrgAliases from the file with "variant = canonical" lines.
+func LoadOrgAliases(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		variant, canonical, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: no '='", n)
+		}
+
+		variant = strings.Join(strings.Fields(variant), " ")
+		canonical = strings.Join(strings.Fields(canonical), " ")
+
+		parser.OrgAliases[strings.ToUpper(variant)] = canonical
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	return nil
+}
+
+// OrgCount - number of records per canonical organization.
+type OrgCount struct {
+	Org   string
+	Count int
+}
+
+// OrgCounts - organizations ordered by record count, call it under read lock.
+func (dump *Dump) OrgCounts() []OrgCount {
+	result := make([]OrgCount, 0, len(dump.orgIdx))
+
+	for org, ids := range dump.orgIdx {
+		result = append(result, OrgCount{Org: org, Count: len(ids)})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].Org < result[j].Org
+	})
+
+	return result
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// TestOversizedRecords tests truncated and quarantined records over the size limits.
+func TestOversizedRecords(t *testing.T) {
+	defer func(dump *Dump, warnings *parser.Warnings, config ParseOptions) {
+		CurrentDump, ParseWarnings, ParseConfig = dump, warnings, config
+	}(CurrentDump, ParseWarnings, ParseConfig)
+
+	ParseWarnings = parser.NewWarnings()
+
+	CurrentDump, ParseConfig.MaxRecordSelectors, ParseConfig.Oversized = NewDump(), 4, parser.OversizedTruncate
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := CurrentDump.ContentIdx[111].Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(record.Domain) != 1 || len(record.URL) != 3 || len(record.IP4) != 0 || len(record.IP6) != 0 {
+		t.Errorf("Truncated: %v\n", record)
+	}
+
+	if _, ok := CurrentDump.ip4Idx[netip.MustParseAddr("10.1.1.1")]; ok {
+		t.Errorf("Truncated IP is indexed\n")
+	}
+
+	if Stats.Oversized == 0 {
+		t.Errorf("Truncated: no stats\n")
+	}
+
+	// the previous version of a quarantined record stays.
+	CurrentDump, ParseConfig.MaxRecordSelectors = NewDump(), 0
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	ParseConfig.MaxRecordSelectors, ParseConfig.Oversized = 4, parser.OversizedQuarantine
+
+	if err := Parse(strings.NewReader(xml02)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := CurrentDump.domainIdx["www.e01.tld"]; !ok || CurrentDump.ContentIdx[111] == nil {
+		t.Errorf("Quarantined: previous version is lost\n")
+	}
+
+	if warnings, _ := ParseWarnings.List(parser.WarnOversizedRecord); len(warnings) != 1 {
+		t.Errorf("Warnings: %v\n", warnings)
+	}
+
+	// fragments over the byte limit are never decoded.
+	CurrentDump, ParseConfig.MaxRecordSelectors, ParseConfig.MaxRecordBytes = NewDump(), 0, 100
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(CurrentDump.ContentIdx) != 0 || Stats.Oversized != 5 {
+		t.Errorf("Bytes: %d records, %d oversized\n", len(CurrentDump.ContentIdx), Stats.Oversized)
+	}
+}
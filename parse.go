@@ -1,13 +1,14 @@
 package main
 
 import (
-	"net"
+	"net/netip"
 	"sync"
 	"time"
 
 	"github.com/yl2chen/cidranger"
 
 	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
 )
 
 type (
@@ -17,17 +18,85 @@ type (
 )
 
 type ParseStatistics struct {
-	Count          int
+	parser.Statistics // records and bytes read, see parser.ContentStream.
+
 	AddCount       int
 	UpdateCount    int
 	RemoveCount    int
+	DuplicateCount int  // same content id seen more than once in one dump.
+	SameCount      int  // changed records which are not updates, see CompareSemantic.
+	Selectorless   int  // records without IPs, subnets, domains and URLs.
+	Anomalies      int  // address anomalies of the current records, see Dump.Anomalies.
+	Bogons         int  // private and bogon addresses of the current records, part of Anomalies.
+	Initial        bool // parsed into an empty index, i.e. everything is added.
 	MaxIDSetLen    int
-	MaxContentSize int
+	JournalBytes   int64         // memory of the ids journal, see IDJournal.
+	JournalReused  bool          // the journal of the previous parse is reused.
+	Duration       time.Duration // parse and commit.
 	Updated        time.Time
+
+	Transitions []BlockTypeTransition // updated records whose block type changed.
 }
 
 var Stats ParseStatistics
 
+// Duplicate content id policies.
+const (
+	DuplicateKeepLast  = "last"  // the later record replaces the earlier one.
+	DuplicateKeepFirst = "first" // the later record is ignored.
+	DuplicateMerge     = "merge" // selectors of the later record are added to the earlier one.
+)
+
+// Payload retention policies.
+const (
+	PayloadKeep = "keep" // the JSON payload of every record is kept, empty is PayloadKeep.
+	PayloadNone = "none" // no payloads, records are rebuilt of the indexed fields, see PackedContent.Record.
+)
+
+// ParseOptions - parser knobs.
+type ParseOptions struct {
+	KeepRaw            bool   // keep deflated <content>...</content> for GetRawContent.
+	CompressThreshold  int    // snappy compress payloads not smaller than this, 0 - never.
+	DuplicatePolicy    string // what to do with duplicate content ids.
+	Hash               string // record and decision hash function, see parser.NewHasher.
+	HashSeed           uint64 // hash seed, 0 - unseeded.
+	Charset            string // charset mode, see parser.CharsetDeclared.
+	Compare            string // compare mode of changed records, see CompareHash.
+	MixedURLs          string // block type of URL records with https and other URLs, see MixedHTTPS.
+	Payload            string // payload retention, see PayloadKeep.
+	Anomalies          string // address anomaly policy, see parser.AnomalyKeep.
+	MaxRecordSelectors int    // selectors of a record, 0 - unlimited.
+	MaxRecordBytes     int    // bytes of <content>...</content>, 0 - unlimited.
+	Oversized          string // oversized record policy, see parser.OversizedTruncate.
+}
+
+// ParseConfig - parser configuration, it is set once at startup.
+var ParseConfig ParseOptions
+
+// ParseWarnings - warnings of all parses.
+var ParseWarnings = parser.NewWarnings()
+
+// ParseWarning - format problems of one kind, element and attribute, see parser.Warning.
+type ParseWarning = parser.Warning
+
+// decoderConfig - knobs of the record decoder.
+func (o ParseOptions) decoderConfig() parser.Config {
+	return parser.Config{
+		Hash:               o.Hash,
+		HashSeed:           o.HashSeed,
+		Charset:            o.Charset,
+		Anomalies:          o.Anomalies,
+		MaxRecordSelectors: o.MaxRecordSelectors,
+		MaxRecordBytes:     o.MaxRecordBytes,
+		Oversized:          o.Oversized,
+	}
+}
+
+// newDecoder - decoder of ParseConfig, warnings are collected in ParseWarnings.
+func newDecoder() (*parser.Decoder, error) {
+	return parser.NewDecoder(ParseConfig.decoderConfig(), ParseWarnings)
+}
+
 func (s *ParseStatistics) Update() {
 	s.Updated = time.Now()
 }
@@ -35,114 +104,162 @@ func (s *ParseStatistics) Update() {
 type Dump struct {
 	sync.RWMutex
 	utime       int64
-	ip4Idx      IP4Set
-	ip6Idx      StringIntSet
-	subnet4Idx  StringIntSet
-	subnet6Idx  StringIntSet
+	generation  int64  // bumped on every applied parse.
+	removed     int    // records removed since the last compaction.
+	dumpID      string // registry dump the generation is parsed from, see SetSource.
+	dumpCRC     string
+	ip4Idx      AddrSet
+	ip6Idx      AddrSet
+	subnet4Idx  PrefixSet
+	subnet6Idx  PrefixSet
 	netTree     cidranger.Ranger
 	urlIdx      StringIntSet
+	urlAnyIdx   StringIntSet // http and https URLs without the scheme, see URLWithoutScheme.
 	domainIdx   StringIntSet
 	decisionIdx DecisionSet
-	ContentIdx  MinContentMap
+	orgIdx      StringIntSet // canonical decision organization.
+	// decision date index.
+	decisionDateIdx *TimeSet
+	includeTimeIdx  *TimeSet // include time index.
+	tsIdx           *TimeSet // registry ts index, records without ts are not in it.
+	ContentIdx      MinContentMap
+
+	sni          sniCache            // lazy SNI list of the generation.
+	dataset      datasetCache        // lazy dataset hash of the generation.
+	suffix       suffixCache         // lazy domain suffix index of the generation.
+	selectorless selectorlessCache   // lazy ids of records without selectors of the generation.
+	decisionText decisionTextCache   // lazy folded decision texts of the generation.
+	hot          hotCounter          // record counts of shared selectors, kept by the index functions.
+	changes      changeBuffer        // record changes of the last generations, see WatchKeep.
+	anomalies    map[int32][]Anomaly // address anomalies of the current records.
+	ordinals     *IDOrdinals         // positions of the records in the dump file of the generation, see parseStage.see.
+	changed      chan struct{}       // closed when the next generation is published.
 }
 
 func NewDump() *Dump {
 	return &Dump{
 		utime:       0,
-		ip4Idx:      make(IP4Set),
-		ip6Idx:      make(StringIntSet),
-		subnet4Idx:  make(StringIntSet),
-		subnet6Idx:  make(StringIntSet),
+		ip4Idx:      make(AddrSet),
+		ip6Idx:      make(AddrSet),
+		subnet4Idx:  make(PrefixSet),
+		subnet6Idx:  make(PrefixSet),
 		urlIdx:      make(StringIntSet),
+		urlAnyIdx:   make(StringIntSet),
 		domainIdx:   make(StringIntSet),
 		decisionIdx: make(DecisionSet),
+		orgIdx:      make(StringIntSet),
 		ContentIdx:  make(MinContentMap),
 		netTree:     cidranger.NewPCTrieRanger(),
+
+		hot:             newHotCounter(),
+		decisionDateIdx: NewTimeSet(),
+		includeTimeIdx:  NewTimeSet(),
+		tsIdx:           NewTimeSet(),
+		changed:         make(chan struct{}),
 	}
 }
 
-func (d *Dump) InsertToIndexIP4(ip4 uint32, id int32) {
+// Changes - current generation and the channel closed on the next one.
+func (d *Dump) Changes() (int64, <-chan struct{}) {
+	d.RLock()
+	defer d.RUnlock()
+
+	return d.generation, d.changed
+}
+
+func (d *Dump) InsertToIndexIP4(ip4 netip.Addr, id int32) {
 	d.ip4Idx.Insert(ip4, id)
+	d.hot.set(hotKey{kind: SelectorIP4, ip: ip4}, len(d.ip4Idx[ip4]))
 }
 
-func (d *Dump) RemoveFromIndexIP4(ip4 uint32, id int32) {
+func (d *Dump) RemoveFromIndexIP4(ip4 netip.Addr, id int32) {
 	d.ip4Idx.Remove(ip4, id)
+	d.hot.set(hotKey{kind: SelectorIP4, ip: ip4}, len(d.ip4Idx[ip4]))
 }
 
-func (d *Dump) InsertToIndexIP6(ip6 string, id int32) {
+func (d *Dump) InsertToIndexIP6(ip6 netip.Addr, id int32) {
 	d.ip6Idx.Insert(ip6, id)
+	d.hot.set(hotKey{kind: SelectorIP6, ip: ip6}, len(d.ip6Idx[ip6]))
 }
 
-func (d *Dump) RemoveFromIndexIP6(ip6 string, id int32) {
+func (d *Dump) RemoveFromIndexIP6(ip6 netip.Addr, id int32) {
 	d.ip6Idx.Remove(ip6, id)
+	d.hot.set(hotKey{kind: SelectorIP6, ip: ip6}, len(d.ip6Idx[ip6]))
 }
 
-func (d *Dump) InsertToIndexSubnet4(subnet4 string, id int32) {
+func (d *Dump) InsertToIndexSubnet4(subnet4 netip.Prefix, id int32) {
 	if d.subnet4Idx.Insert(subnet4, id) {
-		_, network, err := net.ParseCIDR(subnet4)
-		if err != nil {
-			logger.Debug.Printf("Can't parse CIDR: %s: %s\n", subnet4, err.Error())
-		}
-		err = d.netTree.Insert(cidranger.NewBasicRangerEntry(*network))
-		if err != nil {
-			logger.Debug.Printf("Can't insert CIDR: %s: %s\n", subnet4, err.Error())
-		}
+		d.insertToNetTree(subnet4)
 	}
+
+	d.hot.set(hotKey{kind: SelectorSubnet4, subnet: subnet4}, len(d.subnet4Idx[subnet4]))
 }
 
-func (d *Dump) RemoveFromSubnet4(subnet4 string, id int32) {
+func (d *Dump) RemoveFromSubnet4(subnet4 netip.Prefix, id int32) {
 	if d.subnet4Idx.Remove(subnet4, id) {
-		_, network, err := net.ParseCIDR(subnet4)
-		if err != nil {
-			logger.Debug.Printf("Can't parse CIDR: %s: %s\n", subnet4, err.Error())
-		}
-		_, err = d.netTree.Remove(*network)
-		if err != nil {
-			logger.Debug.Printf("Can't remove CIDR: %s: %s\n", subnet4, err.Error())
-		}
+		d.removeFromNetTree(subnet4)
 	}
+
+	d.hot.set(hotKey{kind: SelectorSubnet4, subnet: subnet4}, len(d.subnet4Idx[subnet4]))
 }
 
-func (d *Dump) InsertToIndexSubnet6(subnet6 string, id int32) {
+func (d *Dump) InsertToIndexSubnet6(subnet6 netip.Prefix, id int32) {
 	if d.subnet6Idx.Insert(subnet6, id) {
-		_, network, err := net.ParseCIDR(subnet6)
-		if err != nil {
-			logger.Debug.Printf("Can't parse CIDR: %s: %s\n", subnet6, err.Error())
-		}
-		err = d.netTree.Insert(cidranger.NewBasicRangerEntry(*network))
-		if err != nil {
-			logger.Debug.Printf("Can't insert CIDR: %s: %s\n", subnet6, err.Error())
-		}
+		d.insertToNetTree(subnet6)
 	}
+
+	d.hot.set(hotKey{kind: SelectorSubnet6, subnet: subnet6}, len(d.subnet6Idx[subnet6]))
 }
 
-func (d *Dump) RemoveFromIndexSubnet6(subnet6 string, id int32) {
+func (d *Dump) RemoveFromIndexSubnet6(subnet6 netip.Prefix, id int32) {
 	if d.subnet6Idx.Remove(subnet6, id) {
-		_, network, err := net.ParseCIDR(subnet6)
-		if err != nil {
-			logger.Debug.Printf("Can't parse CIDR: %s: %s\n", subnet6, err.Error())
-		}
-		_, err = d.netTree.Remove(*network)
-		if err != nil {
-			logger.Debug.Printf("Can't remove CIDR: %s: %s\n", subnet6, err.Error())
-		}
+		d.removeFromNetTree(subnet6)
+	}
+
+	d.hot.set(hotKey{kind: SelectorSubnet6, subnet: subnet6}, len(d.subnet6Idx[subnet6]))
+}
+
+// insertToNetTree - the subnet is indexed now.
+func (d *Dump) insertToNetTree(subnet netip.Prefix) {
+	if err := d.netTree.Insert(cidranger.NewBasicRangerEntry(prefixIPNet(subnet))); err != nil {
+		logger.Debug.Printf("Can't insert CIDR: %s: %s\n", subnet, err.Error())
+	}
+}
+
+// removeFromNetTree - the subnet is not indexed anymore.
+func (d *Dump) removeFromNetTree(subnet netip.Prefix) {
+	if _, err := d.netTree.Remove(prefixIPNet(subnet)); err != nil {
+		logger.Debug.Printf("Can't remove CIDR: %s: %s\n", subnet, err.Error())
 	}
 }
 
 func (d *Dump) InsertToIndexURL(url string, id int32) {
 	d.urlIdx.Insert(url, id)
+	d.hot.set(hotKey{kind: SelectorURL, key: url}, len(d.urlIdx[url]))
+
+	if key, ok := URLWithoutScheme(url); ok {
+		d.urlAnyIdx.Insert(key, id)
+	}
 }
 
 func (d *Dump) RemoveFromIndexURL(url string, id int32) {
 	d.urlIdx.Remove(url, id)
+	d.hot.set(hotKey{kind: SelectorURL, key: url}, len(d.urlIdx[url]))
+
+	// the record may still have the URL with the other scheme.
+	if key, ok := URLWithoutScheme(url); ok && !d.urlIdx["http://"+key].Has(id) && !d.urlIdx["https://"+key].Has(id) {
+		d.urlAnyIdx.Remove(key, id)
+	}
 }
 
 func (d *Dump) InsertToIndexDomain(domain string, id int32) {
 	d.domainIdx.Insert(domain, id)
+	d.hot.set(hotKey{kind: SelectorDomain, key: domain}, len(d.domainIdx[domain]))
 }
 
 func (d *Dump) RemoveFromIndexDomain(domain string, id int32) {
 	d.domainIdx.Remove(domain, id)
+	d.hot.set(hotKey{kind: SelectorDomain, key: domain}, len(d.domainIdx[domain]))
 }
 
 func (d *Dump) InsertToIndexDecision(decision uint64, id int32) {
@@ -153,19 +270,50 @@ func (d *Dump) RemoveFromIndexDecision(decision uint64, id int32) {
 	d.decisionIdx.Remove(decision, id)
 }
 
-var CurrentDump = NewDump()
+func (d *Dump) InsertToIndexOrg(org string, id int32) {
+	d.orgIdx.Insert(org, id)
+}
+
+func (d *Dump) RemoveFromIndexOrg(org string, id int32) {
+	d.orgIdx.Remove(org, id)
+}
 
-type Reg struct {
-	UpdateTime         int64
-	UpdateTimeUrgently string
-	FormatVersion      string
+func (d *Dump) InsertToIndexDecisionDate(date int64, id int32) {
+	d.decisionDateIdx.Insert(date, id)
 }
 
+func (d *Dump) RemoveFromIndexDecisionDate(date int64, id int32) {
+	d.decisionDateIdx.Remove(date, id)
+}
+
+func (d *Dump) InsertToIndexIncludeTime(t int64, id int32) {
+	d.includeTimeIdx.Insert(t, id)
+}
+
+func (d *Dump) RemoveFromIndexIncludeTime(t int64, id int32) {
+	d.includeTimeIdx.Remove(t, id)
+}
+
+func (d *Dump) InsertToIndexTs(t int64, id int32) {
+	if t > 0 {
+		d.tsIdx.Insert(t, id)
+	}
+}
+
+func (d *Dump) RemoveFromIndexTs(t int64, id int32) {
+	if t > 0 {
+		d.tsIdx.Remove(t, id)
+	}
+}
+
+var CurrentDump = NewDump()
+
+// Reg - attributes of the register, see parser.Reg.
+type Reg = parser.Reg
+
+// UpdateDumpTime - the registry is republished without changes, records keep their update times.
 func UpdateDumpTime(UpdateTime int64) {
 	CurrentDump.Lock()
-	for _, v := range CurrentDump.ContentIdx {
-		v.RegistryUpdateTime = UpdateTime
-	}
 	CurrentDump.utime = UpdateTime
 	CurrentDump.Unlock()
 }
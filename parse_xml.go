@@ -2,145 +2,57 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
-	"encoding/xml"
 	"fmt"
-	"hash"
-	"hash/fnv"
 	"io"
-	"net"
-	"strconv"
+	"net/netip"
 	"strings"
-
-	"golang.org/x/net/html/charset"
+	"time"
 
 	"github.com/usher2/u2ckdump/internal/logger"
 	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
 )
 
-const (
-	elementContent   = "content"
-	elementDecision  = "decision"
-	elementURL       = "url"
-	elementDomain    = "domain"
-	elementIP4       = "ip"
-	elementIP6       = "ipv6"
-	elementIP4Subnet = "ipSubnet"
-	elementIP6Subnet = "ipv6Subnet"
-)
-
-var hasher64 hash.Hash64
-
-// UnmarshalContent - unmarshal <content> element.
-func UnmarshalContent(contBuf []byte, content *Content) error {
-	buf := bytes.NewReader(contBuf)
-	decoder := xml.NewDecoder(buf)
+// dumpTailSize - bytes at the end of the dump to look for the register close.
+const dumpTailSize = 4096
 
-	for {
-		token, err := decoder.Token()
-		if token == nil {
-			if err != io.EOF {
-				return fmt.Errorf("token: %w", err)
-			}
+// CheckDumpTail - cheap check before Parse touches the index: the dump must end with
+// the register close tag. Corruption in the middle is caught by Parse itself.
+func CheckDumpTail(r io.ReadSeeker) error {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
 
-			break
-		}
+	offset := size - dumpTailSize
+	if offset < 0 {
+		offset = 0
+	}
 
-		switch element := token.(type) {
-		case xml.StartElement:
-			// TODO: one func for one case, handle time parsing
-			switch element.Name.Local {
-			case elementContent:
-				if err := parseContentElement(element, content); err != nil {
-					return fmt.Errorf("parse content elm: %w", err)
-				}
-			case elementDecision:
-				if err := decoder.DecodeElement(&content.Decision, &element); err != nil {
-					return fmt.Errorf("parse decision elm: %w", err)
-				}
-			case elementURL:
-				u := XMLURL{}
-				if err := decoder.DecodeElement(&u, &element); err != nil {
-					return fmt.Errorf("parse url elm: %w", err)
-				}
-
-				content.URL = append(content.URL, URL{URL: u.URL, Ts: parseRFC3339Time(u.Ts)})
-			case elementDomain:
-				domain := XMLDomain{}
-				if err := decoder.DecodeElement(&domain, &element); err != nil {
-					return fmt.Errorf("parse domain elm: %w", err)
-				}
-
-				content.Domain = append(content.Domain, Domain{Domain: domain.Domain, Ts: parseRFC3339Time(domain.Ts)})
-			case elementIP4:
-				ip4 := XMLIP{}
-				if err := decoder.DecodeElement(&ip4, &element); err != nil {
-					return fmt.Errorf("parse ip elm: %w", err)
-				}
-
-				content.IP4 = append(content.IP4, IP4{IP4: IPv4StrToInt(ip4.IP), Ts: parseRFC3339Time(ip4.Ts)})
-			case elementIP6:
-				ip6 := XMLIP6{}
-				if err := decoder.DecodeElement(&ip6, &element); err != nil {
-					return fmt.Errorf("parse ipv6 elm: %w", err)
-				}
-
-				content.IP6 = append(content.IP6, IP6{IP6: net.ParseIP(ip6.IP6), Ts: parseRFC3339Time(ip6.Ts)})
-			case elementIP4Subnet:
-				subnet4 := XMLSubnet{}
-				if err := decoder.DecodeElement(&subnet4, &element); err != nil {
-					return fmt.Errorf("parse subnet elm: %w", err)
-				}
-
-				content.Subnet4 = append(content.Subnet4, Subnet4{Subnet4: subnet4.Subnet, Ts: parseRFC3339Time(subnet4.Ts)})
-			case elementIP6Subnet:
-				subnet6 := XMLSubnet6{}
-				if err := decoder.DecodeElement(&subnet6, &element); err != nil {
-					return fmt.Errorf("parse ipv6 subnet elm: %w", err)
-				}
-
-				content.Subnet6 = append(content.Subnet6, Subnet6{Subnet6: subnet6.Subnet6, Ts: parseRFC3339Time(subnet6.Ts)})
-			}
-		}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
 	}
 
-	return nil
-}
+	tail, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
 
-// pasre <content> element itself.
-func parseContentElement(element xml.StartElement, content *Content) error {
-	for _, attr := range element.Attr {
-		switch attr.Name.Local {
-		case "id":
-			id, err := strconv.Atoi(attr.Value)
-			if err != nil {
-				return fmt.Errorf("id atoi: %w: %s", err, attr.Value)
-			}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
 
-			content.ID = int32(id)
-		case "entryType":
-			entryType, err := strconv.Atoi(attr.Value)
-			if err != nil {
-				return fmt.Errorf("entryType atoi: %w: %s", err, attr.Value)
-			}
+	tail = bytes.TrimRight(tail, " \t\r\n\x00")
 
-			content.EntryType = int32(entryType)
-		case "urgencyType":
-			urgencyType, err := strconv.Atoi(attr.Value)
-			if err != nil {
-				return fmt.Errorf("urgencyType atoi: %w: %s", err, attr.Value)
-			}
+	i := bytes.LastIndex(tail, []byte("</"))
+	if i < 0 || !bytes.HasSuffix(tail, []byte(">")) {
+		return parser.ErrTruncatedDump
+	}
 
-			content.UrgencyType = int32(urgencyType)
-		case "includeTime":
-			content.IncludeTime = parseMoscowTime(attr.Value)
-		case "blockType":
-			content.BlockType = attr.Value
-		case "hash":
-			content.Hash = attr.Value
-		case "ts":
-			content.Ts = parseRFC3339Time(attr.Value)
-		}
+	// reg:register, register or any other prefix.
+	name := string(tail[i+2 : len(tail)-1])
+	if _, local, _ := strings.Cut(name, ":"); name != "register" && local != "register" {
+		return parser.ErrTruncatedDump
 	}
 
 	return nil
@@ -148,158 +60,127 @@ func parseContentElement(element xml.StartElement, content *Content) error {
 
 // Parse - parse dump.
 func Parse(dumpFile io.Reader) error {
-	var (
-		reg                            Reg
-		buffer                         bytes.Buffer
-		bufferOffset, offsetCorrection int64
+	return parse(dumpFile, nil)
+}
 
-		stats ParseStatistics
-	)
+// parse - parse dump, with ckpt the stage is checkpointed and may be resumed, see ParseResumable.
+func parse(dumpFile io.Reader, ckpt *parseCheckpoint) error {
+	var stats ParseStatistics
 
-	hasher64 = fnv.New64a()
-	decoder := xml.NewDecoder(dumpFile)
+	stats.Initial = len(CurrentDump.ContentIdx) == 0
+	started := time.Now()
 
-	// we need this closure, we don't want constructor
-	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
-		r, err := charset.NewReaderLabel(label, input)
-		if err != nil {
-			return nil, err
-		}
+	decoder, err := newDecoder()
+	if err != nil {
+		return err
+	}
 
-		offsetCorrection = decoder.InputOffset()
+	stream := parser.NewContentStream(dumpFile, decoder, &stats.Statistics)
+	stream.Started = ckpt.registerStarted
 
-		return io.TeeReader(r, &buffer), nil
+	// the original bytes are only known without the lenient conversion.
+	if ParseConfig.KeepRaw && ParseConfig.Charset != parser.CharsetLenient {
+		stream.Capturer().KeepInput()
 	}
 
-	// TODO: What is it?
-	ContJournal := make(Int32Map, len(CurrentDump.ContentIdx))
+	// nothing is applied before the whole dump is read.
+	stage := newParseStage()
 
-	for {
-		tokenStartOffset := decoder.InputOffset() - offsetCorrection
+	if ckpt != nil {
+		// a resumed parse reads the rest of the dump after the register start.
+		if err := ckpt.resume(stage, &stats, decoder.Hasher); err != nil {
+			return err
+		}
 
-		token, err := decoder.Token()
-		if token == nil {
-			if err != io.EOF {
-				return err
-			}
+		stream.SetOffset(ckpt.shift)
+	}
 
+	for {
+		id, contBuf, err := stream.Fragment()
+		if err == io.EOF {
 			break
 		}
 
-		switch element := token.(type) {
-		case xml.StartElement:
-			switch element.Name.Local {
-			case "register":
-				parseRegister(element, &reg)
-			case "content":
-				id := getContentId(element)
-
-				// parse <content>...</content> only if need
-				decoder.Skip()
-
-				// read buffer to mark anyway
-				diff := tokenStartOffset - bufferOffset
-				buffer.Next(int(diff))
-				bufferOffset += diff
+		if err != nil {
+			// nothing is committed, the previous generation stays.
+			return err
+		}
 
-				// calc end of element
-				tokenStartOffset = decoder.InputOffset() - offsetCorrection
+		stage.add(CurrentDump, decoder, id, decoder.Hasher.Record(contBuf), contBuf, stream.Capturer().Input(), &stats)
 
-				// create hash of <content>...</content> for comp
-				contBuf := buffer.Next(int(tokenStartOffset - bufferOffset))
-				if stats.MaxContentSize < len(contBuf) {
-					stats.MaxContentSize = len(contBuf)
-				}
+		if err := ckpt.processed(id, stage, &stats, stream.Capturer()); err != nil {
+			logger.Error.Printf("Can't save parse checkpoint, checkpoints are off: %s\n", err.Error())
+		}
+	}
 
-				bufferOffset = tokenStartOffset
+	reg := stream.Reg
 
-				hasher64.Reset()
-				hasher64.Write(contBuf)
+	// Commit.
+	added, removed := CurrentDump.Commit(stage, &stats, reg.UpdateTime)
 
-				newRecordHash := hasher64.Sum64()
+	// the initial load is not news.
+	if !stats.Initial {
+		PublishUrgent(stage, added)
+		PublishFeeds(added, removed)
+		RunHooks(stage, added, removed)
+	}
 
-				// create or update
-				CurrentDump.Lock()
+	stats.Statistics, stats.Duration = stream.Statistics(), time.Since(started)
+	stats.Selectorless = len(CurrentDump.SelectorlessRecords())
 
-				prevCont, exists := CurrentDump.ContentIdx[id]
-				ContJournal[id] = Nothing{} // add to journal.
+	// not in the parse duration, it has its own metrics.
+	CompactIndexes(CurrentDump, CompactThreshold)
 
-				switch {
-				case !exists:
-					newCont, err := NewContent(newRecordHash, contBuf)
-					if err != nil {
-						logger.Error.Printf("Decode Error: %s\n", err)
+	stats.Update()
+	Stats = stats
 
-						break
-					}
+	metricJournalBytes.Set(stats.JournalBytes)
 
-					CurrentDump.NewPackedContent(newCont, reg.UpdateTime)
-					stats.AddCount++
-				case prevCont.RecordHash != newRecordHash:
-					newCont, err := NewContent(newRecordHash, contBuf)
-					if err != nil {
-						logger.Error.Printf("Decode Error: %s\n", err)
+	// Print stats.
 
-						break
-					}
+	logger.Info.Printf("Records: %d Added: %d Updated: %d Removed: %d Duplicates: %d\n", stats.Count, stats.AddCount, stats.UpdateCount, stats.RemoveCount, stats.DuplicateCount)
 
-					CurrentDump.MergePackedContent(newCont, prevCont, reg.UpdateTime)
-					stats.UpdateCount++
-				default:
-					CurrentDump.SetContentUpdateTime(id, reg.UpdateTime)
-				}
+	if stats.SameCount > 0 {
+		logger.Info.Printf("  Changed bytes of same records: %d\n", stats.SameCount)
+	}
 
-				CurrentDump.Unlock()
-				stats.Count++
-			}
-		}
+	if stats.Selectorless > 0 {
+		logger.Warning.Printf("  Records without selectors: %d\n", stats.Selectorless)
+	}
 
-		// read buffer anyway
-		diff := tokenStartOffset - bufferOffset
-		buffer.Next(int(diff))
-		bufferOffset += diff
+	if stats.Anomalies > 0 {
+		logger.Warning.Printf("  Address anomalies: %d\n", stats.Anomalies)
 	}
 
-	// Cleanup.
-	CurrentDump.Cleanup(ContJournal, &stats, reg.UpdateTime)
+	if stats.Bogons > 0 {
+		logger.Warning.Printf("  Private and bogon addresses: %d\n", stats.Bogons)
+	}
 
-	stats.Update()
-	Stats = stats
+	if stats.Oversized > 0 {
+		logger.Warning.Printf("  Oversized records: %d\n", stats.Oversized)
+	}
 
-	// Print stats.
+	if len(stats.Transitions) > 0 {
+		logger.Info.Printf("  Block type transitions: %d\n", len(stats.Transitions))
+	}
 
-	logger.Info.Printf("Records: %d Added: %d Updated: %d Removed: %d\n", stats.Count, stats.AddCount, stats.UpdateCount, stats.RemoveCount)
 	logger.Info.Printf("  IP: %d IPv6: %d Subnets: %d Subnets6: %d Domains: %d URSs: %d\n",
 		len(CurrentDump.ip4Idx), len(CurrentDump.ip6Idx), len(CurrentDump.subnet4Idx), len(CurrentDump.subnet6Idx),
 		len(CurrentDump.domainIdx), len(CurrentDump.urlIdx))
 	logger.Info.Printf("Biggest array: %d\n", stats.MaxIDSetLen)
 	logger.Info.Printf("Biggest content: %d\n", stats.MaxContentSize)
+	logger.Info.Printf("Parsed: %d bytes in %s\n", stats.Size, stats.Duration)
+	logger.Info.Printf("Journal: %d bytes, reused: %t\n", stats.JournalBytes, stats.JournalReused)
 
-	return nil
-}
-
-func NewContent(recordHash uint64, buf []byte) (*Content, error) {
-	content := &Content{
-		RecordHash: recordHash,
-	}
-
-	err := UnmarshalContent(buf, content)
-	if err != nil {
-		return nil, err
+	if stats.ReplacementRecords > 0 || stats.CharsetFallbacks > 0 {
+		logger.Warning.Printf("Charset: %d records with %d replacement characters, %d bytes decoded as cp1251\n",
+			stats.ReplacementRecords, stats.ReplacementChars, stats.CharsetFallbacks)
 	}
 
-	return content, nil
-}
-
-func (dump *Dump) Cleanup(existed Int32Map, stats *ParseStatistics, utime int64) {
-	dump.Lock()
-	defer dump.Unlock()
-
-	dump.purge(existed, stats)   // remove deleted records from index.
-	dump.calcMaxEntityLen(stats) // calc max entity len.
-	dump.utime = utime           // set global update time.
+	return nil
 }
 
+// calcMaxEntityLen - sets MaxIDSetLen of the stats, the biggest id set of the selector indexes.
 func (dump *Dump) calcMaxEntityLen(stats *ParseStatistics) {
 	stats.MaxIDSetLen = 0
 
@@ -336,16 +217,17 @@ func (dump *Dump) calcMaxEntityLen(stats *ParseStatistics) {
 }
 
 // purge - remove deleted records from index.
-func (dump *Dump) purge(existed Int32Map, stats *ParseStatistics) {
+func (dump *Dump) purge(existed *IDJournal, stats *ParseStatistics) []*PackedContent {
+	var removed []*PackedContent
+
 	for id, cont := range dump.ContentIdx {
-		if _, ok := existed[id]; !ok {
+		if !existed.Has(id) {
 			for _, ip4 := range cont.IP4 {
 				dump.RemoveFromIndexIP4(ip4.IP4, cont.ID)
 			}
 
 			for _, ip6 := range cont.IP6 {
-				ip6 := string(ip6.IP6)
-				dump.RemoveFromIndexIP6(ip6, cont.ID)
+				dump.RemoveFromIndexIP6(ip6.IP6, cont.ID)
 			}
 
 			for _, subnet6 := range cont.Subnet6 {
@@ -365,25 +247,23 @@ func (dump *Dump) purge(existed Int32Map, stats *ParseStatistics) {
 			}
 
 			dump.RemoveFromIndexDecision(cont.Decision, cont.ID)
+			dump.RemoveFromIndexDecisionDate(cont.DecisionDate, cont.ID)
+			dump.RemoveFromIndexIncludeTime(cont.IncludeTime, cont.ID)
+			dump.RemoveFromIndexTs(cont.Ts, cont.ID)
+			dump.RemoveFromIndexOrg(cont.Org, cont.ID)
 
 			delete(dump.ContentIdx, id)
+			removed = append(removed, cont)
 
 			stats.RemoveCount++
 		}
 	}
-}
 
-// Marshal - encodes content to JSON.
-func (record *Content) Marshal() []byte {
-	b, err := json.Marshal(record)
-	if err != nil {
-		logger.Error.Printf("Error encoding: %s\n", err.Error())
-	}
-	return b
+	return removed
 }
 
-// constructBlockType - returns block type for content.
-func (record *Content) constructBlockType() int32 {
+// constructBlockType - returns block type for content, URL records are typed by the schemes of their URLs.
+func constructBlockType(record *Content, urls URLClass) int32 {
 	switch record.BlockType {
 	case "ip":
 		return BlockTypeIP
@@ -393,38 +273,67 @@ func (record *Content) constructBlockType() int32 {
 		return BlockTypeMask
 	default:
 		if record.BlockType != "default" && record.BlockType != "" {
-			logger.Error.Printf("Unknown block type: %s\n", record.BlockType)
-		}
-		if record.HTTPSBlock == 0 {
-			return BlockTypeURL
-		} else {
-			return BlockTypeHTTPS
+			ParseWarnings.Add(parser.WarnUnknownBlockType, parser.ElementContent, "blockType", record.BlockType, record.ID)
 		}
+
+		return urlClassifier().BlockType(urls)
 	}
 }
 
-func (dump *Dump) SetContentUpdateTime(id int32, updateTime int64) {
-	dump.ContentIdx[id].RegistryUpdateTime = dump.utime
+// MergePackedContent - merges new content with previous one.
+// It is used to update existing content. The merge is copy-on-write: the updated copy
+// replaces the previous record in the index, the previous one is never changed.
+func (dump *Dump) MergePackedContent(record *Content, payload []byte, prev *PackedContent, updateTime int64) *PackedContent {
+	next := prev.clone()
+	next.refreshPackedContent(record.RecordHash, updateTime, payload)
+
+	dump.EctractAndApplyUpdateIP4(record, next)
+	dump.EctractAndApplyUpdateIP6(record, next)
+	dump.EctractAndApplyUpdateSubnet4(record, next)
+	dump.EctractAndApplyUpdateSubnet6(record, next)
+	dump.EctractAndApplyUpdateDomain(record, next)
+	dump.EctractAndApplyUpdateURL(record, next)
+	dump.EctractAndApplyUpdateDecision(record, next) // reason for ALARM!!!
+	dump.EctractAndApplyUpdateIncludeTime(record, next)
+	dump.EctractAndApplyUpdateTs(record, next)
+
+	dump.ContentIdx[next.ID] = next
+
+	return next
 }
 
-// MergePackedContent - merges new content with previous one.
-// It is used to update existing content.
-func (dump *Dump) MergePackedContent(record *Content, prev *PackedContent, updateTime int64) {
-	prev.refreshPackedContent(record.RecordHash, updateTime, record.Marshal())
+// RehashPackedContent - replaces the record with a copy with the hash, payload and raw
+// fragment of the semantically same record, indexes and the update time are kept.
+func (dump *Dump) RehashPackedContent(record *Content, payload, raw []byte, prev *PackedContent) {
+	next := *prev // selector slices are not changed here, they can be shared.
+	next.RecordHash = record.RecordHash
+	next.setPayload(payload)
 
-	dump.EctractAndApplyUpdateIP4(record, prev)
-	dump.EctractAndApplyUpdateIP6(record, prev)
-	dump.EctractAndApplyUpdateSubnet4(record, prev)
-	dump.EctractAndApplyUpdateSubnet6(record, prev)
-	dump.EctractAndApplyUpdateDomain(record, prev)
-	dump.EctractAndApplyUpdateURL(record, prev)
-	dump.EctractAndApplyUpdateDecision(record, prev) // reason for ALARM!!!
+	if raw != nil {
+		next.Raw = raw
+	}
+
+	dump.ContentIdx[next.ID] = &next
+}
+
+// clone - copy of the record to be changed, selector slices are changed in place, so they are copied too.
+func (pack *PackedContent) clone() *PackedContent {
+	next := *pack
+
+	next.URL = append([]URL(nil), pack.URL...)
+	next.IP4 = append([]IP4(nil), pack.IP4...)
+	next.IP6 = append([]IP6(nil), pack.IP6...)
+	next.Subnet4 = append([]Subnet4(nil), pack.Subnet4...)
+	next.Subnet6 = append([]Subnet6(nil), pack.Subnet6...)
+	next.Domain = append([]Domain(nil), pack.Domain...)
+
+	return &next
 }
 
 // NewPackedContent - creates new content.
 // It is used to add new content.
-func (dump *Dump) NewPackedContent(record *Content, updateTime int64) {
-	fresh := newPackedContent(record.ID, record.RecordHash, updateTime, record.Marshal())
+func (dump *Dump) NewPackedContent(record *Content, payload []byte, updateTime int64) *PackedContent {
+	fresh := newPackedContent(record.ID, record.RecordHash, updateTime, payload)
 	dump.ContentIdx[record.ID] = fresh
 
 	dump.ExtractAndApplyIP4(record, fresh)
@@ -434,31 +343,85 @@ func (dump *Dump) NewPackedContent(record *Content, updateTime int64) {
 	dump.ExtractAndApplyDomain(record, fresh)
 	dump.ExtractAndApplyURL(record, fresh)
 	dump.ExtractAndApplyDecision(record, fresh)
+	dump.ExtractAndApplyIncludeTime(record, fresh)
+	dump.ExtractAndApplyTs(record, fresh)
+
+	return fresh
+}
+
+// unionContent - previous record with selectors of the duplicate appended.
+func (pack *PackedContent) unionContent(dup *Content) (*Content, error) {
+	merged, err := pack.Record()
+	if err != nil {
+		return nil, err
+	}
+
+	merged.DecisionHash = pack.Decision
+
+	return merged.Union(dup), nil
 }
 
 func (dump *Dump) ExtractAndApplyDecision(record *Content, pack *PackedContent) {
-	pack.Decision = hashDecision(&record.Decision)
+	pack.Decision = record.DecisionHash
 	dump.InsertToIndexDecision(pack.Decision, pack.ID)
+
+	pack.DecisionDate = ParseWarnings.CheckTime(parser.ElementDecision, "date", record.Decision.Date, record.ID, parser.ParseDecisionTime(record.Decision.Date))
+	dump.InsertToIndexDecisionDate(pack.DecisionDate, pack.ID)
+
+	pack.Org = parser.NormalizeOrg(record.Decision.Org)
+	dump.InsertToIndexOrg(pack.Org, pack.ID)
+
+	pack.EntryType = record.EntryType
+	pack.UrgencyType = record.UrgencyType
 }
 
 // IT IS REASON FOR ALARM!!!!
 func (dump *Dump) EctractAndApplyUpdateDecision(record *Content, pack *PackedContent) {
 	dump.RemoveFromIndexDecision(pack.Decision, pack.ID)
+	dump.RemoveFromIndexDecisionDate(pack.DecisionDate, pack.ID)
+	dump.RemoveFromIndexOrg(pack.Org, pack.ID)
 
-	pack.Decision = hashDecision(&record.Decision)
+	pack.Decision = record.DecisionHash
+	pack.DecisionDate = ParseWarnings.CheckTime(parser.ElementDecision, "date", record.Decision.Date, record.ID, parser.ParseDecisionTime(record.Decision.Date))
+	pack.Org = parser.NormalizeOrg(record.Decision.Org)
+	pack.EntryType = record.EntryType
+	pack.UrgencyType = record.UrgencyType
 
 	dump.InsertToIndexDecision(pack.Decision, pack.ID)
+	dump.InsertToIndexDecisionDate(pack.DecisionDate, pack.ID)
+	dump.InsertToIndexOrg(pack.Org, pack.ID)
+}
+
+func (dump *Dump) ExtractAndApplyIncludeTime(record *Content, pack *PackedContent) {
+	pack.IncludeTime = record.IncludeTime
+	dump.InsertToIndexIncludeTime(pack.IncludeTime, pack.ID)
 }
 
-func hashDecision(decision *Decision) uint64 {
-	// hash.Write([]byte(v0.Decision.Org + " " + v0.Decision.Number + " " + v0.Decision.Date))
-	hasher64.Reset()
-	hasher64.Write([]byte(decision.Org))
-	hasher64.Write([]byte(" "))
-	hasher64.Write([]byte(decision.Number))
-	hasher64.Write([]byte(" "))
-	hasher64.Write([]byte(decision.Date))
-	return hasher64.Sum64()
+func (dump *Dump) EctractAndApplyUpdateIncludeTime(record *Content, pack *PackedContent) {
+	if pack.IncludeTime == record.IncludeTime {
+		return
+	}
+
+	dump.RemoveFromIndexIncludeTime(pack.IncludeTime, pack.ID)
+
+	pack.IncludeTime = record.IncludeTime
+	dump.InsertToIndexIncludeTime(pack.IncludeTime, pack.ID)
+}
+
+func (dump *Dump) ExtractAndApplyTs(record *Content, pack *PackedContent) {
+	pack.Ts = record.Ts
+	dump.InsertToIndexTs(pack.Ts, pack.ID)
+}
+
+func (dump *Dump) EctractAndApplyUpdateTs(record *Content, pack *PackedContent) {
+	if pack.Ts == record.Ts {
+		return
+	}
+
+	dump.RemoveFromIndexTs(pack.Ts, pack.ID)
+
+	pack.Ts = record.Ts
+	dump.InsertToIndexTs(pack.Ts, pack.ID)
 }
 
 func (dump *Dump) ExtractAndApplyIP4(record *Content, pack *PackedContent) {
@@ -471,7 +434,7 @@ func (dump *Dump) ExtractAndApplyIP4(record *Content, pack *PackedContent) {
 }
 
 func (dump *Dump) EctractAndApplyUpdateIP4(record *Content, pack *PackedContent) {
-	ipExisted := make(map[uint32]Nothing, len(pack.IP4))
+	ipExisted := make(map[netip.Addr]Nothing, len(pack.IP4))
 	if len(record.IP4) > 0 {
 		for _, ip4 := range record.IP4 {
 			pack.InsertIP4(ip4)
@@ -480,7 +443,8 @@ func (dump *Dump) EctractAndApplyUpdateIP4(record *Content, pack *PackedContent)
 		}
 	}
 
-	for _, ip4 := range pack.IP4 {
+	// over a copy, RemoveIP4 shifts pack.IP4 in place.
+	for _, ip4 := range append([]IP4(nil), pack.IP4...) {
 		if _, ok := ipExisted[ip4.IP4]; !ok {
 			pack.RemoveIP4(ip4)
 			dump.RemoveFromIndexIP4(ip4.IP4, pack.ID)
@@ -511,35 +475,35 @@ func (pack *PackedContent) RemoveIP4(ip4 IP4) {
 func (dump *Dump) ExtractAndApplyIP6(record *Content, pack *PackedContent) {
 	if len(record.IP6) > 0 {
 		pack.IP6 = record.IP6
-		for _, ip4 := range pack.IP6 {
-			dump.InsertToIndexIP6(string(ip4.IP6), pack.ID)
+		for _, ip6 := range pack.IP6 {
+			dump.InsertToIndexIP6(ip6.IP6, pack.ID)
 		}
 	}
 }
 
 func (dump *Dump) EctractAndApplyUpdateIP6(record *Content, pack *PackedContent) {
-	ipExisted := make(map[string]Nothing, len(pack.IP6))
+	ipExisted := make(map[netip.Addr]Nothing, len(pack.IP6))
 	if len(record.IP6) > 0 {
 		for _, ip6 := range record.IP6 {
 			pack.InsertIP6(ip6)
 
-			addr := string(ip6.IP6)
-			dump.InsertToIndexIP6(addr, pack.ID)
-			ipExisted[addr] = Nothing{}
+			dump.InsertToIndexIP6(ip6.IP6, pack.ID)
+			ipExisted[ip6.IP6] = Nothing{}
 		}
 	}
 
-	for _, ip6 := range pack.IP6 {
-		if _, ok := ipExisted[string(ip6.IP6)]; !ok {
+	// over a copy, RemoveIP6 shifts pack.IP6 in place.
+	for _, ip6 := range append([]IP6(nil), pack.IP6...) {
+		if _, ok := ipExisted[ip6.IP6]; !ok {
 			pack.RemoveIP6(ip6)
-			dump.RemoveFromIndexIP6(string(ip6.IP6), pack.ID)
+			dump.RemoveFromIndexIP6(ip6.IP6, pack.ID)
 		}
 	}
 }
 
 func (pack *PackedContent) InsertIP6(ip6 IP6) {
 	for _, existedIP6 := range pack.IP6 {
-		if string(ip6.IP6) == string(existedIP6.IP6) && ip6.Ts == existedIP6.Ts {
+		if ip6 == existedIP6 {
 			return
 		}
 	}
@@ -549,7 +513,7 @@ func (pack *PackedContent) InsertIP6(ip6 IP6) {
 
 func (pack *PackedContent) RemoveIP6(ip6 IP6) {
 	for i, existedIP6 := range pack.IP6 {
-		if string(ip6.IP6) == string(existedIP6.IP6) && ip6.Ts == existedIP6.Ts {
+		if ip6 == existedIP6 {
 			pack.IP6 = append(pack.IP6[:i], pack.IP6[i+1:]...)
 
 			return
@@ -567,7 +531,7 @@ func (dump *Dump) ExtractAndApplySubnet4(record *Content, pack *PackedContent) {
 }
 
 func (dump *Dump) EctractAndApplyUpdateSubnet4(record *Content, pack *PackedContent) {
-	subnetExisted := NewStringSet(len(pack.Subnet4))
+	subnetExisted := make(map[netip.Prefix]Nothing, len(pack.Subnet4))
 	if len(record.Subnet4) > 0 {
 		for _, subnet4 := range record.Subnet4 {
 			pack.InsertSubnet4(subnet4)
@@ -576,7 +540,8 @@ func (dump *Dump) EctractAndApplyUpdateSubnet4(record *Content, pack *PackedCont
 		}
 	}
 
-	for _, subnet4 := range pack.Subnet4 {
+	// over a copy, RemoveSubnet4 shifts pack.Subnet4 in place.
+	for _, subnet4 := range append([]Subnet4(nil), pack.Subnet4...) {
 		if _, ok := subnetExisted[subnet4.Subnet4]; !ok {
 			pack.RemoveSubnet4(subnet4)
 			dump.RemoveFromSubnet4(subnet4.Subnet4, pack.ID)
@@ -608,13 +573,13 @@ func (dump *Dump) ExtractAndApplySubnet6(record *Content, pack *PackedContent) {
 	if len(record.Subnet6) > 0 {
 		pack.Subnet6 = record.Subnet6
 		for _, subnet6 := range pack.Subnet6 {
-			dump.InsertToIndexSubnet4(subnet6.Subnet6, pack.ID)
+			dump.InsertToIndexSubnet6(subnet6.Subnet6, pack.ID)
 		}
 	}
 }
 
 func (dump *Dump) EctractAndApplyUpdateSubnet6(record *Content, pack *PackedContent) {
-	subnetExisted := NewStringSet(len(pack.Subnet6))
+	subnetExisted := make(map[netip.Prefix]Nothing, len(pack.Subnet6))
 	if len(record.Subnet6) > 0 {
 		for _, subnet6 := range record.Subnet6 {
 			pack.InsertSubnet6(subnet6)
@@ -623,10 +588,11 @@ func (dump *Dump) EctractAndApplyUpdateSubnet6(record *Content, pack *PackedCont
 		}
 	}
 
-	for _, subnet6 := range pack.Subnet6 {
+	// over a copy, RemoveSubnet6 shifts pack.Subnet6 in place.
+	for _, subnet6 := range append([]Subnet6(nil), pack.Subnet6...) {
 		if _, ok := subnetExisted[subnet6.Subnet6]; !ok {
 			pack.RemoveSubnet6(subnet6)
-			dump.RemoveFromSubnet4(subnet6.Subnet6, pack.ID)
+			dump.RemoveFromIndexSubnet6(subnet6.Subnet6, pack.ID)
 		}
 	}
 }
@@ -676,7 +642,8 @@ func (dump *Dump) EctractAndApplyUpdateDomain(record *Content, pack *PackedConte
 		}
 	}
 
-	for _, domain := range pack.Domain {
+	// over a copy, RemoveDomain shifts pack.Domain in place.
+	for _, domain := range append([]Domain(nil), pack.Domain...) {
 		if _, ok := domainExisted[domain.Domain]; !ok {
 			pack.RemoveDomain(domain)
 
@@ -708,33 +675,48 @@ func (pack *PackedContent) RemoveDomain(domain Domain) {
 }
 
 func (dump *Dump) ExtractAndApplyURL(record *Content, pack *PackedContent) {
+	var urls URLClass
+
+	classifier := urlClassifier()
+
 	if len(record.URL) > 0 {
 		pack.URL = record.URL
 		for _, u := range pack.URL {
 			nURL := NormalizeURL(u.URL)
-			if strings.HasPrefix(nURL, "https://") {
-				record.HTTPSBlock++
-			}
+			classifier.Add(&urls, nURL)
 
 			dump.InsertToIndexURL(nURL, pack.ID)
 		}
 	}
 
-	pack.BlockType = record.constructBlockType()
+	pack.applyURLClass(record, urls)
+}
+
+// applyURLClass - scheme counters and the block type of the record URLs.
+func (pack *PackedContent) applyURLClass(record *Content, urls URLClass) {
+	pack.URLHTTP, pack.URLHTTPS = urls.HTTP, urls.HTTPS
+	record.HTTPSBlock = int(urls.HTTPS)
+	pack.BlockType = constructBlockType(record, urls)
+}
+
+// SNIOnly - is the record a URL block of https URLs only? The path is encrypted,
+// so it can be enforced by the server name only.
+func (pack *PackedContent) SNIOnly() bool {
+	return pack.BlockType == BlockTypeHTTPS && pack.URLHTTP == 0
 }
 
 func (dump *Dump) EctractAndApplyUpdateURL(record *Content, pack *PackedContent) {
+	var urls URLClass
+
 	urlExisted := NewStringSet(len(pack.URL))
-	HTTPSBlock := 0
+	classifier := urlClassifier()
 
 	if len(record.URL) > 0 {
 		for _, u := range record.URL {
 			pack.InsertURL(u)
 
 			nURL := NormalizeURL(u.URL)
-			if strings.HasPrefix(nURL, "https://") {
-				HTTPSBlock++
-			}
+			classifier.Add(&urls, nURL)
 
 			dump.InsertToIndexURL(nURL, pack.ID)
 
@@ -742,10 +724,10 @@ func (dump *Dump) EctractAndApplyUpdateURL(record *Content, pack *PackedContent)
 		}
 	}
 
-	record.HTTPSBlock = HTTPSBlock
-	pack.BlockType = record.constructBlockType()
+	pack.applyURLClass(record, urls)
 
-	for _, u := range pack.URL {
+	// over a copy, RemoveURL shifts pack.URL in place.
+	for _, u := range append([]URL(nil), pack.URL...) {
 		if _, ok := urlExisted[u.URL]; !ok {
 			pack.RemoveURL(u)
 
@@ -777,57 +759,22 @@ func (pack *PackedContent) RemoveURL(u URL) {
 }
 
 func (pack *PackedContent) refreshPackedContent(hash uint64, utime int64, payload []byte) {
-	pack.RecordHash, pack.RegistryUpdateTime, pack.Payload = hash, utime, payload
+	pack.RecordHash, pack.RegistryUpdateTime = hash, utime
+	pack.setPayload(payload)
 }
 
 func newPackedContent(id int32, hash uint64, utime int64, payload []byte) *PackedContent {
-	return &PackedContent{
+	pack := &PackedContent{
 		ID:                 id,
 		RecordHash:         hash,
 		RegistryUpdateTime: utime,
-		Payload:            payload,
-	}
-}
-
-func (v *PackedContent) newPbContent(ip4 uint32, ip6 []byte, domain, url, aggr string) *pb.Content {
-	v0 := pb.Content{}
-	v0.BlockType = v.BlockType
-	v0.RegistryUpdateTime = v.RegistryUpdateTime
-	v0.Id = v.ID
-	v0.Ip4 = ip4
-	v0.Ip6 = ip6
-	v0.Domain = domain
-	v0.Url = url
-	v0.Aggr = aggr
-	v0.Pack = v.Payload
-	return &v0
-}
-
-func getContentId(_e xml.StartElement) int32 {
-	var (
-		id  int
-		err error
-	)
-	for _, _a := range _e.Attr {
-		if _a.Name.Local == "id" {
-			id, err = strconv.Atoi(_a.Value)
-			if err != nil {
-				logger.Debug.Printf("Can't fetch id: %s: %s\n", _a.Value, err.Error())
-			}
-		}
 	}
-	return int32(id)
+
+	pack.setPayload(payload)
+
+	return pack
 }
 
-func parseRegister(element xml.StartElement, r *Reg) {
-	for _, attr := range element.Attr {
-		switch attr.Name.Local {
-		case "formatVersion":
-			r.FormatVersion = attr.Value
-		case "updateTime":
-			r.UpdateTime = parseRFC3339Time(attr.Value)
-		case "updateTimeUrgently":
-			r.UpdateTimeUrgently = attr.Value
-		}
-	}
+func (v *PackedContent) newPbContent(m Match) *pb.Content {
+	return v.newMaskedPbContent(maskAll, m)
 }
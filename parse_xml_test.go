@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
 )
 
 const (
@@ -164,3 +166,279 @@ func Test_Parse(t *testing.T) {
 	}
 	fmt.Println()
 }
+
+const xml03 string = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" xmlns:tns="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" updateTimeUrgently="2010-02-02T02:02:01+03:00" formatVersion="2.4">
+<content id="111" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="XXXX">
+        <decision date="2000-01-01" number="1/1/11-1111" org="ONE"/>
+        <ip>192.168.1.11</ip>
+</content>
+<content id="111" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="XXXY">
+        <decision date="2000-01-01" number="1/1/11-1111" org="ONE"/>
+        <ip>192.168.1.12</ip>
+</content>
+</reg:register>`
+
+func Test_ParseDuplicates(t *testing.T) {
+	defer func(dump *Dump, policy string) {
+		CurrentDump, ParseConfig.DuplicatePolicy = dump, policy
+	}(CurrentDump, ParseConfig.DuplicatePolicy)
+
+	for policy, ips := range map[string][]uint32{
+		DuplicateKeepLast:  {0xC0A8010C},
+		DuplicateKeepFirst: {0xC0A8010B},
+		DuplicateMerge:     {0xC0A8010B, 0xC0A8010C},
+	} {
+		ParseConfig.DuplicatePolicy = policy
+		CurrentDump = NewDump()
+
+		err := Parse(strings.NewReader(xml03))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if Stats.DuplicateCount != 1 || len(CurrentDump.ContentIdx) != 1 {
+			t.Errorf("%s: stat error: %v", policy, Stats)
+		}
+
+		if len(CurrentDump.ip4Idx) != len(ips) {
+			t.Errorf("%s: index error: %v", policy, CurrentDump.ip4Idx)
+		}
+
+		for _, ip := range ips {
+			if _, ok := CurrentDump.ip4Idx[parser.Addr4(ip)]; !ok {
+				t.Errorf("%s: index error: %v", policy, CurrentDump.ip4Idx)
+			}
+		}
+	}
+}
+
+// TestParseTruncated tests that a cut dump is not applied.
+func TestParseTruncated(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	generation, count := CurrentDump.generation, len(CurrentDump.ContentIdx)
+
+	for name, dump := range map[string]string{
+		"no close": strings.TrimSuffix(xml02, "</reg:register>"),
+		"middle":   xml02[:len(xml02)/2],
+	} {
+		if err := CheckDumpTail(strings.NewReader(dump)); !errors.Is(err, parser.ErrTruncatedDump) {
+			t.Errorf("%s: check: %v", name, err)
+		}
+
+		if err := Parse(strings.NewReader(dump)); !errors.Is(err, parser.ErrTruncatedDump) {
+			t.Errorf("%s: parse: %v", name, err)
+		}
+
+		if CurrentDump.generation != generation || len(CurrentDump.ContentIdx) < count {
+			t.Errorf("%s: cut dump is applied: generation %d records %d", name, CurrentDump.generation, len(CurrentDump.ContentIdx))
+		}
+	}
+
+	if err := CheckDumpTail(strings.NewReader(xml01 + "\n")); err != nil {
+		t.Errorf("check: %v", err)
+	}
+}
+
+// TestParseCorrupt tests that a failed parse leaves the index as it was.
+func TestParseCorrupt(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := make(map[int32]string, len(CurrentDump.ContentIdx))
+	for id, pack := range CurrentDump.ContentIdx {
+		payloads[id] = string(pack.PayloadBytes())
+	}
+
+	ip4, domains := len(CurrentDump.ip4Idx), len(CurrentDump.domainIdx)
+
+	// the last record is broken, all the previous ones are staged already.
+	i := strings.LastIndex(xml02, "</content>")
+	corrupt := xml02[:i] + "</contents>" + xml02[i+len("</content>"):]
+
+	if err := Parse(strings.NewReader(corrupt)); err == nil {
+		t.Fatal("corrupt dump is parsed")
+	}
+
+	if len(CurrentDump.ContentIdx) != len(payloads) || len(CurrentDump.ip4Idx) != ip4 || len(CurrentDump.domainIdx) != domains {
+		t.Errorf("index is changed: records %d ip4 %d domains %d", len(CurrentDump.ContentIdx), len(CurrentDump.ip4Idx), len(CurrentDump.domainIdx))
+	}
+
+	for id, payload := range payloads {
+		if pack, ok := CurrentDump.ContentIdx[id]; !ok || string(pack.PayloadBytes()) != payload {
+			t.Errorf("record %d is changed", id)
+		}
+	}
+
+	if report := CurrentDump.VerifyIndexes(); len(report.Problems) != 0 {
+		t.Errorf("index problems: %v", report.Problems)
+	}
+}
+
+// TestParseCopyOnWrite tests that changed records are replaced, not mutated,
+// and that unchanged ones keep the update time of their last change.
+func TestParseCopyOnWrite(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	first := CurrentDump.utime
+	old111, old222 := CurrentDump.ContentIdx[111], CurrentDump.ContentIdx[222]
+	ip4 := append([]IP4(nil), old111.IP4...)
+
+	next := strings.Replace(xml01, `updateTime="2011-01-01T01:01:01+03:00"`, `updateTime="2012-01-01T01:01:01+03:00"`, 1)
+	next = strings.Replace(next, `hash="XXXX"`, `hash="XXXZ"`, 1)
+	next = strings.Replace(next, "<ip>10.1.1.1</ip>", "<ip>10.1.1.2</ip>", 1)
+
+	if err := Parse(strings.NewReader(next)); err != nil {
+		t.Fatal(err)
+	}
+
+	if CurrentDump.utime == first {
+		t.Fatal("dump time is not changed")
+	}
+
+	if CurrentDump.ContentIdx[111] == old111 || CurrentDump.ContentIdx[111].RegistryUpdateTime != CurrentDump.utime {
+		t.Errorf("changed record: %+v", CurrentDump.ContentIdx[111])
+	}
+
+	if old111.RegistryUpdateTime != first || len(old111.IP4) != len(ip4) {
+		t.Errorf("old record is mutated: %+v", old111)
+	}
+
+	for i := range ip4 {
+		if old111.IP4[i] != ip4[i] {
+			t.Errorf("old record is mutated: %v", old111.IP4)
+		}
+	}
+
+	if CurrentDump.ContentIdx[222] != old222 || old222.RegistryUpdateTime != first {
+		t.Errorf("unchanged record: %+v", CurrentDump.ContentIdx[222])
+	}
+
+	// clients see the time of the served dump and the time of the last change.
+	CurrentDump.RLock()
+	v := old222.newPbContent(Match{})
+	CurrentDump.RUnlock()
+
+	if v.GetRegistryUpdateTime() != CurrentDump.utime || v.GetChangeTime() != first {
+		t.Errorf("update time: %d, change time: %d", v.GetRegistryUpdateTime(), v.GetChangeTime())
+	}
+
+	// a restored snapshot keeps the change times.
+	snap := CurrentDump.snapshot()
+	CurrentDump = NewDump()
+
+	if err := CurrentDump.RestoreSnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if CurrentDump.ContentIdx[222].RegistryUpdateTime != first || CurrentDump.ContentIdx[111].RegistryUpdateTime != CurrentDump.utime {
+		t.Errorf("restored change times: %d %d", CurrentDump.ContentIdx[222].RegistryUpdateTime, CurrentDump.ContentIdx[111].RegistryUpdateTime)
+	}
+}
+
+// TestParseSemanticCompare tests that byte changes of the same record are not updates in the semantic mode.
+func TestParseSemanticCompare(t *testing.T) {
+	defer func(dump *Dump, mode string) { CurrentDump, ParseConfig.Compare = dump, mode }(CurrentDump, ParseConfig.Compare)
+
+	CurrentDump = NewDump()
+	ParseConfig.Compare = CompareSemantic
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	old111, old222 := CurrentDump.ContentIdx[111], CurrentDump.ContentIdx[222]
+
+	// attributes, whitespace, entities and selector order of 111, a real change of 222.
+	next := strings.Replace(xml01, `updateTime="2011-01-01T01:01:01+03:00"`, `updateTime="2012-01-01T01:01:01+03:00"`, 1)
+	next = strings.Replace(next, `<decision date="2000-01-01" number="1/1/11-1111" org="ONE"/>`, `<decision  org="&#79;NE" number="1/1/11-1111"   date="2000-01-01" />`, 1)
+	next = strings.Replace(next, "<ip>192.168.1.11</ip>\n        <ip>192.168.0.100</ip>", "<ip>192.168.0.100</ip>\n<ip>192.168.1.11</ip>", 1)
+	next = strings.Replace(next, `number="2/2/22-2222"`, `number="2/2/22-2223"`, 1)
+
+	if err := Parse(strings.NewReader(next)); err != nil {
+		t.Fatal(err)
+	}
+
+	if Stats.UpdateCount != 1 || Stats.SameCount != 1 {
+		t.Errorf("Updated: %d Same: %d\n", Stats.UpdateCount, Stats.SameCount)
+	}
+
+	new111 := CurrentDump.ContentIdx[111]
+	if new111.RecordHash == old111.RecordHash || new111.RegistryUpdateTime != old111.RegistryUpdateTime {
+		t.Errorf("Same record: %+v\n", new111)
+	}
+
+	if CurrentDump.ContentIdx[222].RegistryUpdateTime == old222.RegistryUpdateTime {
+		t.Errorf("Changed record: %+v\n", CurrentDump.ContentIdx[222])
+	}
+
+	// the same bytes again are not even compared.
+	if err := Parse(strings.NewReader(next)); err != nil {
+		t.Fatal(err)
+	}
+
+	if Stats.UpdateCount != 0 || Stats.SameCount != 0 {
+		t.Errorf("Updated: %d Same: %d\n", Stats.UpdateCount, Stats.SameCount)
+	}
+
+	ParseConfig.Compare = CompareHash
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if Stats.UpdateCount != 2 || Stats.SameCount != 0 {
+		t.Errorf("Hash mode updated: %d Same: %d\n", Stats.UpdateCount, Stats.SameCount)
+	}
+}
+
+// TestParseURLSchemes tests URL scheme counts and the SNI only flag of records.
+func TestParseURLSchemes(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	v := CurrentDump.ContentIdx[111].newPbContent(Match{})
+	if v.HttpUrls != 2 || v.HttpsUrls != 1 || v.SniOnly {
+		t.Errorf("mixed: %d %d %v", v.HttpUrls, v.HttpsUrls, v.SniOnly)
+	}
+
+	if v := CurrentDump.ContentIdx[222].newPbContent(Match{}); v.HttpUrls != 0 || v.HttpsUrls != 0 || v.SniOnly {
+		t.Errorf("domain: %d %d %v", v.HttpUrls, v.HttpsUrls, v.SniOnly)
+	}
+
+	next := strings.Replace(xml01, `hash="XXXX"`, `hash="XXXZ"`, 1)
+	next = strings.Replace(next, "<url><![CDATA[http://www.e01.tld/cheese]]></url>", "", 1)
+	next = strings.Replace(next, "<url><![CDATA[http://www.e01.tld/slip]]></url>", "", 1)
+
+	if err := Parse(strings.NewReader(next)); err != nil {
+		t.Fatal(err)
+	}
+
+	v = CurrentDump.ContentIdx[111].newPbContent(Match{})
+	if v.HttpUrls != 0 || v.HttpsUrls != 1 || !v.SniOnly {
+		t.Errorf("https only: %d %d %v", v.HttpUrls, v.HttpsUrls, v.SniOnly)
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"net"
+	"net/netip"
+)
+
+// Addr4 - IPv4 of its uint32 form, as it is in protobuf.
+func Addr4(ip uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)})
+}
+
+// Addr4Uint32 - uint32 form of the IPv4, 0 if it is not one.
+func Addr4Uint32(ip netip.Addr) uint32 {
+	if !ip.Is4() {
+		return 0
+	}
+
+	b := ip.As4()
+
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// Addr6 - IPv6 of its 16 bytes, false for other lengths.
+func Addr6(ip []byte) (netip.Addr, bool) {
+	if len(ip) != net.IPv6len {
+		return netip.Addr{}, false
+	}
+
+	return netip.AddrFrom16([16]byte(ip)), true
+}
+
+// ParseIP4 - address of <ip>. Octets with leading zeros are accepted as the registry has them.
+func ParseIP4(s string) (netip.Addr, bool) {
+	if ip, err := netip.ParseAddr(s); err == nil {
+		return ip.Unmap(), ip.Unmap().Is4()
+	}
+
+	if ip := IPv4StrToInt(s); ip != 0xFFFFFFFF {
+		return Addr4(ip), true
+	}
+
+	return netip.Addr{}, false
+}
+
+// ParseIP6 - address of <ipv6> in the 16 bytes form, IPv4 is mapped.
+func ParseIP6(s string) (netip.Addr, bool) {
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return netip.AddrFrom16(ip.As16()), true
+}
+
+// ParseSubnet4 - network of <ipSubnet>, the address is kept as is, not masked.
+func ParseSubnet4(s string) (netip.Prefix, bool) {
+	subnet, err := netip.ParsePrefix(s)
+	if err != nil || !subnet.Addr().Is4() {
+		return netip.Prefix{}, false
+	}
+
+	return subnet, true
+}
+
+// ParseSubnet6 - network of <ipv6Subnet>, the address is kept as is, not masked.
+func ParseSubnet6(s string) (netip.Prefix, bool) {
+	subnet, err := netip.ParsePrefix(s)
+	if err != nil || !subnet.Addr().Is6() {
+		return netip.Prefix{}, false
+	}
+
+	return subnet, true
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// Address anomaly kinds: registry values which are no addresses or no sensible block targets.
+const (
+	AnomalyBadAddress   = "bad-address"   // neither an IP nor a subnet, always skipped.
+	AnomalyLeadingZeros = "leading-zeros" // IPv4 octet with leading zeros, read as decimal, e.g. 010.1.1.1, never skipped.
+	AnomalyHostBits     = "host-bits"     // subnet address with bits beyond the prefix, e.g. 10.0.0.1/8.
+	AnomalyTooWide      = "too-wide"      // IPv4 subnet wider than /8, IPv6 wider than /16, e.g. 0.0.0.0/0.
+	AnomalySpecial      = "special"       // unspecified, loopback, multicast, link-local or broadcast address.
+	AnomalyPrivate      = "private"       // private use address or subnet, e.g. 10.1.1.1 or fd00::/64, never skipped.
+	AnomalyBogon        = "bogon"         // reserved, shared, documentation or benchmark address or subnet, never skipped.
+)
+
+// Address anomaly policies.
+const (
+	AnomalyKeep   = "keep"   // anomalies are reported and indexed as they are, empty is AnomalyKeep.
+	AnomalyReject = "reject" // anomalies are reported and skipped.
+)
+
+// Widest sensible subnets.
+const (
+	anomalyMinBits4 = 8
+	anomalyMinBits6 = 16
+)
+
+// privatePrefixes - private use ranges, RFC 1918 and RFC 4193.
+var privatePrefixes = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("fc00::/7"),
+}
+
+// bogonPrefixes - ranges which are never routed on the internet besides private and special ones.
+var bogonPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("3fff::/20"),
+	netip.MustParsePrefix("fec0::/10"),
+}
+
+// Anomaly - offending address of a record.
+type Anomaly struct {
+	ID    int32
+	Field string // element of the record: ip, ipv6, ipSubnet, ipv6Subnet.
+	Value string // as in the registry.
+	Kind  string
+}
+
+// addrAnomaly - anomaly kind of the parsed IP, empty if it is fine.
+func addrAnomaly(ip netip.Addr, value string) string {
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() || ip.IsLinkLocalUnicast() ||
+		ip == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return AnomalySpecial
+	}
+
+	if ip.Is4() && hasLeadingZeros(value) {
+		return AnomalyLeadingZeros
+	}
+
+	return AddressClass(netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+}
+
+// subnetAnomaly - anomaly kind of the parsed subnet, empty if it is fine.
+func subnetAnomaly(subnet netip.Prefix) string {
+	minBits := anomalyMinBits6
+	if subnet.Addr().Is4() {
+		minBits = anomalyMinBits4
+	}
+
+	switch {
+	case subnet.Bits() < minBits:
+		return AnomalyTooWide
+	case subnet.Masked() != subnet:
+		return AnomalyHostBits
+	case subnet.Addr().IsLoopback() || subnet.Addr().IsMulticast() || subnet.Addr().IsLinkLocalUnicast():
+		return AnomalySpecial
+	}
+
+	return AddressClass(subnet)
+}
+
+// AddressClass - AnomalyPrivate or AnomalyBogon if the IP or subnet is within such a range, empty otherwise.
+func AddressClass(p netip.Prefix) string {
+	for _, r := range privatePrefixes {
+		if p.Bits() >= r.Bits() && r.Contains(p.Addr()) {
+			return AnomalyPrivate
+		}
+	}
+
+	for _, r := range bogonPrefixes {
+		if p.Bits() >= r.Bits() && r.Contains(p.Addr()) {
+			return AnomalyBogon
+		}
+	}
+
+	return ""
+}
+
+// hasLeadingZeros - an octet of the dotted IPv4 starts with 0 and is not 0.
+func hasLeadingZeros(value string) bool {
+	for _, octet := range strings.Split(value, ".") {
+		if len(octet) > 1 && octet[0] == '0' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// anomaly - note the anomaly of the record, false if the selector is skipped.
+func (d *Decoder) anomaly(record *Content, field, value, kind string) bool {
+	record.Anomalies = append(record.Anomalies, Anomaly{ID: record.ID, Field: field, Value: value, Kind: kind})
+
+	switch kind {
+	case AnomalyBadAddress:
+		return false
+	case AnomalyLeadingZeros, AnomalyPrivate, AnomalyBogon:
+		return true
+	}
+
+	return d.config.Anomalies != AnomalyReject
+}
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// RawElementCapturer - XML tokenizer which also returns the bytes of whole elements as they are
+// in the (decoded) stream, entities unexpanded and CDATA sections kept, for record hashes.
+// Until the encoding declaration the raw input is buffered, after it the UTF-8 decoded stream,
+// offsets of the decoder are corrected by the position of the switch.
+// With KeepInput the input is buffered as well, see Input.
+type RawElementCapturer struct {
+	decoder  *xml.Decoder
+	buffer   bytes.Buffer
+	tee      *switchWriter
+	input    bytes.Buffer // the input as is, only with KeepInput.
+	inputTee *switchWriter
+	captured []byte // input bytes of the last captured element.
+
+	bufferOffset     int64 // stream offset of the buffer start.
+	offsetCorrection int64 // input offset of the decoded stream start.
+	tokenStart       int64 // stream offset of the last token.
+	inputOffset      int64 // input offset of the input buffer start.
+
+	// input offsets of a decoded stream are only known for single byte charsets, a rune is a byte.
+	decoded    bool
+	singleByte bool
+	runes      int64 // runes of the decoded stream before the buffer start.
+}
+
+// NewRawElementCapturer - capturer of the input, utf8 means the input is UTF-8 whatever is declared.
+func NewRawElementCapturer(input io.Reader, utf8 bool) *RawElementCapturer {
+	c := &RawElementCapturer{}
+
+	c.tee = &switchWriter{w: &c.buffer}
+	c.inputTee = &switchWriter{w: &c.input, off: true}
+	c.decoder = xml.NewDecoder(io.TeeReader(input, io.MultiWriter(c.tee, c.inputTee)))
+
+	// UTF-8 dumps are read as is, the decoder doesn't call CharsetReader for them.
+	c.decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		// it is UTF-8 already.
+		if utf8 {
+			return input, nil
+		}
+
+		r, err := charset.NewReaderLabel(label, input)
+		if err != nil {
+			return nil, err
+		}
+
+		enc, _ := htmlindex.Get(label)
+		_, c.singleByte = enc.(*charmap.Charmap)
+		c.decoded = true
+
+		// input offsets are unknown, nothing to keep.
+		if !c.singleByte {
+			c.inputTee.off = true
+			c.input.Reset()
+		}
+
+		// from now on the decoded stream is buffered instead of the raw one.
+		c.tee.off = true
+		c.buffer.Reset()
+
+		c.offsetCorrection = c.decoder.InputOffset()
+		c.bufferOffset = 0
+
+		return io.TeeReader(r, &c.buffer), nil
+	}
+
+	return c
+}
+
+// KeepInput - buffer the input too, call it before the first token.
+func (c *RawElementCapturer) KeepInput() {
+	c.inputTee.off = false
+}
+
+// offset - current offset in the buffered stream.
+func (c *RawElementCapturer) offset() int64 {
+	return c.decoder.InputOffset() - c.offsetCorrection
+}
+
+// InputOffset - offset in the input of the current position, ok is false if it is unknown,
+// i.e. the input is decoded from a multibyte charset.
+func (c *RawElementCapturer) InputOffset() (int64, bool) {
+	return c.inputOffsetAt(c.offset())
+}
+
+// inputOffsetAt - input offset of the stream offset, it is not discarded yet.
+func (c *RawElementCapturer) inputOffsetAt(offset int64) (int64, bool) {
+	if !c.decoded {
+		return offset + c.offsetCorrection, true
+	}
+
+	if !c.singleByte {
+		return 0, false
+	}
+
+	pending := c.buffer.Bytes()[:offset-c.bufferOffset]
+
+	return c.offsetCorrection + c.runes + int64(utf8.RuneCount(pending)), true
+}
+
+// next - drop n bytes of the buffer and return them.
+func (c *RawElementCapturer) next(n int) []byte {
+	b := c.buffer.Next(n)
+	if c.decoded {
+		c.runes += int64(utf8.RuneCount(b))
+	}
+
+	c.bufferOffset += int64(len(b))
+
+	return b
+}
+
+// discard - drop the buffer up to the offset.
+func (c *RawElementCapturer) discard(offset int64) {
+	if diff := offset - c.bufferOffset; diff > 0 {
+		c.next(int(diff))
+	}
+}
+
+// discardInput - drop the input buffer up to the input offset.
+func (c *RawElementCapturer) discardInput(offset int64) {
+	if diff := offset - c.inputOffset; diff > 0 {
+		c.input.Next(int(diff))
+		c.inputOffset += diff
+	}
+}
+
+// Token - next token, see xml.Decoder.Token.
+func (c *RawElementCapturer) Token() (xml.Token, error) {
+	// everything before the token is not needed anymore.
+	c.tokenStart = c.offset()
+	c.discard(c.tokenStart)
+
+	if offset, ok := c.InputOffset(); ok {
+		c.discardInput(offset)
+	}
+
+	return c.decoder.Token()
+}
+
+// Capture - skip the rest of the element started by the last token and return its bytes
+// from < of the start element to > of the end element. The slice is valid until the next call.
+func (c *RawElementCapturer) Capture() ([]byte, error) {
+	if err := c.decoder.Skip(); err != nil {
+		return nil, err
+	}
+
+	end := c.offset()
+
+	c.discard(c.tokenStart)
+	c.captureInput(end)
+
+	return c.next(int(end - c.bufferOffset)), nil
+}
+
+// captureInput - keep the input bytes of the element from the token start to the end,
+// the buffer is at the token start.
+func (c *RawElementCapturer) captureInput(end int64) {
+	c.captured = nil
+
+	if c.inputTee.off {
+		return
+	}
+
+	start, ok := c.inputOffsetAt(c.tokenStart)
+	if !ok {
+		return
+	}
+
+	inputEnd, _ := c.inputOffsetAt(end)
+
+	c.discardInput(start)
+	c.captured = c.input.Next(int(inputEnd - start))
+	c.inputOffset = inputEnd
+}
+
+// Input - the last captured element as it is in the input, before any charset conversion,
+// nil without KeepInput or if input offsets are unknown. The slice is valid until the next call.
+func (c *RawElementCapturer) Input() []byte {
+	return c.captured
+}
+
+// switchWriter - writer which can be turned off.
+type switchWriter struct {
+	w   io.Writer
+	off bool
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	if s.off {
+		return len(p), nil
+	}
+
+	return s.w.Write(p)
+}
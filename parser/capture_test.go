@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// captureElements - raw bytes of all elements with the name.
+func captureElements(t *testing.T, input io.Reader, utf8 bool, name string) []string {
+	t.Helper()
+
+	var elements []string
+
+	c := NewRawElementCapturer(input, utf8)
+
+	for {
+		token, err := c.Token()
+		if token == nil {
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+
+			return elements
+		}
+
+		if element, ok := token.(xml.StartElement); ok && element.Name.Local == name {
+			raw, err := c.Capture()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			elements = append(elements, string(raw))
+		}
+	}
+}
+
+// TestRawElementCapturer tests raw element bytes with multi-byte charsets, entities and CDATA.
+func TestRawElementCapturer(t *testing.T) {
+	const (
+		first  = `<content id="1"><decision org="ИФНС «Москва» &amp; Ко"/><url><![CDATA[http://e1.tld/?a=1&b=<2>]]></url></content>`
+		second = `<content id="2"><domain>пример.рф</domain><url>http://e2.tld/?q=&lt;&#1103;&gt;</url></content>`
+		body   = "\n<reg:register xmlns:reg=\"http://rsoc.ru\">\n" + first + "\n<!-- комментарий -->\n" + second + "</reg:register>\n"
+	)
+
+	cp1251 := func(s string) string {
+		encoded, err := charmap.Windows1251.NewEncoder().String(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return encoded
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		utf8  bool
+	}{
+		{"utf-8", `<?xml version="1.0" encoding="UTF-8"?>` + body, false},
+		{"undeclared", body, false},
+		{"windows-1251", `<?xml version="1.0" encoding="windows-1251"?>` + cp1251(body), false},
+		{"windows-1251 long declaration", `<?xml version="1.0"   encoding="windows-1251"   standalone="yes" ?>` + cp1251(body), false},
+		{"decoded before", `<?xml version="1.0" encoding="windows-1251"?>` + body, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			elements := captureElements(t, strings.NewReader(tc.input), tc.utf8, "content")
+			if len(elements) != 2 || elements[0] != first || elements[1] != second {
+				t.Errorf("Captured: %q\n", elements)
+			}
+		})
+	}
+}
+
+// TestRawElementCapturerSmallReads tests capture when the input comes byte by byte.
+func TestRawElementCapturerSmallReads(t *testing.T) {
+	const element = `<content id="1"><org>Роскомнадзор</org></content>`
+
+	encoded, err := charmap.Windows1251.NewEncoder().String(`<?xml version="1.0" encoding="windows-1251"?><register>` + element + `</register>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	elements := captureElements(t, iotest.OneByteReader(strings.NewReader(encoded)), false, "content")
+	if len(elements) != 1 || elements[0] != element {
+		t.Errorf("Captured: %q\n", elements)
+	}
+}
+
+// TestRawElementCapturerTruncated tests capture of a cut element.
+func TestRawElementCapturerTruncated(t *testing.T) {
+	c := NewRawElementCapturer(strings.NewReader(`<register><content id="1"><url>http://e1.tld/`), false)
+
+	for {
+		token, err := c.Token()
+		if token == nil {
+			t.Fatalf("No content: %v\n", err)
+		}
+
+		if element, ok := token.(xml.StartElement); ok && element.Name.Local == "content" {
+			break
+		}
+	}
+
+	if _, err := c.Capture(); !isUnexpectedEOF(err) {
+		t.Errorf("Capture error: %v\n", err)
+	}
+}
+
+// TestRawElementCapturerInput tests input bytes of elements before the charset conversion.
+func TestRawElementCapturerInput(t *testing.T) {
+	const element = `<content id="1"><org>Роскомнадзор</org></content>`
+
+	encoded, err := charmap.Windows1251.NewEncoder().String(element)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{"utf-8", `<?xml version="1.0" encoding="UTF-8"?><register>` + element + `</register>`, element},
+		{"windows-1251", `<?xml version="1.0" encoding="windows-1251"?><register>` + encoded + `</register>`, encoded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewRawElementCapturer(iotest.HalfReader(strings.NewReader(tc.input)), false)
+			c.KeepInput()
+
+			for {
+				token, err := c.Token()
+				if token == nil {
+					t.Fatalf("No content: %v\n", err)
+				}
+
+				if element, ok := token.(xml.StartElement); ok && element.Name.Local == "content" {
+					break
+				}
+			}
+
+			raw, err := c.Capture()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(raw) != element || string(c.Input()) != tc.expect {
+				t.Errorf("Captured: %q, input: %q\n", raw, c.Input())
+			}
+		})
+	}
+}
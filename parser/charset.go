@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// Charset modes of the parser.
+const (
+	CharsetDeclared = "declared" // decode as declared, count replacement characters.
+	CharsetLenient  = "lenient"  // valid UTF-8 where it is plausible, cp1251 otherwise, whatever is declared.
+	CharsetStrict   = "strict"   // decode as declared, a replacement character fails the parse.
+)
+
+// replacementChar - U+FFFD in UTF-8, left by decoders for undecodable bytes.
+var replacementChar = []byte(string(utf8.RuneError))
+
+// lenientDecoder - transformer to UTF-8 for dumps with a mismatched encoding declaration.
+// Every non-ASCII sequence is kept if it is valid UTF-8 of a rune the registry uses
+// (Cyrillic, typographic punctuation), otherwise its first byte is decoded as cp1251.
+type lenientDecoder struct {
+	fallbacks int64 // bytes decoded as cp1251.
+}
+
+// plausibleRune - rune of Russian registry texts, cp1251 byte pairs rarely decode to them as UTF-8.
+func plausibleRune(r rune) bool {
+	switch {
+	case r >= 0x0400 && r <= 0x04FF: // Cyrillic.
+		return true
+	case r >= 0x00A0 && r <= 0x00BF: // NBSP, «», §, ©, °.
+		return true
+	case r >= 0x2010 && r <= 0x203A: // dashes, quotes, ellipsis.
+		return true
+	case r == 0x2116 || r == 0x20AC: // №, €.
+		return true
+	}
+
+	return false
+}
+
+func (d *lenientDecoder) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	var nDst, nSrc int
+
+	for nSrc < len(src) {
+		b := src[nSrc]
+
+		if b < utf8.RuneSelf {
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+
+			dst[nDst] = b
+			nDst++
+			nSrc++
+
+			continue
+		}
+
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError || !plausibleRune(r) {
+			r, size = charmap.Windows1251.DecodeByte(b), 1
+			d.fallbacks++
+		}
+
+		if nDst+utf8.RuneLen(r) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+
+		nDst += utf8.EncodeRune(dst[nDst:], r)
+		nSrc += size
+	}
+
+	return nDst, nSrc, nil
+}
+
+func (d *lenientDecoder) Reset() {}
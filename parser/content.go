@@ -0,0 +1,241 @@
+package parser
+
+import (
+	"encoding/json"
+	"net/netip"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Content - store for <content> with hash.
+type Content struct {
+	ID          int32     `json:"id"`
+	EntryType   int32     `json:"et"`
+	UrgencyType int32     `json:"ut,omitempty"`
+	Decision    Decision  `json:"d"`
+	IncludeTime int64     `json:"it"`
+	Ts          int64     `json:"ts,omitempty"`
+	BlockType   string    `json:"bt,omitempty"`
+	Hash        string    `json:"h"`
+	URL         []URL     `json:"url,omitempty"`
+	IP4         []IP4     `json:"ip4,omitempty"`
+	IP6         []IP6     `json:"ip6,omitempty"`
+	Subnet4     []Subnet4 `json:"sb4,omitempty"`
+	Subnet6     []Subnet6 `json:"sb6,omitempty"`
+	Domain      []Domain  `json:"dm,omitempty"`
+	HTTPSBlock  int       `json:"hb"`
+	RecordHash  uint64    `json:"u2h"`
+
+	DecisionHash uint64    `json:"-"` // set by Decoder.Decode, see Hasher.
+	Anomalies    []Anomaly `json:"-"` // address anomalies found by Decoder.Decode.
+}
+
+// Subnet6 - store for <ipv6Subnet>.
+type Subnet6 struct {
+	Subnet6 netip.Prefix `json:"sb6"`
+	Ts      int64        `json:"ts,omitempty"`
+}
+
+// Subnet4 - store for <ipSubnet>.
+type Subnet4 struct {
+	Subnet4 netip.Prefix `json:"sb4"`
+	Ts      int64        `json:"ts,omitempty"`
+}
+
+// Domain - store for <domain>.
+type Domain struct {
+	Domain string `json:"dm"`
+	Ts     int64  `json:"ts,omitempty"`
+}
+
+// URL - store for <url>.
+type URL struct {
+	URL string `json:"u"`
+	Ts  int64  `json:"ts,omitempty"`
+}
+
+// IP4 - store for <ip>.
+type IP4 struct {
+	IP4 netip.Addr
+	Ts  int64
+}
+
+// IP6 - store for <ip6>, the address is in the 16 bytes form.
+type IP6 struct {
+	IP6 netip.Addr
+	Ts  int64
+}
+
+// ip4JSON - payload form of IP4, clients decode the address as uint32.
+type ip4JSON struct {
+	IP4 uint32 `json:"ip4"`
+	Ts  int64  `json:"ts,omitempty"`
+}
+
+// ip6JSON - payload form of IP6, clients decode the address as 16 bytes.
+type ip6JSON struct {
+	IP6 []byte `json:"ip6"`
+	Ts  int64  `json:"ts,omitempty"`
+}
+
+// MarshalJSON - IP4 in the payload form.
+func (ip IP4) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ip4JSON{IP4: Addr4Uint32(ip.IP4), Ts: ip.Ts})
+}
+
+// UnmarshalJSON - IP4 of the payload form.
+func (ip *IP4) UnmarshalJSON(b []byte) error {
+	v := ip4JSON{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	ip.IP4, ip.Ts = Addr4(v.IP4), v.Ts
+
+	return nil
+}
+
+// MarshalJSON - IP6 in the payload form.
+func (ip IP6) MarshalJSON() ([]byte, error) {
+	v := ip6JSON{Ts: ip.Ts}
+	if ip.IP6.IsValid() {
+		b := ip.IP6.As16()
+		v.IP6 = b[:]
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON - IP6 of the payload form.
+func (ip *IP6) UnmarshalJSON(b []byte) error {
+	v := ip6JSON{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	ip.IP6, _ = Addr6(v.IP6)
+	ip.Ts = v.Ts
+
+	return nil
+}
+
+// Decision - <decision> and store for <decision>
+type Decision struct {
+	Date   string `xml:"date,attr" json:"dd"`
+	Number string `xml:"number,attr" json:"dn"`
+	Org    string `xml:"org,attr" json:"do"`
+}
+
+// Marshal - encodes content to JSON.
+func (record *Content) Marshal() []byte {
+	b, err := json.Marshal(record)
+	if err != nil {
+		logger.Error.Printf("Error encoding: %s\n", err.Error())
+	}
+	return b
+}
+
+// Union - the record with selectors of the duplicate appended.
+func (record *Content) Union(dup *Content) *Content {
+	record.RecordHash = dup.RecordHash
+	record.HTTPSBlock = 0
+	record.Anomalies = append(record.Anomalies, dup.Anomalies...)
+
+	for _, u := range dup.URL {
+		if !containsURL(record.URL, u) {
+			record.URL = append(record.URL, u)
+		}
+	}
+
+	for _, domain := range dup.Domain {
+		if !containsDomain(record.Domain, domain) {
+			record.Domain = append(record.Domain, domain)
+		}
+	}
+
+	for _, ip4 := range dup.IP4 {
+		if !containsIP4(record.IP4, ip4) {
+			record.IP4 = append(record.IP4, ip4)
+		}
+	}
+
+	for _, ip6 := range dup.IP6 {
+		if !containsIP6(record.IP6, ip6) {
+			record.IP6 = append(record.IP6, ip6)
+		}
+	}
+
+	for _, subnet4 := range dup.Subnet4 {
+		if !containsSubnet4(record.Subnet4, subnet4) {
+			record.Subnet4 = append(record.Subnet4, subnet4)
+		}
+	}
+
+	for _, subnet6 := range dup.Subnet6 {
+		if !containsSubnet6(record.Subnet6, subnet6) {
+			record.Subnet6 = append(record.Subnet6, subnet6)
+		}
+	}
+
+	return record
+}
+
+func containsURL(a []URL, u URL) bool {
+	for _, v := range a {
+		if v == u {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsDomain(a []Domain, domain Domain) bool {
+	for _, v := range a {
+		if v == domain {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsIP4(a []IP4, ip4 IP4) bool {
+	for _, v := range a {
+		if v == ip4 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsIP6(a []IP6, ip6 IP6) bool {
+	for _, v := range a {
+		if v == ip6 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsSubnet4(a []Subnet4, subnet4 Subnet4) bool {
+	for _, v := range a {
+		if v == subnet4 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsSubnet6(a []Subnet6, subnet6 Subnet6) bool {
+	for _, v := range a {
+		if v == subnet6 {
+			return true
+		}
+	}
+
+	return false
+}
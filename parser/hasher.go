@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Hash functions.
+const (
+	HashFNV    = "fnv"    // FNV-1a 64, the default.
+	HashXXHash = "xxhash" // XXH64, faster on big records.
+)
+
+// Hasher - record and decision hashing. It is not safe for concurrent use,
+// make one per goroutine with NewHasher.
+type Hasher interface {
+	// Record - hash of the original <content>...</content>.
+	Record(buf []byte) uint64
+	// Decision - hash of the normalized decision, it is the SearchDecision key.
+	Decision(decision *Decision) uint64
+}
+
+// NewHasher - Hasher constructor. The same name and seed always give the same hashes,
+// so snapshots of different runs are comparable. Seed 0 keeps unseeded hashes.
+func NewHasher(name string, seed uint64) (Hasher, error) {
+	switch name {
+	case HashFNV, "":
+		return &hash64Hasher{h: fnv.New64a(), seed: seed}, nil
+	case HashXXHash:
+		return &xxHasher{d: xxhash.NewWithSeed(seed), seed: seed}, nil
+	}
+
+	return nil, fmt.Errorf("unknown hash: %q", name)
+}
+
+// hash64Hasher - any hash.Hash64, the seed is written before the data.
+type hash64Hasher struct {
+	h    hash.Hash64
+	seed uint64
+}
+
+func (x *hash64Hasher) reset() {
+	x.h.Reset()
+
+	if x.seed != 0 {
+		var b [8]byte
+
+		binary.LittleEndian.PutUint64(b[:], x.seed)
+		x.h.Write(b[:])
+	}
+}
+
+func (x *hash64Hasher) Record(buf []byte) uint64 {
+	x.reset()
+	x.h.Write(buf)
+
+	return x.h.Sum64()
+}
+
+func (x *hash64Hasher) Decision(decision *Decision) uint64 {
+	x.reset()
+	writeDecision(x.h, decision)
+
+	return x.h.Sum64()
+}
+
+// xxHasher - xxhash with native seeding.
+type xxHasher struct {
+	d    *xxhash.Digest
+	seed uint64
+}
+
+func (x *xxHasher) Record(buf []byte) uint64 {
+	x.d.ResetWithSeed(x.seed)
+	x.d.Write(buf)
+
+	return x.d.Sum64()
+}
+
+func (x *xxHasher) Decision(decision *Decision) uint64 {
+	x.d.ResetWithSeed(x.seed)
+	writeDecision(x.d, decision)
+
+	return x.d.Sum64()
+}
+
+// writeDecision - "org number date" with the canonical organization.
+func writeDecision(h hash.Hash64, decision *Decision) {
+	h.Write([]byte(NormalizeOrg(decision.Org)))
+	h.Write([]byte(" "))
+	h.Write([]byte(decision.Number))
+	h.Write([]byte(" "))
+	h.Write([]byte(decision.Date))
+}
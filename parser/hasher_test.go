@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"hash/fnv"
+	"testing"
+)
+
+// TestHasher tests hash compatibility, seeding and reproducibility of NewHasher.
+func TestHasher(t *testing.T) {
+	buf := []byte("<content id=\"1\"></content>")
+	decision := &Decision{Org: "ркн", Number: "1-2-3", Date: "2020-01-01"}
+
+	h := fnv.New64a()
+	h.Write(buf)
+
+	plain, err := NewHasher(HashFNV, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := plain.Record(buf); got != h.Sum64() {
+		t.Errorf("unseeded fnv must be plain FNV-1a: expected %d, got %d", h.Sum64(), got)
+	}
+
+	for _, name := range []string{HashFNV, HashXXHash} {
+		a, _ := NewHasher(name, 42)
+		b, _ := NewHasher(name, 42)
+		c, _ := NewHasher(name, 43)
+
+		if a.Record(buf) != b.Record(buf) || a.Decision(decision) != b.Decision(decision) {
+			t.Errorf("%s: the same seed must give the same hashes", name)
+		}
+
+		if a.Record(buf) == c.Record(buf) {
+			t.Errorf("%s: different seeds must give different hashes", name)
+		}
+
+		if a.Decision(decision) != a.Decision(&Decision{Org: "Роскомнадзор", Number: "1-2-3", Date: "2020-01-01"}) {
+			t.Errorf("%s: decision hash must use the canonical organization", name)
+		}
+	}
+
+	if _, err := NewHasher("md5", 0); err == nil {
+		t.Errorf("expected error for unknown hash")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package parser
 
 // IPv4StrToInt converts a string containing an IPv4 address to its uint32 representation.
 // The input string should be in the format "xxx.xxx.xxx.xxx" where xxx is a number between 0 and 255.
@@ -0,0 +1,29 @@
+package parser
+
+import "strings"
+
+// OrgAliases maps upper case spelling variants of decision organizations
+// to their canonical names. It can be extended, as u2ckdump does with -org-aliases.
+var OrgAliases = map[string]string{
+	"ГЕНПРОКУРАТУРА РФ":                            "Генпрокуратура",
+	"ГЕНЕРАЛЬНАЯ ПРОКУРАТУРА":                      "Генпрокуратура",
+	"ГЕНЕРАЛЬНАЯ ПРОКУРАТУРА РФ":                   "Генпрокуратура",
+	"ГЕНЕРАЛЬНАЯ ПРОКУРАТУРА РОССИЙСКОЙ ФЕДЕРАЦИИ": "Генпрокуратура",
+	"РКН": "Роскомнадзор",
+	"ФЕДЕРАЛЬНАЯ НАЛОГОВАЯ СЛУЖБА":                     "ФНС",
+	"МИНИСТЕРСТВО ВНУТРЕННИХ ДЕЛ":                      "МВД",
+	"МИНИСТЕРСТВО ВНУТРЕННИХ ДЕЛ РОССИЙСКОЙ ФЕДЕРАЦИИ": "МВД",
+}
+
+// NormalizeOrg takes a decision organization name and returns its canonical form.
+// It trims and collapses whitespace, then looks up the case insensitive alias map.
+func NormalizeOrg(org string) string {
+	// Collapse any whitespace runs into a single space.
+	org = strings.Join(strings.Fields(org), " ")
+
+	if canonical, ok := OrgAliases[strings.ToUpper(org)]; ok {
+		return canonical
+	}
+
+	return org
+}
@@ -0,0 +1,27 @@
+package parser
+
+import "testing"
+
+// TestNormalizeOrg tests the NormalizeOrg function.
+func TestNormalizeOrg(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"Роскомнадзор", "Роскомнадзор"},
+		{"  Мосгорсуд ", "Мосгорсуд"},
+		{"Генеральная  прокуратура\tРФ", "Генпрокуратура"},
+		{"генпрокуратура рф", "Генпрокуратура"},
+		{"ркн", "Роскомнадзор"},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result := NormalizeOrg(tc.input)
+			if result != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
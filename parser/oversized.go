@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"strconv"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Oversized record policies, see Config.MaxRecordSelectors.
+const (
+	OversizedTruncate   = "truncate"   // selectors beyond the limit are dropped, empty is OversizedTruncate.
+	OversizedQuarantine = "quarantine" // the record is not applied, the previous version, if any, stays.
+)
+
+// SelectorCount - IPs, subnets, domains and URLs of the record.
+func (record *Content) SelectorCount() int {
+	return len(record.Domain) + len(record.URL) + len(record.IP4) + len(record.IP6) + len(record.Subnet4) + len(record.Subnet6)
+}
+
+// truncate - keep the first limit selectors: domains, URLs, IPs, IPv6s, subnets, IPv6 subnets.
+func (record *Content) truncate(limit int) {
+	keep := func(n int) int {
+		if n > limit {
+			n = limit
+		}
+
+		limit -= n
+
+		return n
+	}
+
+	record.Domain = record.Domain[:keep(len(record.Domain))]
+	record.URL = record.URL[:keep(len(record.URL))]
+	record.IP4 = record.IP4[:keep(len(record.IP4))]
+	record.IP6 = record.IP6[:keep(len(record.IP6))]
+	record.Subnet4 = record.Subnet4[:keep(len(record.Subnet4))]
+	record.Subnet6 = record.Subnet6[:keep(len(record.Subnet6))]
+}
+
+// Oversized - the <content>...</content> is over the byte limit, it is quarantined
+// whatever the policy is, it is not even decoded.
+func (d *Decoder) Oversized(id int32, contBuf []byte, stats *Statistics) bool {
+	if d.config.MaxRecordBytes <= 0 || len(contBuf) <= d.config.MaxRecordBytes {
+		return false
+	}
+
+	value := strconv.Itoa(len(contBuf)) + " bytes"
+
+	d.Warnings.Add(WarnOversizedRecord, ElementContent, "", value, id)
+	logger.Warning.Printf("Oversized content quarantined: %d: %s\n", id, value)
+
+	stats.Oversized++
+
+	return true
+}
+
+// Fit - apply the selector limit to the decoded record, false if it is quarantined.
+func (d *Decoder) Fit(record *Content, stats *Statistics) bool {
+	n := record.SelectorCount()
+	if d.config.MaxRecordSelectors <= 0 || n <= d.config.MaxRecordSelectors {
+		return true
+	}
+
+	value := strconv.Itoa(n) + " selectors"
+
+	d.Warnings.Add(WarnOversizedRecord, ElementContent, "", value, record.ID)
+
+	stats.Oversized++
+
+	if d.config.Oversized == OversizedQuarantine {
+		logger.Warning.Printf("Oversized content quarantined: %d: %s\n", record.ID, value)
+
+		return false
+	}
+
+	logger.Warning.Printf("Oversized content truncated: %d: %s to %d\n", record.ID, value, d.config.MaxRecordSelectors)
+
+	record.truncate(d.config.MaxRecordSelectors)
+
+	return true
+}
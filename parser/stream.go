@@ -0,0 +1,235 @@
+// Package parser reads registry dumps record by record: charset policies, record and decision
+// hashes, address anomalies, size limits and format warnings, without any index.
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"golang.org/x/text/transform"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Config - parser knobs, the zero value decodes as declared with unseeded FNV hashes,
+// keeps address anomalies and has no size limits.
+type Config struct {
+	Hash               string // record and decision hash function, see NewHasher.
+	HashSeed           uint64 // hash seed, 0 - unseeded.
+	Charset            string // charset mode, see CharsetDeclared.
+	Anomalies          string // address anomaly policy, see AnomalyKeep.
+	MaxRecordSelectors int    // selectors of a record, 0 - unlimited.
+	MaxRecordBytes     int    // bytes of <content>...</content>, 0 - unlimited.
+	Oversized          string // oversized record policy, see OversizedTruncate.
+}
+
+// Statistics - records and bytes read.
+type Statistics struct {
+	Count          int   // records read, quarantined ones too.
+	Oversized      int   // records over the size limits, truncated or quarantined.
+	MaxContentSize int   // the biggest <content>...</content>.
+	Size           int64 // bytes of the dump read.
+
+	// charset problems, see Config.Charset.
+	ReplacementRecords int   // records with replacement characters.
+	ReplacementChars   int   // replacement characters in them.
+	CharsetFallbacks   int64 // bytes decoded as cp1251 in the lenient mode.
+}
+
+// Decoder - decoder of <content>...</content> fragments with the config. It is not safe
+// for concurrent use, its Warnings are.
+type Decoder struct {
+	Hasher   Hasher
+	Warnings *Warnings
+
+	config Config
+}
+
+// NewDecoder - decoder of the config, warnings are collected in a new Warnings if it is nil.
+func NewDecoder(config Config, warnings *Warnings) (*Decoder, error) {
+	hasher, err := NewHasher(config.Hash, config.HashSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	if warnings == nil {
+		warnings = NewWarnings()
+	}
+
+	return &Decoder{Hasher: hasher, Warnings: warnings, config: config}, nil
+}
+
+// ContentStream - records of a dump one by one, read and decoded without any index.
+type ContentStream struct {
+	Reg     Reg                       // attributes of the register, set when it starts.
+	Started func(*RawElementCapturer) // called when the register starts, if it is set.
+
+	counter        *readCounter
+	lenient        *lenientDecoder
+	capturer       *RawElementCapturer
+	decoder        *Decoder
+	stats          *Statistics
+	registerClosed bool
+}
+
+// NewContentStream - stream of the dump decoded by the decoder, records, content sizes
+// and replacement characters are counted in the stats.
+func NewContentStream(r io.Reader, decoder *Decoder, stats *Statistics) *ContentStream {
+	s := &ContentStream{
+		counter: &readCounter{r: r},
+		decoder: decoder,
+		stats:   stats,
+	}
+
+	var input io.Reader = s.counter
+
+	if decoder.config.Charset == CharsetLenient {
+		s.lenient = &lenientDecoder{}
+		input = transform.NewReader(s.counter, s.lenient)
+	}
+
+	s.capturer = NewRawElementCapturer(input, s.lenient != nil)
+
+	return s
+}
+
+// Stream - stream of the records of the dump for other pipelines with the config: the charset
+// policy, the hash and the size limits. Records which can't be decoded or are quarantined
+// are skipped, format problems are collected, see Warnings.
+func Stream(r io.Reader, config Config) (*ContentStream, error) {
+	decoder, err := NewDecoder(config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewContentStream(r, decoder, &Statistics{}), nil
+}
+
+// Next - the next record, io.EOF after the last one. ErrTruncatedDump means the dump is cut,
+// the records read before are not the whole registry.
+func (s *ContentStream) Next() (*Content, error) {
+	for {
+		id, contBuf, err := s.Fragment()
+		if err != nil {
+			return nil, err
+		}
+
+		if s.decoder.Oversized(id, contBuf, s.stats) {
+			continue
+		}
+
+		record, err := s.decoder.Decode(s.decoder.Hasher.Record(contBuf), contBuf)
+		if err != nil {
+			logger.Error.Printf("Decode Error: %s\n", err)
+
+			continue
+		}
+
+		if !s.decoder.Fit(record, s.stats) {
+			continue
+		}
+
+		return record, nil
+	}
+}
+
+// Statistics - records, content sizes and bytes read so far.
+func (s *ContentStream) Statistics() Statistics {
+	stats := *s.stats
+	stats.Size = s.counter.n
+
+	if s.lenient != nil {
+		stats.CharsetFallbacks = s.lenient.fallbacks
+	}
+
+	return stats
+}
+
+// Warnings - format problems of the dump, and of other dumps if the decoder shares its Warnings,
+// and the number of dropped ones.
+func (s *ContentStream) Warnings() ([]Warning, int64) {
+	return s.decoder.Warnings.List("")
+}
+
+// Capturer - tokenizer of the stream, for the input of the last fragment and its offset.
+func (s *ContentStream) Capturer() *RawElementCapturer {
+	return s.capturer
+}
+
+// SetOffset - the reader starts at the offset of the dump, it is counted in the read bytes.
+func (s *ContentStream) SetOffset(offset int64) {
+	s.counter.n = offset
+}
+
+// Fragment - id and undecoded <content>...</content> of the next record, io.EOF after
+// the closed register.
+func (s *ContentStream) Fragment() (int32, []byte, error) {
+	for {
+		token, err := s.capturer.Token()
+		if token == nil {
+			if err != io.EOF {
+				return 0, nil, parseError(err)
+			}
+
+			// without the close the dump is cut, a purge would drop the rest of the registry.
+			if !s.registerClosed {
+				return 0, nil, ErrTruncatedDump
+			}
+
+			return 0, nil, io.EOF
+		}
+
+		switch element := token.(type) {
+		case xml.EndElement:
+			if element.Name.Local == "register" {
+				s.registerClosed = true
+			}
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "register":
+				s.decoder.parseRegister(element, &s.Reg)
+
+				if s.Started != nil {
+					s.Started(s.capturer)
+				}
+			case "content":
+				id := getContentId(element)
+
+				// parse <content>...</content> only if need,
+				// hash of it for comp.
+				contBuf, err := s.capturer.Capture()
+				if err != nil {
+					return 0, nil, parseError(err)
+				}
+
+				s.stats.Count++
+
+				if s.stats.MaxContentSize < len(contBuf) {
+					s.stats.MaxContentSize = len(contBuf)
+				}
+
+				if n := bytes.Count(contBuf, replacementChar); n > 0 {
+					logger.Warning.Printf("Content %d: %d replacement characters\n", id, n)
+
+					s.stats.ReplacementRecords++
+					s.stats.ReplacementChars += n
+
+					// nothing is committed yet.
+					if s.decoder.config.Charset == CharsetStrict {
+						return 0, nil, fmt.Errorf("%w: content %d", ErrBadCharset, id)
+					}
+				}
+
+				return id, contBuf, nil
+			default:
+				s.decoder.Warnings.Add(WarnUnknownElement, element.Name.Local, "", "", 0)
+
+				if _, err := s.capturer.Capture(); err != nil {
+					return 0, nil, parseError(err)
+				}
+			}
+		}
+	}
+}
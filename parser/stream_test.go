@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const streamDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="РКН"/>
+        <ip>1.1.1.1</ip>
+        <ip>1.1.1.2</ip>
+        <ip>1.1.1.3</ip>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <url><![CDATA[http://e2.tld/]]></url>
+        <asn>64512</asn>
+</content>
+<content id="3" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="3">
+        <decision date="2000-01-01" number="3" org="ONE"/>
+        <domain><![CDATA[e3.tld]]></domain>
+</content>
+</reg:register>`
+
+// TestStream tests records are streamed with the config, and warnings are returned by the stream.
+func TestStream(t *testing.T) {
+	stream, err := Stream(strings.NewReader(streamDump), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int32
+
+	for {
+		record, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, record.ID)
+
+		if record.ID == 1 && (len(record.IP4) != 3 || record.Decision.Number != "1" || record.DecisionHash == 0) {
+			t.Errorf("Record: %+v\n", record)
+		}
+	}
+
+	if fmt.Sprint(ids) != "[1 2 3]" || stream.Reg.UpdateTime == 0 || stream.Statistics().Count != 3 {
+		t.Errorf("Stream: %v %+v %+v\n", ids, stream.Reg, stream.Statistics())
+	}
+
+	if warnings, _ := stream.Warnings(); len(warnings) != 1 || warnings[0].Kind != WarnUnknownElement || warnings[0].ID != 2 {
+		t.Errorf("Warnings: %+v\n", warnings)
+	}
+
+	// the limits are of the config, not of any global.
+	stream, err = Stream(strings.NewReader(streamDump), Config{MaxRecordSelectors: 2, Oversized: OversizedQuarantine})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if record, err := stream.Next(); err != nil || record.ID != 2 {
+		t.Errorf("Quarantined: %v %v\n", record, err)
+	}
+
+	if warnings, _ := stream.Warnings(); len(warnings) != 2 || stream.Statistics().Oversized != 1 {
+		t.Errorf("Oversized: %+v %+v\n", warnings, stream.Statistics())
+	}
+
+	if _, err := Stream(strings.NewReader(streamDump), Config{Hash: "md5"}); err == nil {
+		t.Error("Unknown hash must fail")
+	}
+
+	// the cut dump is an error after the records read.
+	stream, err = Stream(strings.NewReader(streamDump[:strings.Index(streamDump, `<content id="2"`)]), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if record, err := stream.Next(); err != nil || record.ID != 1 {
+		t.Errorf("First: %v %v\n", record, err)
+	}
+
+	if _, err := stream.Next(); !errors.Is(err, ErrTruncatedDump) {
+		t.Errorf("Truncated: %v\n", err)
+	}
+}
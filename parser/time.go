@@ -1,22 +1,19 @@
-package main
+package parser
 
-import (
-	"time"
+import "time"
 
-	"github.com/usher2/u2ckdump/internal/logger"
-)
-
-// Provides functions to parse RFC3339 time strings into Unix timestamps.
+// Provides functions to parse RFC3339 time strings and decision dates into Unix timestamps.
 // It supports parsing time strings in the Moscow timezone and without a timezone specified.
+// Unparseable dump times are reported as warnings, see WarnBadTime.
 
-// locationMSK represents the Moscow timezone.
-var locationMSK *time.Location
+// LocationMSK represents the Moscow timezone of the registry.
+var LocationMSK *time.Location
 
 // init initializes the Moscow timezone.
 func init() {
 	var err error
 
-	locationMSK, err = time.LoadLocation("Europe/Moscow")
+	LocationMSK, err = time.LoadLocation("Europe/Moscow")
 	if err != nil {
 		panic(err)
 	}
@@ -31,7 +28,6 @@ func parseRFC3339Time(s string) int64 {
 
 	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		// logger.Error.Printf("Can't parse time: %s (%s)\n", err, s)
 		return 0
 	}
 
@@ -48,9 +44,26 @@ func parseMoscowTime(s string) int64 {
 		return 0
 	}
 
-	t, err := time.ParseInLocation(parseIncludeTime, s, locationMSK)
+	t, err := time.ParseInLocation(parseIncludeTime, s, LocationMSK)
+	if err != nil {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// DecisionDateLayout is a format for <decision date="...">.
+const DecisionDateLayout = "2006-01-02"
+
+// ParseDecisionTime converts a decision date in the Moscow timezone to a Unix timestamp of its midnight.
+// Returns 0 if the input string is empty or the parsing fails.
+func ParseDecisionTime(s string) int64 {
+	if s == "" {
+		return 0
+	}
+
+	t, err := time.ParseInLocation(DecisionDateLayout, s, LocationMSK)
 	if err != nil {
-		logger.Error.Printf("Can't parse time: %s (%s)\n", err, s)
 		return 0
 	}
 
@@ -1,4 +1,4 @@
-package main
+package parser
 
 import (
 	"io"
@@ -55,3 +55,25 @@ func TestParseMoscowTime(t *testing.T) {
 		})
 	}
 }
+
+// TestParseDecisionTime tests the ParseDecisionTime function.
+func TestParseDecisionTime(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeStr     string
+		expectedVal int64
+	}{
+		{"Valid Decision Date", "2023-03-25", 1679691600},
+		{"Empty String", "", 0},
+		{"Invalid Date String", "25.03.2023", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseDecisionTime(tt.timeStr)
+			if result != tt.expectedVal {
+				t.Errorf("Expected %d, got %d", tt.expectedVal, result)
+			}
+		})
+	}
+}
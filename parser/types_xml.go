@@ -1,4 +1,4 @@
-package main
+package parser
 
 // XMLSubnet - <ipSubnet>.
 type XMLSubnet struct {
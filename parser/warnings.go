@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"encoding/xml"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Parse warning kinds: the dump is parsed, but its format drifted.
+const (
+	WarnUnknownElement   = "unknown-element"
+	WarnUnknownAttribute = "unknown-attribute"
+	WarnBadTime          = "bad-time"
+	WarnUnknownBlockType = "unknown-block-type"
+	WarnBadAddress       = "bad-address"      // IP or subnet is not parsed, it is skipped.
+	WarnOversizedRecord  = "oversized-record" // record over the size limits, see Config.MaxRecordSelectors.
+)
+
+// maxParseWarnings - distinct warnings kept, others are counted as dropped.
+const maxParseWarnings = 1000
+
+// knownAttrs - attributes of the registry elements, namespace declarations are not checked.
+var knownAttrs = map[string]map[string]struct{}{
+	"register":       {"formatVersion": {}, "updateTime": {}, "updateTimeUrgently": {}},
+	ElementContent:   {"id": {}, "entryType": {}, "urgencyType": {}, "includeTime": {}, "blockType": {}, "hash": {}, "ts": {}},
+	ElementDecision:  {"date": {}, "number": {}, "org": {}},
+	ElementURL:       {"ts": {}},
+	ElementDomain:    {"ts": {}},
+	ElementIP4:       {"ts": {}},
+	ElementIP6:       {"ts": {}},
+	ElementIP4Subnet: {"ts": {}},
+	ElementIP6Subnet: {"ts": {}},
+}
+
+// Warning - format problems of one kind, element and attribute since the start.
+type Warning struct {
+	Kind      string
+	Element   string
+	Attr      string // empty for elements.
+	Value     string // the last offending value.
+	ID        int32  // content id of the last one, 0 outside of <content>.
+	Count     int64
+	FirstSeen int64 // unix time.
+	LastSeen  int64
+}
+
+// warningKey - warnings are aggregated by it.
+type warningKey struct {
+	kind, element, attr string
+}
+
+// Warnings - parse warnings aggregated since the start, it is safe for concurrent use.
+type Warnings struct {
+	sync.Mutex
+	warnings map[warningKey]*Warning
+	dropped  int64
+}
+
+// NewWarnings - empty Warnings, parses may share it.
+func NewWarnings() *Warnings {
+	return &Warnings{warnings: make(map[warningKey]*Warning)}
+}
+
+// Add - count the warning, the first one of its kind, element and attribute is logged.
+func (c *Warnings) Add(kind, element, attr, value string, id int32) {
+	c.Lock()
+	defer c.Unlock()
+
+	key := warningKey{kind, element, attr}
+	now := time.Now().Unix()
+
+	w, ok := c.warnings[key]
+	if !ok {
+		if len(c.warnings) >= maxParseWarnings {
+			c.dropped++
+
+			return
+		}
+
+		logger.Warning.Printf("Parse warning: %s: <%s> %s: %q, content %d\n", kind, element, attr, value, id)
+
+		w = &Warning{Kind: kind, Element: element, Attr: attr, FirstSeen: now}
+		c.warnings[key] = w
+	}
+
+	w.Value, w.ID, w.LastSeen = value, id, now
+	w.Count++
+}
+
+// List - copies of the warnings of the kind (all if it is empty) ordered by kind, element
+// and attribute, and the number of dropped ones.
+func (c *Warnings) List(kind string) ([]Warning, int64) {
+	c.Lock()
+	defer c.Unlock()
+
+	list := make([]Warning, 0, len(c.warnings))
+
+	for _, w := range c.warnings {
+		if kind == "" || w.Kind == kind {
+			list = append(list, *w)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+
+		if list[i].Element != list[j].Element {
+			return list[i].Element < list[j].Element
+		}
+
+		return list[i].Attr < list[j].Attr
+	})
+
+	return list, c.dropped
+}
+
+// checkAttrs - warn about unknown attributes of the known element.
+func (c *Warnings) checkAttrs(element xml.StartElement, id int32) {
+	known := knownAttrs[element.Name.Local]
+
+	for _, attr := range element.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+
+		if _, ok := known[attr.Name.Local]; !ok {
+			c.Add(WarnUnknownAttribute, element.Name.Local, attr.Name.Local, attr.Value, id)
+		}
+	}
+}
+
+// CheckTime - warn if the value is set, but not parsed. It returns the parsed time.
+func (c *Warnings) CheckTime(element, attr, value string, id int32, t int64) int64 {
+	if t == 0 && value != "" {
+		c.Add(WarnBadTime, element, attr, value, id)
+	}
+
+	return t
+}
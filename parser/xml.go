@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Registry elements.
+const (
+	ElementContent   = "content"
+	ElementDecision  = "decision"
+	ElementURL       = "url"
+	ElementDomain    = "domain"
+	ElementIP4       = "ip"
+	ElementIP6       = "ipv6"
+	ElementIP4Subnet = "ipSubnet"
+	ElementIP6Subnet = "ipv6Subnet"
+)
+
+// Parse errors.
+var (
+	ErrTruncatedDump = errors.New("truncated dump")        // dump ends before </reg:register>.
+	ErrBadCharset    = errors.New("replacement character") // undecodable bytes in the strict charset mode.
+)
+
+// Reg - attributes of <register>.
+type Reg struct {
+	UpdateTime         int64
+	UpdateTimeUrgently string
+	FormatVersion      string
+}
+
+// Decode - decode the <content>...</content> of the hash, the decision hash and address anomalies are set.
+func (d *Decoder) Decode(recordHash uint64, buf []byte) (*Content, error) {
+	content := &Content{
+		RecordHash: recordHash,
+	}
+
+	err := d.unmarshal(buf, content)
+	if err != nil {
+		return nil, err
+	}
+
+	content.DecisionHash = d.Hasher.Decision(&content.Decision)
+
+	return content, nil
+}
+
+// unmarshal - unmarshal <content> element.
+func (d *Decoder) unmarshal(contBuf []byte, content *Content) error {
+	buf := bytes.NewReader(contBuf)
+	decoder := xml.NewDecoder(buf)
+
+	// ts attribute of a selector, unparseable ones are warned about.
+	ts := func(element, value string) int64 {
+		return d.Warnings.CheckTime(element, "ts", value, content.ID, parseRFC3339Time(value))
+	}
+
+	for {
+		token, err := decoder.Token()
+		if token == nil {
+			if err != io.EOF {
+				return fmt.Errorf("token: %w", err)
+			}
+
+			break
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			// TODO: one func for one case
+			switch element.Name.Local {
+			case ElementContent:
+				if err := d.parseContentElement(element, content); err != nil {
+					return fmt.Errorf("parse content elm: %w", err)
+				}
+			case ElementDecision:
+				if err := decoder.DecodeElement(&content.Decision, &element); err != nil {
+					return fmt.Errorf("parse decision elm: %w", err)
+				}
+			case ElementURL:
+				u := XMLURL{}
+				if err := decoder.DecodeElement(&u, &element); err != nil {
+					return fmt.Errorf("parse url elm: %w", err)
+				}
+
+				content.URL = append(content.URL, URL{URL: u.URL, Ts: ts(ElementURL, u.Ts)})
+			case ElementDomain:
+				domain := XMLDomain{}
+				if err := decoder.DecodeElement(&domain, &element); err != nil {
+					return fmt.Errorf("parse domain elm: %w", err)
+				}
+
+				content.Domain = append(content.Domain, Domain{Domain: domain.Domain, Ts: ts(ElementDomain, domain.Ts)})
+			case ElementIP4:
+				ip4 := XMLIP{}
+				if err := decoder.DecodeElement(&ip4, &element); err != nil {
+					return fmt.Errorf("parse ip elm: %w", err)
+				}
+
+				ip, ok := ParseIP4(ip4.IP)
+				if !ok {
+					d.Warnings.Add(WarnBadAddress, ElementIP4, "", ip4.IP, content.ID)
+					d.anomaly(content, ElementIP4, ip4.IP, AnomalyBadAddress)
+				} else if kind := addrAnomaly(ip, ip4.IP); kind == "" || d.anomaly(content, ElementIP4, ip4.IP, kind) {
+					content.IP4 = append(content.IP4, IP4{IP4: ip, Ts: ts(ElementIP4, ip4.Ts)})
+				}
+			case ElementIP6:
+				ip6 := XMLIP6{}
+				if err := decoder.DecodeElement(&ip6, &element); err != nil {
+					return fmt.Errorf("parse ipv6 elm: %w", err)
+				}
+
+				ip, ok := ParseIP6(ip6.IP6)
+				if !ok {
+					d.Warnings.Add(WarnBadAddress, ElementIP6, "", ip6.IP6, content.ID)
+					d.anomaly(content, ElementIP6, ip6.IP6, AnomalyBadAddress)
+				} else if kind := addrAnomaly(ip, ip6.IP6); kind == "" || d.anomaly(content, ElementIP6, ip6.IP6, kind) {
+					content.IP6 = append(content.IP6, IP6{IP6: ip, Ts: ts(ElementIP6, ip6.Ts)})
+				}
+			case ElementIP4Subnet:
+				subnet4 := XMLSubnet{}
+				if err := decoder.DecodeElement(&subnet4, &element); err != nil {
+					return fmt.Errorf("parse subnet elm: %w", err)
+				}
+
+				subnet, ok := ParseSubnet4(subnet4.Subnet)
+				if !ok {
+					d.Warnings.Add(WarnBadAddress, ElementIP4Subnet, "", subnet4.Subnet, content.ID)
+					d.anomaly(content, ElementIP4Subnet, subnet4.Subnet, AnomalyBadAddress)
+				} else if kind := subnetAnomaly(subnet); kind == "" || d.anomaly(content, ElementIP4Subnet, subnet4.Subnet, kind) {
+					content.Subnet4 = append(content.Subnet4, Subnet4{Subnet4: subnet, Ts: ts(ElementIP4Subnet, subnet4.Ts)})
+				}
+			case ElementIP6Subnet:
+				subnet6 := XMLSubnet6{}
+				if err := decoder.DecodeElement(&subnet6, &element); err != nil {
+					return fmt.Errorf("parse ipv6 subnet elm: %w", err)
+				}
+
+				subnet, ok := ParseSubnet6(subnet6.Subnet6)
+				if !ok {
+					d.Warnings.Add(WarnBadAddress, ElementIP6Subnet, "", subnet6.Subnet6, content.ID)
+					d.anomaly(content, ElementIP6Subnet, subnet6.Subnet6, AnomalyBadAddress)
+				} else if kind := subnetAnomaly(subnet); kind == "" || d.anomaly(content, ElementIP6Subnet, subnet6.Subnet6, kind) {
+					content.Subnet6 = append(content.Subnet6, Subnet6{Subnet6: subnet, Ts: ts(ElementIP6Subnet, subnet6.Ts)})
+				}
+			default:
+				d.Warnings.Add(WarnUnknownElement, element.Name.Local, "", "", content.ID)
+
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("skip %s elm: %w", element.Name.Local, err)
+				}
+
+				continue
+			}
+
+			// after <content>, its id is known.
+			d.Warnings.checkAttrs(element, content.ID)
+		}
+	}
+
+	return nil
+}
+
+// pasre <content> element itself.
+func (d *Decoder) parseContentElement(element xml.StartElement, content *Content) error {
+	for _, attr := range element.Attr {
+		switch attr.Name.Local {
+		case "id":
+			id, err := strconv.Atoi(attr.Value)
+			if err != nil {
+				return fmt.Errorf("id atoi: %w: %s", err, attr.Value)
+			}
+
+			content.ID = int32(id)
+		case "entryType":
+			entryType, err := strconv.Atoi(attr.Value)
+			if err != nil {
+				return fmt.Errorf("entryType atoi: %w: %s", err, attr.Value)
+			}
+
+			content.EntryType = int32(entryType)
+		case "urgencyType":
+			urgencyType, err := strconv.Atoi(attr.Value)
+			if err != nil {
+				return fmt.Errorf("urgencyType atoi: %w: %s", err, attr.Value)
+			}
+
+			content.UrgencyType = int32(urgencyType)
+		case "includeTime":
+			content.IncludeTime = d.Warnings.CheckTime(ElementContent, attr.Name.Local, attr.Value, content.ID, parseMoscowTime(attr.Value))
+		case "blockType":
+			content.BlockType = attr.Value
+		case "hash":
+			content.Hash = attr.Value
+		case "ts":
+			content.Ts = d.Warnings.CheckTime(ElementContent, attr.Name.Local, attr.Value, content.ID, parseRFC3339Time(attr.Value))
+		}
+	}
+
+	return nil
+}
+
+// parseError - error of the decoder, a cut dump is ErrTruncatedDump.
+func parseError(err error) error {
+	if isUnexpectedEOF(err) {
+		return fmt.Errorf("%w: %s", ErrTruncatedDump, err.Error())
+	}
+
+	return err
+}
+
+// readCounter - counts bytes read.
+type readCounter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// isUnexpectedEOF - the stream is cut in the middle of an element.
+func isUnexpectedEOF(err error) bool {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Msg == "unexpected EOF"
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func getContentId(_e xml.StartElement) int32 {
+	var (
+		id  int
+		err error
+	)
+	for _, _a := range _e.Attr {
+		if _a.Name.Local == "id" {
+			id, err = strconv.Atoi(_a.Value)
+			if err != nil {
+				logger.Debug.Printf("Can't fetch id: %s: %s\n", _a.Value, err.Error())
+			}
+		}
+	}
+	return int32(id)
+}
+
+// parseRegister - attributes of <register>.
+func (d *Decoder) parseRegister(element xml.StartElement, r *Reg) {
+	d.Warnings.checkAttrs(element, 0)
+
+	for _, attr := range element.Attr {
+		switch attr.Name.Local {
+		case "formatVersion":
+			r.FormatVersion = attr.Value
+		case "updateTime":
+			r.UpdateTime = d.Warnings.CheckTime("register", attr.Name.Local, attr.Value, 0, parseRFC3339Time(attr.Value))
+		case "updateTimeUrgently":
+			r.UpdateTimeUrgently = attr.Value
+		}
+	}
+}
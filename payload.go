@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// packPayload - snappy compress the payload if it is bigger than the threshold.
+func packPayload(payload []byte) ([]byte, bool) {
+	if ParseConfig.CompressThreshold <= 0 || len(payload) < ParseConfig.CompressThreshold {
+		return payload, false
+	}
+
+	return snappy.Encode(nil, payload), true
+}
+
+// payloadsKept - are record payloads kept, see PayloadNone.
+func payloadsKept() bool {
+	return ParseConfig.Payload != PayloadNone
+}
+
+// setPayload - store the payload, compressed or not, unless payloads are not kept.
+func (pack *PackedContent) setPayload(payload []byte) {
+	if !payloadsKept() {
+		pack.Payload, pack.Compressed = nil, false
+
+		return
+	}
+
+	pack.Payload, pack.Compressed = packPayload(payload)
+}
+
+// Record - the record of the payload. Without the payload it is rebuilt of the indexed fields:
+// selectors, entry and urgency types, include time, ts, decision date and the canonical organization.
+func (pack *PackedContent) Record() (*Content, error) {
+	if pack.Payload == nil {
+		record := &Content{
+			ID:          pack.ID,
+			EntryType:   pack.EntryType,
+			UrgencyType: pack.UrgencyType,
+			IncludeTime: pack.IncludeTime,
+			Ts:          pack.Ts,
+			BlockType:   registryBlockType(pack.BlockType),
+			Decision:    Decision{Org: pack.Org},
+			URL:         pack.URL,
+			IP4:         pack.IP4,
+			IP6:         pack.IP6,
+			Subnet4:     pack.Subnet4,
+			Subnet6:     pack.Subnet6,
+			Domain:      pack.Domain,
+			RecordHash:  pack.RecordHash,
+		}
+
+		if pack.DecisionDate != 0 {
+			record.Decision.Date = time.Unix(pack.DecisionDate, 0).In(parser.LocationMSK).Format(parser.DecisionDateLayout)
+		}
+
+		return record, nil
+	}
+
+	record := &Content{}
+	if err := json.Unmarshal(pack.PayloadBytes(), record); err != nil {
+		return nil, fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	return record, nil
+}
+
+// PayloadBytes - the payload, decompressed on read if needed.
+func (pack *PackedContent) PayloadBytes() []byte {
+	return payloadBytes(pack.ID, pack.Payload, pack.Compressed)
+}
+
+// registryBlockType - blockType attribute of the block type, URL blocks are "default".
+func registryBlockType(blockType int32) string {
+	switch blockType {
+	case BlockTypeDomain:
+		return "domain"
+	case BlockTypeMask:
+		return "domain-mask"
+	case BlockTypeIP:
+		return "ip"
+	}
+
+	return "default"
+}
+
+// payloadBytes - the stored payload of the record decompressed if needed, nil if it is broken.
+func payloadBytes(id int32, payload []byte, compressed bool) []byte {
+	if !compressed {
+		return payload
+	}
+
+	payload, err := snappy.Decode(nil, payload)
+	if err != nil {
+		logger.Error.Printf("Can't decompress payload: %d: %s\n", id, err.Error())
+
+		return nil
+	}
+
+	return payload
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// payloadQueryMaxIDs - max candidate records of a payload query, every one is decoded.
+const payloadQueryMaxIDs = 10000
+
+// stringerType - values printed by their String method, e.g. addresses and subnets.
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// PayloadPath - parsed path expression over the record payload, see ParsePayloadPath.
+type PayloadPath struct {
+	expr  string
+	steps []payloadStep
+}
+
+// payloadStep - one field of the path.
+type payloadStep struct {
+	index   []int // of the field in the struct.
+	iterate bool  // the field is a list, every item is taken.
+}
+
+// ParsePayloadPath - jq-like path of the record fields, e.g. .Decision.Org or .URL[].URL.
+// Fields are named as in Content or its JSON payload, case insensitive, [] iterates a list.
+// The path must end with a string, a number, a bool, an address or a subnet.
+func ParsePayloadPath(expr string) (*PayloadPath, error) {
+	if !strings.HasPrefix(expr, ".") {
+		return nil, fmt.Errorf("path must start with '.': %q", expr)
+	}
+
+	path := &PayloadPath{expr: expr}
+	typ := reflect.TypeOf(Content{})
+
+	for _, name := range strings.Split(expr[1:], ".") {
+		name, iterate := strings.CutSuffix(name, "[]")
+
+		if typ.Kind() != reflect.Struct || typ.Implements(stringerType) {
+			return nil, fmt.Errorf("%s has no fields: %q", typ.Name(), name)
+		}
+
+		field, ok := payloadField(typ, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field: %q", name)
+		}
+
+		typ = field.Type
+
+		if iterate {
+			if typ.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%s is not a list", name)
+			}
+
+			typ = typ.Elem()
+		}
+
+		path.steps = append(path.steps, payloadStep{index: field.Index, iterate: iterate})
+	}
+
+	if !payloadScalar(typ) {
+		return nil, fmt.Errorf("path must end with a value, not %s", typ.Kind())
+	}
+
+	return path, nil
+}
+
+// payloadField - field of the struct in the payload by its Go or JSON name, case insensitive.
+func payloadField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		if strings.EqualFold(field.Name, name) || (tag != "" && strings.EqualFold(tag, name)) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// payloadScalar - values of the type are printed as they are.
+func payloadScalar(typ reflect.Type) bool {
+	if typ.Implements(stringerType) {
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// String - the path expression.
+func (p *PayloadPath) String() string {
+	return p.expr
+}
+
+// Values - values of the path in the record, in the record order.
+func (p *PayloadPath) Values(record *Content) []string {
+	var values []string
+
+	p.walk(reflect.ValueOf(record).Elem(), 0, &values)
+
+	return values
+}
+
+func (p *PayloadPath) walk(v reflect.Value, i int, values *[]string) {
+	if i == len(p.steps) {
+		*values = append(*values, payloadValue(v))
+
+		return
+	}
+
+	field := v.FieldByIndex(p.steps[i].index)
+
+	if !p.steps[i].iterate {
+		p.walk(field, i+1, values)
+
+		return
+	}
+
+	for j := 0; j < field.Len(); j++ {
+		p.walk(field.Index(j), i+1, values)
+	}
+}
+
+// payloadValue - text of the scalar value.
+func payloadValue(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+
+	return ""
+}
+
+// matchPayloadValues - some value is equal to the query, or contains it case insensitive.
+func matchPayloadValues(values []string, query string, contains bool) bool {
+	if query == "" {
+		return true
+	}
+
+	for _, value := range values {
+		if contains && strings.Contains(strings.ToLower(value), strings.ToLower(query)) {
+			return true
+		}
+
+		if !contains && value == query {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestQueryPayload tests path expressions over payloads of candidate records.
+func TestQueryPayload(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	for _, expr := range []string{"Decision.Org", ".Decision", ".URL", ".URL[].Nope", ".ID[]", ".decisionHash", ".DecisionHash", ".Anomalies", ".IP4[].IP4.Foo"} {
+		if _, err := ParsePayloadPath(expr); err == nil {
+			t.Errorf("Bad path %q is parsed\n", expr)
+		}
+	}
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	resp, _ := s.QueryPayload(context.Background(), &pb.PayloadQueryRequest{Path: ".URL[].URL", Ids: []int32{222, 111, 111, 999}})
+	if resp.GetError() != "" || resp.GetTotal() != 2 || resp.GetResults()[0].GetId() != 111 || len(resp.GetResults()[1].GetValues()) != 0 ||
+		!reflect.DeepEqual(resp.GetResults()[0].GetValues(), []string{"https://www.e01.tld/sex", "http://www.e01.tld/cheese", "http://www.e01.tld/slip"}) {
+		t.Errorf("URLs: %v\n", resp)
+	}
+
+	// JSON names and case insensitive Go names.
+	resp, _ = s.QueryPayload(context.Background(), &pb.PayloadQueryRequest{Path: ".d.ORG", Ids: []int32{111, 555}, Value: "FIVE"})
+	if resp.GetTotal() != 1 || resp.GetResults()[0].GetId() != 555 {
+		t.Errorf("Org: %v\n", resp)
+	}
+
+	resp, _ = s.QueryPayload(context.Background(), &pb.PayloadQueryRequest{Path: ".ip4[].ip4", Ids: []int32{111, 222, 333}, Value: "0.100", Contains: true, Limit: 1})
+	if resp.GetTotal() != 3 || len(resp.GetResults()) != 1 || resp.GetResults()[0].GetValues()[1] != "192.168.0.100" {
+		t.Errorf("IPs: %v\n", resp)
+	}
+
+	if resp, _ := s.QueryPayload(context.Background(), &pb.PayloadQueryRequest{Path: ".Decision"}); resp.GetError() != SrvBadPath {
+		t.Errorf("Bad path: %v\n", resp)
+	}
+
+	if resp, _ := s.QueryPayload(context.Background(), &pb.PayloadQueryRequest{Path: ".ID", Ids: make([]int32, payloadQueryMaxIDs+1)}); resp.GetError() != SrvTooManyIDs {
+		t.Errorf("Too many ids: %v\n", resp)
+	}
+}
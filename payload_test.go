@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestPayloadCompression tests threshold controlled payload compression.
+func TestPayloadCompression(t *testing.T) {
+	defer func(threshold int) { ParseConfig.CompressThreshold = threshold }(ParseConfig.CompressThreshold)
+
+	small := []byte(`{"id":1}`)
+	big := bytes.Repeat([]byte(`{"u":"http://example.com/"},`), 100)
+
+	ParseConfig.CompressThreshold = 64
+
+	pack := newPackedContent(1, 0, 0, small)
+	if pack.Compressed || !bytes.Equal(pack.PayloadBytes(), small) {
+		t.Errorf("small payload must be stored as is")
+	}
+
+	pack.refreshPackedContent(0, 0, big)
+	if !pack.Compressed || len(pack.Payload) >= len(big) || !bytes.Equal(pack.PayloadBytes(), big) {
+		t.Errorf("big payload must be compressed and restored")
+	}
+
+	ParseConfig.CompressThreshold = 0
+
+	pack.refreshPackedContent(0, 0, big)
+	if pack.Compressed {
+		t.Errorf("zero threshold must disable compression")
+	}
+}
+
+// TestPayloadNone tests records without payloads: empty pack, v2 records and snapshots rebuilt of the index.
+func TestPayloadNone(t *testing.T) {
+	defer func(dump *Dump, policy string) { CurrentDump, ParseConfig.Payload = dump, policy }(CurrentDump, ParseConfig.Payload)
+
+	CurrentDump, ParseConfig.Payload = NewDump(), PayloadNone
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	resp, _ := s.SearchID(context.Background(), &pb.IDRequest{Query: 111})
+	if len(resp.GetResults()) != 1 {
+		t.Fatalf("Search: %v\n", resp)
+	}
+
+	v := resp.GetResults()[0]
+	if len(v.GetPack()) != 0 || v.GetDecision().GetDate() == "" || v.GetDecision().GetNumber() != "" {
+		t.Errorf("Content: %v\n", v)
+	}
+
+	record, err := newV2Record(v)
+	if err != nil || len(record.GetIp4()) != 3 || len(record.GetDomains()) != 1 || record.GetIncludeTime() == 0 {
+		t.Errorf("v2 record: %v %v\n", record, err)
+	}
+
+	if pong, _ := s.Ping(context.Background(), &pb.PingRequest{}); pong.GetPayloads() {
+		t.Errorf("Ping: %v\n", pong)
+	}
+
+	CurrentDump.RLock()
+	snap, selectors := CurrentDump.snapshot(), CurrentDump.Selectors()
+	CurrentDump.RUnlock()
+
+	restored := NewDump()
+	if err := restored.RestoreSnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(restored.Selectors(), selectors) {
+		t.Errorf("Restored selectors: %v\n", restored.Selectors())
+	}
+
+	for id, pack := range CurrentDump.ContentIdx {
+		if r := restored.ContentIdx[id]; r == nil || r.Payload != nil || r.BlockType != pack.BlockType || r.DecisionDate != pack.DecisionDate {
+			t.Errorf("Restored: %d: %+v\n", id, r)
+		}
+	}
+}
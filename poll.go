@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"runtime"
 	"time"
@@ -8,18 +11,65 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
 )
 
-// DumpPoll - poll "vygruzki" service for new dumps.
-func DumpPoll(s *grpc.Server, done chan<- struct{}, kill <-chan struct{}, url, token, dir string, d time.Duration) {
-	timer := time.NewTimer(time.Millisecond)
+// DumpSource - where new dumps come from.
+type DumpSource interface {
+	// Refresh - fetch, parse and apply a new dump to the dir, if there is one.
+	// Transient failures are retried within RetryConfig until the context is cancelled.
+	Refresh(ctx context.Context, dir string)
+}
+
+// VigruzkiSource - "vygruzki" service, the registry dump with credentials.
+type VigruzkiSource struct {
+	URL   string
+	Token string
+	TLS   UpstreamTLS
+}
+
+func (v *VigruzkiSource) Refresh(ctx context.Context, dir string) {
+	DumpRefresh(ctx, v.URL, v.Token, v.TLS, dir)
+}
+
+// DumpPoll - poll the source for new dumps.
+// Only the lease holder polls, nil lease means single instance mode.
+// The systemd watchdog is petted between polls, a stuck refresh misses the pets.
+func DumpPoll(s *grpc.Server, done chan<- struct{}, kill <-chan struct{}, lease *Lease, source DumpSource, dir string, first, d time.Duration) {
+	timer := time.NewTimer(first)
 	defer timer.Stop()
 
+	// retries of a refresh are cut by the kill.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-kill:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var watchdog <-chan time.Time
+
+	if interval := SdWatchdogInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		watchdog = ticker.C
+	}
+
 	for {
 		select {
 		case <-timer.C:
-			DumpRefresh(url, token, dir)
+			pollOnce(ctx, lease, source, dir)
+
+			sdNotifyReady()
+
 			timer.Reset(d * time.Second)
+		case <-watchdog:
+			sdPetWatchdog()
 		case <-kill:
 			close(done)
 
@@ -28,11 +78,53 @@ func DumpPoll(s *grpc.Server, done chan<- struct{}, kill <-chan struct{}, url, t
 	}
 }
 
-// DumpRefresh - try to fetch new dump.
-func DumpRefresh(url, token, dir string) {
+// pollOnce - one poll cycle: only the lease holder refreshes. The standby serves the index
+// it has got, so it is not marked refreshed: readiness and freshness show the real age of its data.
+func pollOnce(ctx context.Context, lease *Lease, source DumpSource, dir string) {
+	if m := Maintenance(); m.Paused {
+		logger.Info.Printf("Polling is paused since %s: %s\n", m.Since.Format(time.RFC3339), m.Reason)
+
+		return
+	}
+
+	// both leader and standby serve queries.
+	RefreshExclusions()
+
+	if !lease.Leader() {
+		logger.Debug.Println("Standby mode, skip polling")
+
+		return
+	}
+
+	source.Refresh(ctx, dir)
+}
+
+// DumpRefresh - try to fetch new dump, transient failures are retried within RetryConfig.
+func DumpRefresh(ctx context.Context, url, token string, upstream UpstreamTLS, dir string) {
 	ts := time.Now().Unix()
 
-	lastDump, err := GetLastDumpID(ts, url, token)
+	client, err := upstream.Client()
+	if err != nil {
+		logger.Error.Printf("Can't configure upstream TLS: %s\n", err.Error())
+
+		return
+	}
+
+	budget := NewRetryBudget(ctx, RetryConfig)
+
+	var lastDump *DumpAnswer
+
+	err = budget.Do(RetryLastID, func() error {
+		var err error
+
+		lastDump, err = GetLastDumpID(ctx, client, ts, url, token)
+		if ferr := Faults.Fail(FaultFetch); ferr != nil {
+			err = ferr
+		}
+
+		return err
+	})
+
 	if err != nil {
 		logger.Error.Printf("Can't get last dump id: %s\n", err.Error())
 
@@ -65,38 +157,36 @@ func DumpRefresh(url, token, dir string) {
 	// two states...
 	switch {
 	case lastDump.CRC != cachedDump.CRC:
-		logger.Info.Printf("Getting new dump..")
-
-		err := FetchDump(lastDump.ID, dir+"/dump.zip", url, token)
-		if err != nil {
-			logger.Error.Printf("Can't fetch last dump: %s\n", err.Error())
-
-			return
+		// an interrupted parse of the dump is resumed, it is not fetched again.
+		if ParseCheckpointed(dir, lastDump.ID) {
+			logger.Info.Printf("Resuming parse of dump %s..", lastDump.ID)
+		} else {
+			logger.Info.Printf("Getting new dump..")
+
+			rotation := StartRotation(dir)
+			defer rotation.Close()
+
+			err := budget.Do(RetryFetch, func() error {
+				return FetchAndUnzip(ctx, lastDump.ID, dir, url, token, upstream)
+			})
+			if errors.Is(err, ErrTooBig) {
+				generation, _ := CurrentDump.Changes()
+				RaiseAlert(AlertOversized, generation, fmt.Sprintf("dump %s is refused: %s", lastDump.ID, err.Error()))
+			}
+
+			if err != nil {
+				logger.Error.Printf("Can't get last dump: %s\n", err.Error())
+
+				return
+			}
+
+			if err := rotation.Rotate(cachedDump.ID); err != nil {
+				logger.Error.Printf("Can't rotate dumps: %s\n", err.Error())
+			}
 		}
 
-		logger.Info.Println("Last dump fetched")
-
-		err = DumpUnzip(dir+"/dump.zip", dir+"/dump.xml")
-		if err != nil {
-			logger.Error.Printf("Can't extract last dump: %s\n", err.Error())
-
-			return
-		}
-
-		logger.Info.Println("Last dump extracted")
-
-		// parse xml
-		dumpFile, err := os.Open(dir + "/dump.xml")
-		if err != nil {
-			logger.Error.Printf("Can't open dump file: %s\n", err.Error())
-
-			return
-		}
-
-		defer dumpFile.Close()
-
-		err = Parse(dumpFile)
-		if err != nil {
+		// a cut download is not applied, the previous generation stays.
+		if err := ParseDumpFile(dir, lastDump.ID); err != nil {
 			logger.Error.Printf("Parse error: %s\n", err.Error())
 
 			return
@@ -104,6 +194,9 @@ func DumpRefresh(url, token, dir string) {
 
 		logger.Info.Printf("Dump parsed")
 
+		CurrentDump.SetSource(lastDump.ID, lastDump.CRC)
+		PostParse(dir)
+
 		err = WriteCurrentDumpID(dir+"/current", lastDump)
 		if err != nil {
 			logger.Error.Printf("Can't write currentdump file: %s\n", err.Error())
@@ -119,4 +212,54 @@ func DumpRefresh(url, token, dir string) {
 	default:
 		logger.Info.Printf("No new dump")
 	}
+
+	MarkRefreshed()
+}
+
+// ParseDumpFile - check and parse dump.xml of the dump id in the dir.
+func ParseDumpFile(dir, dumpID string) error {
+	if err := Faults.Fail(FaultParse); err != nil {
+		return err
+	}
+
+	dumpFile, err := os.Open(dir + "/dump.xml")
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	defer dumpFile.Close()
+
+	if err := CheckDumpTail(dumpFile); err != nil {
+		return fmt.Errorf("check: %w", err)
+	}
+
+	// lenient decoding loses input offsets.
+	if ParseCheckpointEvery > 0 && ParseConfig.Charset != parser.CharsetLenient {
+		return ParseResumable(dumpFile, dir, dumpID)
+	}
+
+	return Parse(dumpFile)
+}
+
+// PostParse - jobs after a new generation is applied.
+func PostParse(dir string) {
+	err := SaveSnapshot(dir, SnapshotKeep)
+	if err != nil {
+		logger.Error.Printf("Can't save snapshot: %s\n", err.Error())
+	}
+
+	err = AppendStatsHistory(dir, newStatsPoint(Stats))
+	if err != nil {
+		logger.Error.Printf("Can't save stats history: %s\n", err.Error())
+	}
+
+	entry := newChangelogEntry(Stats)
+	DetectChurn(dir, Stats, &entry)
+	Changelog.Append(entry)
+
+	ExportArtifacts(dir)
+
+	UploadToS3(dir)
+
+	ShadowParse(dir)
 }
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// manifestFilename - exported artifacts and the generations they are made of.
+const manifestFilename = "manifest.json"
+
+// Version - tool version, set at build time with -ldflags "-X main.Version=v1.2.3".
+var Version string
+
+// ToolVersion - Version, the module version of the build or "devel".
+func ToolVersion() string {
+	if Version != "" {
+		return Version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	return "devel"
+}
+
+// Provenance - where an exported artifact comes from.
+type Provenance struct {
+	DumpID             string `json:"dumpId"` // empty if the dump is not fetched by this instance.
+	CRC                string `json:"crc"`
+	RegistryUpdateTime int64  `json:"registryUpdateTime"`
+	Generation         int64  `json:"generation"`
+	Version            string `json:"version"`
+}
+
+// String - one line stamp of the provenance.
+func (p Provenance) String() string {
+	return fmt.Sprintf("dump=%s crc=%s updateTime=%d generation=%d version=%s",
+		p.DumpID, p.CRC, p.RegistryUpdateTime, p.Generation, p.Version)
+}
+
+// SetSource - registry dump the current generation is parsed from.
+func (d *Dump) SetSource(id, crc string) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.dumpID, d.dumpCRC = id, crc
+}
+
+// provenance - provenance of the current generation, call it under lock.
+func (d *Dump) provenance() Provenance {
+	return Provenance{
+		DumpID:             d.dumpID,
+		CRC:                d.dumpCRC,
+		RegistryUpdateTime: d.utime,
+		Generation:         d.generation,
+		Version:            ToolVersion(),
+	}
+}
+
+// Provenance - provenance of the current generation.
+func (d *Dump) Provenance() Provenance {
+	d.RLock()
+	defer d.RUnlock()
+
+	return d.provenance()
+}
+
+// Artifact - exported file of the manifest.
+type Artifact struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Records int    `json:"records"` // rows written: records, names of the bloom filter, ASNs or selectors of the reports.
+	Provenance
+}
+
+// Manifest - manifest.json: exported artifacts, each one with the generation it is made of.
+// Artifacts not exported for the current generation keep their older entries, so a complete
+// set of a generation is the artifacts of the manifest generation.
+type Manifest struct {
+	Generation         int64      `json:"generation"` // current when the manifest is written.
+	DumpID             string     `json:"dumpId"`
+	RegistryUpdateTime int64      `json:"registryUpdateTime"`
+	Records            int        `json:"records"`   // all records of the generation.
+	Artifacts          []Artifact `json:"artifacts"` // ordered by name.
+}
+
+// ReadManifest - manifest of the dir, empty if there is none.
+func ReadManifest(dir string) (*Manifest, error) {
+	manifest := &Manifest{}
+
+	data, err := os.ReadFile(dir + "/" + manifestFilename)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// UpdateManifest - replace entries of the artifacts just exported to the dir with the provenance,
+// exported are their record counts by name, records are all records of the generation.
+func UpdateManifest(dir string, prov Provenance, records int, exported map[string]int) error {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		logger.Warning.Printf("Can't read %s, it is rebuilt: %s\n", manifestFilename, err.Error())
+
+		manifest = &Manifest{}
+	}
+
+	entries := make(map[string]Artifact, len(manifest.Artifacts)+len(exported))
+	for _, a := range manifest.Artifacts {
+		entries[a.Name] = a
+	}
+
+	for name, n := range exported {
+		a, err := newArtifact(dir, name, prov)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		a.Records = n
+		entries[name] = a
+	}
+
+	manifest.Generation, manifest.DumpID, manifest.RegistryUpdateTime = prov.Generation, prov.DumpID, prov.RegistryUpdateTime
+	manifest.Records, manifest.Artifacts = records, make([]Artifact, 0, len(entries))
+
+	for _, a := range entries {
+		manifest.Artifacts = append(manifest.Artifacts, a)
+	}
+
+	sort.Slice(manifest.Artifacts, func(i, j int) bool { return manifest.Artifacts[i].Name < manifest.Artifacts[j].Name })
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	filename := dir + "/" + manifestFilename
+	if err := os.WriteFile(filename+"-tmp", data, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := os.Rename(filename+"-tmp", filename); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// newArtifact - entry of the exported file.
+func newArtifact(dir, name string, prov Provenance) (Artifact, error) {
+	f, err := os.Open(dir + "/" + name)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("open: %w", err)
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("read: %w", err)
+	}
+
+	return Artifact{Name: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil)), Provenance: prov}, nil
+}
+
+// ExportArtifacts - export the enabled artifacts of the new generation and add them to the manifest
+// with the rows each exporter wrote.
+func ExportArtifacts(dir string) {
+	exported := make(map[string]int)
+
+	// the one hour enforcement deadline, it goes first.
+	if n, ok := ExportUrgentCSV(dir); ok {
+		exported[urgentCSVFilename] = n
+	}
+
+	if n, ok := ExportASNReport(dir); ok {
+		exported[asnReportFilename] = n
+	}
+
+	if n, ok := ExportRegistryCSV(dir); ok {
+		exported[registryCSVFilename] = n
+	}
+
+	if n, ok := ExportSNIBloom(dir); ok {
+		exported[sniBloomFilename], exported[sniBloomMetaFilename] = n, n
+	}
+
+	if n, ok := ExportDuplicatesReport(dir); ok {
+		exported[duplicatesReportFilename] = n
+	}
+
+	if n, ok := ExportSQLite(dir); ok {
+		exported[sqliteFilename] = n
+	}
+
+	if len(exported) == 0 {
+		return
+	}
+
+	CurrentDump.RLock()
+	prov, records := CurrentDump.provenance(), len(CurrentDump.ContentIdx)
+	CurrentDump.RUnlock()
+
+	if err := UpdateManifest(dir, prov, records, exported); err != nil {
+		logger.Error.Printf("Can't save %s: %s\n", manifestFilename, err.Error())
+	}
+}
+
+// manifestHandler - /manifest.json with its hash as ETag and the generation in headers,
+// a consumer fetches it again after the artifacts to check the set is not replaced meanwhile.
+func manifestHandler(dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the manifest is replaced by rename.
+		data, err := os.ReadFile(dir + "/" + manifestFilename)
+		if err != nil {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		sum := sha256.Sum256(data)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", strconv.Quote(hex.EncodeToString(sum[:])))
+		w.Header().Set("X-Generation", strconv.FormatInt(manifest.Generation, 10))
+		w.Header().Set("X-Registry-Update-Time", strconv.FormatInt(manifest.RegistryUpdateTime, 10))
+
+		http.ServeContent(w, r, manifestFilename, time.Unix(manifest.RegistryUpdateTime, 0), bytes.NewReader(data))
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestArtifactManifest tests provenance stamps of the exports and the manifest of their generations.
+func TestArtifactManifest(t *testing.T) {
+	defer func(dump *Dump, csv bool, fp float64) {
+		CurrentDump, RegistryCSV, SNIBloomFP = dump, csv, fp
+	}(CurrentDump, RegistryCSV, SNIBloomFP)
+
+	CurrentDump = NewDump()
+	RegistryCSV, SNIBloomFP = true, 0.001
+
+	dir := t.TempDir()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	CurrentDump.SetSource("id1", "crc1")
+	ExportArtifacts(dir)
+
+	var meta SNIBloomMeta
+
+	names := len(CurrentDump.SNIList(nil))
+
+	data, _ := os.ReadFile(dir + "/" + sniBloomMetaFilename)
+	if err := json.Unmarshal(data, &meta); err != nil || meta.DumpID != "id1" || meta.CRC != "crc1" || meta.ToolVersion == "" || meta.Items != names {
+		t.Errorf("Bloom meta: %+v %v\n", meta, err)
+	}
+
+	// the bloom filter is not exported for the next generation, its entry stays.
+	SNIBloomFP = 0
+
+	if err := Parse(strings.NewReader(xml02)); err != nil {
+		t.Fatal(err)
+	}
+
+	CurrentDump.SetSource("id2", "crc2")
+	ExportArtifacts(dir)
+
+	records := len(CurrentDump.ContentIdx)
+
+	manifest, err := ReadManifest(dir)
+	if err != nil || manifest.Generation != 2 || manifest.DumpID != "id2" || manifest.Records != records || len(manifest.Artifacts) != 3 {
+		t.Fatalf("Manifest: %+v %v\n", manifest, err)
+	}
+
+	want := map[string]string{registryCSVFilename: "id2", sniBloomFilename: "id1", sniBloomMetaFilename: "id1"}
+	wantRecords := map[string]int{registryCSVFilename: records, sniBloomFilename: names, sniBloomMetaFilename: names}
+
+	for _, a := range manifest.Artifacts {
+		data, _ := os.ReadFile(dir + "/" + a.Name)
+		if want[a.Name] != a.DumpID || a.Size != int64(len(data)) || len(a.SHA256) != 64 || a.Version != ToolVersion() || wantRecords[a.Name] != a.Records {
+			t.Errorf("Artifact: %+v\n", a)
+		}
+
+		if a.Name == registryCSVFilename && (a.Generation != 2 || !strings.Contains(string(data), "\n# dump=id2 crc=crc2 ")) {
+			t.Errorf("dump.csv: %+v\n", a)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	manifestHandler(dir)(w, httptest.NewRequest("GET", "/"+manifestFilename, nil))
+
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"dumpId": "id2"`) || w.Header().Get("X-Generation") != "2" {
+		t.Errorf("Download: %d %s\n", w.Code, w.Body.String())
+	}
+
+	// the set is not replaced since.
+	r := httptest.NewRequest("GET", "/"+manifestFilename, nil)
+	r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	manifestHandler(dir)(w, r)
+
+	if w.Code != 304 {
+		t.Errorf("Not modified: %d\n", w.Code)
+	}
+}
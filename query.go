@@ -0,0 +1,650 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// MatchQuery - the record matches the Query expression.
+const MatchQuery = "query"
+
+// queryMaxLen - max length of a Query expression.
+const queryMaxLen = 4096
+
+// queryMaxDepth - max nesting of a Query expression.
+const queryMaxDepth = 32
+
+// errQuerySyntax - the expression is not well-formed.
+var errQuerySyntax = errors.New("syntax error")
+
+// queryNode - compiled part of a Query expression.
+type queryNode interface {
+	// lookup - records of the node from the indexes, false if the records are to be scanned.
+	lookup(dump *Dump) (ArrayIntSet, bool)
+	// match - does the record match?
+	match(dump *Dump, pack *PackedContent) bool
+}
+
+// RecordQuery - compiled Query expression, see ParseQuery. It is evaluated under read lock.
+type RecordQuery struct {
+	root queryNode
+}
+
+// indexedPredicate - predicate over an index, records are matched by membership.
+type indexedPredicate struct {
+	ids func(dump *Dump) ArrayIntSet
+	set map[int32]Nothing // lazy, the query is evaluated against one generation.
+}
+
+func (p *indexedPredicate) lookup(dump *Dump) (ArrayIntSet, bool) {
+	return p.ids(dump), true
+}
+
+func (p *indexedPredicate) match(dump *Dump, pack *PackedContent) bool {
+	if p.set == nil {
+		ids := p.ids(dump)
+
+		p.set = make(map[int32]Nothing, len(ids))
+		for _, id := range ids {
+			p.set[id] = Nothing{}
+		}
+	}
+
+	_, ok := p.set[pack.ID]
+
+	return ok
+}
+
+// scanPredicate - predicate over the record fields.
+type scanPredicate func(pack *PackedContent) bool
+
+func (p scanPredicate) lookup(*Dump) (ArrayIntSet, bool) {
+	return nil, false
+}
+
+func (p scanPredicate) match(_ *Dump, pack *PackedContent) bool {
+	return p(pack)
+}
+
+type queryAnd []queryNode
+
+// lookup - intersection of the indexed operands filtered by the others, false if none is indexed.
+func (q queryAnd) lookup(dump *Dump) (ArrayIntSet, bool) {
+	var (
+		ids     ArrayIntSet
+		indexed bool
+		rest    []queryNode
+	)
+
+	for _, node := range q {
+		found, ok := node.lookup(dump)
+		if !ok {
+			rest = append(rest, node)
+
+			continue
+		}
+
+		if !indexed {
+			ids, indexed = found, true
+
+			continue
+		}
+
+		ids = intersectIDs(ids, found)
+	}
+
+	if !indexed {
+		return nil, false
+	}
+
+	result := make(ArrayIntSet, 0, len(ids))
+
+	for _, id := range ids {
+		pack, ok := dump.ContentIdx[id]
+		if !ok {
+			continue
+		}
+
+		if matchAll(dump, pack, rest) {
+			result = append(result, id)
+		}
+	}
+
+	return result, true
+}
+
+func (q queryAnd) match(dump *Dump, pack *PackedContent) bool {
+	return matchAll(dump, pack, q)
+}
+
+type queryOr []queryNode
+
+// lookup - union of the operands, false if any is not indexed.
+func (q queryOr) lookup(dump *Dump) (ArrayIntSet, bool) {
+	var ids ArrayIntSet
+
+	for _, node := range q {
+		found, ok := node.lookup(dump)
+		if !ok {
+			return nil, false
+		}
+
+		ids = append(ids, found...)
+	}
+
+	return ids, true
+}
+
+func (q queryOr) match(dump *Dump, pack *PackedContent) bool {
+	for _, node := range q {
+		if node.match(dump, pack) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type queryNot struct {
+	node queryNode
+}
+
+func (q queryNot) lookup(*Dump) (ArrayIntSet, bool) {
+	return nil, false
+}
+
+func (q queryNot) match(dump *Dump, pack *PackedContent) bool {
+	return !q.node.match(dump, pack)
+}
+
+func matchAll(dump *Dump, pack *PackedContent, nodes []queryNode) bool {
+	for _, node := range nodes {
+		if !node.match(dump, pack) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// intersectIDs - ids of a which are in b.
+func intersectIDs(a, b ArrayIntSet) ArrayIntSet {
+	set := make(map[int32]Nothing, len(b))
+	for _, id := range b {
+		set[id] = Nothing{}
+	}
+
+	var result ArrayIntSet
+
+	for _, id := range a {
+		if _, ok := set[id]; ok {
+			result = append(result, id)
+		}
+	}
+
+	return result
+}
+
+// Records - ids of the matching records, ordered. Call it under read lock.
+func (q *RecordQuery) Records(dump *Dump) []int32 {
+	seen := make(map[int32]Nothing)
+
+	var result []int32
+
+	add := func(id int32) {
+		if _, ok := seen[id]; !ok {
+			seen[id] = Nothing{}
+			result = append(result, id)
+		}
+	}
+
+	if ids, ok := q.root.lookup(dump); ok {
+		for _, id := range ids {
+			if _, ok := dump.ContentIdx[id]; ok {
+				add(id)
+			}
+		}
+	} else {
+		for id, pack := range dump.ContentIdx {
+			if q.root.match(dump, pack) {
+				add(id)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+
+	return result
+}
+
+// queryToken - lexeme of a Query expression: "(", ")", an operator, a word or a quoted string.
+type queryToken struct {
+	text   string
+	quoted bool
+	pos    int
+}
+
+// queryOperators - comparison operators, longer first.
+var queryOperators = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// lexQuery - tokens of the expression.
+func lexQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+			continue
+		case c == '(' || c == ')':
+			tokens = append(tokens, queryToken{text: string(c), pos: i})
+			i++
+
+			continue
+		case c == '"':
+			j := i + 1
+			for ; j < len(expr) && expr[j] != '"'; j++ {
+				if expr[j] == '\\' {
+					j++
+				}
+			}
+
+			if j >= len(expr) {
+				return nil, fmt.Errorf("%w at %d: unterminated string", errQuerySyntax, i)
+			}
+
+			s, err := strconv.Unquote(expr[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w at %d: bad string", errQuerySyntax, i)
+			}
+
+			tokens = append(tokens, queryToken{text: s, quoted: true, pos: i})
+			i = j + 1
+
+			continue
+		}
+
+		if op := queryOperatorAt(expr[i:]); op != "" {
+			tokens = append(tokens, queryToken{text: op, pos: i})
+			i += len(op)
+
+			continue
+		}
+
+		j := i
+		for j < len(expr) && !strings.ContainsRune(" \t\n\r()\"", rune(expr[j])) && queryOperatorAt(expr[j:]) == "" {
+			j++
+		}
+
+		tokens = append(tokens, queryToken{text: expr[i:j], pos: i})
+		i = j
+	}
+
+	return tokens, nil
+}
+
+func queryOperatorAt(s string) string {
+	for _, op := range queryOperators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+
+	return ""
+}
+
+// queryParser - recursive descent parser of Query expressions.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	depth  int
+	end    int // position reported at the end of the expression.
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{pos: p.end}, false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+// keyword - the next token is the unquoted keyword, it is consumed.
+func (p *queryParser) keyword(kw string) bool {
+	t, ok := p.peek()
+	if !ok || t.quoted || !strings.EqualFold(t.text, kw) {
+		return false
+	}
+
+	p.pos++
+
+	return true
+}
+
+func (p *queryParser) fail(t queryToken, format string, args ...any) error {
+	return fmt.Errorf("%w at %d: %s", errQuerySyntax, t.pos, fmt.Sprintf(format, args...))
+}
+
+// ParseQuery - compile the expression: predicates "field op value" combined with and, or, not and
+// parentheses, e.g. `domain = example.com or (org = "*суд*" and decision-date >= 2023-01-01 and not block = ip)`.
+// Fields are ip (the IP or a subnet containing it), subnet (IPs and subnets overlapping the CIDR), domain,
+// url (normalized), id, decision (SearchDecision hash), org (canonical organization, * matches any text),
+// entry, urgency, block (url, https, domain, domain-mask, ip) and decision-date, include-time, ts (YYYY-MM-DD).
+// Operators are = and !=, dates take <, <=, >, >= too. Values with spaces, parentheses or operators are quoted.
+func ParseQuery(expr string) (*RecordQuery, error) {
+	if len(expr) > queryMaxLen {
+		return nil, fmt.Errorf("%w: longer than %d", errQuerySyntax, queryMaxLen)
+	}
+
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens, end: len(expr)}
+
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := p.peek(); ok {
+		return nil, p.fail(t, "unexpected %q", t.text)
+	}
+
+	return &RecordQuery{root: root}, nil
+}
+
+func (p *queryParser) or() (queryNode, error) {
+	var nodes queryOr
+
+	for {
+		node, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+
+		if !p.keyword("or") {
+			break
+		}
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	return nodes, nil
+}
+
+func (p *queryParser) and() (queryNode, error) {
+	var nodes queryAnd
+
+	for {
+		node, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+
+		if !p.keyword("and") {
+			break
+		}
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	return nodes, nil
+}
+
+func (p *queryParser) not() (queryNode, error) {
+	if p.keyword("not") {
+		node, err := p.nested(p.not)
+		if err != nil {
+			return nil, err
+		}
+
+		return queryNot{node}, nil
+	}
+
+	return p.primary()
+}
+
+// nested - parse one level deeper.
+func (p *queryParser) nested(parse func() (queryNode, error)) (queryNode, error) {
+	if p.depth++; p.depth > queryMaxDepth {
+		t, _ := p.peek()
+
+		return nil, p.fail(t, "nested deeper than %d", queryMaxDepth)
+	}
+
+	defer func() { p.depth-- }()
+
+	return parse()
+}
+
+func (p *queryParser) primary() (queryNode, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.fail(t, "unexpected end")
+	}
+
+	if !t.quoted && t.text == "(" {
+		p.pos++
+
+		node, err := p.nested(p.or)
+		if err != nil {
+			return nil, err
+		}
+
+		if t, ok := p.peek(); !ok || t.quoted || t.text != ")" {
+			return nil, p.fail(t, "no closing parenthesis")
+		}
+
+		p.pos++
+
+		return node, nil
+	}
+
+	return p.predicate()
+}
+
+func (p *queryParser) predicate() (queryNode, error) {
+	field, _ := p.peek()
+	if field.quoted || field.text == ")" || queryOperatorAt(field.text) != "" {
+		return nil, p.fail(field, "field expected, got %q", field.text)
+	}
+
+	p.pos++
+
+	op, ok := p.peek()
+	if !ok || op.quoted || queryOperatorAt(op.text) != op.text {
+		return nil, p.fail(op, "operator expected after %s", field.text)
+	}
+
+	p.pos++
+
+	value, ok := p.peek()
+	if !ok || (!value.quoted && (value.text == "(" || value.text == ")" || queryOperatorAt(value.text) != "")) {
+		return nil, p.fail(value, "value expected after %s %s", field.text, op.text)
+	}
+
+	p.pos++
+
+	name := strings.ToLower(field.text)
+
+	if op.text != "=" && op.text != "!=" && !queryTimeField(name) {
+		return nil, p.fail(op, "%s takes = and != only", name)
+	}
+
+	negate := op.text == "!="
+	if negate {
+		op.text = "="
+	}
+
+	node, err := newQueryPredicate(name, op.text, value.text)
+	if err != nil {
+		return nil, p.fail(value, "%s", err.Error())
+	}
+
+	if negate {
+		return queryNot{node}, nil
+	}
+
+	return node, nil
+}
+
+// queryTimeField - the field is a date, compared by <, <=, >, >= too.
+func queryTimeField(field string) bool {
+	switch field {
+	case OrderDecisionDate, OrderIncludeTime, OrderTs:
+		return true
+	}
+
+	return false
+}
+
+// newQueryPredicate - predicate of the field, operator and value.
+func newQueryPredicate(field, op, value string) (queryNode, error) {
+	switch field {
+	case "ip":
+		ip, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad IP: %s", value)
+		}
+
+		ip = ip.Unmap()
+
+		return &indexedPredicate{ids: func(dump *Dump) ArrayIntSet {
+			idx := dump.subnet4Idx
+			if ip.Is6() {
+				idx = dump.subnet6Idx
+			}
+
+			ids, _ := dump.subnetHits(ip, idx)
+
+			return append(ids, dump.ipIDs(ip)...)
+		}}, nil
+	case "subnet":
+		network, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad subnet: %s", value)
+		}
+
+		return scanPredicate(func(pack *PackedContent) bool { return pack.overlaps(network.Masked()) }), nil
+	case SelectorDomain:
+		domain := NormalizeDomain(value)
+
+		return &indexedPredicate{ids: func(dump *Dump) ArrayIntSet { return dump.domainIdx[domain] }}, nil
+	case SelectorURL:
+		u := NormalizeURL(value)
+
+		return &indexedPredicate{ids: func(dump *Dump) ArrayIntSet { return dump.urlIdx[u] }}, nil
+	case MatchID:
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad id: %s", value)
+		}
+
+		return &indexedPredicate{ids: func(dump *Dump) ArrayIntSet { return ArrayIntSet{int32(id)} }}, nil
+	case SelectorDecision:
+		hash, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad decision hash: %s", value)
+		}
+
+		return &indexedPredicate{ids: func(dump *Dump) ArrayIntSet { return dump.decisionIdx[hash] }}, nil
+	case "org":
+		pattern := strings.ToLower(parser.NormalizeOrg(value))
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad org pattern: %s", value)
+		}
+
+		return scanPredicate(func(pack *PackedContent) bool {
+			ok, _ := path.Match(pattern, strings.ToLower(pack.Org))
+
+			return ok
+		}), nil
+	case "entry":
+		entryType, err := ParseEntryType(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return scanPredicate(func(pack *PackedContent) bool { return pack.EntryType == entryType }), nil
+	case "urgency":
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s type: %s", field, value)
+		}
+
+		return scanPredicate(func(pack *PackedContent) bool { return pack.UrgencyType == int32(n) }), nil
+	case "block":
+		blockType, ok := blockTypeNames[value]
+		if !ok {
+			return nil, fmt.Errorf("bad block type: %s", value)
+		}
+
+		return scanPredicate(func(pack *PackedContent) bool { return pack.BlockType == blockType }), nil
+	case OrderDecisionDate, OrderIncludeTime, OrderTs:
+		return newQueryTimePredicate(field, op, value)
+	}
+
+	return nil, fmt.Errorf("unknown field: %s", field)
+}
+
+// newQueryTimePredicate - range of the time index, the date is the whole day.
+func newQueryTimePredicate(field, op, value string) (queryNode, error) {
+	day := parser.ParseDecisionTime(value)
+	if day == 0 {
+		return nil, fmt.Errorf("bad date: %s", value)
+	}
+
+	const dayLen = 24 * 60 * 60
+
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+
+	switch op {
+	case "=":
+		from, to = day, day+dayLen-1
+	case "<":
+		to = day - 1
+	case "<=":
+		to = day + dayLen - 1
+	case ">":
+		from = day + dayLen
+	case ">=":
+		from = day
+	}
+
+	// records without ts are not in the ts index.
+	if field == OrderTs && from <= 0 {
+		from = 1
+	}
+
+	return &indexedPredicate{ids: func(dump *Dump) ArrayIntSet {
+		switch field {
+		case OrderDecisionDate:
+			return dump.decisionDateIdx.Range(from, to)
+		case OrderIncludeTime:
+			return dump.includeTimeIdx.Range(from, to)
+		}
+
+		return dump.tsIdx.Range(from, to)
+	}}, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestQuery tests Query expressions over indexed and scanned predicates.
+func TestQuery(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	for _, expr := range []string{
+		"", "domain", "domain =", "(domain = a", "domain = a)", "domain = a b", "foo = 1", "org < x", "block = nope",
+		"decision-date = 2001-13-01", "ip = 1.2.3", "not", `org = "x`, strings.Repeat("(", 40) + "id = 1" + strings.Repeat(")", 40),
+	} {
+		if _, err := ParseQuery(expr); !errors.Is(err, errQuerySyntax) {
+			t.Errorf("Bad query %q: %v\n", expr, err)
+		}
+	}
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	for expr, want := range map[string][]int32{
+		"domain = WWW.E02.TLD":                {222, 555},
+		"ip = 192.168.0.100":                  {111, 222, 333},
+		"ip = 10.4.1.1":                       {444},
+		"ip = fdaa:f::100 and not org = FIVE": {111, 222, 333, 444},
+		`org = "t*"`:                          {222, 333},
+		"block = ip OR domain = www.e02.tld":  {222, 333, 444, 555},
+		"decision-date >= 2001-01-01 and decision-date < 2001-01-05":                {333, 444},
+		"include-time = 2001-01-01 and entry = 1":                                   {111, 222, 333, 444, 555},
+		`id != 111 and (url = "http://www.e01.tld/cheese" or subnet = 10.4.0.0/24)`: {444},
+		"not (ip = 192.168.0.100 or urgency != 0)":                                  {444, 555},
+		"ts > 2001-01-01": nil,
+	} {
+		query, err := ParseQuery(expr)
+		if err != nil {
+			t.Errorf("%s: %s\n", expr, err.Error())
+
+			continue
+		}
+
+		if got := query.Records(CurrentDump); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %v, want %v\n", expr, got, want)
+		}
+	}
+
+	s := &server{}
+
+	resp, _ := s.Query(context.Background(), &pb.QueryRequest{Query: "ip = fdaa:f::100", Offset: 1, Limit: 2, Descending: true})
+	if resp.GetError() != "" || resp.GetTotal() != 5 || len(resp.GetResults()) != 2 || resp.GetResults()[0].GetId() != 444 ||
+		resp.GetResults()[0].GetMatchedBy().GetKind() != MatchQuery {
+		t.Errorf("Query: %v\n", resp)
+	}
+
+	if resp, _ := s.Query(context.Background(), &pb.QueryRequest{Query: "ip ="}); !strings.HasPrefix(resp.GetError(), SrvBadQuery) {
+		t.Errorf("Bad query: %v\n", resp)
+	}
+}
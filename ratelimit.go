@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimitSweep - how often idle buckets are dropped.
+const rateLimitSweep = time.Minute
+
+// RateLimiter - token bucket per source IP: burst requests at once, then rate per second.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+// tokenBucket - tokens of one source IP.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit - per source IP limiter of the public listeners, nil disables it. It is set once at startup.
+var RateLimit *RateLimiter
+
+// NewRateLimiter - rate requests per second per IP with the burst, burst below 1 means 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{rate: rate, burst: float64(burst), buckets: make(map[string]*tokenBucket)}
+}
+
+// clients - number of tracked source IPs.
+func (l *RateLimiter) clients() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+// Allow - take a token of the IP, false if there is none.
+func (l *RateLimiter) Allow(ip string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) > rateLimitSweep {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// sweep - drop buckets refilled to the burst, they are the same as new ones.
+func (l *RateLimiter) sweep(now time.Time) {
+	for ip, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, ip)
+		}
+	}
+
+	l.swept = now
+}
+
+// retryAfter - seconds until the next token.
+func (l *RateLimiter) retryAfter() int {
+	if l.rate <= 0 {
+		return 1
+	}
+
+	seconds := int(1/l.rate + 0.999)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return seconds
+}
+
+// ServerOptions - interceptors rejecting calls over the limit with RESOURCE_EXHAUSTED, health checks are not limited.
+func (l *RateLimiter) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := l.check(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := l.check(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+
+			return handler(srv, ss)
+		}),
+	}
+}
+
+func (l *RateLimiter) check(ctx context.Context, method string) error {
+	if auditSkipped(method) {
+		return nil
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	if l.Allow(sourceIP(p.Addr.String()), time.Now()) {
+		return nil
+	}
+
+	metricRateLimited.Add("grpc", 1)
+
+	return status.Error(codes.ResourceExhausted, SrvRateLimited)
+}
+
+// Handler - next with 429 Too Many Requests over the limit, probes are not limited.
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" || l.Allow(sourceIP(r.RemoteAddr), time.Now()) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		metricRateLimited.Add("http", 1)
+
+		w.Header().Set("Retry-After", strconv.Itoa(l.retryAfter()))
+		http.Error(w, SrvRateLimited, http.StatusTooManyRequests)
+	})
+}
+
+// sourceIP - host of the address, the address itself if there is no port.
+func sourceIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
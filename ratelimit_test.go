@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestRateLimiter tests token buckets per IP and idle bucket sweeping.
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(1, 2)
+	now := time.Now()
+
+	for i, want := range []bool{true, true, false} {
+		if l.Allow("192.0.2.1", now) != want {
+			t.Errorf("request %d: want %v", i, want)
+		}
+	}
+
+	if !l.Allow("192.0.2.2", now) {
+		t.Error("other IP is limited")
+	}
+
+	if !l.Allow("192.0.2.1", now.Add(time.Second)) || l.Allow("192.0.2.1", now.Add(time.Second)) {
+		t.Error("one token a second is expected")
+	}
+
+	l.Allow("192.0.2.3", now.Add(2*rateLimitSweep))
+
+	if l.clients() != 1 {
+		t.Errorf("idle buckets are kept: %d", l.clients())
+	}
+}
+
+// TestRateLimitListeners tests rejected gRPC and HTTP requests.
+func TestRateLimitListeners(t *testing.T) {
+	l := NewRateLimiter(0.001, 1)
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer(l.ServerOptions()...)
+	pb.RegisterCheckServer(srv, &server{})
+
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(listen.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCheckClient(conn)
+
+	if _, err := client.Ping(context.Background(), &pb.PingRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Ping(context.Background(), &pb.PingRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("gRPC: %v", err)
+	}
+
+	handler := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i, want := range []int{200, 429} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/feed/added.rss", nil)
+		r.RemoteAddr = "192.0.2.9:1234"
+
+		handler.ServeHTTP(w, r)
+
+		if w.Code != want {
+			t.Errorf("HTTP %d: %d", i, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/healthz", nil)
+	r.RemoteAddr = "192.0.2.9:1234"
+
+	handler.ServeHTTP(w, r)
+
+	if w.Code != 200 {
+		t.Errorf("probe is limited: %d", w.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// compressRaw - deflate the original <content>...</content> fragment, the writer is reused by the parse.
+func (s *parseStage) compressRaw(raw []byte) []byte {
+	var buf bytes.Buffer
+
+	if s.compressor == nil {
+		s.compressor, _ = flate.NewWriter(&buf, flate.BestSpeed)
+	} else {
+		s.compressor.Reset(&buf)
+	}
+
+	s.compressor.Write(raw)
+	s.compressor.Close()
+
+	return buf.Bytes()
+}
+
+// decompressRaw - inflate the stored fragment.
+func decompressRaw(packed []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(packed))
+	defer r.Close()
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("inflate: %w", err)
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestGetRawContent tests raw fragments are the bytes of the dump, not the converted ones.
+func TestGetRawContent(t *testing.T) {
+	defer func(dump *Dump, keep bool) { CurrentDump, ParseConfig.KeepRaw = dump, keep }(CurrentDump, ParseConfig.KeepRaw)
+
+	const content = `<content id="1" includeTime="2017-01-01T00:00:00" entryType="1" hash="1"><decision date="2017-01-01" number="1" org="Роскомнадзор"/><url>http://e1.tld/</url><ip>10.0.0.1</ip></content>`
+
+	encoded, err := charmap.Windows1251.NewEncoder().String(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dump := `<?xml version="1.0" encoding="windows-1251"?>` + "\n" +
+		`<reg:register updateTime="2017-01-01T00:00:00+03:00" updateTimeUrgently="2017-01-01T00:00:00" formatVersion="2.4" xmlns:reg="http://rsoc.ru" xmlns:tns="http://rsoc.ru">` + "\n" +
+		encoded + "\n</reg:register>\n"
+
+	CurrentDump, ParseConfig.KeepRaw = NewDump(), true
+
+	if err := Parse(strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	resp, _ := s.GetRawContent(context.Background(), &pb.IDRequest{Query: 1})
+	if resp.GetError() != "" || !bytes.Equal(resp.GetRaw(), []byte(encoded)) {
+		t.Errorf("Raw: %q, error: %s\n", resp.GetRaw(), resp.GetError())
+	}
+}
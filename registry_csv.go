@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// registryCSVFilename - export in the "Реестр" community format (z-i dump.csv).
+const registryCSVFilename = "dump.csv"
+
+// registryCSVSeparator - separator of values in one field.
+const registryCSVSeparator = " | "
+
+// RegistryCSV - export dump.csv after every parse.
+var RegistryCSV bool
+
+// RegistryCSVOrder - order of the records in dump.csv and urgent.csv, OrderID or OrderOrdinal
+// for the order of the registry file. It is set once at startup.
+var RegistryCSVOrder = OrderID
+
+// WriteRegistryCSV - write the records passing the filter to dump.csv in the dir, returns the records written.
+func WriteRegistryCSV(dir string, dump *Dump, filter *ExportFilter) (int, error) {
+	return writeRegistryCSVFile(dir+"/"+registryCSVFilename, dump, filter)
+}
+
+// writeRegistryCSVFile - write the records passing the filter in the dump.csv format to the file,
+// returns the records written.
+func writeRegistryCSVFile(filename string, dump *Dump, filter *ExportFilter) (int, error) {
+	tmpfilename := filename + "-tmp"
+
+	f, err := os.Create(tmpfilename)
+	if err != nil {
+		return 0, fmt.Errorf("create: %w", err)
+	}
+
+	n, err := writeRegistryCSV(f, dump, filter)
+	if err != nil {
+		f.Close()
+
+		return 0, fmt.Errorf("write: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tmpfilename, filename); err != nil {
+		return 0, fmt.Errorf("rename: %w", err)
+	}
+
+	return n, nil
+}
+
+// writeRegistryCSV - write the records as dump.csv of the z-i tooling: windows-1251,
+// "Updated: <time>" line, "# <provenance>" line, then one line per record "IPs;domain;URL;org;number;date".
+// Multiple values are separated with " | ", fields are not quoted as in the original. Returns the records written.
+func writeRegistryCSV(out io.Writer, dump *Dump, filter *ExportFilter) (int, error) {
+	dump.RLock()
+	utime, prov := dump.utime, dump.provenance()
+	ids := make([]int32, 0, len(dump.ContentIdx))
+	for id, pack := range dump.ContentIdx {
+		if filter.Match(pack) {
+			ids = append(ids, id)
+		}
+	}
+
+	ids, _ = dump.orderResults(ids, RegistryCSVOrder, OrderID, false)
+
+	packs := make([]*PackedContent, 0, len(ids))
+	for _, id := range ids {
+		packs = append(packs, dump.ContentIdx[id])
+	}
+	dump.RUnlock()
+
+	// records are never changed in place, so they can be read without the lock.
+
+	w := bufio.NewWriter(encoding.ReplaceUnsupported(charmap.Windows1251.NewEncoder()).Writer(out))
+
+	fmt.Fprintf(w, "Updated: %s\n", time.Unix(utime, 0).UTC().Format("2006-01-02 15:04:05 -0700"))
+	fmt.Fprintf(w, "# %s\n", prov)
+
+	n := 0
+
+	for _, pack := range packs {
+		record, err := pack.Record()
+		if err != nil {
+			logger.Error.Printf("Can't decode payload: %d: %s\n", pack.ID, err.Error())
+
+			continue
+		}
+
+		w.WriteString(registryCSVLine(record, filter))
+
+		n++
+	}
+
+	return n, w.Flush()
+}
+
+// registryCSVLine - line of the record, IPs are IPv4, IPv4 subnets, IPv6, IPv6 subnets
+// passing the address kinds of the filter.
+func registryCSVLine(record *Content, filter *ExportFilter) string {
+	ips := make([]string, 0, len(record.IP4)+len(record.Subnet4)+len(record.IP6)+len(record.Subnet6))
+
+	for _, ip4 := range record.IP4 {
+		if filter.Addr(ip4.IP4) {
+			ips = append(ips, ip4.IP4.String())
+		}
+	}
+
+	for _, subnet4 := range record.Subnet4 {
+		if filter.Address(subnet4.Subnet4) {
+			ips = append(ips, subnet4.Subnet4.String())
+		}
+	}
+
+	for _, ip6 := range record.IP6 {
+		if filter.Addr(ip6.IP6) {
+			ips = append(ips, ip6.IP6.String())
+		}
+	}
+
+	for _, subnet6 := range record.Subnet6 {
+		if filter.Address(subnet6.Subnet6) {
+			ips = append(ips, subnet6.Subnet6.String())
+		}
+	}
+
+	domains := make([]string, 0, len(record.Domain))
+	for _, domain := range record.Domain {
+		domains = append(domains, domain.Domain)
+	}
+
+	urls := make([]string, 0, len(record.URL))
+	for _, u := range record.URL {
+		urls = append(urls, u.URL)
+	}
+
+	return strings.Join([]string{
+		strings.Join(ips, registryCSVSeparator),
+		strings.Join(domains, registryCSVSeparator),
+		strings.Join(urls, registryCSVSeparator),
+		record.Decision.Org,
+		record.Decision.Number,
+		record.Decision.Date,
+	}, ";") + "\n"
+}
+
+// ExportRegistryCSV - write dump.csv of the new generation, if enabled, the records written
+// and true if it is written. Only records passing ExportFilterConfig are written.
+func ExportRegistryCSV(dir string) (int, bool) {
+	if !RegistryCSV {
+		return 0, false
+	}
+
+	n, err := WriteRegistryCSV(dir, CurrentDump, ExportFilterConfig)
+	if err != nil {
+		logger.Error.Printf("Can't save %s: %s\n", registryCSVFilename, err.Error())
+
+		return 0, false
+	}
+
+	return n, true
+}
+
+// registryCSVHandler - /dump.csv, the last exported file, or /dump.csv?filter=spec
+// made of the current generation with the filter instead of -export-filter.
+func registryCSVHandler(dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !RegistryCSV {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if !r.URL.Query().Has("filter") {
+			w.Header().Set("Content-Type", "text/csv; charset=windows-1251")
+			http.ServeFile(w, r, dir+"/"+registryCSVFilename)
+
+			return
+		}
+
+		filter, err := ParseExportFilter(r.URL.Query().Get("filter"))
+		if err != nil {
+			http.Error(w, SrvBadFilter+": "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if CurrentDump == nil || CurrentDump.utime == 0 {
+			http.Error(w, SrvDataNotReady, http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=windows-1251")
+
+		if _, err := writeRegistryCSV(w, CurrentDump, filter); err != nil {
+			logger.Debug.Printf("Can't send %s: %s\n", registryCSVFilename, err.Error())
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// TestRegistryCSV tests dump.csv export and download.
+func TestRegistryCSV(t *testing.T) {
+	defer func(dump *Dump, enabled bool) { CurrentDump, RegistryCSV = dump, enabled }(CurrentDump, RegistryCSV)
+
+	CurrentDump = NewDump()
+	RegistryCSV = true
+
+	org, err := charmap.Windows1251.NewEncoder().String("Роскомнадзор")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xml := strings.Replace(xml01, `org="ONE"`, `org="`+org+`"`, 1)
+	if err := Parse(strings.NewReader(xml)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	ExportRegistryCSV(dir)
+
+	dat, err := os.ReadFile(dir + "/" + registryCSVFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := charmap.Windows1251.NewDecoder().String(string(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) != 7 || lines[0] != "Updated: 2010-12-31 22:01:01 +0000" || lines[1] != "# "+CurrentDump.Provenance().String() {
+		t.Fatalf("lines: %q", lines)
+	}
+
+	want := "192.168.1.11 | 192.168.0.100 | 10.1.1.1 | fd11:1::1 | fd11:11::1 | fdaa:f::100;www.e01.tld;" +
+		"https://www.e01.tld/sex | http://www.e01.tld/cheese | http://www.e01.tld/slip;Роскомнадзор;1/1/11-1111;2000-01-01"
+	if lines[2] != want {
+		t.Errorf("111: %s", lines[2])
+	}
+
+	if !strings.HasPrefix(lines[5], "192.168.4.44 | 192.168.4.100 | 10.4.4.4 | 10.4.0.0/16 | fd44:4::1") {
+		t.Errorf("444: %s", lines[5])
+	}
+
+	w := httptest.NewRecorder()
+	registryCSVHandler(dir)(w, httptest.NewRequest("GET", "/"+registryCSVFilename, nil))
+
+	if w.Code != 200 || w.Body.String() != string(dat) || !strings.Contains(w.Header().Get("Content-Type"), "windows-1251") {
+		t.Errorf("download: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+// TestRecordOrdinals tests records keep their positions in the dump file for ordering and snapshots.
+func TestRecordOrdinals(t *testing.T) {
+	defer func(dump *Dump, order string) { CurrentDump, RegistryCSVOrder = dump, order }(CurrentDump, RegistryCSVOrder)
+
+	CurrentDump = NewDump()
+
+	// 555 goes first, the duplicate of 111 keeps the first position.
+	head := xml01[:strings.Index(xml01, `<content id="111"`)]
+	first := xml01[strings.Index(xml01, `<content id="555"`):strings.Index(xml01, `</reg:register>`)]
+	rest := xml01[strings.Index(xml01, `<content id="111"`):strings.Index(xml01, `<content id="555"`)]
+	dup := xml01[strings.Index(xml01, `<content id="111"`):strings.Index(xml01, `<content id="222"`)]
+
+	if err := Parse(strings.NewReader(head + first + rest + dup + `</reg:register>`)); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, ok := CurrentDump.orderResults([]int32{111, 222, 333, 444, 555}, OrderOrdinal, OrderID, false)
+	if !ok || fmt.Sprint(ids) != "[555 111 222 333 444]" || CurrentDump.ContentIdx[555].newPbContent(Match{}).GetOrdinal() != 1 {
+		t.Errorf("Ordered: %v %v\n", ids, CurrentDump.ordinals)
+	}
+
+	RegistryCSVOrder = OrderOrdinal
+
+	var b strings.Builder
+	if n, err := writeRegistryCSV(&b, CurrentDump, nil); err != nil || n != 5 {
+		t.Fatal(n, err)
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	if len(lines) != 8 || !strings.Contains(lines[2], ";FIVE;") || !strings.Contains(lines[3], ";ONE;") {
+		t.Errorf("dump.csv: %q\n", lines)
+	}
+
+	restored := NewDump()
+
+	CurrentDump.RLock()
+	snap := CurrentDump.snapshot()
+	CurrentDump.RUnlock()
+
+	if err := restored.RestoreSnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.ordinals.Get(555) != 1 || restored.ordinals.Get(444) != 5 {
+		t.Errorf("Restored: %v\n", restored.ordinals)
+	}
+}
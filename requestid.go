@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// RequestIDHeader - metadata key of the request id, it is echoed in the response header.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLen - longer client ids are replaced, they go to logs as is.
+const maxRequestIDLen = 64
+
+// requestIDKey - context key of the request id.
+type requestIDKey struct{}
+
+// RequestID - id of the request, "-" outside of a request.
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+
+	return "-"
+}
+
+// incomingRequestID - id of the client if it is sane, or a new one.
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDHeader); len(v) > 0 && validRequestID(v[0]) {
+			return v[0]
+		}
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
+
+// validRequestID - short and printable, nothing to break log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestIDOptions - interceptors setting the request id, they go first so others see it.
+func requestIDOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(requestIDUnary),
+		grpc.ChainStreamInterceptor(requestIDStream),
+	}
+}
+
+func requestIDUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id := incomingRequestID(ctx)
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+
+	resp, err := handler(ctx, req)
+
+	logRequestError(id, info.FullMethod, resp, err)
+
+	return resp, err
+}
+
+func requestIDStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := incomingRequestID(ss.Context())
+
+	ss.SetHeader(metadata.Pairs(RequestIDHeader, id))
+
+	err := handler(srv, &requestIDStreamCtx{ServerStream: ss, ctx: context.WithValue(ss.Context(), requestIDKey{}, id)})
+
+	logRequestError(id, info.FullMethod, nil, err)
+
+	return err
+}
+
+// requestIDStreamCtx - stream with the request id in its context.
+type requestIDStreamCtx struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDStreamCtx) Context() context.Context {
+	return s.ctx
+}
+
+// logRequestError - failed calls and in-band errors of responses with the request id.
+func logRequestError(id, method string, resp interface{}, err error) {
+	if err != nil {
+		logger.Debug.Printf("[%s] %s failed: %s: %s\n", id, method, status.Code(err), err.Error())
+
+		return
+	}
+
+	if r, ok := resp.(interface{ GetError() string }); ok && r.GetError() != "" {
+		logger.Debug.Printf("[%s] %s error: %s\n", id, method, r.GetError())
+	}
+}
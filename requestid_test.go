@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestRequestID tests request ids of responses and audit records.
+func TestRequestID(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &auditBuffer{}
+
+	opts, err := GRPCServerOptions{}.ServerOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer(append(opts, NewAuditLog(buf, "").ServerOptions()...)...)
+	pb.RegisterCheckServer(srv, &server{})
+
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(listen.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCheckClient(conn)
+
+	for sent, check := range map[string]func(string) bool{
+		"client-42": func(id string) bool { return id == "client-42" },
+		"":          func(id string) bool { return len(id) == 16 },
+		"bad id":    func(id string) bool { return len(id) == 16 },
+	} {
+		ctx := context.Background()
+		if sent != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, sent)
+		}
+
+		var header metadata.MD
+
+		if _, err := client.SearchID(ctx, &pb.IDRequest{Query: 111}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+
+		ids := header.Get(RequestIDHeader)
+		if len(ids) != 1 || !check(ids[0]) {
+			t.Errorf("%q: %v", sent, ids)
+
+			continue
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+		record := AuditRecord{}
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &record); err != nil {
+			t.Fatal(err)
+		}
+
+		if record.ReqID != ids[0] {
+			t.Errorf("%q: audit %s, response %s", sent, record.ReqID, ids[0])
+		}
+	}
+}
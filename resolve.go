@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// ResolveOptions - opt-in resolution of the hosts of domain-only records, for operators
+// enforcing by IP. The view is derived: it is never part of the index or the searches.
+type ResolveOptions struct {
+	Interval time.Duration // between rounds, 0 disables.
+	MaxHosts int           // hosts resolved in one round, the rest are left out.
+	TTL      time.Duration // resolved addresses are reused for this long.
+	Timeout  time.Duration // of one lookup.
+	Workers  int           // concurrent lookups.
+}
+
+// ResolveConfig - resolution configuration, it is set once at startup.
+var ResolveConfig = ResolveOptions{MaxHosts: 10000, TTL: time.Hour, Timeout: 5 * time.Second, Workers: 16}
+
+// resolveLookup - resolver of a host, replaced in tests.
+var resolveLookup = net.DefaultResolver.LookupNetIP
+
+// ResolvedHost - addresses a host of domain-only records resolved to.
+type ResolvedHost struct {
+	Host     string
+	Addrs    []netip.Addr // ordered.
+	Resolved time.Time
+	Err      string  // the last lookup failed, Addrs are of the previous one, if any.
+	IDs      []int32 // records of the host, ordered.
+}
+
+// ResolvedView - hosts of domain-only records with their current addresses.
+type ResolvedView struct {
+	sync.RWMutex
+	hosts     map[string]*ResolvedHost
+	byAddr    map[netip.Addr][]string
+	round     time.Time // end of the last round, zero before the first one.
+	truncated int       // hosts left out of the last round by MaxHosts.
+}
+
+// Resolved - the current view.
+var Resolved = &ResolvedView{}
+
+// domainOnlyHosts - hosts of the domains and URLs of the records without IPs and subnets,
+// with their records. Call it under read lock.
+func (dump *Dump) domainOnlyHosts() map[string][]int32 {
+	hosts := make(map[string][]int32)
+
+	add := func(host string, id int32) {
+		if host == "" {
+			return
+		}
+
+		// literal addresses are enforced as they are.
+		if _, err := netip.ParseAddr(host); err == nil {
+			return
+		}
+
+		ids := hosts[host]
+		if len(ids) > 0 && ids[len(ids)-1] == id {
+			return
+		}
+
+		hosts[host] = append(ids, id)
+	}
+
+	ids := make([]int32, 0, len(dump.ContentIdx))
+	for id := range dump.ContentIdx {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		pack := dump.ContentIdx[id]
+		if len(pack.IP4)+len(pack.IP6)+len(pack.Subnet4)+len(pack.Subnet6) > 0 {
+			continue
+		}
+
+		for _, d := range pack.Domain {
+			add(NormalizeDomain(d.Domain), id)
+		}
+
+		for _, u := range pack.URL {
+			if parsed, err := url.Parse(NormalizeURL(u.URL)); err == nil {
+				add(NormalizeDomain(parsed.Hostname()), id)
+			}
+		}
+	}
+
+	return hosts
+}
+
+// Refresh - resolve the hosts, reusing addresses younger than TTL, and replace the view.
+// Hosts over MaxHosts are left out in the host order.
+func (v *ResolvedView) Refresh(ctx context.Context, hosts map[string][]int32, opts ResolveOptions, now time.Time) {
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+
+	sort.Strings(names)
+
+	truncated := 0
+	if opts.MaxHosts > 0 && len(names) > opts.MaxHosts {
+		truncated = len(names) - opts.MaxHosts
+		names = names[:opts.MaxHosts]
+	}
+
+	v.RLock()
+	prev := v.hosts
+	v.RUnlock()
+
+	next := make(map[string]*ResolvedHost, len(names))
+
+	var stale []*ResolvedHost
+
+	for _, host := range names {
+		h := &ResolvedHost{Host: host, IDs: hosts[host]}
+
+		if old, ok := prev[host]; ok {
+			h.Addrs, h.Resolved, h.Err = old.Addrs, old.Resolved, old.Err
+		}
+
+		if h.Resolved.IsZero() || now.Sub(h.Resolved) >= opts.TTL {
+			stale = append(stale, h)
+		}
+
+		next[host] = h
+	}
+
+	failed := resolveHosts(ctx, stale, opts, now)
+
+	byAddr := make(map[netip.Addr][]string)
+
+	for _, host := range names {
+		for _, addr := range next[host].Addrs {
+			byAddr[addr] = append(byAddr[addr], host)
+		}
+	}
+
+	v.Lock()
+	v.hosts, v.byAddr, v.round, v.truncated = next, byAddr, now, truncated
+	v.Unlock()
+
+	metricResolvedHosts.Set(int64(len(next)))
+	metricResolveFailures.Set(int64(failed))
+
+	logger.Info.Printf("Resolved hosts: %d, looked up: %d, failed: %d, left out: %d\n", len(next), len(stale), failed, truncated)
+}
+
+// resolveHosts - look up the hosts by the workers, returns the failed lookups count.
+// A failed lookup keeps the previous addresses.
+func resolveHosts(ctx context.Context, list []*ResolvedHost, opts ResolveOptions, now time.Time) int {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	queue := make(chan *ResolvedHost)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for h := range queue {
+				lookupCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+				addrs, err := resolveLookup(lookupCtx, "ip", h.Host)
+				cancel()
+
+				if err != nil {
+					h.Err = err.Error()
+
+					mu.Lock()
+					failed++
+					mu.Unlock()
+
+					continue
+				}
+
+				for i := range addrs {
+					addrs[i] = addrs[i].Unmap()
+				}
+
+				sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+
+				h.Addrs, h.Resolved, h.Err = addrs, now, ""
+			}
+		}()
+	}
+
+	for _, h := range list {
+		if ctx.Err() != nil {
+			break
+		}
+
+		queue <- h
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return failed
+}
+
+// Hosts - resolved hosts ordered by name, the round time and the hosts left out of it.
+func (v *ResolvedView) Hosts() ([]*ResolvedHost, time.Time, int) {
+	v.RLock()
+	defer v.RUnlock()
+
+	list := make([]*ResolvedHost, 0, len(v.hosts))
+	for _, h := range v.hosts {
+		list = append(list, h)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Host < list[j].Host })
+
+	return list, v.round, v.truncated
+}
+
+// Host - the resolved host, nil if it is not of a domain-only record.
+func (v *ResolvedView) Host(host string) *ResolvedHost {
+	v.RLock()
+	defer v.RUnlock()
+
+	return v.hosts[host]
+}
+
+// Addr - resolved hosts with the address, ordered by name.
+func (v *ResolvedView) Addr(addr netip.Addr) []*ResolvedHost {
+	v.RLock()
+	defer v.RUnlock()
+
+	list := make([]*ResolvedHost, 0, len(v.byAddr[addr.Unmap()]))
+	for _, host := range v.byAddr[addr.Unmap()] {
+		list = append(list, v.hosts[host])
+	}
+
+	return list
+}
+
+// ResolveLoop - resolve the hosts of the current dump every ResolveConfig.Interval.
+func ResolveLoop(done chan<- struct{}, kill <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-kill:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// the first round waits for the first dump.
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			next := ResolveConfig.Interval
+
+			if CurrentDump != nil && CurrentDump.utime > 0 {
+				CurrentDump.RLock()
+				hosts := CurrentDump.domainOnlyHosts()
+				CurrentDump.RUnlock()
+
+				Resolved.Refresh(ctx, hosts, ResolveConfig, time.Now())
+			} else {
+				next = time.Second
+			}
+
+			timer.Reset(next)
+		case <-kill:
+			close(done)
+
+			return
+		}
+	}
+}
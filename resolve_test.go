@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestResolvedView tests hosts of domain-only records, the lookups cache and ResolveHosts.
+func TestResolvedView(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+	defer func(lookup func(context.Context, string, string) ([]netip.Addr, error)) { resolveLookup = lookup }(resolveLookup)
+	defer func(opts ResolveOptions) { ResolveConfig = opts }(ResolveConfig)
+	defer func(view *ResolvedView) { Resolved = view }(Resolved)
+
+	CurrentDump = NewDump()
+	Resolved = &ResolvedView{}
+
+	xml := strings.Replace(xml01, `</reg:register>`, `<content id="666" includeTime="2001-01-01T06:06:06" entryType="1" blockType="domain-mask" hash="SSSS">
+        <decision date="2001-01-06" number="6" org="SIX"/>
+        <domain><![CDATA[*.E06.tld]]></domain>
+        <url><![CDATA[https://www.e06.tld:8443/x]]></url>
+        <url><![CDATA[http://203.0.113.9/y]]></url>
+</content>
+<content id="777" includeTime="2001-01-01T07:07:07" entryType="1" blockType="default" hash="TTTT">
+        <decision date="2001-01-07" number="7" org="SEVEN"/>
+        <url><![CDATA[http://www.e06.tld/z]]></url>
+        <url><![CDATA[https://www.e07.tld/]]></url>
+</content>
+</reg:register>`, 1)
+
+	if err := Parse(strings.NewReader(xml)); err != nil {
+		t.Fatal(err)
+	}
+
+	CurrentDump.RLock()
+	hosts := CurrentDump.domainOnlyHosts()
+	CurrentDump.RUnlock()
+
+	if len(hosts) != 3 || len(hosts["e06.tld"]) != 1 || len(hosts["www.e06.tld"]) != 2 || hosts["www.e07.tld"][0] != 777 {
+		t.Fatalf("Hosts: %v", hosts)
+	}
+
+	var lookups atomic.Int32
+
+	down := false
+	resolveLookup = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		lookups.Add(1)
+
+		if host == "www.e07.tld" || down {
+			return nil, errors.New("no such host")
+		}
+
+		return []netip.Addr{netip.MustParseAddr("::ffff:198.51.100.6"), netip.MustParseAddr("2001:db8::6")}, nil
+	}
+
+	opts := ResolveOptions{Interval: time.Minute, TTL: time.Hour, Timeout: time.Second, Workers: 2}
+	ResolveConfig = opts
+	now := time.Unix(1700000000, 0)
+
+	s := &server{}
+
+	if resp, _ := s.ResolveHosts(context.Background(), &pb.ResolveRequest{}); resp.GetError() != SrvDataNotReady {
+		t.Errorf("Before the first round: %q", resp.GetError())
+	}
+
+	Resolved.Refresh(context.Background(), hosts, opts, now)
+
+	if h := Resolved.Host("www.e06.tld"); h == nil || len(h.Addrs) != 2 || h.Addrs[0].String() != "198.51.100.6" || !h.Resolved.Equal(now) {
+		t.Errorf("www.e06.tld: %+v", h)
+	}
+
+	if h := Resolved.Host("www.e07.tld"); h == nil || h.Err == "" || len(h.Addrs) != 0 {
+		t.Errorf("www.e07.tld: %+v", h)
+	}
+
+	if list := Resolved.Addr(netip.MustParseAddr("198.51.100.6")); len(list) != 2 || list[0].Host != "e06.tld" {
+		t.Errorf("By address: %d hosts", len(list))
+	}
+
+	// fresh addresses are reused, failed lookups keep the previous addresses.
+	lookups.Store(0)
+	down = true
+
+	Resolved.Refresh(context.Background(), hosts, opts, now.Add(time.Minute))
+
+	if lookups.Load() != 1 {
+		t.Errorf("Lookups of fresh hosts: %d", lookups.Load())
+	}
+
+	Resolved.Refresh(context.Background(), hosts, opts, now.Add(2*time.Hour))
+
+	if h := Resolved.Host("e06.tld"); len(h.Addrs) != 2 || h.Err == "" || !h.Resolved.Equal(now) {
+		t.Errorf("Failed lookup: %+v", h)
+	}
+
+	resp, _ := s.ResolveHosts(context.Background(), &pb.ResolveRequest{Ip: "2001:db8::6", Limit: 1})
+	if resp.GetError() != "" || resp.GetTotal() != 2 || len(resp.GetHosts()) != 1 || resp.GetHosts()[0].GetResolved() != now.Unix() ||
+		strings.Join(resp.GetHosts()[0].GetIps(), " ") != "198.51.100.6 2001:db8::6" {
+		t.Errorf("ResolveHosts by address: %v", resp)
+	}
+
+	resp, _ = s.ResolveHosts(context.Background(), &pb.ResolveRequest{Host: "WWW.E06.TLD"})
+	if len(resp.GetHosts()) != 1 || len(resp.GetHosts()[0].GetIds()) != 2 {
+		t.Errorf("ResolveHosts by host: %v", resp)
+	}
+
+	if resp, _ := s.ResolveHosts(context.Background(), &pb.ResolveRequest{Ip: "bad"}); resp.GetError() != SrvBadSelector {
+		t.Errorf("Bad address: %q", resp.GetError())
+	}
+
+	opts.MaxHosts = 1
+	Resolved.Refresh(context.Background(), hosts, opts, now.Add(3*time.Hour))
+
+	if list, _, leftOut := Resolved.Hosts(); len(list) != 1 || leftOut != 2 || list[0].Host != "e06.tld" {
+		t.Errorf("Max hosts: %d hosts, %d left out", len(list), leftOut)
+	}
+
+	ResolveConfig.Interval = 0
+
+	if resp, _ := s.ResolveHosts(context.Background(), &pb.ResolveRequest{}); resp.GetError() != SrvNoResolve {
+		t.Errorf("Disabled: %q", resp.GetError())
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Refresh stages retried within the budget.
+const (
+	RetryLastID = "last-id" // GetLastDumpID or the z-i mirror pull.
+	RetryFetch  = "fetch"   // FetchAndUnzip: download and extraction.
+)
+
+// RetryOptions - retries of transient refresh failures within one poll cycle.
+type RetryOptions struct {
+	Attempts int           // retries of all stages of one refresh together, 0 disables.
+	Delay    time.Duration // delay before the first retry, doubled for the next ones.
+	MaxDelay time.Duration // cap of the delay.
+}
+
+// RetryConfig - retry configuration, it is set once at startup.
+var RetryConfig = RetryOptions{Attempts: 3, Delay: 2 * time.Second, MaxDelay: 30 * time.Second}
+
+// RetryBudget - retries left in one refresh, shared by its stages, so a flapping
+// upstream can't hold the poller past the budget. Cancelled context stops the retries.
+type RetryBudget struct {
+	ctx   context.Context
+	opts  RetryOptions
+	left  int
+	delay time.Duration
+}
+
+// NewRetryBudget - budget of one refresh.
+func NewRetryBudget(ctx context.Context, opts RetryOptions) *RetryBudget {
+	return &RetryBudget{ctx: ctx, opts: opts, left: opts.Attempts, delay: opts.Delay}
+}
+
+// Left - retries left.
+func (b *RetryBudget) Left() int {
+	return b.left
+}
+
+// Do - run the stage, retry transient failures while the budget lasts, the last error otherwise.
+func (b *RetryBudget) Do(stage string, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil || !retryable(err) || b.left <= 0 || b.ctx.Err() != nil {
+			return err
+		}
+
+		wait := b.jitter()
+
+		logger.Warning.Printf("Retry %s in %s (%d left): %s\n", stage, wait.Round(time.Millisecond), b.left-1, err.Error())
+
+		b.left--
+		metricRefreshRetries.Add(stage, 1)
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-b.ctx.Done():
+			timer.Stop()
+
+			return err
+		}
+	}
+}
+
+// jitter - the next delay, random in [delay/2, delay), and double the delay up to the cap.
+func (b *RetryBudget) jitter() time.Duration {
+	d := b.delay
+
+	b.delay *= 2
+	if b.opts.MaxDelay > 0 && b.delay > b.opts.MaxDelay {
+		b.delay = b.opts.MaxDelay
+	}
+
+	if d <= 1 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2))) //nolint:gosec // not a secret.
+}
+
+// ErrTransient - failure of a stage which may be gone on the next attempt, but is not a network
+// error of this process: the sandboxed fetch and the git of the z-i mirror.
+var ErrTransient = errors.New("transient failure")
+
+// retryable - the failure may be gone on the next attempt: network errors, timeouts and 5xx answers.
+// Other answers, missing or corrupt archives, oversized dumps, injected faults and cancellation are final.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTransient)
+}
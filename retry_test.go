@@ -0,0 +1,100 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestRetryBudget tests retries shared by the stages, final failures and cancellation.
+func TestRetryBudget(t *testing.T) {
+	opts := RetryOptions{Attempts: 3, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	transient := fmt.Errorf("do request: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+
+	budget := NewRetryBudget(context.Background(), opts)
+
+	calls := 0
+	flapping := func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+
+		return nil
+	}
+
+	if err := budget.Do(RetryLastID, flapping); err != nil || calls != 3 || budget.Left() != 1 {
+		t.Errorf("flapping: error %v, calls %d, left %d", err, calls, budget.Left())
+	}
+
+	calls = 0
+	failing := func() error {
+		calls++
+
+		return transient
+	}
+
+	if err := budget.Do(RetryFetch, failing); !errors.Is(err, transient) || calls != 2 || budget.Left() != 0 {
+		t.Errorf("budget is shared: error %v, calls %d, left %d", err, calls, budget.Left())
+	}
+
+	calls = 0
+	tooBig := func() error {
+		calls++
+
+		return fmt.Errorf("sandbox: %w", ErrTooBig)
+	}
+
+	if err := NewRetryBudget(context.Background(), opts).Do(RetryFetch, tooBig); !errors.Is(err, ErrTooBig) || calls != 1 {
+		t.Errorf("too big is final: error %v, calls %d", err, calls)
+	}
+
+	for _, final := range []error{
+		fmt.Errorf("fetch: %w", &HTTPStatusError{Code: 404}),
+		fmt.Errorf("extract: %w", ErrNoDumpEntry),
+		fmt.Errorf("extract: %w", zip.ErrFormat),
+		fmt.Errorf("fetch: %w", ErrInjectedFault),
+	} {
+		calls = 0
+
+		if err := NewRetryBudget(context.Background(), opts).Do(RetryFetch, func() error { calls++; return final }); err != final || calls != 1 {
+			t.Errorf("%v is final: error %v, calls %d", final, err, calls)
+		}
+	}
+
+	for _, again := range []error{
+		fmt.Errorf("fetch: %w", &HTTPStatusError{Code: 503}),
+		fmt.Errorf("sandbox: %w", ErrTransient),
+		context.DeadlineExceeded,
+	} {
+		if !retryable(again) {
+			t.Errorf("%v must be retried", again)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls = 0
+	cancelling := func() error {
+		calls++
+		cancel()
+
+		return transient
+	}
+
+	if err := NewRetryBudget(ctx, opts).Do(RetryFetch, cancelling); !errors.Is(err, transient) || calls != 1 {
+		t.Errorf("cancelled: error %v, calls %d", err, calls)
+	}
+
+	budget = NewRetryBudget(context.Background(), RetryOptions{Attempts: 5, Delay: 4 * time.Second, MaxDelay: 10 * time.Second})
+
+	for i, limit := range []time.Duration{4, 8, 10, 10} {
+		if d := budget.jitter(); d < limit*time.Second/2 || d >= limit*time.Second {
+			t.Errorf("delay %d: %s, limit %ds", i, d, limit)
+		}
+	}
+}
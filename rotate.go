@@ -0,0 +1,202 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// RotateOptions - retention of old dumps in the dump cache dir.
+type RotateOptions struct {
+	Keep     int   // number of gzipped old dump.xml files, 0 - don't keep.
+	MaxBytes int64 // max total size of the kept files, 0 - unlimited.
+}
+
+// RotateConfig - retention configuration, it is set once at startup.
+var RotateConfig RotateOptions
+
+const (
+	archivePrefix = "dump-"
+	archiveSuffix = ".xml.gz"
+)
+
+// DumpRotation - dump.xml which is about to be replaced. It is kept open, the new one replaces it
+// by rename, so it is archived only when the new one is in place, see Rotate.
+type DumpRotation struct {
+	dir  string
+	file *os.File
+	info os.FileInfo
+}
+
+// StartRotation - open the current dump.xml of the dir, nil if there is nothing to archive.
+func StartRotation(dir string) *DumpRotation {
+	if RotateConfig.Keep <= 0 {
+		return nil
+	}
+
+	f, err := os.Open(dir + "/dump.xml")
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error.Printf("Can't open dump to rotate: %s\n", err.Error())
+		}
+
+		return nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		logger.Error.Printf("Can't stat dump to rotate: %s\n", err.Error())
+		f.Close()
+
+		return nil
+	}
+
+	return &DumpRotation{dir: dir, file: f, info: info}
+}
+
+// Rotate - gzip the replaced dump.xml as the dump id and drop the oldest archives.
+// Nothing is archived if dump.xml is not replaced. The dump without id is named by its mtime,
+// an archive which already exists is not written again.
+func (r *DumpRotation) Rotate(id string) error {
+	if r == nil {
+		return nil
+	}
+
+	if info, err := os.Stat(r.dir + "/dump.xml"); err == nil && os.SameFile(info, r.info) {
+		return nil
+	}
+
+	if id == "" {
+		id = fmt.Sprintf("%d", r.info.ModTime().Unix())
+	}
+
+	name := fmt.Sprintf("%s/%s%s%s", r.dir, archivePrefix, id, archiveSuffix)
+
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		if err := gzipFile(r.file, name); err != nil {
+			return fmt.Errorf("archive: %w", err)
+		}
+	}
+
+	return pruneArchives(r.dir)
+}
+
+// Close - release the replaced dump.xml.
+func (r *DumpRotation) Close() {
+	if r != nil {
+		r.file.Close()
+	}
+}
+
+// pruneArchives - keep last RotateConfig.Keep archives not bigger than RotateConfig.MaxBytes in total.
+func pruneArchives(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, archivePrefix+"*"+archiveSuffix))
+	if err != nil {
+		return fmt.Errorf("glob: %w", err)
+	}
+
+	type archive struct {
+		name  string
+		size  int64
+		mtime time.Time
+	}
+
+	archives := make([]archive, 0, len(files))
+
+	for _, name := range files {
+		fi, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+
+		archives = append(archives, archive{name: name, size: fi.Size(), mtime: fi.ModTime()})
+	}
+
+	// newest first.
+	sort.Slice(archives, func(i, j int) bool { return archives[i].mtime.After(archives[j].mtime) })
+
+	var total int64
+
+	for i, a := range archives {
+		total += a.size
+
+		if i < RotateConfig.Keep && (RotateConfig.MaxBytes <= 0 || total <= RotateConfig.MaxBytes) {
+			continue
+		}
+
+		logger.Info.Printf("Remove old dump: %s\n", a.name)
+
+		err := os.Remove(a.name)
+		if err != nil {
+			logger.Error.Printf("Can't remove old dump: %s\n", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func gzipFile(in io.Reader, dst string) error {
+	tfn := dst + "-tmp"
+
+	out, err := os.Create(tfn)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+
+	_, err = io.Copy(zw, in)
+	if err != nil {
+		return fmt.Errorf("compress: %w", err)
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return fmt.Errorf("gzip: %w", err)
+	}
+
+	err = out.Close()
+	if err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	err = os.Rename(tfn, dst)
+	if err != nil {
+		return fmt.Errorf("file rename: %w", err)
+	}
+
+	return nil
+}
+
+// CleanupOrphans - remove temp files left by interrupted refreshes.
+func CleanupOrphans(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Error.Printf("Can't read dump dir: %s\n", err.Error())
+
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, "-tmp") || strings.HasSuffix(name, "-temp")) {
+			continue
+		}
+
+		logger.Warning.Printf("Remove orphaned temp file: %s\n", name)
+
+		err := os.Remove(filepath.Join(dir, name))
+		if err != nil {
+			logger.Error.Printf("Can't remove temp file: %s\n", err.Error())
+		}
+	}
+}
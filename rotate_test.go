@@ -0,0 +1,103 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDumpRotation tests only a replaced dump.xml is archived, once, and old archives are pruned.
+func TestDumpRotation(t *testing.T) {
+	defer func(config RotateOptions) { RotateConfig = config }(RotateConfig)
+
+	RotateConfig = RotateOptions{Keep: 2}
+
+	dir := t.TempDir()
+
+	replace := func(content string) {
+		t.Helper()
+
+		if err := os.WriteFile(dir+"/dump.xml-temp", []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Rename(dir+"/dump.xml-temp", dir+"/dump.xml"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archives := func() []string {
+		t.Helper()
+
+		files, err := filepath.Glob(filepath.Join(dir, archivePrefix+"*"+archiveSuffix))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return files
+	}
+
+	// nothing to archive yet.
+	if rotation := StartRotation(dir); rotation != nil {
+		t.Fatalf("Rotation without dump.xml\n")
+	}
+
+	replace("first")
+
+	// the fetch failed, dump.xml is the same.
+	rotation := StartRotation(dir)
+	if err := rotation.Rotate("1"); err != nil || len(archives()) != 0 {
+		t.Fatalf("Not replaced dump is archived: %v %v\n", err, archives())
+	}
+
+	rotation.Close()
+
+	rotation = StartRotation(dir)
+	replace("second")
+
+	if err := rotation.Rotate("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	rotation.Close()
+
+	f, err := os.Open(dir + "/" + archivePrefix + "1" + archiveSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ := io.ReadAll(zr); string(b) != "first" {
+		t.Errorf("Archived: %q\n", b)
+	}
+
+	// without id the archive is named by the mtime of the replaced dump.
+	for _, content := range []string{"third", "fourth"} {
+		rotation = StartRotation(dir)
+		replace(content)
+
+		if err := rotation.Rotate(""); err != nil {
+			t.Fatal(err)
+		}
+
+		rotation.Close()
+	}
+
+	if files := archives(); len(files) != 2 {
+		t.Errorf("Archives: %v\n", files)
+	}
+
+	RotateConfig.Keep = 0
+
+	if rotation := StartRotation(dir); rotation != nil {
+		t.Errorf("Rotation is disabled\n")
+	}
+}
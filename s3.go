@@ -0,0 +1,305 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Client - minimal S3 API client: path-style requests signed with AWS Signature V4,
+// enough for MinIO and AWS. Credentials are taken from the AWS_* environment variables.
+type S3Client struct {
+	Endpoint     string // scheme and host, e.g. https://s3.eu-central-1.amazonaws.com or http://minio:9000.
+	Bucket       string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	HTTP         *http.Client
+}
+
+// S3Object - object of a listing.
+type S3Object struct {
+	Key  string
+	Size int64
+}
+
+// ErrS3NotFound - no such object.
+var ErrS3NotFound = errors.New("object not found")
+
+// NewS3Client - client of the bucket, credentials from the environment.
+func NewS3Client(endpoint, bucket, region string) *S3Client {
+	return &S3Client{
+		Endpoint:     strings.TrimRight(endpoint, "/"),
+		Bucket:       bucket,
+		Region:       region,
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		HTTP:         &http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+// PutFile - upload the file as the object.
+func (c *S3Client) PutFile(key, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	defer f.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+
+	req, err := c.request(http.MethodPut, key, nil, io.NopCloser(f), hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+// Get - object body, the caller closes it.
+func (c *S3Client) Get(key string) (io.ReadCloser, error) {
+	req, err := c.request(http.MethodGet, key, nil, nil, emptySHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Delete - remove the object.
+func (c *S3Client) Delete(key string) error {
+	req, err := c.request(http.MethodDelete, key, nil, nil, emptySHA256)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+// List - all objects with the prefix, in key order.
+func (c *S3Client) List(prefix string) ([]S3Object, error) {
+	var (
+		objects []S3Object
+		token   string
+	)
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := c.request(http.MethodGet, "", query, nil, emptySHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		result := struct {
+			Contents []struct {
+				Key  string
+				Size int64
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}{}
+
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("list: %w", err)
+		}
+
+		for _, obj := range result.Contents {
+			objects = append(objects, S3Object{Key: obj.Key, Size: obj.Size})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+
+		token = result.NextContinuationToken
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	return objects, nil
+}
+
+func (c *S3Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", req.URL.Path, ErrS3NotFound)
+	}
+
+	return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// emptySHA256 - payload hash of requests without a body.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// request - signed request for the object key, an empty key addresses the bucket.
+func (c *S3Client) request(method, key string, query url.Values, body io.ReadCloser, payloadHash string) (*http.Request, error) {
+	path := "/" + s3Escape(c.Bucket)
+	if key != "" {
+		path += "/" + s3Escape(key)
+	}
+
+	rawQuery := s3Query(query)
+
+	u, err := url.Parse(c.Endpoint + path)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint: %w", err)
+	}
+
+	u.RawPath, u.RawQuery = path, rawQuery
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Body = body
+	}
+
+	now := time.Now().UTC()
+	date, stamp := now.Format("20060102"), now.Format("20060102T150405Z")
+
+	req.Header.Set("x-amz-date", stamp)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headers := "host:" + u.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + stamp + "\n"
+
+	if c.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.SessionToken)
+
+		signed = append(signed, "x-amz-security-token")
+		headers += "x-amz-security-token:" + c.SessionToken + "\n"
+	}
+
+	canonical := strings.Join([]string{method, path, rawQuery, headers, strings.Join(signed, ";"), payloadHash}, "\n")
+	scope := date + "/" + c.Region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + sha256Hex(canonical)
+
+	key256 := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	key256 = hmacSHA256(key256, c.Region)
+	key256 = hmacSHA256(key256, "s3")
+	key256 = hmacSHA256(key256, "aws4_request")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, strings.Join(signed, ";"), hex.EncodeToString(hmacSHA256(key256, toSign))))
+
+	return req, nil
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+
+	return mac.Sum(nil)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// s3Escape - URI encoding of SigV4, slashes are kept.
+func s3Escape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', strings.IndexByte("-_.~/", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// s3Query - canonical query string: sorted keys, SigV4 encoding.
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, strings.ReplaceAll(s3Escape(k), "/", "%2F")+"="+strings.ReplaceAll(s3Escape(v), "/", "%2F"))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// S3Bootstrap - load the latest uploaded snapshot at startup if the local dump gives nothing.
+// It is set once at startup.
+var S3Bootstrap bool
+
+// ErrNoRemoteSnapshot - nothing is uploaded to the bucket yet.
+var ErrNoRemoteSnapshot = errors.New("no snapshot in the bucket")
+
+// BootstrapFromS3 - restore the index from the latest snapshot of the bucket.
+// The next poll applies the registry dump as an update.
+func (o S3UploadOptions) BootstrapFromS3() error {
+	client := NewS3Client(o.Endpoint, o.Bucket, o.Region)
+
+	objects, err := client.List(o.Prefix + s3SnapshotKind)
+	if err != nil {
+		return err
+	}
+
+	objects = s3Objects(objects, o.Prefix+s3SnapshotKind)
+	if len(objects) == 0 {
+		return ErrNoRemoteSnapshot
+	}
+
+	key := objects[len(objects)-1].Key
+
+	body, err := client.Get(key)
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	snap, err := decodeSnapshotZstd(body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	if err := CurrentDump.RestoreSnapshot(snap); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	logger.Info.Printf("Bootstrapped from S3: %s, %d records\n", key, len(snap.Contents))
+
+	return nil
+}
+
+// decodeSnapshotZstd - snapshot written by WriteSnapshotZstd.
+func decodeSnapshotZstd(r io.Reader) (*Snapshot, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("zstd: %w", err)
+	}
+
+	defer zr.Close()
+
+	snap := &Snapshot{}
+
+	if err := gob.NewDecoder(zr).Decode(snap); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	return snap, nil
+}
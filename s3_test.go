@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 - in-memory bucket for tests.
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test/") || r.Header.Get("x-amz-content-sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/bucket":
+		result := struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []struct{ Key string }
+		}{}
+
+		for key := range s.objects {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				result.Contents = append(result.Contents, struct{ Key string }{key})
+			}
+		}
+
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut:
+		s.objects[key], _ = io.ReadAll(r.Body)
+	case r.Method == http.MethodGet:
+		if dat, ok := s.objects[key]; ok {
+			w.Write(dat)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestS3Upload tests snapshot and dump uploads with retention.
+func TestS3Upload(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	bucket := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(bucket)
+
+	defer srv.Close()
+
+	opts := S3UploadOptions{Endpoint: srv.URL, Bucket: "bucket", Region: "us-east-1", Prefix: "u2/", Keep: 1}
+	if err := opts.SetUploads("snapshot,dump"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	CurrentDump = NewDump()
+
+	for _, dump := range []string{xml01, xml02} {
+		if err := Parse(strings.NewReader(dump)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(dir+"/dump.xml", []byte(dump), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		job, err := opts.prepare(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := job(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := make([]string, 0, len(bucket.objects))
+	for key := range bucket.objects {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	stamp := strings.TrimPrefix(strings.TrimSuffix(keys[0], s3DumpSuffix), "u2/"+s3DumpKind)
+	if len(keys) != 2 || keys[1] != "u2/"+s3SnapshotKind+stamp+s3SnapshotSuffix || stamp != strconv.FormatInt(CurrentDump.utime, 10) {
+		t.Fatalf("objects: %v", keys)
+	}
+
+	client := NewS3Client(srv.URL, "bucket", "us-east-1")
+
+	body, err := client.Get(keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer body.Close()
+
+	snap, err := decodeSnapshotZstd(body)
+	if err != nil || len(snap.Contents) != len(CurrentDump.ContentIdx) {
+		t.Errorf("snapshot: %v", err)
+	}
+
+	if _, err := client.Get("u2/none"); err == nil {
+		t.Error("missing object is found")
+	}
+}
+
+// TestS3Bootstrap tests the index restore from the latest uploaded snapshot.
+func TestS3Bootstrap(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	bucket := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(bucket)
+
+	defer srv.Close()
+
+	opts := S3UploadOptions{Endpoint: srv.URL, Bucket: "bucket", Region: "us-east-1", Snapshot: true}
+
+	CurrentDump = NewDump()
+	if err := opts.BootstrapFromS3(); !errors.Is(err, ErrNoRemoteSnapshot) {
+		t.Fatalf("empty bucket: %v", err)
+	}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := opts.prepare(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := job(); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := CurrentDump
+
+	CurrentDump = NewDump()
+	if err := opts.BootstrapFromS3(); err != nil {
+		t.Fatal(err)
+	}
+
+	if CurrentDump.utime != parsed.utime || len(CurrentDump.ContentIdx) != len(parsed.ContentIdx) ||
+		len(CurrentDump.ip4Idx) != len(parsed.ip4Idx) || len(CurrentDump.subnet4Idx) != len(parsed.subnet4Idx) ||
+		len(CurrentDump.domainIdx) != len(parsed.domainIdx) || len(CurrentDump.urlIdx) != len(parsed.urlIdx) {
+		t.Fatal("restored index differs")
+	}
+
+	if report := CurrentDump.VerifyIndexes(); len(report.Problems) != 0 {
+		t.Errorf("problems: %v", report.Problems)
+	}
+
+	// the same dump is no change after the restore.
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if Stats.AddCount != 0 || Stats.UpdateCount != 0 || Stats.RemoveCount != 0 {
+		t.Errorf("stats: %+v", Stats)
+	}
+}
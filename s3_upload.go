@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// S3UploadOptions - post-parse upload to an S3 compatible bucket, an empty bucket disables it.
+type S3UploadOptions struct {
+	Endpoint string
+	Bucket   string
+	Region   string
+	Prefix   string // object key prefix, e.g. "u2ckdump/".
+	Snapshot bool   // upload the index snapshot.
+	Dump     bool   // upload dump.xml.
+	Keep     int    // objects of each kind kept in the bucket, 0 keeps all.
+}
+
+// S3Config - S3 upload configuration, it is set once at startup.
+var S3Config = S3UploadOptions{Region: "us-east-1"}
+
+// Kinds of uploaded objects, keys are <prefix><kind>-<registry update time><suffix>.
+const (
+	s3SnapshotKind   = "snapshot-"
+	s3SnapshotSuffix = ".gob.zst"
+	s3DumpKind       = "dump-"
+	s3DumpSuffix     = ".xml.zst"
+)
+
+// SetUploads - enable comma separated uploads: snapshot, dump.
+func (o *S3UploadOptions) SetUploads(kinds string) error {
+	for _, kind := range strings.Split(kinds, ",") {
+		switch strings.TrimSpace(kind) {
+		case "":
+		case "snapshot":
+			o.Snapshot = true
+		case "dump":
+			o.Dump = true
+		default:
+			return fmt.Errorf("unknown upload: %s", kind)
+		}
+	}
+
+	return nil
+}
+
+// s3Uploading - an upload is running, the next generation is skipped until it ends.
+var s3Uploading atomic.Bool
+
+// UploadToS3 - start the upload of the new generation in the background.
+func UploadToS3(dir string) {
+	if S3Config.Bucket == "" || !S3Config.Snapshot && !S3Config.Dump {
+		return
+	}
+
+	if !s3Uploading.CompareAndSwap(false, true) {
+		logger.Warning.Printf("S3 upload is still running, skip the generation\n")
+
+		return
+	}
+
+	job, err := S3Config.prepare(dir)
+	if err != nil {
+		s3Uploading.Store(false)
+		logger.Error.Printf("Can't prepare S3 upload: %s\n", err.Error())
+
+		return
+	}
+
+	go func() {
+		defer s3Uploading.Store(false)
+
+		if err := job(); err != nil {
+			logger.Error.Printf("S3 upload error: %s\n", err.Error())
+		}
+	}()
+}
+
+// prepare - freeze the generation: encode the snapshot and open the dump, the rest runs in the job.
+func (o S3UploadOptions) prepare(dir string) (func() error, error) {
+	CurrentDump.RLock()
+	utime := CurrentDump.utime
+	CurrentDump.RUnlock()
+
+	var (
+		snapshotFilename string
+		dumpFile         *os.File
+	)
+
+	if o.Snapshot {
+		snapshotFilename = dir + "/s3-" + s3SnapshotKind + "upload-tmp"
+
+		if err := CurrentDump.WriteSnapshotZstd(snapshotFilename); err != nil {
+			os.Remove(snapshotFilename)
+
+			return nil, fmt.Errorf("snapshot: %w", err)
+		}
+	}
+
+	if o.Dump {
+		// the poller replaces dump.xml by rename, the open file stays of this generation.
+		f, err := os.Open(dir + "/dump.xml")
+		if err != nil {
+			if snapshotFilename != "" {
+				os.Remove(snapshotFilename)
+			}
+
+			return nil, fmt.Errorf("dump: %w", err)
+		}
+
+		dumpFile = f
+	}
+
+	return func() error {
+		client := NewS3Client(o.Endpoint, o.Bucket, o.Region)
+		stamp := strconv.FormatInt(utime, 10)
+
+		if snapshotFilename != "" {
+			defer os.Remove(snapshotFilename)
+
+			if err := o.put(client, s3SnapshotKind, stamp+s3SnapshotSuffix, snapshotFilename); err != nil {
+				return fmt.Errorf("snapshot: %w", err)
+			}
+		}
+
+		if dumpFile != nil {
+			defer dumpFile.Close()
+
+			dumpFilename := dir + "/s3-" + s3DumpKind + "upload-tmp"
+			defer os.Remove(dumpFilename)
+
+			if err := compressZstd(dumpFile, dumpFilename); err != nil {
+				return fmt.Errorf("dump: %w", err)
+			}
+
+			if err := o.put(client, s3DumpKind, stamp+s3DumpSuffix, dumpFilename); err != nil {
+				return fmt.Errorf("dump: %w", err)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+// put - upload the file and apply the retention to its kind.
+func (o S3UploadOptions) put(client *S3Client, kind, name, filename string) error {
+	key := o.Prefix + kind + name
+
+	if err := client.PutFile(key, filename); err != nil {
+		return err
+	}
+
+	logger.Info.Printf("Uploaded to S3: %s\n", key)
+
+	if o.Keep <= 0 {
+		return nil
+	}
+
+	objects, err := client.List(o.Prefix + kind)
+	if err != nil {
+		return fmt.Errorf("retention: %w", err)
+	}
+
+	objects = s3Objects(objects, o.Prefix+kind)
+
+	for len(objects) > o.Keep {
+		if err := client.Delete(objects[0].Key); err != nil {
+			logger.Error.Printf("Can't remove old S3 object: %s\n", err.Error())
+		}
+
+		objects = objects[1:]
+	}
+
+	return nil
+}
+
+// s3Objects - objects named by registry update time, ascending.
+func s3Objects(objects []S3Object, prefix string) []S3Object {
+	stamped := objects[:0]
+
+	for _, obj := range objects {
+		name := strings.TrimPrefix(obj.Key, prefix)
+		if i := strings.IndexByte(name, '.'); i > 0 {
+			name = name[:i]
+		}
+
+		if _, err := strconv.ParseInt(name, 10, 64); err == nil {
+			stamped = append(stamped, obj)
+		}
+	}
+
+	return stamped
+}
+
+// WriteSnapshotZstd - save current generation to the file as zstd compressed gob.
+func (dump *Dump) WriteSnapshotZstd(filename string) error {
+	dump.RLock()
+	defer dump.RUnlock()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	defer f.Close()
+
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		return fmt.Errorf("zstd: %w", err)
+	}
+
+	if err := gob.NewEncoder(zw).Encode(dump.snapshot()); err != nil {
+		zw.Close()
+
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("zstd: %w", err)
+	}
+
+	return f.Close()
+}
+
+// compressZstd - write the zstd compressed reader to the file.
+func compressZstd(r io.Reader, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	defer f.Close()
+
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		return fmt.Errorf("zstd: %w", err)
+	}
+
+	if _, err := io.Copy(zw, r); err != nil {
+		zw.Close()
+
+		return fmt.Errorf("compress: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("zstd: %w", err)
+	}
+
+	return f.Close()
+}
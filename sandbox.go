@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// SandboxOptions - run fetch and unzip in a separate process with restricted privileges.
+type SandboxOptions struct {
+	Enabled bool
+	UID     int // -1 - keep current.
+	GID     int // -1 - keep current.
+}
+
+// SandboxConfig - sandbox configuration, it is set once at startup.
+var SandboxConfig = SandboxOptions{UID: -1, GID: -1}
+
+// Environment of the sandboxed fetch process, the key is not passed in argv.
+const (
+	sandboxEnvURL = "U2CK_DUMP_URL"
+	sandboxEnvKey = "U2CK_DUMP_KEY"
+)
+
+// Exit codes of the sandboxed fetch.
+const (
+	sandboxExitTooBig    = 3 // a dump over the size limits, see ErrTooBig.
+	sandboxExitTransient = 4 // a failure worth a retry, see retryable.
+)
+
+// FetchAndUnzip - fetch dump.zip and extract dump.xml, in a sandbox if configured.
+func FetchAndUnzip(ctx context.Context, id, dir, url, token string, upstream UpstreamTLS) error {
+	if !SandboxConfig.Enabled {
+		return fetchAndUnzip(ctx, id, dir, url, token, upstream)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("executable: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, self, "fetch", id, dir, strconv.FormatInt(UnzipMaxBytes, 10), UnzipEntry, UnzipSignature,
+		strconv.FormatInt(UnzipMaxArchiveBytes, 10), strconv.FormatFloat(UnzipMaxRatio, 'g', -1, 64))
+	cmd.Env = append([]string{sandboxEnvURL + "=" + url, sandboxEnvKey + "=" + token}, upstream.env()...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+
+	if SandboxConfig.UID >= 0 || SandboxConfig.GID >= 0 {
+		cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid()), NoSetGroups: true}
+		if SandboxConfig.UID >= 0 {
+			cred.Uid = uint32(SandboxConfig.UID)
+		}
+
+		if SandboxConfig.GID >= 0 {
+			cred.Gid = uint32(SandboxConfig.GID)
+		}
+
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	}
+
+	logger.Debug.Printf("Run sandboxed fetch: %s\n", cmd.String())
+
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		switch exitErr.ExitCode() {
+		case sandboxExitTooBig:
+			return fmt.Errorf("sandbox: %w", ErrTooBig)
+		case sandboxExitTransient:
+			return fmt.Errorf("sandbox: %w", ErrTransient)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+
+	// faults are armed in this process, not in the sandbox.
+	if err := Faults.Fail(FaultUnzip); err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+
+	return nil
+}
+
+func fetchAndUnzip(ctx context.Context, id, dir, url, token string, upstream UpstreamTLS) error {
+	client, err := upstream.Client()
+	if err != nil {
+		return fmt.Errorf("upstream TLS: %w", err)
+	}
+
+	err = FetchDump(ctx, client, id, dir+"/dump.zip", url, token)
+	if err != nil {
+		return fmt.Errorf("fetch: %w", err)
+	}
+
+	logger.Info.Println("Last dump fetched")
+
+	err = Faults.Fail(FaultUnzip)
+	if err == nil {
+		err = DumpUnzip(dir+"/dump.zip", dir+"/dump.xml")
+	}
+
+	if err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+
+	logger.Info.Println("Last dump extracted")
+
+	return nil
+}
+
+// fetchCommand - sandboxed side of FetchAndUnzip.
+func fetchCommand(args []string) int {
+	if len(args) != 7 {
+		fmt.Fprintf(os.Stderr, "Usage: %s fetch <id> <dir> <max extracted bytes> <dump entry> <signature entry> <max archive bytes> <max ratio>\n", os.Args[0])
+
+		return 2
+	}
+
+	maxBytes, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Bad max extracted bytes: %s\n", err.Error())
+
+		return 2
+	}
+
+	maxArchive, err := strconv.ParseInt(args[5], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Bad max archive bytes: %s\n", err.Error())
+
+		return 2
+	}
+
+	maxRatio, err := strconv.ParseFloat(args[6], 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Bad max ratio: %s\n", err.Error())
+
+		return 2
+	}
+
+	UnzipMaxBytes, UnzipMaxArchiveBytes, UnzipMaxRatio = maxBytes, maxArchive, maxRatio
+	UnzipEntry, UnzipSignature = args[3], args[4]
+
+	err = fetchAndUnzip(context.Background(), args[0], args[1], os.Getenv(sandboxEnvURL), os.Getenv(sandboxEnvKey), upstreamTLSFromEnv())
+	if errors.Is(err, ErrTooBig) {
+		logger.Error.Printf("Can't fetch last dump: %s\n", err.Error())
+
+		return sandboxExitTooBig
+	}
+
+	if retryable(err) {
+		logger.Error.Printf("Can't fetch last dump: %s\n", err.Error())
+
+		return sandboxExitTransient
+	}
+
+	if err != nil {
+		logger.Error.Printf("Can't fetch last dump: %s\n", err.Error())
+
+		return 1
+	}
+
+	return 0
+}
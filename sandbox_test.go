@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFetchCommand tests the sandboxed side of the fetch: arguments, the key from the environment
+// and exit codes of the size limits.
+func TestFetchCommand(t *testing.T) {
+	defer func(max, archive int64, ratio float64, entry, sig string) {
+		UnzipMaxBytes, UnzipMaxArchiveBytes, UnzipMaxRatio, UnzipEntry, UnzipSignature = max, archive, ratio, entry, sig
+	}(UnzipMaxBytes, UnzipMaxArchiveBytes, UnzipMaxRatio, UnzipEntry, UnzipSignature)
+
+	archive := filepath.Join(t.TempDir(), "dump.zip")
+	writeZip(t, archive, "dump.xml")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.URL.Path != "/get/1" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		http.ServeFile(w, r, archive)
+	}))
+	defer server.Close()
+
+	t.Setenv(sandboxEnvURL, server.URL)
+	t.Setenv(sandboxEnvKey, "secret")
+
+	dir := t.TempDir()
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"usage", []string{"1", dir}, 2},
+		{"bad limit", []string{"1", dir, "many", "dump.xml", "", "0", "0"}, 2},
+		{"too big", []string{"1", dir, "1", "dump.xml", "", "0", "0"}, sandboxExitTooBig},
+		{"not found", []string{"2", dir, "0", "dump.xml", "", "0", "0"}, 1},
+		{"ok", []string{"1", dir, "0", "dump.xml", "", "0", "0"}, 0},
+	} {
+		if code := fetchCommand(tc.args); code != tc.code {
+			t.Errorf("%s: exit code %d\n", tc.name, code)
+		}
+	}
+
+	if dat, _ := os.ReadFile(dir + "/dump.xml"); string(dat) != "dump.xml" {
+		t.Errorf("Extracted: %q\n", dat)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+// ScheduleProjection - what becomes enforceable by a future time compared with now.
+type ScheduleProjection struct {
+	Records   []int32    // records included after now and by the time, ordered by include time, then by id.
+	Selectors []Selector // selectors enforceable by the time and not now, ordered by kind and value.
+}
+
+// ProjectSchedule - records and selectors enforceable at the time and not now, call it under read lock.
+// A record is enforceable since its include time, its selector since its ts, if it is set.
+func (dump *Dump) ProjectSchedule(now, at int64) ScheduleProjection {
+	added, _ := DiffSelectors(dump.enforceableSelectors(now), dump.enforceableSelectors(at))
+
+	return ScheduleProjection{
+		Records:   dump.includeTimeIdx.Range(now+1, at),
+		Selectors: added,
+	}
+}
+
+// enforceableSelectors - normalized selectors enforceable at the time, call it under read lock.
+func (dump *Dump) enforceableSelectors(t int64) SelectorSet {
+	set := make(SelectorSet)
+
+	for _, pack := range dump.ContentIdx {
+		if pack.IncludeTime > t {
+			continue
+		}
+
+		pack.addEnforceableSelectors(set, t)
+	}
+
+	return set
+}
+
+// addEnforceableSelectors - add normalized selectors of the record with ts not after the time to the set.
+func (pack *PackedContent) addEnforceableSelectors(set SelectorSet, t int64) {
+	for _, ip4 := range pack.IP4 {
+		if ip4.Ts <= t {
+			set[Selector{SelectorIP4, ip4.IP4.String()}] = Nothing{}
+		}
+	}
+
+	for _, ip6 := range pack.IP6 {
+		if ip6.Ts <= t {
+			set[Selector{SelectorIP6, ip6.IP6.String()}] = Nothing{}
+		}
+	}
+
+	for _, subnet4 := range pack.Subnet4 {
+		if subnet4.Ts <= t {
+			set[Selector{SelectorSubnet4, subnet4.Subnet4.String()}] = Nothing{}
+		}
+	}
+
+	for _, subnet6 := range pack.Subnet6 {
+		if subnet6.Ts <= t {
+			set[Selector{SelectorSubnet6, subnet6.Subnet6.String()}] = Nothing{}
+		}
+	}
+
+	for _, domain := range pack.Domain {
+		if domain.Ts <= t {
+			set[Selector{SelectorDomain, NormalizeDomain(domain.Domain)}] = Nothing{}
+		}
+	}
+
+	for _, u := range pack.URL {
+		if u.Ts <= t {
+			set[Selector{SelectorURL, NormalizeURL(u.URL)}] = Nothing{}
+		}
+	}
+}
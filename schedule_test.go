@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// TestProjectSchedule tests records and selectors enforceable at a future time by include time and ts.
+func TestProjectSchedule(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	now := time.Now()
+	includeTime := func(d time.Duration) string {
+		return now.Add(d).In(parser.LocationMSK).Format("2006-01-02T15:04:05")
+	}
+
+	dump := fmt.Sprintf(`<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="%s" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <ip>10.0.0.1</ip>
+        <ip ts="%s">10.0.0.2</ip>
+</content>
+<content id="2" includeTime="%s" entryType="1" blockType="domain" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <domain><![CDATA[www.e2.tld]]></domain>
+        <ip>10.0.0.1</ip>
+</content>
+<content id="3" includeTime="%s" entryType="1" blockType="domain" hash="3">
+        <decision date="2000-01-01" number="3" org="ONE"/>
+        <domain><![CDATA[www.e3.tld]]></domain>
+</content>
+</reg:register>`, includeTime(-time.Hour), now.Add(time.Hour).Format(time.RFC3339), includeTime(2*time.Hour), includeTime(48*time.Hour))
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	resp, err := s.ProjectSchedule(context.Background(), &pb.ScheduleRequest{At: now.Add(3 * time.Hour).Unix()})
+	if err != nil || resp.GetError() != "" {
+		t.Fatalf("Projection: %v %s\n", err, resp.GetError())
+	}
+
+	if len(resp.GetResults()) != 1 || resp.GetResults()[0].GetId() != 2 || resp.GetTotal() != 1 {
+		t.Errorf("Records: %v\n", resp.GetResults())
+	}
+
+	var selectors []string
+	for _, sel := range resp.GetSelectors() {
+		selectors = append(selectors, sel.GetKind()+":"+sel.GetSelector())
+	}
+
+	// 10.0.0.1 is enforceable now, 10.0.0.2 of the enforced record since its ts.
+	if want := []string{"domain:www.e2.tld", "ip4:10.0.0.2"}; !reflect.DeepEqual(selectors, want) {
+		t.Errorf("Selectors: %v, want %v\n", selectors, want)
+	}
+
+	resp, _ = s.ProjectSchedule(context.Background(), &pb.ScheduleRequest{At: now.Add(-time.Hour).Unix()})
+	if resp.GetError() != SrvBadTimeRange {
+		t.Errorf("Past: %q\n", resp.GetError())
+	}
+}
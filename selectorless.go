@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// MatchSelectorless - match kind of ListSelectorlessRecords results.
+const MatchSelectorless = "selectorless"
+
+// selectorlessCache - ids of records without selectors of one generation.
+type selectorlessCache struct {
+	sync.Mutex
+	generation int64
+	ids        []int32
+}
+
+// selectorless - the record has a decision and a block type only, no IP, subnet, domain or URL.
+// Such records are in the decision, date and organization indexes, but match no selector search.
+func (pack *PackedContent) selectorless() bool {
+	return len(pack.IP4) == 0 && len(pack.IP6) == 0 && len(pack.Subnet4) == 0 && len(pack.Subnet6) == 0 &&
+		len(pack.Domain) == 0 && len(pack.URL) == 0
+}
+
+// SelectorlessRecords - sorted ids of records without selectors, call it under read lock.
+// The result is shared, don't change it.
+func (dump *Dump) SelectorlessRecords() []int32 {
+	dump.selectorless.Lock()
+	defer dump.selectorless.Unlock()
+
+	if dump.selectorless.ids != nil && dump.selectorless.generation == dump.generation {
+		return dump.selectorless.ids
+	}
+
+	ids := make([]int32, 0)
+
+	for id, pack := range dump.ContentIdx {
+		if pack.selectorless() {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	dump.selectorless.generation, dump.selectorless.ids = dump.generation, ids
+
+	return ids
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Compare modes of changed records.
+const (
+	CompareHash     = "hash"     // any byte change of <content>...</content> is an update.
+	CompareSemantic = "semantic" // a changed record is an update only if the parsed record differs.
+)
+
+// semanticForm - record without its hash and with selectors in a stable order.
+type semanticForm struct {
+	Content
+	Selectors []string `json:"sel"`
+}
+
+// semanticKey - canonical encoding of what the record says, attribute order,
+// whitespace, entities and selector order don't change it.
+func semanticKey(record *Content) ([]byte, error) {
+	form := semanticForm{Content: *record}
+
+	form.RecordHash, form.HTTPSBlock, form.DecisionHash = 0, 0, 0
+	form.URL, form.IP4, form.IP6, form.Subnet4, form.Subnet6, form.Domain = nil, nil, nil, nil, nil, nil
+
+	add := func(kind string, v interface{}) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		form.Selectors = append(form.Selectors, kind+string(b))
+
+		return nil
+	}
+
+	var err error
+
+	for i := 0; err == nil && i < len(record.URL); i++ {
+		err = add("u", record.URL[i])
+	}
+
+	for i := 0; err == nil && i < len(record.IP4); i++ {
+		err = add("4", record.IP4[i])
+	}
+
+	for i := 0; err == nil && i < len(record.IP6); i++ {
+		err = add("6", record.IP6[i])
+	}
+
+	for i := 0; err == nil && i < len(record.Subnet4); i++ {
+		err = add("s4", record.Subnet4[i])
+	}
+
+	for i := 0; err == nil && i < len(record.Subnet6); i++ {
+		err = add("s6", record.Subnet6[i])
+	}
+
+	for i := 0; err == nil && i < len(record.Domain); i++ {
+		err = add("d", record.Domain[i])
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(form.Selectors)
+
+	return json.Marshal(form)
+}
+
+// semanticEqual - the record says the same as the payload of the packed one.
+func (pack *PackedContent) semanticEqual(record *Content) (bool, error) {
+	prev := &Content{}
+	if err := json.Unmarshal(pack.PayloadBytes(), prev); err != nil {
+		return false, fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	a, err := semanticKey(prev)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := semanticKey(record)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(a, b), nil
+}
@@ -4,36 +4,51 @@ package main
 
 import (
 	"context"
-	"net"
+	"net/netip"
+	"time"
 
 	"github.com/usher2/u2ckdump/internal/logger"
 	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
 )
 
 // server - our grpc server.
 type server struct {
 	pb.UnimplementedCheckServer
+	dir  string          // dump cache dir with retained snapshots.
+	kill <-chan struct{} // closed on shutdown, releases long polls.
 }
 
 // SearchDecision - search by decision number.
 func (s *server) SearchDecision(ctx context.Context, in *pb.DecisionRequest) (*pb.SearchResponse, error) {
 	query := in.GetQuery()
 
-	logger.Debug.Printf("Received decision: %d\n", query)
+	logger.Debug.Printf("[%s] Received decision: %d\n", RequestID(ctx), query)
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
 
 	// TODO: Change to DunpSnap search method.
 	if CurrentDump != nil && CurrentDump.utime > 0 {
 		CurrentDump.RLock()
 
-		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
-		results := CurrentDump.decisionIdx[query]
-		resp.Results = make([]*pb.Content, 0, len(results))
+		resp := cachedSearch(cacheKey("decision", query, mask), func() *pb.SearchResponse {
+			resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+			results := CurrentDump.decisionIdx[query]
+			resp.Results = make([]*pb.Content, 0, len(results))
 
-		for _, id := range results {
-			if v, ok := CurrentDump.ContentIdx[id]; ok {
-				resp.Results = append(resp.Results, v.newPbContent(0, nil, "", "", ""))
+			for _, id := range results {
+				if v, ok := CurrentDump.ContentIdx[id]; ok {
+					resp.Results = append(resp.Results, v.newMaskedPbContent(mask, Match{Kind: MatchDecision}))
+				}
 			}
-		}
+
+			return resp
+		})
 
 		CurrentDump.RUnlock()
 
@@ -43,21 +58,73 @@ func (s *server) SearchDecision(ctx context.Context, in *pb.DecisionRequest) (*p
 	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
 }
 
+// SearchTextDecision - search by a substring of the decision number or organization, see FoldText.
+func (s *server) SearchTextDecision(ctx context.Context, in *pb.TextDecisionRequest) (*pb.SearchResponse, error) {
+	logger.Debug.Printf("[%s] Received decision text: %q, %d, %d\n", RequestID(ctx), in.GetQuery(), in.GetOffset(), in.GetLimit())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		ids, ok := CurrentDump.SearchDecisionText(in.GetQuery())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvShortQuery}, nil
+		}
+
+		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		results, ok := CurrentDump.orderResults(ids, in.GetOrderBy(), OrderID, in.GetDescending())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvBadOrder}, nil
+		}
+
+		page := paginate(results, in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = make([]*pb.Content, 0, len(page))
+
+		for _, id := range page {
+			resp.Results = append(resp.Results, CurrentDump.ContentIdx[id].newMaskedPbContent(mask, Match{Kind: MatchDecisionText}))
+		}
+
+		return resp, nil
+	}
+
+	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
+}
+
 // SearchID - search by content ID.
 func (s *server) SearchID(ctx context.Context, in *pb.IDRequest) (*pb.SearchResponse, error) {
 	query := in.GetQuery()
 
-	logger.Debug.Printf("Received content ID: %d\n", query)
+	logger.Debug.Printf("[%s] Received content ID: %d\n", RequestID(ctx), query)
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
 
 	// TODO: Change to DunpSnap search method.
 	if CurrentDump != nil && CurrentDump.utime > 0 {
 		CurrentDump.RLock()
 
-		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		resp := cachedSearch(cacheKey("id", query, mask), func() *pb.SearchResponse {
+			resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
 
-		if result, ok := CurrentDump.ContentIdx[query]; ok {
-			resp.Results = append(resp.Results, result.newPbContent(0, nil, "", "", ""))
-		}
+			if result, ok := CurrentDump.ContentIdx[query]; ok {
+				resp.Results = append(resp.Results, result.newMaskedPbContent(mask, Match{Kind: MatchID}))
+			}
+
+			return resp
+		})
 
 		CurrentDump.RUnlock()
 
@@ -70,61 +137,49 @@ func (s *server) SearchID(ctx context.Context, in *pb.IDRequest) (*pb.SearchResp
 // SearchID - search by IPv4.
 func (s *server) SearchIP4(c context.Context, in *pb.IP4Request) (*pb.SearchResponse, error) {
 	query := in.GetQuery()
-	ipBytes := net.IP{
-		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff,
-		byte((query & 0xFF000000) >> 24),
-		byte((query & 0x00FF0000) >> 16),
-		byte((query & 0x0000FF00) >> 8),
-		byte(query & 0x000000FF),
-	}
+	ip := parser.Addr4(query)
+
+	logger.Debug.Printf("[%s] Received IPv4: %s\n", RequestID(c), ip)
 
-	logger.Debug.Printf("Received IPv4: %s\n", ipBytes)
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(c), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
 
-	var resultSubnets, resulIPs ArrayIntSet
-	var subnets []string
+	var resulIPs ArrayIntSet
 
 	// TODO: Change to DunpSnap search method.
 	if CurrentDump != nil && CurrentDump.utime > 0 {
 		CurrentDump.RLock()
 
-		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		resp := cachedSearch(cacheKey("ip4", query, mask), func() *pb.SearchResponse {
+			resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
 
-		// TODO: Change to DumpSnap search method
-		cnw, err := CurrentDump.netTree.ContainingNetworks(ipBytes)
-		if err != nil {
-			logger.Debug.Printf("Can't get containing networks: %s: %s\n", ipBytes, err)
-		} else {
-			for _, entry := range cnw {
-				subnet := entry.Network()
-				subnetStr := subnet.String()
-
-				if a, ok := CurrentDump.subnet4Idx[subnetStr]; ok {
-					resultSubnets = append(resultSubnets, a...)
-
-					for range a {
-						subnets = append(subnets, subnetStr)
-					}
-				}
-			}
-		}
+			// TODO: Change to DumpSnap search method
+			resultSubnets, subnets := CurrentDump.subnetHits(ip, CurrentDump.subnet4Idx)
 
-		if a, ok := CurrentDump.ip4Idx[query]; ok {
-			resulIPs = append(resulIPs, a...)
-		}
+			if a, ok := CurrentDump.ip4Idx[ip]; ok {
+				resulIPs = append(resulIPs, a...)
+			}
 
-		resp.Results = make([]*pb.Content, 0, len(resultSubnets)+len(resulIPs))
+			resp.Results = make([]*pb.Content, 0, len(resultSubnets)+len(resulIPs))
 
-		for i, id := range resultSubnets {
-			if cont, ok := CurrentDump.ContentIdx[id]; ok {
-				resp.Results = append(resp.Results, cont.newPbContent(0, nil, "", "", subnets[i]))
+			for i, id := range resultSubnets {
+				if cont, ok := CurrentDump.ContentIdx[id]; ok {
+					resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: SelectorSubnet4, Subnet: subnets[i]}))
+				}
 			}
-		}
 
-		for _, id := range resulIPs {
-			if cont, ok := CurrentDump.ContentIdx[id]; ok {
-				resp.Results = append(resp.Results, cont.newPbContent(query, nil, "", "", ""))
+			for _, id := range resulIPs {
+				if cont, ok := CurrentDump.ContentIdx[id]; ok {
+					resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: SelectorIP4, IP: ip}))
+				}
 			}
-		}
+
+			return resp
+		})
 
 		CurrentDump.RUnlock()
 
@@ -134,25 +189,74 @@ func (s *server) SearchIP4(c context.Context, in *pb.IP4Request) (*pb.SearchResp
 	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
 }
 
+// subnetHits - records of indexed subnets containing the IP and the subnet of each one, call it under lock.
+// They are aggregated hits: the IP itself may be not in the registry.
+func (dump *Dump) subnetHits(ip netip.Addr, idx PrefixSet) (ArrayIntSet, []netip.Prefix) {
+	var (
+		ids     ArrayIntSet
+		subnets []netip.Prefix
+	)
+
+	cnw, err := dump.netTree.ContainingNetworks(ip.AsSlice())
+	if err != nil {
+		logger.Debug.Printf("Can't get containing networks: %s: %s\n", ip, err)
+
+		return nil, nil
+	}
+
+	for _, entry := range cnw {
+		subnet := ipNetPrefix(entry.Network())
+
+		if a, ok := idx[subnet]; ok {
+			ids = append(ids, a...)
+
+			for range a {
+				subnets = append(subnets, subnet)
+			}
+		}
+	}
+
+	return ids, subnets
+}
+
 // SearchID - search by IPv6.
 func (s *server) SearchIP6(ctx context.Context, in *pb.IP6Request) (*pb.SearchResponse, error) {
 	query := in.GetQuery()
 
-	logger.Debug.Printf("Received IPv6: %v\n", query)
+	logger.Debug.Printf("[%s] Received IPv6: %v\n", RequestID(ctx), query)
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
 
 	// TODO: Change to DunpSnap search method.
 	if CurrentDump != nil && CurrentDump.utime > 0 {
 		CurrentDump.RLock()
 
-		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
-		results := CurrentDump.ip6Idx[string(query)]
-		resp.Results = make([]*pb.Content, 0, len(results))
+		resp := cachedSearch(cacheKey("ip6", query, mask), func() *pb.SearchResponse {
+			resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+			ip, _ := parser.Addr6(query)
+			resultSubnets, subnets := CurrentDump.subnetHits(ip, CurrentDump.subnet6Idx)
+			results := CurrentDump.ip6Idx[ip]
+			resp.Results = make([]*pb.Content, 0, len(resultSubnets)+len(results))
 
-		for _, id := range results {
-			if cont, ok := CurrentDump.ContentIdx[id]; ok {
-				resp.Results = append(resp.Results, cont.newPbContent(0, query, "", "", ""))
+			for i, id := range resultSubnets {
+				if cont, ok := CurrentDump.ContentIdx[id]; ok {
+					resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: SelectorSubnet6, Subnet: subnets[i]}))
+				}
 			}
-		}
+
+			for _, id := range results {
+				if cont, ok := CurrentDump.ContentIdx[id]; ok {
+					resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: SelectorIP6, IP: ip}))
+				}
+			}
+
+			return resp
+		})
 
 		CurrentDump.RUnlock()
 
@@ -166,22 +270,43 @@ func (s *server) SearchIP6(ctx context.Context, in *pb.IP6Request) (*pb.SearchRe
 func (s *server) SearchURL(ctx context.Context, in *pb.URLRequest) (*pb.SearchResponse, error) {
 	query := in.GetQuery()
 
-	logger.Debug.Printf("Received URL: %v\n", query)
+	logger.Debug.Printf("[%s] Received URL: %v, %t\n", RequestID(ctx), query, in.GetSchemeAgnostic())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
 
 	// TODO: Change to DunpSnap search method.
 	if CurrentDump != nil && CurrentDump.utime > 0 {
 		CurrentDump.RLock()
 
-		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
-		results := CurrentDump.urlIdx[query]
-		resp.Results = make([]*pb.Content, 0, len(results))
+		if in.GetSchemeAgnostic() {
+			resp := cachedSearch(cacheKey("url-any", query, mask), func() *pb.SearchResponse {
+				return CurrentDump.searchURLAnyScheme(query, mask)
+			})
 
-		for _, id := range results {
-			if cont, ok := CurrentDump.ContentIdx[id]; ok {
-				resp.Results = append(resp.Results, cont.newPbContent(0, nil, "", query, ""))
-			}
+			CurrentDump.RUnlock()
+
+			return resp, nil
 		}
 
+		resp := cachedSearch(cacheKey("url", query, mask), func() *pb.SearchResponse {
+			resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+			results := CurrentDump.urlIdx[query]
+			resp.Results = make([]*pb.Content, 0, len(results))
+
+			for _, id := range results {
+				if cont, ok := CurrentDump.ContentIdx[id]; ok {
+					resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: SelectorURL, URL: query}))
+				}
+			}
+
+			return resp
+		})
+
 		CurrentDump.RUnlock()
 
 		return resp, nil
@@ -190,25 +315,65 @@ func (s *server) SearchURL(ctx context.Context, in *pb.URLRequest) (*pb.SearchRe
 	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
 }
 
+// searchURLAnyScheme - records with the http or https URL of the query host and path, the matched
+// selector is the URL of the record, https if it has both. Call it under read lock.
+func (dump *Dump) searchURLAnyScheme(query string, mask contentMask) *pb.SearchResponse {
+	key, ok := URLWithoutScheme(query)
+	if !ok {
+		key = query
+	}
+
+	resp := &pb.SearchResponse{RegistryUpdateTime: dump.utime}
+	results := dump.urlAnyIdx[key]
+	resp.Results = make([]*pb.Content, 0, len(results))
+
+	for _, id := range results {
+		cont, ok := dump.ContentIdx[id]
+		if !ok {
+			continue
+		}
+
+		matched := "https://" + key
+		if !dump.urlIdx[matched].Has(id) {
+			matched = "http://" + key
+		}
+
+		resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: SelectorURL, URL: matched}))
+	}
+
+	return resp
+}
+
 // SearchID - search by domain.
 func (s *server) SearchDomain(ctx context.Context, in *pb.DomainRequest) (*pb.SearchResponse, error) {
 	query := in.GetQuery()
 
-	logger.Debug.Printf("Received Domain: %v\n", query)
+	logger.Debug.Printf("[%s] Received Domain: %v\n", RequestID(ctx), query)
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
 
 	// TODO: Change to DunpSnap search method.
 	if CurrentDump != nil && CurrentDump.utime > 0 {
 		CurrentDump.RLock()
 
-		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
-		results := CurrentDump.domainIdx[query]
-		resp.Results = make([]*pb.Content, 0, len(results))
+		resp := cachedSearch(cacheKey("domain", query, mask), func() *pb.SearchResponse {
+			resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+			results := CurrentDump.domainIdx[query]
+			resp.Results = make([]*pb.Content, 0, len(results))
 
-		for _, id := range results {
-			if cont, ok := CurrentDump.ContentIdx[id]; ok {
-				resp.Results = append(resp.Results, cont.newPbContent(0, nil, query, "", ""))
+			for _, id := range results {
+				if cont, ok := CurrentDump.ContentIdx[id]; ok {
+					resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, cont.domainMatch(query)))
+				}
 			}
-		}
+
+			return resp
+		})
 
 		CurrentDump.RUnlock()
 
@@ -222,13 +387,21 @@ func (s *server) SearchDomain(ctx context.Context, in *pb.DomainRequest) (*pb.Se
 func (s *server) Ping(ctx context.Context, in *pb.PingRequest) (*pb.PongResponse, error) {
 	ping := in.GetPing()
 
-	logger.Debug.Printf("Received Ping: %v\n", ping)
+	logger.Debug.Printf("[%s] Received Ping: %v\n", RequestID(ctx), ping)
 
 	// TODO: Change to DunpSnap search method.
 	if CurrentDump != nil && CurrentDump.utime > 0 {
 		CurrentDump.RLock()
 
-		resp := &pb.PongResponse{Pong: SrvPongMessage, RegistryUpdateTime: CurrentDump.utime}
+		resp := &pb.PongResponse{
+			Pong:               SrvPongMessage,
+			RegistryUpdateTime: CurrentDump.utime,
+			Generation:         CurrentDump.generation,
+			DatasetHash:        CurrentDump.DatasetHash(),
+			Payloads:           payloadsKept(),
+			RegistryAge:        time.Now().Unix() - CurrentDump.utime,
+			Paused:             Maintenance().Paused,
+		}
 
 		CurrentDump.RUnlock()
 
@@ -237,3 +410,73 @@ func (s *server) Ping(ctx context.Context, in *pb.PingRequest) (*pb.PongResponse
 
 	return &pb.PongResponse{Error: SrvDataNotReady}, nil
 }
+
+// GetRawContent - original <content>...</content> fragment by content ID.
+func (s *server) GetRawContent(ctx context.Context, in *pb.IDRequest) (*pb.RawContentResponse, error) {
+	query := in.GetQuery()
+
+	logger.Debug.Printf("[%s] Received raw content ID: %d\n", RequestID(ctx), query)
+
+	if !ParseConfig.KeepRaw {
+		return &pb.RawContentResponse{Error: SrvRawDisabled}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.RawContentResponse{RegistryUpdateTime: CurrentDump.utime}
+
+		if result, ok := CurrentDump.ContentIdx[query]; ok && result.Raw != nil {
+			raw, err := decompressRaw(result.Raw)
+			if err != nil {
+				logger.Error.Printf("[%s] Can't decompress raw content: %d: %s\n", RequestID(ctx), query, err.Error())
+
+				return &pb.RawContentResponse{Error: SrvRawBroken}, nil
+			}
+
+			resp.Raw = raw
+		}
+
+		return resp, nil
+	}
+
+	return &pb.RawContentResponse{Error: SrvDataNotReady}, nil
+}
+
+// GetContentSelectors - selectors of one type of a record page by page, for records too big for one response.
+func (s *server) GetContentSelectors(ctx context.Context, in *pb.ContentSelectorsRequest) (*pb.ContentSelectorsResponse, error) {
+	logger.Debug.Printf("[%s] Received content selectors: %d, %s, %q, %d\n", RequestID(ctx), in.GetId(), in.GetType(), in.GetCursor(), in.GetLimit())
+
+	if in.GetId() <= 0 {
+		return &pb.ContentSelectorsResponse{Error: SrvBadID}, nil
+	}
+
+	if !contentSelectorKind(in.GetType()) {
+		return &pb.ContentSelectorsResponse{Error: SrvBadSelector}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		pack, ok := CurrentDump.ContentIdx[in.GetId()]
+		if !ok {
+			return &pb.ContentSelectorsResponse{RegistryUpdateTime: CurrentDump.utime}, nil
+		}
+
+		page, total, next, ok := ContentSelectorPage(pack, in.GetType(), in.GetCursor(), in.GetLimit())
+		if !ok {
+			return &pb.ContentSelectorsResponse{Error: SrvBadCursor}, nil
+		}
+
+		return &pb.ContentSelectorsResponse{
+			RegistryUpdateTime: pack.RegistryUpdateTime,
+			Total:              int32(total),
+			Selectors:          page,
+			NextCursor:         next,
+		}, nil
+	}
+
+	return &pb.ContentSelectorsResponse{Error: SrvDataNotReady}, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// GetAddressAnomalies - IPs and subnets of the current records which are no addresses or no sensible
+// block targets, with the record id and the value as in the registry.
+func (s *server) GetAddressAnomalies(ctx context.Context, in *pb.AnomaliesRequest) (*pb.AnomaliesResponse, error) {
+	logger.Debug.Printf("[%s] Received address anomalies: %q, %d, %d\n", RequestID(ctx), in.GetKind(), in.GetOffset(), in.GetLimit())
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		anomalies, counts := CurrentDump.Anomalies(in.GetKind())
+		start, end := pageBounds(len(anomalies), in.GetOffset(), in.GetLimit())
+
+		policy := ParseConfig.Anomalies
+		if policy == "" {
+			policy = parser.AnomalyKeep
+		}
+
+		resp := &pb.AnomaliesResponse{
+			RegistryUpdateTime: CurrentDump.utime,
+			Anomalies:          make([]*pb.AddressAnomaly, 0, end-start),
+			Total:              int32(len(anomalies)),
+			Counts:             make([]*pb.AnomalyCount, 0, len(counts)),
+			Policy:             policy,
+		}
+
+		for _, a := range anomalies[start:end] {
+			resp.Anomalies = append(resp.Anomalies, &pb.AddressAnomaly{Id: a.ID, Field: a.Field, Value: a.Value, Kind: a.Kind})
+		}
+
+		for kind, n := range counts {
+			resp.Counts = append(resp.Counts, &pb.AnomalyCount{Kind: kind, Count: int32(n)})
+		}
+
+		sort.Slice(resp.Counts, func(i, j int) bool { return resp.Counts[i].Kind < resp.Counts[j].Kind })
+
+		return resp, nil
+	}
+
+	return &pb.AnomaliesResponse{Error: SrvDataNotReady}, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// GetASNReport - blocked addresses and records per autonomous system, for collateral damage estimates.
+func (s *server) GetASNReport(ctx context.Context, in *pb.ASNReportRequest) (*pb.ASNReportResponse, error) {
+	logger.Debug.Printf("[%s] Received ASN report: %v, limit %d, %q\n", RequestID(ctx), in.GetAsn(), in.GetLimit(), in.GetFilter())
+
+	if ASNs == nil {
+		return &pb.ASNReportResponse{Error: SrvNoASN}, nil
+	}
+
+	records, err := ParseExportFilter(in.GetFilter())
+	if err != nil {
+		return &pb.ASNReportResponse{Error: SrvBadFilter + ": " + err.Error()}, nil
+	}
+
+	if CurrentDump == nil || CurrentDump.utime == 0 {
+		return &pb.ASNReportResponse{Error: SrvDataNotReady}, nil
+	}
+
+	filter := make(map[uint32]Nothing, len(in.GetAsn()))
+	for _, asn := range in.GetAsn() {
+		filter[asn] = Nothing{}
+	}
+
+	CurrentDump.RLock()
+	resp := &pb.ASNReportResponse{RegistryUpdateTime: CurrentDump.utime}
+	CurrentDump.RUnlock()
+
+	for _, u := range ASNReport(ASNs, records) {
+		if len(filter) > 0 {
+			if _, ok := filter[u.ASN]; !ok {
+				continue
+			}
+		}
+
+		if in.GetLimit() > 0 && len(resp.Rows) >= int(in.GetLimit()) {
+			break
+		}
+
+		resp.Rows = append(resp.Rows, &pb.ASNUsage{
+			Asn:     u.ASN,
+			Name:    u.Name,
+			Country: u.Country,
+			Ip4:     u.IP4,
+			Ip6:     int32(u.IP6),
+			Subnet6: int32(u.Subnet6),
+			Records: int32(u.Records),
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// GetChangelog - parse summaries of the last generations with annotations, oldest first.
+func (s *server) GetChangelog(ctx context.Context, in *pb.ChangelogRequest) (*pb.ChangelogResponse, error) {
+	logger.Debug.Printf("[%s] Received changelog: since %d, limit %d\n", RequestID(ctx), in.GetSince(), in.GetLimit())
+
+	filter, err := ParseTransitionFilter(in.GetTransitions())
+	if err != nil {
+		return &pb.ChangelogResponse{Error: SrvBadTransition + ": " + err.Error()}, nil
+	}
+
+	entries := Changelog.Since(in.GetSince(), int(in.GetLimit()))
+
+	resp := &pb.ChangelogResponse{Entries: make([]*pb.ChangelogEntry, 0, len(entries))}
+
+	for _, entry := range entries {
+		transitions := filter.Filter(entry.Transitions)
+		if filter != nil && len(transitions) == 0 {
+			continue
+		}
+
+		resp.Entries = append(resp.Entries, &pb.ChangelogEntry{
+			Generation:         entry.Generation,
+			RegistryUpdateTime: entry.UpdateTime,
+			Parsed:             entry.Parsed.Unix(),
+			Count:              int32(entry.Count),
+			Added:              int32(entry.Added),
+			Updated:            int32(entry.Updated),
+			Removed:            int32(entry.Removed),
+			Annotations:        entry.Annotations,
+			Transitions:        newPbTransitions(transitions),
+		})
+	}
+
+	return resp, nil
+}
+
+// newPbTransitions - block type transitions of the changelog entry.
+func newPbTransitions(list []BlockTypeTransition) []*pb.BlockTypeTransition {
+	var transitions []*pb.BlockTypeTransition
+
+	for _, t := range list {
+		transitions = append(transitions, &pb.BlockTypeTransition{Id: t.ID, From: t.From, To: t.To})
+	}
+
+	return transitions
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// comparePeerTimeout - max time to get the peer status.
+const comparePeerTimeout = 10 * time.Second
+
+// ComparePeers - peers CompareWith may connect to, the server never dials arbitrary addresses.
+var ComparePeers = make(StringMap)
+
+// SetComparePeers - peers from the comma separated list.
+func SetComparePeers(list string) {
+	for _, peer := range strings.Split(list, ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			ComparePeers[peer] = Nothing{}
+		}
+	}
+}
+
+// CompareWith - compare the dataset with the peer, for replica validation.
+func (s *server) CompareWith(ctx context.Context, in *pb.CompareRequest) (*pb.CompareResponse, error) {
+	peer := in.GetPeer()
+
+	logger.Debug.Printf("[%s] Received compare with: %s\n", RequestID(ctx), peer)
+
+	if _, ok := ComparePeers[peer]; !ok {
+		return &pb.CompareResponse{Error: SrvUnknownPeer}, nil
+	}
+
+	if CurrentDump == nil || CurrentDump.utime == 0 {
+		return &pb.CompareResponse{Error: SrvDataNotReady}, nil
+	}
+
+	CurrentDump.RLock()
+	resp := &pb.CompareResponse{
+		RegistryUpdateTime: CurrentDump.utime,
+		Generation:         CurrentDump.generation,
+		DatasetHash:        CurrentDump.DatasetHash(),
+	}
+	CurrentDump.RUnlock()
+
+	pong, err := pingPeer(ctx, peer)
+	if err != nil {
+		logger.Debug.Printf("[%s] Can't compare with %s: %s\n", RequestID(ctx), peer, err.Error())
+
+		resp.Error = SrvPeerFailed + ": " + err.Error()
+
+		return resp, nil
+	}
+
+	if pong.GetError() != "" {
+		resp.Error = SrvPeerFailed + ": " + pong.GetError()
+
+		return resp, nil
+	}
+
+	resp.PeerRegistryUpdateTime = pong.GetRegistryUpdateTime()
+	resp.PeerGeneration = pong.GetGeneration()
+	resp.PeerDatasetHash = pong.GetDatasetHash()
+	resp.SameDump = resp.RegistryUpdateTime == resp.PeerRegistryUpdateTime
+	resp.Identical = resp.SameDump && resp.DatasetHash == resp.PeerDatasetHash
+
+	return resp, nil
+}
+
+// pingPeer - status of the peer.
+func pingPeer(ctx context.Context, peer string) (*pb.PongResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, comparePeerTimeout)
+	defer cancel()
+
+	conn, ctx, err := dialPeer(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	return pb.NewCheckClient(conn).Ping(ctx, &pb.PingRequest{Ping: "compare"})
+}
+
+// dialPeer - connection to the peer, the peer logs the same request id.
+func dialPeer(ctx context.Context, peer string) (*grpc.ClientConn, context.Context, error) {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id)
+	}
+
+	conn, err := grpc.DialContext(ctx, peer, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, ctx, nil
+}
+
+// compareFields - result fields CompareSelector compares, the rest depend on the local policy.
+var compareFields = &fieldmaskpb.FieldMask{Paths: []string{"id", "registryUpdateTime", "blockType", "decision", "matchedBy"}}
+
+// selectorQuery - the same search of the local index and the peer.
+type selectorQuery struct {
+	kind     string
+	id       int32
+	ip       netip.Addr
+	subnet   netip.Prefix
+	domain   string
+	url      string
+	decision uint64
+}
+
+// newSelectorQuery - query of the kind: ip, subnet, domain, url, id or decision.
+func newSelectorQuery(kind, query string) (selectorQuery, error) {
+	q := selectorQuery{kind: kind}
+
+	switch kind {
+	case "ip":
+		ip, err := netip.ParseAddr(query)
+		if err != nil {
+			return q, err
+		}
+
+		q.ip = ip.Unmap()
+	case "subnet":
+		subnet, err := netip.ParsePrefix(query)
+		if err != nil {
+			return q, err
+		}
+
+		q.subnet = subnet.Masked()
+	case "domain":
+		q.domain = NormalizeDomain(query)
+	case "url":
+		q.url = NormalizeURL(query)
+	case "id":
+		n, err := strconv.ParseInt(query, 10, 32)
+		if err != nil {
+			return q, err
+		}
+
+		q.id = int32(n)
+	case "decision":
+		n, err := strconv.ParseUint(query, 10, 64)
+		if err != nil {
+			return q, err
+		}
+
+		q.decision = n
+	default:
+		return q, fmt.Errorf("unknown kind: %s", kind)
+	}
+
+	return q, nil
+}
+
+// local - search the local index.
+func (q selectorQuery) local(ctx context.Context, s *server) (*pb.SearchResponse, error) {
+	switch {
+	case q.kind == "ip" && q.ip.Is4():
+		return s.SearchIP4(ctx, &pb.IP4Request{Query: parser.Addr4Uint32(q.ip), Fields: compareFields})
+	case q.kind == "ip":
+		ip6 := q.ip.As16()
+
+		return s.SearchIP6(ctx, &pb.IP6Request{Query: ip6[:], Fields: compareFields})
+	case q.kind == "subnet":
+		return s.Query(ctx, q.subnetQuery())
+	case q.kind == "domain":
+		return s.SearchDomain(ctx, &pb.DomainRequest{Query: q.domain, Fields: compareFields})
+	case q.kind == "url":
+		return s.SearchURL(ctx, &pb.URLRequest{Query: q.url, Fields: compareFields})
+	case q.kind == "id":
+		return s.SearchID(ctx, &pb.IDRequest{Query: q.id, Fields: compareFields})
+	}
+
+	return s.SearchDecision(ctx, &pb.DecisionRequest{Query: q.decision, Fields: compareFields})
+}
+
+// subnetQuery - records with addresses or subnets overlapping the subnet, there is no subnet index search.
+func (q selectorQuery) subnetQuery() *pb.QueryRequest {
+	return &pb.QueryRequest{Query: "subnet = " + q.subnet.String(), Limit: maxPageLimit, Fields: compareFields}
+}
+
+// peer - the same search of the peer.
+func (q selectorQuery) peer(ctx context.Context, client pb.CheckClient) (*pb.SearchResponse, error) {
+	switch {
+	case q.kind == "ip" && q.ip.Is4():
+		return client.SearchIP4(ctx, &pb.IP4Request{Query: parser.Addr4Uint32(q.ip), Fields: compareFields})
+	case q.kind == "ip":
+		ip6 := q.ip.As16()
+
+		return client.SearchIP6(ctx, &pb.IP6Request{Query: ip6[:], Fields: compareFields})
+	case q.kind == "subnet":
+		return client.Query(ctx, q.subnetQuery())
+	case q.kind == "domain":
+		return client.SearchDomain(ctx, &pb.DomainRequest{Query: q.domain, Fields: compareFields})
+	case q.kind == "url":
+		return client.SearchURL(ctx, &pb.URLRequest{Query: q.url, Fields: compareFields})
+	case q.kind == "id":
+		return client.SearchID(ctx, &pb.IDRequest{Query: q.id, Fields: compareFields})
+	}
+
+	return client.SearchDecision(ctx, &pb.DecisionRequest{Query: q.decision, Fields: compareFields})
+}
+
+// CompareSelector - run the same search here and on the peer and report differing results,
+// for mirror operators to find divergence caused by parse bugs or stale dumps.
+func (s *server) CompareSelector(ctx context.Context, in *pb.CompareSelectorRequest) (*pb.CompareSelectorResponse, error) {
+	peer := in.GetPeer()
+
+	logger.Debug.Printf("[%s] Received compare selector with: %s: %s %s\n", RequestID(ctx), peer, in.GetKind(), in.GetQuery())
+
+	if _, ok := ComparePeers[peer]; !ok {
+		return &pb.CompareSelectorResponse{Error: SrvUnknownPeer}, nil
+	}
+
+	q, err := newSelectorQuery(in.GetKind(), in.GetQuery())
+	if err != nil {
+		return &pb.CompareSelectorResponse{Error: SrvBadSelector + ": " + err.Error()}, nil
+	}
+
+	local, _ := q.local(ctx, s)
+	if local.GetError() != "" {
+		return &pb.CompareSelectorResponse{Error: local.GetError()}, nil
+	}
+
+	resp := &pb.CompareSelectorResponse{RegistryUpdateTime: local.GetRegistryUpdateTime(), Results: int32(len(local.GetResults()))}
+
+	remote, err := searchPeer(ctx, peer, q)
+	if err == nil && remote.GetError() != "" {
+		err = fmt.Errorf("%s", remote.GetError())
+	}
+
+	if err != nil {
+		logger.Debug.Printf("[%s] Can't compare with %s: %s\n", RequestID(ctx), peer, err.Error())
+
+		resp.Error = SrvPeerFailed + ": " + err.Error()
+
+		return resp, nil
+	}
+
+	resp.PeerRegistryUpdateTime = remote.GetRegistryUpdateTime()
+	resp.PeerResults = int32(len(remote.GetResults()))
+	resp.SameDump = resp.RegistryUpdateTime == resp.PeerRegistryUpdateTime
+	resp.Diffs = diffResults(local.GetResults(), remote.GetResults())
+
+	return resp, nil
+}
+
+// searchPeer - run the query on the peer.
+func searchPeer(ctx context.Context, peer string, q selectorQuery) (*pb.SearchResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, comparePeerTimeout)
+	defer cancel()
+
+	conn, ctx, err := dialPeer(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	return q.peer(ctx, pb.NewCheckClient(conn))
+}
+
+// diffResults - results differing by the id and the matched selector, ordered by id.
+func diffResults(local, peer []*pb.Content) []*pb.SelectorDiff {
+	key := func(v *pb.Content) string {
+		return fmt.Sprintf("%d\x00%s\x00%s", v.GetId(), v.GetMatchedBy().GetKind(), v.GetMatchedBy().GetSelector())
+	}
+
+	diffs := make(map[string]*pb.SelectorDiff, len(local))
+
+	for _, v := range local {
+		diffs[key(v)] = &pb.SelectorDiff{Id: v.GetId(), MatchedBy: v.GetMatchedBy(), Local: v}
+	}
+
+	for _, v := range peer {
+		if d, ok := diffs[key(v)]; ok {
+			d.Peer = v
+		} else {
+			diffs[key(v)] = &pb.SelectorDiff{Id: v.GetId(), MatchedBy: v.GetMatchedBy(), Peer: v}
+		}
+	}
+
+	var res []*pb.SelectorDiff
+
+	for _, d := range diffs {
+		if d.Local != nil && d.Peer != nil {
+			if d.Local.GetRegistryUpdateTime() != d.Peer.GetRegistryUpdateTime() {
+				d.Fields = append(d.Fields, "registryUpdateTime")
+			}
+
+			if d.Local.GetBlockType() != d.Peer.GetBlockType() {
+				d.Fields = append(d.Fields, "blockType")
+			}
+
+			if !sameDecision(d.Local.GetDecision(), d.Peer.GetDecision()) {
+				d.Fields = append(d.Fields, "decision")
+			}
+
+			if len(d.Fields) == 0 {
+				continue
+			}
+		}
+
+		res = append(res, d)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].GetId() != res[j].GetId() {
+			return res[i].GetId() < res[j].GetId()
+		}
+
+		return res[i].GetMatchedBy().GetSelector() < res[j].GetMatchedBy().GetSelector()
+	})
+
+	return res
+}
+
+// sameDecision - the same number, date, org and hash.
+func sameDecision(a, b *pb.Decision) bool {
+	return a.GetNumber() == b.GetNumber() && a.GetDate() == b.GetDate() && a.GetOrg() == b.GetOrg() && a.GetHash() == b.GetHash()
+}
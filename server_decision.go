@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// GetDecisionSummary - records of a decision with their selector counts, see DecisionSummary.
+func (s *server) GetDecisionSummary(ctx context.Context, in *pb.DecisionSummaryRequest) (*pb.DecisionSummaryResponse, error) {
+	d := in.GetDecision()
+
+	logger.Debug.Printf("[%s] Received decision summary: %d %q %q %q, %d, %d\n",
+		RequestID(ctx), d.GetHash(), d.GetOrg(), d.GetNumber(), d.GetDate(), in.GetOffset(), in.GetLimit())
+
+	hash := d.GetHash()
+
+	if hash == 0 {
+		if d.GetOrg() == "" || d.GetNumber() == "" || d.GetDate() == "" {
+			return &pb.DecisionSummaryResponse{Error: SrvBadDecision}, nil
+		}
+
+		var err error
+
+		hash, err = DecisionHash(d.GetOrg(), d.GetNumber(), d.GetDate())
+		if err != nil {
+			logger.Error.Printf("[%s] Can't hash decision: %s\n", RequestID(ctx), err.Error())
+
+			return &pb.DecisionSummaryResponse{Error: SrvBadDecision}, nil
+		}
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.DecisionSummaryResponse{RegistryUpdateTime: CurrentDump.utime}
+
+		summary, ok := CurrentDump.DecisionSummary(hash)
+		if !ok {
+			return resp, nil
+		}
+
+		resp.Decision = CurrentDump.ContentIdx[summary.IDs[0]].newPbDecision()
+		resp.Total = int32(len(summary.IDs))
+		resp.Ids = paginate(summary.IDs, in.GetOffset(), in.GetLimit())
+		resp.Ip4 = int32(summary.Selectors[SelectorIP4])
+		resp.Ip6 = int32(summary.Selectors[SelectorIP6])
+		resp.Subnet4 = int32(summary.Selectors[SelectorSubnet4])
+		resp.Subnet6 = int32(summary.Selectors[SelectorSubnet6])
+		resp.Domains = int32(summary.Selectors[SelectorDomain])
+		resp.Urls = int32(summary.Selectors[SelectorURL])
+
+		return resp, nil
+	}
+
+	return &pb.DecisionSummaryResponse{Error: SrvDataNotReady}, nil
+}
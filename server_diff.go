@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// DiffGenerations - stream selector level diff between two generations.
+func (s *server) DiffGenerations(in *pb.DiffRequest, stream pb.Check_DiffGenerationsServer) error {
+	logger.Debug.Printf("[%s] Received diff: %d - %d\n", RequestID(stream.Context()), in.GetFrom(), in.GetTo())
+
+	if CurrentDump == nil || CurrentDump.utime == 0 {
+		return stream.Send(&pb.SelectorDelta{Error: SrvDataNotReady})
+	}
+
+	to := in.GetTo()
+	if to == 0 {
+		CurrentDump.RLock()
+		to = CurrentDump.generation
+		CurrentDump.RUnlock()
+	}
+
+	fromSet, err := GenerationSelectors(s.dir, in.GetFrom())
+	if err != nil {
+		logger.Debug.Printf("[%s] Can't load generation: %s\n", RequestID(stream.Context()), err.Error())
+
+		return stream.Send(&pb.SelectorDelta{Error: SrvNoGeneration})
+	}
+
+	toSet, err := GenerationSelectors(s.dir, to)
+	if err != nil {
+		logger.Debug.Printf("[%s] Can't load generation: %s\n", RequestID(stream.Context()), err.Error())
+
+		return stream.Send(&pb.SelectorDelta{Error: SrvNoGeneration})
+	}
+
+	added, removed := DiffSelectors(fromSet, toSet)
+	added, removed = excludeSelectors(added), excludeSelectors(removed)
+
+	for _, sel := range removed {
+		if err := stream.Send(&pb.SelectorDelta{Kind: sel.Kind, Selector: sel.Value}); err != nil {
+			return err
+		}
+	}
+
+	for _, sel := range added {
+		if err := stream.Send(&pb.SelectorDelta{Kind: sel.Kind, Selector: sel.Value, Added: true}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// excludeSelectors - selectors not covered by the local exclusion list.
+func excludeSelectors(a []Selector) []Selector {
+	e := CurrentExclusions()
+	if e == nil {
+		return a
+	}
+
+	kept := a[:0]
+
+	for _, sel := range a {
+		if e.Selector(sel) == "" {
+			kept = append(kept, sel)
+		}
+	}
+
+	return kept
+}
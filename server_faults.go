@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// InjectFault - arm or clear poller failures of the test mode, see FaultInjection. It is admin only.
+func (s *server) InjectFault(ctx context.Context, in *pb.FaultRequest) (*pb.FaultResponse, error) {
+	logger.Debug.Printf("[%s] Received fault: %q, %d, %t\n", RequestID(ctx), in.GetStage(), in.GetCount(), in.GetClear())
+
+	if !FaultInjection {
+		return &pb.FaultResponse{Error: SrvNoFaults}, nil
+	}
+
+	if !adminAllowed(ctx) {
+		return &pb.FaultResponse{Error: SrvAdminOnly}, nil
+	}
+
+	switch {
+	case in.GetClear() && in.GetStage() == "":
+		Faults.Clear("")
+	case !validFaultStage(in.GetStage()) || in.GetCount() < 0:
+		return &pb.FaultResponse{Error: SrvBadStage}, nil
+	case in.GetClear():
+		Faults.Clear(in.GetStage())
+	default:
+		Faults.Arm(in.GetStage(), in.GetCount())
+	}
+
+	logger.Warning.Printf("[%s] Faults: %+v\n", RequestID(ctx), Faults.List())
+
+	resp := &pb.FaultResponse{}
+
+	for _, f := range Faults.List() {
+		resp.Faults = append(resp.Faults, &pb.Fault{Stage: f.Stage, Remaining: f.Remaining})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// ListRecent - newest added, removed or urgent records.
+func (s *server) ListRecent(ctx context.Context, in *pb.RecentRequest) (*pb.RecentResponse, error) {
+	logger.Debug.Printf("[%s] Received recent: removed=%v, urgent=%v, %d\n", RequestID(ctx), in.GetRemoved(), in.GetUrgent(), in.GetLimit())
+
+	feed := RecentAdded
+
+	switch {
+	case in.GetUrgent():
+		feed = RecentUrgent
+	case in.GetRemoved():
+		feed = RecentRemoved
+	}
+
+	items := feed.Latest(int(in.GetLimit()))
+
+	resp := &pb.RecentResponse{Items: make([]*pb.RecentItem, 0, len(items))}
+
+	for _, item := range items {
+		v := &pb.RecentItem{
+			Id:                 item.ID,
+			Generation:         item.Generation,
+			RegistryUpdateTime: item.UpdateTime,
+			Time:               item.Time.Unix(),
+			DecisionNumber:     item.Decision.Number,
+			DecisionDate:       item.Decision.Date,
+			DecisionOrg:        item.Decision.Org,
+			IncludeTime:        item.IncludeTime,
+			BlockType:          item.BlockType,
+			Selectors:          make([]*pb.FeedSelector, 0, len(item.Selectors)),
+			More:               int32(item.More),
+			Urgent:             item.Urgent,
+		}
+
+		for _, sel := range item.Selectors {
+			v.Selectors = append(v.Selectors, &pb.FeedSelector{Kind: sel.Kind, Value: sel.Value})
+		}
+
+		resp.Items = append(resp.Items, v)
+	}
+
+	return resp, nil
+}
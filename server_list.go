@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// Pagination limits for list-style RPCs.
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 10000
+)
+
+// pageBounds - slice bounds of the page for a list of n items.
+func pageBounds(n int, offset, limit int32) (int, int) {
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	if offset < 0 || int(offset) >= n {
+		return n, n
+	}
+
+	end := int(offset) + int(limit)
+	if end > n {
+		end = n
+	}
+
+	return int(offset), end
+}
+
+// paginate - cut the page from ids.
+func paginate(ids []int32, offset, limit int32) []int32 {
+	start, end := pageBounds(len(ids), offset, limit)
+
+	return ids[start:end]
+}
+
+// Result orders of list-style RPCs.
+const (
+	OrderID           = "id"
+	OrderUpdateTime   = "update-time"
+	OrderDecisionDate = "decision-date"
+	OrderIncludeTime  = "include-time"
+	OrderTs           = "ts"      // registry ts, records without it come first.
+	OrderOrdinal      = "ordinal" // position in the dump file, records of older snapshots come first.
+)
+
+// orderKey - sort key of the record for the order, nil for an unknown order. Call it under read lock.
+func (dump *Dump) orderKey(order string) func(*PackedContent) int64 {
+	switch order {
+	case OrderOrdinal:
+		return func(pack *PackedContent) int64 { return int64(dump.ordinals.Get(pack.ID)) }
+	case OrderID:
+		return func(pack *PackedContent) int64 { return int64(pack.ID) }
+	case OrderUpdateTime:
+		return func(pack *PackedContent) int64 { return pack.RegistryUpdateTime }
+	case OrderDecisionDate:
+		return func(pack *PackedContent) int64 { return pack.DecisionDate }
+	case OrderIncludeTime:
+		return func(pack *PackedContent) int64 { return pack.IncludeTime }
+	case OrderTs:
+		return func(pack *PackedContent) int64 { return pack.Ts }
+	}
+
+	return nil
+}
+
+// orderResults - ids ordered by the key, then by ID, call it under read lock. The empty order is
+// the index one (def), the ids are returned as they are if it is ascending. ok is false for an unknown order.
+func (dump *Dump) orderResults(ids []int32, order, def string, descending bool) ([]int32, bool) {
+	if order == "" {
+		if !descending {
+			return ids, true
+		}
+
+		order = def
+	}
+
+	key := dump.orderKey(order)
+	if key == nil {
+		return nil, false
+	}
+
+	keys := make(map[int32]int64, len(ids))
+	for _, id := range ids {
+		if pack, ok := dump.ContentIdx[id]; ok {
+			keys[id] = key(pack)
+		}
+	}
+
+	// the ids may be shared.
+	ordered := append([]int32(nil), ids...)
+
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if descending {
+			a, b = b, a
+		}
+
+		if keys[a] != keys[b] {
+			return keys[a] < keys[b]
+		}
+
+		return a < b
+	})
+
+	return ordered, true
+}
+
+// SearchDecisionDate - list content by decision date range.
+func (s *server) SearchDecisionDate(ctx context.Context, in *pb.DecisionDateRequest) (*pb.SearchResponse, error) {
+	logger.Debug.Printf("[%s] Received decision date range: %s - %s\n", RequestID(ctx), in.GetFrom(), in.GetTo())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	from, to := parser.ParseDecisionTime(in.GetFrom()), parser.ParseDecisionTime(in.GetTo())
+	if from == 0 || to == 0 {
+		return &pb.SearchResponse{Error: SrvBadDate}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		results, ok := CurrentDump.orderResults(CurrentDump.decisionDateIdx.Range(from, to), in.GetOrderBy(), OrderDecisionDate, in.GetDescending())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvBadOrder}, nil
+		}
+
+		page := paginate(results, in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = make([]*pb.Content, 0, len(page))
+
+		for _, id := range page {
+			if cont, ok := CurrentDump.ContentIdx[id]; ok {
+				resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: MatchDecisionDate}))
+			}
+		}
+
+		return resp, nil
+	}
+
+	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
+}
+
+// includeTimeRange - [from, to] of the request, ok is false for a bad request.
+func includeTimeRange(in *pb.IncludeTimeRequest, now int64) (int64, int64, bool) {
+	switch {
+	case in.GetScheduled() && in.GetWithin() != 0:
+		return 0, 0, false
+	case in.GetScheduled():
+		return now + 1, math.MaxInt64, true
+	}
+
+	return timeRange(in.GetFrom(), in.GetTo(), in.GetWithin(), now)
+}
+
+// timeRange - [from, to] of the request bounds, the last within seconds if within is set.
+func timeRange(from, to, within, now int64) (int64, int64, bool) {
+	switch {
+	case within < 0:
+		return 0, 0, false
+	case within > 0:
+		return now - within, now, true
+	}
+
+	if to == 0 {
+		to = math.MaxInt64
+	}
+
+	if from > to {
+		return 0, 0, false
+	}
+
+	return from, to, true
+}
+
+// SearchIncludeTime - list content by include time: a range, scheduled (included in the future)
+// or recently included. Records are ordered by include time by default.
+func (s *server) SearchIncludeTime(ctx context.Context, in *pb.IncludeTimeRequest) (*pb.SearchResponse, error) {
+	logger.Debug.Printf("[%s] Received include time range: %d - %d, scheduled: %t, within: %d\n", RequestID(ctx),
+		in.GetFrom(), in.GetTo(), in.GetScheduled(), in.GetWithin())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	from, to, ok := includeTimeRange(in, time.Now().Unix())
+	if !ok {
+		return &pb.SearchResponse{Error: SrvBadTimeRange}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		results, ok := CurrentDump.orderResults(CurrentDump.includeTimeIdx.Range(from, to), in.GetOrderBy(), OrderIncludeTime, in.GetDescending())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvBadOrder}, nil
+		}
+
+		page := paginate(results, in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = make([]*pb.Content, 0, len(page))
+
+		for _, id := range page {
+			if cont, ok := CurrentDump.ContentIdx[id]; ok {
+				resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: MatchIncludeTime}))
+			}
+		}
+
+		return resp, nil
+	}
+
+	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
+}
+
+// SearchRegistryTs - list content by the registry ts: a range or recently changed in the registry,
+// whenever the changes are seen here. Records without ts are not listed, they are ordered by ts by default.
+func (s *server) SearchRegistryTs(ctx context.Context, in *pb.RegistryTsRequest) (*pb.SearchResponse, error) {
+	logger.Debug.Printf("[%s] Received registry ts range: %d - %d, within: %d\n", RequestID(ctx), in.GetFrom(), in.GetTo(), in.GetWithin())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	from, to, ok := timeRange(in.GetFrom(), in.GetTo(), in.GetWithin(), time.Now().Unix())
+	if !ok {
+		return &pb.SearchResponse{Error: SrvBadTimeRange}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		results, ok := CurrentDump.orderResults(CurrentDump.tsIdx.Range(from, to), in.GetOrderBy(), OrderTs, in.GetDescending())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvBadOrder}, nil
+		}
+
+		page := paginate(results, in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = make([]*pb.Content, 0, len(page))
+
+		for _, id := range page {
+			if cont, ok := CurrentDump.ContentIdx[id]; ok {
+				resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: MatchTs}))
+			}
+		}
+
+		return resp, nil
+	}
+
+	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
+}
+
+// ListSelectorlessRecords - list records without IPs, subnets, domains and URLs, ordered by id by default.
+// They carry a decision and a block type only, so no selector search finds them.
+func (s *server) ListSelectorlessRecords(ctx context.Context, in *pb.SelectorlessRequest) (*pb.SearchResponse, error) {
+	logger.Debug.Printf("[%s] Received selectorless list: %d, %d\n", RequestID(ctx), in.GetOffset(), in.GetLimit())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		results, ok := CurrentDump.orderResults(CurrentDump.SelectorlessRecords(), in.GetOrderBy(), OrderID, in.GetDescending())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvBadOrder}, nil
+		}
+
+		page := paginate(results, in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = make([]*pb.Content, 0, len(page))
+
+		for _, id := range page {
+			if cont, ok := CurrentDump.ContentIdx[id]; ok {
+				resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: MatchSelectorless}))
+			}
+		}
+
+		return resp, nil
+	}
+
+	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
+}
+
+// ListSNI - list all server names needed to block HTTPS, domain and mask records.
+func (s *server) ListSNI(ctx context.Context, in *pb.SNIRequest) (*pb.SNIResponse, error) {
+	logger.Debug.Printf("[%s] Received SNI list: %d, %d, %q\n", RequestID(ctx), in.GetOffset(), in.GetLimit(), in.GetFilter())
+
+	filter, err := ParseExportFilter(in.GetFilter())
+	if err != nil {
+		return &pb.SNIResponse{Error: SrvBadFilter + ": " + err.Error()}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		list := excludeSNI(CurrentDump.SNIList(filter))
+		start, end := pageBounds(len(list), in.GetOffset(), in.GetLimit())
+
+		return &pb.SNIResponse{
+			RegistryUpdateTime: CurrentDump.utime,
+			Sni:                list[start:end],
+			Total:              int32(len(list)),
+		}, nil
+	}
+
+	return &pb.SNIResponse{Error: SrvDataNotReady}, nil
+}
+
+// SearchDomainSuffix - indexed domains under the suffix with their records, e.g. all blocked .ua domains.
+func (s *server) SearchDomainSuffix(ctx context.Context, in *pb.DomainSuffixRequest) (*pb.DomainSuffixResponse, error) {
+	logger.Debug.Printf("[%s] Received domain suffix: %s, %d, %d\n", RequestID(ctx), in.GetSuffix(), in.GetOffset(), in.GetLimit())
+
+	suffix := NormalizeSuffix(in.GetSuffix())
+	if suffix == "" {
+		return &pb.DomainSuffixResponse{Error: SrvBadSelector}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		domains := CurrentDump.DomainsBySuffix(suffix)
+		start, end := pageBounds(len(domains), in.GetOffset(), in.GetLimit())
+
+		resp := &pb.DomainSuffixResponse{
+			RegistryUpdateTime: CurrentDump.utime,
+			Domains:            make([]*pb.DomainHit, 0, end-start),
+			Total:              int32(len(domains)),
+		}
+
+		for _, domain := range domains[start:end] {
+			// index sets are changed in place, the response is sent after the lock.
+			ids := append([]int32(nil), CurrentDump.domainIdx[domain]...)
+			resp.Domains = append(resp.Domains, &pb.DomainHit{Domain: domain, Ids: ids})
+		}
+
+		return resp, nil
+	}
+
+	return &pb.DomainSuffixResponse{Error: SrvDataNotReady}, nil
+}
+
+// ListHotSelectors - IPs, subnets, domains and URLs referenced by the most records, i.e. the most collateral
+// of blocking and duplication in the registry.
+func (s *server) ListHotSelectors(ctx context.Context, in *pb.HotSelectorRequest) (*pb.HotSelectorResponse, error) {
+	logger.Debug.Printf("[%s] Received hot selectors: %s, %d, %d, %d\n", RequestID(ctx), in.GetKind(), in.GetMinCount(), in.GetOffset(), in.GetLimit())
+
+	if !hotKind(in.GetKind()) {
+		return &pb.HotSelectorResponse{Error: SrvBadSelector}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		hot, total := CurrentDump.HotSelectors(in.GetKind(), int(in.GetMinCount()), in.GetOffset(), in.GetLimit())
+
+		resp := &pb.HotSelectorResponse{
+			RegistryUpdateTime: CurrentDump.utime,
+			Selectors:          make([]*pb.HotSelector, 0, len(hot)),
+			Total:              int32(total),
+		}
+
+		for _, h := range hot {
+			v := &pb.HotSelector{Kind: h.Sel.Kind, Value: h.Sel.Value, Count: int32(h.Count)}
+			if in.GetIds() {
+				v.Ids = append([]int32(nil), CurrentDump.selectorRecords(h.Sel)...)
+			}
+
+			resp.Selectors = append(resp.Selectors, v)
+		}
+
+		return resp, nil
+	}
+
+	return &pb.HotSelectorResponse{Error: SrvDataNotReady}, nil
+}
+
+// ListOrganizations - canonical decision organizations with record counts.
+func (s *server) ListOrganizations(ctx context.Context, in *pb.OrgRequest) (*pb.OrgResponse, error) {
+	logger.Debug.Printf("[%s] Received organization list\n", RequestID(ctx))
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		counts := CurrentDump.OrgCounts()
+		resp := &pb.OrgResponse{
+			RegistryUpdateTime: CurrentDump.utime,
+			Orgs:               make([]*pb.OrgCount, 0, len(counts)),
+		}
+
+		for _, c := range counts {
+			resp.Orgs = append(resp.Orgs, &pb.OrgCount{Org: c.Org, Count: int32(c.Count)})
+		}
+
+		return resp, nil
+	}
+
+	return &pb.OrgResponse{Error: SrvDataNotReady}, nil
+}
+
+// ListEntryTypes - registry entry types of the records with record counts.
+func (s *server) ListEntryTypes(ctx context.Context, in *pb.EntryTypeRequest) (*pb.EntryTypeResponse, error) {
+	logger.Debug.Printf("[%s] Received entry type list\n", RequestID(ctx))
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		counts := CurrentDump.EntryTypeCounts()
+		resp := &pb.EntryTypeResponse{
+			RegistryUpdateTime: CurrentDump.utime,
+			EntryTypes:         make([]*pb.EntryTypeCount, 0, len(counts)),
+		}
+
+		for _, c := range counts {
+			resp.EntryTypes = append(resp.EntryTypes, &pb.EntryTypeCount{EntryType: c.EntryType, Name: EntryTypeName(c.EntryType), Count: int32(c.Count)})
+		}
+
+		return resp, nil
+	}
+
+	return &pb.EntryTypeResponse{Error: SrvDataNotReady}, nil
+}
+
+// excludeSNI - server names not covered by the local exclusion list.
+// The cached list is shared, so it is copied.
+func excludeSNI(list []string) []string {
+	e := CurrentExclusions()
+	if e == nil {
+		return list
+	}
+
+	kept := make([]string, 0, len(list))
+
+	for _, name := range list {
+		if e.Domain(name) == "" {
+			kept = append(kept, name)
+		}
+	}
+
+	return kept
+}
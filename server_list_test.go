@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// TestSearchIncludeTime tests scheduled, recent and range include time filters and result orders.
+func TestSearchIncludeTime(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	now := time.Now()
+	includeTime := func(d time.Duration) string {
+		return now.Add(d).In(parser.LocationMSK).Format("2006-01-02T15:04:05")
+	}
+
+	var dump strings.Builder
+
+	dump.WriteString(`<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+`)
+
+	for id, d := range map[int]time.Duration{1: -48 * time.Hour, 2: -time.Hour, 3: 24 * time.Hour, 4: 48 * time.Hour} {
+		fmt.Fprintf(&dump, `<content id="%d" includeTime="%s" entryType="1" blockType="domain" hash="%d">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <domain><![CDATA[www.e%d.tld]]></domain>
+</content>
+`, id, includeTime(d), id, id)
+	}
+
+	dump.WriteString(`</reg:register>`)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(dump.String())); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	for name, tc := range map[string]struct {
+		in  *pb.IncludeTimeRequest
+		ids []int32
+	}{
+		"scheduled": {&pb.IncludeTimeRequest{Scheduled: true}, []int32{3, 4}},
+		"24h":       {&pb.IncludeTimeRequest{Within: 86400}, []int32{2}},
+		"range":     {&pb.IncludeTimeRequest{From: now.Add(-72 * time.Hour).Unix(), To: now.Unix()}, []int32{1, 2}},
+		"from":      {&pb.IncludeTimeRequest{From: now.Unix()}, []int32{3, 4}},
+		"page":      {&pb.IncludeTimeRequest{Offset: 1, Limit: 2}, []int32{2, 3}},
+		"latest":    {&pb.IncludeTimeRequest{Descending: true, Limit: 3}, []int32{4, 3, 2}},
+		"id":        {&pb.IncludeTimeRequest{OrderBy: OrderID, Descending: true}, []int32{4, 3, 2, 1}},
+		"ties":      {&pb.IncludeTimeRequest{OrderBy: OrderDecisionDate, Descending: true}, []int32{4, 3, 2, 1}},
+	} {
+		resp, err := s.SearchIncludeTime(context.Background(), tc.in)
+		if err != nil || resp.GetError() != "" {
+			t.Fatalf("%s: %v %s", name, err, resp.GetError())
+		}
+
+		var ids []int32
+		for _, c := range resp.GetResults() {
+			ids = append(ids, c.GetId())
+		}
+
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: %v, want %v", name, ids, tc.ids)
+		}
+	}
+
+	resp, _ := s.SearchIncludeTime(context.Background(), &pb.IncludeTimeRequest{Scheduled: true, Within: 60})
+	if resp.GetError() != SrvBadTimeRange {
+		t.Errorf("conflicting filters: %q", resp.GetError())
+	}
+
+	resp, _ = s.SearchIncludeTime(context.Background(), &pb.IncludeTimeRequest{OrderBy: "hash"})
+	if resp.GetError() != SrvBadOrder {
+		t.Errorf("unknown order: %q", resp.GetError())
+	}
+}
+
+// TestSearchRegistryTs tests records are listed by the registry ts, which is reindexed when it changes.
+func TestSearchRegistryTs(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	now := time.Now()
+
+	dump := func(ts map[int]time.Duration) string {
+		var b strings.Builder
+
+		b.WriteString(`<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+`)
+
+		for id := 1; id <= 3; id++ {
+			attr := ""
+			if d, ok := ts[id]; ok {
+				attr = ` ts="` + now.Add(d).Format(time.RFC3339) + `"`
+			}
+
+			fmt.Fprintf(&b, `<content id="%d" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="%d"%s>
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <domain><![CDATA[www.e%d.tld]]></domain>
+</content>
+`, id, id, attr, id)
+		}
+
+		b.WriteString(`</reg:register>`)
+
+		return b.String()
+	}
+
+	search := func(in *pb.RegistryTsRequest) []int32 {
+		resp, err := (&server{}).SearchRegistryTs(context.Background(), in)
+		if err != nil || resp.GetError() != "" {
+			t.Fatalf("%v: %v %s", in, err, resp.GetError())
+		}
+
+		var ids []int32
+		for _, c := range resp.GetResults() {
+			ids = append(ids, c.GetId())
+		}
+
+		return ids
+	}
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(dump(map[int]time.Duration{1: -30 * time.Hour, 2: -2 * time.Hour}))); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := search(&pb.RegistryTsRequest{Within: 3 * 3600}); !reflect.DeepEqual(ids, []int32{2}) {
+		t.Errorf("3h: %v", ids)
+	}
+
+	if ids := search(&pb.RegistryTsRequest{}); !reflect.DeepEqual(ids, []int32{1, 2}) {
+		t.Errorf("all: %v", ids)
+	}
+
+	// the registry changes record 1 again.
+	if err := Parse(strings.NewReader(dump(map[int]time.Duration{1: -time.Hour, 2: -2 * time.Hour}))); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := search(&pb.RegistryTsRequest{Within: 3 * 3600}); !reflect.DeepEqual(ids, []int32{2, 1}) {
+		t.Errorf("changed: %v", ids)
+	}
+
+	resp, _ := (&server{}).SearchRegistryTs(context.Background(), &pb.RegistryTsRequest{Within: -1})
+	if resp.GetError() != SrvBadTimeRange {
+		t.Errorf("bad range: %q", resp.GetError())
+	}
+}
+
+const aggrDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <ip>10.1.1.1</ip>
+        <ipv6>fd00:1::1</ipv6>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <ipSubnet>10.1.0.0/16</ipSubnet>
+        <ipv6Subnet>fd00:1::/32</ipv6Subnet>
+</content>
+</reg:register>`
+
+// TestSearchAggr tests that subnet hits carry the subnet in aggr and exact hits the address.
+func TestSearchAggr(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(aggrDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &server{}
+	ip6 := []byte(net.ParseIP("fd00:1::1").To16())
+
+	resp4, _ := srv.SearchIP4(context.Background(), &pb.IP4Request{Query: parser.IPv4StrToInt("10.1.1.1")})
+	resp6, _ := srv.SearchIP6(context.Background(), &pb.IP6Request{Query: ip6})
+
+	for name, results := range map[string][]*pb.Content{"ip4": resp4.GetResults(), "ip6": resp6.GetResults()} {
+		got := map[int32]string{}
+
+		for _, r := range results {
+			switch {
+			case r.GetAggr() != "":
+				got[r.GetId()] = r.GetAggr()
+			case r.GetIp4() != 0:
+				got[r.GetId()] = int2Ip4(r.GetIp4())
+			case r.GetIp6() != nil:
+				got[r.GetId()] = net.IP(r.GetIp6()).String()
+			}
+		}
+
+		want := map[int32]string{1: "10.1.1.1", 2: "10.1.0.0/16"}
+		if name == "ip6" {
+			want = map[int32]string{1: "fd00:1::1", 2: "fd00:1::/32"}
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %v", name, got)
+		}
+	}
+}
+
+// TestSearchDomainSuffix tests domains under a suffix and paging.
+func TestSearchDomainSuffix(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	// more domains of the record 111.
+	CurrentDump.Lock()
+	for _, domain := range []string{"tld", "e01-x.tld", "a.www.e01.tld", "e01.tldx"} {
+		CurrentDump.InsertToIndexDomain(domain, 111)
+	}
+	CurrentDump.generation++
+	CurrentDump.Unlock()
+
+	s := &server{}
+
+	for suffix, want := range map[string]string{
+		"tld":         "tld e01-x.tld www.e01.tld a.www.e01.tld www.e02.tld",
+		".TLD":        "tld e01-x.tld www.e01.tld a.www.e01.tld www.e02.tld",
+		"www.e01.tld": "www.e01.tld a.www.e01.tld",
+		"e01.tld":     "www.e01.tld a.www.e01.tld",
+		"ld":          "",
+	} {
+		resp, err := s.SearchDomainSuffix(context.Background(), &pb.DomainSuffixRequest{Suffix: suffix})
+		if err != nil || resp.GetError() != "" {
+			t.Fatalf("%s: %v %v", suffix, resp, err)
+		}
+
+		var got []string
+		for _, hit := range resp.GetDomains() {
+			got = append(got, hit.GetDomain())
+		}
+
+		if strings.Join(got, " ") != want || int(resp.GetTotal()) != len(got) {
+			t.Errorf("%s: %v", suffix, got)
+		}
+	}
+
+	resp, err := s.SearchDomainSuffix(context.Background(), &pb.DomainSuffixRequest{Suffix: "tld", Offset: 3, Limit: 1})
+	if err != nil || resp.GetTotal() != 5 || len(resp.GetDomains()) != 1 ||
+		resp.GetDomains()[0].GetDomain() != "a.www.e01.tld" || !reflect.DeepEqual(resp.GetDomains()[0].GetIds(), []int32{111}) {
+		t.Errorf("page: %v %v", resp, err)
+	}
+
+	if resp, _ := s.SearchDomainSuffix(context.Background(), &pb.DomainSuffixRequest{Suffix: "."}); resp.GetError() != SrvBadSelector {
+		t.Errorf("empty suffix: %v", resp)
+	}
+}
+
+// TestListHotSelectors tests shared selectors by record count and their counts after records are removed.
+func TestListHotSelectors(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+
+	list := func(in *pb.HotSelectorRequest) string {
+		resp, err := s.ListHotSelectors(context.Background(), in)
+		if err != nil || resp.GetError() != "" {
+			t.Fatalf("%v: %v %v", in, resp, err)
+		}
+
+		var got []string
+		for _, h := range resp.GetSelectors() {
+			got = append(got, fmt.Sprintf("%s:%s:%d", h.GetKind(), h.GetValue(), h.GetCount()))
+		}
+
+		return fmt.Sprintf("%d %s", resp.GetTotal(), strings.Join(got, " "))
+	}
+
+	for _, tc := range []struct {
+		in   *pb.HotSelectorRequest
+		want string
+	}{
+		{&pb.HotSelectorRequest{}, "3 ip6:fdaa:f::100:5 ip4:192.168.0.100:3 domain:www.e02.tld:2"},
+		{&pb.HotSelectorRequest{Kind: SelectorDomain}, "1 domain:www.e02.tld:2"},
+		{&pb.HotSelectorRequest{Kind: SelectorSubnet4}, "0 "},
+		{&pb.HotSelectorRequest{Offset: 1, Limit: 1}, "3 ip4:192.168.0.100:3"},
+		{&pb.HotSelectorRequest{MinCount: 3}, "2 ip6:fdaa:f::100:5 ip4:192.168.0.100:3"},
+		{&pb.HotSelectorRequest{Kind: SelectorURL}, "0 "},
+	} {
+		if got := list(tc.in); got != tc.want {
+			t.Errorf("%v: %s", tc.in, got)
+		}
+	}
+
+	if resp, _ := s.ListHotSelectors(context.Background(), &pb.HotSelectorRequest{Kind: "decision"}); resp.GetError() != SrvBadSelector {
+		t.Errorf("decision kind: %v", resp)
+	}
+
+	if resp, _ := s.ListHotSelectors(context.Background(), &pb.HotSelectorRequest{Kind: SelectorIP4, Ids: true}); !reflect.DeepEqual(resp.GetSelectors()[0].GetIds(), []int32{111, 222, 333}) {
+		t.Errorf("ids: %v", resp)
+	}
+
+	// 555 is removed: www.e02.tld is not shared anymore.
+	i := strings.Index(xml01, `<content id="555"`)
+	j := strings.Index(xml01, "</reg:register>")
+
+	if err := Parse(strings.NewReader(xml01[:i] + xml01[j:])); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := list(&pb.HotSelectorRequest{}); got != "2 ip6:fdaa:f::100:4 ip4:192.168.0.100:3" {
+		t.Errorf("after remove: %s", got)
+	}
+}
+
+const selectorlessDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <ip>10.1.1.1</ip>
+        <ip>10.1.1.2</ip>
+        <ip>10.1.1.3</ip>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+</content>
+</reg:register>`
+
+// TestListSelectorlessRecords tests records without selectors and a record losing all of them.
+func TestListSelectorlessRecords(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(selectorlessDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &server{}
+
+	resp, _ := srv.ListSelectorlessRecords(context.Background(), &pb.SelectorlessRequest{})
+	if resp.GetTotal() != 1 || len(resp.GetResults()) != 1 || resp.GetResults()[0].GetId() != 2 || Stats.Selectorless != 1 {
+		t.Fatalf("first: %v %d", resp, Stats.Selectorless)
+	}
+
+	// indexed by decision anyway.
+	if len(CurrentDump.decisionIdx[CurrentDump.ContentIdx[2].Decision]) != 1 {
+		t.Errorf("decision index: %v", CurrentDump.decisionIdx)
+	}
+
+	// all IPs are removed at once.
+	next := strings.Replace(selectorlessDump, `hash="1">`, `hash="11">`, 1)
+	next = strings.Replace(next, "<ip>10.1.1.1</ip>\n        <ip>10.1.1.2</ip>\n        <ip>10.1.1.3</ip>\n", "", 1)
+
+	if err := Parse(strings.NewReader(next)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(CurrentDump.ip4Idx) != 0 || len(CurrentDump.ContentIdx[1].IP4) != 0 {
+		t.Errorf("stale IPs: %v %v", CurrentDump.ip4Idx, CurrentDump.ContentIdx[1].IP4)
+	}
+
+	resp, _ = srv.ListSelectorlessRecords(context.Background(), &pb.SelectorlessRequest{Offset: 1, Limit: 1})
+	if resp.GetTotal() != 2 || len(resp.GetResults()) != 1 || resp.GetResults()[0].GetId() != 2 ||
+		resp.GetResults()[0].GetMatchedBy().GetKind() != MatchSelectorless {
+		t.Errorf("second: %v", resp)
+	}
+}
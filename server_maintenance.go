@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// SetMaintenance - pause or resume the poller, or report its state. Pause and resume are admin only.
+func (s *server) SetMaintenance(ctx context.Context, in *pb.MaintenanceRequest) (*pb.MaintenanceResponse, error) {
+	logger.Debug.Printf("[%s] Received maintenance: %t, %t, %q\n", RequestID(ctx), in.GetPause(), in.GetResume(), in.GetReason())
+
+	var m MaintenanceState
+
+	switch {
+	case in.GetPause() && in.GetResume():
+		return &pb.MaintenanceResponse{Error: SrvBadQuery}, nil
+	case (in.GetPause() || in.GetResume()) && !adminAllowed(ctx):
+		return &pb.MaintenanceResponse{Error: SrvAdminOnly}, nil
+	case in.GetPause():
+		m = PausePolling(in.GetReason(), time.Now())
+	case in.GetResume():
+		m = ResumePolling()
+	default:
+		m = Maintenance()
+	}
+
+	return newPbMaintenance(m), nil
+}
+
+// newPbMaintenance - the maintenance state.
+func newPbMaintenance(m MaintenanceState) *pb.MaintenanceResponse {
+	v := &pb.MaintenanceResponse{Paused: m.Paused, Reason: m.Reason}
+	if m.Paused {
+		v.Since = m.Since.Unix()
+	}
+
+	return v
+}
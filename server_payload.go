@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// QueryPayload - values of the path expression in the payloads of the candidate records,
+// for ad-hoc questions about fields without an index.
+func (s *server) QueryPayload(ctx context.Context, in *pb.PayloadQueryRequest) (*pb.PayloadQueryResponse, error) {
+	logger.Debug.Printf("[%s] Received payload query: %q, %d ids, %q\n", RequestID(ctx), in.GetPath(), len(in.GetIds()), in.GetValue())
+
+	path, err := ParsePayloadPath(in.GetPath())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad payload path: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.PayloadQueryResponse{Error: SrvBadPath}, nil
+	}
+
+	if len(in.GetIds()) > payloadQueryMaxIDs {
+		return &pb.PayloadQueryResponse{Error: SrvTooManyIDs}, nil
+	}
+
+	ids := append([]int32(nil), in.GetIds()...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.PayloadQueryResponse{RegistryUpdateTime: CurrentDump.utime}
+
+		var results []*pb.PayloadValues
+
+		for i, id := range ids {
+			if i > 0 && ids[i-1] == id {
+				continue
+			}
+
+			pack, ok := CurrentDump.ContentIdx[id]
+			if !ok {
+				continue
+			}
+
+			record, err := pack.Record()
+			if err != nil {
+				logger.Error.Printf("[%s] Can't decode record %d: %s\n", RequestID(ctx), id, err.Error())
+
+				continue
+			}
+
+			values := path.Values(record)
+			if !matchPayloadValues(values, in.GetValue(), in.GetContains()) {
+				continue
+			}
+
+			results = append(results, &pb.PayloadValues{Id: id, Values: values})
+		}
+
+		start, end := pageBounds(len(results), in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = results[start:end]
+
+		return resp, nil
+	}
+
+	return &pb.PayloadQueryResponse{Error: SrvDataNotReady}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// Query - records matching the expression, see ParseQuery.
+func (s *server) Query(ctx context.Context, in *pb.QueryRequest) (*pb.SearchResponse, error) {
+	logger.Debug.Printf("[%s] Received query: %q, %d, %d\n", RequestID(ctx), in.GetQuery(), in.GetOffset(), in.GetLimit())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	query, err := ParseQuery(in.GetQuery())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad query: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadQuery + ": " + err.Error()}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		results, ok := CurrentDump.orderResults(query.Records(CurrentDump), in.GetOrderBy(), OrderID, in.GetDescending())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvBadOrder}, nil
+		}
+
+		page := paginate(results, in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = make([]*pb.Content, 0, len(page))
+
+		for _, id := range page {
+			if cont, ok := CurrentDump.ContentIdx[id]; ok {
+				resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: MatchQuery}))
+			}
+		}
+
+		return resp, nil
+	}
+
+	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// ResolveHosts - resolved hosts of domain-only records, by host or address.
+func (s *server) ResolveHosts(ctx context.Context, in *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	logger.Debug.Printf("[%s] Received resolved hosts: %q, %q\n", RequestID(ctx), in.GetHost(), in.GetIp())
+
+	if ResolveConfig.Interval <= 0 {
+		return &pb.ResolveResponse{Error: SrvNoResolve}, nil
+	}
+
+	list, round, leftOut := Resolved.Hosts()
+	if round.IsZero() {
+		return &pb.ResolveResponse{Error: SrvDataNotReady}, nil
+	}
+
+	switch {
+	case in.GetIp() != "":
+		addr, err := netip.ParseAddr(in.GetIp())
+		if err != nil {
+			return &pb.ResolveResponse{Error: SrvBadSelector}, nil
+		}
+
+		list = Resolved.Addr(addr)
+	case in.GetHost() != "":
+		list = list[:0]
+		if h := Resolved.Host(NormalizeDomain(in.GetHost())); h != nil {
+			list = append(list, h)
+		}
+	}
+
+	start, end := pageBounds(len(list), in.GetOffset(), in.GetLimit())
+
+	resp := &pb.ResolveResponse{
+		Round:   round.Unix(),
+		Total:   int32(len(list)),
+		LeftOut: int32(leftOut),
+		Hosts:   make([]*pb.ResolvedHost, 0, end-start),
+	}
+
+	for _, h := range list[start:end] {
+		resp.Hosts = append(resp.Hosts, newPbResolvedHost(h))
+	}
+
+	return resp, nil
+}
+
+// newPbResolvedHost - the resolved host.
+func newPbResolvedHost(h *ResolvedHost) *pb.ResolvedHost {
+	v := &pb.ResolvedHost{Host: h.Host, Error: h.Err, Ids: h.IDs, Ips: make([]string, 0, len(h.Addrs))}
+
+	if !h.Resolved.IsZero() {
+		v.Resolved = h.Resolved.Unix()
+	}
+
+	for _, addr := range h.Addrs {
+		v.Ips = append(v.Ips, addr.String())
+	}
+
+	return v
+}
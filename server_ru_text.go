@@ -2,6 +2,43 @@ package main
 
 // Server messages.
 const (
-	SrvDataNotReady = "Данные не готовы"
-	SrvPongMessage  = "Я внимаю, мой Повелитель"
+	SrvDataNotReady  = "Данные не готовы"
+	SrvPongMessage   = "Я внимаю, мой Повелитель"
+	SrvRawDisabled   = "Исходный XML не сохраняется"
+	SrvRawBroken     = "Исходный XML повреждён"
+	SrvBadDate       = "Неверная дата"
+	SrvBadTimeRange  = "Неверный интервал времени"
+	SrvNoGeneration  = "Поколение не сохранено"
+	SrvBadFieldMask  = "Неизвестное поле в маске"
+	SrvFeedAdded     = "Реестр: новые записи"
+	SrvFeedRemoved   = "Реестр: удалённые записи"
+	SrvFeedUrgent    = "Реестр: срочные записи"
+	SrvUrgentMark    = "[срочно]"
+	SrvNoHistory     = "История статистики недоступна"
+	SrvNoASN         = "База автономных систем не загружена"
+	SrvBadSelector   = "Неверный селектор"
+	SrvBadFilter     = "Неверный фильтр"
+	SrvBadOrder      = "Неизвестный порядок сортировки"
+	SrvUnknownPeer   = "Узел не разрешён"
+	SrvPeerFailed    = "Узел недоступен"
+	SrvRateLimited   = "Слишком много запросов"
+	SrvBadID         = "Неверный номер записи"
+	SrvBadTag        = "Неверная метка"
+	SrvTagsFailed    = "Метки не сохранены"
+	SrvShortQuery    = "Слишком короткий запрос"
+	SrvBadGeneration = "Неверное поколение"
+	SrvNoFaults      = "Режим внедрения сбоев выключен"
+	SrvBadStage      = "Неизвестный этап"
+	SrvBadPath       = "Неверный путь"
+	SrvTooManyIDs    = "Слишком много записей"
+	SrvNoShadow      = "Теневой разбор не выполнялся"
+	SrvBadDecision   = "Неверное решение"
+	SrvBadCursor     = "Неверный курсор"
+	SrvBadQuery      = "Неверный запрос"
+	SrvBadTransition = "Неверный фильтр смены типа блокировки"
+	SrvNoResolve     = "Разрешение имён выключено"
+	SrvAdminOnly     = "Нужен токен администратора"
+	SrvLongNote      = "Слишком длинная заметка"
+	SrvUnknownID     = "Записи нет в реестре"
+	SrvDataChanged   = "Данные изменились, повторите проверку"
 )
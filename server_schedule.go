@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// ProjectSchedule - records and selectors enforceable at a future time and not now,
+// to stage the configuration ahead of scheduled include times.
+func (s *server) ProjectSchedule(ctx context.Context, in *pb.ScheduleRequest) (*pb.ScheduleResponse, error) {
+	logger.Debug.Printf("[%s] Received schedule projection: %d\n", RequestID(ctx), in.GetAt())
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.ScheduleResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	now := time.Now().Unix()
+	if in.GetAt() <= now {
+		return &pb.ScheduleResponse{Error: SrvBadTimeRange}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		projection := CurrentDump.ProjectSchedule(now, in.GetAt())
+		page := paginate(projection.Records, in.GetOffset(), in.GetLimit())
+		selectors := excludeSelectors(projection.Selectors)
+
+		resp := &pb.ScheduleResponse{
+			RegistryUpdateTime: CurrentDump.utime,
+			Now:                now,
+			Results:            make([]*pb.Content, 0, len(page)),
+			Total:              int32(len(projection.Records)),
+			Selectors:          make([]*pb.SelectorDelta, 0, len(selectors)),
+		}
+
+		for _, id := range page {
+			if cont, ok := CurrentDump.ContentIdx[id]; ok {
+				resp.Results = append(resp.Results, cont.newMaskedPbContent(mask, Match{Kind: MatchIncludeTime}))
+			}
+		}
+
+		for _, sel := range selectors {
+			resp.Selectors = append(resp.Selectors, &pb.SelectorDelta{Kind: sel.Kind, Selector: sel.Value, Added: true})
+		}
+
+		return resp, nil
+	}
+
+	return &pb.ScheduleResponse{Error: SrvDataNotReady}, nil
+}
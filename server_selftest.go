@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// Self-test sample sizes.
+const (
+	defaultSelfTestSample = 100
+	maxSelfTestSample     = 10000
+	selfTestExamples      = 5 // problems quoted in the detail.
+)
+
+// SelfTest - run internal checks on samples of the current generation, for post-deploy verification.
+func (s *server) SelfTest(ctx context.Context, in *pb.SelfTestRequest) (*pb.SelfTestResponse, error) {
+	logger.Debug.Printf("[%s] Received self-test: %d\n", RequestID(ctx), in.GetSample())
+
+	n := int(in.GetSample())
+	if n <= 0 {
+		n = defaultSelfTestSample
+	}
+
+	if n > maxSelfTestSample {
+		n = maxSelfTestSample
+	}
+
+	if CurrentDump == nil || CurrentDump.utime == 0 {
+		return &pb.SelfTestResponse{Error: SrvDataNotReady}, nil
+	}
+
+	CurrentDump.RLock()
+
+	resp := &pb.SelfTestResponse{RegistryUpdateTime: CurrentDump.utime, Generation: CurrentDump.generation}
+	sample := CurrentDump.sampleContents(n)
+
+	resp.Checks = append(resp.Checks,
+		runSelfCheck("lookup", func() (bool, string) { return CurrentDump.selfCheckLookup(sample) }),
+		runSelfCheck("subnet", func() (bool, string) { return CurrentDump.selfCheckSubnet(sample) }),
+		runSelfCheck("backref", func() (bool, string) { return CurrentDump.selfCheckBackref(n) }),
+		runSelfCheck("payload", func() (bool, string) { return selfCheckPayload(sample) }),
+	)
+
+	CurrentDump.RUnlock()
+
+	resp.Checks = append(resp.Checks, runSelfCheck("snapshot", func() (bool, string) { return selfCheckSnapshot(s.dir) }))
+
+	resp.Ok = true
+
+	for _, check := range resp.Checks {
+		if !check.Ok {
+			logger.Warning.Printf("[%s] Self-test %s failed: %s\n", RequestID(ctx), check.Name, check.Detail)
+
+			resp.Ok = false
+		}
+	}
+
+	return resp, nil
+}
+
+func runSelfCheck(name string, check func() (bool, string)) *pb.SelfCheck {
+	start := time.Now()
+	ok, detail := check()
+
+	return &pb.SelfCheck{Name: name, Ok: ok, Detail: detail, Duration: time.Since(start).Microseconds()}
+}
+
+// sampleContents - up to n records, call it under lock.
+// Map iteration starts at a random point, it is random enough for a self-test.
+func (dump *Dump) sampleContents(n int) []*PackedContent {
+	sample := make([]*PackedContent, 0, n)
+
+	for _, pack := range dump.ContentIdx {
+		if len(sample) >= n {
+			break
+		}
+
+		sample = append(sample, pack)
+	}
+
+	return sample
+}
+
+// problemsDetail - number of checked items and first problems.
+func problemsDetail(checked int, problems []IndexProblem) (bool, string) {
+	if len(problems) == 0 {
+		return true, fmt.Sprintf("checked %d", checked)
+	}
+
+	examples := make([]string, 0, selfTestExamples)
+	for i := 0; i < len(problems) && i < selfTestExamples; i++ {
+		examples = append(examples, problems[i].String())
+	}
+
+	return false, fmt.Sprintf("checked %d, problems %d: %s", checked, len(problems), strings.Join(examples, "; "))
+}
+
+// selfCheckLookup - every selector of sampled records is found by the index.
+func (dump *Dump) selfCheckLookup(sample []*PackedContent) (bool, string) {
+	var problems []IndexProblem
+
+	for _, pack := range sample {
+		problems = append(problems, dump.checkRecord(pack)...)
+	}
+
+	return problemsDetail(len(sample), problems)
+}
+
+// selfCheckSubnet - subnets of sampled records are found in the radix tree by their network address.
+func (dump *Dump) selfCheckSubnet(sample []*PackedContent) (bool, string) {
+	var (
+		problems []IndexProblem
+		checked  int
+	)
+
+	check := func(kind string, subnet netip.Prefix, id int32) {
+		checked++
+
+		entries, err := dump.netTree.ContainingNetworks(subnet.Addr().AsSlice())
+		if err == nil {
+			for _, entry := range entries {
+				if ipNetPrefix(entry.Network()) == subnet.Masked() {
+					return
+				}
+			}
+		}
+
+		problems = append(problems, IndexProblem{Sel: Selector{kind, subnet.String()}, ID: id})
+	}
+
+	for _, pack := range sample {
+		for _, subnet4 := range pack.Subnet4 {
+			check(SelectorSubnet4, subnet4.Subnet4, pack.ID)
+		}
+
+		for _, subnet6 := range pack.Subnet6 {
+			check(SelectorSubnet6, subnet6.Subnet6, pack.ID)
+		}
+	}
+
+	return problemsDetail(checked, problems)
+}
+
+// selfCheckBackref - up to n entries of every index point to records carrying the selector.
+func (dump *Dump) selfCheckBackref(n int) (bool, string) {
+	var (
+		problems []IndexProblem
+		checked  int
+		kind     string
+		count    int
+	)
+
+	dump.rangeIndexes(func(sel Selector, ids ArrayIntSet) bool {
+		if sel.Kind != kind {
+			kind, count = sel.Kind, 0
+		}
+
+		problems = append(problems, dump.checkIndexEntry(sel, ids, (*PackedContent).recordSelectors)...)
+		checked++
+		count++
+
+		return count < n
+	})
+
+	return problemsDetail(checked, problems)
+}
+
+// selfCheckPayload - payloads of sampled records decode to the same record.
+func selfCheckPayload(sample []*PackedContent) (bool, string) {
+	if !payloadsKept() {
+		return true, "disabled"
+	}
+
+	var (
+		broken []string
+		bad    int
+	)
+
+	for _, pack := range sample {
+		record := Content{}
+
+		err := json.Unmarshal(pack.PayloadBytes(), &record)
+		if err == nil && record.ID != pack.ID {
+			err = fmt.Errorf("id %d", record.ID)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		bad++
+
+		if len(broken) < selfTestExamples {
+			broken = append(broken, fmt.Sprintf("%d: %s", pack.ID, err.Error()))
+		}
+	}
+
+	if bad > 0 {
+		return false, fmt.Sprintf("checked %d, broken %d: %s", len(sample), bad, strings.Join(broken, "; "))
+	}
+
+	return true, fmt.Sprintf("checked %d", len(sample))
+}
+
+// selfCheckSnapshot - the latest retained snapshot is readable.
+func selfCheckSnapshot(dir string) (bool, string) {
+	if SnapshotKeep <= 0 {
+		return true, "disabled"
+	}
+
+	generations, err := ListSnapshots(dir)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	if len(generations) == 0 {
+		return false, "no snapshots"
+	}
+
+	generation := generations[len(generations)-1]
+
+	snap, err := ReadSnapshot(SnapshotFilename(dir, generation))
+	if err != nil {
+		return false, fmt.Sprintf("generation %d: %s", generation, err.Error())
+	}
+
+	return true, fmt.Sprintf("generation %d, records %d", snap.Generation, len(snap.Contents))
+}
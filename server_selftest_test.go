@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestSelfTest tests that SelfTest passes on a fresh index and catches broken back-pointers.
+func TestSelfTest(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{dir: t.TempDir()}
+
+	resp, err := s.SelfTest(context.Background(), &pb.SelfTestRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.GetOk() {
+		t.Fatalf("expected ok, got %v", resp.GetChecks())
+	}
+
+	// break both directions: a record without its index entry and an entry without its record.
+	CurrentDump.ip4Idx.Remove(netip.MustParseAddr("10.4.4.4"), 444)
+	CurrentDump.InsertToIndexDomain("ghost.tld", 999)
+
+	resp, _ = s.SelfTest(context.Background(), &pb.SelfTestRequest{Sample: 1000})
+	if resp.GetOk() {
+		t.Fatalf("expected failure")
+	}
+
+	failed := make(map[string]string)
+	for _, check := range resp.GetChecks() {
+		if !check.GetOk() {
+			failed[check.GetName()] = check.GetDetail()
+		}
+	}
+
+	if !strings.Contains(failed["lookup"], "missing ip4 10.4.4.4 <- 444") {
+		t.Errorf("lookup: %q", failed["lookup"])
+	}
+
+	if !strings.Contains(failed["backref"], "dangling domain ghost.tld -> 999") {
+		t.Errorf("backref: %q", failed["backref"])
+	}
+}
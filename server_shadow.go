@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// GetShadowReport - comparison of the last shadow parse with the live generation, see ShadowParse.
+func (s *server) GetShadowReport(ctx context.Context, in *pb.ShadowReportRequest) (*pb.ShadowReportResponse, error) {
+	logger.Debug.Printf("[%s] Received shadow report request\n", RequestID(ctx))
+
+	report := LastShadowReport()
+	if report == nil {
+		return &pb.ShadowReportResponse{Error: SrvNoShadow}, nil
+	}
+
+	resp := &pb.ShadowReportResponse{
+		Generation: report.Generation,
+		Started:    report.Started.Unix(),
+		Finished:   report.Finished.Unix(),
+		Live:       int32(report.Live),
+		Shadow:     int32(report.Shadow),
+		Missing:    int32(report.Missing),
+		Extra:      int32(report.Extra),
+		Differ:     int32(report.Differ),
+		Failure:    report.Failure,
+	}
+
+	for _, d := range report.Discrepancies {
+		resp.Discrepancies = append(resp.Discrepancies, &pb.ShadowDiscrepancy{Id: d.ID, Kind: d.Kind, Fields: d.Fields})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// Simulate - collisions of a hypothetical record with the index, nothing is changed.
+func (s *server) Simulate(ctx context.Context, in *pb.SimulateRequest) (*pb.SimulateResponse, error) {
+	logger.Debug.Printf("[%s] Received simulate: %d IPs, %d subnets, %d domains, %d URLs\n", RequestID(ctx),
+		len(in.GetIp()), len(in.GetSubnet()), len(in.GetDomain()), len(in.GetUrl()))
+
+	if CurrentDump == nil || CurrentDump.utime == 0 {
+		return &pb.SimulateResponse{Error: SrvDataNotReady}, nil
+	}
+
+	rec := SimRecord{IPs: in.GetIp(), Subnets: in.GetSubnet(), Domains: in.GetDomain(), URLs: in.GetUrl()}
+
+	report, err := CurrentDump.Simulate(rec, int(in.GetLimit()))
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad simulated record: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SimulateResponse{Error: SrvBadSelector + ": " + err.Error()}, nil
+	}
+
+	CurrentDump.RLock()
+	resp := &pb.SimulateResponse{
+		RegistryUpdateTime: CurrentDump.utime,
+		Collisions:         make([]*pb.Collision, 0, len(report.Collisions)),
+		Total:              int32(report.Total),
+		Records:            int32(report.Records),
+	}
+	CurrentDump.RUnlock()
+
+	for _, c := range report.Collisions {
+		resp.Collisions = append(resp.Collisions, &pb.Collision{
+			Selector: c.Selector,
+			Kind:     c.Kind,
+			Existing: c.Existing,
+			Ids:      c.IDs,
+		})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// GetStatsHistory - per-parse statistics for trend charts, oldest first.
+func (s *server) GetStatsHistory(ctx context.Context, in *pb.StatsHistoryRequest) (*pb.StatsHistoryResponse, error) {
+	logger.Debug.Printf("[%s] Received stats history: %d - %d, limit %d\n", RequestID(ctx), in.GetFrom(), in.GetTo(), in.GetLimit())
+
+	points, err := StatsHistory(s.dir, in.GetFrom(), in.GetTo(), int(in.GetLimit()))
+	if err != nil {
+		logger.Error.Printf("[%s] Can't read stats history: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.StatsHistoryResponse{Error: SrvNoHistory}, nil
+	}
+
+	resp := &pb.StatsHistoryResponse{Points: make([]*pb.StatsPoint, 0, len(points))}
+
+	for _, p := range points {
+		resp.Points = append(resp.Points, &pb.StatsPoint{
+			Time:               p.Time,
+			Generation:         p.Generation,
+			RegistryUpdateTime: p.UpdateTime,
+			Count:              int32(p.Count),
+			Added:              int32(p.Added),
+			Updated:            int32(p.Updated),
+			Removed:            int32(p.Removed),
+			Duplicates:         int32(p.Duplicates),
+			Ip4:                int32(p.IP4),
+			Ip6:                int32(p.IP6),
+			Subnet4:            int32(p.Subnet4),
+			Subnet6:            int32(p.Subnet6),
+			Domains:            int32(p.Domains),
+			Urls:               int32(p.URLs),
+			MaxIdSet:           int32(p.MaxIDSet),
+			MaxContent:         int32(p.MaxContent),
+			Size:               p.Size,
+			DurationMs:         p.Duration.Milliseconds(),
+			Anomalies:          int32(p.Anomalies),
+		})
+	}
+
+	return resp, nil
+}
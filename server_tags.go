@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TagRecord - set or remove an operator tag of the record, tags are kept apart from the dump.
+func (s *server) TagRecord(ctx context.Context, in *pb.TagRequest) (*pb.TagResponse, error) {
+	logger.Debug.Printf("[%s] Received tag: %d, %q, %t\n", RequestID(ctx), in.GetId(), in.GetTag(), in.GetRemove())
+
+	if !adminAllowed(ctx) {
+		return &pb.TagResponse{Error: SrvAdminOnly}, nil
+	}
+
+	if in.GetId() <= 0 {
+		return &pb.TagResponse{Error: SrvBadID}, nil
+	}
+
+	if len(in.GetNote()) > tagNoteMaxLen {
+		return &pb.TagResponse{Error: SrvLongNote}, nil
+	}
+
+	// tags are set on records of the registry only, they are removed from any, see TagStore.
+	if !in.GetRemove() {
+		if CurrentDump == nil || CurrentDump.utime == 0 {
+			return &pb.TagResponse{Error: SrvDataNotReady}, nil
+		}
+
+		CurrentDump.RLock()
+		_, ok := CurrentDump.ContentIdx[in.GetId()]
+		CurrentDump.RUnlock()
+
+		if !ok {
+			return &pb.TagResponse{Error: SrvUnknownID}, nil
+		}
+	}
+
+	var tag string
+
+	// remove without a tag removes all tags of the record.
+	if !in.GetRemove() || in.GetTag() != "" {
+		var ok bool
+
+		if tag, ok = NormalizeTag(in.GetTag()); !ok {
+			return &pb.TagResponse{Error: SrvBadTag}, nil
+		}
+	}
+
+	var (
+		tags []RecordTag
+		err  error
+	)
+
+	if in.GetRemove() {
+		tags, err = RecordTags.Remove(in.GetId(), tag)
+	} else {
+		tags, err = RecordTags.Set(in.GetId(), tag, in.GetNote(), time.Now())
+	}
+
+	if err != nil {
+		logger.Error.Printf("[%s] Can't save tags: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.TagResponse{Error: SrvTagsFailed}, nil
+	}
+
+	logTagChange(RequestID(ctx), in.GetId(), tag, in.GetRemove())
+
+	return &pb.TagResponse{Tags: newPbTags(tags)}, nil
+}
+
+// ListTagged - list records with the operator tag, ordered by id by default.
+// Tagged ids which are not in the registry now are left out.
+func (s *server) ListTagged(ctx context.Context, in *pb.TaggedRequest) (*pb.SearchResponse, error) {
+	logger.Debug.Printf("[%s] Received tagged list: %q, %d, %d\n", RequestID(ctx), in.GetTag(), in.GetOffset(), in.GetLimit())
+
+	tag, ok := NormalizeTag(in.GetTag())
+	if !ok {
+		return &pb.SearchResponse{Error: SrvBadTag}, nil
+	}
+
+	mask, err := newContentMask(in.GetFields())
+	if err != nil {
+		logger.Debug.Printf("[%s] Bad field mask: %s\n", RequestID(ctx), err.Error())
+
+		return &pb.SearchResponse{Error: SrvBadFieldMask}, nil
+	}
+
+	if CurrentDump != nil && CurrentDump.utime > 0 {
+		CurrentDump.RLock()
+		defer CurrentDump.RUnlock()
+
+		var ids []int32
+
+		for _, id := range RecordTags.IDs(tag) {
+			if _, ok := CurrentDump.ContentIdx[id]; ok {
+				ids = append(ids, id)
+			}
+		}
+
+		resp := &pb.SearchResponse{RegistryUpdateTime: CurrentDump.utime}
+		results, ok := CurrentDump.orderResults(ids, in.GetOrderBy(), OrderID, in.GetDescending())
+		if !ok {
+			return &pb.SearchResponse{Error: SrvBadOrder}, nil
+		}
+
+		page := paginate(results, in.GetOffset(), in.GetLimit())
+
+		resp.Total = int32(len(results))
+		resp.Results = make([]*pb.Content, 0, len(page))
+
+		for _, id := range page {
+			resp.Results = append(resp.Results, CurrentDump.ContentIdx[id].newMaskedPbContent(mask, Match{Kind: MatchTag}))
+		}
+
+		return resp, nil
+	}
+
+	return &pb.SearchResponse{Error: SrvDataNotReady}, nil
+}
@@ -0,0 +1,355 @@
+package main
+
+//go:generate protoc -I msg --go-grpc_out=msg --go_out=msg --go_opt=paths=source_relative --go-grpc_opt=paths=source_relative msg/v2/msg.proto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+	pbv2 "github.com/usher2/u2ckdump/msg/v2"
+)
+
+// v1Service - full name of the deprecated v1 service, v2Service - its successor.
+const (
+	v1Service = "msg.Check"
+	v2Service = "msg.v2.Check"
+)
+
+// Deprecation metadata of v1 responses.
+const (
+	DeprecationHeader = "deprecation"     // always "true".
+	SunsetHeader      = "sunset"          // YYYY-MM-DD v1 is served until, if announced.
+	SuccessorHeader   = "x-api-successor" // the service to move to.
+)
+
+// RegistryUpdateTimeHeader - registry update time of the data a v2 search stream is read from.
+const RegistryUpdateTimeHeader = "x-registry-update-time"
+
+// V1Sunset - announced end of v1 in deprecation metadata, empty if not announced.
+var V1Sunset string
+
+// serverV2 - v2 API translated to the v1 handlers, so both versions serve the same data.
+type serverV2 struct {
+	pbv2.UnimplementedCheckServer
+	v1 *server
+}
+
+// Search - records of the selector, one message per record.
+func (s *serverV2) Search(in *pbv2.SearchRequest, stream pbv2.Check_SearchServer) error {
+	ctx := stream.Context()
+
+	resp, err := s.search(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	if resp.GetError() != "" {
+		return v1Status(resp.GetError())
+	}
+
+	stream.SetHeader(metadata.Pairs(RegistryUpdateTimeHeader, strconv.FormatInt(resp.GetRegistryUpdateTime(), 10)))
+
+	entryTypes := make(map[pbv2.EntryType]bool, len(in.GetEntryTypes()))
+	for _, entryType := range in.GetEntryTypes() {
+		entryTypes[entryType] = true
+	}
+
+	for _, content := range resp.GetResults() {
+		record, err := newV2Record(content)
+		if err != nil {
+			logger.Error.Printf("[%s] Can't translate record: %d: %s\n", RequestID(ctx), content.GetId(), err.Error())
+
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		if len(entryTypes) > 0 && !entryTypes[record.GetEntryType()] {
+			continue
+		}
+
+		if err := stream.Send(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// search - the v1 search of the query.
+func (s *serverV2) search(ctx context.Context, in *pbv2.SearchRequest) (*pb.SearchResponse, error) {
+	switch q := in.GetQuery().(type) {
+	case *pbv2.SearchRequest_Id:
+		return s.v1.SearchID(ctx, &pb.IDRequest{Query: q.Id})
+	case *pbv2.SearchRequest_Ip4:
+		ip := net.ParseIP(q.Ip4).To4()
+		if ip == nil || strings.Contains(q.Ip4, ":") {
+			return nil, status.Errorf(codes.InvalidArgument, "bad IPv4 address: %q", q.Ip4)
+		}
+
+		return s.v1.SearchIP4(ctx, &pb.IP4Request{Query: ip4ToInt(ip)})
+	case *pbv2.SearchRequest_Ip6:
+		ip := net.ParseIP(q.Ip6)
+		if ip == nil || !strings.Contains(q.Ip6, ":") {
+			return nil, status.Errorf(codes.InvalidArgument, "bad IPv6 address: %q", q.Ip6)
+		}
+
+		return s.v1.SearchIP6(ctx, &pb.IP6Request{Query: ip.To16()})
+	case *pbv2.SearchRequest_Url:
+		return s.v1.SearchURL(ctx, &pb.URLRequest{Query: q.Url})
+	case *pbv2.SearchRequest_Domain:
+		return s.v1.SearchDomain(ctx, &pb.DomainRequest{Query: q.Domain})
+	case *pbv2.SearchRequest_Decision:
+		return s.v1.SearchDecision(ctx, &pb.DecisionRequest{Query: q.Decision})
+	}
+
+	return nil, status.Error(codes.InvalidArgument, "no query")
+}
+
+// Watch - the current generation if it differs from the client's one, then every new generation.
+// With changes every generation since the client's one is sent with its record changes,
+// with delta also with the selectors and records a replica applies.
+func (s *serverV2) Watch(in *pbv2.WatchRequest, stream pbv2.Check_WatchServer) error {
+	logger.Debug.Printf("[%s] Received watch: %d, changes: %t, delta: %t\n", RequestID(stream.Context()), in.GetGeneration(), in.GetChanges(), in.GetDelta())
+
+	filter, err := ParseTransitionFilter(in.GetTransitions())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, SrvBadTransition+": "+err.Error())
+	}
+
+	last := in.GetGeneration()
+
+	for {
+		generation, changed := CurrentDump.Changes()
+		if generation != last && in.GetChanges() {
+			sent, err := sendChanges(stream, last, in.GetDelta(), filter)
+			if err != nil {
+				return err
+			}
+
+			last = sent
+		} else if generation != last {
+			last = generation
+
+			if g, ok := currentGeneration(); ok {
+				if err := stream.Send(g); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-s.v1.kill:
+			return nil
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// Status - the current generation.
+func (s *serverV2) Status(ctx context.Context, in *pbv2.StatusRequest) (*pbv2.Generation, error) {
+	g, ok := currentGeneration()
+	if !ok {
+		return nil, v1Status(SrvDataNotReady)
+	}
+
+	return g, nil
+}
+
+// sendChanges - generations after the last one with their record changes and block type transitions
+// passing the filter, and their deltas if asked, or the current one with resync if they are not retained.
+// It returns the last generation sent.
+func sendChanges(stream pbv2.Check_WatchServer, last int64, delta bool, filter TransitionFilter) (int64, error) {
+	changes, ok := CurrentDump.ChangesSince(last)
+	if !ok {
+		g, ready := currentGeneration()
+		if !ready {
+			return last, nil
+		}
+
+		g.Resync = true
+
+		return g.GetGeneration(), stream.Send(g)
+	}
+
+	for _, c := range changes {
+		g := &pbv2.Generation{
+			Generation:         c.Generation,
+			RegistryUpdateTime: c.UpdateTime,
+			Payloads:           payloadsKept(),
+			Added:              c.Added,
+			Updated:            c.Updated,
+			Removed:            c.Removed,
+			Urgent:             c.Urgent,
+		}
+
+		for _, t := range filter.Filter(c.Transitions) {
+			g.Transitions = append(g.Transitions, &pbv2.BlockTypeTransition{Id: t.ID, From: pbv2.BlockType(t.From), To: pbv2.BlockType(t.To)})
+		}
+
+		if delta {
+			g.Delta = generationDelta(c)
+		}
+
+		if err := stream.Send(g); err != nil {
+			return last, err
+		}
+
+		last = c.Generation
+	}
+
+	return last, nil
+}
+
+// currentGeneration - generation of the current dump, false if it is not loaded.
+func currentGeneration() (*pbv2.Generation, bool) {
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	if CurrentDump.utime == 0 {
+		return nil, false
+	}
+
+	return &pbv2.Generation{
+		Generation:         CurrentDump.generation,
+		RegistryUpdateTime: CurrentDump.utime,
+		DatasetHash:        CurrentDump.DatasetHash(),
+		Payloads:           payloadsKept(),
+	}, true
+}
+
+// v1Status - status of an in-band v1 error.
+func v1Status(msg string) error {
+	switch msg {
+	case SrvDataNotReady:
+		return status.Error(codes.Unavailable, msg)
+	case SrvBadFieldMask, SrvBadSelector, SrvBadDate, SrvBadTimeRange, SrvBadFilter:
+		return status.Error(codes.InvalidArgument, msg)
+	}
+
+	return status.Error(codes.Internal, msg)
+}
+
+// newV2Record - typed record of a v1 result with all fields, selectors are decoded from the pack.
+func newV2Record(v *pb.Content) (*pbv2.Record, error) {
+	content, err := v2Content(v)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &pbv2.Record{
+		Id:                 v.GetId(),
+		RegistryUpdateTime: v.GetChangeTime(),
+		BlockType:          pbv2.BlockType(v.GetBlockType()),
+		EntryType:          pbv2.EntryType(content.EntryType),
+		IncludeTime:        content.IncludeTime,
+		Sni:                v.GetSni(),
+		HttpUrls:           v.GetHttpUrls(),
+		HttpsUrls:          v.GetHttpsUrls(),
+		SniOnly:            v.GetSniOnly(),
+		Excluded:           v.GetExcluded(),
+	}
+
+	if d := v.GetDecision(); d != nil {
+		record.Decision = &pbv2.Decision{Number: d.GetNumber(), Date: d.GetDate(), Org: d.GetOrg(), Hash: d.GetHash()}
+	}
+
+	if m := v.GetMatchedBy(); m != nil {
+		record.Match = &pbv2.Match{Kind: m.GetKind(), Selector: m.GetSelector()}
+	}
+
+	for _, u := range content.URL {
+		record.Urls = append(record.Urls, u.URL)
+	}
+
+	for _, domain := range content.Domain {
+		record.Domains = append(record.Domains, domain.Domain)
+	}
+
+	for _, ip4 := range content.IP4 {
+		record.Ip4 = append(record.Ip4, ip4.IP4.String())
+	}
+
+	for _, ip6 := range content.IP6 {
+		record.Ip6 = append(record.Ip6, ip6.IP6.String())
+	}
+
+	for _, subnet4 := range content.Subnet4 {
+		record.Subnet4 = append(record.Subnet4, subnet4.Subnet4.String())
+	}
+
+	for _, subnet6 := range content.Subnet6 {
+		record.Subnet6 = append(record.Subnet6, subnet6.Subnet6.String())
+	}
+
+	return record, nil
+}
+
+// v2Content - record of a v1 result: its pack or, if payloads are not kept, the indexed record
+// rebuilt, see PackedContent.Record.
+func v2Content(v *pb.Content) (*Content, error) {
+	if len(v.GetPack()) > 0 || payloadsKept() {
+		content := &Content{}
+		if err := json.Unmarshal(v.GetPack(), content); err != nil {
+			return nil, fmt.Errorf("decode pack: %w", err)
+		}
+
+		return content, nil
+	}
+
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	pack, ok := CurrentDump.ContentIdx[v.GetId()]
+	if !ok || pack.RegistryUpdateTime != v.GetChangeTime() {
+		return nil, fmt.Errorf("record %d is changed", v.GetId())
+	}
+
+	return pack.Record()
+}
+
+// deprecationOptions - interceptors adding deprecation metadata to v1 responses.
+func deprecationOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if isV1Method(info.FullMethod) {
+				grpc.SetHeader(ctx, deprecationMD())
+			}
+
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if isV1Method(info.FullMethod) {
+				ss.SetHeader(deprecationMD())
+			}
+
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// isV1Method - "/msg.Check/SearchIP4".
+func isV1Method(method string) bool {
+	return strings.HasPrefix(method, "/"+v1Service+"/")
+}
+
+// deprecationMD - deprecation metadata of v1 responses.
+func deprecationMD() metadata.MD {
+	md := metadata.Pairs(DeprecationHeader, "true", SuccessorHeader, v2Service)
+	if V1Sunset != "" {
+		md.Set(SunsetHeader, V1Sunset)
+	}
+
+	return md
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	pbv2 "github.com/usher2/u2ckdump/msg/v2"
+)
+
+// TestServerV2 tests v2 records translated from v1, status codes, Watch and v1 deprecation metadata.
+func TestServerV2(t *testing.T) {
+	defer func(dump *Dump, sunset string) { CurrentDump, V1Sunset = dump, sunset }(CurrentDump, V1Sunset)
+
+	CurrentDump = NewDump()
+	V1Sunset = "2030-01-01"
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer(deprecationOptions()...)
+	v1 := &server{}
+	pb.RegisterCheckServer(srv, v1)
+	pbv2.RegisterCheckServer(srv, &serverV2{v1: v1})
+
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(listen.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	ctx := context.Background()
+	client := pbv2.NewCheckClient(conn)
+
+	if _, err := client.Status(ctx, &pbv2.StatusRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("not ready: %v", err)
+	}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(req *pbv2.SearchRequest) ([]*pbv2.Record, metadata.MD, error) {
+		t.Helper()
+
+		stream, err := client.Search(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var records []*pbv2.Record
+
+		for {
+			record, err := stream.Recv()
+			if err == io.EOF {
+				header, _ := stream.Header()
+
+				return records, header, nil
+			}
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			records = append(records, record)
+		}
+	}
+
+	records, header, err := search(&pbv2.SearchRequest{Query: &pbv2.SearchRequest_Ip4{Ip4: "192.168.0.100"}})
+	if err != nil || len(records) != 3 || len(header.Get(RegistryUpdateTimeHeader)) == 0 || len(header.Get(DeprecationHeader)) != 0 {
+		t.Fatalf("ip4: %v %v %v", records, header, err)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].GetId() < records[j].GetId() })
+
+	r := records[0]
+	if r.GetId() != 111 || r.GetBlockType() != pbv2.BlockType_BLOCK_TYPE_HTTPS || r.GetEntryType() != pbv2.EntryType_ENTRY_TYPE_EAIS || r.GetIncludeTime() == 0 ||
+		r.GetDecision().GetOrg() == "" || !reflect.DeepEqual(r.GetDomains(), []string{"www.e01.tld"}) ||
+		len(r.GetUrls()) == 0 || len(r.GetIp4()) != 3 || r.GetIp4()[1] != "192.168.0.100" || r.GetIp6()[2] != "fdaa:f::100" ||
+		r.GetMatch().GetKind() != SelectorIP4 || r.GetMatch().GetSelector() != "192.168.0.100" {
+		t.Errorf("record: %v", r)
+	}
+
+	records, _, err = search(&pbv2.SearchRequest{Query: &pbv2.SearchRequest_Ip6{Ip6: "fdaa:f::100"}})
+	if err != nil || len(records) != 5 {
+		t.Errorf("ip6: %d %v", len(records), err)
+	}
+
+	records, _, err = search(&pbv2.SearchRequest{Query: &pbv2.SearchRequest_Ip6{Ip6: "fdaa:f::100"},
+		EntryTypes: []pbv2.EntryType{pbv2.EntryType_ENTRY_TYPE_COPYRIGHT, pbv2.EntryType_ENTRY_TYPE_EAIS}})
+	if err != nil || len(records) != 5 {
+		t.Errorf("ip6 of eais: %d %v", len(records), err)
+	}
+
+	records, _, err = search(&pbv2.SearchRequest{Query: &pbv2.SearchRequest_Ip6{Ip6: "fdaa:f::100"},
+		EntryTypes: []pbv2.EntryType{pbv2.EntryType_ENTRY_TYPE_COPYRIGHT}})
+	if err != nil || len(records) != 0 {
+		t.Errorf("ip6 of copyright: %d %v", len(records), err)
+	}
+
+	for _, req := range []*pbv2.SearchRequest{
+		{},
+		{Query: &pbv2.SearchRequest_Ip4{Ip4: "fdaa:f::100"}},
+		{Query: &pbv2.SearchRequest_Ip6{Ip6: "192.168.0.100"}},
+	} {
+		if _, _, err := search(req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("bad query %v: %v", req, err)
+		}
+	}
+
+	g, err := client.Status(ctx, &pbv2.StatusRequest{})
+	if err != nil || g.GetGeneration() != CurrentDump.generation || g.GetDatasetHash() == "" {
+		t.Fatalf("status: %v %v", g, err)
+	}
+
+	// the current generation is sent at once if the client is behind.
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watch, err := client.Watch(watchCtx, &pbv2.WatchRequest{Generation: g.GetGeneration() - 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := watch.Recv(); err != nil || got.GetGeneration() != g.GetGeneration() {
+		t.Errorf("watch: %v %v", got, err)
+	}
+
+	// the changes of the initial load are not retained.
+	watch, err = client.Watch(watchCtx, &pbv2.WatchRequest{Generation: g.GetGeneration() - 1, Changes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := watch.Recv(); err != nil || got.GetGeneration() != g.GetGeneration() || !got.GetResync() {
+		t.Errorf("watch changes: %v %v", got, err)
+	}
+
+	// v1 is deprecated.
+	var v1Header metadata.MD
+
+	if _, err := pb.NewCheckClient(conn).Ping(ctx, &pb.PingRequest{}, grpc.Header(&v1Header)); err != nil {
+		t.Fatal(err)
+	}
+
+	if v1Header.Get(DeprecationHeader)[0] != "true" || v1Header.Get(SunsetHeader)[0] != V1Sunset || v1Header.Get(SuccessorHeader)[0] != v2Service {
+		t.Errorf("v1 metadata: %v", v1Header)
+	}
+}
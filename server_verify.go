@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// Verify problems returned by default and at most.
+const (
+	defaultVerifyLimit = 100
+	maxVerifyLimit     = 10000
+)
+
+// VerifyIndexes - full index consistency check, optionally repairs found problems.
+func (s *server) VerifyIndexes(ctx context.Context, in *pb.VerifyRequest) (*pb.VerifyResponse, error) {
+	logger.Debug.Printf("[%s] Received verify: repair=%v\n", RequestID(ctx), in.GetRepair())
+
+	limit := int(in.GetLimit())
+	if limit <= 0 {
+		limit = defaultVerifyLimit
+	}
+
+	if limit > maxVerifyLimit {
+		limit = maxVerifyLimit
+	}
+
+	if in.GetRepair() && !adminAllowed(ctx) {
+		return &pb.VerifyResponse{Error: SrvAdminOnly}, nil
+	}
+
+	if CurrentDump == nil || CurrentDump.utime == 0 {
+		return &pb.VerifyResponse{Error: SrvDataNotReady}, nil
+	}
+
+	// the check doesn't block parses and other writers.
+	CurrentDump.RLock()
+	report := CurrentDump.VerifyIndexes()
+	utime, generation := CurrentDump.utime, CurrentDump.generation
+	CurrentDump.RUnlock()
+
+	var stale bool
+
+	// the write lock is only taken to apply the repairs of the checked generation.
+	if in.GetRepair() && len(report.Problems) > 0 {
+		CurrentDump.Lock()
+		if stale = CurrentDump.generation != generation; !stale {
+			report.Repaired = CurrentDump.RepairIndexes(report.Problems)
+		}
+		CurrentDump.Unlock()
+
+		if report.Repaired > 0 {
+			SearchCache.Purge()
+		}
+	}
+
+	resp := &pb.VerifyResponse{
+		RegistryUpdateTime: utime,
+		Generation:         generation,
+		Records:            int32(report.Records),
+		Entries:            int32(report.Entries),
+		ProblemCount:       int32(len(report.Problems)),
+		Repaired:           int32(report.Repaired),
+	}
+
+	for i := 0; i < len(report.Problems) && i < limit; i++ {
+		resp.Problems = append(resp.Problems, report.Problems[i].String())
+	}
+
+	if stale {
+		resp.Error = SrvDataChanged
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// Long poll timeouts.
+const (
+	defaultWaitTimeout = 60 * time.Second
+	maxWaitTimeout     = 10 * time.Minute
+)
+
+// WaitForChange - block until the current generation differs from the given one or the timeout expires.
+// A generation lower than the client's one means the server was restarted, it is a change too.
+func (s *server) WaitForChange(ctx context.Context, in *pb.WaitRequest) (*pb.WaitResponse, error) {
+	logger.Debug.Printf("[%s] Received wait: %d, %ds\n", RequestID(ctx), in.GetGeneration(), in.GetTimeout())
+
+	timeout := time.Duration(in.GetTimeout()) * time.Second
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+
+	if timeout > maxWaitTimeout {
+		timeout = maxWaitTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		generation, changed := CurrentDump.Changes()
+		if generation != in.GetGeneration() {
+			break
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return s.waitResponse(false), nil
+		case <-s.kill:
+			return s.waitResponse(false), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return s.waitResponse(true), nil
+}
+
+func (s *server) waitResponse(changed bool) *pb.WaitResponse {
+	CurrentDump.RLock()
+	defer CurrentDump.RUnlock()
+
+	if CurrentDump.utime == 0 {
+		return &pb.WaitResponse{Error: SrvDataNotReady}
+	}
+
+	return &pb.WaitResponse{
+		RegistryUpdateTime: CurrentDump.utime,
+		Generation:         CurrentDump.generation,
+		Changed:            changed,
+	}
+}
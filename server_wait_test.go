@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestWaitForChange tests the long poll wakes up on a new generation, on a restart and on shutdown.
+func TestWaitForChange(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	kill := make(chan struct{})
+	srv := &server{kill: kill}
+	ctx := context.Background()
+
+	generation, _ := CurrentDump.Changes()
+
+	// the client has seen another generation, e.g. of the previous run.
+	resp, err := srv.WaitForChange(ctx, &pb.WaitRequest{Generation: generation + 100})
+	if err != nil || !resp.GetChanged() || resp.GetGeneration() != generation {
+		t.Fatalf("Restart: %v %v\n", resp, err)
+	}
+
+	done := make(chan *pb.WaitResponse)
+
+	go func() {
+		resp, _ := srv.WaitForChange(ctx, &pb.WaitRequest{Generation: generation, Timeout: 60})
+		done <- resp
+	}()
+
+	if err := Parse(strings.NewReader(xml02)); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := <-done; !resp.GetChanged() || resp.GetGeneration() <= generation {
+		t.Errorf("Change: %v\n", resp)
+	}
+
+	generation, _ = CurrentDump.Changes()
+
+	go func() {
+		resp, _ := srv.WaitForChange(ctx, &pb.WaitRequest{Generation: generation, Timeout: 60})
+		done <- resp
+	}()
+
+	close(kill)
+
+	if resp := <-done; resp.GetChanged() || resp.GetGeneration() != generation {
+		t.Errorf("Shutdown: %v\n", resp)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// GetParseWarnings - format drift of the dumps parsed since the start: unknown elements and
+// attributes, unparseable times, unknown block types.
+func (s *server) GetParseWarnings(ctx context.Context, in *pb.ParseWarningsRequest) (*pb.ParseWarningsResponse, error) {
+	logger.Debug.Printf("[%s] Received parse warnings: %q\n", RequestID(ctx), in.GetKind())
+
+	warnings, dropped := ParseWarnings.List(in.GetKind())
+
+	resp := &pb.ParseWarningsResponse{Warnings: make([]*pb.ParseWarning, 0, len(warnings)), Dropped: dropped}
+
+	for _, w := range warnings {
+		resp.Warnings = append(resp.Warnings, &pb.ParseWarning{
+			Kind:      w.Kind,
+			Element:   w.Element,
+			Attribute: w.Attr,
+			Value:     w.Value,
+			Id:        w.ID,
+			Count:     w.Count,
+			FirstSeen: w.FirstSeen,
+			LastSeen:  w.LastSeen,
+		})
+	}
+
+	return resp, nil
+}
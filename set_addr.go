@@ -0,0 +1,66 @@
+package main
+
+import "net/netip"
+
+// AddrSet - address map of int array object for ref purpose.
+type AddrSet map[netip.Addr]ArrayIntSet
+
+// Remove - delete item from the address map of int array.
+func (a *AddrSet) Remove(ip netip.Addr, id int32) {
+	if v, ok := (*a)[ip]; ok {
+		v = v.Del(id)
+
+		if len(v) == 0 {
+			delete(*a, ip)
+
+			return
+		}
+
+		(*a)[ip] = v
+	}
+}
+
+// Insert - add item to the address map of int array.
+func (a *AddrSet) Insert(ip netip.Addr, id int32) {
+	v, ok := (*a)[ip]
+	if !ok {
+		v = make(ArrayIntSet, 0, 1)
+	}
+
+	(*a)[ip] = v.Add(id)
+}
+
+// PrefixSet - subnet map of int array object for ref purpose.
+type PrefixSet map[netip.Prefix]ArrayIntSet
+
+// Remove - delete item from the subnet map of int array, true if it was the last one.
+func (a *PrefixSet) Remove(subnet netip.Prefix, id int32) bool {
+	if v, ok := (*a)[subnet]; ok {
+		v = v.Del(id)
+
+		if len(v) == 0 {
+			delete(*a, subnet)
+
+			return true
+		}
+
+		(*a)[subnet] = v
+	}
+
+	return false
+}
+
+// Insert - add item to the subnet map of int array, true if it is the first one.
+func (a *PrefixSet) Insert(subnet netip.Prefix, id int32) bool {
+	first := false
+
+	v, ok := (*a)[subnet]
+	if !ok {
+		v = make(ArrayIntSet, 0, 1)
+		first = true
+	}
+
+	(*a)[subnet] = v.Add(id)
+
+	return first
+}
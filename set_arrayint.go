@@ -8,6 +8,17 @@ func (a ArrayIntSet) Blank() bool {
 	return len(a) == 0
 }
 
+// Has - is the item in the array?
+func (a ArrayIntSet) Has(x int32) bool {
+	for _, v := range a {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Add - add item to the array.
 func (a ArrayIntSet) Add(x int32) ArrayIntSet {
 	for _, v := range a {
@@ -0,0 +1,76 @@
+package main
+
+import "sort"
+
+// TimeSet - time map of int array object with ordered keys for range queries.
+type TimeSet struct {
+	idx  map[int64]ArrayIntSet
+	keys []int64 // sorted keys, rebuilt by Reindex().
+}
+
+// NewTimeSet - TimeSet constructor.
+func NewTimeSet() *TimeSet {
+	return &TimeSet{idx: make(map[int64]ArrayIntSet)}
+}
+
+// Remove - delete item from the time map of int array.
+func (a *TimeSet) Remove(t int64, id int32) {
+	if v, ok := a.idx[t]; ok {
+		v = v.Del(id)
+
+		if len(v) == 0 {
+			delete(a.idx, t)
+
+			return
+		}
+
+		a.idx[t] = v
+	}
+}
+
+// Insert - add item to the time map of int array.
+func (a *TimeSet) Insert(t int64, id int32) {
+	v, ok := a.idx[t]
+	if !ok {
+		v = make(ArrayIntSet, 0, 1)
+	}
+
+	a.idx[t] = v.Add(id)
+}
+
+// Len - number of distinct times.
+func (a *TimeSet) Len() int {
+	return len(a.idx)
+}
+
+// Reindex - rebuild ordered keys, call it under write lock after updates.
+func (a *TimeSet) Reindex() {
+	keys := make([]int64, 0, len(a.idx))
+	for t := range a.idx {
+		keys = append(keys, t)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	a.keys = keys
+}
+
+// Range - IDs with time in [from, to], ordered by time, then by ID.
+func (a *TimeSet) Range(from, to int64) []int32 {
+	var result []int32
+
+	start := sort.Search(len(a.keys), func(i int) bool { return a.keys[i] >= from })
+
+	for _, t := range a.keys[start:] {
+		if t > to {
+			break
+		}
+
+		ids := append(ArrayIntSet(nil), a.idx[t]...)
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		result = append(result, ids...)
+	}
+
+	return result
+}
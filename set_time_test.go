@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestTimeSetRange tests ordered range queries over the time index.
+func TestTimeSetRange(t *testing.T) {
+	a := NewTimeSet()
+	a.Insert(30, 3)
+	a.Insert(10, 12)
+	a.Insert(10, 11)
+	a.Insert(20, 2)
+	a.Insert(40, 4)
+	a.Remove(40, 4)
+	a.Reindex()
+
+	if a.Len() != 3 {
+		t.Errorf("Len: %d", a.Len())
+	}
+
+	if got := a.Range(10, 30); !reflect.DeepEqual(got, []int32{11, 12, 2, 3}) {
+		t.Errorf("Range(10, 30): %v", got)
+	}
+
+	if got := a.Range(15, 25); !reflect.DeepEqual(got, []int32{2}) {
+		t.Errorf("Range(15, 25): %v", got)
+	}
+
+	if got := a.Range(31, 100); len(got) != 0 {
+		t.Errorf("Range(31, 100): %v", got)
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// Shadow discrepancy kinds.
+const (
+	ShadowMissing = "missing" // the live record is not in the shadow index.
+	ShadowExtra   = "extra"   // the shadow record is not in the live index.
+	ShadowDiffer  = "differ"  // fields of the record differ.
+)
+
+// shadowMaxListed - discrepancies listed in the report, all of them are counted.
+const shadowMaxListed = 100
+
+// ShadowOptions - shadow parse of every applied dump: another parser, a new build or the same one
+// with other parse flags, parses dump.xml to its own index in a separate process, its records
+// are compared with the live ones. Serving is not affected.
+type ShadowOptions struct {
+	Enabled    bool
+	Binary     string   // parser executable with the shadow subcommand, empty is this one.
+	Args       []string // shadow parse flags, they override the flags of the live parse.
+	OrgAliases string   // -org-aliases of the live parse.
+}
+
+// ShadowConfig - shadow parse configuration, it is set once at startup.
+var ShadowConfig ShadowOptions
+
+// ShadowRecord - fields of a parsed record which don't depend on the hash or payload options.
+type ShadowRecord struct {
+	ID           int32    `json:"id"`
+	BlockType    int32    `json:"blockType"`
+	EntryType    int32    `json:"entryType"`
+	UrgencyType  int32    `json:"urgencyType,omitempty"`
+	IncludeTime  int64    `json:"includeTime"`
+	Ts           int64    `json:"ts,omitempty"`
+	DecisionDate int64    `json:"decisionDate"`
+	Org          string   `json:"org"`
+	Selectors    []string `json:"selectors"` // "kind value" normalized, ordered.
+}
+
+// ShadowDiscrepancy - record the shadow parse disagrees with.
+type ShadowDiscrepancy struct {
+	ID     int32
+	Kind   string
+	Fields []string // ShadowDiffer: JSON names of the differing fields.
+}
+
+// ShadowReport - comparison of the shadow parse with the live generation.
+type ShadowReport struct {
+	Generation    int64
+	Started       time.Time
+	Finished      time.Time
+	Live          int
+	Shadow        int
+	Missing       int
+	Extra         int
+	Differ        int
+	Discrepancies []ShadowDiscrepancy // the first shadowMaxListed, ordered by id.
+	Failure       string              // the shadow parse failed or the live index moved on, nothing is compared.
+}
+
+// Total - all discrepancies.
+func (r *ShadowReport) Total() int {
+	return r.Missing + r.Extra + r.Differ
+}
+
+var (
+	shadowRunning atomic.Bool
+	shadowLast    atomic.Value // *ShadowReport
+)
+
+// LastShadowReport - report of the last finished shadow parse, nil if there is none.
+func LastShadowReport() *ShadowReport {
+	r, _ := shadowLast.Load().(*ShadowReport)
+
+	return r
+}
+
+// newShadowRecord - comparable fields of the record.
+func newShadowRecord(pack *PackedContent) ShadowRecord {
+	set := make(SelectorSet)
+	pack.addSelectors(set)
+
+	sels := make([]Selector, 0, len(set))
+	for sel := range set {
+		sels = append(sels, sel)
+	}
+
+	sortSelectors(sels)
+
+	v := ShadowRecord{
+		ID:           pack.ID,
+		BlockType:    pack.BlockType,
+		EntryType:    pack.EntryType,
+		UrgencyType:  pack.UrgencyType,
+		IncludeTime:  pack.IncludeTime,
+		Ts:           pack.Ts,
+		DecisionDate: pack.DecisionDate,
+		Org:          pack.Org,
+		Selectors:    make([]string, 0, len(sels)),
+	}
+
+	for _, sel := range sels {
+		v.Selectors = append(v.Selectors, sel.Kind+" "+sel.Value)
+	}
+
+	return v
+}
+
+// diff - JSON names of the fields which differ.
+func (v ShadowRecord) diff(other ShadowRecord) []string {
+	var fields []string
+
+	for _, f := range []struct {
+		name string
+		same bool
+	}{
+		{"blockType", v.BlockType == other.BlockType},
+		{"entryType", v.EntryType == other.EntryType},
+		{"urgencyType", v.UrgencyType == other.UrgencyType},
+		{"includeTime", v.IncludeTime == other.IncludeTime},
+		{"ts", v.Ts == other.Ts},
+		{"decisionDate", v.DecisionDate == other.DecisionDate},
+		{"org", v.Org == other.Org},
+		{"selectors", equalStrings(v.Selectors, other.Selectors)},
+	} {
+		if !f.same {
+			fields = append(fields, f.name)
+		}
+	}
+
+	return fields
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteShadowRecords - comparable fields of all records as JSON lines ordered by id, call it under read lock.
+func (dump *Dump) WriteShadowRecords(w io.Writer) error {
+	ids := make([]int32, 0, len(dump.ContentIdx))
+	for id := range dump.ContentIdx {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	enc := json.NewEncoder(w)
+
+	for _, id := range ids {
+		if err := enc.Encode(newShadowRecord(dump.ContentIdx[id])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadShadowRecords - records written by WriteShadowRecords.
+func ReadShadowRecords(r io.Reader) (map[int32]ShadowRecord, error) {
+	records := make(map[int32]ShadowRecord)
+
+	dec := json.NewDecoder(r)
+
+	for {
+		var v ShadowRecord
+
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		records[v.ID] = v
+	}
+}
+
+// CompareShadow - discrepancies of the shadow records with the live generation, call it under read lock.
+func (dump *Dump) CompareShadow(shadow map[int32]ShadowRecord, generation int64) *ShadowReport {
+	report := &ShadowReport{Generation: generation, Live: len(dump.ContentIdx), Shadow: len(shadow)}
+
+	if dump.generation != generation {
+		report.Failure = fmt.Sprintf("live index moved on to generation %d", dump.generation)
+
+		return report
+	}
+
+	var list []ShadowDiscrepancy
+
+	for id, pack := range dump.ContentIdx {
+		v, ok := shadow[id]
+		if !ok {
+			report.Missing++
+			list = append(list, ShadowDiscrepancy{ID: id, Kind: ShadowMissing})
+
+			continue
+		}
+
+		if fields := newShadowRecord(pack).diff(v); len(fields) > 0 {
+			report.Differ++
+			list = append(list, ShadowDiscrepancy{ID: id, Kind: ShadowDiffer, Fields: fields})
+		}
+	}
+
+	for id := range shadow {
+		if _, ok := dump.ContentIdx[id]; !ok {
+			report.Extra++
+			list = append(list, ShadowDiscrepancy{ID: id, Kind: ShadowExtra})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	if len(list) > shadowMaxListed {
+		list = list[:shadowMaxListed]
+	}
+
+	report.Discrepancies = list
+
+	return report
+}
+
+// shadowArgs - flags of the live parse followed by the shadow ones, the last ones win.
+func shadowArgs() []string {
+	args := []string{
+		"-hash", ParseConfig.Hash,
+		"-hash-seed", strconv.FormatUint(ParseConfig.HashSeed, 10),
+		"-charset", ParseConfig.Charset,
+		"-compare", ParseConfig.Compare,
+		"-mixed-urls", ParseConfig.MixedURLs,
+		"-payload", ParseConfig.Payload,
+		"-anomalies", ParseConfig.Anomalies,
+		"-duplicates", ParseConfig.DuplicatePolicy,
+		"-oversized", ParseConfig.Oversized,
+		"-max-record-selectors", strconv.Itoa(ParseConfig.MaxRecordSelectors),
+		"-max-record-bytes", strconv.Itoa(ParseConfig.MaxRecordBytes),
+		"-org-aliases", ShadowConfig.OrgAliases,
+	}
+
+	return append(args, ShadowConfig.Args...)
+}
+
+// ShadowParse - parse dump.xml of the dir in the shadow parser and compare it with the live generation
+// in the background. A shadow parse still running is not interrupted, the dump is skipped.
+func ShadowParse(dir string) {
+	if !ShadowConfig.Enabled {
+		return
+	}
+
+	if !shadowRunning.CompareAndSwap(false, true) {
+		logger.Warning.Printf("Shadow parse is still running, skipped\n")
+
+		return
+	}
+
+	generation, _ := CurrentDump.Changes()
+
+	go func() {
+		defer shadowRunning.Store(false)
+
+		report := runShadowParse(dir+"/dump.xml", generation)
+		shadowLast.Store(report)
+
+		metricShadowDiscrepancies.Set(int64(report.Total()))
+
+		switch {
+		case report.Failure != "":
+			logger.Error.Printf("Shadow parse of generation %d: %s\n", generation, report.Failure)
+		case report.Total() > 0:
+			RaiseAlert(AlertShadow, generation, fmt.Sprintf("shadow parse differs: %d missing, %d extra, %d differ",
+				report.Missing, report.Extra, report.Differ))
+		default:
+			logger.Info.Printf("Shadow parse of generation %d: %d records, no discrepancies\n", generation, report.Shadow)
+		}
+	}()
+}
+
+// runShadowParse - run the shadow parser and compare its records.
+func runShadowParse(filename string, generation int64) *ShadowReport {
+	started := time.Now()
+
+	fail := func(err error) *ShadowReport {
+		return &ShadowReport{Generation: generation, Started: started, Finished: time.Now(), Failure: err.Error()}
+	}
+
+	binary := ShadowConfig.Binary
+	if binary == "" {
+		self, err := os.Executable()
+		if err != nil {
+			return fail(fmt.Errorf("executable: %w", err))
+		}
+
+		binary = self
+	}
+
+	cmd := exec.Command(binary, append(append([]string{"shadow"}, shadowArgs()...), filename)...)
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fail(fmt.Errorf("pipe: %w", err))
+	}
+
+	logger.Debug.Printf("Run shadow parse: %s\n", cmd.String())
+
+	if err := cmd.Start(); err != nil {
+		return fail(fmt.Errorf("start: %w", err))
+	}
+
+	shadow, err := ReadShadowRecords(stdout)
+	if err != nil {
+		io.Copy(io.Discard, stdout)
+	}
+
+	if werr := cmd.Wait(); werr != nil {
+		return fail(fmt.Errorf("shadow parser: %w", werr))
+	}
+
+	if err != nil {
+		return fail(fmt.Errorf("read: %w", err))
+	}
+
+	CurrentDump.RLock()
+	report := CurrentDump.CompareShadow(shadow, generation)
+	CurrentDump.RUnlock()
+
+	report.Started, report.Finished = started, time.Now()
+
+	return report
+}
+
+// shadowCommand - shadow side of ShadowParse: parse the dump file with the flags given
+// and write its records by WriteShadowRecords to stdout.
+func shadowCommand(args []string) int {
+	fs := flag.NewFlagSet("shadow", flag.ContinueOnError)
+
+	hash := fs.String("hash", parser.HashFNV, "Record and decision hash: fnv, xxhash")
+	hashSeed := fs.Uint64("hash-seed", 0, "Hash seed")
+	charset := fs.String("charset", parser.CharsetDeclared, "Dump charset handling: declared, lenient, strict")
+	compare := fs.String("compare", CompareHash, "Changed records: hash, semantic")
+	mixedURLs := fs.String("mixed-urls", MixedHTTPS, "URL records with https and other URLs are: https, url")
+	payload := fs.String("payload", PayloadKeep, "Record payloads: keep, none")
+	anomalies := fs.String("anomalies", parser.AnomalyKeep, "Address anomalies: keep, reject")
+	duplicates := fs.String("duplicates", DuplicateKeepLast, "Duplicate content id policy: last, first, merge")
+	oversized := fs.String("oversized", parser.OversizedTruncate, "Records over -max-record-selectors: truncate, quarantine")
+	maxSelectors := fs.Int("max-record-selectors", 0, "Max IPs, subnets, domains and URLs of a record, 0 means unlimited")
+	maxBytes := fs.Int("max-record-bytes", 0, "Max <content> size in bytes, 0 means unlimited")
+	orgAliases := fs.String("org-aliases", "", "File with \"variant = canonical\" decision organization aliases")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s shadow [flags] <dump.xml>\n", os.Args[0])
+		fs.PrintDefaults()
+
+		return 2
+	}
+
+	if _, err := parser.NewHasher(*hash, *hashSeed); err != nil {
+		fmt.Fprintf(os.Stderr, "Bad hash: %s\n", err.Error())
+
+		return 2
+	}
+
+	ParseConfig = ParseOptions{
+		DuplicatePolicy:    *duplicates,
+		Hash:               *hash,
+		HashSeed:           *hashSeed,
+		Charset:            *charset,
+		Compare:            *compare,
+		MixedURLs:          *mixedURLs,
+		Payload:            *payload,
+		Anomalies:          *anomalies,
+		MaxRecordSelectors: *maxSelectors,
+		MaxRecordBytes:     *maxBytes,
+		Oversized:          *oversized,
+	}
+
+	if *orgAliases != "" {
+		if err := LoadOrgAliases(*orgAliases); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't load organization aliases: %s\n", err.Error())
+
+			return 1
+		}
+	}
+
+	dumpFile, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't open dump file: %s\n", err.Error())
+
+		return 1
+	}
+
+	defer dumpFile.Close()
+
+	if err := Parse(dumpFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Can't parse dump file: %s\n", err.Error())
+
+		return 1
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+
+	CurrentDump.RLock()
+	err = CurrentDump.WriteShadowRecords(w)
+	CurrentDump.RUnlock()
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't write records: %s\n", err.Error())
+
+		return 1
+	}
+
+	return 0
+}
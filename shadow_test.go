@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCompareShadow tests discrepancies of a differently configured shadow parse with the live index.
+func TestCompareShadow(t *testing.T) {
+	defer func(dump *Dump, config ParseOptions) { CurrentDump, ParseConfig = dump, config }(CurrentDump, ParseConfig)
+
+	// the shadow parse: mixed URL records are URL blocks, 555 is 666.
+	CurrentDump = NewDump()
+	ParseConfig.MixedURLs = MixedURL
+
+	if err := Parse(strings.NewReader(strings.Replace(xml01, `<content id="555"`, `<content id="666"`, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := CurrentDump.WriteShadowRecords(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	shadow, err := ReadShadowRecords(&buf)
+	if err != nil || len(shadow) != 5 || !reflect.DeepEqual(shadow[111].Selectors[:2], []string{"domain www.e01.tld", "ip4 10.1.1.1"}) {
+		t.Fatalf("Shadow records: %v %v\n", shadow, err)
+	}
+
+	CurrentDump = NewDump()
+	ParseConfig.MixedURLs = MixedHTTPS
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	generation, _ := CurrentDump.Changes()
+
+	report := CurrentDump.CompareShadow(shadow, generation)
+	if report.Failure != "" || report.Live != 5 || report.Shadow != 5 || report.Total() != 3 ||
+		!reflect.DeepEqual(report.Discrepancies, []ShadowDiscrepancy{
+			{ID: 111, Kind: ShadowDiffer, Fields: []string{"blockType"}},
+			{ID: 555, Kind: ShadowMissing},
+			{ID: 666, Kind: ShadowExtra},
+		}) {
+		t.Errorf("Report: %+v\n", report)
+	}
+
+	if report := CurrentDump.CompareShadow(shadow, generation-1); report.Failure == "" || report.Total() != 0 {
+		t.Errorf("Moved on: %+v\n", report)
+	}
+}
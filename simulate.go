@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Collision kinds of a simulated selector.
+const (
+	CollisionExact  = "exact"  // the selector is already in the index.
+	CollisionWithin = "within" // an indexed subnet or parent domain covers it.
+	CollisionCovers = "covers" // it covers indexed IPs, subnets or subdomains.
+)
+
+// SimRecord - selectors of a hypothetical record.
+type SimRecord struct {
+	IPs     []string // IPv4 or IPv6.
+	Subnets []string // IPv4 or IPv6 CIDR.
+	Domains []string
+	URLs    []string
+}
+
+// Collision - indexed selector the simulated one collides with.
+type Collision struct {
+	Selector string // simulated, as given.
+	Kind     string
+	Existing string // indexed, normalized.
+	IDs      ArrayIntSet
+}
+
+// SimReport - collisions of the simulated record.
+type SimReport struct {
+	Collisions []Collision // up to the limit.
+	Total      int         // all collisions.
+	Records    int         // distinct records collided with.
+}
+
+// Simulate - what the record would collide with in the index, the first limit collisions are kept.
+func (dump *Dump) Simulate(rec SimRecord, limit int) (*SimReport, error) {
+	report := &SimReport{}
+	records := make(Int32Map)
+
+	add := func(selector, kind, existing string, ids ArrayIntSet) {
+		if len(ids) == 0 {
+			return
+		}
+
+		for _, id := range ids {
+			records[id] = Nothing{}
+		}
+
+		report.Total++
+
+		if limit <= 0 || len(report.Collisions) < limit {
+			// index sets are changed in place, the report outlives the lock.
+			ids = append(ArrayIntSet(nil), ids...)
+			report.Collisions = append(report.Collisions, Collision{Selector: selector, Kind: kind, Existing: existing, IDs: ids})
+		}
+	}
+
+	dump.RLock()
+	defer dump.RUnlock()
+
+	for _, s := range rec.IPs {
+		ip, err := netip.ParseAddr(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("bad IP: %s", s)
+		}
+
+		ip = ip.Unmap().WithZone("")
+		add(s, CollisionExact, ip.String(), dump.ipIDs(ip))
+
+		for _, subnet := range dump.containingSubnets(ip, -1) {
+			add(s, CollisionWithin, subnet.String(), dump.subnetIDs(subnet))
+		}
+	}
+
+	for _, s := range rec.Subnets {
+		network, err := netip.ParsePrefix(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("bad subnet: %s", s)
+		}
+
+		network = network.Masked()
+		exact := network.String()
+
+		add(s, CollisionExact, exact, dump.subnetIDs(network))
+
+		for _, subnet := range dump.containingSubnets(network.Addr(), network.Bits()) {
+			add(s, CollisionWithin, subnet.String(), dump.subnetIDs(subnet))
+		}
+
+		for _, existing := range dump.coveredSelectors(network) {
+			if existing != exact {
+				add(s, CollisionCovers, existing, dump.selectorIDs(existing))
+			}
+		}
+	}
+
+	for _, s := range rec.Domains {
+		domain := NormalizeDomain(strings.TrimSpace(s))
+		if domain == "" {
+			return nil, fmt.Errorf("bad domain: %s", s)
+		}
+
+		add(s, CollisionExact, domain, dump.domainIdx[domain])
+
+		for _, parent := range parentDomains(domain) {
+			add(s, CollisionWithin, parent, dump.domainIdx[parent])
+		}
+
+		for _, sub := range dump.subdomains(domain) {
+			add(s, CollisionCovers, sub, dump.domainIdx[sub])
+		}
+	}
+
+	for _, s := range rec.URLs {
+		u := NormalizeURL(strings.TrimSpace(s))
+
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Hostname() == "" {
+			return nil, fmt.Errorf("bad URL: %s", s)
+		}
+
+		add(s, CollisionExact, u, dump.urlIdx[u])
+
+		host := NormalizeDomain(parsed.Hostname())
+
+		for _, domain := range append([]string{host}, parentDomains(host)...) {
+			add(s, CollisionWithin, domain, dump.domainIdx[domain])
+		}
+	}
+
+	report.Records = len(records)
+
+	return report, nil
+}
+
+// containingSubnets - indexed subnets with the IP, broader than ones bits if ones >= 0.
+func (dump *Dump) containingSubnets(ip netip.Addr, ones int) []netip.Prefix {
+	entries, err := dump.netTree.ContainingNetworks(ip.AsSlice())
+	if err != nil {
+		return nil
+	}
+
+	subnets := make([]netip.Prefix, 0, len(entries))
+
+	for _, entry := range entries {
+		subnet := ipNetPrefix(entry.Network())
+		if ones >= 0 && subnet.Bits() >= ones {
+			continue
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets
+}
+
+// coveredSelectors - indexed subnets and IPs inside the network, sorted.
+func (dump *Dump) coveredSelectors(network netip.Prefix) []string {
+	var selectors []string
+
+	if entries, err := dump.netTree.CoveredNetworks(prefixIPNet(network)); err == nil {
+		for _, entry := range entries {
+			selectors = append(selectors, ipNetPrefix(entry.Network()).String())
+		}
+	}
+
+	idx := dump.ip6Idx
+	if network.Addr().Is4() {
+		idx = dump.ip4Idx
+	}
+
+	for ip := range idx {
+		if network.Contains(ip) {
+			selectors = append(selectors, ip.String())
+		}
+	}
+
+	sort.Strings(selectors)
+
+	return selectors
+}
+
+// subnetIDs - records of the indexed subnet.
+func (dump *Dump) subnetIDs(subnet netip.Prefix) ArrayIntSet {
+	if ids, ok := dump.subnet4Idx[subnet]; ok {
+		return ids
+	}
+
+	return dump.subnet6Idx[subnet]
+}
+
+// ipIDs - records of the indexed IP, IPv6 are indexed in the 16 bytes form.
+func (dump *Dump) ipIDs(ip netip.Addr) ArrayIntSet {
+	if ip.Is4() {
+		return dump.ip4Idx[ip]
+	}
+
+	return dump.ip6Idx[netip.AddrFrom16(ip.As16())]
+}
+
+// selectorIDs - records of the indexed IP or subnet.
+func (dump *Dump) selectorIDs(s string) ArrayIntSet {
+	if subnet, err := netip.ParsePrefix(s); err == nil {
+		return dump.subnetIDs(subnet)
+	}
+
+	ip, _ := netip.ParseAddr(s)
+
+	return dump.ipIDs(ip)
+}
+
+// subdomains - indexed subdomains of the domain, sorted.
+func (dump *Dump) subdomains(domain string) []string {
+	var subs []string
+
+	suffix := "." + domain
+
+	for d := range dump.domainIdx {
+		if strings.HasSuffix(d, suffix) {
+			subs = append(subs, d)
+		}
+	}
+
+	sort.Strings(subs)
+
+	return subs
+}
+
+// parentDomains - parent domains without the top level one, nearest first.
+func parentDomains(domain string) []string {
+	var parents []string
+
+	for {
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+
+		domain = domain[i+1:]
+		if !strings.Contains(domain, ".") {
+			break
+		}
+
+		parents = append(parents, domain)
+	}
+
+	return parents
+}
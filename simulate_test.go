@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSimulate tests collisions of hypothetical selectors with the index.
+func TestSimulate(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		rec  SimRecord
+		want string
+	}{
+		{SimRecord{IPs: []string{"10.4.4.4"}}, "10.4.4.4 exact 10.4.4.4 [444], 10.4.4.4 within 10.4.0.0/16 [444]"},
+		{SimRecord{IPs: []string{"192.0.2.1"}}, ""},
+		{SimRecord{Subnets: []string{"10.4.4.0/24"}}, "10.4.4.0/24 within 10.4.0.0/16 [444], 10.4.4.0/24 covers 10.4.4.4 [444]"},
+		{SimRecord{Subnets: []string{"10.4.0.0/16"}}, "10.4.0.0/16 exact 10.4.0.0/16 [444], 10.4.0.0/16 covers 10.4.4.4 [444]"},
+		{SimRecord{Domains: []string{"e01.tld"}}, "e01.tld covers www.e01.tld [111]"},
+		{SimRecord{Domains: []string{"a.www.e01.tld"}}, "a.www.e01.tld within www.e01.tld [111]"},
+		{SimRecord{URLs: []string{"http://www.e01.tld/cheese"}}, "http://www.e01.tld/cheese exact http://www.e01.tld/cheese [111], http://www.e01.tld/cheese within www.e01.tld [111]"},
+	} {
+		report, err := CurrentDump.Simulate(tc.rec, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make([]string, 0, len(report.Collisions))
+		for _, c := range report.Collisions {
+			got = append(got, fmt.Sprintf("%s %s %s %v", c.Selector, c.Kind, c.Existing, c.IDs))
+		}
+
+		if strings.Join(got, ", ") != tc.want {
+			t.Errorf("%+v: %s", tc.rec, strings.Join(got, ", "))
+		}
+	}
+
+	// the whole /8 covers the subnet and five IPs of five records, two are returned.
+	report, err := CurrentDump.Simulate(SimRecord{Subnets: []string{"10.0.0.0/8"}}, 2)
+	if err != nil || report.Total != 6 || report.Records != 5 || len(report.Collisions) != 2 {
+		t.Errorf("limit: %+v %v", report, err)
+	}
+
+	if _, err := CurrentDump.Simulate(SimRecord{IPs: []string{"10.4.4"}}, 0); err == nil {
+		t.Error("bad IP is accepted")
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// Snapshot - serialized parsed dump of one generation.
+type Snapshot struct {
+	Generation int64
+	UpdateTime int64
+	Contents   []*SnapshotRecord
+}
+
+// SnapshotRecord - stored fields of a record, selectors are restored from the payload.
+// They have the names and types of PackedContent fields snapshots were written with before,
+// so gob reads older snapshots too.
+type SnapshotRecord struct {
+	ID                 int32
+	RegistryUpdateTime int64
+	Decision           uint64
+	Payload            []byte
+	Compressed         bool
+	Raw                []byte
+	Ordinal            int32 // position in the dump file, 0 in older snapshots.
+}
+
+// PayloadBytes - the payload, decompressed on read if needed.
+func (rec *SnapshotRecord) PayloadBytes() []byte {
+	return payloadBytes(rec.ID, rec.Payload, rec.Compressed)
+}
+
+// SnapshotKeep - number of retained snapshots, 0 disables snapshots.
+var SnapshotKeep int
+
+// ErrNoSnapshot - no snapshot is retained in the dir.
+var ErrNoSnapshot = errors.New("no snapshot")
+
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".gob.gz"
+)
+
+// SnapshotFilename - snapshot file name for the generation.
+func SnapshotFilename(dir string, generation int64) string {
+	return fmt.Sprintf("%s/%s%d%s", dir, snapshotPrefix, generation, snapshotSuffix)
+}
+
+// snapshot - in-memory snapshot, call it under lock.
+func (dump *Dump) snapshot() *Snapshot {
+	snap := &Snapshot{
+		Generation: dump.generation,
+		UpdateTime: dump.utime,
+		Contents:   make([]*SnapshotRecord, 0, len(dump.ContentIdx)),
+	}
+
+	for _, cont := range dump.ContentIdx {
+		rec := &SnapshotRecord{
+			ID:                 cont.ID,
+			RegistryUpdateTime: cont.RegistryUpdateTime,
+			Decision:           cont.Decision,
+			Payload:            cont.Payload,
+			Compressed:         cont.Compressed,
+			Raw:                cont.Raw,
+			Ordinal:            dump.ordinals.Get(cont.ID),
+		}
+
+		// without payloads the record is rebuilt to be restored.
+		if cont.Payload == nil {
+			record, _ := cont.Record()
+			rec.Payload = record.Marshal()
+		}
+
+		snap.Contents = append(snap.Contents, rec)
+	}
+
+	sort.Slice(snap.Contents, func(i, j int) bool { return snap.Contents[i].ID < snap.Contents[j].ID })
+
+	return snap
+}
+
+// WriteSnapshot - save current generation to the file.
+func (dump *Dump) WriteSnapshot(filename string) error {
+	dump.RLock()
+	defer dump.RUnlock()
+
+	return writeSnapshot(filename, dump.snapshot())
+}
+
+func writeSnapshot(filename string, snap *Snapshot) error {
+	tfn := filename + "-tmp"
+
+	f, err := os.Create(tfn)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+
+	err = gob.NewEncoder(zw).Encode(snap)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return fmt.Errorf("gzip: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	err = os.Rename(tfn, filename)
+	if err != nil {
+		return fmt.Errorf("file rename: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSnapshot - load snapshot from the file.
+func ReadSnapshot(filename string) (*Snapshot, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+
+	snap := &Snapshot{}
+
+	err = gob.NewDecoder(zr).Decode(snap)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	return snap, nil
+}
+
+// RestoreSnapshot - replace the index with the snapshot records as a new generation.
+func (dump *Dump) RestoreSnapshot(snap *Snapshot) error {
+	stats := ParseStatistics{Initial: len(dump.ContentIdx) == 0}
+	stage := newParseStage()
+
+	for _, pack := range snap.Contents {
+		record := &Content{}
+
+		payload := pack.PayloadBytes()
+		if err := json.Unmarshal(payload, record); err != nil {
+			return fmt.Errorf("content %d: %w", pack.ID, err)
+		}
+
+		record.DecisionHash = pack.Decision
+
+		stage.journal.Add(pack.ID)
+		if pack.Ordinal > 0 {
+			stage.ordinals.Set(pack.ID, pack.Ordinal)
+		}
+		stage.records[pack.ID] = &stagedRecord{content: record, payload: payload, changed: pack.RegistryUpdateTime}
+		stage.order = append(stage.order, pack.ID)
+
+		if ParseConfig.KeepRaw {
+			stage.records[pack.ID].raw = pack.Raw
+		}
+	}
+
+	// records keep their own change times.
+	added, _ := dump.Commit(stage, &stats, snap.UpdateTime)
+
+	stats.Count, stats.AddCount = len(snap.Contents), len(added)
+	stats.UpdateCount = stats.Count - stats.AddCount
+
+	stats.Update()
+	Stats = stats
+
+	return nil
+}
+
+// ListSnapshots - generations of retained snapshots, ascending.
+func ListSnapshots(dir string) ([]int64, error) {
+	files, err := filepath.Glob(filepath.Join(dir, snapshotPrefix+"*"+snapshotSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("glob: %w", err)
+	}
+
+	generations := make([]int64, 0, len(files))
+
+	for _, filename := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(filename), snapshotPrefix), snapshotSuffix)
+
+		generation, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		generations = append(generations, generation)
+	}
+
+	sort.Slice(generations, func(i, j int) bool { return generations[i] < generations[j] })
+
+	return generations, nil
+}
+
+// SaveSnapshot - save current generation and keep only last keep snapshots.
+func SaveSnapshot(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	CurrentDump.RLock()
+	generation := CurrentDump.generation
+	CurrentDump.RUnlock()
+
+	err := CurrentDump.WriteSnapshot(SnapshotFilename(dir, generation))
+	if err != nil {
+		return err
+	}
+
+	generations, err := ListSnapshots(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(generations) > keep {
+		err := os.Remove(SnapshotFilename(dir, generations[0]))
+		if err != nil {
+			logger.Error.Printf("Can't remove old snapshot: %s\n", err.Error())
+		}
+
+		generations = generations[1:]
+	}
+
+	return nil
+}
+
+// RestoreGeneration - continue generation numbering after the last retained snapshot.
+func RestoreGeneration(dir string) {
+	generations, err := ListSnapshots(dir)
+	if err != nil {
+		logger.Error.Printf("Can't list snapshots: %s\n", err.Error())
+
+		return
+	}
+
+	if len(generations) > 0 {
+		CurrentDump.Lock()
+		CurrentDump.generation = generations[len(generations)-1]
+		CurrentDump.Unlock()
+	}
+}
+
+// RestoreLatestSnapshot - restore the index from the latest retained snapshot, e.g. the one
+// the previous instance saved, a new instance serves it without parsing the dump.
+func RestoreLatestSnapshot(dir string) error {
+	generations, err := ListSnapshots(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(generations) == 0 {
+		return ErrNoSnapshot
+	}
+
+	filename := SnapshotFilename(dir, generations[len(generations)-1])
+
+	snap, err := ReadSnapshot(filename)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if err := CurrentDump.RestoreSnapshot(snap); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	logger.Info.Printf("Restored from %s: %d records\n", filename, len(snap.Contents))
+
+	return nil
+}
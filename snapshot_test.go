@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSnapshotDiff tests snapshot round trip and selector level diff.
+func TestSnapshotDiff(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	dir := t.TempDir()
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveSnapshot(dir, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	fromSet := CurrentDump.snapshot().Selectors()
+
+	CurrentDump = NewDump()
+	CurrentDump.generation = 1
+	if err := Parse(strings.NewReader(xml02)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveSnapshot(dir, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if generations, _ := ListSnapshots(dir); len(generations) != 1 || generations[0] != 2 {
+		t.Fatalf("retention: %v", generations)
+	}
+
+	toSet, err := GenerationSelectors(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed := DiffSelectors(fromSet, toSet)
+
+	hasSelector := func(a []Selector, sel Selector) bool {
+		for _, v := range a {
+			if v == sel {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !hasSelector(added, Selector{SelectorDomain, "www.example01.com"}) ||
+		!hasSelector(removed, Selector{SelectorDomain, "www.e01.tld"}) ||
+		!hasSelector(removed, Selector{SelectorURL, "http://www.e01.tld/slip"}) ||
+		hasSelector(added, Selector{SelectorSubnet4, "10.4.0.0/16"}) ||
+		hasSelector(removed, Selector{SelectorSubnet4, "10.4.0.0/16"}) {
+		t.Errorf("diff error: +%v -%v", added, removed)
+	}
+}
+
+// TestRestoreLatestSnapshot tests the handoff of the index through the latest snapshot.
+func TestRestoreLatestSnapshot(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	dir := t.TempDir()
+
+	CurrentDump = NewDump()
+	if err := RestoreLatestSnapshot(dir); err != ErrNoSnapshot {
+		t.Errorf("Empty dir: %v\n", err)
+	}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveSnapshot(dir, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	old := CurrentDump
+
+	CurrentDump = NewDump()
+	if err := RestoreLatestSnapshot(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(CurrentDump.ContentIdx) != len(old.ContentIdx) || CurrentDump.utime != old.utime ||
+		len(CurrentDump.ip4Idx) != len(old.ip4Idx) || len(CurrentDump.domainIdx) != len(old.domainIdx) {
+		t.Errorf("Restored: %d records, %d IPs\n", len(CurrentDump.ContentIdx), len(CurrentDump.ip4Idx))
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// sniCache - sorted SNI list of one generation.
+type sniCache struct {
+	sync.Mutex
+	generation int64
+	list       []string
+}
+
+// SNI - server names an SNI filtering DPI needs to block the record.
+// Only HTTPS, domain and domain mask blocks are enforceable by SNI.
+func (pack *PackedContent) SNI() []string {
+	var result []string
+
+	seen := make(StringMap)
+	add := func(name string) {
+		if _, ok := seen[name]; !ok && name != "" {
+			seen[name] = Nothing{}
+			result = append(result, name)
+		}
+	}
+
+	switch pack.BlockType {
+	case BlockTypeHTTPS:
+		for _, u := range pack.URL {
+			nurl, err := url.Parse(NormalizeURL(u.URL))
+			if err != nil || nurl.Scheme != "https" {
+				continue
+			}
+
+			add(nurl.Hostname())
+		}
+	case BlockTypeDomain:
+		for _, domain := range pack.Domain {
+			add(NormalizeDomain(domain.Domain))
+		}
+	case BlockTypeMask:
+		for _, domain := range pack.Domain {
+			nDomain := NormalizeDomain(domain.Domain)
+			if nDomain == "" {
+				continue
+			}
+
+			add(nDomain)
+			add("*." + nDomain)
+		}
+	}
+
+	return result
+}
+
+// SNIList - sorted unique SNI values of all records passing the filter, call it under read lock.
+// The list of all records is cached.
+func (dump *Dump) SNIList(filter *ExportFilter) []string {
+	if filter != nil {
+		return dump.sniList(filter)
+	}
+
+	dump.sni.Lock()
+	defer dump.sni.Unlock()
+
+	if dump.sni.list != nil && dump.sni.generation == dump.generation {
+		return dump.sni.list
+	}
+
+	dump.sni.generation, dump.sni.list = dump.generation, dump.sniList(nil)
+
+	return dump.sni.list
+}
+
+func (dump *Dump) sniList(filter *ExportFilter) []string {
+	seen := make(StringMap)
+	list := make([]string, 0)
+
+	for _, cont := range dump.ContentIdx {
+		if !filter.Match(cont) {
+			continue
+		}
+
+		for _, name := range cont.SNI() {
+			if _, ok := seen[name]; !ok {
+				seen[name] = Nothing{}
+				list = append(list, name)
+			}
+		}
+	}
+
+	sort.Strings(list)
+
+	return list
+}
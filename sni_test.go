@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+const sniDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <url><![CDATA[https://www.E1.tld/a]]></url>
+        <url><![CDATA[https://www.e1.tld/b]]></url>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="2">
+        <decision date="2000-01-01" number="2" org="TWO"/>
+        <domain><![CDATA[e2.tld]]></domain>
+</content>
+<content id="3" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain-mask" hash="3">
+        <decision date="2000-01-01" number="3" org="ONE"/>
+        <domain><![CDATA[*.e3.tld]]></domain>
+</content>
+<content id="4" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="4">
+        <decision date="2000-01-01" number="4" org="ONE"/>
+        <ip>10.1.1.1</ip>
+</content>
+<content id="5" includeTime="2001-01-01T01:01:01" entryType="1" hash="5">
+        <decision date="2000-01-01" number="5" org="ONE"/>
+        <url><![CDATA[http://www.e5.tld/a]]></url>
+</content>
+</reg:register>`
+
+// TestListSNI tests SNI values of HTTPS, domain and mask records, paging and filters.
+func TestListSNI(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(sniDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &server{}
+
+	for _, tc := range []struct {
+		name   string
+		in     *pb.SNIRequest
+		expect []string
+		total  int32
+	}{
+		{"all", &pb.SNIRequest{}, []string{"*.e3.tld", "e2.tld", "e3.tld", "www.e1.tld"}, 4},
+		{"page", &pb.SNIRequest{Offset: 1, Limit: 2}, []string{"e2.tld", "e3.tld"}, 4},
+		{"filter", &pb.SNIRequest{Filter: "org=TWO"}, []string{"e2.tld"}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := srv.ListSNI(context.Background(), tc.in)
+			if err != nil || resp.GetError() != "" {
+				t.Fatalf("ListSNI: %v %v\n", resp, err)
+			}
+
+			if !reflect.DeepEqual(resp.GetSni(), tc.expect) || resp.GetTotal() != tc.total {
+				t.Errorf("SNI: %q, total: %d\n", resp.GetSni(), resp.GetTotal())
+			}
+		})
+	}
+
+	if resp, _ := srv.ListSNI(context.Background(), &pb.SNIRequest{Filter: "bogus"}); resp.GetError() == "" {
+		t.Errorf("Bad filter must fail: %v\n", resp)
+	}
+}
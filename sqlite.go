@@ -0,0 +1,309 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3" // database/sql driver "sqlite3", requires cgo.
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// sqliteFilename - the records as a normalized SQLite database for arbitrary SQL of analysts.
+const sqliteFilename = "dump.sqlite"
+
+// SQLiteExport - export dump.sqlite after every parse. It is set once at startup.
+var SQLiteExport bool
+
+// sqliteSchema - tables are created empty, indexes after the rows are inserted.
+var sqliteSchema = []string{
+	`CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+	`CREATE TABLE decisions (id INTEGER PRIMARY KEY, date TEXT NOT NULL, number TEXT NOT NULL, org TEXT NOT NULL)`,
+	`CREATE TABLE content (
+		id INTEGER PRIMARY KEY,
+		entry_type INTEGER NOT NULL,
+		urgency_type INTEGER NOT NULL,
+		block_type TEXT NOT NULL,
+		include_time INTEGER NOT NULL,
+		ts INTEGER NOT NULL,
+		registry_update_time INTEGER NOT NULL,
+		decision_id INTEGER NOT NULL REFERENCES decisions (id),
+		hash TEXT NOT NULL
+	)`,
+	`CREATE TABLE ips (content_id INTEGER NOT NULL REFERENCES content (id), ip TEXT NOT NULL, version INTEGER NOT NULL, subnet INTEGER NOT NULL)`,
+	`CREATE TABLE domains (content_id INTEGER NOT NULL REFERENCES content (id), domain TEXT NOT NULL)`,
+	`CREATE TABLE urls (content_id INTEGER NOT NULL REFERENCES content (id), url TEXT NOT NULL)`,
+}
+
+// sqliteIndexes - lookups by selector and joins by record.
+var sqliteIndexes = []string{
+	`CREATE INDEX content_decision ON content (decision_id)`,
+	`CREATE INDEX ips_ip ON ips (ip)`,
+	`CREATE INDEX ips_content ON ips (content_id)`,
+	`CREATE INDEX domains_domain ON domains (domain)`,
+	`CREATE INDEX domains_content ON domains (content_id)`,
+	`CREATE INDEX urls_url ON urls (url)`,
+	`CREATE INDEX urls_content ON urls (content_id)`,
+}
+
+// WriteSQLite - replace dump.sqlite in the dir with the records passing the filter.
+// Domains and URLs are normalized as they are indexed, decisions are deduplicated.
+func WriteSQLite(dir string, dump *Dump, filter *ExportFilter) (int, error) {
+	filename := dir + "/" + sqliteFilename
+	tmpfilename := filename + "-tmp"
+
+	// a database left by a failed export would be appended to.
+	if err := os.Remove(tmpfilename); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("remove: %w", err)
+	}
+
+	n, err := writeSQLiteFile(tmpfilename, dump, filter)
+	if err != nil {
+		os.Remove(tmpfilename)
+
+		return 0, err
+	}
+
+	if err := os.Rename(tmpfilename, filename); err != nil {
+		return 0, fmt.Errorf("rename: %w", err)
+	}
+
+	return n, nil
+}
+
+// writeSQLiteFile - create the database file, returns the number of records.
+func writeSQLiteFile(filename string, dump *Dump, filter *ExportFilter) (int, error) {
+	dump.RLock()
+	prov := dump.provenance()
+	ids := make([]int32, 0, len(dump.ContentIdx))
+	for id, pack := range dump.ContentIdx {
+		if filter.Match(pack) {
+			ids = append(ids, id)
+		}
+	}
+
+	ids, _ = dump.orderResults(ids, OrderID, OrderID, false)
+
+	packs := make([]*PackedContent, 0, len(ids))
+	for _, id := range ids {
+		packs = append(packs, dump.ContentIdx[id])
+	}
+	dump.RUnlock()
+
+	// the file is written once, durability is given by the rename.
+	db, err := sql.Open("sqlite3", "file:"+filename+"?_journal_mode=OFF&_synchronous=OFF")
+	if err != nil {
+		return 0, fmt.Errorf("open: %w", err)
+	}
+
+	defer db.Close()
+
+	for _, stmt := range sqliteSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return 0, fmt.Errorf("schema: %w", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	w, err := newSQLiteWriter(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	meta := [][2]string{
+		{"dump_id", prov.DumpID},
+		{"crc", prov.CRC},
+		{"registry_update_time", strconv.FormatInt(prov.RegistryUpdateTime, 10)},
+		{"generation", strconv.FormatInt(prov.Generation, 10)},
+		{"version", prov.Version},
+		{"filter", filter.String()},
+	}
+
+	for _, kv := range meta {
+		if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)`, kv[0], kv[1]); err != nil {
+			return 0, fmt.Errorf("meta: %w", err)
+		}
+	}
+
+	// records are never changed in place, so they can be read without the lock.
+
+	n := 0
+
+	for _, pack := range packs {
+		record, err := pack.Record()
+		if err != nil {
+			logger.Error.Printf("Can't decode payload: %d: %s\n", pack.ID, err.Error())
+
+			continue
+		}
+
+		if err := w.insert(pack, record, filter); err != nil {
+			return 0, fmt.Errorf("content %d: %w", pack.ID, err)
+		}
+
+		n++
+	}
+
+	for _, stmt := range sqliteIndexes {
+		if _, err := tx.Exec(stmt); err != nil {
+			return 0, fmt.Errorf("index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+
+	if err := db.Close(); err != nil {
+		return 0, fmt.Errorf("close: %w", err)
+	}
+
+	return n, nil
+}
+
+// sqliteWriter - prepared inserts of one export.
+type sqliteWriter struct {
+	decisions map[Decision]int64
+	decision  *sql.Stmt
+	content   *sql.Stmt
+	ip        *sql.Stmt
+	domain    *sql.Stmt
+	url       *sql.Stmt
+}
+
+func newSQLiteWriter(tx *sql.Tx) (*sqliteWriter, error) {
+	w := &sqliteWriter{decisions: make(map[Decision]int64)}
+
+	for _, s := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&w.decision, `INSERT INTO decisions (id, date, number, org) VALUES (?, ?, ?, ?)`},
+		{&w.content, `INSERT INTO content (id, entry_type, urgency_type, block_type, include_time, ts, registry_update_time, decision_id, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`},
+		{&w.ip, `INSERT INTO ips (content_id, ip, version, subnet) VALUES (?, ?, ?, ?)`},
+		{&w.domain, `INSERT INTO domains (content_id, domain) VALUES (?, ?)`},
+		{&w.url, `INSERT INTO urls (content_id, url) VALUES (?, ?)`},
+	} {
+		stmt, err := tx.Prepare(s.query)
+		if err != nil {
+			return nil, fmt.Errorf("prepare: %w", err)
+		}
+
+		*s.stmt = stmt
+	}
+
+	return w, nil
+}
+
+// insert - the record with its decision, IPs and subnets passing the address kinds of the filter, domains and URLs.
+func (w *sqliteWriter) insert(pack *PackedContent, record *Content, filter *ExportFilter) error {
+	decisionID, ok := w.decisions[record.Decision]
+	if !ok {
+		decisionID = int64(len(w.decisions) + 1)
+		w.decisions[record.Decision] = decisionID
+
+		if _, err := w.decision.Exec(decisionID, record.Decision.Date, record.Decision.Number, record.Decision.Org); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.content.Exec(record.ID, record.EntryType, record.UrgencyType, record.BlockType, record.IncludeTime,
+		record.Ts, pack.RegistryUpdateTime, decisionID, record.Hash); err != nil {
+		return err
+	}
+
+	type ipRow struct {
+		ip      string
+		version int
+		subnet  bool
+	}
+
+	var ips []ipRow
+
+	for _, ip4 := range record.IP4 {
+		if filter.Addr(ip4.IP4) {
+			ips = append(ips, ipRow{ip4.IP4.String(), 4, false})
+		}
+	}
+
+	for _, subnet4 := range record.Subnet4 {
+		if filter.Address(subnet4.Subnet4) {
+			ips = append(ips, ipRow{subnet4.Subnet4.String(), 4, true})
+		}
+	}
+
+	for _, ip6 := range record.IP6 {
+		if filter.Addr(ip6.IP6) {
+			ips = append(ips, ipRow{ip6.IP6.String(), 6, false})
+		}
+	}
+
+	for _, subnet6 := range record.Subnet6 {
+		if filter.Address(subnet6.Subnet6) {
+			ips = append(ips, ipRow{subnet6.Subnet6.String(), 6, true})
+		}
+	}
+
+	for _, r := range ips {
+		if _, err := w.ip.Exec(record.ID, r.ip, r.version, r.subnet); err != nil {
+			return err
+		}
+	}
+
+	for _, domain := range record.Domain {
+		if _, err := w.domain.Exec(record.ID, NormalizeDomain(domain.Domain)); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range record.URL {
+		if _, err := w.url.Exec(record.ID, NormalizeURL(u.URL)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportSQLite - write dump.sqlite of the new generation, if enabled, the records added
+// and true if it is written. Only records passing ExportFilterConfig are added.
+func ExportSQLite(dir string) (int, bool) {
+	if !SQLiteExport {
+		return 0, false
+	}
+
+	n, err := WriteSQLite(dir, CurrentDump, ExportFilterConfig)
+	if err != nil {
+		logger.Error.Printf("Can't save %s: %s\n", sqliteFilename, err.Error())
+
+		return 0, false
+	}
+
+	logger.Info.Printf("SQLite database: %d records\n", n)
+
+	return n, true
+}
+
+// sqliteHandler - /dump.sqlite, the last exported database.
+func sqliteHandler(dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !SQLiteExport {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.sqlite3")
+		http.ServeFile(w, r, dir+"/"+sqliteFilename)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+// TestWriteSQLite tests tables of the exported database and the export filter.
+func TestWriteSQLite(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	n, err := WriteSQLite(dir, CurrentDump, nil)
+	if err != nil || n != 5 {
+		t.Fatalf("WriteSQLite: %d, %v", n, err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+dir+"/"+sqliteFilename+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	for _, c := range []struct {
+		query    string
+		expected string
+	}{
+		{`SELECT count(*) FROM content`, "5"},
+		{`SELECT count(*) FROM decisions`, "5"},
+		{`SELECT value FROM meta WHERE key = 'registry_update_time'`, "1293832861"},
+		{`SELECT group_concat(content_id, ' ') FROM (SELECT content_id FROM domains WHERE domain = 'www.e02.tld' ORDER BY content_id)`, "222 555"},
+		{`SELECT group_concat(content_id, ' ') FROM (SELECT content_id FROM ips WHERE ip = '192.168.0.100' ORDER BY content_id)`, "111 222 333"},
+		{`SELECT c.id FROM ips i JOIN content c ON c.id = i.content_id WHERE i.subnet AND i.version = 4`, "444"},
+		{`SELECT d.org FROM content c JOIN decisions d ON d.id = c.decision_id WHERE c.id = 333`, "THREE"},
+		{`SELECT count(*) FROM urls WHERE content_id = 111`, "3"},
+	} {
+		var got string
+		if err := db.QueryRow(c.query).Scan(&got); err != nil || got != c.expected {
+			t.Errorf("%s: %q, %v, expected %q", c.query, got, err, c.expected)
+		}
+	}
+
+	filter, err := ParseExportFilter("org=ONE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := WriteSQLite(dir, CurrentDump, filter); err != nil || n != 1 {
+		t.Errorf("Filtered: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"compress/flate"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// parseStage - changes of the parse not applied yet. The live dump is only read
+// while the dump is parsed, a failed parse leaves it as it was.
+type parseStage struct {
+	journal  *IDJournal              // all ids of the dump.
+	ordinals *IDOrdinals             // 1-based positions of all ids in the dump, the first one of duplicates.
+	records  map[int32]*stagedRecord // new and changed records.
+	order    []int32                 // staged ids in the dump order.
+
+	compressor *flate.Writer // deflate writer of raw fragments, see compressRaw.
+}
+
+// stagedRecord - new or changed record, everything expensive is done outside the dump lock.
+type stagedRecord struct {
+	content *Content
+	payload []byte // marshaled content.
+	raw     []byte // compressed <content>...</content>, only if KeepRaw.
+	changed int64  // registry update time of the last change, 0 - of the committed dump, see RestoreSnapshot.
+	same    bool   // changed bytes, same record, see CompareSemantic.
+}
+
+func newParseStage() *parseStage {
+	return &parseStage{
+		journal:  acquireJournal(),
+		ordinals: acquireOrdinals(),
+		records:  make(map[int32]*stagedRecord),
+	}
+}
+
+// see - the id is in the dump, the next position if it is seen first.
+func (s *parseStage) see(id int32) {
+	if !s.journal.Add(id) {
+		return
+	}
+
+	s.ordinals.Set(id, int32(s.journal.Len()))
+}
+
+// put - stage the record, raw is the fragment as it is in the dump, nil if it is kept as is.
+func (s *parseStage) put(id int32, record *Content, raw []byte) {
+	staged, ok := s.records[id]
+	if !ok {
+		staged = &stagedRecord{}
+		s.records[id] = staged
+		s.order = append(s.order, id)
+	}
+
+	staged.content = record
+	staged.payload = record.Marshal()
+	staged.same = false
+
+	if raw != nil && ParseConfig.KeepRaw {
+		staged.raw = s.compressRaw(raw)
+	}
+}
+
+// changeTime - registry update time of the record change committed in the dump of utime.
+func (staged *stagedRecord) changeTime(utime int64) int64 {
+	if staged.changed > 0 {
+		return staged.changed
+	}
+
+	return utime
+}
+
+// restore - stage the record of a checkpoint as it was, see parseCheckpoint.
+func (s *parseStage) restore(id int32, staged *stagedRecord) {
+	if _, ok := s.records[id]; !ok {
+		s.order = append(s.order, id)
+	}
+
+	s.records[id] = staged
+}
+
+// add - decide what to do with the <content>...</content> of the dump, raw is the same fragment
+// before the charset conversion, see parser.RawElementCapturer.Input.
+func (s *parseStage) add(dump *Dump, decoder *parser.Decoder, id int32, recordHash uint64, contBuf, raw []byte, stats *ParseStatistics) {
+	dump.RLock()
+
+	prevCont, exists := dump.ContentIdx[id]
+
+	var prevHash uint64
+	if exists {
+		prevHash = prevCont.RecordHash
+	}
+
+	dump.RUnlock()
+
+	staged, isStaged := s.records[id]
+	if isStaged {
+		prevHash = staged.content.RecordHash
+	}
+
+	duplicate := s.journal.Has(id)
+	if duplicate {
+		logger.Warning.Printf("Duplicate content id: %d\n", id)
+		stats.DuplicateCount++
+	}
+
+	s.see(id) // add to journal.
+
+	known := exists || isStaged
+
+	if decoder.Oversized(id, contBuf, &stats.Statistics) {
+		return
+	}
+
+	switch {
+	case duplicate && ParseConfig.DuplicatePolicy == DuplicateKeepFirst:
+		// the first one is already staged.
+	case duplicate && known && ParseConfig.DuplicatePolicy == DuplicateMerge:
+		newCont, err := decoder.Decode(recordHash, contBuf)
+		if err != nil {
+			logger.Error.Printf("Decode Error: %s\n", err)
+
+			break
+		}
+
+		var merged *Content
+
+		if isStaged {
+			merged = staged.content.Union(newCont)
+		} else {
+			dump.RLock()
+			merged, err = prevCont.unionContent(newCont)
+			if err == nil {
+				merged.Anomalies = append(append([]Anomaly(nil), dump.anomalies[id]...), merged.Anomalies...)
+			}
+			dump.RUnlock()
+
+			if err != nil {
+				logger.Error.Printf("Merge Error: %s\n", err)
+
+				break
+			}
+		}
+
+		if !decoder.Fit(merged, &stats.Statistics) {
+			break
+		}
+
+		s.put(id, merged, nil)
+		stats.UpdateCount++
+	case !known:
+		newCont, err := decoder.Decode(recordHash, contBuf)
+		if err != nil {
+			logger.Error.Printf("Decode Error: %s\n", err)
+
+			break
+		}
+
+		if !decoder.Fit(newCont, &stats.Statistics) {
+			break
+		}
+
+		s.put(id, newCont, raw)
+		stats.AddCount++
+	case prevHash != recordHash:
+		newCont, err := decoder.Decode(recordHash, contBuf)
+		if err != nil {
+			logger.Error.Printf("Decode Error: %s\n", err)
+
+			break
+		}
+
+		if !decoder.Fit(newCont, &stats.Statistics) {
+			break
+		}
+
+		s.put(id, newCont, raw)
+
+		// the payload of the live record is never changed, it is read without the lock.
+		if exists && !isStaged && ParseConfig.Compare == CompareSemantic {
+			same, err := prevCont.semanticEqual(newCont)
+			if err != nil {
+				logger.Error.Printf("Compare Error: %d: %s\n", id, err)
+			}
+
+			if same {
+				s.records[id].same = true
+				stats.SameCount++
+
+				break
+			}
+		}
+
+		stats.UpdateCount++
+	}
+}
+
+// Commit - apply the stage, remove records missed in the dump and publish
+// the new generation in one critical section. It returns added ids and removed records.
+// Changed records are replaced, not changed in place, see MergePackedContent.
+func (dump *Dump) Commit(s *parseStage, stats *ParseStatistics, utime int64) ([]int32, []*PackedContent) {
+	dump.Lock()
+	defer dump.Unlock()
+
+	var added, updated []int32
+
+	var transitions []BlockTypeTransition
+
+	// selector changes of the generation for replicas, see Delta.
+	var touched map[Selector]bool
+	if WatchKeep > 0 && !stats.Initial {
+		touched = dump.touchedSelectors(s)
+	}
+
+	for _, id := range s.order {
+		staged := s.records[id]
+
+		dump.setAnomalies(id, staged.content.Anomalies)
+
+		if prev, ok := dump.ContentIdx[id]; ok {
+			if staged.same {
+				dump.RehashPackedContent(staged.content, staged.payload, staged.raw, prev)
+
+				continue
+			}
+
+			next := dump.MergePackedContent(staged.content, staged.payload, prev, staged.changeTime(utime))
+
+			if staged.raw != nil {
+				next.Raw = staged.raw
+			}
+
+			if next.BlockType != prev.BlockType {
+				transitions = append(transitions, BlockTypeTransition{ID: id, From: prev.BlockType, To: next.BlockType})
+			}
+
+			updated = append(updated, id)
+
+			continue
+		}
+
+		fresh := dump.NewPackedContent(staged.content, staged.payload, staged.changeTime(utime))
+		fresh.Raw = staged.raw
+
+		added = append(added, fresh.ID)
+	}
+
+	// unchanged records keep the update time of their last change.
+
+	removed := dump.purge(s.journal, stats) // remove deleted records from index.
+	releaseOrdinals(dump.ordinals)
+	dump.ordinals = s.ordinals
+	dump.removed += len(removed)
+
+	for _, pack := range removed {
+		delete(dump.anomalies, pack.ID)
+	}
+
+	stats.Anomalies, stats.Bogons = dump.publishAnomalies()
+	stats.Transitions = transitions
+
+	dump.calcMaxEntityLen(stats)       // calc max entity len.
+	dump.decisionDateIdx.Reindex()     // order time index.
+	dump.includeTimeIdx.Reindex()      // order time index.
+	dump.tsIdx.Reindex()               // order time index.
+	dump.utime = utime                 // set global update time.
+	dump.dumpID, dump.dumpCRC = "", "" // the caller sets them, see SetSource.
+	dump.generation++                  // publish new generation.
+
+	// the initial load is not a change to catch up from.
+	if stats.Initial {
+		dump.changes.reset()
+	} else {
+		changes := dump.newGenerationChanges(dump.generation, utime, added, updated, removed)
+		changes.Transitions = transitions
+		changes.SelectorsAdded, changes.SelectorsRemoved = dump.selectorChanges(touched)
+
+		dump.changes.push(changes, WatchKeep)
+	}
+
+	close(dump.changed) // wake up waiters.
+	dump.changed = make(chan struct{})
+
+	// the journal is left for the next parse.
+	stats.JournalBytes, stats.JournalReused = s.journal.Bytes(), s.journal.reused
+	releaseJournal(s.journal)
+	s.journal = nil
+
+	return added, removed
+}
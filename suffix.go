@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// suffixCache - indexed domains of one generation sorted by reversed labels,
+// so the domains under a suffix are a contiguous range.
+type suffixCache struct {
+	sync.Mutex
+	generation int64
+	keys       []string // reversed: "ua.example.www".
+	domains    []string // as indexed: "www.example.ua".
+}
+
+// reverseDomain - labels in reversed order.
+func reverseDomain(domain string) string {
+	labels := strings.Split(domain, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+
+	return strings.Join(labels, ".")
+}
+
+// NormalizeSuffix - the suffix as indexed domains, leading dot is optional.
+func NormalizeSuffix(suffix string) string {
+	return NormalizeDomain(strings.TrimPrefix(strings.TrimSpace(suffix), "."))
+}
+
+// DomainsBySuffix - indexed domains equal to the normalized suffix or under it,
+// call it under read lock. The result is shared, don't change it.
+func (dump *Dump) DomainsBySuffix(suffix string) []string {
+	keys, domains := dump.suffixIndex()
+
+	key := reverseDomain(suffix)
+
+	// "." + 1 is "/", the subdomains are between them.
+	lo := sort.SearchStrings(keys, key+".")
+	hi := sort.SearchStrings(keys, key+"/")
+
+	if exact := sort.SearchStrings(keys, key); exact < len(keys) && keys[exact] == key {
+		// keys like "ua-x" may sort between the exact one and its subdomains.
+		if exact == lo-1 {
+			return domains[exact:hi]
+		}
+
+		result := make([]string, 0, hi-lo+1)
+
+		return append(append(result, domains[exact]), domains[lo:hi]...)
+	}
+
+	return domains[lo:hi]
+}
+
+// suffixIndex - sorted reversed domains of the generation, built on first use.
+func (dump *Dump) suffixIndex() ([]string, []string) {
+	dump.suffix.Lock()
+	defer dump.suffix.Unlock()
+
+	if dump.suffix.keys != nil && dump.suffix.generation == dump.generation {
+		return dump.suffix.keys, dump.suffix.domains
+	}
+
+	keys := make([]string, 0, len(dump.domainIdx))
+	for domain := range dump.domainIdx {
+		keys = append(keys, reverseDomain(domain))
+	}
+
+	sort.Strings(keys)
+
+	domains := make([]string, len(keys))
+	for i, key := range keys {
+		domains[i] = reverseDomain(key)
+	}
+
+	dump.suffix.generation, dump.suffix.keys, dump.suffix.domains = dump.generation, keys, domains
+
+	return keys, domains
+}
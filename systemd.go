@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// systemd notify states, see sd_notify(3).
+const (
+	SdReady    = "READY=1"
+	SdStopping = "STOPPING=1"
+	SdWatchdog = "WATCHDOG=1"
+)
+
+// sdReadyOnce - READY is sent once, when the first index is loaded.
+var sdReadyOnce sync.Once
+
+// SdNotify - send the state to the service manager, false if it is not asked for, i.e. no $NOTIFY_SOCKET.
+func SdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// abstract namespace socket.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("dial: %w", err)
+	}
+
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("write: %w", err)
+	}
+
+	return true, nil
+}
+
+// SdWatchdogInterval - how often to pet the watchdog, half of WatchdogSec, 0 if it is disabled
+// or meant for another process.
+func SdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// sdNotifyReady - READY once the first index is loaded, later calls do nothing.
+func sdNotifyReady() {
+	CurrentDump.RLock()
+	utime := CurrentDump.utime
+	CurrentDump.RUnlock()
+
+	if utime == 0 {
+		return
+	}
+
+	sdReadyOnce.Do(func() {
+		if ok, err := SdNotify(SdReady); err != nil {
+			logger.Error.Printf("Can't notify systemd: %s\n", err.Error())
+		} else if ok {
+			logger.Info.Printf("Ready, systemd is notified\n")
+		}
+	})
+}
+
+// sdPetWatchdog - WATCHDOG if the dump lock can be taken, a parser deadlocked under it misses the pets
+// and the service is restarted.
+func sdPetWatchdog() {
+	// the lock is only probed.
+	CurrentDump.RLock()
+	CurrentDump.RUnlock()
+
+	if _, err := SdNotify(SdWatchdog); err != nil {
+		logger.Error.Printf("Can't pet systemd watchdog: %s\n", err.Error())
+	}
+}
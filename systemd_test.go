@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestSdNotify tests notify messages and the watchdog interval of the service manager environment.
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	if ok, err := SdNotify(SdReady); ok || err != nil {
+		t.Errorf("No socket: %t %v\n", ok, err)
+	}
+
+	dir, err := os.MkdirTemp("", "sd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: dir + "/notify", Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", dir+"/notify")
+
+	if ok, err := SdNotify(SdWatchdog); !ok || err != nil {
+		t.Fatalf("Notify: %t %v\n", ok, err)
+	}
+
+	buf := make([]byte, 64)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != SdWatchdog {
+		t.Errorf("Received: %q %v\n", buf[:n], err)
+	}
+
+	for env, want := range map[[2]string]time.Duration{
+		{"", ""}:                                0,
+		{"10000000", ""}:                        5 * time.Second,
+		{"10000000", strconv.Itoa(os.Getpid())}: 5 * time.Second,
+		{"10000000", strconv.Itoa(os.Getpid() + 1)}: 0,
+	} {
+		t.Setenv("WATCHDOG_USEC", env[0])
+		t.Setenv("WATCHDOG_PID", env[1])
+
+		if got := SdWatchdogInterval(); got != want {
+			t.Errorf("%v: %s, want %s\n", env, got, want)
+		}
+	}
+}
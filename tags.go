@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+const (
+	tagsFilename    = "tags.json"
+	tagsLogFilename = "tags.log" // changes after tags.json is written, a JSON line of all tags of a record each.
+)
+
+// tagsLogCompact - tags.json is rewritten once the log has that many lines more than tagged records.
+const tagsLogCompact = 1024
+
+// MatchTag - records listed by an operator tag.
+const MatchTag = "tag"
+
+// Tag limits: tag names are short labels like "appeal" or "false-positive", notes are free text, not documents.
+const (
+	tagMaxLen     = 64
+	tagNoteMaxLen = 1024
+)
+
+// RecordTag - operator label of a record with a note, e.g. an appeal or a complaint.
+type RecordTag struct {
+	Tag  string `json:"tag"`
+	Note string `json:"note,omitempty"`
+	Time int64  `json:"time"` // unix time it is set.
+}
+
+// tagsLogEntry - line of tags.log, no tags means all tags of the record are removed.
+type tagsLogEntry struct {
+	ID   int32       `json:"id"`
+	Tags []RecordTag `json:"tags,omitempty"`
+}
+
+// TagStore - operator tags of record ids. They are kept in tags.json of the dump dir apart from
+// the dump, so they survive refreshes, and stay on ids removed from the registry.
+// A change is appended to tags.log, tags.json is only rewritten when the log gets long.
+type TagStore struct {
+	sync.RWMutex
+	filename string // empty keeps the tags in memory only.
+	logname  string
+	logged   int   // lines of tags.log.
+	version  int64 // bumped on every change, part of the search cache epoch.
+	tags     map[int32][]RecordTag
+}
+
+// RecordTags - operator tags, loaded from the dump dir at startup.
+var RecordTags = &TagStore{tags: make(map[int32][]RecordTag)}
+
+// LoadTags - read tags.json of the dir and replay tags.log, missing files mean no tags.
+// The log is compacted into tags.json.
+func (s *TagStore) LoadTags(dir string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.filename, s.logname = dir+"/"+tagsFilename, dir+"/"+tagsLogFilename
+	s.tags = make(map[int32][]RecordTag)
+	s.logged = 0
+	s.version++
+
+	data, err := os.ReadFile(s.filename)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(data, &s.tags); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+	}
+
+	data, err = os.ReadFile(s.logname)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("read log: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	for scanner.Scan() {
+		var entry tagsLogEntry
+
+		// the last line may be cut by a crash, the change was not acknowledged.
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logger.Warning.Printf("Bad tags log line, the rest is skipped: %s\n", err.Error())
+
+			break
+		}
+
+		if len(entry.Tags) == 0 {
+			delete(s.tags, entry.ID)
+		} else {
+			s.tags[entry.ID] = entry.Tags
+		}
+
+		s.logged++
+	}
+
+	return s.compact()
+}
+
+// NormalizeTag - lower case tag name of letters, digits, '-', '_', '.' and ':', ok is false for a bad one.
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > tagMaxLen {
+		return "", false
+	}
+
+	for _, r := range tag {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.:", r)) {
+			return "", false
+		}
+	}
+
+	return tag, true
+}
+
+// Set - attach the tag to the record or replace its note, tags of a record are ordered by name.
+func (s *TagStore) Set(id int32, tag, note string, now time.Time) ([]RecordTag, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tags := make([]RecordTag, 0, len(s.tags[id])+1)
+
+	for _, t := range s.tags[id] {
+		if t.Tag != tag {
+			tags = append(tags, t)
+		}
+	}
+
+	tags = append(tags, RecordTag{Tag: tag, Note: note, Time: now.Unix()})
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+
+	return tags, s.update(id, tags)
+}
+
+// Remove - detach the tag from the record, an empty tag removes all of them.
+func (s *TagStore) Remove(id int32, tag string) ([]RecordTag, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var tags []RecordTag
+
+	for _, t := range s.tags[id] {
+		if tag != "" && t.Tag != tag {
+			tags = append(tags, t)
+		}
+	}
+
+	if len(tags) == len(s.tags[id]) {
+		return tags, nil
+	}
+
+	return tags, s.update(id, tags)
+}
+
+// update - replace tags of the record and log the change, call it under lock.
+// Slices are copy-on-write, readers may hold the former one.
+func (s *TagStore) update(id int32, tags []RecordTag) error {
+	if err := s.appendLog(tagsLogEntry{ID: id, Tags: tags}); err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		delete(s.tags, id)
+	} else {
+		s.tags[id] = tags
+	}
+
+	s.version++
+
+	// the change is logged already, a failed compaction is retried with the next one.
+	if s.logged > len(s.tags)+tagsLogCompact {
+		if err := s.compact(); err != nil {
+			logger.Warning.Printf("Can't compact tags log: %s\n", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// appendLog - append the change to tags.log, call it under lock.
+func (s *TagStore) appendLog(entry tagsLogEntry) error {
+	if s.filename == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	f, err := os.OpenFile(s.logname, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log: %w", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+
+		return fmt.Errorf("write log: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close log: %w", err)
+	}
+
+	s.logged++
+
+	return nil
+}
+
+// compact - write all tags to tags.json and drop the log, call it under lock.
+// Replaying the log again after a crash in between gives the same tags.
+func (s *TagStore) compact() error {
+	if s.filename == "" || s.logged == 0 {
+		return nil
+	}
+
+	if err := s.save(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.logname); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove log: %w", err)
+	}
+
+	s.logged = 0
+
+	return nil
+}
+
+// save - write tags.json atomically, call it under lock.
+func (s *TagStore) save() error {
+	if s.filename == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.tags)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := os.WriteFile(s.filename+"-tmp", data, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := os.Rename(s.filename+"-tmp", s.filename); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// Tags - tags of the record, shared and must not be modified.
+func (s *TagStore) Tags(id int32) []RecordTag {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.tags[id]
+}
+
+// IDs - ids of the records with the tag, ordered.
+func (s *TagStore) IDs(tag string) []int32 {
+	s.RLock()
+	defer s.RUnlock()
+
+	var ids []int32
+
+	for id, tags := range s.tags {
+		for _, t := range tags {
+			if t.Tag == tag {
+				ids = append(ids, id)
+
+				break
+			}
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// Version - change counter of the tags.
+func (s *TagStore) Version() int64 {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.version
+}
+
+// newPbTags - tags of the record for the response, nil if none.
+func newPbTags(tags []RecordTag) []*pb.RecordTag {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	res := make([]*pb.RecordTag, 0, len(tags))
+	for _, t := range tags {
+		res = append(res, &pb.RecordTag{Tag: t.Tag, Note: t.Note, Time: t.Time})
+	}
+
+	return res
+}
+
+// logTagChange - tags are changed by operators, keep a trace of who asked.
+func logTagChange(requestID string, id int32, tag string, remove bool) {
+	action := "set"
+	if remove {
+		action = "removed"
+	}
+
+	logger.Info.Printf("[%s] Tag %s %s: %d\n", requestID, tag, action, id)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestTags tests operator tags are set, listed, kept across reloads and shown in search results.
+func TestTags(t *testing.T) {
+	defer func(dump *Dump, tags *TagStore, cache *QueryCache, token string) {
+		CurrentDump, RecordTags, SearchCache, AdminToken = dump, tags, cache, token
+	}(CurrentDump, RecordTags, SearchCache, AdminToken)
+
+	CurrentDump, RecordTags, SearchCache, AdminToken = NewDump(), &TagStore{}, NewQueryCache(10), "secret"
+
+	dir := t.TempDir()
+	if err := RecordTags.LoadTags(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+
+	// cached before tagging, the tag has to show up anyway.
+	s.SearchID(ctx, &pb.IDRequest{Query: 111})
+
+	for _, in := range []*pb.TagRequest{
+		{Id: 111, Tag: " Appeal ", Note: "case 1"},
+		{Id: 111, Tag: "false-positive"},
+		{Id: 222, Tag: "appeal"},
+		{Id: 111, Tag: "appeal", Note: "case 2"},
+	} {
+		if resp, _ := s.TagRecord(ctx, in); resp.GetError() != "" {
+			t.Fatalf("Tag: %v: %s\n", in, resp.GetError())
+		}
+	}
+
+	for _, tc := range []struct {
+		ctx context.Context
+		in  *pb.TagRequest
+		err string
+	}{
+		{ctx, &pb.TagRequest{Id: 111, Tag: "bad tag"}, SrvBadTag},
+		{ctx, &pb.TagRequest{Id: 999, Tag: "appeal"}, SrvUnknownID},
+		{ctx, &pb.TagRequest{Id: 111, Tag: "appeal", Note: strings.Repeat("x", tagNoteMaxLen+1)}, SrvLongNote},
+		{context.Background(), &pb.TagRequest{Id: 111, Tag: "appeal"}, SrvAdminOnly},
+	} {
+		if resp, _ := s.TagRecord(tc.ctx, tc.in); resp.GetError() != tc.err {
+			t.Errorf("%v: %v\n", tc.in, resp)
+		}
+	}
+
+	// changes are logged, tags.json is not rewritten.
+	if _, err := os.Stat(dir + "/" + tagsFilename); !os.IsNotExist(err) {
+		t.Errorf("Tags are saved on a change: %v\n", err)
+	}
+
+	resp, _ := s.SearchID(ctx, &pb.IDRequest{Query: 111})
+	if tags := resp.GetResults()[0].GetTags(); len(tags) != 2 || tags[0].GetTag() != "appeal" || tags[0].GetNote() != "case 2" || tags[1].GetTag() != "false-positive" {
+		t.Errorf("Search: %v\n", tags)
+	}
+
+	// tags survive a restart, the log is compacted.
+	RecordTags = &TagStore{}
+	if err := RecordTags.LoadTags(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dir + "/" + tagsLogFilename); !os.IsNotExist(err) {
+		t.Errorf("Tags log is not compacted: %v\n", err)
+	}
+
+	list, _ := s.ListTagged(ctx, &pb.TaggedRequest{Tag: "APPEAL", Descending: true})
+	if list.GetTotal() != 2 || list.GetResults()[0].GetId() != 222 || list.GetResults()[1].GetMatchedBy().GetKind() != MatchTag {
+		t.Errorf("List: %v\n", list)
+	}
+
+	if resp, _ := s.TagRecord(ctx, &pb.TagRequest{Id: 111, Remove: true}); resp.GetError() != "" || len(resp.GetTags()) != 0 {
+		t.Errorf("Remove: %v\n", resp)
+	}
+
+	if ids := RecordTags.IDs("appeal"); len(ids) != 1 || ids[0] != 222 {
+		t.Errorf("IDs: %v\n", ids)
+	}
+
+	// a removal is replayed, a cut last line is skipped.
+	f, err := os.OpenFile(dir+"/"+tagsLogFilename, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.WriteString(`{"id":222,"tags":[{"tag":"cut"`)
+	f.Close()
+
+	RecordTags = &TagStore{}
+	if err := RecordTags.LoadTags(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if tags := RecordTags.Tags(111); len(tags) != 0 {
+		t.Errorf("Removed tags: %v\n", tags)
+	}
+
+	if tags := RecordTags.Tags(222); len(tags) != 1 || tags[0].Tag != "appeal" {
+		t.Errorf("Tags after a cut log: %v\n", tags)
+	}
+}
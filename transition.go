@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// transitionAny - any block type in a transition filter.
+const transitionAny = "*"
+
+// BlockTypeTransition - updated record whose computed block type changed, e.g. url to domain
+// or url to https, its enforcement has to be reconfigured.
+type BlockTypeTransition struct {
+	ID   int32
+	From int32
+	To   int32
+}
+
+// String - "id from>to" with block type names.
+func (t BlockTypeTransition) String() string {
+	return fmt.Sprintf("%d %s>%s", t.ID, blockTypeName(t.From), blockTypeName(t.To))
+}
+
+// blockTypeName - name of the block type as in export filters.
+func blockTypeName(blockType int32) string {
+	for name, bt := range blockTypeNames {
+		if bt == blockType {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// transitionPair - from and to block types, -1 is any.
+type transitionPair struct {
+	from, to int32
+}
+
+// TransitionFilter - "from>to" pairs, a transition matches any of them. nil matches all.
+type TransitionFilter []transitionPair
+
+// ParseTransitionFilter - comma separated "from>to" pairs of block type names (url, https, domain,
+// domain-mask, ip) or "*", e.g. "url>domain,*>ip". Empty spec gives nil.
+func ParseTransitionFilter(spec string) (TransitionFilter, error) {
+	var filter TransitionFilter
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		from, to, found := strings.Cut(item, ">")
+		if !found {
+			return nil, fmt.Errorf("no \">\": %q", item)
+		}
+
+		var pair transitionPair
+
+		for _, side := range []struct {
+			name string
+			bt   *int32
+		}{
+			{strings.TrimSpace(from), &pair.from},
+			{strings.TrimSpace(to), &pair.to},
+		} {
+			if side.name == transitionAny {
+				*side.bt = -1
+
+				continue
+			}
+
+			bt, ok := blockTypeNames[side.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown block type: %q", side.name)
+			}
+
+			*side.bt = bt
+		}
+
+		filter = append(filter, pair)
+	}
+
+	return filter, nil
+}
+
+// Match - does the transition match the filter?
+func (f TransitionFilter) Match(t BlockTypeTransition) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, p := range f {
+		if (p.from < 0 || p.from == t.From) && (p.to < 0 || p.to == t.To) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter - the matching transitions.
+func (f TransitionFilter) Filter(list []BlockTypeTransition) []BlockTypeTransition {
+	if f == nil {
+		return list
+	}
+
+	var matched []BlockTypeTransition
+
+	for _, t := range list {
+		if f.Match(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	return matched
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestBlockTypeTransitions tests transitions of updated records in the changes and the changelog filter.
+func TestBlockTypeTransitions(t *testing.T) {
+	defer func(dump *Dump, keep int, log *ChangelogRing) {
+		CurrentDump, WatchKeep, Changelog = dump, keep, log
+	}(CurrentDump, WatchKeep, Changelog)
+
+	CurrentDump, WatchKeep, Changelog = NewDump(), 2, &ChangelogRing{}
+
+	// 111 url to domain, 222 domain to ip.
+	xml := strings.Replace(xml01, `<content id="111" includeTime="2001-01-01T01:01:01" entryType="1" blockType="default"`,
+		`<content id="111" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain"`, 1)
+	xml = strings.Replace(xml, `<content id="222" includeTime="2001-01-01T02:02:02" entryType="1" blockType="domain"`,
+		`<content id="222" includeTime="2001-01-01T02:02:02" entryType="1" blockType="ip"`, 1)
+
+	for _, dump := range []string{xml01, xml} {
+		if err := Parse(strings.NewReader(dump)); err != nil {
+			t.Fatal(err)
+		}
+
+		Changelog.Append(newChangelogEntry(Stats))
+	}
+
+	expected := []BlockTypeTransition{{ID: 111, From: BlockTypeHTTPS, To: BlockTypeDomain}, {ID: 222, From: BlockTypeDomain, To: BlockTypeIP}}
+
+	if !reflect.DeepEqual(Stats.Transitions, expected) {
+		t.Errorf("Transitions: %v, expected %v", Stats.Transitions, expected)
+	}
+
+	if changes, ok := CurrentDump.ChangesSince(1); !ok || len(changes) != 1 || !reflect.DeepEqual(changes[0].Transitions, expected) {
+		t.Errorf("Changes: %+v %t", changes, ok)
+	}
+
+	s := &server{}
+
+	for _, c := range []struct {
+		filter  string
+		entries int
+		ids     []int32
+	}{
+		{"", 2, []int32{111, 222}},
+		{"*>*", 1, []int32{111, 222}},
+		{"https>domain", 1, []int32{111}},
+		{"url>*, *>ip", 1, []int32{222}},
+		{"ip>*", 0, nil},
+	} {
+		resp, _ := s.GetChangelog(context.Background(), &pb.ChangelogRequest{Transitions: c.filter})
+
+		var ids []int32
+
+		for _, entry := range resp.GetEntries() {
+			for _, tr := range entry.GetTransitions() {
+				ids = append(ids, tr.GetId())
+			}
+		}
+
+		if resp.GetError() != "" || len(resp.GetEntries()) != c.entries || !reflect.DeepEqual(ids, c.ids) {
+			t.Errorf("%q: %v", c.filter, resp)
+		}
+	}
+
+	for _, bad := range []string{"url", "url>web", "x>ip"} {
+		if resp, _ := s.GetChangelog(context.Background(), &pb.ChangelogRequest{Transitions: bad}); !strings.HasPrefix(resp.GetError(), SrvBadTransition) {
+			t.Errorf("%q: %v", bad, resp)
+		}
+	}
+}
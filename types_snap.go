@@ -1,5 +1,7 @@
 package main
 
+import "github.com/usher2/u2ckdump/parser"
+
 // Block types: url, https, domain, mask, ip.
 const (
 	BlockTypeURL = iota
@@ -9,81 +11,40 @@ const (
 	BlockTypeIP
 )
 
-// PackedContent - packed version of Content.
+// PackedContent - packed version of Content. Records in the index are never changed, changes replace them.
 type PackedContent struct {
 	ID                 int32
 	BlockType          int32 // for protobuf
-	RegistryUpdateTime int64
+	RegistryUpdateTime int64 // registry update time of the dump the record was added or last changed in.
 	Decision           uint64
+	DecisionDate       int64  // Unix time of the decision date midnight.
+	IncludeTime        int64  // Unix time the record is included (blocked since).
+	Ts                 int64  // Unix time the record is last changed in the registry, its ts, 0 if absent.
+	Org                string // Canonical decision organization.
+	EntryType          int32  // Registry entry type (legal ground).
+	UrgencyType        int32  // Registry urgency type, not 0 is urgent, see Urgent.
 	URL                []URL
 	IP4                []IP4
 	IP6                []IP6
 	Subnet4            []Subnet4
 	Subnet6            []Subnet6
 	Domain             []Domain
-	Payload            []byte // It is a protobuf message.
+	URLHTTP            int32  // URLs with the http scheme.
+	URLHTTPS           int32  // URLs with the https scheme.
+	Payload            []byte // It is a protobuf message, use PayloadBytes() to read.
+	Compressed         bool   // Payload is snappy compressed.
+	Raw                []byte // Deflated <content>...</content>, if ParseConfig.KeepRaw.
 	RecordHash         uint64
 }
 
-// Content - store for <content> with hash.
-type Content struct {
-	ID          int32     `json:"id"`
-	EntryType   int32     `json:"et"`
-	UrgencyType int32     `json:"ut,omitempty"`
-	Decision    Decision  `json:"d"`
-	IncludeTime int64     `json:"it"`
-	Ts          int64     `json:"ts,omitempty"`
-	BlockType   string    `json:"bt,omitempty"`
-	Hash        string    `json:"h"`
-	URL         []URL     `json:"url,omitempty"`
-	IP4         []IP4     `json:"ip4,omitempty"`
-	IP6         []IP6     `json:"ip6,omitempty"`
-	Subnet4     []Subnet4 `json:"sb4,omitempty"`
-	Subnet6     []Subnet6 `json:"sb6,omitempty"`
-	Domain      []Domain  `json:"dm,omitempty"`
-	HTTPSBlock  int       `json:"hb"`
-	RecordHash  uint64    `json:"u2h"`
-}
-
-// Subnet6 - store for <ipv6Subnet>.
-type Subnet6 struct {
-	Subnet6 string `json:"sb6"`
-	Ts      int64  `json:"ts,omitempty"`
-}
-
-// Subnet4 - store for <ipSubnet>.
-type Subnet4 struct {
-	Subnet4 string `json:"sb4"`
-	Ts      int64  `json:"ts,omitempty"`
-}
-
-// Domain - store for <domain>.
-type Domain struct {
-	Domain string `json:"dm"`
-	Ts     int64  `json:"ts,omitempty"`
-}
-
-// URL - store for <url>.
-type URL struct {
-	URL string `json:"u"`
-	Ts  int64  `json:"ts,omitempty"`
-}
-
-// IP4 - store for <ip>.
-type IP4 struct {
-	IP4 uint32 `json:"ip4"`
-	Ts  int64  `json:"ts,omitempty"`
-}
-
-// IP6 - store for <ip6>
-type IP6 struct {
-	IP6 []byte `json:"ip6"`
-	Ts  int64  `json:"ts,omitempty"`
-}
-
-// Decision - <decision> and store for <decision>
-type Decision struct {
-	Date   string `xml:"date,attr" json:"dd"`
-	Number string `xml:"number,attr" json:"dn"`
-	Org    string `xml:"org,attr" json:"do"`
-}
+// Record model of the parser, see package parser.
+type (
+	Content  = parser.Content
+	Decision = parser.Decision
+	URL      = parser.URL
+	Domain   = parser.Domain
+	IP4      = parser.IP4
+	IP6      = parser.IP6
+	Subnet4  = parser.Subnet4
+	Subnet6  = parser.Subnet6
+)
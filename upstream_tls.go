@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Environment of the sandboxed fetch with the upstream TLS files, see UpstreamTLS.
+const (
+	sandboxEnvCA       = "U2CK_DUMP_CA"
+	sandboxEnvCert     = "U2CK_DUMP_CERT"
+	sandboxEnvCertKey  = "U2CK_DUMP_CERT_KEY"
+	sandboxEnvInsecure = "U2CK_DUMP_INSECURE"
+)
+
+// ErrNoCACerts - the CA bundle has no PEM certificates.
+var ErrNoCACerts = errors.New("no CA certificates")
+
+// UpstreamTLS - TLS of the connections to a dump source: a CA bundle of a non-standard CA
+// and a client certificate for mTLS. The files are read on every connection, so rotated
+// certificates are picked up without a restart.
+type UpstreamTLS struct {
+	CAFile   string // PEM CA bundle the server certificate is verified with instead of the system roots.
+	CertFile string // PEM client certificate, with KeyFile.
+	KeyFile  string
+	Insecure bool // don't verify the server certificate at all, the dump may be forged.
+}
+
+// IsZero - the default TLS of the system.
+func (t UpstreamTLS) IsZero() bool {
+	return t == UpstreamTLS{}
+}
+
+// Config - TLS config of the files, nil for the defaults.
+func (t UpstreamTLS) Config() (*tls.Config, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key go together")
+	}
+
+	conf := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: t.Insecure} //nolint:gosec // it is asked for.
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("CA: %w", err)
+		}
+
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA: %s: %w", t.CAFile, ErrNoCACerts)
+		}
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// Client - HTTP client of the dump source.
+func (t UpstreamTLS) Client() (*http.Client, error) {
+	conf, err := t.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	if conf == nil {
+		return &http.Client{}, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = conf
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// gitConfig - git -c options of the TLS, paths are absolute as git runs in the checkout.
+func (t UpstreamTLS) gitConfig() []string {
+	var args []string
+
+	for _, opt := range []struct{ name, file string }{
+		{"http.sslCAInfo", t.CAFile},
+		{"http.sslCert", t.CertFile},
+		{"http.sslKey", t.KeyFile},
+	} {
+		if opt.file == "" {
+			continue
+		}
+
+		if abs, err := filepath.Abs(opt.file); err == nil {
+			opt.file = abs
+		}
+
+		args = append(args, "-c", opt.name+"="+opt.file)
+	}
+
+	if t.Insecure {
+		args = append(args, "-c", "http.sslVerify=false")
+	}
+
+	return args
+}
+
+// env - environment of the sandboxed fetch.
+func (t UpstreamTLS) env() []string {
+	env := []string{sandboxEnvCA + "=" + t.CAFile, sandboxEnvCert + "=" + t.CertFile, sandboxEnvCertKey + "=" + t.KeyFile}
+	if t.Insecure {
+		env = append(env, sandboxEnvInsecure+"=1")
+	}
+
+	return env
+}
+
+// upstreamTLSFromEnv - TLS passed to the sandboxed fetch.
+func upstreamTLSFromEnv() UpstreamTLS {
+	return UpstreamTLS{
+		CAFile:   os.Getenv(sandboxEnvCA),
+		CertFile: os.Getenv(sandboxEnvCert),
+		KeyFile:  os.Getenv(sandboxEnvCertKey),
+		Insecure: os.Getenv(sandboxEnvInsecure) != "",
+	}
+}
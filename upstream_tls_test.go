@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// writeTestCert - self-signed client certificate and its key in the dir.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "u2ckdump"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ = x509.ParseCertificate(der)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = dir+"/client.pem", dir+"/client.key"
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	return certFile, keyFile, cert
+}
+
+// TestUpstreamTLS tests the custom CA, the client certificate and the insecure mode of the upstream client.
+func TestUpstreamTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	certFile, keyFile, clientCert := writeTestCert(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+
+	defer server.Close()
+
+	caFile := dir + "/ca.pem"
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+
+	get := func(upstream UpstreamTLS) error {
+		client, err := upstream.Client()
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+
+		resp.Body.Close()
+
+		return nil
+	}
+
+	cases := []struct {
+		name     string
+		upstream UpstreamTLS
+		ok       bool
+	}{
+		{"system roots", UpstreamTLS{}, false},
+		{"ca without cert", UpstreamTLS{CAFile: caFile}, false},
+		{"ca and cert", UpstreamTLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, true},
+		{"insecure and cert", UpstreamTLS{CertFile: certFile, KeyFile: keyFile, Insecure: true}, true},
+		{"cert without key", UpstreamTLS{CAFile: caFile, CertFile: certFile}, false},
+		{"ca is not pem", UpstreamTLS{CAFile: keyFile}, false},
+		{"no ca", UpstreamTLS{CAFile: dir + "/nope.pem"}, false},
+	}
+
+	for _, c := range cases {
+		if err := get(c.upstream); (err == nil) != c.ok {
+			t.Errorf("%s: error %v, ok %v", c.name, err, c.ok)
+		}
+	}
+
+	if _, err := (UpstreamTLS{CAFile: keyFile}).Config(); err == nil {
+		t.Errorf("ca of a key: no error")
+	}
+
+	args := UpstreamTLS{CAFile: caFile, Insecure: true}.gitConfig()
+	if len(args) != 4 || args[1] != "http.sslCAInfo="+caFile || args[3] != "http.sslVerify=false" {
+		t.Errorf("git config: %q", args)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+)
+
+// urgentCSVFilename - urgent records in the dump.csv format, operators enforce them within an hour.
+const urgentCSVFilename = "urgent.csv"
+
+// urgentFilterClause - export filter clause of urgent records.
+const urgentFilterClause = "urgency=!0"
+
+// Urgent record events of the webhook.
+const (
+	UrgentAdded   = "added"
+	UrgentUpdated = "updated"
+)
+
+// Urgent records fast path, they are set once at startup.
+var (
+	UrgentCSV     bool   // export urgent.csv after every parse.
+	UrgentWebhook string // URL to POST urgent records of every parse as JSON right after it is applied, empty disables.
+)
+
+// RecentUrgent - urgent added and updated records feed.
+var RecentUrgent = &FeedRing{}
+
+// UrgentRecord - urgent record as it is sent to the webhook.
+type UrgentRecord struct {
+	ID          int32    `json:"id"`
+	Event       string   `json:"event"` // added or updated.
+	UrgencyType int32    `json:"urgencyType"`
+	IncludeTime int64    `json:"includeTime"`
+	BlockType   int32    `json:"blockType"`
+	Decision    Decision `json:"decision"`
+	Selectors   []string `json:"selectors"` // "kind value", the first feedMaxSelectors of them.
+	More        int      `json:"more,omitempty"`
+}
+
+// UrgentEvent - urgent records of one generation as it is sent to the webhook.
+type UrgentEvent struct {
+	Generation int64          `json:"generation"`
+	UpdateTime int64          `json:"updateTime"` // registry update time.
+	Time       time.Time      `json:"time"`
+	Records    []UrgentRecord `json:"records"`
+}
+
+// Urgent - urgent block of the registry, operators have to enforce it within an hour.
+func (pack *PackedContent) Urgent() bool {
+	return pack.UrgencyType != 0
+}
+
+// urgentIDs - urgent records of the ids, call it under lock.
+func (dump *Dump) urgentIDs(idLists ...[]int32) []int32 {
+	var urgent []int32
+
+	for _, ids := range idLists {
+		for _, id := range ids {
+			if pack, ok := dump.ContentIdx[id]; ok && pack.Urgent() {
+				urgent = append(urgent, id)
+			}
+		}
+	}
+
+	return urgent
+}
+
+// PublishUrgent - push urgent added and updated records of the just published generation
+// to the urgent feed and the webhook, before exports and snapshots.
+func PublishUrgent(s *parseStage, added []int32) {
+	if FeedSize <= 0 && UrgentWebhook == "" {
+		return
+	}
+
+	fresh := make(Int32Map, len(added))
+	for _, id := range added {
+		fresh[id] = Nothing{}
+	}
+
+	now := time.Now()
+
+	CurrentDump.RLock()
+
+	event := UrgentEvent{Generation: CurrentDump.generation, UpdateTime: CurrentDump.utime, Time: now}
+
+	var items []FeedItem
+
+	for _, id := range s.order {
+		pack, ok := CurrentDump.ContentIdx[id]
+		if s.records[id].same || !ok || !pack.Urgent() {
+			continue
+		}
+
+		item := newFeedItem(pack, event.Generation, event.UpdateTime, now)
+		items = append(items, item)
+
+		record := UrgentRecord{
+			ID:          id,
+			Event:       UrgentUpdated,
+			UrgencyType: pack.UrgencyType,
+			IncludeTime: item.IncludeTime,
+			BlockType:   item.BlockType,
+			Decision:    item.Decision,
+			Selectors:   make([]string, 0, len(item.Selectors)),
+			More:        item.More,
+		}
+
+		if _, ok := fresh[id]; ok {
+			record.Event = UrgentAdded
+		}
+
+		for _, sel := range item.Selectors {
+			record.Selectors = append(record.Selectors, sel.Kind+" "+sel.Value)
+		}
+
+		event.Records = append(event.Records, record)
+	}
+
+	CurrentDump.RUnlock()
+
+	metricUrgent.Set(int64(len(items)))
+
+	if len(items) == 0 {
+		return
+	}
+
+	logger.Warning.Printf("Urgent records: %d\n", len(items))
+
+	if FeedSize > 0 {
+		if len(items) > FeedSize {
+			items = items[len(items)-FeedSize:]
+		}
+
+		RecentUrgent.Push(items, FeedSize)
+	}
+
+	if UrgentWebhook != "" {
+		go func() {
+			if err := postJSON(UrgentWebhook, event); err != nil {
+				logger.Error.Printf("Can't send urgent records: %s\n", err.Error())
+			}
+		}()
+	}
+}
+
+// ExportUrgentCSV - write urgent.csv of the new generation, if enabled, the records written
+// and true if it is written. Only urgent records passing ExportFilterConfig are written.
+func ExportUrgentCSV(dir string) (int, bool) {
+	if !UrgentCSV {
+		return 0, false
+	}
+
+	filter, err := ParseExportFilter(ExportFilterConfig.String() + ";" + urgentFilterClause)
+	if err != nil {
+		logger.Error.Printf("Can't save %s: %s\n", urgentCSVFilename, err.Error())
+
+		return 0, false
+	}
+
+	n, err := writeRegistryCSVFile(dir+"/"+urgentCSVFilename, CurrentDump, filter)
+	if err != nil {
+		logger.Error.Printf("Can't save %s: %s\n", urgentCSVFilename, err.Error())
+
+		return 0, false
+	}
+
+	return n, true
+}
+
+// urgentCSVHandler - /urgent.csv, the last exported file.
+func urgentCSVHandler(dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !UrgentCSV {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=windows-1251")
+		http.ServeFile(w, r, dir+"/"+urgentCSVFilename)
+	}
+}
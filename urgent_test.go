@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const urgentDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <domain><![CDATA[a.tld]]></domain>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="domain" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <domain><![CDATA[b.tld]]></domain>
+</content>
+</reg:register>`
+
+// TestUrgent tests urgent added and updated records reach the feed, the webhook, Watch changes and urgent.csv.
+func TestUrgent(t *testing.T) {
+	defer func(dump *Dump, csv bool, webhook string, feed *FeedRing) {
+		CurrentDump, UrgentCSV, UrgentWebhook, RecentUrgent = dump, csv, webhook, feed
+	}(CurrentDump, UrgentCSV, UrgentWebhook, RecentUrgent)
+
+	events := make(chan UrgentEvent, 1)
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event UrgentEvent
+
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer hook.Close()
+
+	CurrentDump, UrgentCSV, UrgentWebhook, RecentUrgent = NewDump(), true, hook.URL, &FeedRing{}
+
+	if err := Parse(strings.NewReader(urgentDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	// record 1 turns urgent, record 3 is added urgent, record 2 stays.
+	next := strings.Replace(urgentDump, `blockType="domain" hash="1">`, `urgencyType="1" blockType="domain" hash="11">`, 1)
+	next = strings.Replace(next, "</reg:register>", `<content id="3" includeTime="2001-01-01T01:01:01" entryType="1" urgencyType="1" blockType="domain" hash="3">
+        <decision date="2000-01-01" number="3" org="ONE"/>
+        <domain><![CDATA[c.tld]]></domain>
+</content>
+</reg:register>`, 1)
+
+	if err := Parse(strings.NewReader(next)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-events:
+		if len(event.Records) != 2 || event.Records[0].ID != 1 || event.Records[0].Event != UrgentUpdated ||
+			event.Records[1].ID != 3 || event.Records[1].Event != UrgentAdded || event.Records[1].Selectors[0] != "domain c.tld" {
+			t.Errorf("Webhook: %+v\n", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("No webhook\n")
+	}
+
+	if items := RecentUrgent.Latest(0); len(items) != 2 || !items[0].Urgent || !strings.HasPrefix(feedItemTitle(items[0]), SrvUrgentMark) {
+		t.Errorf("Feed: %+v\n", items)
+	}
+
+	if changes, ok := CurrentDump.ChangesSince(1); !ok || len(changes) != 1 || len(changes[0].Urgent) != 2 {
+		t.Errorf("Changes: %+v\n", changes)
+	}
+
+	dir := t.TempDir()
+	if n, ok := ExportUrgentCSV(dir); !ok || n != 2 {
+		t.Fatalf("No urgent.csv: %d\n", n)
+	}
+
+	data, _ := os.ReadFile(dir + "/" + urgentCSVFilename)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[2], ";a.tld;") || !strings.HasPrefix(lines[3], ";c.tld;") {
+		t.Errorf("urgent.csv: %q\n", data)
+	}
+}
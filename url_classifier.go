@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// Mixed URL policies: block type of a URL record with https and other URLs.
+const (
+	MixedHTTPS = "https" // any https URL makes the record an HTTPS block.
+	MixedURL   = "url"   // the record is an HTTPS block only if all its URLs are https.
+)
+
+// URL schemes of the classifier.
+const (
+	SchemeOther = iota
+	SchemeHTTP
+	SchemeHTTPS
+)
+
+// URLClassifier - schemes of the record URLs and the block type they make.
+type URLClassifier struct {
+	Mixed string // mixed URL policy, empty is MixedHTTPS.
+}
+
+// URLClass - URLs of a record by scheme.
+type URLClass struct {
+	HTTP  int32
+	HTTPS int32
+	Other int32
+}
+
+// urlClassifier - classifier of ParseConfig.
+func urlClassifier() URLClassifier {
+	return URLClassifier{Mixed: ParseConfig.MixedURLs}
+}
+
+// Scheme - scheme of the normalized URL. An explicit port does not change the scheme:
+// http://host:443/ is plain http and https://host:80/ is TLS. URLs without a scheme
+// are classified by the port, 443 is https and 80 is http.
+func (c URLClassifier) Scheme(nURL string) int {
+	i := strings.Index(nURL, "://")
+	if i < 0 {
+		host := nURL
+		if j := strings.IndexAny(host, "/?#"); j >= 0 {
+			host = host[:j]
+		}
+
+		_, port, err := net.SplitHostPort(host)
+		if err != nil {
+			return SchemeOther
+		}
+
+		switch port {
+		case "443":
+			return SchemeHTTPS
+		case "80":
+			return SchemeHTTP
+		}
+
+		return SchemeOther
+	}
+
+	switch strings.ToLower(nURL[:i]) {
+	case "https":
+		return SchemeHTTPS
+	case "http":
+		return SchemeHTTP
+	}
+
+	return SchemeOther
+}
+
+// Add - count the normalized URL.
+func (c URLClassifier) Add(class *URLClass, nURL string) {
+	switch c.Scheme(nURL) {
+	case SchemeHTTPS:
+		class.HTTPS++
+	case SchemeHTTP:
+		class.HTTP++
+	default:
+		class.Other++
+	}
+}
+
+// BlockType - BlockTypeHTTPS or BlockTypeURL of a URL record.
+func (c URLClassifier) BlockType(class URLClass) int32 {
+	if class.HTTPS == 0 {
+		return BlockTypeURL
+	}
+
+	if c.Mixed == MixedURL && class.HTTP+class.Other > 0 {
+		return BlockTypeURL
+	}
+
+	return BlockTypeHTTPS
+}
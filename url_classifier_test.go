@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const mixedURLDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4">
+<content id="1" includeTime="2001-01-01T01:01:01" entryType="1" hash="1">
+        <decision date="2000-01-01" number="1" org="ONE"/>
+        <url><![CDATA[https://a.tld/1]]></url>
+        <url><![CDATA[http://a.tld/2]]></url>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" hash="2">
+        <decision date="2000-01-01" number="2" org="ONE"/>
+        <url><![CDATA[HTTPS://b.tld:8443/1]]></url>
+</content>
+<content id="3" includeTime="2001-01-01T01:01:01" entryType="1" hash="3">
+        <decision date="2000-01-01" number="3" org="ONE"/>
+        <url><![CDATA[http://c.tld:443/1]]></url>
+</content>
+</reg:register>`
+
+// TestURLClassifier tests URL schemes and the block types of mixed URL records by the policy.
+func TestURLClassifier(t *testing.T) {
+	defer func(dump *Dump, mixed string) { CurrentDump, ParseConfig.MixedURLs = dump, mixed }(CurrentDump, ParseConfig.MixedURLs)
+
+	var c URLClassifier
+
+	for nURL, want := range map[string]int{
+		"https://a.tld/":      SchemeHTTPS,
+		"HTTP://a.tld/":       SchemeHTTP,
+		"http://a.tld:443/":   SchemeHTTP,
+		"https://a.tld:80/":   SchemeHTTPS,
+		"ftp://a.tld/":        SchemeOther,
+		"a.tld:443/path":      SchemeHTTPS,
+		"a.tld:80?q=1":        SchemeHTTP,
+		"a.tld/path":          SchemeOther,
+		"[fd00::1]:443/index": SchemeHTTPS,
+	} {
+		if got := c.Scheme(nURL); got != want {
+			t.Errorf("Scheme: %s: %d, want %d\n", nURL, got, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		mixed string
+		want  map[int32]int32
+	}{
+		{"", map[int32]int32{1: BlockTypeHTTPS, 2: BlockTypeHTTPS, 3: BlockTypeURL}},
+		{MixedURL, map[int32]int32{1: BlockTypeURL, 2: BlockTypeHTTPS, 3: BlockTypeURL}},
+	} {
+		CurrentDump, ParseConfig.MixedURLs = NewDump(), tc.mixed
+
+		if err := Parse(strings.NewReader(mixedURLDump)); err != nil {
+			t.Fatal(err)
+		}
+
+		for id, want := range tc.want {
+			if pack := CurrentDump.ContentIdx[id]; pack.BlockType != want {
+				t.Errorf("%q: %d: block type %d, want %d\n", tc.mixed, id, pack.BlockType, want)
+			}
+		}
+
+		if pack := CurrentDump.ContentIdx[1]; pack.URLHTTP != 1 || pack.URLHTTPS != 1 || pack.SNIOnly() {
+			t.Errorf("%q: counters: %d %d\n", tc.mixed, pack.URLHTTP, pack.URLHTTPS)
+		}
+	}
+}
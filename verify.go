@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/netip"
+	"sort"
+	"strconv"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// VerifyReport - result of the full index verification.
+type VerifyReport struct {
+	Records  int // checked records.
+	Entries  int // checked index entries.
+	Problems []IndexProblem
+	Repaired int
+}
+
+// VerifyIndexes - check that every index entry references a record carrying the selector
+// and every record selector is indexed, call it under lock.
+func (dump *Dump) VerifyIndexes() *VerifyReport {
+	report := &VerifyReport{}
+
+	selectors := make(map[int32]SelectorSet, len(dump.ContentIdx))
+
+	for _, pack := range dump.ContentIdx {
+		report.Problems = append(report.Problems, dump.checkRecord(pack)...)
+		report.Records++
+	}
+
+	memo := func(pack *PackedContent) SelectorSet {
+		set, ok := selectors[pack.ID]
+		if !ok {
+			set = pack.recordSelectors()
+			selectors[pack.ID] = set
+		}
+
+		return set
+	}
+
+	dump.rangeIndexes(func(sel Selector, ids ArrayIntSet) bool {
+		report.Problems = append(report.Problems, dump.checkIndexEntry(sel, ids, memo)...)
+		report.Entries++
+
+		return true
+	})
+
+	sort.Slice(report.Problems, func(i, j int) bool {
+		a, b := report.Problems[i], report.Problems[j]
+		if a.Sel != b.Sel {
+			return a.Sel.Kind < b.Sel.Kind || a.Sel.Kind == b.Sel.Kind && a.Sel.Value < b.Sel.Value
+		}
+
+		return a.ID < b.ID
+	})
+
+	return report
+}
+
+// RepairIndexes - drop dangling index entries, then add missing ones, call it under write lock.
+// Dangling entries go first: a subnet may move between the subnet indexes sharing the radix tree.
+func (dump *Dump) RepairIndexes(problems []IndexProblem) int {
+	repaired := 0
+
+	for _, dangling := range []bool{true, false} {
+		for _, p := range problems {
+			if p.Dangling != dangling {
+				continue
+			}
+
+			if dump.repairIndex(p) {
+				repaired++
+			}
+		}
+	}
+
+	if repaired > 0 {
+		logger.Warning.Printf("Repaired index entries: %d of %d\n", repaired, len(problems))
+	}
+
+	return repaired
+}
+
+func (dump *Dump) repairIndex(p IndexProblem) bool {
+	switch p.Sel.Kind {
+	case SelectorIP4:
+		ip4, _ := parser.ParseIP4(p.Sel.Value)
+		if p.Dangling {
+			dump.RemoveFromIndexIP4(ip4, p.ID)
+		} else {
+			dump.InsertToIndexIP4(ip4, p.ID)
+		}
+	case SelectorIP6:
+		ip6, _ := parser.ParseIP6(p.Sel.Value)
+		if p.Dangling {
+			dump.RemoveFromIndexIP6(ip6, p.ID)
+		} else {
+			dump.InsertToIndexIP6(ip6, p.ID)
+		}
+	case SelectorSubnet4:
+		subnet4, _ := netip.ParsePrefix(p.Sel.Value)
+		if p.Dangling {
+			dump.RemoveFromSubnet4(subnet4, p.ID)
+		} else {
+			dump.InsertToIndexSubnet4(subnet4, p.ID)
+		}
+	case SelectorSubnet6:
+		subnet6, _ := netip.ParsePrefix(p.Sel.Value)
+		if p.Dangling {
+			dump.RemoveFromIndexSubnet6(subnet6, p.ID)
+		} else {
+			dump.InsertToIndexSubnet6(subnet6, p.ID)
+		}
+	case SelectorDomain:
+		if p.Dangling {
+			dump.RemoveFromIndexDomain(p.Sel.Value, p.ID)
+		} else {
+			dump.InsertToIndexDomain(p.Sel.Value, p.ID)
+		}
+	case SelectorURL:
+		if p.Dangling {
+			dump.RemoveFromIndexURL(p.Sel.Value, p.ID)
+		} else {
+			dump.InsertToIndexURL(p.Sel.Value, p.ID)
+		}
+	case SelectorDecision:
+		decision, err := strconv.ParseUint(p.Sel.Value, 10, 64)
+		if err != nil {
+			return false
+		}
+
+		if p.Dangling {
+			dump.RemoveFromIndexDecision(decision, p.ID)
+		} else {
+			dump.InsertToIndexDecision(decision, p.ID)
+		}
+	default:
+		return false
+	}
+
+	return true
+}
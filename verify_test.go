@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestVerifyIndexes tests detection and repair of a subnet6 indexed as subnet4.
+func TestVerifyIndexes(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(strings.Replace(xml01,
+		"<ipSubnet>10.4.0.0/16</ipSubnet>",
+		"<ipSubnet>10.4.0.0/16</ipSubnet>\n        <ipv6Subnet>fd44::/32</ipv6Subnet>", 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if report := CurrentDump.VerifyIndexes(); len(report.Problems) != 0 {
+		t.Fatalf("expected consistent indexes, got %v", report.Problems)
+	}
+
+	// the old parser put IPv6 subnets to the IPv4 subnet index.
+	CurrentDump.RemoveFromIndexSubnet6(netip.MustParsePrefix("fd44::/32"), 444)
+	CurrentDump.InsertToIndexSubnet4(netip.MustParsePrefix("fd44::/32"), 444)
+
+	report := CurrentDump.VerifyIndexes()
+	if len(report.Problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", report.Problems)
+	}
+
+	if got := report.Problems[0].String() + "; " + report.Problems[1].String(); got != "dangling subnet4 fd44::/32 -> 444; missing subnet6 fd44::/32 <- 444" {
+		t.Errorf("unexpected problems: %s", got)
+	}
+
+	if repaired := CurrentDump.RepairIndexes(report.Problems); repaired != 2 {
+		t.Errorf("expected 2 repaired, got %d", repaired)
+	}
+
+	if report := CurrentDump.VerifyIndexes(); len(report.Problems) != 0 {
+		t.Errorf("expected repaired indexes, got %v", report.Problems)
+	}
+
+	if ok, detail := CurrentDump.selfCheckSubnet(CurrentDump.sampleContents(10)); !ok {
+		t.Errorf("radix tree after repair: %s", detail)
+	}
+}
+
+// TestVerifyIndexesRepairAdmin tests repair over RPC needs the admin token.
+func TestVerifyIndexesRepairAdmin(t *testing.T) {
+	defer func(dump *Dump, token string) { CurrentDump, AdminToken = dump, token }(CurrentDump, AdminToken)
+
+	CurrentDump, AdminToken = NewDump(), "secret"
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	CurrentDump.InsertToIndexDomain("stray.tld", 111)
+
+	srv := &server{}
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	guest := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer guess"))
+
+	if resp, _ := srv.VerifyIndexes(guest, &pb.VerifyRequest{Repair: true}); resp.GetError() != SrvAdminOnly {
+		t.Errorf("Repair without the token: %v\n", resp)
+	}
+
+	// the check itself is open.
+	if resp, _ := srv.VerifyIndexes(guest, &pb.VerifyRequest{}); resp.GetError() != "" || resp.GetProblemCount() != 1 {
+		t.Errorf("Check: %v\n", resp)
+	}
+
+	if resp, _ := srv.VerifyIndexes(admin, &pb.VerifyRequest{Repair: true}); resp.GetError() != "" || resp.GetRepaired() != 1 {
+		t.Errorf("Repair: %v\n", resp)
+	}
+
+	if report := CurrentDump.VerifyIndexes(); len(report.Problems) != 0 {
+		t.Errorf("Not repaired: %v\n", report.Problems)
+	}
+
+	AdminToken = ""
+
+	if resp, _ := srv.VerifyIndexes(admin, &pb.VerifyRequest{Repair: true}); resp.GetError() != SrvAdminOnly {
+		t.Errorf("Repair without the configured token: %v\n", resp)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"google.golang.org/grpc"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	pb "github.com/usher2/u2ckdump/msg"
+	pbv2 "github.com/usher2/u2ckdump/msg/v2"
+)
+
+// Commit - VCS revision, set at build time with -ldflags "-X main.Commit=...".
+var Commit string
+
+// protoVersion - the latest API version served, v1 is served too.
+const protoVersion = "v2"
+
+// Optional features of GetVersion, enabled by the configuration.
+const (
+	FeaturePayloads        = "payloads"         // record payloads are kept.
+	FeatureRawContent      = "raw-content"      // GetRawContent has the original fragments.
+	FeatureSemanticCompare = "semantic-compare" // changed bytes of the same record are not updates.
+	FeatureWatch           = "watch"            // Watch replays missed generations.
+	FeatureFeeds           = "feeds"            // ListRecent and the feeds of the gateway.
+	FeatureUrgentCSV       = "urgent-csv"
+	FeatureUrgentWebhook   = "urgent-webhook"
+	FeatureRegistryCSV     = "registry-csv"
+	FeatureSNIBloom        = "sni-bloom"
+	FeatureSnapshots       = "snapshots" // DiffGenerations between retained snapshots.
+	FeatureExclusions      = "exclusions"
+	FeatureASN             = "asn" // GetASNReport has the ASN table.
+	FeatureComparePeers    = "compare-peers"
+	FeatureHooks           = "hooks"
+	FeatureAudit           = "audit"
+	FeatureRateLimit       = "rate-limit"
+	FeatureParseCheckpoint = "parse-checkpoint"
+	FeatureGRPCWeb         = "grpc-web"
+	FeatureFaultInjection  = "fault-injection" // InjectFault arms poller failures, a test instance.
+	FeatureDuplicates      = "duplicates-report"
+	FeatureShadow          = "shadow" // GetShadowReport compares a shadow parse of every dump.
+)
+
+// ToolCommit - Commit or the VCS revision of the build info, "-dirty" if it is modified.
+func ToolCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var revision, modified string
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+
+	return revision
+}
+
+// Features - optional features enabled on the instance, ordered.
+func Features() []string {
+	var features []string
+
+	for feature, on := range map[string]bool{
+		FeaturePayloads:        payloadsKept(),
+		FeatureRawContent:      ParseConfig.KeepRaw,
+		FeatureSemanticCompare: ParseConfig.Compare == CompareSemantic,
+		FeatureWatch:           WatchKeep > 0,
+		FeatureFeeds:           FeedSize > 0,
+		FeatureUrgentCSV:       UrgentCSV,
+		FeatureUrgentWebhook:   UrgentWebhook != "",
+		FeatureRegistryCSV:     RegistryCSV,
+		FeatureSNIBloom:        SNIBloomFP > 0,
+		FeatureSnapshots:       SnapshotKeep > 0,
+		FeatureExclusions:      ExclusionSource != "",
+		FeatureASN:             ASNs != nil,
+		FeatureComparePeers:    len(ComparePeers) > 0,
+		FeatureHooks:           Hooks != nil,
+		FeatureAudit:           Audit != nil,
+		FeatureRateLimit:       RateLimit != nil,
+		FeatureParseCheckpoint: ParseCheckpointEvery > 0,
+		FeatureGRPCWeb:         len(GRPCWebOrigins) > 0,
+		FeatureFaultInjection:  FaultInjection,
+		FeatureDuplicates:      DuplicatesReportMin > 0,
+		FeatureShadow:          ShadowConfig.Enabled,
+	} {
+		if on {
+			features = append(features, feature)
+		}
+	}
+
+	sort.Strings(features)
+
+	return features
+}
+
+// serviceMethods - full method names of the service, ordered.
+func serviceMethods(desc grpc.ServiceDesc) []string {
+	methods := make([]string, 0, len(desc.Methods)+len(desc.Streams))
+
+	for _, m := range desc.Methods {
+		methods = append(methods, "/"+desc.ServiceName+"/"+m.MethodName)
+	}
+
+	for _, s := range desc.Streams {
+		methods = append(methods, "/"+desc.ServiceName+"/"+s.StreamName)
+	}
+
+	sort.Strings(methods)
+
+	return methods
+}
+
+// GetVersion - build and API of the instance, clients check features before calling new methods.
+func (s *server) GetVersion(ctx context.Context, in *pb.VersionRequest) (*pb.VersionResponse, error) {
+	logger.Debug.Printf("[%s] Received version request\n", RequestID(ctx))
+
+	return &pb.VersionResponse{
+		Version:      ToolVersion(),
+		Commit:       ToolCommit(),
+		GoVersion:    runtime.Version(),
+		Os:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		ProtoVersion: protoVersion,
+		Services:     []string{v1Service, v2Service},
+		Methods:      append(serviceMethods(pb.Check_ServiceDesc), serviceMethods(pbv2.Check_ServiceDesc)...),
+		Features:     Features(),
+	}, nil
+}
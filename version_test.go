@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+)
+
+// TestGetVersion tests methods and features of the version response.
+func TestGetVersion(t *testing.T) {
+	defer func(version string, feedSize int, urgent bool) {
+		Version, FeedSize, UrgentCSV = version, feedSize, urgent
+	}(Version, FeedSize, UrgentCSV)
+
+	Version, FeedSize, UrgentCSV = "v1.2.3", 0, true
+
+	resp, err := (&server{}).GetVersion(context.Background(), &pb.VersionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.GetVersion() != "v1.2.3" || resp.GetProtoVersion() != protoVersion || len(resp.GetServices()) != 2 {
+		t.Errorf("Version: %v\n", resp)
+	}
+
+	methods := make(StringMap)
+	for _, method := range resp.GetMethods() {
+		methods[method] = Nothing{}
+	}
+
+	for _, method := range []string{"/msg.Check/GetVersion", "/msg.Check/Ping", "/msg.v2.Check/Watch"} {
+		if _, ok := methods[method]; !ok {
+			t.Errorf("No method %s\n", method)
+		}
+	}
+
+	has := func(feature string) bool {
+		i := sort.SearchStrings(resp.GetFeatures(), feature)
+
+		return i < len(resp.GetFeatures()) && resp.GetFeatures()[i] == feature
+	}
+
+	if !has(FeatureUrgentCSV) || has(FeatureFeeds) {
+		t.Errorf("Features: %v\n", resp.GetFeatures())
+	}
+}
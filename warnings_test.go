@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/usher2/u2ckdump/msg"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+const warningsDump = `<?xml version="1.0" encoding="windows-1251"?>
+<reg:register xmlns:reg="http://rsoc.ru" updateTime="2011-01-01T01:01:01+03:00" formatVersion="2.4" flavour="x">
+<content id="1" includeTime="2001-01-01 01:01:01" entryType="1" blockType="ip-mask" hash="1" priority="9">
+        <decision date="2000-01-01" number="1" org="ONE" court="yes"/>
+        <ip ts="yesterday">10.1.1.1</ip>
+        <asn>64512</asn>
+</content>
+<content id="2" includeTime="2001-01-01T01:01:01" entryType="1" blockType="ip" hash="2">
+        <decision date="01.01.2000" number="2" org="ONE"/>
+        <ip>10.1.1.2</ip>
+</content>
+<signature>x</signature>
+</reg:register>`
+
+// TestParseWarnings tests warnings of format drift and no warnings of a good dump.
+func TestParseWarnings(t *testing.T) {
+	defer func(dump *Dump, warnings *parser.Warnings) { CurrentDump, ParseWarnings = dump, warnings }(CurrentDump, ParseWarnings)
+
+	CurrentDump = NewDump()
+	ParseWarnings = parser.NewWarnings()
+
+	if err := Parse(strings.NewReader(xml01)); err != nil {
+		t.Fatal(err)
+	}
+
+	if warnings, _ := ParseWarnings.List(""); len(warnings) != 0 {
+		t.Fatalf("Good dump: %+v\n", warnings)
+	}
+
+	CurrentDump = NewDump()
+	if err := Parse(strings.NewReader(warningsDump)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(CurrentDump.ContentIdx) != 2 || len(CurrentDump.ip4Idx) != 2 {
+		t.Fatalf("Records: %d %v\n", len(CurrentDump.ContentIdx), CurrentDump.ip4Idx)
+	}
+
+	resp, _ := (&server{}).GetParseWarnings(context.Background(), &pb.ParseWarningsRequest{})
+
+	got := make(map[string]string)
+	for _, w := range resp.GetWarnings() {
+		got[w.GetKind()+" "+w.GetElement()+" "+w.GetAttribute()] = w.GetValue()
+
+		if w.GetCount() != 1 || w.GetFirstSeen() == 0 {
+			t.Errorf("Warning: %v\n", w)
+		}
+	}
+
+	want := map[string]string{
+		"unknown-attribute register flavour":   "x",
+		"unknown-attribute content priority":   "9",
+		"unknown-attribute decision court":     "yes",
+		"unknown-element asn ":                 "",
+		"unknown-element signature ":           "",
+		"bad-time content includeTime":         "2001-01-01 01:01:01",
+		"bad-time ip ts":                       "yesterday",
+		"bad-time decision date":               "01.01.2000",
+		"unknown-block-type content blockType": "ip-mask",
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("Warnings: %v\n", got)
+	}
+
+	for k, v := range want {
+		if value, ok := got[k]; !ok || value != v {
+			t.Errorf("%s: %q %v\n", k, value, ok)
+		}
+	}
+
+	resp, _ = (&server{}).GetParseWarnings(context.Background(), &pb.ParseWarningsRequest{Kind: parser.WarnBadTime})
+	if len(resp.GetWarnings()) != 3 || resp.GetWarnings()[0].GetId() == 0 {
+		t.Errorf("Bad times: %v\n", resp.GetWarnings())
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/usher2/u2ckdump/internal/logger"
+	"github.com/usher2/u2ckdump/parser"
+)
+
+// ziCheckout - clone of the mirror in the dump cache dir.
+const ziCheckout = "z-i"
+
+// ziGitTimeout - max time of one git command.
+const ziGitTimeout = 10 * time.Minute
+
+// ziUpdated - "Updated:" line of dump.csv.
+const ziUpdated = "2006-01-02 15:04:05 -0700"
+
+// ErrNoZIDump - no dump.csv in the mirror.
+var ErrNoZIDump = errors.New("no dump.csv in the mirror")
+
+// ZISource - public zapret-info git mirror, for users without registry credentials.
+// The mirror has dump.csv (or dump-NN.csv parts) without record ids, entry types
+// and include times, so records are synthesized:
+//   - the id is a hash of the decision, domains and URLs, so the same record keeps its id;
+//   - the entry type is 1, the include time is the decision date;
+//   - the block type follows the selectors: URLs - default, "*." domain - domain-mask, domain, ip.
+type ZISource struct {
+	Repo   string
+	Branch string
+	TLS    UpstreamTLS
+}
+
+func (z *ZISource) Refresh(ctx context.Context, dir string) {
+	checkout := dir + "/" + ziCheckout
+
+	var commit string
+
+	err := NewRetryBudget(ctx, RetryConfig).Do(RetryLastID, func() error {
+		var err error
+
+		commit, err = ziPull(ctx, z.Repo, z.Branch, checkout, z.TLS)
+		if ferr := Faults.Fail(FaultFetch); ferr != nil {
+			err = ferr
+		}
+
+		return err
+	})
+
+	if err != nil {
+		logger.Error.Printf("Can't pull z-i mirror: %s\n", err.Error())
+
+		return
+	}
+
+	logger.Info.Printf("Last z-i commit: %s\n", commit)
+
+	cachedDump, err := ReadCurrentDumpID(dir + "/current")
+	if err != nil {
+		logger.Error.Printf("Can't read cached dump id: %s\n", err.Error())
+	}
+
+	if cachedDump.ID == commit {
+		logger.Info.Printf("No new dump")
+
+		MarkRefreshed()
+
+		return
+	}
+
+	defer runtime.GC()
+
+	logger.Info.Printf("Converting new dump..")
+
+	rotation := StartRotation(dir)
+	defer rotation.Close()
+
+	updated, err := ConvertZIDump(checkout, dir+"/dump.xml")
+	if err != nil {
+		logger.Error.Printf("Can't convert z-i dump: %s\n", err.Error())
+
+		return
+	}
+
+	if err := rotation.Rotate(cachedDump.ID); err != nil {
+		logger.Error.Printf("Can't rotate dumps: %s\n", err.Error())
+	}
+
+	if err := ParseDumpFile(dir, commit); err != nil {
+		logger.Error.Printf("Parse error: %s\n", err.Error())
+
+		return
+	}
+
+	logger.Info.Printf("Dump parsed")
+
+	CurrentDump.SetSource(commit, commit)
+	PostParse(dir)
+
+	err = WriteCurrentDumpID(dir+"/current", &DumpAnswer{ID: commit, CRC: commit, UpdateTime: updated})
+	if err != nil {
+		logger.Error.Printf("Can't write currentdump file: %s\n", err.Error())
+
+		return
+	}
+
+	logger.Info.Println("Last dump metainfo saved")
+
+	MarkRefreshed()
+}
+
+// ziPull - clone or update the shallow checkout of the branch, returns its commit.
+func ziPull(ctx context.Context, repo, branch, checkout string, upstream UpstreamTLS) (string, error) {
+	remote := upstream.gitConfig()
+
+	if _, err := os.Stat(checkout + "/.git"); err != nil {
+		if err := os.RemoveAll(checkout); err != nil {
+			return "", fmt.Errorf("clean: %w", err)
+		}
+
+		if _, err := git(ctx, "", append(remote, "clone", "--quiet", "--depth", "1", "--branch", branch, repo, checkout)...); err != nil {
+			return "", fmt.Errorf("clone: %w: %w", ErrTransient, err)
+		}
+	} else {
+		if _, err := git(ctx, checkout, append(remote, "fetch", "--quiet", "--depth", "1", repo, branch)...); err != nil {
+			return "", fmt.Errorf("fetch: %w: %w", ErrTransient, err)
+		}
+
+		if _, err := git(ctx, checkout, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return "", fmt.Errorf("reset: %w", err)
+		}
+	}
+
+	commit, err := git(ctx, checkout, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("rev-parse: %w", err)
+	}
+
+	return commit, nil
+}
+
+// git - run git in the dir, returns trimmed stdout.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ziGitTimeout)
+	defer cancel()
+
+	var stderr strings.Builder
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ziDumpFiles - dump.csv or its dump-NN.csv parts in order.
+func ziDumpFiles(checkout string) ([]string, error) {
+	if _, err := os.Stat(checkout + "/dump.csv"); err == nil {
+		return []string{checkout + "/dump.csv"}, nil
+	}
+
+	parts, err := filepath.Glob(checkout + "/dump-*.csv")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 0 {
+		return nil, ErrNoZIDump
+	}
+
+	sort.Strings(parts)
+
+	return parts, nil
+}
+
+// ConvertZIDump - write the dump of the checkout as registry XML, returns its update time.
+func ConvertZIDump(checkout, filename string) (int64, error) {
+	files, err := ziDumpFiles(checkout)
+	if err != nil {
+		return 0, err
+	}
+
+	readers := make([]io.Reader, 0, 2*len(files))
+
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return 0, fmt.Errorf("open: %w", err)
+		}
+
+		defer f.Close()
+
+		// a part may end without a newline.
+		readers = append(readers, f, strings.NewReader("\n"))
+	}
+
+	tmpfilename := filename + "-tmp"
+
+	out, err := os.Create(tmpfilename)
+	if err != nil {
+		return 0, fmt.Errorf("create: %w", err)
+	}
+
+	updated, err := WriteZIDump(out, io.MultiReader(readers...))
+	if err != nil {
+		out.Close()
+
+		return 0, fmt.Errorf("write: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return 0, fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tmpfilename, filename); err != nil {
+		return 0, fmt.Errorf("rename: %w", err)
+	}
+
+	return updated, nil
+}
+
+// ziRecord - one line of dump.csv.
+type ziRecord struct {
+	ips, domains, urls []string
+	org, number, date  string
+	line               string
+}
+
+// parseZILine - "IPs;domains;URLs;org;number;date", values separated with " | ".
+// URLs may have ";" in them, so the first two and the last three fields are taken as is.
+func parseZILine(line string) (*ziRecord, bool) {
+	fields := strings.Split(line, ";")
+	if len(fields) < 6 {
+		return nil, false
+	}
+
+	n := len(fields)
+
+	return &ziRecord{
+		ips:     ziValues(fields[0]),
+		domains: ziValues(fields[1]),
+		urls:    ziValues(strings.Join(fields[2:n-3], ";")),
+		org:     strings.TrimSpace(fields[n-3]),
+		number:  strings.TrimSpace(fields[n-2]),
+		date:    strings.TrimSpace(fields[n-1]),
+		line:    line,
+	}, true
+}
+
+// ziValues - non-empty values of the field.
+func ziValues(field string) []string {
+	var values []string
+
+	for _, v := range strings.Split(field, "|") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// id - positive id of the record, the same for the same decision and domains and URLs.
+func (r *ziRecord) id() int32 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s;%s;%s;%s;%s", r.org, r.number, r.date, strings.Join(r.domains, "|"), strings.Join(r.urls, "|"))
+
+	if id := int32(h.Sum32() & 0x7fffffff); id != 0 {
+		return id
+	}
+
+	return 1
+}
+
+// blockType - block type of the registry by the selectors.
+func (r *ziRecord) blockType() string {
+	switch {
+	case len(r.urls) > 0:
+		return "default"
+	case len(r.domains) > 0 && strings.HasPrefix(r.domains[0], "*."):
+		return "domain-mask"
+	case len(r.domains) > 0:
+		return "domain"
+	}
+
+	return "ip"
+}
+
+// WriteZIDump - convert windows-1251 dump.csv to the registry XML in UTF-8, returns the update time.
+// Colliding ids are moved to the next free one, lines of the same id in one dump are rare.
+func WriteZIDump(out io.Writer, in io.Reader) (int64, error) {
+	var updated int64
+
+	scanner := bufio.NewScanner(charmap.Windows1251.NewDecoder().Reader(in))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	w := bufio.NewWriter(out)
+	ids := make(Int32Map)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "Updated:") {
+			// every part of a split dump has it.
+			if updated != 0 {
+				continue
+			}
+
+			t, err := time.Parse(ziUpdated, strings.TrimSpace(strings.TrimPrefix(line, "Updated:")))
+			if err != nil {
+				return 0, fmt.Errorf("updated: %w", err)
+			}
+
+			updated = t.Unix()
+
+			fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<reg:register xmlns:reg=\"http://rsoc.ru\" updateTime=\"%s\" formatVersion=\"2.4\">\n",
+				t.In(parser.LocationMSK).Format(time.RFC3339))
+
+			continue
+		}
+
+		if updated == 0 {
+			return 0, fmt.Errorf("%w: no Updated line", ErrNoZIDump)
+		}
+
+		// provenance of dump.csv exported by u2ckdump.
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		record, ok := parseZILine(line)
+		if !ok {
+			if line != "" {
+				logger.Debug.Printf("Bad z-i line: %s\n", line)
+			}
+
+			continue
+		}
+
+		id := record.id()
+		for {
+			if _, ok := ids[id]; !ok {
+				break
+			}
+
+			if id++; id <= 0 {
+				id = 1
+			}
+		}
+
+		ids[id] = Nothing{}
+
+		writeZIContent(w, id, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("read: %w", err)
+	}
+
+	if updated == 0 {
+		return 0, fmt.Errorf("%w: no Updated line", ErrNoZIDump)
+	}
+
+	w.WriteString("</reg:register>\n")
+
+	return updated, w.Flush()
+}
+
+// writeZIContent - <content> of the record.
+func writeZIContent(w *bufio.Writer, id int32, r *ziRecord) {
+	h := fnv.New64a()
+	h.Write([]byte(r.line))
+
+	fmt.Fprintf(w, "<content id=\"%d\" includeTime=\"%sT00:00:00\" entryType=\"1\" blockType=\"%s\" hash=\"%016X\">\n",
+		id, ziEscape(r.date), r.blockType(), h.Sum64())
+	fmt.Fprintf(w, "<decision date=\"%s\" number=\"%s\" org=\"%s\"/>\n", ziEscape(r.date), ziEscape(r.number), ziEscape(r.org))
+
+	for _, u := range r.urls {
+		fmt.Fprintf(w, "<url>%s</url>\n", ziEscape(u))
+	}
+
+	for _, domain := range r.domains {
+		fmt.Fprintf(w, "<domain>%s</domain>\n", ziEscape(domain))
+	}
+
+	for _, ip := range r.ips {
+		element := parser.ElementIP4
+
+		switch {
+		case strings.Contains(ip, ":") && strings.Contains(ip, "/"):
+			element = parser.ElementIP6Subnet
+		case strings.Contains(ip, ":"):
+			element = parser.ElementIP6
+		case strings.Contains(ip, "/"):
+			element = parser.ElementIP4Subnet
+		}
+
+		fmt.Fprintf(w, "<%s>%s</%s>\n", element, ziEscape(ip), element)
+	}
+
+	w.WriteString("</content>\n")
+}
+
+// ziEscape - text escaped for XML.
+func ziEscape(s string) string {
+	var b strings.Builder
+
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/usher2/u2ckdump/parser"
+)
+
+const ziDump = `Updated: 2011-01-01 01:01:01 +0000
+1.1.1.1 | 10.0.0.0/8 | fd00::1 | fd00::/16;;;ФНС России;1;2001-01-01
+;www.e1.tld;;МВД & Ко;2;2002-02-02
+2.2.2.2;*.e2.tld;;Роскомнадзор;3;2003-03-03
+3.3.3.3;www.e3.tld;http://www.e3.tld/a;b=1 | https://www.e3.tld/c;Суд;4;2004-04-04
+bad line
+`
+
+// ziCP1251 - the dump as in the mirror.
+func ziCP1251(t *testing.T, dump string) []byte {
+	t.Helper()
+
+	b, err := charmap.Windows1251.NewEncoder().Bytes([]byte(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// ziParse - parse the converted dump into a new current dump.
+func ziParse(t *testing.T, dump string) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	if _, err := WriteZIDump(&out, bytes.NewReader(ziCP1251(t, dump))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Parse(&out); err != nil {
+		t.Fatalf("%s\n%s", err, out.String())
+	}
+}
+
+// TestWriteZIDump tests records synthesized from dump.csv and stable ids of them.
+func TestWriteZIDump(t *testing.T) {
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+	ziParse(t, ziDump)
+
+	if Stats.Count != 4 || CurrentDump.utime != 1293843661 {
+		t.Fatalf("Count: %d Updated: %d\n", Stats.Count, CurrentDump.utime)
+	}
+
+	blockTypes := make(map[int32]int)
+	for _, pack := range CurrentDump.ContentIdx {
+		blockTypes[pack.BlockType]++
+	}
+
+	if blockTypes[BlockTypeIP] != 1 || blockTypes[BlockTypeDomain] != 1 || blockTypes[BlockTypeMask] != 1 || blockTypes[BlockTypeHTTPS] != 1 {
+		t.Errorf("Block types: %v\n", blockTypes)
+	}
+
+	for _, ok := range []bool{
+		len(CurrentDump.ip4Idx[netip.MustParseAddr("1.1.1.1")]) == 1,
+		len(CurrentDump.subnet4Idx[netip.MustParsePrefix("10.0.0.0/8")]) == 1,
+		len(CurrentDump.subnet6Idx[netip.MustParsePrefix("fd00::/16")]) == 1,
+		len(CurrentDump.domainIdx["www.e1.tld"]) == 1,
+		len(CurrentDump.urlIdx[NormalizeURL("http://www.e3.tld/a;b=1")]) == 1,
+		len(CurrentDump.orgIdx[parser.NormalizeOrg("МВД & Ко")]) == 1,
+	} {
+		if !ok {
+			t.Errorf("Indexes: %v %v %v\n", CurrentDump.urlIdx, CurrentDump.orgIdx, CurrentDump.subnet6Idx)
+
+			break
+		}
+	}
+
+	// the same records in another order are not changed.
+	lines := strings.Split(strings.TrimSpace(ziDump), "\n")
+	lines[1], lines[4] = lines[4], lines[1]
+
+	ziParse(t, strings.Join(lines, "\n"))
+
+	if Stats.AddCount != 0 || Stats.UpdateCount != 0 || Stats.RemoveCount != 0 {
+		t.Errorf("Reordered: %+v\n", Stats)
+	}
+
+	// changed IPs are an update, changed domains are another record.
+	next := strings.Replace(ziDump, "2.2.2.2;", "2.2.2.3;", 1)
+	next = strings.Replace(next, ";www.e1.tld;", ";www.e11.tld;", 1)
+
+	ziParse(t, next)
+
+	if Stats.AddCount != 1 || Stats.UpdateCount != 1 || Stats.RemoveCount != 1 {
+		t.Errorf("Changed: %+v\n", Stats)
+	}
+}
+
+// TestZISource tests the mirror clone, update and conversion of dump-NN.csv parts.
+func TestZISource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git")
+	}
+
+	defer func(dump *Dump) { CurrentDump = dump }(CurrentDump)
+
+	CurrentDump = NewDump()
+
+	repo, dir := t.TempDir(), t.TempDir()
+
+	run := func(args ...string) {
+		t.Helper()
+
+		if _, err := git(context.Background(), repo, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.SplitAfter(ziDump, "\n")
+
+	write := func(name, dump string) {
+		t.Helper()
+
+		if err := os.WriteFile(repo+"/"+name, ziCP1251(t, dump), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "--quiet")
+	run("checkout", "--quiet", "-b", "master")
+
+	// parts of a split dump have their own Updated lines.
+	part := lines[0] + strings.TrimSuffix(strings.Join(lines[3:], ""), "\n")
+
+	write("dump-00.csv", strings.Join(lines[:3], ""))
+	write("dump-01.csv", part)
+	run("add", ".")
+	run("commit", "--quiet", "-m", "first")
+
+	source := &ZISource{Repo: repo, Branch: "master"}
+	source.Refresh(context.Background(), dir)
+
+	if len(CurrentDump.ContentIdx) != 4 {
+		t.Fatalf("First: %d records\n", len(CurrentDump.ContentIdx))
+	}
+
+	current, err := ReadCurrentDumpID(dir + "/current")
+	if err != nil || len(current.ID) != 40 || current.UpdateTime != 1293843661 {
+		t.Fatalf("Current: %+v %v\n", current, err)
+	}
+
+	write("dump-01.csv", strings.Replace(part, "3.3.3.3;", "3.3.3.4;", 1))
+	run("commit", "--quiet", "-am", "second")
+
+	source.Refresh(context.Background(), dir)
+
+	if Stats.UpdateCount != 1 || len(CurrentDump.ContentIdx) != 4 {
+		t.Errorf("Second: %+v %d\n", Stats, len(CurrentDump.ContentIdx))
+	}
+
+	// nothing new.
+	Stats = ParseStatistics{}
+
+	source.Refresh(context.Background(), dir)
+
+	if Stats.Count != 0 {
+		t.Errorf("Third: %+v\n", Stats)
+	}
+}